@@ -0,0 +1,113 @@
+// 'pgcenter baseline' - captures rates of stats considered as normal, for comparing live stats with them later.
+
+package baseline
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/baseline"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config defines config container for configuring 'pgcenter baseline'.
+type Config struct {
+	OutputFile string        // file where baseline is saved
+	Duration   time.Duration // interval over which rates are calculated
+}
+
+// RunMain is the 'pgcenter baseline' main entry point.
+func RunMain(dbConfig postgres.Config, config Config) error {
+	if config.Duration < time.Second {
+		return fmt.Errorf("duration must be at least 1s")
+	}
+
+	db, err := postgres.Connect(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	props, err := stat.GetPostgresProperties(db)
+	if err != nil {
+		return err
+	}
+
+	views := view.New()
+	err = views.Configure(props.QueryOptions(0))
+	if err != nil {
+		return err
+	}
+
+	base, err := capture(db, views, config.Duration, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	err = base.Save(config.OutputFile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("INFO: baseline saved into %s\n", config.OutputFile)
+	return nil
+}
+
+// capture takes two snapshots of views with rates separated by duration and builds baseline from them. Views which
+// stats are not available (e.g. pg_stat_statements is not installed) are skipped.
+func capture(db *postgres.DB, views view.Views, duration time.Duration, w io.Writer) (baseline.Baseline, error) {
+	names := make([]string, 0, len(views))
+	for name, v := range views {
+		if v.DiffIntvl != [2]int{0, 0} {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	b := baseline.NewBuilder()
+	skipped := map[string]bool{}
+
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			_, _ = fmt.Fprintf(w, "INFO: capturing baseline during %s\n", duration)
+			time.Sleep(duration)
+		}
+
+		for _, name := range names {
+			if skipped[name] {
+				continue
+			}
+
+			res, err := stat.NewViewResult(db, views[name])
+			if err != nil {
+				_, _ = fmt.Fprintf(w, "WARNING: skip %s: %s\n", name, err)
+				skipped[name] = true
+				continue
+			}
+
+			b.Add(name, time.Now(), res)
+		}
+	}
+
+	cfg := db.Config.Config
+	source := fmt.Sprintf("%s:%d/%s", cfg.Host, cfg.Port, cfg.Database)
+
+	base, err := b.Build(source, views)
+	if err != nil {
+		return baseline.Baseline{}, err
+	}
+
+	captured := make([]string, 0, len(base.Views))
+	for name := range base.Views {
+		captured = append(captured, name)
+	}
+	sort.Strings(captured)
+	_, _ = fmt.Fprintf(w, "INFO: captured: %s\n", strings.Join(captured, ", "))
+
+	return base, nil
+}
@@ -0,0 +1,31 @@
+package baseline
+
+import (
+	"bytes"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_capture(t *testing.T) {
+	db, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	props, err := stat.GetPostgresProperties(db)
+	assert.NoError(t, err)
+
+	views := view.New()
+	assert.NoError(t, views.Configure(query.NewOptions(props.VersionNum, props.Recovery, props.GucTrackCommitTimestamp, 0)))
+
+	var buf bytes.Buffer
+	got, err := capture(db, views, time.Second, &buf)
+	assert.NoError(t, err)
+	assert.Contains(t, got.Views, "databases")
+	assert.NotContains(t, got.Views, "activity")
+	assert.Contains(t, buf.String(), "INFO: captured:")
+}
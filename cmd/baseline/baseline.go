@@ -0,0 +1,59 @@
+// Entry point for 'pgcenter baseline' command.
+
+package baseline
+
+import (
+	"github.com/lesovsky/pgcenter/baseline"
+	"github.com/lesovsky/pgcenter/internal/completion"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var (
+	baselineConfig baseline.Config
+	connOptions    postgres.ConnectionOptions
+
+	// CommandDefinition defines 'baseline' sub-command.
+	CommandDefinition = &cobra.Command{
+		Use:   "baseline",
+		Short: "capture stats baseline",
+		Long:  `'pgcenter baseline' connects to PostgreSQL, captures rates of stats considered as normal and saves them into file for comparing in 'pgcenter top'.`,
+		RunE: func(command *cobra.Command, args []string) error {
+			// Parse extra arguments.
+			if len(args) > 0 {
+				connOptions.ParseExtraArgs(args)
+			}
+
+			// Create connection config.
+			pgConfig, err := connOptions.NewConfig()
+			if err != nil {
+				return err
+			}
+
+			return baseline.RunMain(pgConfig, baselineConfig)
+		},
+	}
+)
+
+func init() {
+	CommandDefinition.Flags().StringVarP(&connOptions.Host, "host", "h", "", "database server host or socket directory")
+	CommandDefinition.Flags().IntVarP(&connOptions.Port, "port", "p", 0, "database server port")
+	CommandDefinition.Flags().StringVarP(&connOptions.User, "username", "U", "", "database user name")
+	CommandDefinition.Flags().StringVarP(&connOptions.Dbname, "dbname", "d", "", "database name or connection string to connect to")
+	CommandDefinition.Flags().StringVarP(&connOptions.Service, "service", "", "", "connection service name defined in pg_service.conf")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Mode, "sslmode", "", "", "SSL mode: disable, allow, prefer, require, verify-ca, verify-full")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.RootCert, "sslrootcert", "", "", "file with SSL root certificates")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Cert, "sslcert", "", "", "file with SSL client certificate")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Key, "sslkey", "", "", "file with SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Password, "sslpassword", "", "", "password for encrypted SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Destination, "ssh", "", "", "connect through SSH tunnel to jump host: [user@]host[:port]")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Key, "ssh-key", "", "", "file with private key for SSH authentication")
+	CommandDefinition.Flags().StringVarP(&connOptions.Auth, "auth", "", "", "authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad")
+	CommandDefinition.Flags().DurationVarP(&connOptions.StatementTimeout, "statement-timeout", "", 30*time.Second, "statement_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().DurationVarP(&connOptions.LockTimeout, "lock-timeout", "", 5*time.Second, "lock_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().StringVarP(&baselineConfig.OutputFile, "file", "f", "pgcenter.baseline.json", "file where baseline is saved")
+	CommandDefinition.Flags().DurationVarP(&baselineConfig.Duration, "duration", "D", time.Minute, "interval over which rates are captured")
+
+	completion.DynamicValues(CommandDefinition, "file", completion.KindBaselines)
+}
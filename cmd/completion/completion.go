@@ -0,0 +1,49 @@
+// Entry point for 'pgcenter completion' command.
+
+package completion
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/completion"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+const (
+	// completeCommandName defines name of hidden command used by completion scripts for getting candidates.
+	completeCommandName = "__complete"
+)
+
+var (
+	// CommandDefinition defines 'completion' sub-command.
+	CommandDefinition = &cobra.Command{
+		Use:       "completion",
+		Short:     "generate shell completion script",
+		Long:      `'pgcenter completion' prints completion script for bash, zsh or fish.`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		RunE: func(command *cobra.Command, args []string) error {
+			script, err := completion.Script(args[0], command.Root().Name(), completeCommandName)
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(script)
+			return nil
+		},
+	}
+
+	// CompleteCommandDefinition defines hidden sub-command which prints candidates for completing command line.
+	CompleteCommandDefinition = &cobra.Command{
+		Use:                completeCommandName,
+		Hidden:             true,
+		DisableFlagParsing: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			candidates := completion.Complete(command.Root(), args)
+			if len(candidates) > 0 {
+				fmt.Println(strings.Join(candidates, "\n"))
+			}
+			return nil
+		},
+	}
+)
@@ -5,8 +5,11 @@ package config
 import (
 	"fmt"
 	"github.com/lesovsky/pgcenter/config"
+	"github.com/lesovsky/pgcenter/internal/completion"
 	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
 	"github.com/spf13/cobra"
+	"time"
 )
 
 var (
@@ -25,7 +28,7 @@ var (
 			}
 
 			// Create connection config.
-			pgConfig, err := postgres.NewConfig(connOptions.Host, connOptions.Port, connOptions.User, connOptions.Dbname)
+			pgConfig, err := connOptions.NewConfig()
 			if err != nil {
 				return err
 			}
@@ -36,37 +39,81 @@ var (
 				return err
 			}
 
-			// Select runtime mode.
-			mode := localOptions.mode()
-
-			return config.RunMain(pgConfig, mode)
+			return config.RunMain(pgConfig, localOptions.config())
 		},
 	}
 )
 
 func init() {
 	CommandDefinition.Flags().StringVarP(&connOptions.Host, "host", "h", "", "database server host or socket directory")
-	CommandDefinition.Flags().IntVarP(&connOptions.Port, "port", "p", 5432, "database server port")
+	CommandDefinition.Flags().IntVarP(&connOptions.Port, "port", "p", 0, "database server port")
 	CommandDefinition.Flags().StringVarP(&connOptions.User, "username", "U", "", "database user name")
-	CommandDefinition.Flags().StringVarP(&connOptions.Dbname, "dbname", "d", "", "database name to connect to")
+	CommandDefinition.Flags().StringVarP(&connOptions.Dbname, "dbname", "d", "", "database name or connection string to connect to")
+	CommandDefinition.Flags().StringVarP(&connOptions.Service, "service", "", "", "connection service name defined in pg_service.conf")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Mode, "sslmode", "", "", "SSL mode: disable, allow, prefer, require, verify-ca, verify-full")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.RootCert, "sslrootcert", "", "", "file with SSL root certificates")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Cert, "sslcert", "", "", "file with SSL client certificate")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Key, "sslkey", "", "", "file with SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Password, "sslpassword", "", "", "password for encrypted SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Destination, "ssh", "", "", "connect through SSH tunnel to jump host: [user@]host[:port]")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Key, "ssh-key", "", "", "file with private key for SSH authentication")
+	CommandDefinition.Flags().StringVarP(&connOptions.Auth, "auth", "", "", "authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad")
+	CommandDefinition.Flags().DurationVarP(&connOptions.StatementTimeout, "statement-timeout", "", 30*time.Second, "statement_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().DurationVarP(&connOptions.LockTimeout, "lock-timeout", "", 5*time.Second, "lock_timeout for pgcenter's queries (0 - use server's setting)")
 	CommandDefinition.Flags().BoolVarP(&localOptions.install, "install", "i", false, "install stats schema into the database")
 	CommandDefinition.Flags().BoolVarP(&localOptions.uninstall, "uninstall", "u", false, "uninstall stats schema from the database")
+	CommandDefinition.Flags().BoolVarP(&localOptions.upgrade, "upgrade", "", false, "upgrade stats schema installed in the database")
+	CommandDefinition.Flags().StringVarP(&localOptions.flavor, "flavor", "", config.FlavorPlperlu, "implementation of schema functions: plperlu, plpgsql")
+	CommandDefinition.Flags().StringVarP(&localOptions.schema, "schema", "", query.DefaultStatSchema, "name of the schema where functions and views are installed")
+	CommandDefinition.Flags().StringVarP(&localOptions.grantRole, "grant", "g", "", "install schema in restricted mode and grant access to specified role")
+	CommandDefinition.Flags().BoolVarP(&localOptions.dryRun, "dry-run", "", false, "print SQL instead of executing it")
+	CommandDefinition.Flags().StringVarP(&localOptions.extensionDir, "emit-extension", "", "", "write extension control and SQL files into specified directory")
+
+	completion.StaticValues(CommandDefinition, "flavor", config.FlavorPlperlu, config.FlavorPlpgsql)
 }
 
 // options defines set of options used only in 'pgcenter config' scope
 type options struct {
 	install   bool
 	uninstall bool
+	upgrade   bool
+	flavor    string
+	schema    string
+	grantRole string
+	dryRun    bool
+	// directory for extension files
+	extensionDir string
 }
 
 // validate performs sanity checks of passed options
 func (opts *options) validate() error {
-	if !opts.install && !opts.uninstall {
-		return fmt.Errorf("using '--install' or '--uninstall' options are mandatory")
+	var n int
+	for _, v := range []bool{opts.install, opts.uninstall, opts.upgrade, opts.extensionDir != ""} {
+		if v {
+			n++
+		}
+	}
+
+	if n == 0 {
+		return fmt.Errorf("using '--install', '--uninstall', '--upgrade' or '--emit-extension' options are mandatory")
+	}
+
+	if n > 1 {
+		return fmt.Errorf("can't use '--install', '--uninstall', '--upgrade' and '--emit-extension' options together")
+	}
+
+	switch opts.flavor {
+	case config.FlavorPlperlu, config.FlavorPlpgsql:
+	default:
+		return fmt.Errorf("unknown flavor '%s', use one of: %s, %s", opts.flavor, config.FlavorPlperlu, config.FlavorPlpgsql)
 	}
 
-	if opts.install == opts.uninstall {
-		return fmt.Errorf("can't use '--install' and '--uninstall' options together")
+	if opts.grantRole != "" && (opts.uninstall || opts.extensionDir != "") {
+		return fmt.Errorf("'--grant' option could be used only with '--install' or '--upgrade'")
+	}
+
+	if opts.dryRun && opts.extensionDir != "" {
+		return fmt.Errorf("'--dry-run' option could not be used with '--emit-extension'")
 	}
 
 	return nil
@@ -80,5 +127,23 @@ func (opts *options) mode() int {
 	if opts.uninstall {
 		return config.Uninstall
 	}
+	if opts.upgrade {
+		return config.Upgrade
+	}
+	if opts.extensionDir != "" {
+		return config.EmitExtension
+	}
 	return -1
 }
+
+// config returns config for 'pgcenter config' command.
+func (opts *options) config() config.Config {
+	return config.Config{
+		Mode:      opts.mode(),
+		Flavor:    opts.flavor,
+		Schema:    opts.schema,
+		GrantRole: opts.grantRole,
+		DryRun:    opts.dryRun,
+		OutputDir: opts.extensionDir,
+	}
+}
@@ -11,10 +11,21 @@ func Test_options_validate(t *testing.T) {
 		in    options
 		valid bool
 	}{
-		{in: options{install: true, uninstall: false}, valid: true},
-		{in: options{install: false, uninstall: true}, valid: true},
-		{in: options{install: false, uninstall: false}, valid: false},
-		{in: options{install: true, uninstall: true}, valid: false},
+		{in: options{install: true, uninstall: false, flavor: "plperlu"}, valid: true},
+		{in: options{install: false, uninstall: true, flavor: "plperlu"}, valid: true},
+		{in: options{install: false, uninstall: false, flavor: "plperlu"}, valid: false},
+		{in: options{install: true, uninstall: true, flavor: "plperlu"}, valid: false},
+		{in: options{install: true, flavor: "plperlu", grantRole: "pg_monitor"}, valid: true},
+		{in: options{uninstall: true, flavor: "plperlu", grantRole: "pg_monitor"}, valid: false},
+		{in: options{install: true, flavor: "plpgsql"}, valid: true},
+		{in: options{install: true, flavor: "invalid"}, valid: false},
+		{in: options{upgrade: true, flavor: "plperlu"}, valid: true},
+		{in: options{upgrade: true, flavor: "plperlu", grantRole: "pg_monitor"}, valid: true},
+		{in: options{install: true, upgrade: true, flavor: "plperlu"}, valid: false},
+		{in: options{extensionDir: "/tmp", flavor: "plperlu"}, valid: true},
+		{in: options{extensionDir: "/tmp", install: true, flavor: "plperlu"}, valid: false},
+		{in: options{extensionDir: "/tmp", flavor: "plperlu", grantRole: "pg_monitor"}, valid: false},
+		{in: options{extensionDir: "/tmp", flavor: "plperlu", dryRun: true}, valid: false},
 	}
 
 	for _, tc := range testcases {
@@ -34,6 +45,8 @@ func Test_options_mode(t *testing.T) {
 	}{
 		{in: options{install: true}, want: config.Install},
 		{in: options{uninstall: true}, want: config.Uninstall},
+		{in: options{upgrade: true}, want: config.Upgrade},
+		{in: options{extensionDir: "/tmp"}, want: config.EmitExtension},
 		{in: options{}, want: -1},
 	}
 
@@ -41,3 +54,21 @@ func Test_options_mode(t *testing.T) {
 		assert.Equal(t, tc.want, tc.in.mode())
 	}
 }
+
+func Test_options_config(t *testing.T) {
+	testcases := []struct {
+		in   options
+		want config.Config
+	}{
+		{in: options{install: true, flavor: "plperlu"}, want: config.Config{Mode: config.Install, Flavor: "plperlu"}},
+		{in: options{install: true, flavor: "plpgsql", grantRole: "pg_monitor"}, want: config.Config{Mode: config.Install, Flavor: "plpgsql", GrantRole: "pg_monitor"}},
+		{in: options{uninstall: true, flavor: "plperlu"}, want: config.Config{Mode: config.Uninstall, Flavor: "plperlu"}},
+		{in: options{install: true, flavor: "plperlu", dryRun: true}, want: config.Config{Mode: config.Install, Flavor: "plperlu", DryRun: true}},
+		{in: options{install: true, flavor: "plperlu", schema: "monitoring"}, want: config.Config{Mode: config.Install, Flavor: "plperlu", Schema: "monitoring"}},
+		{in: options{extensionDir: "/tmp", flavor: "plpgsql"}, want: config.Config{Mode: config.EmitExtension, Flavor: "plpgsql", OutputDir: "/tmp"}},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, tc.in.config())
+	}
+}
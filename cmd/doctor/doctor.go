@@ -0,0 +1,53 @@
+// Entry point for 'pgcenter doctor' command.
+
+package doctor
+
+import (
+	"github.com/lesovsky/pgcenter/doctor"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var (
+	connOptions postgres.ConnectionOptions
+
+	// CommandDefinition defines 'doctor' sub-command.
+	CommandDefinition = &cobra.Command{
+		Use:   "doctor",
+		Short: "checks environment and prerequisites",
+		Long:  `'pgcenter doctor' checks Postgres and environment meet pgcenter requirements.`,
+		RunE: func(command *cobra.Command, args []string) error {
+			// Parse extra arguments.
+			if len(args) > 0 {
+				connOptions.ParseExtraArgs(args)
+			}
+
+			// Create connection config.
+			pgConfig, err := connOptions.NewConfig()
+			if err != nil {
+				return err
+			}
+
+			return doctor.RunMain(pgConfig)
+		},
+	}
+)
+
+func init() {
+	CommandDefinition.Flags().StringVarP(&connOptions.Host, "host", "h", "", "database server host or socket directory")
+	CommandDefinition.Flags().IntVarP(&connOptions.Port, "port", "p", 0, "database server port")
+	CommandDefinition.Flags().StringVarP(&connOptions.User, "username", "U", "", "database user name")
+	CommandDefinition.Flags().StringVarP(&connOptions.Dbname, "dbname", "d", "", "database name or connection string to connect to")
+	CommandDefinition.Flags().StringVarP(&connOptions.Service, "service", "", "", "connection service name defined in pg_service.conf")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Mode, "sslmode", "", "", "SSL mode: disable, allow, prefer, require, verify-ca, verify-full")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.RootCert, "sslrootcert", "", "", "file with SSL root certificates")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Cert, "sslcert", "", "", "file with SSL client certificate")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Key, "sslkey", "", "", "file with SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Password, "sslpassword", "", "", "password for encrypted SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Destination, "ssh", "", "", "connect through SSH tunnel to jump host: [user@]host[:port]")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Key, "ssh-key", "", "", "file with private key for SSH authentication")
+	CommandDefinition.Flags().StringVarP(&connOptions.Auth, "auth", "", "", "authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad")
+	CommandDefinition.Flags().DurationVarP(&connOptions.StatementTimeout, "statement-timeout", "", 30*time.Second, "statement_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().DurationVarP(&connOptions.LockTimeout, "lock-timeout", "", 5*time.Second, "lock_timeout for pgcenter's queries (0 - use server's setting)")
+}
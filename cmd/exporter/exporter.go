@@ -0,0 +1,78 @@
+// Entry point for 'pgcenter exporter' command.
+
+package exporter
+
+import (
+	"github.com/lesovsky/pgcenter/exporter"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/settings"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+var (
+	exporterConfig exporter.Config
+	connOptions    postgres.ConnectionOptions
+	configFile     string
+
+	// CommandDefinition defines 'exporter' sub-command.
+	CommandDefinition = &cobra.Command{
+		Use:   "exporter",
+		Short: "serve stats as Prometheus metrics",
+		Long:  `'pgcenter exporter' collects Postgres and system stats and serves them as Prometheus metrics and over HTTP JSON API.`,
+		RunE: func(command *cobra.Command, args []string) error {
+			// Parse extra arguments.
+			if len(args) > 0 {
+				connOptions.ParseExtraArgs(args)
+			}
+
+			// Create connection config.
+			pgConfig, err := connOptions.NewConfig()
+			if err != nil {
+				return err
+			}
+
+			// Take API token from environment, hence it is not visible in the list of processes.
+			if exporterConfig.APIToken == "" {
+				exporterConfig.APIToken = os.Getenv("PGCENTER_API_TOKEN")
+			}
+
+			// Read configuration file.
+			s, err := settings.Load(configFile)
+			if err != nil {
+				return err
+			}
+
+			exporterConfig.Alerts = s.Alerts
+			exporterConfig.Hooks = s.Hooks
+			exporterConfig.Devices = s.Devices
+
+			return exporter.RunMain(pgConfig, exporterConfig)
+		},
+	}
+)
+
+func init() {
+	CommandDefinition.Flags().StringVarP(&connOptions.Host, "host", "h", "", "database server host or socket directory")
+	CommandDefinition.Flags().IntVarP(&connOptions.Port, "port", "p", 0, "database server port")
+	CommandDefinition.Flags().StringVarP(&connOptions.User, "username", "U", "", "database user name")
+	CommandDefinition.Flags().StringVarP(&connOptions.Dbname, "dbname", "d", "", "database name or connection string to connect to")
+	CommandDefinition.Flags().StringVarP(&connOptions.Service, "service", "", "", "connection service name defined in pg_service.conf")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Mode, "sslmode", "", "", "SSL mode: disable, allow, prefer, require, verify-ca, verify-full")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.RootCert, "sslrootcert", "", "", "file with SSL root certificates")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Cert, "sslcert", "", "", "file with SSL client certificate")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Key, "sslkey", "", "", "file with SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Password, "sslpassword", "", "", "password for encrypted SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Destination, "ssh", "", "", "connect through SSH tunnel to jump host: [user@]host[:port]")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Key, "ssh-key", "", "", "file with private key for SSH authentication")
+	CommandDefinition.Flags().StringVarP(&connOptions.Auth, "auth", "", "", "authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad")
+	CommandDefinition.Flags().DurationVarP(&connOptions.StatementTimeout, "statement-timeout", "", 30*time.Second, "statement_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().DurationVarP(&connOptions.LockTimeout, "lock-timeout", "", 5*time.Second, "lock_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().StringVarP(&exporterConfig.Listen, "listen", "l", ":9119", "address to listen on for metrics requests")
+	CommandDefinition.Flags().DurationVarP(&exporterConfig.Interval, "interval", "i", 10*time.Second, "stats collecting interval, rates are calculated over this interval")
+	CommandDefinition.Flags().BoolVarP(&exporterConfig.API, "api", "", false, "serve stats over HTTP JSON API and web UI")
+	CommandDefinition.Flags().StringVarP(&exporterConfig.APIToken, "api-token", "", "", "token required for API requests (default $PGCENTER_API_TOKEN)")
+	CommandDefinition.Flags().IntVarP(&exporterConfig.APIHistory, "api-history", "", 60, "number of recent snapshots available over API")
+	CommandDefinition.Flags().StringVarP(&configFile, "config-file", "", "", "configuration file with alert rules and hooks (default: $PGCENTER_CONFIG or ~/.pgcenter.yaml)")
+}
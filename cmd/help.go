@@ -4,10 +4,16 @@ package main
 
 import (
 	"fmt"
+	"github.com/lesovsky/pgcenter/cmd/baseline"
+	"github.com/lesovsky/pgcenter/cmd/completion"
 	"github.com/lesovsky/pgcenter/cmd/config"
+	"github.com/lesovsky/pgcenter/cmd/doctor"
+	"github.com/lesovsky/pgcenter/cmd/exporter"
 	"github.com/lesovsky/pgcenter/cmd/profile"
 	"github.com/lesovsky/pgcenter/cmd/record"
 	"github.com/lesovsky/pgcenter/cmd/report"
+	"github.com/lesovsky/pgcenter/cmd/snapshot"
+	"github.com/lesovsky/pgcenter/cmd/stat"
 	top "github.com/lesovsky/pgcenter/cmd/top"
 )
 
@@ -19,29 +25,105 @@ Usage:
   pgcenter [command] [command-flags] [args]
 
 Available commands:
+  baseline	%s
+  completion	%s
   config	%s
+  doctor	%s
+  exporter	%s
   profile	%s
   record	%s
   report	%s
+  snapshot	%s
+  stat		%s
   top		%s
 
 Flags:
   -?, --help		show this help and exit
       --version		show version information and exit
+      --log-level LEVEL	level of logged messages: debug, info, warn, error (default: warn)
+      --log-file FILE	append log messages to file instead of stderr
+
+Logging flags are accepted by all commands.
 
 Use "pgcenter [command] --help" for more information about a command.
 
 Report bugs to <%s>.
 `,
 		pgcenter.Long,
+		baseline.CommandDefinition.Short,
+		completion.CommandDefinition.Short,
 		config.CommandDefinition.Short,
+		doctor.CommandDefinition.Short,
+		exporter.CommandDefinition.Short,
 		profile.CommandDefinition.Short,
 		record.CommandDefinition.Short,
 		report.CommandDefinition.Short,
+		snapshot.CommandDefinition.Short,
+		stat.CommandDefinition.Short,
 		top.CommandDefinition.Short,
 		programIssuesURL)
 }
 
+func printBaselineHelp() string {
+	return fmt.Sprintf(`%s
+
+Usage:
+  pgcenter baseline [OPTIONS]... [DBNAME [USERNAME]]
+
+Options:
+  -d, --dbname DBNAME		database name or connection string to connect to
+  -h, --host HOSTNAME		database server host or socket directory
+  -p, --port PORT		database server port (default from PGPORT or 5432)
+  -U, --username USERNAME	database user name
+      --service NAME		connection service name defined in pg_service.conf
+      --sslmode MODE		SSL mode: disable, allow, prefer, require, verify-ca, verify-full
+      --sslrootcert FILE	file with SSL root certificates
+      --sslcert FILE		file with SSL client certificate
+      --sslkey FILE		file with SSL client private key
+      --sslpassword PASSWORD	password for encrypted SSL client private key
+      --ssh [USER@]HOST[:PORT]	connect through SSH tunnel to jump host
+      --ssh-key FILE		file with private key for SSH authentication
+      --auth METHOD		authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad
+      --statement-timeout DURATION	statement_timeout for pgcenter's queries (default: 30s, 0 disables)
+      --lock-timeout DURATION	lock_timeout for pgcenter's queries (default: 5s, 0 disables)
+
+  -f, --file FILE		file where baseline is saved (default: pgcenter.baseline.json)
+  -D, --duration DURATION	interval over which rates are captured (default: 1m)
+
+General options:
+  -?, --help		show this help and exit
+
+Baseline could also be derived from stats recorded by 'pgcenter record', see 'pgcenter report --save-baseline'.
+
+Report bugs to <%s>.
+`,
+		baseline.CommandDefinition.Long,
+		programIssuesURL)
+}
+
+func printCompletionHelp() string {
+	return fmt.Sprintf(`%s
+
+Usage:
+  pgcenter completion SHELL
+
+Shells:
+  bash		load with: source <(pgcenter completion bash)
+  zsh		load with: source <(pgcenter completion zsh)
+  fish		load with: pgcenter completion fish | source
+
+General options:
+  -?, --help		show this help and exit
+
+Commands, flags, names of views, stats files, baseline files, schema flavors and connection services defined in
+pg_service.conf are completed.
+
+Report bugs to <%s>.
+`,
+		completion.CommandDefinition.Long,
+		programIssuesURL)
+}
+
 func printConfigHelp() string {
 	return fmt.Sprintf(`%s
 
@@ -51,10 +133,27 @@ Usage:
 Options:
   -i, --install			install pgcenter's stats schema
   -u, --uninstall		uninstall pgcenter's stats schema
-  -d, --dbname DBNAME		database name to connect to
+      --upgrade			upgrade pgcenter's stats schema to the current version
+  -g, --grant ROLE		install schema in restricted mode and grant access to ROLE
+      --flavor FLAVOR		implementation of schema functions: plperlu (default), plpgsql
+      --schema SCHEMA		name of the schema for functions and views (default pgcenter)
+      --dry-run			print SQL instead of executing it
+      --emit-extension DIR	write extension control and SQL files into DIR
+  -d, --dbname DBNAME		database name or connection string to connect to
   -h, --host HOSTNAME		database server host or socket directory
-  -p, --port PORT		database server port (default 5432)
+  -p, --port PORT		database server port (default from PGPORT or 5432)
   -U, --username USERNAME	database user name
+      --service NAME		connection service name defined in pg_service.conf
+      --sslmode MODE		SSL mode: disable, allow, prefer, require, verify-ca, verify-full
+      --sslrootcert FILE	file with SSL root certificates
+      --sslcert FILE		file with SSL client certificate
+      --sslkey FILE		file with SSL client private key
+      --sslpassword PASSWORD	password for encrypted SSL client private key
+      --ssh [USER@]HOST[:PORT]	connect through SSH tunnel to jump host
+      --ssh-key FILE		file with private key for SSH authentication
+      --auth METHOD		authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad
+      --statement-timeout DURATION	statement_timeout for pgcenter's queries (default: 30s, 0 disables)
+      --lock-timeout DURATION	lock_timeout for pgcenter's queries (default: 5s, 0 disables)
 
 General options:
   -?, --help		show this help and exit
@@ -65,6 +164,77 @@ Report bugs to <%s>.
 		programIssuesURL)
 }
 
+func printDoctorHelp() string {
+	return fmt.Sprintf(`%s
+
+Usage:
+  pgcenter doctor [OPTIONS]... [DBNAME [USERNAME]]
+
+Options:
+  -d, --dbname DBNAME		database name or connection string to connect to
+  -h, --host HOSTNAME		database server host or socket directory
+  -p, --port PORT		database server port (default from PGPORT or 5432)
+  -U, --username USERNAME	database user name
+      --service NAME		connection service name defined in pg_service.conf
+      --sslmode MODE		SSL mode: disable, allow, prefer, require, verify-ca, verify-full
+      --sslrootcert FILE	file with SSL root certificates
+      --sslcert FILE		file with SSL client certificate
+      --sslkey FILE		file with SSL client private key
+      --sslpassword PASSWORD	password for encrypted SSL client private key
+      --ssh [USER@]HOST[:PORT]	connect through SSH tunnel to jump host
+      --ssh-key FILE		file with private key for SSH authentication
+      --auth METHOD		authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad
+      --statement-timeout DURATION	statement_timeout for pgcenter's queries (default: 30s, 0 disables)
+      --lock-timeout DURATION	lock_timeout for pgcenter's queries (default: 5s, 0 disables)
+
+General options:
+  -?, --help		show this help and exit
+
+Report bugs to <%s>.
+`,
+		doctor.CommandDefinition.Long,
+		programIssuesURL)
+}
+
+func printExporterHelp() string {
+	return fmt.Sprintf(`%s
+
+Usage:
+  pgcenter exporter [OPTIONS]... [DBNAME [USERNAME]]
+
+Options:
+  -d, --dbname DBNAME		database name or connection string to connect to
+  -h, --host HOSTNAME		database server host or socket directory
+  -p, --port PORT		database server port (default from PGPORT or 5432)
+  -U, --username USERNAME	database user name
+      --service NAME		connection service name defined in pg_service.conf
+      --sslmode MODE		SSL mode: disable, allow, prefer, require, verify-ca, verify-full
+      --sslrootcert FILE	file with SSL root certificates
+      --sslcert FILE		file with SSL client certificate
+      --sslkey FILE		file with SSL client private key
+      --sslpassword PASSWORD	password for encrypted SSL client private key
+      --ssh [USER@]HOST[:PORT]	connect through SSH tunnel to jump host
+      --ssh-key FILE		file with private key for SSH authentication
+      --auth METHOD		authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad
+      --statement-timeout DURATION	statement_timeout for pgcenter's queries (default: 30s, 0 disables)
+      --lock-timeout DURATION	lock_timeout for pgcenter's queries (default: 5s, 0 disables)
+
+  -l, --listen ADDRESS		address to listen on for metrics requests (default: :9119)
+  -i, --interval DURATION	stats collecting interval, rates are calculated over this interval (default: 10s)
+      --api			serve stats over HTTP JSON API and web UI
+      --api-token TOKEN		token required for API requests (default: $PGCENTER_API_TOKEN)
+      --api-history NUM		number of recent snapshots available over API (default: 60)
+      --config-file FILE	configuration file with alert rules and hooks (default: $PGCENTER_CONFIG or ~/.pgcenter.yaml)
+
+General options:
+  -?, --help		show this help and exit
+
+Report bugs to <%s>.
+`,
+		exporter.CommandDefinition.Long,
+		programIssuesURL)
+}
+
 func printProfileHelp() string {
 	return fmt.Sprintf(`%s
 
@@ -72,14 +242,45 @@ Usage:
  pgcenter profile [OPTIONS]... [DBNAME [USERNAME]]
 
 Options:
- -d, --dbname DBNAME		database name to connect to
+ -d, --dbname DBNAME		database name or connection string to connect to
  -h, --host HOSTNAME		database server host or socket directory
- -p, --port PORT		database server port (default 5432)
+ -p, --port PORT		database server port (default from PGPORT or 5432)
  -U, --username USERNAME	database user name
+     --service NAME		connection service name defined in pg_service.conf
+     --sslmode MODE		SSL mode: disable, allow, prefer, require, verify-ca, verify-full
+     --sslrootcert FILE	file with SSL root certificates
+     --sslcert FILE		file with SSL client certificate
+     --sslkey FILE		file with SSL client private key
+     --sslpassword PASSWORD	password for encrypted SSL client private key
+     --ssh [USER@]HOST[:PORT]	connect through SSH tunnel to jump host
+     --ssh-key FILE		file with private key for SSH authentication
+     --auth METHOD		authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad
+     --statement-timeout DURATION	statement_timeout for pgcenter's queries (default: 30s, 0 disables)
+     --lock-timeout DURATION	lock_timeout for pgcenter's queries (default: 5s, 0 disables)
 
  -P, --pid PID			backend PID to profile to
- -F, --freq FREQ		profile at this frequency (default: 100ms, min: 1ms, max: 1s)
+     --user USER		profile backends of USER
+     --database DATABASE	profile backends connected to DATABASE
+     --appname APPNAME		profile backends of application APPNAME
+     --query-regex REGEX	profile backends which queries match REGEX
+     --all			profile all backends
+     --follow			wait for matching backends and attach to them one by one
+ -F, --freq FREQ		profile at this frequency (default: 100ms, min: 100us, max: 1s)
  -s, --strsize SIZE		limit length of print query strings to STRSIZE chars (default 128)
+     --format FORMAT		output format: text (default), flamegraph
+     --output OUTPUT		print profile in structured format: json, csv
+     --duration DURATION	stop profiling after DURATION (e.g. 30s, 5m)
+     --max-samples NUM		stop profiling after taking NUM samples
+     --until-idle		stop profiling when profiled backends become idle
+     --per-statement		aggregate samples per statement across executions
+     --group-by GROUPS		roll up profile by: nested (default), wait_event_type, wait_event, queryid, backend
+     --cpu-times		split on-CPU time into user and system time (local instances only)
+     --daemon			profile continuously and write profiles into rotating files
+     --directory DIR		directory for profile files written in daemon mode (default: current directory)
+     --rotate PERIOD		rotate profile files every: hour (default), day
+     --keep NUM			keep specified number of newest profile files (default: keep all)
+     --config-file FILE		configuration file with hooks run in daemon mode (default: $PGCENTER_CONFIG or ~/.pgcenter.yaml)
+     --load FILES		report profile files (or directories) written in daemon mode
 
 General options:
  -?, --help		show this help and exit
@@ -97,10 +298,39 @@ Usage:
   pgcenter top [OPTIONS]... [DBNAME [USERNAME]]
 
 Options:
-  -d, --dbname DBNAME		database name to connect to
+  -d, --dbname DBNAME		database name or connection string to connect to
   -h, --host HOSTNAME		database server host or socket directory
-  -p, --port PORT		database server port (default 5432)
+  -p, --port PORT		database server port (default from PGPORT or 5432)
   -U, --username USERNAME	database user name
+      --service NAME		connection service name defined in pg_service.conf
+      --sslmode MODE		SSL mode: disable, allow, prefer, require, verify-ca, verify-full
+      --sslrootcert FILE	file with SSL root certificates
+      --sslcert FILE		file with SSL client certificate
+      --sslkey FILE		file with SSL client private key
+      --sslpassword PASSWORD	password for encrypted SSL client private key
+      --ssh [USER@]HOST[:PORT]	connect through SSH tunnel to jump host
+      --ssh-key FILE		file with private key for SSH authentication
+      --auth METHOD		authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad
+      --statement-timeout DURATION	statement_timeout for pgcenter's queries (default: 30s, 0 disables)
+      --lock-timeout DURATION	lock_timeout for pgcenter's queries (default: 5s, 0 disables)
+      --instance TARGET	additional instance to connect to: HOST[:PORT] or connection string (repeatable)
+      --cluster FILE	file with list of instances shown in cluster overview
+      --discovery KIND:URL	discover instances for cluster overview using Patroni REST API, etcd or Consul
+      --k8s-selector SELECTOR	discover Postgres pods in Kubernetes using label selector, e.g. app=postgres
+  -n, --k8s-namespace NAMESPACE	Kubernetes namespace of pods (default: namespace of kubectl context)
+      --k8s-context CONTEXT	kubectl context used for discovery (default: current context)
+      --k8s-port-forward	connect to pods through 'kubectl port-forward' (default: true outside of Kubernetes)
+      --read-only		disable actions which change state of Postgres (default: PGCENTER_READ_ONLY)
+      --log-source SOURCE	source of Postgres log used in log tail: file, journald[:UNIT], syslog:[HOST]:PORT (default: file)
+      --bpf			show I/O and futex latencies of local backends measured with BPF (requires bpftrace and CAP_BPF)
+      --baseline FILE		compare stats with baseline saved by 'pgcenter baseline' or 'pgcenter report --save-baseline'
+      --baseline-threshold NUM	growth relative to baseline highlighted as regression, in percents (default: 50)
+      --audit-file FILE		file where cancelled queries, terminated backends, stats resets, reloads and config edits are recorded (default: ~/.pgcenter_audit.log)
+      --offline		when local Postgres is unreachable at startup, show system stats, data directory state and log until it accepts connections (default: true)
+      --pgdata DIR		data directory of local Postgres used in offline mode (default: $PGDATA or location from socket lock file)
+      --dbname-filter NAMES	show activity and statements of specified databases only, comma-separated (repeatable)
+      --user-filter NAMES	show activity and statements of specified users only, comma-separated (repeatable)
+      --config-file FILE	configuration file with alert rules, plugins and hooks (default: $PGCENTER_CONFIG or ~/.pgcenter.yaml)
 
 General options:
   -?, --help		show this help and exit
@@ -118,10 +348,21 @@ Usage:
  pgcenter record [OPTIONS]... [DBNAME [USERNAME]]
 
 Options:
- -d, --dbname DBNAME		database name to connect to
+ -d, --dbname DBNAME		database name or connection string to connect to
  -h, --host HOSTNAME		database server host or socket directory
- -p, --port PORT		database server port (default 5432)
+ -p, --port PORT		database server port (default from PGPORT or 5432)
  -U, --username USERNAME	database user name
+     --service NAME		connection service name defined in pg_service.conf
+     --sslmode MODE		SSL mode: disable, allow, prefer, require, verify-ca, verify-full
+     --sslrootcert FILE	file with SSL root certificates
+     --sslcert FILE		file with SSL client certificate
+     --sslkey FILE		file with SSL client private key
+     --sslpassword PASSWORD	password for encrypted SSL client private key
+     --ssh [USER@]HOST[:PORT]	connect through SSH tunnel to jump host
+     --ssh-key FILE		file with private key for SSH authentication
+     --auth METHOD		authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad
+     --statement-timeout DURATION	statement_timeout for pgcenter's queries (default: 30s, 0 disables)
+     --lock-timeout DURATION	lock_timeout for pgcenter's queries (default: 5s, 0 disables)
 
  -i, --interval DURATION	statistics recording interval (default: 1s)
  -c, --count INT		number of statistics samples to record
@@ -129,6 +370,7 @@ Options:
  -a, --append			append statistics to file (defailt: true)
  -s, --strlimit INT		maximum query length to record (default: 0, no limit)
  -1, --oneshot			append single statistics snapshot and exit (alias for --interval 0 --count 1)
+     --config-file FILE		configuration file with alert rules, plugins and hooks (default: $PGCENTER_CONFIG or ~/.pgcenter.yaml)
 
 General options:
  -?, --help		show this help and exit
@@ -156,6 +398,13 @@ Options:
  -l, --limit INT		print only limited number of rows per sample (default: unlimited)
  -t, --strlimit INT		maximum string size to print (default: 32, 0 disables)
  -r, --rate DURATION		statistics changes rate interval (default: 1s)
+     --save-baseline FILE	save baseline derived from statistics into file, for comparing with in 'pgcenter top'
+     --load CONNINFO		load statistics into database specified by connection string
+     --sslmode MODE		SSL mode used for loading: disable, allow, prefer, require, verify-ca, verify-full
+     --sslrootcert FILE		file with SSL root certificates used for loading
+     --sslcert FILE		file with SSL client certificate used for loading
+     --sslkey FILE		file with SSL client private key used for loading
+     --sslpassword PASSWORD	password for encrypted SSL client private key used for loading
 
 Report options:
  -A, --activity			show pg_stat_activity statistics
@@ -181,3 +430,87 @@ Report bugs to <%s>.
 		report.CommandDefinition.Long,
 		programIssuesURL)
 }
+
+func printSnapshotHelp() string {
+	return fmt.Sprintf(`%s
+
+Usage:
+  pgcenter snapshot [OPTIONS]... [DBNAME [USERNAME]]
+
+Options:
+  -d, --dbname DBNAME		database name or connection string to connect to
+  -h, --host HOSTNAME		database server host or socket directory
+  -p, --port PORT		database server port (default from PGPORT or 5432)
+  -U, --username USERNAME	database user name
+      --service NAME		connection service name defined in pg_service.conf
+      --sslmode MODE		SSL mode: disable, allow, prefer, require, verify-ca, verify-full
+      --sslrootcert FILE	file with SSL root certificates
+      --sslcert FILE		file with SSL client certificate
+      --sslkey FILE		file with SSL client private key
+      --sslpassword PASSWORD	password for encrypted SSL client private key
+      --ssh [USER@]HOST[:PORT]	connect through SSH tunnel to jump host
+      --ssh-key FILE		file with private key for SSH authentication
+      --auth METHOD		authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad
+      --statement-timeout DURATION	statement_timeout for pgcenter's queries (default: 30s, 0 disables)
+      --lock-timeout DURATION	lock_timeout for pgcenter's queries (default: 5s, 0 disables)
+
+      --target CONNINFO		connection string of database where snapshots are stored (default: the source database)
+      --schema NAME		schema where snapshots are stored (default: pgcenter_snapshots)
+  -i, --interval DURATION	interval between snapshots (default: 30m)
+  -c, --count INT		number of snapshots to take (default: 0, until interrupted)
+      --retention DURATION	remove snapshots older than specified age (default: 168h, 0 keeps forever)
+      --reset-statements DURATION	reset pg_stat_statements after snapshot when specified time passed since last reset
+  -l, --list			list stored snapshots and exit
+      --compare FROM:TO		show changes of stats between two snapshots and exit
+
+General options:
+  -?, --help		show this help and exit
+
+Changes between snapshots could also be queried with SQL function SCHEMA.compare(FROM, TO).
+
+Report bugs to <%s>.
+`,
+		snapshot.CommandDefinition.Long,
+		programIssuesURL)
+}
+
+func printStatHelp() string {
+	return fmt.Sprintf(`%s
+
+Usage:
+  pgcenter stat [OPTIONS]... [DBNAME [USERNAME]]
+
+Options:
+  -d, --dbname DBNAME		database name or connection string to connect to
+  -h, --host HOSTNAME		database server host or socket directory
+  -p, --port PORT		database server port (default from PGPORT or 5432)
+  -U, --username USERNAME	database user name
+      --service NAME		connection service name defined in pg_service.conf
+      --sslmode MODE		SSL mode: disable, allow, prefer, require, verify-ca, verify-full
+      --sslrootcert FILE	file with SSL root certificates
+      --sslcert FILE		file with SSL client certificate
+      --sslkey FILE		file with SSL client private key
+      --sslpassword PASSWORD	password for encrypted SSL client private key
+      --ssh [USER@]HOST[:PORT]	connect through SSH tunnel to jump host
+      --ssh-key FILE		file with private key for SSH authentication
+      --auth METHOD		authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad
+      --statement-timeout DURATION	statement_timeout for pgcenter's queries (default: 30s, 0 disables)
+      --lock-timeout DURATION	lock_timeout for pgcenter's queries (default: 5s, 0 disables)
+
+  -V, --view NAME		stats view to print, unique prefix of view name is allowed (default: databases)
+  -i, --interval INTERVAL	interval between snapshots in seconds or as duration, e.g. 5 or 5s (default: 1s)
+  -c, --count INT		number of snapshots to print (default: 1)
+  -f, --format FORMAT		output format: table, json, csv (default: table)
+  -o, --order COLNAME		order rows by column (default: view's default order)
+      --asc			use ascendant order
+  -l, --limit INT		print only limited number of rows per snapshot (default: unlimited)
+  -t, --strlimit INT		maximum string size in table output (default: 32, 0 disables)
+
+General options:
+  -?, --help		show this help and exit
+
+Report bugs to <%s>.
+`,
+		stat.CommandDefinition.Long,
+		programIssuesURL)
+}
@@ -2,14 +2,26 @@ package main
 
 import (
 	"fmt"
+	"github.com/lesovsky/pgcenter/cmd/baseline"
+	"github.com/lesovsky/pgcenter/cmd/completion"
 	"github.com/lesovsky/pgcenter/cmd/config"
+	"github.com/lesovsky/pgcenter/cmd/doctor"
+	"github.com/lesovsky/pgcenter/cmd/exporter"
 	"github.com/lesovsky/pgcenter/cmd/profile"
 	"github.com/lesovsky/pgcenter/cmd/record"
 	"github.com/lesovsky/pgcenter/cmd/report"
+	"github.com/lesovsky/pgcenter/cmd/snapshot"
+	"github.com/lesovsky/pgcenter/cmd/stat"
 	"github.com/lesovsky/pgcenter/cmd/top"
+	"github.com/lesovsky/pgcenter/internal/log"
 	"github.com/spf13/cobra"
 )
 
+var (
+	logLevel string
+	logFile  string
+)
+
 // pgcenter describes the root command of program
 var pgcenter = &cobra.Command{
 	Use:           programName,
@@ -18,21 +30,50 @@ var pgcenter = &cobra.Command{
 	SilenceUsage:  true,
 	SilenceErrors: true,
 	Version:       printVersion(),
+	PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+		return log.Setup(logLevel, logFile)
+	},
 }
 
 func init() {
 	pgcenter.PersistentFlags().BoolP("help", "?", false, "show this help and exit")
+	pgcenter.PersistentFlags().StringVar(&logLevel, "log-level", log.DefaultLevel, "level of logged messages: debug, info, warn, error")
+	pgcenter.PersistentFlags().StringVar(&logFile, "log-file", "", "append log messages to file instead of stderr")
 
 	// Setup help and versions templates for main program
 	pgcenter.SetVersionTemplate(printVersion())
 	pgcenter.SetHelpTemplate(printMainHelp())
 
+	// Setup 'baseline' sub-command
+	pgcenter.AddCommand(baseline.CommandDefinition)
+	baseline.CommandDefinition.SetVersionTemplate(printVersion())
+	baseline.CommandDefinition.SetHelpTemplate(printBaselineHelp())
+	baseline.CommandDefinition.SetUsageTemplate(printBaselineHelp())
+
+	// Setup 'completion' sub-command and hidden command used by completion scripts
+	pgcenter.AddCommand(completion.CommandDefinition, completion.CompleteCommandDefinition)
+	completion.CommandDefinition.SetVersionTemplate(printVersion())
+	completion.CommandDefinition.SetHelpTemplate(printCompletionHelp())
+	completion.CommandDefinition.SetUsageTemplate(printCompletionHelp())
+
 	// Setup 'config' sub-command
 	pgcenter.AddCommand(config.CommandDefinition)
 	config.CommandDefinition.SetVersionTemplate(printVersion())
 	config.CommandDefinition.SetHelpTemplate(printConfigHelp())
 	config.CommandDefinition.SetUsageTemplate(printConfigHelp())
 
+	// Setup 'doctor' sub-command
+	pgcenter.AddCommand(doctor.CommandDefinition)
+	doctor.CommandDefinition.SetVersionTemplate(printVersion())
+	doctor.CommandDefinition.SetHelpTemplate(printDoctorHelp())
+	doctor.CommandDefinition.SetUsageTemplate(printDoctorHelp())
+
+	// Setup 'exporter' sub-command
+	pgcenter.AddCommand(exporter.CommandDefinition)
+	exporter.CommandDefinition.SetVersionTemplate(printVersion())
+	exporter.CommandDefinition.SetHelpTemplate(printExporterHelp())
+	exporter.CommandDefinition.SetUsageTemplate(printExporterHelp())
+
 	// Setup 'profile' sub-command
 	pgcenter.AddCommand(profile.CommandDefinition)
 	profile.CommandDefinition.SetVersionTemplate(printVersion())
@@ -51,6 +92,18 @@ func init() {
 	report.CommandDefinition.SetHelpTemplate(printReportHelp())
 	report.CommandDefinition.SetUsageTemplate(printReportHelp())
 
+	// Setup 'snapshot' sub-command
+	pgcenter.AddCommand(snapshot.CommandDefinition)
+	snapshot.CommandDefinition.SetVersionTemplate(printVersion())
+	snapshot.CommandDefinition.SetHelpTemplate(printSnapshotHelp())
+	snapshot.CommandDefinition.SetUsageTemplate(printSnapshotHelp())
+
+	// Setup 'stat' sub-command
+	pgcenter.AddCommand(stat.CommandDefinition)
+	stat.CommandDefinition.SetVersionTemplate(printVersion())
+	stat.CommandDefinition.SetHelpTemplate(printStatHelp())
+	stat.CommandDefinition.SetUsageTemplate(printStatHelp())
+
 	// Setup 'top' sub-command
 	pgcenter.AddCommand(top.CommandDefinition)
 	top.CommandDefinition.SetVersionTemplate(printVersion())
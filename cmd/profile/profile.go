@@ -4,15 +4,20 @@ package profile
 
 import (
 	"fmt"
+	"github.com/lesovsky/pgcenter/internal/completion"
 	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/settings"
 	"github.com/lesovsky/pgcenter/profile"
 	"github.com/spf13/cobra"
+	"regexp"
+	"strings"
 	"time"
 )
 
 var (
 	profileConfig profile.Config
 	connOptions   postgres.ConnectionOptions
+	configFile    string
 
 	// CommandDefinition is the definition of 'profile' CLI sub-command
 	CommandDefinition = &cobra.Command{
@@ -26,7 +31,7 @@ var (
 			}
 
 			// Create connection config.
-			pgConfig, err := postgres.NewConfig(connOptions.Host, connOptions.Port, connOptions.User, connOptions.Dbname)
+			pgConfig, err := connOptions.NewConfig()
 			if err != nil {
 				return err
 			}
@@ -36,6 +41,15 @@ var (
 				return err
 			}
 
+			// Read configuration file with hooks run on rotation of profile files.
+			if profileConfig.Daemon {
+				s, err := settings.Load(configFile)
+				if err != nil {
+					return err
+				}
+				profileConfig.Hooks = s.Hooks
+			}
+
 			return profile.RunMain(pgConfig, profileConfig)
 		},
 	}
@@ -43,19 +57,127 @@ var (
 
 func init() {
 	CommandDefinition.Flags().StringVarP(&connOptions.Host, "host", "h", "", "database server host or socket directory")
-	CommandDefinition.Flags().IntVarP(&connOptions.Port, "port", "p", 5432, "database server port")
+	CommandDefinition.Flags().IntVarP(&connOptions.Port, "port", "p", 0, "database server port")
 	CommandDefinition.Flags().StringVarP(&connOptions.User, "username", "U", "", "database user name")
-	CommandDefinition.Flags().StringVarP(&connOptions.Dbname, "dbname", "d", "", "database name to connect to")
+	CommandDefinition.Flags().StringVarP(&connOptions.Dbname, "dbname", "d", "", "database name or connection string to connect to")
+	CommandDefinition.Flags().StringVarP(&connOptions.Service, "service", "", "", "connection service name defined in pg_service.conf")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Mode, "sslmode", "", "", "SSL mode: disable, allow, prefer, require, verify-ca, verify-full")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.RootCert, "sslrootcert", "", "", "file with SSL root certificates")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Cert, "sslcert", "", "", "file with SSL client certificate")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Key, "sslkey", "", "", "file with SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Password, "sslpassword", "", "", "password for encrypted SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Destination, "ssh", "", "", "connect through SSH tunnel to jump host: [user@]host[:port]")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Key, "ssh-key", "", "", "file with private key for SSH authentication")
+	CommandDefinition.Flags().StringVarP(&connOptions.Auth, "auth", "", "", "authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad")
+	CommandDefinition.Flags().DurationVarP(&connOptions.StatementTimeout, "statement-timeout", "", 30*time.Second, "statement_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().DurationVarP(&connOptions.LockTimeout, "lock-timeout", "", 5*time.Second, "lock_timeout for pgcenter's queries (0 - use server's setting)")
 	CommandDefinition.Flags().IntVarP(&profileConfig.Pid, "pid", "P", 0, "PID of Postgres backend to profile to")
 	CommandDefinition.Flags().DurationVarP(&profileConfig.Frequency, "freq", "F", 100*time.Millisecond, "profile with this frequency (default: 100ms)")
 	CommandDefinition.Flags().IntVarP(&profileConfig.Strsize, "strsize", "s", 128, "limit length of print query strings to STRSIZE chars (default 128)")
+	CommandDefinition.Flags().StringVarP(&profileConfig.User, "user", "", "", "profile backends of specified user")
+	CommandDefinition.Flags().StringVarP(&profileConfig.Database, "database", "", "", "profile backends connected to specified database")
+	CommandDefinition.Flags().StringVarP(&profileConfig.AppName, "appname", "", "", "profile backends of specified application")
+	CommandDefinition.Flags().StringVarP(&profileConfig.QueryRegex, "query-regex", "", "", "profile backends which queries match regular expression")
+	CommandDefinition.Flags().BoolVarP(&profileConfig.All, "all", "", false, "profile all backends")
+	CommandDefinition.Flags().BoolVarP(&profileConfig.Follow, "follow", "", false, "wait for matching backends and attach to them one by one")
+	CommandDefinition.Flags().StringVarP(&profileConfig.Format, "format", "", profile.FormatText, "output format: text, flamegraph")
+	CommandDefinition.Flags().StringVarP(&profileConfig.Output, "output", "", "", "print profile in structured format: json, csv")
+	CommandDefinition.Flags().DurationVarP(&profileConfig.Duration, "duration", "", 0, "stop profiling after specified duration")
+	CommandDefinition.Flags().IntVarP(&profileConfig.MaxSamples, "max-samples", "", 0, "stop profiling after taking specified number of samples")
+	CommandDefinition.Flags().BoolVarP(&profileConfig.UntilIdle, "until-idle", "", false, "stop profiling when profiled backends become idle")
+	CommandDefinition.Flags().BoolVarP(&profileConfig.PerStatement, "per-statement", "", false, "aggregate samples per statement across executions")
+	CommandDefinition.Flags().StringSliceVarP(&profileConfig.GroupBy, "group-by", "", []string{profile.GroupByNested}, "roll up profile by: nested, wait_event_type, wait_event, queryid, backend")
+	CommandDefinition.Flags().BoolVarP(&profileConfig.CPUTimes, "cpu-times", "", false, "split on-CPU time into user and system time (local instances only)")
+	CommandDefinition.Flags().BoolVarP(&profileConfig.Daemon, "daemon", "", false, "profile continuously and write profiles into rotating files")
+	CommandDefinition.Flags().StringVarP(&profileConfig.Directory, "directory", "", ".", "directory for profile files written in daemon mode")
+	CommandDefinition.Flags().StringVarP(&profileConfig.Rotate, "rotate", "", profile.RotateHour, "rotate profile files every: hour, day")
+	CommandDefinition.Flags().IntVarP(&profileConfig.Keep, "keep", "", 0, "keep specified number of newest profile files (default: keep all)")
+	CommandDefinition.Flags().StringVarP(&configFile, "config-file", "", "", "configuration file with hooks run in daemon mode (default: $PGCENTER_CONFIG or ~/.pgcenter.yaml)")
+	CommandDefinition.Flags().StringSliceVarP(&profileConfig.Load, "load", "", nil, "report profile files (or directories) written in daemon mode")
 
-	_ = CommandDefinition.MarkFlagRequired("pid")
+	completion.StaticValues(CommandDefinition, "group-by", profile.GroupByValues...)
+	completion.StaticValues(CommandDefinition, "format", profile.FormatText, profile.FormatFlamegraph)
+	completion.StaticValues(CommandDefinition, "output", "json", "csv")
+	completion.StaticValues(CommandDefinition, "rotate", profile.RotateHour, profile.RotateDay)
 }
 
 func validate(config profile.Config) error {
-	if config.Frequency < time.Millisecond || config.Frequency > time.Second {
-		return fmt.Errorf("invalid profile frequency, must be between 1 millisecond and 1 second")
+	if config.Frequency < 100*time.Microsecond || config.Frequency > time.Second {
+		return fmt.Errorf("invalid profile frequency, must be between 100 microseconds and 1 second")
+	}
+
+	if config.Duration < 0 || config.MaxSamples < 0 {
+		return fmt.Errorf("invalid duration or max samples, must be positive")
+	}
+
+	if len(config.Load) > 0 {
+		if config.Pid != 0 || config.Filtered() || config.Daemon {
+			return fmt.Errorf("'--load' option could not be used together with '--pid', '--daemon' or backends filters")
+		}
+	} else if config.Daemon {
+		if config.Pid != 0 || config.Output != "" || (config.Format != "" && config.Format != profile.FormatText) || config.CPUTimes {
+			return fmt.Errorf("'--daemon' option could not be used together with '--pid', '--format', '--output' or '--cpu-times'")
+		}
+
+		switch config.Rotate {
+		case profile.RotateHour, profile.RotateDay:
+		default:
+			return fmt.Errorf("unknown rotation period '%s', use one of: %s, %s", config.Rotate, profile.RotateHour, profile.RotateDay)
+		}
+
+		if config.Keep < 0 {
+			return fmt.Errorf("invalid number of kept profile files, must be positive")
+		}
+	} else if config.Pid == 0 && !config.Filtered() {
+		return fmt.Errorf("'--pid' or one of '--user', '--database', '--appname', '--query-regex', '--all' options must be specified")
+	}
+
+	if config.Pid != 0 && config.Filtered() {
+		return fmt.Errorf("'--pid' option could not be used together with '--user', '--database', '--appname', '--query-regex' or '--all'")
+	}
+
+	if config.Follow && (!config.Filtered() || config.Daemon || len(config.Load) > 0) {
+		return fmt.Errorf("'--follow' option requires one of '--user', '--database', '--appname', '--query-regex', '--all' and could not be used together with '--daemon' or '--load'")
+	}
+
+	switch config.Format {
+	case "", profile.FormatText, profile.FormatFlamegraph:
+	default:
+		return fmt.Errorf("unknown format '%s', use one of: %s, %s", config.Format, profile.FormatText, profile.FormatFlamegraph)
+	}
+
+	switch config.Output {
+	case "", profile.FormatJSON, profile.FormatCSV:
+	default:
+		return fmt.Errorf("unknown output '%s', use one of: %s, %s", config.Output, profile.FormatJSON, profile.FormatCSV)
+	}
+
+	if config.Output != "" && config.Format != "" && config.Format != profile.FormatText {
+		return fmt.Errorf("'--output' option could not be used together with '--format %s'", config.Format)
+	}
+
+	for _, g := range config.GroupBy {
+		if !isGroupBy(g) {
+			return fmt.Errorf("unknown grouping '%s', use one of: %s", g, strings.Join(profile.GroupByValues, ", "))
+		}
 	}
+
+	if config.QueryRegex != "" {
+		_, err := regexp.Compile(config.QueryRegex)
+		if err != nil {
+			return fmt.Errorf("invalid query regex: %s", err)
+		}
+	}
+
 	return nil
 }
+
+// isGroupBy returns true if grouping is supported by profiler.
+func isGroupBy(g string) bool {
+	for _, v := range profile.GroupByValues {
+		if g == v {
+			return true
+		}
+	}
+	return false
+}
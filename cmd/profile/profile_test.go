@@ -12,9 +12,40 @@ func Test_validate(t *testing.T) {
 		valid bool
 		cfg   profile.Config
 	}{
-		{valid: true, cfg: profile.Config{Frequency: 50 * time.Millisecond}},
-		{valid: false, cfg: profile.Config{Frequency: time.Millisecond - 1}},
-		{valid: false, cfg: profile.Config{Frequency: time.Second + 1}},
+		{valid: true, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond}},
+		{valid: true, cfg: profile.Config{Pid: 1, Frequency: 500 * time.Microsecond}},
+		{valid: false, cfg: profile.Config{Pid: 1, Frequency: 100*time.Microsecond - 1}},
+		{valid: false, cfg: profile.Config{Pid: 1, Frequency: time.Second + 1}},
+		{valid: false, cfg: profile.Config{Frequency: 50 * time.Millisecond}},
+		{valid: true, cfg: profile.Config{Frequency: 50 * time.Millisecond, All: true}},
+		{valid: true, cfg: profile.Config{Frequency: 50 * time.Millisecond, User: "postgres", AppName: "psql"}},
+		{valid: true, cfg: profile.Config{Frequency: 50 * time.Millisecond, QueryRegex: "^SELECT"}},
+		{valid: false, cfg: profile.Config{Frequency: 50 * time.Millisecond, QueryRegex: "(invalid"}},
+		{valid: false, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, Database: "postgres"}},
+		{valid: true, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, Format: "flamegraph"}},
+		{valid: false, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, Format: "invalid"}},
+		{valid: true, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, Format: "text", Output: "json"}},
+		{valid: true, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, Output: "csv"}},
+		{valid: true, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, Duration: time.Minute, MaxSamples: 100, UntilIdle: true}},
+		{valid: false, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, Duration: -time.Minute}},
+		{valid: false, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, MaxSamples: -1}},
+		{valid: false, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, Output: "xml"}},
+		{valid: false, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, Format: "flamegraph", Output: "json"}},
+		{valid: true, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, GroupBy: []string{"nested", "queryid", "backend"}}},
+		{valid: true, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, GroupBy: []string{"wait_event_type", "wait_event"}}},
+		{valid: false, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, GroupBy: []string{"nested", "invalid"}}},
+		{valid: true, cfg: profile.Config{Frequency: time.Second, Daemon: true, Rotate: "hour"}},
+		{valid: true, cfg: profile.Config{Frequency: time.Second, Daemon: true, Rotate: "day", Keep: 7, Database: "postgres"}},
+		{valid: false, cfg: profile.Config{Frequency: time.Second, Daemon: true, Rotate: "week"}},
+		{valid: false, cfg: profile.Config{Frequency: time.Second, Daemon: true, Rotate: "hour", Keep: -1}},
+		{valid: false, cfg: profile.Config{Pid: 1, Frequency: time.Second, Daemon: true, Rotate: "hour"}},
+		{valid: false, cfg: profile.Config{Frequency: time.Second, Daemon: true, Rotate: "hour", Output: "json"}},
+		{valid: true, cfg: profile.Config{Frequency: 50 * time.Millisecond, Load: []string{"/tmp"}, Output: "json"}},
+		{valid: false, cfg: profile.Config{Frequency: 50 * time.Millisecond, Load: []string{"/tmp"}, All: true}},
+		{valid: false, cfg: profile.Config{Frequency: 50 * time.Millisecond, Load: []string{"/tmp"}, Daemon: true}},
+		{valid: true, cfg: profile.Config{Frequency: 50 * time.Millisecond, QueryRegex: "^INSERT INTO orders", Follow: true}},
+		{valid: false, cfg: profile.Config{Pid: 1, Frequency: 50 * time.Millisecond, Follow: true}},
+		{valid: false, cfg: profile.Config{Frequency: time.Second, Daemon: true, Rotate: "hour", Follow: true, All: true}},
 	}
 
 	for _, tc := range testcases {
@@ -3,7 +3,9 @@
 package record
 
 import (
+	"github.com/lesovsky/pgcenter/internal/completion"
 	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/settings"
 	"github.com/lesovsky/pgcenter/record"
 	"github.com/spf13/cobra"
 	"time"
@@ -13,6 +15,7 @@ var (
 	recordConfig record.Config
 	connOptions  postgres.ConnectionOptions
 	oneshot      bool
+	configFile   string
 
 	// CommandDefinition defines 'record' sub-command.
 	CommandDefinition = &cobra.Command{
@@ -33,11 +36,23 @@ var (
 			}
 
 			// Create connection config.
-			pgConfig, err := postgres.NewConfig(connOptions.Host, connOptions.Port, connOptions.User, connOptions.Dbname)
+			pgConfig, err := connOptions.NewConfig()
 			if err != nil {
 				return err
 			}
 
+			// Read configuration file.
+			s, err := settings.Load(configFile)
+			if err != nil {
+				return err
+			}
+
+			recordConfig.Alerts = s.Alerts
+			recordConfig.Plugins = s.Plugins
+			recordConfig.Hooks = s.Hooks
+			recordConfig.Push = s.Push
+			recordConfig.Devices = s.Devices
+
 			return record.RunMain(pgConfig, recordConfig)
 		},
 	}
@@ -47,13 +62,27 @@ func init() {
 	defaultRecordFile := "pgcenter.stat.tar"
 
 	CommandDefinition.Flags().StringVarP(&connOptions.Host, "host", "h", "", "database server host or socket directory")
-	CommandDefinition.Flags().IntVarP(&connOptions.Port, "port", "p", 5432, "database server port")
+	CommandDefinition.Flags().IntVarP(&connOptions.Port, "port", "p", 0, "database server port")
 	CommandDefinition.Flags().StringVarP(&connOptions.User, "username", "U", "", "database user name")
-	CommandDefinition.Flags().StringVarP(&connOptions.Dbname, "dbname", "d", "", "database name to connect to")
+	CommandDefinition.Flags().StringVarP(&connOptions.Dbname, "dbname", "d", "", "database name or connection string to connect to")
+	CommandDefinition.Flags().StringVarP(&connOptions.Service, "service", "", "", "connection service name defined in pg_service.conf")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Mode, "sslmode", "", "", "SSL mode: disable, allow, prefer, require, verify-ca, verify-full")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.RootCert, "sslrootcert", "", "", "file with SSL root certificates")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Cert, "sslcert", "", "", "file with SSL client certificate")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Key, "sslkey", "", "", "file with SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Password, "sslpassword", "", "", "password for encrypted SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Destination, "ssh", "", "", "connect through SSH tunnel to jump host: [user@]host[:port]")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Key, "ssh-key", "", "", "file with private key for SSH authentication")
+	CommandDefinition.Flags().StringVarP(&connOptions.Auth, "auth", "", "", "authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad")
+	CommandDefinition.Flags().DurationVarP(&connOptions.StatementTimeout, "statement-timeout", "", 30*time.Second, "statement_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().DurationVarP(&connOptions.LockTimeout, "lock-timeout", "", 5*time.Second, "lock_timeout for pgcenter's queries (0 - use server's setting)")
 	CommandDefinition.Flags().DurationVarP(&recordConfig.Interval, "interval", "i", time.Second, "statistics recording interval (default: 1 second)")
 	CommandDefinition.Flags().IntVarP(&recordConfig.Count, "count", "c", -1, "number of statistics samples to record")
 	CommandDefinition.Flags().StringVarP(&recordConfig.OutputFile, "file", "f", defaultRecordFile, "file where statistics are saved")
 	CommandDefinition.Flags().BoolVarP(&recordConfig.AppendFile, "append", "a", false, "append statistics to file (default: true)")
 	CommandDefinition.Flags().IntVarP(&recordConfig.StringLimit, "strlimit", "t", 0, "maximum query length to record (default: 0, no limit)")
 	CommandDefinition.Flags().BoolVarP(&oneshot, "oneshot", "1", false, "append single statistics snapshot to file and exit")
+	CommandDefinition.Flags().StringVarP(&configFile, "config-file", "", "", "configuration file with alert rules, plugins and hooks (default: $PGCENTER_CONFIG or ~/.pgcenter.yaml)")
+
+	completion.DynamicValues(CommandDefinition, "file", completion.KindArchives)
 }
@@ -4,6 +4,8 @@ package report
 
 import (
 	"fmt"
+	"github.com/lesovsky/pgcenter/internal/completion"
+	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/report"
 	"github.com/spf13/cobra"
 	"regexp"
@@ -19,21 +21,27 @@ type options struct {
 	showReplication bool   // Show stats from pg_stat_replication
 	showDatabases   bool   // Show stats from pg_stat_database
 	showTables      bool   // Show stats from pg_stat_user_tables, pg_statio_user_tables
+	showTablesIO    bool   // Show stats from pg_statio_user_tables
 	showIndexes     bool   // Show stats from pg_stat_user_indexes, pg_statio_user_indexes
 	showSizes       bool   // Show tables sizes
 	showFunctions   bool   // Show stats from pg_stat_user_functions
+	showCheckpoints bool   // Show stats from pg_stat_bgwriter, pg_stat_checkpointer
+	showRoles       bool   // Show stats from pg_roles, pg_stat_activity
 	showStatements  string // Show stats from pg_stat_statements
 	showProgress    string // Show stats from pg_stat_progress_* stats
 
-	inputFile      string        // Input file with statistics
-	tsStart, tsEnd string        // Show stats within an interval
-	orderColName   string        // Name of the column used for sorting
-	orderDesc      bool          // Specify to use descendant order
-	orderAsc       bool          // Specify to use ascendant order
-	filter         string        // Perform filtering
-	rowLimit       int           // Number of rows per timestamp
-	strLimit       int           // Trim all strings longer than this limit
-	rate           time.Duration // Stats rate
+	inputFile      string              // Input file with statistics
+	tsStart, tsEnd string              // Show stats within an interval
+	orderColName   string              // Name of the column used for sorting
+	orderDesc      bool                // Specify to use descendant order
+	orderAsc       bool                // Specify to use ascendant order
+	filter         string              // Perform filtering
+	rowLimit       int                 // Number of rows per timestamp
+	strLimit       int                 // Trim all strings longer than this limit
+	rate           time.Duration       // Stats rate
+	loadConninfo   string              // Load stats into database specified by connection string
+	loadSSL        postgres.SSLOptions // SSL options used for loading stats
+	saveBaseline   string              // Save baseline derived from stats into file
 }
 
 var (
@@ -61,9 +69,12 @@ func init() {
 	CommandDefinition.Flags().BoolVarP(&opts.showReplication, "replication", "R", false, "show pg_stat_replication report")
 	CommandDefinition.Flags().BoolVarP(&opts.showDatabases, "databases", "D", false, "show pg_stat_database report")
 	CommandDefinition.Flags().BoolVarP(&opts.showTables, "tables", "T", false, "show pg_stat_user_tables and pg_statio_user_tables report")
+	CommandDefinition.Flags().BoolVar(&opts.showTablesIO, "tables-io", false, "show pg_statio_user_tables report")
 	CommandDefinition.Flags().BoolVarP(&opts.showIndexes, "indexes", "I", false, "show pg_stat_user_indexes and pg_statio_user_indexes report")
 	CommandDefinition.Flags().BoolVarP(&opts.showSizes, "sizes", "S", false, "show tables sizes report")
 	CommandDefinition.Flags().BoolVarP(&opts.showFunctions, "functions", "F", false, "show pg_stat_user_functions report")
+	CommandDefinition.Flags().BoolVarP(&opts.showCheckpoints, "checkpoints", "C", false, "show checkpoints report")
+	CommandDefinition.Flags().BoolVar(&opts.showRoles, "roles", false, "show roles connections report")
 	CommandDefinition.Flags().StringVarP(&opts.showStatements, "statements", "X", "", "show pg_stat_statements report")
 	CommandDefinition.Flags().StringVarP(&opts.showProgress, "progress", "P", "", "show pg_stat_progress_* report")
 
@@ -77,13 +88,23 @@ func init() {
 	CommandDefinition.Flags().IntVarP(&opts.rowLimit, "limit", "l", 0, "print only limited number of rows per sample")
 	CommandDefinition.Flags().IntVarP(&opts.strLimit, "strlimit", "t", 32, "maximum string size for long lines to print (default: 32)")
 	CommandDefinition.Flags().DurationVarP(&opts.rate, "rate", "r", time.Second, "statistics changes rate interval (default: 1s)")
+	CommandDefinition.Flags().StringVarP(&opts.saveBaseline, "save-baseline", "", "", "save baseline derived from statistics into file, for comparing with in 'pgcenter top'")
+	CommandDefinition.Flags().StringVarP(&opts.loadConninfo, "load", "", "", "load statistics into database specified by connection string")
+	CommandDefinition.Flags().StringVarP(&opts.loadSSL.Mode, "sslmode", "", "", "SSL mode used for loading: disable, allow, prefer, require, verify-ca, verify-full")
+	CommandDefinition.Flags().StringVarP(&opts.loadSSL.RootCert, "sslrootcert", "", "", "file with SSL root certificates used for loading")
+	CommandDefinition.Flags().StringVarP(&opts.loadSSL.Cert, "sslcert", "", "", "file with SSL client certificate used for loading")
+	CommandDefinition.Flags().StringVarP(&opts.loadSSL.Key, "sslkey", "", "", "file with SSL client private key used for loading")
+	CommandDefinition.Flags().StringVarP(&opts.loadSSL.Password, "sslpassword", "", "", "password for encrypted SSL client private key used for loading")
+
+	completion.DynamicValues(CommandDefinition, "file", completion.KindArchives)
+	completion.DynamicValues(CommandDefinition, "save-baseline", completion.KindBaselines)
 }
 
 // validate parses and validates options passed by user and returns options ready for 'pgcenter report'.
 func (opts options) validate() (report.Config, error) {
 	// Select report type
 	r := selectReport(opts)
-	if r == "" {
+	if r == "" && opts.loadConninfo == "" && opts.saveBaseline == "" {
 		return report.Config{}, fmt.Errorf("report type is not specified, quit")
 	}
 
@@ -123,6 +144,9 @@ func (opts options) validate() (report.Config, error) {
 		RowLimit:      opts.rowLimit,
 		TruncLimit:    opts.strLimit,
 		Rate:          opts.rate,
+		LoadConninfo:  opts.loadConninfo,
+		LoadSSL:       opts.loadSSL,
+		BaselineFile:  opts.saveBaseline,
 	}, nil
 }
 
@@ -137,12 +161,18 @@ func selectReport(opts options) string {
 		return "databases"
 	case opts.showTables:
 		return "tables"
+	case opts.showTablesIO:
+		return "tables_io"
 	case opts.showIndexes:
 		return "indexes"
 	case opts.showFunctions:
 		return "functions"
 	case opts.showSizes:
 		return "sizes"
+	case opts.showCheckpoints:
+		return "checkpoints"
+	case opts.showRoles:
+		return "roles"
 	case opts.showStatements != "":
 		switch opts.showStatements {
 		case "m":
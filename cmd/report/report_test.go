@@ -16,6 +16,7 @@ func Test_options_validate(t *testing.T) {
 		{valid: true, opts: options{showActivity: true, tsStart: "2021-01-01 12:00:00", tsEnd: "2021-01-01 13:00:00", rate: time.Second}},
 		{valid: true, opts: options{showActivity: true, tsStart: "2021-01-01 12:00:00", tsEnd: "2021-01-01 13:00:00", rate: 0}},
 		{valid: false, opts: options{tsStart: "2021-01-01 12:00:00", tsEnd: "2021-01-01 13:00:00", rate: time.Second}}, // no report type specified
+		{valid: true, opts: options{loadConninfo: "host=127.0.0.1", rate: time.Second}},                                // no report type required for loading
 		{valid: false, opts: options{showActivity: true, tsStart: "2021-01-32", rate: time.Second}},                    // invalid report start timestamp
 		{valid: false, opts: options{showActivity: true, filter: `colname:"["`, rate: time.Second}},                    // invalid regexp
 	}
@@ -42,7 +43,10 @@ func Test_selectReport(t *testing.T) {
 		{opts: options{showTables: true}, want: "tables"},
 		{opts: options{showIndexes: true}, want: "indexes"},
 		{opts: options{showFunctions: true}, want: "functions"},
+		{opts: options{showTablesIO: true}, want: "tables_io"},
 		{opts: options{showSizes: true}, want: "sizes"},
+		{opts: options{showCheckpoints: true}, want: "checkpoints"},
+		{opts: options{showRoles: true}, want: "roles"},
 		{opts: options{showStatements: "m"}, want: "statements_timings"},
 		{opts: options{showStatements: "g"}, want: "statements_general"},
 		{opts: options{showStatements: "i"}, want: "statements_io"},
@@ -0,0 +1,62 @@
+// Entry point for 'pgcenter snapshot' command.
+
+package snapshot
+
+import (
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/snapshot"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var (
+	snapshotConfig snapshot.Config
+	connOptions    postgres.ConnectionOptions
+
+	// CommandDefinition defines 'snapshot' sub-command.
+	CommandDefinition = &cobra.Command{
+		Use:   "snapshot",
+		Short: "store stats snapshots into database",
+		Long:  `'pgcenter snapshot' connects to PostgreSQL, periodically stores changes of stats into tables of a database and compares stored snapshots.`,
+		RunE: func(command *cobra.Command, args []string) error {
+			// Parse extra arguments.
+			if len(args) > 0 {
+				connOptions.ParseExtraArgs(args)
+			}
+
+			// Create connection config.
+			pgConfig, err := connOptions.NewConfig()
+			if err != nil {
+				return err
+			}
+
+			return snapshot.RunMain(pgConfig, snapshotConfig)
+		},
+	}
+)
+
+func init() {
+	CommandDefinition.Flags().StringVarP(&connOptions.Host, "host", "h", "", "database server host or socket directory")
+	CommandDefinition.Flags().IntVarP(&connOptions.Port, "port", "p", 0, "database server port")
+	CommandDefinition.Flags().StringVarP(&connOptions.User, "username", "U", "", "database user name")
+	CommandDefinition.Flags().StringVarP(&connOptions.Dbname, "dbname", "d", "", "database name or connection string to connect to")
+	CommandDefinition.Flags().StringVarP(&connOptions.Service, "service", "", "", "connection service name defined in pg_service.conf")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Mode, "sslmode", "", "", "SSL mode: disable, allow, prefer, require, verify-ca, verify-full")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.RootCert, "sslrootcert", "", "", "file with SSL root certificates")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Cert, "sslcert", "", "", "file with SSL client certificate")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Key, "sslkey", "", "", "file with SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Password, "sslpassword", "", "", "password for encrypted SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Destination, "ssh", "", "", "connect through SSH tunnel to jump host: [user@]host[:port]")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Key, "ssh-key", "", "", "file with private key for SSH authentication")
+	CommandDefinition.Flags().StringVarP(&connOptions.Auth, "auth", "", "", "authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad")
+	CommandDefinition.Flags().DurationVarP(&connOptions.StatementTimeout, "statement-timeout", "", 30*time.Second, "statement_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().DurationVarP(&connOptions.LockTimeout, "lock-timeout", "", 5*time.Second, "lock_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().StringVarP(&snapshotConfig.Target, "target", "", "", "connection string of database where snapshots are stored (default: the source database)")
+	CommandDefinition.Flags().StringVarP(&snapshotConfig.Schema, "schema", "", snapshot.DefaultSchema, "schema where snapshots are stored")
+	CommandDefinition.Flags().DurationVarP(&snapshotConfig.Interval, "interval", "i", 30*time.Minute, "interval between snapshots")
+	CommandDefinition.Flags().IntVarP(&snapshotConfig.Count, "count", "c", 0, "number of snapshots to take (0 - until interrupted)")
+	CommandDefinition.Flags().DurationVarP(&snapshotConfig.Retention, "retention", "", 7*24*time.Hour, "remove snapshots older than specified age (0 - keep forever)")
+	CommandDefinition.Flags().DurationVarP(&snapshotConfig.ResetStatements, "reset-statements", "", 0, "reset pg_stat_statements after snapshot when specified time passed since last reset (0 - never)")
+	CommandDefinition.Flags().BoolVarP(&snapshotConfig.List, "list", "l", false, "list stored snapshots and exit")
+	CommandDefinition.Flags().StringVarP(&snapshotConfig.Compare, "compare", "", "", "show changes of stats between two snapshots (format: FROM:TO) and exit")
+}
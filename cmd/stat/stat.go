@@ -0,0 +1,92 @@
+// Entry point for 'pgcenter stat' command.
+
+package stat
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/completion"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/stat"
+	"github.com/spf13/cobra"
+	"strconv"
+	"time"
+)
+
+var (
+	statConfig  stat.Config
+	connOptions postgres.ConnectionOptions
+	interval    string
+	orderAsc    bool
+
+	// CommandDefinition defines 'stat' sub-command.
+	CommandDefinition = &cobra.Command{
+		Use:   "stat",
+		Short: "print stats snapshots and exit",
+		Long:  `'pgcenter stat' connects to PostgreSQL, takes a number of stats snapshots, prints them with calculated rates and exits.`,
+		RunE: func(command *cobra.Command, args []string) error {
+			// Parse extra arguments.
+			if len(args) > 0 {
+				connOptions.ParseExtraArgs(args)
+			}
+
+			var err error
+			statConfig.Interval, err = parseInterval(interval)
+			if err != nil {
+				return err
+			}
+
+			statConfig.OrderDesc = !orderAsc
+
+			// Create connection config.
+			pgConfig, err := connOptions.NewConfig()
+			if err != nil {
+				return err
+			}
+
+			return stat.RunMain(pgConfig, statConfig)
+		},
+	}
+)
+
+func init() {
+	CommandDefinition.Flags().StringVarP(&connOptions.Host, "host", "h", "", "database server host or socket directory")
+	CommandDefinition.Flags().IntVarP(&connOptions.Port, "port", "p", 0, "database server port")
+	CommandDefinition.Flags().StringVarP(&connOptions.User, "username", "U", "", "database user name")
+	CommandDefinition.Flags().StringVarP(&connOptions.Dbname, "dbname", "d", "", "database name or connection string to connect to")
+	CommandDefinition.Flags().StringVarP(&connOptions.Service, "service", "", "", "connection service name defined in pg_service.conf")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Mode, "sslmode", "", "", "SSL mode: disable, allow, prefer, require, verify-ca, verify-full")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.RootCert, "sslrootcert", "", "", "file with SSL root certificates")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Cert, "sslcert", "", "", "file with SSL client certificate")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Key, "sslkey", "", "", "file with SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSL.Password, "sslpassword", "", "", "password for encrypted SSL client private key")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Destination, "ssh", "", "", "connect through SSH tunnel to jump host: [user@]host[:port]")
+	CommandDefinition.Flags().StringVarP(&connOptions.SSH.Key, "ssh-key", "", "", "file with private key for SSH authentication")
+	CommandDefinition.Flags().StringVarP(&connOptions.Auth, "auth", "", "", "authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad")
+	CommandDefinition.Flags().DurationVarP(&connOptions.StatementTimeout, "statement-timeout", "", 30*time.Second, "statement_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().DurationVarP(&connOptions.LockTimeout, "lock-timeout", "", 5*time.Second, "lock_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().StringVarP(&statConfig.View, "view", "V", "databases", "stats view to print, unique prefix of view name is allowed")
+	CommandDefinition.Flags().StringVarP(&interval, "interval", "i", "1s", "interval between snapshots in seconds or as duration, e.g. 5 or 5s")
+	CommandDefinition.Flags().IntVarP(&statConfig.Count, "count", "c", 1, "number of snapshots to print")
+	CommandDefinition.Flags().StringVarP(&statConfig.Format, "format", "f", stat.FormatTable, "output format: table, json, csv")
+	CommandDefinition.Flags().StringVarP(&statConfig.OrderColName, "order", "o", "", "sort rows by column using descendant order")
+	CommandDefinition.Flags().BoolVarP(&orderAsc, "asc", "", false, "sort rows by column using ascendant order")
+	CommandDefinition.Flags().IntVarP(&statConfig.RowLimit, "limit", "l", 0, "print only limited number of rows per snapshot")
+	CommandDefinition.Flags().IntVarP(&statConfig.TruncLimit, "strlimit", "t", 32, "maximum string size for long values in table output")
+
+	completion.DynamicValues(CommandDefinition, "view", completion.KindViews)
+	completion.StaticValues(CommandDefinition, "format", stat.FormatTable, stat.FormatJSON, stat.FormatCSV)
+}
+
+// parseInterval parses interval specified as number of seconds or as duration.
+func parseInterval(s string) (time.Duration, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval '%s': must be number of seconds or duration, e.g. 5 or 5s", s)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,31 @@
+package stat
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_parseInterval(t *testing.T) {
+	testcases := []struct {
+		valid bool
+		in    string
+		want  time.Duration
+	}{
+		{valid: true, in: "5", want: 5 * time.Second},
+		{valid: true, in: "5s", want: 5 * time.Second},
+		{valid: true, in: "1m30s", want: 90 * time.Second},
+		{valid: false, in: "abc"},
+		{valid: false, in: ""},
+	}
+
+	for _, tc := range testcases {
+		got, err := parseInterval(tc.in)
+		if tc.valid {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
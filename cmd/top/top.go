@@ -3,13 +3,41 @@
 package top
 
 import (
+	"context"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/baseline"
+	"github.com/lesovsky/pgcenter/internal/completion"
+	"github.com/lesovsky/pgcenter/internal/discovery"
 	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/settings"
 	"github.com/lesovsky/pgcenter/top"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 )
 
 var (
-	opts postgres.ConnectionOptions
+	opts          postgres.ConnectionOptions
+	readOnly      bool
+	instances     []string
+	configFile    string
+	cluster       string
+	discoverySpec string
+	logSource     string
+	bpf           bool
+	baselineFile  string
+	threshold     float64
+	auditFile     string
+	offline       bool
+	dataDir       string
+	databases     []string
+	users         []string
+	k8s           discovery.KubernetesOptions
 
 	// CommandDefinition defines 'top' sub-command.
 	CommandDefinition = &cobra.Command{
@@ -22,13 +50,92 @@ var (
 				opts.ParseExtraArgs(args)
 			}
 
+			var sources int
+			for _, set := range []bool{cluster != "", discoverySpec != "", k8s.Selector != ""} {
+				if set {
+					sources++
+				}
+			}
+
+			if sources > 1 {
+				return fmt.Errorf("options --cluster, --discovery and --k8s-selector can't be used together")
+			}
+
+			if sources > 0 && len(instances) > 0 {
+				return fmt.Errorf("options --cluster, --discovery and --k8s-selector can't be used together with --instance")
+			}
+
 			// Create connection config.
-			pgConfig, err := postgres.NewConfig(opts.Host, opts.Port, opts.User, opts.Dbname)
+			pgConfig, err := opts.NewConfig()
 			if err != nil {
 				return err
 			}
 
-			return top.RunMain(pgConfig)
+			// Create connection configs for additional instances, connection options are inherited.
+			var configs []postgres.Config
+			for _, target := range instances {
+				o, err := opts.WithTarget(target)
+				if err != nil {
+					return err
+				}
+
+				c, err := o.NewConfig()
+				if err != nil {
+					return err
+				}
+				configs = append(configs, c)
+			}
+
+			// Read configuration file.
+			s, err := settings.Load(configFile)
+			if err != nil {
+				return err
+			}
+
+			topOpts := top.Options{ReadOnly: readOnly, Actions: s.Actions, Instances: configs, Alerts: s.Alerts, Plugins: s.Plugins, Hooks: s.Hooks, Push: s.Push, UI: s.UI, Header: s.Header, Notify: s.Notify, LogSource: logSource, BPF: bpf, Threshold: threshold, AuditFile: auditFile, Offline: offline, DataDir: dataDir, Databases: databases, Users: users, Devices: s.Devices}
+
+			// Data directory is used in offline mode, by default it is taken from environment like Postgres utilities do.
+			if topOpts.DataDir == "" {
+				topOpts.DataDir = os.Getenv("PGDATA")
+			}
+
+			// Read baseline which stats are compared with.
+			if baselineFile != "" {
+				topOpts.Baseline, err = baseline.Load(baselineFile)
+				if err != nil {
+					return err
+				}
+			}
+
+			if cluster != "" {
+				members, err := readClusterFile(cluster, opts)
+				if err != nil {
+					return err
+				}
+				return top.RunCluster(members, nil, topOpts)
+			}
+
+			if discoverySpec != "" {
+				source, err := discovery.NewSource(discoverySpec)
+				if err != nil {
+					return err
+				}
+				return top.RunCluster(nil, discoverFunc(source, opts), topOpts)
+			}
+
+			if k8s.Selector != "" {
+				source, err := discovery.NewKubernetesSource(k8s)
+				if err != nil {
+					return err
+				}
+				// Stop port forwarders on exit.
+				if c, ok := source.(io.Closer); ok {
+					defer func() { _ = c.Close() }()
+				}
+				return top.RunCluster(nil, discoverFunc(source, opts), topOpts)
+			}
+
+			return top.RunMain(pgConfig, topOpts)
 		},
 	}
 )
@@ -38,5 +145,130 @@ func init() {
 	CommandDefinition.Flags().StringVarP(&opts.Host, "host", "h", "", "database server host or socket directory")
 	CommandDefinition.Flags().IntVarP(&opts.Port, "port", "p", 0, "database server port")
 	CommandDefinition.Flags().StringVarP(&opts.User, "username", "U", "", "database user name")
-	CommandDefinition.Flags().StringVarP(&opts.Dbname, "dbname", "d", "", "database name to connect to")
+	CommandDefinition.Flags().StringVarP(&opts.Dbname, "dbname", "d", "", "database name or connection string to connect to")
+	CommandDefinition.Flags().StringVarP(&opts.Service, "service", "", "", "connection service name defined in pg_service.conf")
+	CommandDefinition.Flags().StringVarP(&opts.SSL.Mode, "sslmode", "", "", "SSL mode: disable, allow, prefer, require, verify-ca, verify-full")
+	CommandDefinition.Flags().StringVarP(&opts.SSL.RootCert, "sslrootcert", "", "", "file with SSL root certificates")
+	CommandDefinition.Flags().StringVarP(&opts.SSL.Cert, "sslcert", "", "", "file with SSL client certificate")
+	CommandDefinition.Flags().StringVarP(&opts.SSL.Key, "sslkey", "", "", "file with SSL client private key")
+	CommandDefinition.Flags().StringVarP(&opts.SSL.Password, "sslpassword", "", "", "password for encrypted SSL client private key")
+	CommandDefinition.Flags().StringVarP(&opts.SSH.Destination, "ssh", "", "", "connect through SSH tunnel to jump host: [user@]host[:port]")
+	CommandDefinition.Flags().StringVarP(&opts.SSH.Key, "ssh-key", "", "", "file with private key for SSH authentication")
+	CommandDefinition.Flags().StringVarP(&opts.Auth, "auth", "", "", "authentication method: password, aws-rds-iam, gcp-cloudsql-iam, azure-ad")
+	CommandDefinition.Flags().DurationVarP(&opts.StatementTimeout, "statement-timeout", "", 30*time.Second, "statement_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().DurationVarP(&opts.LockTimeout, "lock-timeout", "", 5*time.Second, "lock_timeout for pgcenter's queries (0 - use server's setting)")
+	CommandDefinition.Flags().StringArrayVarP(&instances, "instance", "", nil, "additional instance to connect to: host[:port] or connection string (repeatable)")
+	CommandDefinition.Flags().StringVarP(&cluster, "cluster", "", "", "file with list of instances shown in cluster overview")
+	CommandDefinition.Flags().StringVarP(&discoverySpec, "discovery", "", "", "discover instances for cluster overview: patroni|etcd|consul:URL[,URL...]")
+	CommandDefinition.Flags().StringVarP(&k8s.Selector, "k8s-selector", "", "", "discover Postgres pods in Kubernetes using label selector, e.g. app=postgres")
+	CommandDefinition.Flags().StringVarP(&k8s.Namespace, "k8s-namespace", "n", "", "Kubernetes namespace of pods (default: namespace of kubectl context)")
+	CommandDefinition.Flags().StringVarP(&k8s.Context, "k8s-context", "", "", "kubectl context used for discovery (default: current context)")
+	CommandDefinition.Flags().BoolVarP(&k8s.PortForward, "k8s-port-forward", "", !discovery.InCluster(), "connect to pods through 'kubectl port-forward' (default: true outside of Kubernetes)")
+	CommandDefinition.Flags().BoolVarP(&readOnly, "read-only", "", readOnlyDefault(), "disable actions which change state of Postgres (default: PGCENTER_READ_ONLY)")
+	CommandDefinition.Flags().StringVarP(&logSource, "log-source", "", "file", "source of Postgres log used in log tail: file, journald[:UNIT], syslog:[HOST]:PORT")
+	CommandDefinition.Flags().BoolVarP(&bpf, "bpf", "", false, "show I/O and futex latencies of local backends measured with BPF (requires bpftrace and CAP_BPF)")
+	CommandDefinition.Flags().StringVarP(&baselineFile, "baseline", "", "", "compare stats with baseline saved by 'pgcenter baseline' or 'pgcenter report --save-baseline'")
+	CommandDefinition.Flags().Float64VarP(&threshold, "baseline-threshold", "", baseline.DefaultThreshold, "growth relative to baseline highlighted as regression, in percents")
+	CommandDefinition.Flags().StringVarP(&auditFile, "audit-file", "", "", "file where cancelled queries, terminated backends, stats resets, reloads and config edits are recorded (default: ~/.pgcenter_audit.log)")
+	CommandDefinition.Flags().BoolVarP(&offline, "offline", "", true, "when local Postgres is unreachable at startup, show system stats, data directory state and log until it accepts connections")
+	CommandDefinition.Flags().StringVarP(&dataDir, "pgdata", "", "", "data directory of local Postgres used in offline mode (default: $PGDATA or location from socket lock file)")
+	CommandDefinition.Flags().StringSliceVarP(&databases, "dbname-filter", "", nil, "show activity and statements of specified databases only, comma-separated (repeatable)")
+	CommandDefinition.Flags().StringSliceVarP(&users, "user-filter", "", nil, "show activity and statements of specified users only, comma-separated (repeatable)")
+	CommandDefinition.Flags().StringVarP(&configFile, "config-file", "", "", "configuration file with alert rules, plugins and hooks (default: $PGCENTER_CONFIG or ~/.pgcenter.yaml)")
+
+	completion.DynamicValues(CommandDefinition, "baseline", completion.KindBaselines)
+	completion.StaticValues(CommandDefinition, "log-source", "file", "journald", "syslog:")
+}
+
+// readOnlyDefault returns default value of '--read-only' option, which is set by PGCENTER_READ_ONLY environment variable.
+func readOnlyDefault() bool {
+	v, err := strconv.ParseBool(os.Getenv("PGCENTER_READ_ONLY"))
+	return err == nil && v
+}
+
+// clusterFile defines file with list of instances used in cluster overview.
+type clusterFile struct {
+	Instances []struct {
+		Name   string `yaml:"name"`   // name shown in overview, target is used if empty
+		Target string `yaml:"target"` // host[:port] or connection string
+	} `yaml:"instances"`
+}
+
+// readClusterFile reads list of instances from file and creates connection configs for them. Connection options
+// specified in command line are inherited by instances.
+func readClusterFile(filename string, opts postgres.ConnectionOptions) ([]top.ClusterInstance, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, fmt.Errorf("read cluster file failed: %s", err)
+	}
+
+	members, err := parseClusterFile(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("parse cluster file %s failed: %s", filename, err)
+	}
+
+	return members, nil
+}
+
+// parseClusterFile parses list of instances and creates connection configs for them.
+func parseClusterFile(data []byte, opts postgres.ConnectionOptions) ([]top.ClusterInstance, error) {
+	var f clusterFile
+	err := yaml.UnmarshalStrict(data, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.Instances) == 0 {
+		return nil, fmt.Errorf("no instances specified")
+	}
+
+	members := make([]top.ClusterInstance, 0, len(f.Instances))
+	for i, inst := range f.Instances {
+		o, err := opts.WithTarget(inst.Target)
+		if err != nil {
+			return nil, fmt.Errorf("instance %d: %s", i+1, err)
+		}
+
+		c, err := o.NewConfig()
+		if err != nil {
+			return nil, fmt.Errorf("instance %d: %s", i+1, err)
+		}
+
+		name := inst.Name
+		if name == "" {
+			name = inst.Target
+		}
+
+		members = append(members, top.ClusterInstance{Name: name, Config: c})
+	}
+
+	return members, nil
+}
+
+// discoverFunc returns function which discovers instances of cluster and creates connection configs for them.
+// Connection options specified in command line are inherited by instances.
+func discoverFunc(source discovery.Source, opts postgres.ConnectionOptions) top.DiscoverFunc {
+	return func(ctx context.Context) ([]top.ClusterInstance, error) {
+		members, err := source.Members(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		instances := make([]top.ClusterInstance, 0, len(members))
+		for _, m := range members {
+			o, err := opts.WithTarget(m.Target())
+			if err != nil {
+				return nil, fmt.Errorf("member %s: %s", m.Name, err)
+			}
+
+			c, err := o.NewConfig()
+			if err != nil {
+				return nil, fmt.Errorf("member %s: %s", m.Name, err)
+			}
+
+			instances = append(instances, top.ClusterInstance{Name: m.Name, Config: c})
+		}
+
+		return instances, nil
+	}
 }
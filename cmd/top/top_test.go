@@ -0,0 +1,70 @@
+package top
+
+import (
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func Test_readOnlyDefault(t *testing.T) {
+	testcases := []struct {
+		value string
+		want  bool
+	}{
+		{value: "", want: false},
+		{value: "true", want: true},
+		{value: "1", want: true},
+		{value: "false", want: false},
+		{value: "invalid", want: false},
+	}
+
+	for _, tc := range testcases {
+		assert.NoError(t, os.Setenv("PGCENTER_READ_ONLY", tc.value))
+		assert.Equal(t, tc.want, readOnlyDefault())
+	}
+	assert.NoError(t, os.Unsetenv("PGCENTER_READ_ONLY"))
+}
+
+func Test_parseClusterFile(t *testing.T) {
+	opts := postgres.ConnectionOptions{User: "postgres", Dbname: "pgbench"}
+
+	data := []byte(`
+instances:
+  - name: primary
+    target: 10.0.0.1:5433
+  - target: 10.0.0.2
+  - name: standby
+    target: "host=10.0.0.3 port=5434 user=monitor dbname=postgres"
+`)
+
+	got, err := parseClusterFile(data, opts)
+	assert.NoError(t, err)
+	assert.Len(t, got, 3)
+
+	assert.Equal(t, "primary", got[0].Name)
+	assert.Equal(t, "10.0.0.1", got[0].Config.Config.Host)
+	assert.Equal(t, uint16(5433), got[0].Config.Config.Port)
+	assert.Equal(t, "postgres", got[0].Config.Config.User)
+	assert.Equal(t, "pgbench", got[0].Config.Config.Database)
+
+	assert.Equal(t, "10.0.0.2", got[1].Name)
+	assert.Equal(t, "10.0.0.2", got[1].Config.Config.Host)
+
+	assert.Equal(t, "standby", got[2].Name)
+	assert.Equal(t, "10.0.0.3", got[2].Config.Config.Host)
+	assert.Equal(t, uint16(5434), got[2].Config.Config.Port)
+	assert.Equal(t, "monitor", got[2].Config.Config.User)
+
+	// Invalid files.
+	for _, data := range []string{
+		"",
+		"instances: []",
+		"instances:\n  - name: empty\n",
+		"instances:\n  - target: host:invalid\n",
+		"instances:\n  - target: host\n    unknown: value\n",
+	} {
+		_, err := parseClusterFile([]byte(data), opts)
+		assert.Error(t, err)
+	}
+}
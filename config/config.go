@@ -3,35 +3,95 @@ package config
 import (
 	"context"
 	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/lesovsky/pgcenter/internal/interrupt"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"io"
+	"os"
+	"strings"
 )
 
 const (
-	// Flags which tells to pgcenter install or uninstall schema.
+	// Flags which tells to pgcenter install, uninstall or upgrade schema.
 	Install = iota
 	Uninstall
+	Upgrade
+	EmitExtension
 )
 
+const (
+	// FlavorPlperlu defines stats schema implemented using untrusted PL/Perl.
+	FlavorPlperlu = "plperlu"
+	// FlavorPlpgsql defines stats schema implemented using PL/pgSQL and pg_read_file().
+	FlavorPlpgsql = "plpgsql"
+)
+
+// Config defines config of 'pgcenter config' command.
+type Config struct {
+	Mode      int    // Install, uninstall, upgrade schema or emit extension files
+	Flavor    string // Implementation of schema functions
+	Schema    string // Name of the schema where functions and views are installed
+	GrantRole string // Role which is granted to use schema installed in restricted mode
+	DryRun    bool   // Print SQL instead of executing it
+	OutputDir string // Directory where extension files are written
+}
+
 // RunMain is the main entry point for 'pgcenter config' command.
-func RunMain(dbConfig postgres.Config, mode int) error {
-	db, err := postgres.Connect(dbConfig)
+func RunMain(dbConfig postgres.Config, config Config) error {
+	// Print SQL without connecting to Postgres.
+	if config.DryRun {
+		return printQueries(os.Stdout, config)
+	}
+
+	// Write extension files without connecting to Postgres.
+	if config.Mode == EmitExtension {
+		files, err := emitExtension(config)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("pgCenter extension files written: %s.", strings.Join(files, ", "))
+		return nil
+	}
+
+	// In case of SIGINT queries in flight are cancelled, transaction is rolled back.
+	ctx, cancel := interrupt.Context(context.Background(), os.Interrupt)
+	defer cancel()
+
+	db, err := postgres.ConnectContext(ctx, dbConfig)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	switch mode {
+	switch config.Mode {
 	case Install:
-		if err := doInstall(db); err != nil {
+		if err := doInstall(ctx, db, config); err != nil {
 			return err
 		}
 		fmt.Printf("pgCenter schema installed.")
+		if config.GrantRole != "" {
+			fmt.Printf(" Access granted to %s.", config.GrantRole)
+		}
 	case Uninstall:
-		if err := doUninstall(db); err != nil {
+		if err := doUninstall(ctx, db, config); err != nil {
 			return err
 		}
 		fmt.Printf("pgCenter schema uninstalled.")
+	case Upgrade:
+		from, err := doUpgrade(ctx, db, config)
+		if err != nil {
+			return err
+		}
+		if from == query.StatSchemaVersion {
+			fmt.Printf("pgCenter schema is up to date (version %d).", from)
+		} else {
+			fmt.Printf("pgCenter schema upgraded from version %d to %d.", from, query.StatSchemaVersion)
+		}
+		if config.GrantRole != "" {
+			fmt.Printf(" Access granted to %s.", config.GrantRole)
+		}
 	default:
 		// should not be here, but who knows...
 		fmt.Printf("do nothing, unknown mode selected.")
@@ -42,34 +102,96 @@ func RunMain(dbConfig postgres.Config, mode int) error {
 }
 
 // doInstall begins transaction and create pgcenter schema, functions and views.
-func doInstall(db *postgres.DB) error {
-	queries := []string{
-		query.StatSchemaCreateSchema,
-		query.StatSchemaCreateFunction1,
-		query.StatSchemaCreateFunction2,
-		query.StatSchemaCreateFunction3,
-		query.StatSchemaCreateView1,
-		query.StatSchemaCreateView2,
-		query.StatSchemaCreateView3,
-		query.StatSchemaCreateView4,
-		query.StatSchemaCreateView5,
-		query.StatSchemaCreateView6,
-	}
-
-	tx, err := db.Conn.Begin(context.Background())
+func doInstall(ctx context.Context, db *postgres.DB, config Config) error {
+	if config.Flavor == FlavorPlpgsql {
+		var version int
+		err := db.QueryRowContext(ctx, query.GetSetting, "server_version_num").Scan(&version)
+		if err != nil {
+			return err
+		}
+		if version < 130000 {
+			return fmt.Errorf("%s flavor requires Postgres 13 or newer", FlavorPlpgsql)
+		}
+	}
+
+	queries, err := installQueries(config)
+	if err != nil {
+		return err
+	}
+
+	return execQueries(ctx, db, queries)
+}
+
+// doUpgrade detects flavor and mode of installed schema and replaces schema functions and views with the current ones.
+// Returns version of the schema installed before upgrade.
+func doUpgrade(ctx context.Context, db *postgres.DB, config Config) (int, error) {
+	schema := config.schemaName()
+
+	var exists bool
+	err := db.QueryRowContext(ctx, query.CheckSchemaExists, schema).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, fmt.Errorf("%s schema is not installed, use '--install' for installing it", schema)
+	}
+
+	version, err := stat.GetStatSchemaVersion(db, schema)
+	if err != nil {
+		return 0, err
+	}
+
+	if version == query.StatSchemaVersion && config.GrantRole == "" {
+		return version, nil
+	}
+
+	if version > query.StatSchemaVersion {
+		return 0, fmt.Errorf("installed schema version %d is newer than supported %d, upgrade pgcenter", version, query.StatSchemaVersion)
+	}
+
+	// Functions of plpgsql flavor read stats using get_proc_lines() function.
+	err = db.QueryRowContext(ctx, query.CheckFunctionExists, pgx.Identifier{schema, "get_proc_lines"}.Sanitize()).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+
+	flavor := FlavorPlperlu
+	if exists {
+		flavor = FlavorPlpgsql
+	}
+
+	// Functions of restricted schema are executed with privileges of the owner.
+	var restricted bool
+	err = db.QueryRowContext(ctx, query.CheckFunctionSecurityDefiner, pgx.Identifier{schema, "get_sys_clk_ticks"}.Sanitize()).Scan(&restricted)
+	if err != nil {
+		return 0, err
+	}
+
+	queries, err := schemaQueries(flavor, restricted || config.GrantRole != "", config.schemaOptions())
+	if err != nil {
+		return 0, err
+	}
+
+	return version, execQueries(ctx, db, queries)
+}
+
+// execQueries executes queries within single transaction. Executing is cancelled when context is done.
+func execQueries(ctx context.Context, db *postgres.DB, queries []string) error {
+	tx, err := db.Conn.Begin(ctx)
 	if err != nil {
 		return err
 	}
 
 	for _, q := range queries {
-		_, err := tx.Exec(context.Background(), q)
+		_, err := tx.Exec(ctx, q)
 		if err != nil {
+			// Transaction is rolled back even if executing has been cancelled.
 			_ = tx.Rollback(context.Background())
 			return err
 		}
 	}
 
-	err = tx.Commit(context.Background())
+	err = tx.Commit(ctx)
 	if err != nil {
 		return err
 	}
@@ -77,9 +199,158 @@ func doInstall(db *postgres.DB) error {
 	return nil
 }
 
+// installQueries returns list of queries required for installing schema.
+func installQueries(config Config) ([]string, error) {
+	return schemaQueries(config.Flavor, config.GrantRole != "", config.schemaOptions())
+}
+
+// schemaName returns name of the schema where functions and views are installed.
+func (c Config) schemaName() string {
+	if c.Schema == "" {
+		return query.DefaultStatSchema
+	}
+	return c.Schema
+}
+
+// schemaOptions returns options used for formatting schema queries.
+func (c Config) schemaOptions() query.SchemaOptions {
+	return query.NewSchemaOptions(c.schemaName(), c.GrantRole)
+}
+
+// schemaQueries returns list of queries which create schema of specified flavor. In restricted mode functions are
+// not available to PUBLIC, and if role is specified in options it is granted to use the schema.
+func schemaQueries(flavor string, restricted bool, opts query.SchemaOptions) ([]string, error) {
+	var create, secure []string
+
+	switch flavor {
+	case FlavorPlperlu, "":
+		create = []string{
+			query.StatSchemaCreateSchema,
+			query.StatSchemaCreateFunction1,
+			query.StatSchemaCreateFunction2,
+			query.StatSchemaCreateFunction3,
+			query.StatSchemaCreateFunction4,
+			query.StatSchemaCreateVersionFunction,
+			query.StatSchemaCreateView1,
+			query.StatSchemaCreateView2,
+			query.StatSchemaCreateView3,
+			query.StatSchemaCreateView4,
+			query.StatSchemaCreateView5,
+			query.StatSchemaCreateView6,
+		}
+		secure = []string{
+			query.StatSchemaSecureFunction1,
+			query.StatSchemaSecureFunction2,
+			query.StatSchemaSecureFunction3,
+			query.StatSchemaSecureFunction4,
+		}
+	case FlavorPlpgsql:
+		create = []string{
+			query.StatSchemaCreateSchema,
+			query.StatSchemaPlpgsqlCreateFunction1,
+			query.StatSchemaPlpgsqlCreateFunction2,
+			query.StatSchemaPlpgsqlCreateFunction3,
+			query.StatSchemaPlpgsqlCreateFunction4,
+			query.StatSchemaCreateVersionFunction,
+			query.StatSchemaPlpgsqlCreateView1,
+			query.StatSchemaPlpgsqlCreateView2,
+			query.StatSchemaPlpgsqlCreateView3,
+			query.StatSchemaPlpgsqlCreateView4,
+			query.StatSchemaPlpgsqlCreateView5,
+			query.StatSchemaPlpgsqlCreateView6,
+		}
+		secure = []string{
+			query.StatSchemaSecureFunction1,
+			query.StatSchemaSecureFunction2,
+			query.StatSchemaPlpgsqlSecureFunction3,
+			query.StatSchemaSecureFunction4,
+		}
+	default:
+		return nil, fmt.Errorf("unknown schema flavor: %s", flavor)
+	}
+
+	var templates = create
+
+	// In restricted mode functions are executed as superuser who installs the schema,
+	// and only the specified role is allowed to use them.
+	if restricted {
+		templates = append(templates, secure...)
+		templates = append(templates, query.StatSchemaRevokeFunctions, query.StatSchemaGrantVersionFunction)
+	}
+
+	if opts.Role != "" {
+		templates = append(templates, query.StatSchemaGrantSchema, query.StatSchemaGrantFunctions, query.StatSchemaGrantViews)
+	}
+
+	opts.Restricted = restricted
+
+	queries := make([]string, 0, len(templates))
+	for _, tmpl := range templates {
+		q, err := query.FormatSchema(tmpl, opts)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+
+	return queries, nil
+}
+
+// printQueries prints SQL which is executed in the selected mode.
+func printQueries(w io.Writer, config Config) error {
+	var queries []string
+	var err error
+
+	switch config.Mode {
+	case Install, Upgrade:
+		// Upgrade replaces all functions and views, the same SQL as for install is used.
+		queries, err = installQueries(config)
+		if err != nil {
+			return err
+		}
+	case Uninstall:
+		q, err := query.FormatSchema(query.StatSchemaDropSchema, config.schemaOptions())
+		if err != nil {
+			return err
+		}
+		queries = []string{q}
+	default:
+		return fmt.Errorf("unknown mode selected")
+	}
+
+	_, err = fmt.Fprintln(w, "BEGIN;")
+	if err != nil {
+		return err
+	}
+
+	err = writeQueries(w, queries)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, "\nCOMMIT;")
+	return err
+}
+
+// writeQueries writes queries separated by semicolons.
+func writeQueries(w io.Writer, queries []string) error {
+	for _, q := range queries {
+		_, err := fmt.Fprintf(w, "\n%s;\n", strings.TrimSuffix(strings.TrimSpace(q), ";"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // doUninstall drops pgcenter stats schema.
-func doUninstall(db *postgres.DB) error {
-	_, err := db.Exec(query.StatSchemaDropSchema)
+func doUninstall(ctx context.Context, db *postgres.DB, config Config) error {
+	q, err := query.FormatSchema(query.StatSchemaDropSchema, config.schemaOptions())
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, q)
 	if err != nil {
 		return err
 	}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"bytes"
 	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -13,6 +16,98 @@ func TestRunMain(t *testing.T) {
 	// run tests in dedicated database to avoid interfering with other test which depends on stats schema
 	config.Config.Database = config.Config.Database + "_config"
 
-	assert.NoError(t, RunMain(config, Install))
-	assert.NoError(t, RunMain(config, Uninstall))
+	assert.NoError(t, RunMain(config, Config{Mode: Install}))
+	assert.NoError(t, RunMain(config, Config{Mode: Install, GrantRole: "pg_monitor"}))
+	assert.NoError(t, RunMain(config, Config{Mode: Install, Flavor: FlavorPlpgsql}))
+	assert.NoError(t, RunMain(config, Config{Mode: Upgrade}))
+	assert.NoError(t, RunMain(config, Config{Mode: Uninstall}))
+	assert.NoError(t, RunMain(config, Config{Mode: Install, Schema: "monitoring"}))
+	assert.NoError(t, RunMain(config, Config{Mode: Upgrade, Schema: "monitoring"}))
+	assert.NoError(t, RunMain(config, Config{Mode: Uninstall, Schema: "monitoring"}))
+	assert.Error(t, RunMain(config, Config{Mode: Upgrade}))
+}
+
+func Test_installQueries(t *testing.T) {
+	got, err := installQueries(Config{Mode: Install})
+	assert.NoError(t, err)
+	assert.Len(t, got, 12)
+	assert.Contains(t, got[5], "SELECT 3;")
+
+	got, err = installQueries(Config{Mode: Install, GrantRole: "pgcenter_monitor"})
+	assert.NoError(t, err)
+	assert.Len(t, got, 21)
+	assert.Equal(t, `GRANT USAGE ON SCHEMA pgcenter TO "pgcenter_monitor"`, got[18])
+	assert.Equal(t, `GRANT SELECT ON ALL TABLES IN SCHEMA pgcenter TO "pgcenter_monitor"`, got[20])
+
+	got, err = installQueries(Config{Mode: Install, Flavor: FlavorPlpgsql, GrantRole: "pgcenter_monitor"})
+	assert.NoError(t, err)
+	assert.Len(t, got, 21)
+	assert.Contains(t, got[3], "CREATE OR REPLACE FUNCTION pgcenter.get_proc_lines(")
+	assert.Contains(t, got[4], "FROM pgcenter.get_proc_lines('/proc/net/dev'")
+	assert.Equal(t, "ALTER FUNCTION pgcenter.get_proc_lines(character varying, integer, character varying) SECURITY DEFINER SET search_path = pg_catalog, pg_temp", got[14])
+	assert.Equal(t, "ALTER FUNCTION pgcenter.get_netdevs_link_settings() SECURITY DEFINER SET search_path = pg_catalog, pg_temp", got[15])
+
+	// Custom schema.
+	got, err = installQueries(Config{Mode: Install, Schema: "monitoring", GrantRole: "pgcenter_monitor"})
+	assert.NoError(t, err)
+	assert.Len(t, got, 21)
+	assert.Equal(t, "CREATE SCHEMA IF NOT EXISTS monitoring", got[0])
+	assert.Contains(t, got[11], "FROM monitoring.get_proc_stats('/proc/uptime'")
+	assert.Equal(t, `GRANT USAGE ON SCHEMA monitoring TO "pgcenter_monitor"`, got[18])
+	for _, q := range got {
+		assert.NotRegexp(t, `pgcenter\.(get|sys)_`, q)
+	}
+
+	_, err = installQueries(Config{Mode: Install, Flavor: "invalid"})
+	assert.Error(t, err)
+}
+
+func Test_schemaQueries(t *testing.T) {
+	// Upgrade of restricted schema without granting access to a role.
+	got, err := schemaQueries(FlavorPlperlu, true, query.NewSchemaOptions("", ""))
+	assert.NoError(t, err)
+	assert.Len(t, got, 18)
+	assert.Equal(t, "REVOKE ALL ON ALL FUNCTIONS IN SCHEMA pgcenter FROM PUBLIC", got[16])
+	assert.Equal(t, "GRANT EXECUTE ON FUNCTION pgcenter.get_schema_version() TO PUBLIC", got[17])
+
+	// Reading files is restricted only in restricted mode, the function is executed with privileges of the owner.
+	assert.Contains(t, got[3], "pgcenter: reading $_[0] is not allowed")
+
+	got, err = schemaQueries(FlavorPlperlu, false, query.NewSchemaOptions("", ""))
+	assert.NoError(t, err)
+	assert.Len(t, got, 12)
+	assert.Contains(t, got[3], "get_proc_stats")
+	assert.NotContains(t, got[3], "is not allowed")
+	assert.True(t, strings.Contains(got[3], "AS $$\nopen FILE, $_[0];"))
+}
+
+func Test_printQueries(t *testing.T) {
+	testcases := []struct {
+		config Config
+		valid  bool
+		want   []string
+	}{
+		{config: Config{Mode: Install}, valid: true, want: []string{"CREATE SCHEMA IF NOT EXISTS pgcenter;", "LANGUAGE plperlu"}},
+		{config: Config{Mode: Install, Flavor: FlavorPlpgsql, GrantRole: "monitoring"}, valid: true, want: []string{"pg_read_file", `TO "monitoring";`}},
+		{config: Config{Mode: Upgrade}, valid: true, want: []string{"CREATE OR REPLACE FUNCTION pgcenter.get_schema_version()"}},
+		{config: Config{Mode: Uninstall}, valid: true, want: []string{"DROP SCHEMA IF EXISTS pgcenter CASCADE;"}},
+		{config: Config{Mode: Uninstall, Schema: "Monitoring"}, valid: true, want: []string{`DROP SCHEMA IF EXISTS "Monitoring" CASCADE;`}},
+		{config: Config{Mode: Install, Flavor: "invalid"}, valid: false},
+		{config: Config{Mode: -1}, valid: false},
+	}
+
+	for _, tc := range testcases {
+		buf := &bytes.Buffer{}
+		err := printQueries(buf, tc.config)
+		if tc.valid {
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(buf.String(), "BEGIN;\n"))
+			assert.True(t, strings.HasSuffix(buf.String(), "\nCOMMIT;\n"))
+			for _, want := range tc.want {
+				assert.Contains(t, buf.String(), want)
+			}
+		} else {
+			assert.Error(t, err)
+		}
+	}
 }
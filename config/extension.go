@@ -0,0 +1,122 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"io/ioutil"
+	"path/filepath"
+)
+
+const (
+	// extensionName defines name of the extension.
+	extensionName = "pgcenter"
+	// extensionFirstVersion defines the first version of stats schema distributed as an extension.
+	extensionFirstVersion = 2
+)
+
+// emitExtension writes control and SQL script files which allow to install stats schema using CREATE EXTENSION.
+// Returns list of written files.
+func emitExtension(config Config) ([]string, error) {
+	files, err := extensionFiles(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, f := range files {
+		path := filepath.Join(config.OutputDir, f.name)
+		err := ioutil.WriteFile(path, f.data, 0644) // #nosec G306
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+
+	return written, nil
+}
+
+// extensionFile describes single file of the extension.
+type extensionFile struct {
+	name string
+	data []byte
+}
+
+// extensionFiles returns control file, install script for the current schema version and update scripts from
+// previous versions.
+func extensionFiles(config Config) ([]extensionFile, error) {
+	flavor := config.Flavor
+	if flavor == "" {
+		flavor = FlavorPlperlu
+	}
+
+	opts := query.NewSchemaOptions(config.schemaName(), "")
+
+	queries, err := schemaQueries(flavor, false, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	createSchema, err := query.FormatSchema(query.StatSchemaCreateSchema, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Schema is created by CREATE EXTENSION accordingly to control file, skip explicit creation.
+	var objects []string
+	for _, q := range queries {
+		if q != createSchema {
+			objects = append(objects, q)
+		}
+	}
+
+	current := query.StatSchemaVersion
+
+	files := []extensionFile{{name: extensionName + ".control", data: extensionControl(flavor, config.schemaName(), current)}}
+
+	script, err := extensionScript(fmt.Sprintf("CREATE EXTENSION %s", extensionName), objects)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, extensionFile{name: fmt.Sprintf("%s--%d.sql", extensionName, current), data: script})
+
+	// All functions and views are created using CREATE OR REPLACE, hence update scripts are the same as install script.
+	for v := extensionFirstVersion; v < current; v++ {
+		script, err := extensionScript(fmt.Sprintf("ALTER EXTENSION %s UPDATE TO '%d'", extensionName, current), objects)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, extensionFile{name: fmt.Sprintf("%s--%d--%d.sql", extensionName, v, current), data: script})
+	}
+
+	return files, nil
+}
+
+// extensionControl returns content of extension control file.
+func extensionControl(flavor string, schema string, version int) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# %s extension\n", extensionName)
+	fmt.Fprintf(buf, "comment = 'pgCenter stats schema: system statistics functions and views (%s flavor)'\n", flavor)
+	fmt.Fprintf(buf, "default_version = '%d'\n", version)
+	fmt.Fprintf(buf, "relocatable = false\n")
+	fmt.Fprintf(buf, "schema = %s\n", schema)
+	fmt.Fprintf(buf, "superuser = true\n")
+	if flavor == FlavorPlperlu {
+		fmt.Fprintf(buf, "requires = 'plperlu'\n")
+	}
+	return buf.Bytes()
+}
+
+// extensionScript returns content of extension SQL script.
+func extensionScript(command string, queries []string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "-- complain if script is sourced in psql, rather than via %s\n", command)
+	fmt.Fprintf(buf, "\\echo Use \"%s\" to load this file. \\quit\n", command)
+
+	err := writeQueries(buf, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_emitExtension(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-extension")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	files, err := emitExtension(Config{Mode: EmitExtension, OutputDir: dir})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "pgcenter.control"),
+		filepath.Join(dir, fmt.Sprintf("pgcenter--%d.sql", query.StatSchemaVersion)),
+		filepath.Join(dir, fmt.Sprintf("pgcenter--%d--%d.sql", extensionFirstVersion, query.StatSchemaVersion)),
+	}, files)
+
+	// Non-existent directory.
+	_, err = emitExtension(Config{Mode: EmitExtension, OutputDir: filepath.Join(dir, "invalid")})
+	assert.Error(t, err)
+}
+
+func Test_extensionFiles(t *testing.T) {
+	testcases := []struct {
+		flavor  string
+		control []string
+		script  []string
+	}{
+		{flavor: FlavorPlperlu, control: []string{"requires = 'plperlu'"}, script: []string{"LANGUAGE plperlu"}},
+		{flavor: FlavorPlpgsql, script: []string{"LANGUAGE plpgsql", "pg_read_file"}},
+	}
+
+	for _, tc := range testcases {
+		files, err := extensionFiles(Config{Flavor: tc.flavor})
+		assert.NoError(t, err)
+		assert.Len(t, files, 1+query.StatSchemaVersion-extensionFirstVersion+1)
+
+		control := string(files[0].data)
+		assert.Contains(t, control, fmt.Sprintf("default_version = '%d'", query.StatSchemaVersion))
+		assert.Contains(t, control, "schema = pgcenter")
+		for _, want := range tc.control {
+			assert.Contains(t, control, want)
+		}
+		if tc.flavor != FlavorPlperlu {
+			assert.NotContains(t, control, "requires")
+		}
+
+		script := string(files[1].data)
+		assert.Contains(t, script, `\echo Use "CREATE EXTENSION pgcenter" to load this file. \quit`)
+		assert.Contains(t, script, "CREATE OR REPLACE FUNCTION pgcenter.get_schema_version()")
+		assert.NotContains(t, script, "CREATE SCHEMA")
+		assert.NotContains(t, script, "BEGIN;")
+		for _, want := range tc.script {
+			assert.Contains(t, script, want)
+		}
+
+		// Update scripts contain the same objects as install script.
+		update := string(files[2].data)
+		assert.Contains(t, update, fmt.Sprintf(`\echo Use "ALTER EXTENSION pgcenter UPDATE TO '%d'" to load this file. \quit`, query.StatSchemaVersion))
+		assert.Contains(t, update, "CREATE OR REPLACE FUNCTION pgcenter.get_netdevs_link_settings(")
+	}
+
+	// Custom schema.
+	files, err := extensionFiles(Config{Flavor: FlavorPlperlu, Schema: "monitoring"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(files[0].data), "schema = monitoring")
+	assert.Contains(t, string(files[1].data), "CREATE OR REPLACE VIEW monitoring.sys_proc_stat AS")
+	assert.NotRegexp(t, `pgcenter\.(get|sys)_`, string(files[1].data))
+
+	_, err = extensionFiles(Config{Flavor: "invalid"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,278 @@
+package doctor
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// Statuses of performed checks.
+	statusOK = iota
+	statusWarn
+	statusFail
+)
+
+// minVersionNum defines the oldest supported Postgres version.
+const minVersionNum = 90500
+
+// procFiles defines list of files used for reading system stats.
+var procFiles = []string{"/proc/stat", "/proc/loadavg", "/proc/meminfo", "/proc/diskstats", "/proc/net/dev", "/proc/uptime"}
+
+// result describes result of a single check.
+type result struct {
+	name    string // name of the check
+	status  int    // status of the check
+	message string // details about check's result
+	hint    string // remediation steps
+}
+
+// RunMain is the main entry point for 'pgcenter doctor' command.
+func RunMain(dbConfig postgres.Config) error {
+	results, caps := doChecks(dbConfig)
+
+	err := printResults(os.Stdout, results)
+	if err != nil {
+		return err
+	}
+
+	err = printCapabilities(os.Stdout, caps)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if r.status == statusFail {
+			return fmt.Errorf("some checks failed")
+		}
+	}
+
+	return nil
+}
+
+// doChecks connects to Postgres, performs all checks and probes availability of stats views.
+func doChecks(dbConfig postgres.Config) ([]result, []stat.Capability) {
+	db, err := postgres.Connect(dbConfig)
+	if err != nil {
+		return []result{{
+			name: "connection", status: statusFail, message: err.Error(),
+			hint: "check connection settings, Postgres is running and accepts connections (listen_addresses, pg_hba.conf)",
+		}}, nil
+	}
+	defer db.Close()
+
+	results := []result{{name: "connection", status: statusOK, message: "connected to " + formatConn(db)}}
+
+	props, err := stat.GetPostgresProperties(db)
+	if err != nil {
+		return append(results, result{
+			name: "server version", status: statusFail, message: fmt.Sprintf("failed to get Postgres properties: %s", err),
+			hint: fmt.Sprintf("pgcenter supports Postgres %s and newer", stat.FormatVersion(minVersionNum)),
+		}), nil
+	}
+
+	results = append(results, checkVersion(props.VersionNum, props.Version))
+
+	results = append(results, checkPrivileges(props.Privileges))
+
+	settings := map[string]string{}
+	for _, name := range []string{"track_activities", "track_counts", "track_io_timing", "track_functions", "shared_preload_libraries"} {
+		var value string
+		if err := db.QueryRow(query.GetSetting, name).Scan(&value); err == nil {
+			settings[name] = value
+		}
+	}
+
+	results = append(results, checkTrackSettings(settings)...)
+	results = append(results, checkPgss(props.ExtPGSSAvail, settings["shared_preload_libraries"]))
+
+	if db.Local {
+		results = append(results, checkProcfs(procFiles))
+	} else {
+		// Properties of stats schema are gathered only for remote Postgres.
+		results = append(results, checkSchema(props.SchemaPgcenterAvail, props.SchemaName, props.SchemaVersion))
+	}
+
+	return results, stat.GetCapabilities(db, props)
+}
+
+// checkVersion checks Postgres version is supported.
+func checkVersion(num int, version string) result {
+	if num < minVersionNum {
+		return result{
+			name: "server version", status: statusFail, message: fmt.Sprintf("Postgres %s is not supported", version),
+			hint: fmt.Sprintf("pgcenter supports Postgres %s and newer", stat.FormatVersion(minVersionNum)),
+		}
+	}
+	return result{name: "server version", status: statusOK, message: fmt.Sprintf("Postgres %s is supported", version)}
+}
+
+// checkPrivileges checks the role has enough privileges for seeing all stats.
+func checkPrivileges(p stat.Privileges) result {
+	switch {
+	case p.Superuser:
+		return result{name: "privileges", status: statusOK, message: "connected as superuser"}
+	case p.AllStats():
+		return result{name: "privileges", status: statusOK, message: "role is a member of pg_monitor or pg_read_all_stats"}
+	default:
+		return result{
+			name: "privileges", status: statusWarn, message: "role is not a superuser and not a member of pg_monitor",
+			hint: "queries and details of other users' sessions will be hidden, grant pg_monitor to the role: GRANT pg_monitor TO <role>",
+		}
+	}
+}
+
+// checkTrackSettings checks stats collector settings required for complete stats.
+func checkTrackSettings(settings map[string]string) []result {
+	var results []result
+
+	for _, name := range []string{"track_activities", "track_counts", "track_io_timing"} {
+		value, ok := settings[name]
+		if !ok {
+			continue
+		}
+
+		if value == "on" {
+			results = append(results, result{name: name, status: statusOK, message: "enabled"})
+			continue
+		}
+
+		r := result{name: name, status: statusWarn, message: "disabled", hint: fmt.Sprintf("ALTER SYSTEM SET %s = on; SELECT pg_reload_conf();", name)}
+		if name != "track_io_timing" {
+			r.status = statusFail
+		}
+		results = append(results, r)
+	}
+
+	if value, ok := settings["track_functions"]; ok {
+		if value == "none" {
+			results = append(results, result{
+				name: "track_functions", status: statusWarn, message: "disabled, functions stats are not available",
+				hint: "ALTER SYSTEM SET track_functions = 'pl'; SELECT pg_reload_conf();",
+			})
+		} else {
+			results = append(results, result{name: "track_functions", status: statusOK, message: value})
+		}
+	}
+
+	return results
+}
+
+// checkPgss checks pg_stat_statements is loaded and installed.
+func checkPgss(installed bool, preload string) result {
+	var loaded bool
+	for _, lib := range strings.Split(preload, ",") {
+		if strings.TrimSpace(lib) == "pg_stat_statements" {
+			loaded = true
+		}
+	}
+
+	switch {
+	case !loaded:
+		return result{
+			name: "pg_stat_statements", status: statusWarn, message: "library is not loaded, statements stats are not available",
+			hint: "add pg_stat_statements to shared_preload_libraries, restart Postgres and run: CREATE EXTENSION pg_stat_statements;",
+		}
+	case !installed:
+		return result{
+			name: "pg_stat_statements", status: statusWarn, message: "extension is not installed in the database",
+			hint: "CREATE EXTENSION pg_stat_statements;",
+		}
+	default:
+		return result{name: "pg_stat_statements", status: statusOK, message: "available"}
+	}
+}
+
+// checkSchema checks pgcenter stats schema is installed and up to date.
+func checkSchema(available bool, name string, version int) result {
+	switch {
+	case !available:
+		return result{
+			name: "stats schema", status: statusWarn, message: "not installed, system stats of remote host are not available",
+			hint: "install schema using 'pgcenter config --install'",
+		}
+	case version < query.StatSchemaVersion:
+		return result{
+			name: "stats schema", status: statusWarn, message: fmt.Sprintf("version %d is older than expected %d", version, query.StatSchemaVersion),
+			hint: fmt.Sprintf("upgrade schema using 'pgcenter config --upgrade --schema %s'", name),
+		}
+	default:
+		return result{name: "stats schema", status: statusOK, message: fmt.Sprintf("installed into schema %s, version %d", name, version)}
+	}
+}
+
+// checkProcfs checks files with system stats are readable.
+func checkProcfs(files []string) result {
+	var failed []string
+	for _, name := range files {
+		f, err := os.Open(name) // #nosec G304
+		if err != nil {
+			failed = append(failed, name)
+			continue
+		}
+		_ = f.Close()
+	}
+
+	if len(failed) > 0 {
+		return result{
+			name: "procfs", status: statusFail, message: fmt.Sprintf("can't read %s", strings.Join(failed, ", ")),
+			hint: "system stats are read from local /proc filesystem, run pgcenter on Linux host where Postgres is running",
+		}
+	}
+
+	return result{name: "procfs", status: statusOK, message: "system stats files are readable"}
+}
+
+// printResults prints results of checks.
+func printResults(w io.Writer, results []result) error {
+	var warnings, failures int
+
+	for _, r := range results {
+		var status string
+		switch r.status {
+		case statusOK:
+			status = "[ OK ]"
+		case statusWarn:
+			status = "[WARN]"
+			warnings++
+		default:
+			status = "[FAIL]"
+			failures++
+		}
+
+		_, err := fmt.Fprintf(w, "%s %s: %s\n", status, r.name, r.message)
+		if err != nil {
+			return err
+		}
+
+		if r.hint != "" && r.status != statusOK {
+			_, err := fmt.Fprintf(w, "       hint: %s\n", r.hint)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d checks, %d warnings, %d failures\n", len(results), warnings, failures)
+	return err
+}
+
+// printCapabilities prints availability of stats views. Nothing is printed if availability is unknown.
+func printCapabilities(w io.Writer, caps []stat.Capability) error {
+	if len(caps) == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "\nviews availability:\n%s", stat.FormatCapabilities(caps))
+	return err
+}
+
+// formatConn returns description of established connection.
+func formatConn(db *postgres.DB) string {
+	c := db.Config.Config
+	return fmt.Sprintf("%s:%d user=%s database=%s", c.Host, c.Port, c.User, c.Database)
+}
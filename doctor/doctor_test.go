@@ -0,0 +1,99 @@
+package doctor
+
+import (
+	"bytes"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_doChecks(t *testing.T) {
+	config, err := postgres.NewTestConfig()
+	assert.NoError(t, err)
+
+	got, caps := doChecks(config)
+	assert.Greater(t, len(got), 1)
+	assert.Equal(t, statusOK, got[0].status)
+	assert.NotEmpty(t, caps)
+
+	// Testing unavailable Postgres.
+	config.Config.Port = 1
+	got, caps = doChecks(config)
+	assert.Len(t, got, 1)
+	assert.Nil(t, caps)
+	assert.Equal(t, statusFail, got[0].status)
+}
+
+func Test_checkVersion(t *testing.T) {
+	assert.Equal(t, statusOK, checkVersion(130002, "13.2").status)
+	assert.Equal(t, statusOK, checkVersion(90500, "9.5.0").status)
+	assert.Equal(t, statusFail, checkVersion(90400, "9.4.0").status)
+}
+
+func Test_checkPrivileges(t *testing.T) {
+	assert.Equal(t, statusOK, checkPrivileges(stat.Privileges{Superuser: true}).status)
+	assert.Equal(t, statusOK, checkPrivileges(stat.Privileges{PgMonitor: true}).status)
+	assert.Equal(t, statusOK, checkPrivileges(stat.Privileges{PgReadAllStats: true}).status)
+	assert.Equal(t, statusWarn, checkPrivileges(stat.Privileges{}).status)
+}
+
+func Test_checkTrackSettings(t *testing.T) {
+	got := checkTrackSettings(map[string]string{
+		"track_activities": "on", "track_counts": "off", "track_io_timing": "off", "track_functions": "none",
+	})
+	assert.Equal(t, []int{statusOK, statusFail, statusWarn, statusWarn}, []int{got[0].status, got[1].status, got[2].status, got[3].status})
+
+	got = checkTrackSettings(map[string]string{"track_functions": "all"})
+	assert.Len(t, got, 1)
+	assert.Equal(t, statusOK, got[0].status)
+}
+
+func Test_checkPgss(t *testing.T) {
+	assert.Equal(t, statusOK, checkPgss(true, "auto_explain, pg_stat_statements").status)
+	assert.Equal(t, statusWarn, checkPgss(false, "pg_stat_statements").status)
+	assert.Equal(t, statusWarn, checkPgss(false, "").status)
+}
+
+func Test_checkSchema(t *testing.T) {
+	assert.Equal(t, statusOK, checkSchema(true, "pgcenter", query.StatSchemaVersion).status)
+	assert.Equal(t, statusWarn, checkSchema(true, "pgcenter", 1).status)
+	assert.Equal(t, statusWarn, checkSchema(false, "", 0).status)
+	assert.Contains(t, checkSchema(true, "monitoring", 1).hint, "--schema monitoring")
+}
+
+func Test_checkProcfs(t *testing.T) {
+	assert.Equal(t, statusOK, checkProcfs([]string{"testdata/proc/stat"}).status)
+	assert.Equal(t, statusFail, checkProcfs([]string{"testdata/proc/stat", "testdata/proc/invalid"}).status)
+}
+
+func Test_printResults(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := printResults(buf, []result{
+		{name: "connection", status: statusOK, message: "connected", hint: "not printed"},
+		{name: "privileges", status: statusWarn, message: "not enough", hint: "grant pg_monitor"},
+		{name: "procfs", status: statusFail, message: "can't read"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"[ OK ] connection: connected\n[WARN] privileges: not enough\n       hint: grant pg_monitor\n[FAIL] procfs: can't read\n\n3 checks, 1 warnings, 1 failures\n",
+		buf.String(),
+	)
+}
+
+func Test_printCapabilities(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, printCapabilities(buf, nil))
+	assert.Equal(t, "", buf.String())
+
+	err := printCapabilities(buf, []stat.Capability{
+		{View: "activity", Level: stat.AvailableFull},
+		{View: "statements_io", Level: stat.AvailableNone, Reasons: []string{"pg_stat_statements not installed"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"\nviews availability:\nactivity             full\nstatements_io        none     pg_stat_statements not installed\n",
+		buf.String(),
+	)
+}
@@ -0,0 +1,502 @@
+package exporter
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/log"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// viewAliases defines short names of views accepted by API.
+var viewAliases = map[string]string{
+	"statements": "statements_timings",
+}
+
+// systemParts defines parts of system stats which could be requested separately.
+var systemParts = []string{"loadavg", "cpu", "memory", "disks", "network"}
+
+// viewResponse defines stats of the view returned by API.
+type viewResponse struct {
+	Time    time.Time   `json:"time"`
+	View    string      `json:"view"`
+	Rates   bool        `json:"rates"` // values within diff interval are per-second rates
+	Columns []string    `json:"columns"`
+	Rows    [][]*string `json:"rows"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// activityResponse defines Postgres activity stats returned by API.
+type activityResponse struct {
+	Time                 time.Time `json:"time"`
+	Uptime               string    `json:"uptime"`
+	Recovery             bool      `json:"recovery"`
+	ConnectionsTotal     int       `json:"connections_total"`
+	ConnectionsIdle      int       `json:"connections_idle"`
+	ConnectionsIdleXact  int       `json:"connections_idle_xact"`
+	ConnectionsActive    int       `json:"connections_active"`
+	ConnectionsWaiting   int       `json:"connections_waiting"`
+	ConnectionsOther     int       `json:"connections_other"`
+	PreparedTransactions int       `json:"prepared_transactions"`
+	Autovacuums          int       `json:"autovacuums"`
+	AntiwraparoundVacuum int       `json:"antiwraparound_vacuums"`
+	UserVacuums          int       `json:"user_vacuums"`
+	XactMaxTime          string    `json:"xact_max_time"`
+	PreparedMaxTime      string    `json:"prepared_max_time"`
+	VacuumMaxTime        string    `json:"vacuum_max_time"`
+	StatementsPerSecond  *int      `json:"statements_per_second,omitempty"`
+	StatementsAvgTime    *float32  `json:"statements_avg_time_ms,omitempty"`
+}
+
+// systemResponse defines system stats returned by API. Only requested parts of stats are filled.
+type systemResponse struct {
+	Time    time.Time      `json:"time"`
+	LoadAvg *loadavgStats  `json:"loadavg,omitempty"`
+	CPU     *cpuStats      `json:"cpu,omitempty"`
+	Memory  *memoryStats   `json:"memory,omitempty"`
+	Disks   *[]diskStats   `json:"disks,omitempty"`
+	Network *[]netdevStats `json:"network,omitempty"`
+}
+
+// loadavgStats defines load average.
+type loadavgStats struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
+// cpuStats defines CPU usage by mode, in percents.
+type cpuStats struct {
+	User    float64 `json:"user"`
+	Nice    float64 `json:"nice"`
+	System  float64 `json:"system"`
+	Idle    float64 `json:"idle"`
+	Iowait  float64 `json:"iowait"`
+	Irq     float64 `json:"irq"`
+	Softirq float64 `json:"softirq"`
+	Steal   float64 `json:"steal"`
+}
+
+// memoryStats defines memory and swap usage, in megabytes.
+type memoryStats struct {
+	MemTotal     uint64 `json:"mem_total"`
+	MemFree      uint64 `json:"mem_free"`
+	MemUsed      uint64 `json:"mem_used"`
+	MemCached    uint64 `json:"mem_cached"`
+	MemBuffers   uint64 `json:"mem_buffers"`
+	MemDirty     uint64 `json:"mem_dirty"`
+	MemWriteback uint64 `json:"mem_writeback"`
+	MemSlab      uint64 `json:"mem_slab"`
+	SwapTotal    uint64 `json:"swap_total"`
+	SwapFree     uint64 `json:"swap_free"`
+	SwapUsed     uint64 `json:"swap_used"`
+}
+
+// diskStats defines usage of block device.
+type diskStats struct {
+	Device                    string  `json:"device"`
+	ReadsPerSecond            float64 `json:"reads_per_second"`
+	WritesPerSecond           float64 `json:"writes_per_second"`
+	ReadMegabytesPerSecond    float64 `json:"read_megabytes_per_second"`
+	WrittenMegabytesPerSecond float64 `json:"written_megabytes_per_second"`
+	AwaitMilliseconds         float64 `json:"await_ms"`
+	QueueSize                 float64 `json:"queue_size"`
+	Utilization               float64 `json:"utilization_percent"`
+}
+
+// netdevStats defines usage of network interface.
+type netdevStats struct {
+	Interface                   string  `json:"interface"`
+	ReceivedBytesPerSecond      float64 `json:"received_bytes_per_second"`
+	TransmittedBytesPerSecond   float64 `json:"transmitted_bytes_per_second"`
+	ReceivedPacketsPerSecond    float64 `json:"received_packets_per_second"`
+	TransmittedPacketsPerSecond float64 `json:"transmitted_packets_per_second"`
+	ErrorsPerSecond             float64 `json:"errors_per_second"`
+	Utilization                 float64 `json:"utilization_percent"`
+}
+
+// viewQuery defines parameters of view request.
+type viewQuery struct {
+	sort    string                    // name of column used for sorting
+	order   string                    // sort order: asc or desc
+	limit   int                       // max number of returned rows, zero means no limit
+	filters map[string]*regexp.Regexp // rows are returned only if values of all specified columns match the patterns
+}
+
+// apiError defines error returned by API.
+type apiError struct {
+	status int
+	msg    string
+}
+
+// registerAPI registers handlers of API requests. All requests require token.
+func (app *app) registerAPI(mux *http.ServeMux) {
+	for _, path := range []string{"/views", "/views/"} {
+		mux.HandleFunc(path, requireToken(app.config.APIToken, app.serveViews))
+	}
+	for _, path := range []string{"/system", "/system/"} {
+		mux.HandleFunc(path, requireToken(app.config.APIToken, app.serveSystem))
+	}
+	mux.HandleFunc("/activity", requireToken(app.config.APIToken, app.serveActivity))
+}
+
+// requireToken wraps handler with checking of bearer token and request method.
+func requireToken(token string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pgcenter"`)
+			writeError(w, apiError{http.StatusUnauthorized, "missing or invalid API token"})
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, apiError{http.StatusMethodNotAllowed, "method not allowed"})
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+// serveViews serves list of views (/views), stats of the view (/views/<name>) with sorting, filtering and limiting rows.
+func (app *app) serveViews(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/views"), "/")
+	if name == "" {
+		names := make([]string, 0, len(app.views))
+		for n := range app.views {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		writeJSON(w, map[string][]string{"views": names})
+		return
+	}
+
+	if alias, ok := viewAliases[name]; ok {
+		name = alias
+	}
+
+	v, ok := app.views[name]
+	if !ok {
+		writeError(w, apiError{http.StatusNotFound, fmt.Sprintf("unknown view '%s'", name)})
+		return
+	}
+
+	q, err := parseViewQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, apiError{http.StatusBadRequest, err.Error()})
+		return
+	}
+
+	app.serveSnapshots(w, r, func(s snapshot) (interface{}, *apiError) {
+		stats, ok := s.views[name]
+		if !ok {
+			return nil, &apiError{http.StatusServiceUnavailable, fmt.Sprintf("stats of view '%s' are not collected", name)}
+		}
+
+		return newViewResponse(s.time, name, stats, q, v.OrderKey, v.OrderDesc)
+	})
+}
+
+// serveSystem serves system stats (/system) or particular part of system stats (/system/<part>).
+func (app *app) serveSystem(w http.ResponseWriter, r *http.Request) {
+	part := strings.Trim(strings.TrimPrefix(r.URL.Path, "/system"), "/")
+	if part != "" && !stringInSlice(part, systemParts) {
+		writeError(w, apiError{http.StatusNotFound, fmt.Sprintf("unknown system stats '%s', available: %s", part, strings.Join(systemParts, ", "))})
+		return
+	}
+
+	app.serveSnapshots(w, r, func(s snapshot) (interface{}, *apiError) {
+		if s.system == nil {
+			return nil, &apiError{http.StatusNotFound, "system stats are not available, Postgres is remote and pgcenter stats schema is not installed"}
+		}
+		return newSystemResponse(s.time, *s.system, part), nil
+	})
+}
+
+// serveActivity serves Postgres activity stats.
+func (app *app) serveActivity(w http.ResponseWriter, r *http.Request) {
+	pgss := app.props.ExtPGSSAvail
+	app.serveSnapshots(w, r, func(s snapshot) (interface{}, *apiError) {
+		return newActivityResponse(s.time, s.activity, pgss), nil
+	})
+}
+
+// serveSnapshots responds with data made of the latest snapshot. If 'history' parameter is specified, responds with
+// array of data made of the specified number of recent snapshots, ordered from the oldest to the latest.
+func (app *app) serveSnapshots(w http.ResponseWriter, r *http.Request, fn func(s snapshot) (interface{}, *apiError)) {
+	history := 0
+	if value := r.URL.Query().Get("history"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeError(w, apiError{http.StatusBadRequest, fmt.Sprintf("invalid history '%s', must be a positive number", value)})
+			return
+		}
+		history = n
+	}
+
+	app.mu.RLock()
+	snapshots := app.history
+	app.mu.RUnlock()
+
+	if len(snapshots) == 0 {
+		writeError(w, apiError{http.StatusServiceUnavailable, "stats have not been collected yet"})
+		return
+	}
+
+	if history == 0 {
+		data, apiErr := fn(snapshots[len(snapshots)-1])
+		if apiErr != nil {
+			writeError(w, *apiErr)
+			return
+		}
+		writeJSON(w, data)
+		return
+	}
+
+	if history < len(snapshots) {
+		snapshots = snapshots[len(snapshots)-history:]
+	}
+
+	items := make([]interface{}, 0, len(snapshots))
+	for _, s := range snapshots {
+		data, apiErr := fn(s)
+		if apiErr != nil {
+			writeError(w, *apiErr)
+			return
+		}
+		items = append(items, data)
+	}
+
+	writeJSON(w, items)
+}
+
+// parseViewQuery parses and validates parameters of view request.
+func parseViewQuery(values url.Values) (viewQuery, error) {
+	q := viewQuery{sort: values.Get("sort"), order: values.Get("order"), filters: map[string]*regexp.Regexp{}}
+
+	if q.order != "" && q.order != "asc" && q.order != "desc" {
+		return viewQuery{}, fmt.Errorf("invalid order '%s', must be 'asc' or 'desc'", q.order)
+	}
+
+	if value := values.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return viewQuery{}, fmt.Errorf("invalid limit '%s', must be a positive number", value)
+		}
+		q.limit = n
+	}
+
+	for _, f := range values["filter"] {
+		parts := strings.SplitN(f, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return viewQuery{}, fmt.Errorf("invalid filter '%s', must be in format 'column:regexp'", f)
+		}
+
+		re, err := regexp.Compile(parts[1])
+		if err != nil {
+			return viewQuery{}, fmt.Errorf("invalid filter '%s': %s", f, err)
+		}
+		q.filters[parts[0]] = re
+	}
+
+	return q, nil
+}
+
+// newViewResponse returns filtered, sorted and limited stats of the view. View's default order is used if sorting is not
+// requested. Snapshot is shared between requests, hence its rows are not modified.
+func newViewResponse(t time.Time, name string, stats viewStats, q viewQuery, orderKey int, orderDesc bool) (viewResponse, *apiError) {
+	resp := viewResponse{Time: t, View: name, Rates: stats.rates, Columns: stats.result.Cols, Rows: [][]*string{}}
+	if stats.err != nil {
+		resp.Error = stats.err.Error()
+		return resp, nil
+	}
+
+	colIdx := map[string]int{}
+	for i, col := range stats.result.Cols {
+		colIdx[col] = i
+	}
+
+	key, desc := orderKey, orderDesc
+	if q.sort != "" {
+		i, ok := colIdx[q.sort]
+		if !ok {
+			return viewResponse{}, &apiError{http.StatusBadRequest, fmt.Sprintf("unknown sort column '%s'", q.sort)}
+		}
+		key = i
+	}
+	if q.order != "" {
+		desc = q.order == "desc"
+	}
+
+	filters := map[int]*regexp.Regexp{}
+	for col, re := range q.filters {
+		i, ok := colIdx[col]
+		if !ok {
+			return viewResponse{}, &apiError{http.StatusBadRequest, fmt.Sprintf("unknown filter column '%s'", col)}
+		}
+		filters[i] = re
+	}
+
+	rows := make([][]sql.NullString, 0, len(stats.result.Values))
+	for _, row := range stats.result.Values {
+		if matchFilters(row, filters) {
+			rows = append(rows, row)
+		}
+	}
+
+	res := stat.PGresult{Values: rows, Cols: stats.result.Cols, Ncols: stats.result.Ncols, Nrows: len(rows), Valid: true}
+	if key < res.Ncols {
+		res.Sort(key, desc)
+	}
+
+	if q.limit > 0 && len(res.Values) > q.limit {
+		res.Values = res.Values[:q.limit]
+	}
+
+	for _, row := range res.Values {
+		values := make([]*string, len(row))
+		for i := range row {
+			if row[i].Valid {
+				value := row[i].String
+				values[i] = &value
+			}
+		}
+		resp.Rows = append(resp.Rows, values)
+	}
+
+	return resp, nil
+}
+
+// matchFilters returns true if values of the row match all filters.
+func matchFilters(row []sql.NullString, filters map[int]*regexp.Regexp) bool {
+	for i, re := range filters {
+		if !re.MatchString(row[i].String) {
+			return false
+		}
+	}
+	return true
+}
+
+// newActivityResponse returns Postgres activity stats, statements stats are returned only if pg_stat_statements is available.
+func newActivityResponse(t time.Time, a stat.Activity, pgss bool) activityResponse {
+	resp := activityResponse{
+		Time:                 t,
+		Uptime:               a.Uptime,
+		Recovery:             strings.HasPrefix(a.Recovery, "t"),
+		ConnectionsTotal:     a.ConnTotal,
+		ConnectionsIdle:      a.ConnIdle,
+		ConnectionsIdleXact:  a.ConnIdleXact,
+		ConnectionsActive:    a.ConnActive,
+		ConnectionsWaiting:   a.ConnWaiting,
+		ConnectionsOther:     a.ConnOthers,
+		PreparedTransactions: a.ConnPrepared,
+		Autovacuums:          a.AVWorkers,
+		AntiwraparoundVacuum: a.AVAntiwrap,
+		UserVacuums:          a.AVUser,
+		XactMaxTime:          a.XactMaxTime,
+		PreparedMaxTime:      a.PrepMaxTime,
+		VacuumMaxTime:        a.AVMaxTime,
+	}
+
+	if pgss {
+		resp.StatementsPerSecond = &a.CallsRate
+		resp.StatementsAvgTime = &a.StmtAvgTime
+	}
+
+	return resp
+}
+
+// newSystemResponse returns requested part of system stats, or all stats if part is not specified.
+func newSystemResponse(t time.Time, s stat.System, part string) systemResponse {
+	resp := systemResponse{Time: t}
+
+	if part == "" || part == "loadavg" {
+		resp.LoadAvg = &loadavgStats{Load1: s.LoadAvg.One, Load5: s.LoadAvg.Five, Load15: s.LoadAvg.Fifteen}
+	}
+
+	if part == "" || part == "cpu" {
+		resp.CPU = &cpuStats{
+			User: s.CpuStat.User, Nice: s.CpuStat.Nice, System: s.CpuStat.Sys, Idle: s.CpuStat.Idle,
+			Iowait: s.CpuStat.Iowait, Irq: s.CpuStat.Irq, Softirq: s.CpuStat.Softirq, Steal: s.CpuStat.Steal,
+		}
+	}
+
+	if part == "" || part == "memory" {
+		resp.Memory = &memoryStats{
+			MemTotal: s.Meminfo.MemTotal, MemFree: s.Meminfo.MemFree, MemUsed: s.Meminfo.MemUsed,
+			MemCached: s.Meminfo.MemCached, MemBuffers: s.Meminfo.MemBuffers, MemDirty: s.Meminfo.MemDirty,
+			MemWriteback: s.Meminfo.MemWriteback, MemSlab: s.Meminfo.MemSlab,
+			SwapTotal: s.Meminfo.SwapTotal, SwapFree: s.Meminfo.SwapFree, SwapUsed: s.Meminfo.SwapUsed,
+		}
+	}
+
+	if part == "" || part == "disks" {
+		disks := []diskStats{}
+		for _, d := range s.Diskstats {
+			// Inactive devices are skipped by stats collector.
+			if d.Device == "" {
+				continue
+			}
+			disks = append(disks, diskStats{
+				Device: d.Device, ReadsPerSecond: d.Rcompleted, WritesPerSecond: d.Wcompleted,
+				ReadMegabytesPerSecond: d.Rsectors, WrittenMegabytesPerSecond: d.Wsectors,
+				AwaitMilliseconds: d.Await, QueueSize: d.Tweighted, Utilization: d.Util,
+			})
+		}
+		resp.Disks = &disks
+	}
+
+	if part == "" || part == "network" {
+		netdevs := []netdevStats{}
+		for _, n := range s.Netdevs {
+			// Inactive interfaces are skipped by stats collector.
+			if n.Ifname == "" {
+				continue
+			}
+			netdevs = append(netdevs, netdevStats{
+				Interface: n.Ifname, ReceivedBytesPerSecond: n.Rbytes, TransmittedBytesPerSecond: n.Tbytes,
+				ReceivedPacketsPerSecond: n.Rpackets, TransmittedPacketsPerSecond: n.Tpackets,
+				ErrorsPerSecond: n.Rerrs + n.Terrs, Utilization: n.Utilization,
+			})
+		}
+		resp.Network = &netdevs
+	}
+
+	return resp
+}
+
+// writeJSON writes data as JSON response.
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Error("write response failed", "error", err)
+	}
+}
+
+// writeError writes error as JSON response with corresponding status code.
+func writeError(w http.ResponseWriter, e apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": e.msg})
+}
+
+// stringInSlice returns true if slice contains the string.
+func stringInSlice(s string, list []string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,281 @@
+package exporter
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// newTestAPIApp creates app with API enabled and with specified number of snapshots in history.
+func newTestAPIApp(n int) *app {
+	app := &app{
+		config: Config{API: true, APIToken: "secret", APIHistory: 10},
+		views: view.Views{
+			"databases":          {Name: "databases", OrderKey: 0, OrderDesc: false},
+			"statements_timings": {Name: "statements_timings", OrderKey: 1, OrderDesc: true},
+		},
+		props: stat.PostgresProperties{ExtPGSSAvail: true},
+	}
+
+	for i := 0; i < n; i++ {
+		app.history = append(app.history, snapshot{
+			time:     time.Date(2021, 1, 1, 0, 0, i, 0, time.UTC),
+			system:   &stat.System{LoadAvg: stat.LoadAvg{One: float64(i)}},
+			activity: stat.Activity{ConnTotal: 10 + i, Recovery: "f", CallsRate: 100},
+			views: map[string]viewStats{
+				"databases": {
+					result: stat.PGresult{
+						Valid: true, Ncols: 2, Nrows: 3, Cols: []string{"datname", "commits"},
+						Values: [][]sql.NullString{
+							{{String: "postgres", Valid: true}, {String: "5", Valid: true}},
+							{{String: "pgbench", Valid: true}, {String: "20", Valid: true}},
+							{{String: "template1", Valid: true}, {Valid: false}},
+						},
+					},
+					rates: i > 0,
+				},
+				"statements_timings": {err: fmt.Errorf("collect statements_timings stats failed: test")},
+			},
+		})
+	}
+
+	return app
+}
+
+// doTestRequest performs request to API of the app and returns response.
+func doTestRequest(app *app, target string, token string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	app.registerAPI(mux)
+
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func Test_requireToken(t *testing.T) {
+	h := requireToken("secret", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	testcases := []struct {
+		method string
+		auth   string
+		want   int
+	}{
+		{method: http.MethodGet, auth: "Bearer secret", want: http.StatusOK},
+		{method: http.MethodHead, auth: "Bearer secret", want: http.StatusOK},
+		{method: http.MethodGet, auth: "", want: http.StatusUnauthorized},
+		{method: http.MethodGet, auth: "Bearer invalid", want: http.StatusUnauthorized},
+		{method: http.MethodGet, auth: "secret", want: http.StatusUnauthorized},
+		{method: http.MethodGet, auth: "Basic secret", want: http.StatusUnauthorized},
+		{method: http.MethodPost, auth: "Bearer secret", want: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testcases {
+		r := httptest.NewRequest(tc.method, "/views", nil)
+		if tc.auth != "" {
+			r.Header.Set("Authorization", tc.auth)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		assert.Equal(t, tc.want, w.Code)
+	}
+}
+
+func Test_parseViewQuery(t *testing.T) {
+	testcases := []struct {
+		query string
+		valid bool
+		want  viewQuery
+	}{
+		{query: "", valid: true, want: viewQuery{}},
+		{query: "sort=commits&order=asc&limit=10", valid: true, want: viewQuery{sort: "commits", order: "asc", limit: 10}},
+		{query: "order=invalid", valid: false},
+		{query: "limit=0", valid: false},
+		{query: "limit=invalid", valid: false},
+		{query: "filter=datname", valid: false},
+		{query: "filter=:^pg", valid: false},
+		{query: "filter=datname:[", valid: false},
+	}
+
+	for _, tc := range testcases {
+		values, err := url.ParseQuery(tc.query)
+		assert.NoError(t, err)
+
+		got, err := parseViewQuery(values)
+		if tc.valid {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want.sort, got.sort)
+			assert.Equal(t, tc.want.order, got.order)
+			assert.Equal(t, tc.want.limit, got.limit)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+
+	values, err := url.ParseQuery("filter=datname:^pg&filter=commits:0$")
+	assert.NoError(t, err)
+	got, err := parseViewQuery(values)
+	assert.NoError(t, err)
+	assert.Len(t, got.filters, 2)
+	assert.Equal(t, "^pg", got.filters["datname"].String())
+}
+
+func Test_app_serveViews(t *testing.T) {
+	app := newTestAPIApp(2)
+
+	// Views list.
+	w := doTestRequest(app, "/views", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"views":["databases","statements_timings"]}`, w.Body.String())
+
+	testcases := []struct {
+		target string
+		want   [][]*string
+	}{
+		{target: "/views/databases", want: [][]*string{{sp("pgbench"), sp("20")}, {sp("postgres"), sp("5")}, {sp("template1"), nil}}},
+		{target: "/views/databases?sort=commits&order=desc", want: [][]*string{{sp("pgbench"), sp("20")}, {sp("postgres"), sp("5")}, {sp("template1"), nil}}},
+		{target: "/views/databases?sort=datname&order=desc&limit=2", want: [][]*string{{sp("template1"), nil}, {sp("postgres"), sp("5")}}},
+		{target: "/views/databases?filter=datname:^p&filter=commits:^2", want: [][]*string{{sp("pgbench"), sp("20")}}},
+		{target: "/views/databases?filter=datname:unknown", want: [][]*string{}},
+	}
+
+	for _, tc := range testcases {
+		w := doTestRequest(app, tc.target, "secret")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var got viewResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, "databases", got.View)
+		assert.True(t, got.Rates)
+		assert.Equal(t, []string{"datname", "commits"}, got.Columns)
+		assert.Equal(t, tc.want, got.Rows)
+	}
+
+	// Rows of the stored snapshot are not modified by sorting.
+	assert.Equal(t, "postgres", app.history[1].views["databases"].result.Values[0][0].String)
+
+	// History of snapshots.
+	w = doTestRequest(app, "/views/databases?history=5", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var history []viewResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Len(t, history, 2)
+	assert.False(t, history[0].Rates)
+	assert.True(t, history[1].Rates)
+	assert.True(t, history[0].Time.Before(history[1].Time))
+
+	// Alias of statements view, error of collecting view stats is returned in response.
+	w = doTestRequest(app, "/views/statements", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got viewResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "statements_timings", got.View)
+	assert.Contains(t, got.Error, "test")
+
+	// Invalid requests.
+	for target, want := range map[string]int{
+		"/views/unknown":                  http.StatusNotFound,
+		"/views/databases?sort=unknown":   http.StatusBadRequest,
+		"/views/databases?filter=x:y":     http.StatusBadRequest,
+		"/views/databases?limit=-1":       http.StatusBadRequest,
+		"/views/databases?history=0":      http.StatusBadRequest,
+		"/views/databases?history=string": http.StatusBadRequest,
+	} {
+		w = doTestRequest(app, target, "secret")
+		assert.Equal(t, want, w.Code, target)
+		assert.Contains(t, w.Body.String(), `"error"`)
+	}
+
+	// Token is required.
+	w = doTestRequest(app, "/views/databases", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Stats have not been collected yet.
+	w = doTestRequest(newTestAPIApp(0), "/views/databases", "secret")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func Test_app_serveSystem(t *testing.T) {
+	app := newTestAPIApp(3)
+
+	w := doTestRequest(app, "/system", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	for _, part := range append([]string{"time"}, systemParts...) {
+		assert.Contains(t, got, part)
+	}
+
+	w = doTestRequest(app, "/system/loadavg", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"time":"2021-01-01T00:00:02Z","loadavg":{"load1":2,"load5":0,"load15":0}}`, w.Body.String())
+
+	w = doTestRequest(app, "/system/disks?history=2", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"time":"2021-01-01T00:00:01Z","disks":[]},{"time":"2021-01-01T00:00:02Z","disks":[]}]`, w.Body.String())
+
+	w = doTestRequest(app, "/system/unknown", "secret")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// System stats are not available.
+	app.history[2].system = nil
+	w = doTestRequest(app, "/system/cpu", "secret")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_app_serveActivity(t *testing.T) {
+	app := newTestAPIApp(1)
+
+	w := doTestRequest(app, "/activity", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got activityResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, 10, got.ConnectionsTotal)
+	assert.False(t, got.Recovery)
+	assert.Equal(t, 100, *got.StatementsPerSecond)
+
+	// Statements stats are not returned without pg_stat_statements.
+	app.props.ExtPGSSAvail = false
+	w = doTestRequest(app, "/activity", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "statements_per_second")
+}
+
+func Test_newSystemResponse(t *testing.T) {
+	s := stat.System{
+		Diskstats: stat.Diskstats{{Device: "sda", Rcompleted: 10, Util: 50}, {}},
+		Netdevs:   stat.Netdevs{{Ifname: "eth0", Rbytes: 1000, Rerrs: 1, Terrs: 2}, {}},
+	}
+
+	got := newSystemResponse(time.Time{}, s, "")
+	assert.NotNil(t, got.LoadAvg)
+	assert.NotNil(t, got.CPU)
+	assert.NotNil(t, got.Memory)
+	assert.Equal(t, []diskStats{{Device: "sda", ReadsPerSecond: 10, Utilization: 50}}, *got.Disks)
+	assert.Equal(t, []netdevStats{{Interface: "eth0", ReceivedBytesPerSecond: 1000, ErrorsPerSecond: 3}}, *got.Network)
+
+	got = newSystemResponse(time.Time{}, s, "cpu")
+	assert.NotNil(t, got.CPU)
+	assert.Nil(t, got.LoadAvg)
+	assert.Nil(t, got.Memory)
+	assert.Nil(t, got.Disks)
+	assert.Nil(t, got.Network)
+}
+
+// sp returns pointer to the string.
+func sp(s string) *string {
+	return &s
+}
@@ -0,0 +1,343 @@
+// 'pgcenter exporter' - serves Postgres and system stats as Prometheus metrics and over HTTP JSON API.
+
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/log"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Config defines config container for configuring 'pgcenter exporter'.
+type Config struct {
+	Listen     string            // address to listen on for HTTP requests
+	Interval   time.Duration     // stats collecting interval, rates are calculated over this interval
+	API        bool              // serve stats over HTTP JSON API
+	APIToken   string            // token required for API requests
+	APIHistory int               // number of recent snapshots kept for API requests
+	Alerts     alert.Config      // alert rules evaluated along with stats collecting
+	Hooks      []hook.Config     // user commands run when alerts fire and resolve
+	Devices    stat.FilterConfig // patterns of block devices and network interfaces which stats are skipped
+}
+
+// RunMain is the 'pgcenter exporter' main entry point.
+func RunMain(dbConfig postgres.Config, config Config) error {
+	if config.Interval < time.Second {
+		return fmt.Errorf("collecting interval must be at least 1s")
+	}
+
+	if config.API {
+		if config.APIToken == "" {
+			return fmt.Errorf("API token is not specified, use --api-token or PGCENTER_API_TOKEN environment variable")
+		}
+		if config.APIHistory < 1 {
+			return fmt.Errorf("number of API snapshots must be at least 1")
+		}
+	}
+
+	filter, err := stat.NewFilter(config.Devices)
+	if err != nil {
+		return err
+	}
+
+	db, err := postgres.Connect(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	app, err := newApp(db, config)
+	if err != nil {
+		return err
+	}
+	app.sampler.SetFilter(filter)
+
+	// Alert rules are evaluated using stats collected for metrics, stats used by rules are collected along with them.
+	if config.Alerts.Enabled() {
+		monitor, err := alert.NewMonitor(config.Alerts, func(format string, a ...interface{}) {
+			log.Error(fmt.Sprintf(format, a...))
+		})
+		if err != nil {
+			return err
+		}
+
+		views := view.New()
+		err = views.Configure(app.props.QueryOptions(0))
+		if err != nil {
+			return err
+		}
+
+		err = monitor.Configure(views)
+		if err != nil {
+			return err
+		}
+
+		hooks, err := hook.NewRunner(config.Hooks, func(format string, a ...interface{}) {
+			log.Error(fmt.Sprintf(format, a...))
+		})
+		if err != nil {
+			return err
+		}
+		monitor.SetHooks(hooks)
+		monitor.SetInstance(db.Config)
+		monitor.Require(app.sampler)
+
+		app.alerts = monitor
+		defer monitor.Wait()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// In case of SIGINT or SIGTERM stop program gracefully.
+	doQuit := make(chan os.Signal, 1)
+	signal.Notify(doQuit, syscall.SIGINT, syscall.SIGTERM)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", app.serveMetrics)
+	mux.HandleFunc("/", app.serveIndex)
+	if config.API {
+		app.registerAPI(mux)
+		mux.HandleFunc("/ui", serveWebUI)
+	}
+	server := &http.Server{Addr: config.Listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	fmt.Printf("INFO: serving metrics on %s/metrics\n", config.Listen)
+	if config.API {
+		fmt.Printf("INFO: serving API on %s, web UI on %s/ui\n", config.Listen, config.Listen)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		app.sampler.Run(ctx, app.update)
+		close(done)
+	}()
+
+	select {
+	case err = <-errCh:
+	case sig := <-doQuit:
+		fmt.Printf("INFO: got %s, exiting\n", sig.String())
+		err = server.Shutdown(context.Background())
+	}
+
+	cancel()
+	<-done
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// app defines 'pgcenter exporter' runtime dependencies.
+type app struct {
+	config  Config
+	sampler *stat.Sampler
+	props   stat.PostgresProperties
+	views   view.Views
+	alerts  *alert.Monitor // evaluates alert rules using collected stats, nil if alerts are not configured
+
+	mu      sync.RWMutex
+	metrics []byte     // metrics rendered after the last collecting
+	history []snapshot // recent snapshots served by API, the latest is the last
+}
+
+// snapshot defines stats collected at once.
+type snapshot struct {
+	time     time.Time
+	system   *stat.System // nil when system stats are not available
+	activity stat.Activity
+	views    map[string]viewStats
+}
+
+// viewStats defines stats of the view collected at once.
+type viewStats struct {
+	result stat.PGresult // rates (when calculated) or current values
+	rates  bool          // rates have been calculated using the previous snapshot
+	err    error         // error occurred during collecting stats of the view
+}
+
+// newApp creates 'pgcenter exporter' app and configures stats views depending on Postgres version. Only exported views
+// are collected, unless API is enabled.
+func newApp(db *postgres.DB, config Config) (*app, error) {
+	sampler, err := stat.NewSampler(db, config.Interval)
+	if err != nil {
+		return nil, err
+	}
+
+	props := sampler.Properties()
+	if msg := stat.CheckStatSchemaVersion(props); msg != "" {
+		fmt.Println(msg)
+	}
+
+	views := view.New()
+	err = views.Configure(props.QueryOptions(0))
+	if err != nil {
+		return nil, err
+	}
+
+	// Leave required views only, statements views require pg_stat_statements.
+	for name := range views {
+		if _, ok := exportedViews[name]; (!ok && !config.API) || (!props.ExtPGSSAvail && strings.HasPrefix(name, "statements_")) {
+			delete(views, name)
+		}
+	}
+
+	sampler.CollectSystem()
+	sampler.CollectActivity()
+	for _, v := range views {
+		sampler.AddView(v, true)
+	}
+
+	return &app{
+		config:  config,
+		sampler: sampler,
+		props:   props,
+		views:   views,
+	}, nil
+}
+
+// update renders metrics using collected stats, metrics will be served until the next update. When API is enabled,
+// collected stats are also kept in history. Collected stats are passed to alerts monitor, if it's configured.
+func (app *app) update(sample stat.Sample, err error) {
+	var (
+		s       snapshot
+		metrics []metric
+	)
+
+	if err == nil {
+		app.props = app.sampler.Properties()
+		s, err = app.newSnapshot(sample)
+	}
+	if err != nil {
+		log.Error("collect stats failed", "error", err)
+	} else {
+		metrics = s.metrics(app.views, app.props.ExtPGSSAvail)
+	}
+
+	metrics = append(metrics,
+		newMetric("up", "Stats have been collected successfully (1) or not (0).", boolValue(err == nil)),
+		newMetric("collect_duration_seconds", "Duration of stats collecting.", sample.Duration.Seconds()),
+	)
+
+	if app.alerts != nil {
+		app.alerts.Evaluate(sample, err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeMetrics(&buf, metrics); err != nil {
+		log.Error("render metrics failed", "error", err)
+		return
+	}
+
+	app.mu.Lock()
+	app.metrics = buf.Bytes()
+	if err == nil && app.config.API {
+		app.history = append(app.history, s)
+		if len(app.history) > app.config.APIHistory {
+			app.history = app.history[len(app.history)-app.config.APIHistory:]
+		}
+	}
+	app.mu.Unlock()
+}
+
+// newSnapshot makes snapshot of stats of views served by exporter. Failure of collecting stats of the view doesn't fail
+// whole snapshot, the error is kept in snapshot and metrics of the view are skipped.
+func (app *app) newSnapshot(sample stat.Sample) (snapshot, error) {
+	if sample.SystemError != nil {
+		return snapshot{}, sample.SystemError
+	}
+	if sample.ActivityError != nil {
+		return snapshot{}, sample.ActivityError
+	}
+
+	s := snapshot{time: sample.Time, system: sample.System, activity: *sample.Activity, views: map[string]viewStats{}}
+
+	for name := range app.views {
+		vs := sample.Views[name]
+		if vs.Err != nil {
+			if _, ok := exportedViews[name]; ok {
+				log.Warn("skip metrics of view", "view", name, "error", vs.Err)
+			}
+		}
+		s.views[name] = viewStats{result: vs.Result, rates: vs.Rates, err: vs.Err}
+	}
+
+	return s, nil
+}
+
+// metrics returns metrics based on stats of the snapshot, only exported views are used.
+func (s snapshot) metrics(views view.Views, pgss bool) []metric {
+	var metrics []metric
+	if s.system != nil {
+		metrics = systemMetrics(*s.system)
+	}
+
+	metrics = append(metrics, activityMetrics(s.activity, pgss)...)
+
+	// Iterate views in stable order, hence metrics are always rendered in the same order.
+	names := make([]string, 0, len(views))
+	for name := range views {
+		if _, ok := exportedViews[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		stats, ok := s.views[name]
+		if !ok || stats.err != nil {
+			continue
+		}
+		metrics = append(metrics, viewMetrics(views[name], exportedViews[name], stats.result, stats.rates)...)
+	}
+
+	return metrics
+}
+
+// serveMetrics serves metrics rendered after the last collecting.
+func (app *app) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	app.mu.RLock()
+	metrics := app.metrics
+	app.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(metrics)
+}
+
+// serveIndex serves page with links to metrics and web UI.
+func (app *app) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	links := `<p><a href="/metrics">Metrics</a></p>`
+	if app.config.API {
+		links += `<p><a href="/ui">Web UI</a></p>`
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = fmt.Fprintf(w, `<html><head><title>pgCenter exporter</title></head><body><h1>pgCenter exporter</h1>%s</body></html>`, links)
+}
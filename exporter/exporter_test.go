@@ -0,0 +1,117 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_newApp(t *testing.T) {
+	db, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	app, err := newApp(db, Config{Interval: time.Second})
+	assert.NoError(t, err)
+
+	for name, v := range app.views {
+		assert.Contains(t, exportedViews, name)
+		assert.NotEqual(t, "", v.Query)
+	}
+	assert.NotContains(t, app.views, "activity")
+
+	// Rates are exported since the second collecting.
+	app.update(app.sampler.Collect(context.Background()))
+	assert.Contains(t, string(app.metrics), "pgcenter_up 1\n")
+	assert.NotContains(t, string(app.metrics), "pgcenter_databases_commits_per_second")
+
+	time.Sleep(time.Second)
+	app.update(app.sampler.Collect(context.Background()))
+	assert.Contains(t, string(app.metrics), "pgcenter_up 1\n")
+	assert.Contains(t, string(app.metrics), "pgcenter_databases_commits_per_second{datname=")
+
+	// All views are collected when API is enabled, only the specified number of snapshots is kept.
+	app, err = newApp(db, Config{Interval: time.Second, API: true, APIToken: "secret", APIHistory: 1})
+	assert.NoError(t, err)
+	assert.Contains(t, app.views, "activity")
+
+	app.update(app.sampler.Collect(context.Background()))
+	app.update(app.sampler.Collect(context.Background()))
+	assert.Len(t, app.history, 1)
+	assert.Contains(t, app.history[0].views, "activity")
+}
+
+func Test_app_newSnapshot(t *testing.T) {
+	app := &app{views: view.Views{"databases": {Name: "databases"}, "activity": {Name: "activity"}}}
+	sample := stat.Sample{
+		Time:     time.Now(),
+		Activity: &stat.Activity{ConnTotal: 10},
+		Views: map[string]stat.ViewSample{
+			"databases":     {Result: stat.PGresult{Valid: true}, Rates: true},
+			"activity":      {Err: fmt.Errorf("collect activity stats failed")},
+			"alert:xid_age": {Result: stat.PGresult{Valid: true}},
+		},
+	}
+
+	// Failure of view which is not exported is kept in snapshot, views which are not served are skipped.
+	s, err := app.newSnapshot(sample)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, s.activity.ConnTotal)
+	assert.Len(t, s.views, 2)
+	assert.True(t, s.views["databases"].rates)
+	assert.Error(t, s.views["activity"].err)
+
+	// Failure of exported view is kept in snapshot, its metrics are skipped.
+	sample.Views["databases"] = stat.ViewSample{Err: fmt.Errorf("collect databases stats failed")}
+	s, err = app.newSnapshot(sample)
+	assert.NoError(t, err)
+	assert.Len(t, s.views, 2)
+	assert.Error(t, s.views["databases"].err)
+	assert.Len(t, s.metrics(app.views, false), len(activityMetrics(s.activity, false)))
+
+	sample.ActivityError = fmt.Errorf("collect activity stats failed")
+	_, err = app.newSnapshot(sample)
+	assert.Error(t, err)
+}
+
+func Test_app_serveMetrics(t *testing.T) {
+	app := &app{metrics: []byte("pgcenter_up 1\n")}
+
+	w := httptest.NewRecorder()
+	app.serveMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	resp := w.Result()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4"))
+	assert.Equal(t, "pgcenter_up 1\n", string(body))
+}
+
+func Test_app_serveIndex(t *testing.T) {
+	app := &app{}
+
+	w := httptest.NewRecorder()
+	app.serveIndex(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `href="/metrics"`)
+	assert.NotContains(t, w.Body.String(), `href="/ui"`)
+
+	app.config.API = true
+	w = httptest.NewRecorder()
+	app.serveIndex(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Contains(t, w.Body.String(), `href="/ui"`)
+
+	w = httptest.NewRecorder()
+	app.serveIndex(w, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,265 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// metricPrefix defines prefix of all metrics names.
+const metricPrefix = "pgcenter_"
+
+// exportedViews defines stats views exported as metrics and columns of the views used as labels. Other columns with
+// numeric values are exported as metrics: columns within view's diff interval are exported as per-second rates (the
+// same as in 'pgcenter top'), other columns are exported as is. Views with stats of particular backends (activity and
+// progress views) are not exported because of high cardinality of labels. For the same reason process IDs are neither
+// labels nor metrics, e.g. walsender gets a new pid at every reconnect of replica.
+var exportedViews = map[string][]string{
+	"databases":          {"datname"},
+	"tables":             {"relation"},
+	"tables_io":          {"relation"},
+	"indexes":            {"index"},
+	"functions":          {"funcid", "function"},
+	"sizes":              {"relation"},
+	"replication":        {"client", "user", "name", "state", "mode"},
+	"roles":              {"role", "super", "login"},
+	"statements_timings": {"user", "database", "queryid"},
+	"statements_general": {"user", "database", "queryid"},
+	"statements_io":      {"user", "database", "queryid"},
+	"statements_temp":    {"user", "database", "queryid"},
+	"statements_local":   {"user", "database", "queryid"},
+}
+
+// invalidNameCharsRE defines characters which are not allowed in metrics and labels names.
+var invalidNameCharsRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// label defines name and value of metric's label.
+type label struct {
+	name  string
+	value string
+}
+
+// sample defines single value of metric with particular labels.
+type sample struct {
+	labels []label
+	value  float64
+}
+
+// metric defines Prometheus metric with all its samples. All metrics are gauges, because rates are already calculated.
+type metric struct {
+	name    string
+	help    string
+	samples []sample
+}
+
+// newMetric creates metric with a single sample without labels.
+func newMetric(name, help string, value float64) metric {
+	return metric{name: metricPrefix + name, help: help, samples: []sample{{value: value}}}
+}
+
+// writeMetrics writes metrics in Prometheus text exposition format.
+func writeMetrics(w io.Writer, metrics []metric) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		if len(m.samples) == 0 {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n", m.name, escapeHelp(m.help), m.name)
+		for _, s := range m.samples {
+			_, _ = bw.WriteString(m.name)
+			if len(s.labels) > 0 {
+				pairs := make([]string, len(s.labels))
+				for i, l := range s.labels {
+					pairs[i] = l.name + `="` + escapeLabelValue(l.value) + `"`
+				}
+				_, _ = bw.WriteString("{" + strings.Join(pairs, ",") + "}")
+			}
+			_, _ = bw.WriteString(" " + strconv.FormatFloat(s.value, 'g', -1, 64) + "\n")
+		}
+	}
+
+	return bw.Flush()
+}
+
+// escapeHelp escapes backslashes and line feeds in metric's help.
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeLabelValue escapes backslashes, double quotes and line feeds in label's value.
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// metricName returns name of metric (or label) with invalid characters replaced.
+func metricName(s string) string {
+	return invalidNameCharsRE.ReplaceAllString(s, "_")
+}
+
+// systemMetrics returns metrics based on system stats. Values of CPU and memory are labeled by mode and type,
+// other values are separate metrics.
+func systemMetrics(s stat.System) []metric {
+	var metrics []metric
+	for _, v := range stat.LoadValues(s.LoadAvg) {
+		metrics = append(metrics, newMetric("system_"+v.Name, v.Help, v.Value))
+	}
+
+	cpu := metric{name: metricPrefix + stat.SystemCPU + "_usage_percent", help: "CPU usage by mode, in percents."}
+	for _, v := range stat.CPUValues(s.CpuStat) {
+		cpu.samples = append(cpu.samples, sample{labels: []label{{"mode", v.Name}}, value: v.Value})
+	}
+	metrics = append(metrics, cpu)
+
+	mem := metric{name: metricPrefix + stat.SystemMemory + "_megabytes", help: "Memory and swap usage by type, in megabytes."}
+	for _, v := range stat.MemoryValues(s.Meminfo) {
+		mem.samples = append(mem.samples, sample{labels: []label{{"type", v.Name}}, value: v.Value})
+	}
+	metrics = append(metrics, mem)
+
+	// Metrics are defined using values of empty device, hence they are described even when there are no devices.
+	var disk []metric
+	for _, v := range stat.DiskValues(stat.Diskstat{}) {
+		disk = append(disk, metric{name: metricPrefix + stat.SystemDisk + "_" + v.Name, help: v.Help})
+	}
+	for _, d := range s.Diskstats {
+		// Inactive devices are skipped by stats collector.
+		if d.Device == "" {
+			continue
+		}
+		labels := []label{{stat.SystemDiskLabel, d.Device}}
+		for i, v := range stat.DiskValues(d) {
+			disk[i].samples = append(disk[i].samples, sample{labels: labels, value: v.Value})
+		}
+	}
+	metrics = append(metrics, disk...)
+
+	var net []metric
+	for _, v := range stat.NetworkValues(stat.Netdev{}) {
+		net = append(net, metric{name: metricPrefix + stat.SystemNetwork + "_" + v.Name, help: v.Help})
+	}
+	for _, n := range s.Netdevs {
+		// Inactive interfaces are skipped by stats collector.
+		if n.Ifname == "" {
+			continue
+		}
+		labels := []label{{stat.SystemNetworkLabel, n.Ifname}}
+		for i, v := range stat.NetworkValues(n) {
+			net[i].samples = append(net[i].samples, sample{labels: labels, value: v.Value})
+		}
+	}
+	metrics = append(metrics, net...)
+
+	return metrics
+}
+
+// activityMetrics returns metrics based on Postgres activity stats.
+func activityMetrics(a stat.Activity, pgss bool) []metric {
+	conns := metric{name: metricPrefix + "activity_connections", help: "Number of client connections by state."}
+	for _, v := range []struct {
+		state string
+		value int
+	}{
+		{"idle", a.ConnIdle}, {"idle_xact", a.ConnIdleXact}, {"active", a.ConnActive},
+		{"waiting", a.ConnWaiting}, {"other", a.ConnOthers},
+	} {
+		conns.samples = append(conns.samples, sample{labels: []label{{"state", v.state}}, value: float64(v.value)})
+	}
+
+	autovacuum := metric{name: metricPrefix + "activity_vacuums", help: "Number of running vacuums by type."}
+	for _, v := range []struct {
+		kind  string
+		value int
+	}{
+		{"autovacuum", a.AVWorkers}, {"antiwraparound", a.AVAntiwrap}, {"user", a.AVUser},
+	} {
+		autovacuum.samples = append(autovacuum.samples, sample{labels: []label{{"type", v.kind}}, value: float64(v.value)})
+	}
+
+	metrics := []metric{
+		newMetric("activity_connections_total", "Total number of client connections.", float64(a.ConnTotal)),
+		conns,
+		newMetric("activity_prepared_transactions", "Number of prepared transactions.", float64(a.ConnPrepared)),
+		autovacuum,
+		newMetric("activity_recovery", "Postgres is in recovery (1) or not (0).", boolValue(strings.HasPrefix(a.Recovery, "t"))),
+	}
+
+	if pgss {
+		metrics = append(metrics,
+			newMetric("activity_statements_per_second", "Statements executed per second.", float64(a.CallsRate)),
+			newMetric("activity_statements_avg_time_milliseconds", "Average duration of statements, in milliseconds.", float64(a.StmtAvgTime)),
+		)
+	}
+
+	return metrics
+}
+
+// viewMetrics returns metrics based on stats of the view. Values of columns within diff interval are exported only if
+// rates are calculated, i.e. when the previous snapshot was available.
+func viewMetrics(v view.View, labelCols []string, res stat.PGresult, rates bool) []metric {
+	isLabel := map[string]bool{}
+	for _, name := range labelCols {
+		isLabel[name] = true
+	}
+
+	var labelIdx []int
+	var metrics []metric
+	var metricIdx []int
+	for i, col := range res.Cols {
+		if isLabel[col] {
+			labelIdx = append(labelIdx, i)
+			continue
+		}
+		if col == "pid" {
+			continue
+		}
+
+		rate := v.DiffIntvl != [2]int{0, 0} && i >= v.DiffIntvl[0] && i <= v.DiffIntvl[1]
+		if rate && !rates {
+			continue
+		}
+
+		m := metric{name: metricPrefix + metricName(v.Name+"_"+col), help: fmt.Sprintf("Value of '%s' column of '%s' view.", col, v.Name)}
+		if rate {
+			m.name += "_per_second"
+			m.help = fmt.Sprintf("Value of '%s' column of '%s' view, per second.", col, v.Name)
+		}
+
+		metrics = append(metrics, m)
+		metricIdx = append(metricIdx, i)
+	}
+
+	for _, row := range res.Values {
+		labels := make([]label, 0, len(labelIdx))
+		for _, i := range labelIdx {
+			labels = append(labels, label{name: metricName(res.Cols[i]), value: row[i].String})
+		}
+
+		for j, i := range metricIdx {
+			// Skip NULLs and non-numeric values, e.g. intervals.
+			if !row[i].Valid {
+				continue
+			}
+			value, err := strconv.ParseFloat(row[i].String, 64)
+			if err != nil {
+				continue
+			}
+			metrics[j].samples = append(metrics[j].samples, sample{labels: labels, value: value})
+		}
+	}
+
+	return metrics
+}
+
+// boolValue returns numeric representation of boolean value.
+func boolValue(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
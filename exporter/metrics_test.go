@@ -0,0 +1,135 @@
+package exporter
+
+import (
+	"bytes"
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_writeMetrics(t *testing.T) {
+	metrics := []metric{
+		newMetric("up", "Stats have been collected.", 1),
+		{name: "pgcenter_empty", help: "Metric without samples is skipped."},
+		{
+			name: "pgcenter_test", help: "Help with \\ and\nnewline.",
+			samples: []sample{
+				{labels: []label{{"name", `quoted "value"`}, {"path", `C:\dir` + "\n"}}, value: 1.5},
+				{labels: []label{{"name", "plain"}}, value: 100},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeMetrics(&buf, metrics))
+	assert.Equal(t, `# HELP pgcenter_up Stats have been collected.
+# TYPE pgcenter_up gauge
+pgcenter_up 1
+# HELP pgcenter_test Help with \\ and\nnewline.
+# TYPE pgcenter_test gauge
+pgcenter_test{name="quoted \"value\"",path="C:\\dir\n"} 1.5
+pgcenter_test{name="plain"} 100
+`, buf.String())
+}
+
+func Test_metricName(t *testing.T) {
+	assert.Equal(t, "databases_read_t", metricName("databases_read_t"))
+	assert.Equal(t, "statements_io_t_hits", metricName("statements_io_t-hits"))
+	assert.Equal(t, "sizes_total_size_", metricName("sizes_total size%"))
+}
+
+func Test_viewMetrics(t *testing.T) {
+	v := view.View{Name: "functions", DiffIntvl: [2]int{3, 3}}
+	res := stat.PGresult{
+		Cols: []string{"funcid", "function", "total_calls", "calls", "total_t"},
+		Values: [][]sql.NullString{
+			{{String: "16384", Valid: true}, {String: "public.f1", Valid: true}, {String: "100", Valid: true}, {String: "5", Valid: true}, {String: "00:00:01", Valid: true}},
+			{{String: "16385", Valid: true}, {String: "public.f2", Valid: true}, {String: "20", Valid: true}, {String: "0", Valid: true}, {String: "", Valid: false}},
+		},
+		Ncols: 5, Nrows: 2, Valid: true,
+	}
+
+	// Rates are not calculated yet.
+	got := viewMetrics(v, exportedViews["functions"], res, false)
+	assert.Len(t, got, 2)
+	assert.Equal(t, "pgcenter_functions_total_calls", got[0].name)
+	assert.Equal(t, []sample{
+		{labels: []label{{"funcid", "16384"}, {"function", "public.f1"}}, value: 100},
+		{labels: []label{{"funcid", "16385"}, {"function", "public.f2"}}, value: 20},
+	}, got[0].samples)
+
+	// Interval values are not numeric, hence not exported.
+	assert.Equal(t, "pgcenter_functions_total_t", got[1].name)
+	assert.Len(t, got[1].samples, 0)
+
+	got = viewMetrics(v, exportedViews["functions"], res, true)
+	assert.Len(t, got, 3)
+	assert.Equal(t, "pgcenter_functions_calls_per_second", got[1].name)
+	assert.Equal(t, []sample{
+		{labels: []label{{"funcid", "16384"}, {"function", "public.f1"}}, value: 5},
+		{labels: []label{{"funcid", "16385"}, {"function", "public.f2"}}, value: 0},
+	}, got[1].samples)
+}
+
+func Test_viewMetrics_replication(t *testing.T) {
+	v := view.View{Name: "replication", DiffIntvl: [2]int{6, 6}}
+	res := stat.PGresult{
+		Cols: []string{"pid", "client", "user", "name", "state", "mode", "wal"},
+		Values: [][]sql.NullString{
+			{{String: "4242", Valid: true}, {String: "10.0.0.2", Valid: true}, {String: "replicator", Valid: true}, {String: "replica1", Valid: true}, {String: "streaming", Valid: true}, {String: "async", Valid: true}, {String: "10", Valid: true}},
+		},
+		Ncols: 7, Nrows: 1, Valid: true,
+	}
+
+	// Pid is neither label nor metric, reconnected walsender doesn't produce a new series.
+	got := viewMetrics(v, exportedViews["replication"], res, true)
+	assert.Len(t, got, 1)
+	assert.Equal(t, "pgcenter_replication_wal_per_second", got[0].name)
+	assert.Equal(t, []sample{
+		{labels: []label{{"client", "10.0.0.2"}, {"user", "replicator"}, {"name", "replica1"}, {"state", "streaming"}, {"mode", "async"}}, value: 10},
+	}, got[0].samples)
+}
+
+func Test_systemMetrics(t *testing.T) {
+	s := stat.System{
+		LoadAvg:   stat.LoadAvg{One: 1.5, Five: 1, Fifteen: 0.5},
+		CpuStat:   stat.CpuStat{User: 10, Sys: 5, Idle: 85},
+		Meminfo:   stat.Meminfo{MemTotal: 1024, MemUsed: 512},
+		Diskstats: stat.Diskstats{{Device: "sda", Rcompleted: 10, Util: 5}, {}},
+		Netdevs:   stat.Netdevs{{Ifname: "eth0", Rbytes: 1000}, {}},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeMetrics(&buf, systemMetrics(s)))
+	got := buf.String()
+
+	assert.Contains(t, got, "pgcenter_system_load1 1.5\n")
+	assert.Contains(t, got, `pgcenter_system_cpu_usage_percent{mode="user"} 10`+"\n")
+	assert.Contains(t, got, `pgcenter_system_memory_megabytes{type="mem_used"} 512`+"\n")
+	assert.Contains(t, got, `pgcenter_system_disk_reads_per_second{device="sda"} 10`+"\n")
+	assert.Contains(t, got, `pgcenter_system_disk_utilization_percent{device="sda"} 5`+"\n")
+	assert.Contains(t, got, `pgcenter_system_network_received_bytes_per_second{interface="eth0"} 1000`+"\n")
+	assert.NotContains(t, got, `device=""`)
+	assert.NotContains(t, got, `interface=""`)
+}
+
+func Test_activityMetrics(t *testing.T) {
+	a := stat.Activity{ConnTotal: 10, ConnIdle: 6, ConnActive: 4, AVWorkers: 1, Recovery: "f", CallsRate: 150, StmtAvgTime: 1.5}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeMetrics(&buf, activityMetrics(a, false)))
+	got := buf.String()
+
+	assert.Contains(t, got, "pgcenter_activity_connections_total 10\n")
+	assert.Contains(t, got, `pgcenter_activity_connections{state="idle"} 6`+"\n")
+	assert.Contains(t, got, `pgcenter_activity_vacuums{type="autovacuum"} 1`+"\n")
+	assert.Contains(t, got, "pgcenter_activity_recovery 0\n")
+	assert.NotContains(t, got, "pgcenter_activity_statements_per_second")
+
+	buf.Reset()
+	assert.NoError(t, writeMetrics(&buf, activityMetrics(a, true)))
+	assert.Contains(t, buf.String(), "pgcenter_activity_statements_per_second 150\n")
+	assert.Contains(t, buf.String(), "pgcenter_activity_statements_avg_time_milliseconds 1.5\n")
+}
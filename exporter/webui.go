@@ -0,0 +1,310 @@
+package exporter
+
+import (
+	"net/http"
+)
+
+// serveWebUI serves web UI page. The page itself doesn't require token, it asks token and uses it in API requests.
+func serveWebUI(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/ui" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write([]byte(webPage))
+}
+
+// webPage defines web UI page which mirrors 'pgcenter top': summary of system and activity stats, stats views with
+// sorting, filtering and auto-refresh. All values are inserted as text, hence they can't inject markup.
+const webPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>pgCenter</title>
+<style>
+body { margin: 0; font: 13px monospace; background: #000; color: #ddd; }
+header, nav, #controls, #message { padding: 4px 8px; }
+header { white-space: pre; line-height: 1.4; }
+nav button, #controls button { font: inherit; background: #222; color: #ddd; border: 1px solid #444; margin: 2px; cursor: pointer; }
+nav button.active { background: #ddd; color: #000; }
+#controls input, #controls select, #login input { font: inherit; background: #111; color: #ddd; border: 1px solid #444; }
+#message { color: #f66; min-height: 1.4em; }
+#login { padding: 40px; }
+table { border-collapse: collapse; width: 100%; }
+th { background: #ddd; color: #000; text-align: left; padding: 2px 6px; cursor: pointer; white-space: nowrap; }
+th.sorted { background: #fff; font-weight: bold; }
+th input { width: 90%; font: inherit; }
+td { padding: 1px 6px; white-space: nowrap; max-width: 60em; overflow: hidden; text-overflow: ellipsis; }
+tr:hover td { background: #222; }
+.hidden { display: none; }
+</style>
+</head>
+<body>
+<form id="login" class="hidden">
+  <p>pgCenter API token:</p>
+  <input id="token" type="password" size="40" autocomplete="off">
+  <button type="submit">Login</button>
+</form>
+<div id="main" class="hidden">
+  <header id="summary"></header>
+  <nav id="views"></nav>
+  <div id="controls">
+    refresh: <select id="refresh">
+      <option value="0">off</option><option value="1">1s</option><option value="5" selected>5s</option>
+      <option value="10">10s</option><option value="30">30s</option><option value="60">60s</option>
+    </select>
+    limit: <input id="limit" type="number" min="1" size="6">
+    <button id="reset" type="button">reset filters</button>
+    <button id="logout" type="button">logout</button>
+    <span id="status"></span>
+  </div>
+  <div id="message"></div>
+  <table><thead id="thead"></thead><tbody id="tbody"></tbody></table>
+</div>
+<script>
+(function() {
+  "use strict";
+
+  var state = { view: "databases", views: {}, timer: null };
+
+  function $(id) { return document.getElementById(id); }
+
+  function token() { return sessionStorage.getItem("pgcenter-token"); }
+
+  function api(path) {
+    return fetch(path, { headers: { "Authorization": "Bearer " + token() }, cache: "no-store" }).then(function(resp) {
+      return resp.json().then(function(data) {
+        if (resp.status === 401) {
+          logout();
+        }
+        if (!resp.ok) {
+          throw new Error(data.error || resp.statusText);
+        }
+        return data;
+      });
+    });
+  }
+
+  function resetHeader() {
+    $("thead").textContent = "";
+    $("thead").removeAttribute("data-columns");
+  }
+
+  function viewState(name) {
+    if (!state.views[name]) {
+      state.views[name] = { sort: "", order: "", filters: {} };
+    }
+    return state.views[name];
+  }
+
+  function fmt(n) { return (Math.round(n * 100) / 100).toFixed(2); }
+
+  function renderSummary(activity, system) {
+    var lines = [];
+    if (system) {
+      lines.push("load average: " + fmt(system.loadavg.load1) + ", " + fmt(system.loadavg.load5) + ", " + fmt(system.loadavg.load15));
+      lines.push("%cpu: " + fmt(system.cpu.user) + " us, " + fmt(system.cpu.system) + " sy, " + fmt(system.cpu.nice) + " ni, " +
+        fmt(system.cpu.idle) + " id, " + fmt(system.cpu.iowait) + " wa, " + fmt(system.cpu.irq) + " hi, " +
+        fmt(system.cpu.softirq) + " si, " + fmt(system.cpu.steal) + " st");
+      lines.push("MiB mem: " + system.memory.mem_total + " total, " + system.memory.mem_free + " free, " +
+        system.memory.mem_used + " used, " + (system.memory.mem_cached + system.memory.mem_buffers) + " buff/cache");
+      lines.push("MiB swap: " + system.memory.swap_total + " total, " + system.memory.swap_free + " free, " + system.memory.swap_used + " used");
+    }
+    lines.push("state [" + (activity.recovery ? "recovery" : "primary") + "]: up " + activity.uptime);
+    lines.push("activity: " + activity.connections_total + " total, " + activity.connections_idle + " idle, " +
+      activity.connections_idle_xact + " idle_xact, " + activity.connections_active + " active, " +
+      activity.connections_waiting + " waiting, " + activity.connections_other + " others, " +
+      activity.prepared_transactions + " prepared");
+    lines.push("autovacuum: " + activity.autovacuums + " workers, " + activity.antiwraparound_vacuums + " antiwraparound, " +
+      activity.user_vacuums + " manual");
+    var stmt = "";
+    if (activity.statements_per_second !== undefined) {
+      stmt = ", statements: " + activity.statements_per_second + " stmt/s, " + fmt(activity.statements_avg_time_ms) + " stmt_avg_time";
+    }
+    lines.push("xact_maxtime: " + activity.xact_max_time + ", prep_maxtime: " + activity.prepared_max_time +
+      ", av_maxtime: " + activity.vacuum_max_time + stmt);
+    $("summary").textContent = lines.join("\n");
+  }
+
+  function renderViews(names) {
+    var nav = $("views");
+    nav.textContent = "";
+    names.forEach(function(name) {
+      var b = document.createElement("button");
+      b.type = "button";
+      b.textContent = name;
+      if (name === state.view) {
+        b.className = "active";
+      }
+      b.onclick = function() {
+        state.view = name;
+        renderViews(names);
+        resetHeader();
+        refresh();
+      };
+      nav.appendChild(b);
+    });
+  }
+
+  function renderTable(data) {
+    var vs = viewState(data.view);
+    var thead = $("thead");
+
+    // Header is rebuilt only when columns are changed, hence focus of filter inputs is kept.
+    if (thead.getAttribute("data-columns") !== data.view + ":" + data.columns.join(",")) {
+      thead.textContent = "";
+      thead.setAttribute("data-columns", data.view + ":" + data.columns.join(","));
+      var names = document.createElement("tr");
+      var filters = document.createElement("tr");
+      data.columns.forEach(function(col) {
+        var th = document.createElement("th");
+        th.textContent = col;
+        th.setAttribute("data-column", col);
+        th.onclick = function() {
+          if (vs.sort === col) {
+            vs.order = vs.order === "desc" ? "asc" : "desc";
+          } else {
+            vs.sort = col;
+            vs.order = "desc";
+          }
+          refresh();
+        };
+        names.appendChild(th);
+
+        var fth = document.createElement("th");
+        var input = document.createElement("input");
+        input.placeholder = "filter";
+        input.value = vs.filters[col] || "";
+        input.onchange = function() {
+          vs.filters[col] = input.value;
+          refresh();
+        };
+        fth.appendChild(input);
+        filters.appendChild(fth);
+      });
+      thead.appendChild(names);
+      thead.appendChild(filters);
+    }
+
+    Array.prototype.forEach.call(thead.querySelectorAll("th[data-column]"), function(th) {
+      var col = th.getAttribute("data-column");
+      th.className = col === vs.sort ? "sorted" : "";
+      th.textContent = col + (col === vs.sort ? (vs.order === "desc" ? " ▼" : " ▲") : "") + (vs.filters[col] ? " *" : "");
+    });
+
+    var tbody = $("tbody");
+    tbody.textContent = "";
+    data.rows.forEach(function(row) {
+      var tr = document.createElement("tr");
+      row.forEach(function(value) {
+        var td = document.createElement("td");
+        td.textContent = value === null ? "" : value;
+        td.title = td.textContent;
+        tr.appendChild(td);
+      });
+      tbody.appendChild(tr);
+    });
+
+    $("message").textContent = data.error || (data.rates ? "" : "rates will be available after the next stats collecting");
+    $("status").textContent = "updated: " + new Date(data.time).toLocaleTimeString();
+  }
+
+  function viewPath() {
+    var vs = viewState(state.view);
+    var params = [];
+    if (vs.sort) {
+      params.push("sort=" + encodeURIComponent(vs.sort), "order=" + vs.order);
+    }
+    Object.keys(vs.filters).forEach(function(col) {
+      if (vs.filters[col]) {
+        params.push("filter=" + encodeURIComponent(col + ":" + vs.filters[col]));
+      }
+    });
+    if ($("limit").value) {
+      params.push("limit=" + encodeURIComponent($("limit").value));
+    }
+    return "/views/" + encodeURIComponent(state.view) + (params.length ? "?" + params.join("&") : "");
+  }
+
+  function refresh() {
+    if (!token()) {
+      return;
+    }
+
+    var system = api("/system").catch(function() { return null; });
+    Promise.all([api("/activity"), system]).then(function(res) {
+      renderSummary(res[0], res[1]);
+    }).catch(function(err) {
+      $("summary").textContent = "ERROR: " + err.message;
+    });
+
+    // Skip responses received after switching to another view.
+    var view = state.view;
+    api(viewPath()).then(function(data) {
+      if (view === state.view) {
+        renderTable(data);
+      }
+    }).catch(function(err) {
+      $("message").textContent = "ERROR: " + err.message;
+    });
+  }
+
+  function schedule() {
+    clearInterval(state.timer);
+    var seconds = parseInt($("refresh").value, 10);
+    if (seconds > 0) {
+      state.timer = setInterval(refresh, seconds * 1000);
+    }
+  }
+
+  function login() {
+    $("login").className = "hidden";
+    $("main").className = "";
+    api("/views").then(function(data) {
+      if (data.views.indexOf(state.view) < 0) {
+        state.view = data.views[0];
+      }
+      renderViews(data.views);
+      refresh();
+      schedule();
+    }).catch(function(err) {
+      $("message").textContent = "ERROR: " + err.message;
+    });
+  }
+
+  function logout() {
+    clearInterval(state.timer);
+    sessionStorage.removeItem("pgcenter-token");
+    $("main").className = "hidden";
+    $("login").className = "";
+  }
+
+  $("login").onsubmit = function(e) {
+    e.preventDefault();
+    sessionStorage.setItem("pgcenter-token", $("token").value);
+    $("token").value = "";
+    login();
+  };
+  $("logout").onclick = logout;
+  $("refresh").onchange = function() { schedule(); refresh(); };
+  $("limit").onchange = refresh;
+  $("reset").onclick = function() {
+    viewState(state.view).filters = {};
+    resetHeader();
+    refresh();
+  };
+
+  if (token()) {
+    login();
+  } else {
+    logout();
+  }
+})();
+</script>
+</body>
+</html>
+`
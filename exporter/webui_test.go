@@ -0,0 +1,21 @@
+package exporter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_serveWebUI(t *testing.T) {
+	w := httptest.NewRecorder()
+	serveWebUI(w, httptest.NewRequest(http.MethodGet, "/ui", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.NotEqual(t, "", w.Header().Get("Content-Security-Policy"))
+	assert.Contains(t, w.Body.String(), "<title>pgCenter</title>")
+
+	w = httptest.NewRecorder()
+	serveWebUI(w, httptest.NewRequest(http.MethodGet, "/ui/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
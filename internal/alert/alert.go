@@ -0,0 +1,445 @@
+// Package alert implements evaluation of alert rules and sending notifications about fired and resolved alerts.
+package alert
+
+import (
+	"context"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// StatusFiring defines status of fired alert.
+	StatusFiring = "firing"
+	// StatusResolved defines status of resolved alert.
+	StatusResolved = "resolved"
+)
+
+// Alert defines alert which is firing.
+type Alert struct {
+	Rule      string
+	Severity  string
+	Labels    []Label
+	Value     float64
+	Deviation float64   // deviation from the mean in standard deviations, for anomalies
+	Since     time.Time // when condition became true
+}
+
+// String returns human-readable representation of the alert.
+func (a Alert) String() string {
+	value := formatValue(a.Value)
+	if a.Deviation != 0 {
+		value += fmt.Sprintf(" (%+.1f sigmas)", a.Deviation)
+	}
+
+	if len(a.Labels) == 0 {
+		return fmt.Sprintf("%s: %s", a.Rule, value)
+	}
+	return fmt.Sprintf("%s (%s): %s", a.Rule, labelsString(a.Labels), value)
+}
+
+// state defines state of alert with particular labels.
+type state struct {
+	rule      *Rule
+	labels    []Label
+	value     float64
+	deviation float64   // deviation from the mean in standard deviations, for anomalies
+	since     time.Time // when condition became true
+	firing    bool
+}
+
+// Monitor evaluates alert rules using stats collected by sampler and sends notifications when alerts fire and resolve.
+// Firing alerts are available for showing in UI.
+type Monitor struct {
+	config    Config
+	notifiers []notifier
+	hooks     *hook.Runner                          // runs user hooks when alerts fire and resolve
+	logf      func(format string, a ...interface{}) // logs errors of rules evaluation and notifications
+	instance  string                                // name of Postgres instance used in notifications
+
+	views     view.Views                    // views used by rules
+	prevTime  time.Time                     // time of the previous sample, used for rates of queries
+	prevQuery map[string]map[string]float64 // previous values of queries metrics keyed by rule and labels, used for rates
+
+	mu      sync.Mutex
+	states  map[string]*state   // states of alerts whose conditions are true, keyed by rule and labels
+	windows map[string]*rolling // recent values of metrics of anomaly rules, keyed by rule and labels
+	wg      sync.WaitGroup      // in-flight notifications
+}
+
+// NewMonitor validates alerting configuration and creates monitor.
+func NewMonitor(config Config, logf func(format string, a ...interface{})) (*Monitor, error) {
+	// Rules are validated in place, use own copy of them because config could be shared by several monitors.
+	config.Rules = append([]Rule(nil), config.Rules...)
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid alerts configuration: %s", err)
+	}
+
+	m := &Monitor{
+		config:    config,
+		logf:      logf,
+		views:     view.Views{},
+		prevQuery: map[string]map[string]float64{},
+		states:    map[string]*state{},
+		windows:   map[string]*rolling{},
+	}
+
+	for _, r := range config.Receivers {
+		m.notifiers = append(m.notifiers, newNotifier(r))
+	}
+
+	return m, nil
+}
+
+// SetHooks sets runner of user hooks which are run when alerts fire and resolve.
+func (m *Monitor) SetHooks(r *hook.Runner) {
+	m.hooks = r
+}
+
+// SetInstance sets name of Postgres instance used in notifications.
+func (m *Monitor) SetInstance(db postgres.Config) {
+	m.instance = instanceName(db)
+}
+
+// Configure checks views used by rules exist in configured views. Rules' queries are wrapped into views, hence they
+// are collected by sampler along with other views.
+func (m *Monitor) Configure(views view.Views) error {
+	for _, r := range m.config.Rules {
+		switch r.View {
+		case SourceSystem, SourceSummary:
+		case "":
+			m.views[queryView(r.Name)] = view.View{Name: queryView(r.Name), Query: r.Query}
+		default:
+			v, ok := views[r.View]
+			if !ok {
+				return fmt.Errorf("rule '%s': unknown view '%s'", r.Name, r.View)
+			}
+			m.views[r.View] = v
+		}
+	}
+
+	return nil
+}
+
+// Require requests collecting of stats used by rules.
+func (m *Monitor) Require(s *stat.Sampler) {
+	for _, r := range m.config.Rules {
+		switch r.View {
+		case SourceSystem:
+			s.CollectSystem()
+		case SourceSummary:
+			s.CollectActivity()
+		}
+	}
+
+	for _, v := range m.views {
+		s.AddView(v, true)
+	}
+}
+
+// Interval returns interval of rules evaluation.
+func (m *Monitor) Interval() time.Duration {
+	return m.config.Interval
+}
+
+// Wait waits for in-flight notifications and hooks.
+func (m *Monitor) Wait() {
+	m.wg.Wait()
+	m.hooks.Wait()
+}
+
+// Firing returns alerts which are firing, ordered by rule name and labels.
+func (m *Monitor) Firing() []Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var alerts []Alert
+	for _, s := range m.states {
+		if s.firing {
+			alerts = append(alerts, Alert{Rule: s.rule.Name, Severity: s.rule.Severity, Labels: s.labels, Value: s.value, Deviation: s.deviation, Since: s.since})
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Rule != alerts[j].Rule {
+			return alerts[i].Rule < alerts[j].Rule
+		}
+		return labelsString(alerts[i].Labels) < labelsString(alerts[j].Labels)
+	})
+
+	return alerts
+}
+
+// Evaluate evaluates rules using collected sample and sends notifications. Rules which stats have been collected
+// successfully are evaluated even if collecting of other stats failed. Errors are logged.
+func (m *Monitor) Evaluate(s stat.Sample, err error) {
+	if err != nil {
+		m.logf("alerts: %s", err)
+		return
+	}
+
+	// Counters have been reset, rates of queries can't be calculated using previous values.
+	if s.Reset {
+		m.prevQuery = map[string]map[string]float64{}
+	}
+
+	samples, err := m.collect(s)
+
+	var events []Event
+	for i := range m.config.Rules {
+		r := &m.config.Rules[i]
+		if ss, ok := samples[r.Name]; ok {
+			events = append(events, m.evaluate(r, ss, s.Time)...)
+		}
+	}
+
+	m.notify(events)
+
+	if err != nil {
+		m.logf("alerts: %s", err)
+	}
+}
+
+// collect returns samples of rules metrics taken from collected stats. Rules of views with rates are skipped until
+// rates are calculated. Errors of particular rules are combined into single error.
+func (m *Monitor) collect(s stat.Sample) (map[string][]sample, error) {
+	var (
+		errs    []string
+		samples = map[string][]sample{}
+	)
+
+	var itv float64
+	if !m.prevTime.IsZero() {
+		itv = s.Time.Sub(m.prevTime).Seconds()
+	}
+	m.prevTime = s.Time
+
+	for _, r := range m.config.Rules {
+		var ss []sample
+		var err error
+
+		switch r.View {
+		case SourceSystem:
+			switch {
+			case s.SystemError != nil:
+				err = s.SystemError
+			case s.System == nil:
+				err = fmt.Errorf("system stats are not available, Postgres is remote and pgcenter stats schema is not installed")
+			default:
+				ss = systemSamples(*s.System, r.Metric)
+			}
+		case SourceSummary:
+			if s.ActivityError != nil {
+				err = s.ActivityError
+				break
+			}
+			ss, err = summarySamples(*s.Activity, r.Metric)
+		case "":
+			vs := s.Views[queryView(r.Name)]
+			if vs.Err != nil {
+				err = vs.Err
+				break
+			}
+			ss, err = resultSamples(vs.Result, r.Metric, r.Labels)
+			if err == nil && r.Rate {
+				ss = m.queryRates(r.Name, ss, itv)
+			}
+		default:
+			vs := s.Views[r.View]
+			if vs.Err != nil {
+				err = vs.Err
+				break
+			}
+
+			v := m.views[r.View]
+			if !vs.Rates && v.DiffIntvl != [2]int{0, 0} {
+				continue
+			}
+
+			// Rows of view are labeled by view's unique key by default.
+			labels := r.Labels
+			if labels == nil && len(vs.Result.Cols) > 0 {
+				labels = []string{vs.Result.Cols[v.UniqueKey]}
+			}
+			ss, err = resultSamples(vs.Result, r.Metric, labels)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("rule '%s': %s", r.Name, err))
+			continue
+		}
+
+		samples[r.Name] = ss
+	}
+
+	if len(errs) > 0 {
+		return samples, fmt.Errorf("%s", joinErrors(errs))
+	}
+
+	return samples, nil
+}
+
+// queryRates returns rates per second of query's metric, calculated using values of the previous evaluation made
+// specified number of seconds ago. Samples which have no previous values are skipped.
+func (m *Monitor) queryRates(rule string, samples []sample, itv float64) []sample {
+	prev := m.prevQuery[rule]
+	curr := make(map[string]float64, len(samples))
+	m.prevQuery[rule] = curr
+
+	var rates []sample
+	for _, s := range samples {
+		key := labelsString(s.labels)
+		curr[key] = s.value
+
+		if p, ok := prev[key]; ok && itv > 0 {
+			rates = append(rates, sample{labels: s.labels, value: (s.value - p) / itv})
+		}
+	}
+
+	return rates
+}
+
+// evaluate evaluates rule using samples of its metric and returns events about fired and resolved alerts. Alert fires
+// when condition has been true during the rule's duration, and resolves when condition is false or sample is gone.
+func (m *Monitor) evaluate(r *Rule, samples []sample, now time.Time) []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []Event
+	seen, evaluated := map[string]bool{}, map[string]bool{}
+
+	for _, s := range samples {
+		key := r.Name + "\x00" + labelsString(s.labels)
+		evaluated[key] = true
+
+		matched, deviation := m.check(r, key, s.value)
+		if !matched {
+			continue
+		}
+
+		seen[key] = true
+
+		st, ok := m.states[key]
+		if !ok {
+			st = &state{rule: r, labels: s.labels, since: now}
+			m.states[key] = st
+		}
+		st.value, st.deviation = s.value, deviation
+
+		if !st.firing && now.Sub(st.since) >= r.For {
+			st.firing = true
+			events = append(events, m.newEvent(StatusFiring, st, now))
+		}
+	}
+
+	for key, st := range m.states {
+		if st.rule != r || seen[key] {
+			continue
+		}
+
+		if st.firing {
+			events = append(events, m.newEvent(StatusResolved, st, now))
+		}
+		delete(m.states, key)
+	}
+
+	// Forget recent values of rows which have gone.
+	for key := range m.windows {
+		if strings.HasPrefix(key, r.Name+"\x00") && !evaluated[key] {
+			delete(m.windows, key)
+		}
+	}
+
+	return events
+}
+
+// check returns true if value satisfies the rule's condition. For rules with anomaly detection, value is checked
+// against recent values of the metric with the same labels, and then it is remembered. Deviation of the value from
+// the mean of recent values is returned in standard deviations.
+func (m *Monitor) check(r *Rule, key string, value float64) (bool, float64) {
+	if r.Anomaly == nil {
+		return r.compare(value), 0
+	}
+
+	w, ok := m.windows[key]
+	if !ok {
+		w = &rolling{}
+		m.windows[key] = w
+	}
+
+	deviation, ok := w.deviation(value)
+	w.add(value, r.Anomaly.Window)
+
+	if !ok || !r.Anomaly.isAnomaly(deviation) {
+		return false, 0
+	}
+
+	return true, deviation
+}
+
+// newEvent creates event about alert.
+func (m *Monitor) newEvent(status string, st *state, now time.Time) Event {
+	return Event{
+		Status:      status,
+		Rule:        st.rule.Name,
+		Severity:    st.rule.Severity,
+		Description: st.rule.Description,
+		Instance:    m.instance,
+		Labels:      st.labels,
+		Value:       st.value,
+		Condition:   st.rule.Condition,
+		Deviation:   st.deviation,
+		Since:       st.since,
+		Time:        now,
+	}
+}
+
+// notify sends events to all receivers in background, hence slow receivers don't delay rules evaluation.
+func (m *Monitor) notify(events []Event) {
+	for _, e := range events {
+		name := hook.AlertFiring
+		if e.Status == StatusResolved {
+			name = hook.AlertResolved
+		}
+		m.hooks.Fire(hook.Event{Event: name, Time: e.Time, Instance: e.Instance, Details: e})
+
+		for _, n := range m.notifiers {
+			m.wg.Add(1)
+			go func(n notifier, e Event) {
+				defer m.wg.Done()
+
+				ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+				defer cancel()
+
+				if err := n.notify(ctx, e); err != nil {
+					m.logf("alerts: send notification about %s failed: %s", e.Rule, err)
+				}
+			}(n, e)
+		}
+	}
+}
+
+// queryView returns name of the view which wraps query of the rule.
+func queryView(rule string) string {
+	return "alert:" + rule
+}
+
+// instanceName returns name of Postgres instance used in notifications.
+func instanceName(c postgres.Config) string {
+	return hook.InstanceName(c)
+}
+
+// joinErrors joins error messages.
+func joinErrors(errs []string) string {
+	s := errs[0]
+	for _, e := range errs[1:] {
+		s += "; " + e
+	}
+	return s
+}
@@ -0,0 +1,286 @@
+package alert
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMonitor_evaluate(t *testing.T) {
+	m, err := NewMonitor(Config{Rules: []Rule{
+		{Name: "lag", View: "replication", Metric: "total_lag", Condition: "> 100", For: 2 * time.Minute},
+	}}, t.Logf)
+	assert.NoError(t, err)
+
+	r := &m.config.Rules[0]
+	ts := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	replica1, replica2 := []Label{{"client_addr", "10.0.0.1"}}, []Label{{"client_addr", "10.0.0.2"}}
+
+	// Condition became true, but alert is pending.
+	events := m.evaluate(r, []sample{{labels: replica1, value: 150}, {labels: replica2, value: 50}}, ts)
+	assert.Len(t, events, 0)
+	assert.Len(t, m.Firing(), 0)
+
+	// Condition is true during specified duration, alert fires.
+	events = m.evaluate(r, []sample{{labels: replica1, value: 200}, {labels: replica2, value: 150}}, ts.Add(2*time.Minute))
+	assert.Len(t, events, 1)
+	assert.Equal(t, StatusFiring, events[0].Status)
+	assert.Equal(t, replica1, events[0].Labels)
+	assert.Equal(t, float64(200), events[0].Value)
+	assert.Equal(t, ts, events[0].Since)
+	assert.Equal(t, []Alert{{Rule: "lag", Severity: "warning", Labels: replica1, Value: 200, Since: ts}}, m.Firing())
+
+	// Firing alert is not notified again.
+	events = m.evaluate(r, []sample{{labels: replica1, value: 300}, {labels: replica2, value: 150}}, ts.Add(3*time.Minute))
+	assert.Len(t, events, 0)
+	assert.Equal(t, float64(300), m.Firing()[0].Value)
+
+	// Condition of pending alert became false, condition of firing alert remains true.
+	events = m.evaluate(r, []sample{{labels: replica1, value: 300}, {labels: replica2, value: 50}}, ts.Add(4*time.Minute))
+	assert.Len(t, events, 0)
+	assert.Len(t, m.states, 1)
+
+	// Row has gone, alert resolves.
+	events = m.evaluate(r, []sample{{labels: replica2, value: 50}}, ts.Add(5*time.Minute))
+	assert.Len(t, events, 1)
+	assert.Equal(t, StatusResolved, events[0].Status)
+	assert.Equal(t, replica1, events[0].Labels)
+	assert.Len(t, m.Firing(), 0)
+	assert.Len(t, m.states, 0)
+}
+
+func TestMonitor_evaluate_immediately(t *testing.T) {
+	m, err := NewMonitor(Config{Rules: []Rule{
+		{Name: "waiting", View: "summary", Metric: "connections_waiting", Condition: "> 10"},
+		{Name: "load", View: "system", Metric: "load1", Condition: "> 10", Severity: "critical"},
+	}}, t.Logf)
+	assert.NoError(t, err)
+
+	ts := time.Now()
+
+	// Alert without duration fires immediately.
+	events := m.evaluate(&m.config.Rules[0], []sample{{value: 20}}, ts)
+	assert.Len(t, events, 1)
+	events = m.evaluate(&m.config.Rules[1], []sample{{value: 20}}, ts)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "critical", events[0].Severity)
+	assert.Len(t, m.Firing(), 2)
+	assert.Equal(t, "load", m.Firing()[0].Rule)
+
+	// States of other rules are not affected.
+	events = m.evaluate(&m.config.Rules[0], []sample{{value: 5}}, ts.Add(time.Second))
+	assert.Len(t, events, 1)
+	assert.Equal(t, StatusResolved, events[0].Status)
+	assert.Equal(t, "waiting", events[0].Rule)
+	assert.Len(t, m.Firing(), 1)
+}
+
+func TestMonitor_evaluate_anomaly(t *testing.T) {
+	m, err := NewMonitor(Config{Rules: []Rule{
+		{Name: "tps", View: "summary", Metric: "statements_per_second", Anomaly: &Anomaly{Sigmas: 3, Window: 10}},
+	}}, t.Logf)
+	assert.NoError(t, err)
+
+	r := &m.config.Rules[0]
+	ts := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Recent values are accumulated, mean is 100, standard deviation is 10.
+	for i := 0; i < 10; i++ {
+		events := m.evaluate(r, []sample{{value: float64(90 + i%2*20)}}, ts.Add(time.Duration(i)*time.Second))
+		assert.Len(t, events, 0)
+	}
+
+	// Value deviates from the mean, alert fires.
+	events := m.evaluate(r, []sample{{value: 150}}, ts.Add(10*time.Second))
+	assert.Len(t, events, 1)
+	assert.Equal(t, StatusFiring, events[0].Status)
+	assert.Equal(t, "beyond 3 sigmas from mean", events[0].Condition)
+	assert.Equal(t, float64(5), events[0].Deviation)
+	assert.Equal(t, "tps: 150 (+5.0 sigmas)", m.Firing()[0].String())
+
+	// Value returns to normal, alert resolves.
+	events = m.evaluate(r, []sample{{value: 100}}, ts.Add(11*time.Second))
+	assert.Len(t, events, 1)
+	assert.Equal(t, StatusResolved, events[0].Status)
+
+	// Recent values of rows which have gone are forgotten.
+	m.evaluate(r, nil, ts.Add(12*time.Second))
+	assert.Len(t, m.windows, 0)
+}
+
+func TestMonitor_queryRates(t *testing.T) {
+	m, err := NewMonitor(Config{}, t.Logf)
+	assert.NoError(t, err)
+
+	db1, db2 := []Label{{"datname", "db1"}}, []Label{{"datname", "db2"}}
+
+	// No previous values, no rates.
+	assert.Len(t, m.queryRates("xacts", []sample{{labels: db1, value: 1000}}, 0), 0)
+
+	got := m.queryRates("xacts", []sample{{labels: db1, value: 1500}, {labels: db2, value: 100}}, 10)
+	assert.Equal(t, []sample{{labels: db1, value: 50}}, got)
+}
+
+func TestNewMonitor(t *testing.T) {
+	rules := []Rule{{Name: "load", View: "system", Metric: "load1", Condition: "> 10"}}
+
+	_, err := NewMonitor(Config{Rules: rules}, t.Logf)
+	assert.NoError(t, err)
+
+	// Rules of passed config are not modified.
+	assert.Equal(t, "", rules[0].op)
+
+	_, err = NewMonitor(Config{Rules: []Rule{{Name: "load"}}}, t.Logf)
+	assert.Error(t, err)
+}
+
+func TestMonitor_notify(t *testing.T) {
+	var mu sync.Mutex
+	var received []Event
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		mu.Lock()
+		received = append(received, e)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	var logged []string
+	m, err := NewMonitor(Config{Receivers: []Receiver{{Type: "webhook", URL: ts.URL}, {Type: "webhook", URL: ts.URL + "/invalid\x00"}}},
+		func(format string, a ...interface{}) {
+			mu.Lock()
+			logged = append(logged, format)
+			mu.Unlock()
+		},
+	)
+	assert.NoError(t, err)
+
+	m.notify([]Event{testEvent(StatusFiring), testEvent(StatusResolved)})
+	m.wg.Wait()
+
+	assert.Len(t, received, 2)
+	assert.Len(t, logged, 2)
+}
+
+// testDBConfig returns config of connection to test Postgres.
+func testDBConfig(t *testing.T) postgres.Config {
+	config, err := postgres.NewTestConfig()
+	assert.NoError(t, err)
+	return config
+}
+
+func TestMonitor_Configure(t *testing.T) {
+	m, err := NewMonitor(Config{Rules: []Rule{
+		{Name: "lag", View: "replication", Metric: "total_lag", Condition: "> 100"},
+		{Name: "xid_age", Query: "SELECT datname, age(datfrozenxid) AS xid_age FROM pg_database", Metric: "xid_age", Condition: "> 1B"},
+		{Name: "load", View: "system", Metric: "load1", Condition: "> 10"},
+	}}, t.Logf)
+	assert.NoError(t, err)
+
+	assert.NoError(t, m.Configure(view.Views{"replication": {Name: "replication"}, "tables": {Name: "tables"}}))
+	assert.Equal(t, view.Views{
+		"replication":   {Name: "replication"},
+		"alert:xid_age": {Name: "alert:xid_age", Query: "SELECT datname, age(datfrozenxid) AS xid_age FROM pg_database"},
+	}, m.views)
+
+	assert.Error(t, m.Configure(view.Views{"tables": {Name: "tables"}}))
+}
+
+func TestMonitor_Evaluate(t *testing.T) {
+	m, err := NewMonitor(Config{Rules: []Rule{
+		{Name: "commits", View: "databases", Metric: "commits", Condition: "> 100"},
+		{Name: "xid_age", Query: "SELECT datname, age(datfrozenxid) AS xid_age FROM pg_database", Metric: "xid_age", Condition: "> 1B"},
+		{Name: "load", View: "system", Metric: "load1", Condition: "> 10"},
+		{Name: "waiting", View: "summary", Metric: "connections_waiting", Condition: "> 10"},
+	}}, t.Logf)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Configure(view.Views{"databases": {Name: "databases", DiffIntvl: [2]int{1, 1}}}))
+
+	databases := stat.PGresult{
+		Valid: true, Ncols: 2, Nrows: 1, Cols: []string{"datname", "commits"},
+		Values: [][]sql.NullString{{{String: "shop", Valid: true}, {String: "150", Valid: true}}},
+	}
+	xids := stat.PGresult{
+		Valid: true, Ncols: 2, Nrows: 1, Cols: []string{"datname", "xid_age"},
+		Values: [][]sql.NullString{{{String: "shop", Valid: true}, {String: "2000000000", Valid: true}}},
+	}
+
+	// Rates of views are not calculated yet, system stats are not available.
+	s := stat.Sample{
+		Time:     time.Now(),
+		Activity: &stat.Activity{ConnWaiting: 20},
+		Views: map[string]stat.ViewSample{
+			"databases":     {Current: databases, Result: databases},
+			"alert:xid_age": {Current: xids, Result: xids},
+		},
+	}
+	m.Evaluate(s, nil)
+
+	firing := m.Firing()
+	assert.Len(t, firing, 2)
+	assert.Equal(t, "waiting", firing[0].Rule)
+	assert.Equal(t, "xid_age", firing[1].Rule)
+
+	// Rates are calculated.
+	s.Time = s.Time.Add(time.Second)
+	s.System = &stat.System{LoadAvg: stat.LoadAvg{One: 20}}
+	s.Views["databases"] = stat.ViewSample{Current: databases, Result: databases, Rates: true}
+	m.Evaluate(s, nil)
+	assert.Len(t, m.Firing(), 4)
+
+	// Collecting failed, alerts are kept.
+	m.Evaluate(stat.Sample{}, fmt.Errorf("connection lost"))
+	assert.Len(t, m.Firing(), 4)
+	m.Wait()
+}
+
+func TestMonitor_Evaluate_spill(t *testing.T) {
+	config := Config{SpillRate: "10MB"}
+	assert.True(t, config.Enabled())
+
+	m, err := NewMonitor(config, t.Logf)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Configure(view.Views{"replication_slots": {Name: "replication_slots", DiffIntvl: [2]int{1, 1}}}))
+
+	slots := stat.PGresult{
+		Valid: true, Ncols: 2, Nrows: 2, Cols: []string{"slot_name", "spill_bytes"},
+		Values: [][]sql.NullString{
+			{{String: "sub1", Valid: true}, {String: "20971520", Valid: true}},
+			{{String: "sub2", Valid: true}, {String: "1048576", Valid: true}},
+		},
+	}
+
+	// Only slot which spills more than threshold fires.
+	m.Evaluate(stat.Sample{Time: time.Now(), Views: map[string]stat.ViewSample{
+		"replication_slots": {Current: slots, Result: slots, Rates: true},
+	}}, nil)
+
+	firing := m.Firing()
+	assert.Len(t, firing, 1)
+	assert.Equal(t, "logical_decoding_spill", firing[0].Rule)
+	assert.Equal(t, []Label{{Name: "slot_name", Value: "sub1"}}, firing[0].Labels)
+	m.Wait()
+
+	// Name of predefined rule can't be used by other rules.
+	_, err = NewMonitor(Config{SpillRate: "10MB", Rules: []Rule{{Name: "logical_decoding_spill", View: "system", Metric: "load1", Condition: "> 1"}}}, t.Logf)
+	assert.Error(t, err)
+
+	// Invalid threshold.
+	_, err = NewMonitor(Config{SpillRate: "10XB"}, t.Logf)
+	assert.Error(t, err)
+}
+
+func Test_instanceName(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:21913/pgcenter_fixtures", instanceName(testDBConfig(t)))
+}
@@ -0,0 +1,123 @@
+package alert
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// defaultAnomalySigmas defines default number of standard deviations from the mean considered as anomaly.
+	defaultAnomalySigmas = 3
+	// defaultAnomalyWindow defines default number of recent evaluations used for calculating mean and deviation.
+	defaultAnomalyWindow = 60
+	// minAnomalySamples defines minimum number of values in the window required for detecting anomalies.
+	minAnomalySamples = 10
+
+	// Directions of anomalies.
+	anomalyBoth = "both"
+	anomalyUp   = "up"
+	anomalyDown = "down"
+)
+
+// Anomaly defines detection of anomalies: alert fires when metric deviates from its rolling mean by more than
+// specified number of standard deviations.
+type Anomaly struct {
+	Sigmas    float64 `yaml:"sigmas"`    // number of standard deviations, default is 3
+	Window    int     `yaml:"window"`    // number of recent evaluations used for mean and deviation, default is 60
+	Direction string  `yaml:"direction"` // deviations taken into account: both (default), up, down
+}
+
+// validate checks anomaly detection settings and sets defaults.
+func (a *Anomaly) validate() error {
+	if a.Sigmas == 0 {
+		a.Sigmas = defaultAnomalySigmas
+	}
+	if a.Sigmas < 0 {
+		return fmt.Errorf("anomaly sigmas must be positive")
+	}
+
+	if a.Window == 0 {
+		a.Window = defaultAnomalyWindow
+	}
+	if a.Window < minAnomalySamples {
+		return fmt.Errorf("anomaly window must be at least %d evaluations", minAnomalySamples)
+	}
+
+	switch a.Direction {
+	case "":
+		a.Direction = anomalyBoth
+	case anomalyBoth, anomalyUp, anomalyDown:
+	default:
+		return fmt.Errorf("unknown anomaly direction '%s', supported: both, up, down", a.Direction)
+	}
+
+	return nil
+}
+
+// condition returns human-readable condition of the anomaly, used in notifications.
+func (a *Anomaly) condition() string {
+	switch a.Direction {
+	case anomalyUp:
+		return fmt.Sprintf("above mean by %s sigmas", formatValue(a.Sigmas))
+	case anomalyDown:
+		return fmt.Sprintf("below mean by %s sigmas", formatValue(a.Sigmas))
+	default:
+		return fmt.Sprintf("beyond %s sigmas from mean", formatValue(a.Sigmas))
+	}
+}
+
+// rolling defines window of recent values of metric with particular labels.
+type rolling struct {
+	values []float64 // ring buffer of values
+	next   int       // position of the next value
+}
+
+// deviation returns deviation of value from the mean of the window in standard deviations. Returns false if there are
+// not enough values in the window, or values in the window are constant.
+func (r *rolling) deviation(value float64) (float64, bool) {
+	n := float64(len(r.values))
+	if len(r.values) < minAnomalySamples {
+		return 0, false
+	}
+
+	var sum float64
+	for _, v := range r.values {
+		sum += v
+	}
+	mean := sum / n
+
+	var sq float64
+	for _, v := range r.values {
+		sq += (v - mean) * (v - mean)
+	}
+	stddev := math.Sqrt(sq / n)
+
+	if stddev == 0 {
+		return 0, false
+	}
+
+	return (value - mean) / stddev, true
+}
+
+// add adds value into the window, the oldest value is removed when the window is full.
+func (r *rolling) add(value float64, size int) {
+	if len(r.values) < size {
+		r.values = append(r.values, value)
+		return
+	}
+
+	r.values[r.next] = value
+	r.next = (r.next + 1) % size
+}
+
+// isAnomaly returns true if deviation (in standard deviations) is an anomaly.
+func (a *Anomaly) isAnomaly(deviation float64) bool {
+	switch a.Direction {
+	case anomalyUp:
+		return deviation >= a.Sigmas
+	case anomalyDown:
+		return deviation <= -a.Sigmas
+	default:
+		return math.Abs(deviation) >= a.Sigmas
+	}
+}
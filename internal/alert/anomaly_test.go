@@ -0,0 +1,67 @@
+package alert
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAnomaly_validate(t *testing.T) {
+	a := &Anomaly{}
+	assert.NoError(t, a.validate())
+	assert.Equal(t, &Anomaly{Sigmas: 3, Window: 60, Direction: "both"}, a)
+	assert.Equal(t, "beyond 3 sigmas from mean", a.condition())
+
+	a = &Anomaly{Sigmas: 2.5, Window: 30, Direction: "up"}
+	assert.NoError(t, a.validate())
+	assert.Equal(t, "above mean by 2.5 sigmas", a.condition())
+
+	for _, a := range []*Anomaly{{Sigmas: -1}, {Window: 5}, {Direction: "sideways"}} {
+		assert.Error(t, a.validate())
+	}
+}
+
+func TestAnomaly_isAnomaly(t *testing.T) {
+	testcases := []struct {
+		direction string
+		deviation float64
+		want      bool
+	}{
+		{direction: "both", deviation: 3.5, want: true},
+		{direction: "both", deviation: -3.5, want: true},
+		{direction: "both", deviation: 2, want: false},
+		{direction: "up", deviation: 3, want: true},
+		{direction: "up", deviation: -4, want: false},
+		{direction: "down", deviation: -4, want: true},
+		{direction: "down", deviation: 4, want: false},
+	}
+
+	for _, tc := range testcases {
+		a := &Anomaly{Sigmas: 3, Direction: tc.direction}
+		assert.Equal(t, tc.want, a.isAnomaly(tc.deviation))
+	}
+}
+
+func Test_rolling(t *testing.T) {
+	r := &rolling{}
+
+	// Not enough values.
+	for i := 0; i < minAnomalySamples-1; i++ {
+		r.add(float64(10+i%2*2), 12)
+	}
+	_, ok := r.deviation(100)
+	assert.False(t, ok)
+
+	// Mean is 11, standard deviation is 1.
+	r.add(12, 12)
+	d, ok := r.deviation(15)
+	assert.True(t, ok)
+	assert.Equal(t, float64(4), d)
+
+	// The oldest values are replaced when window is full.
+	for i := 0; i < 20; i++ {
+		r.add(5, 12)
+	}
+	assert.Len(t, r.values, 12)
+	_, ok = r.deviation(100)
+	assert.False(t, ok) // values are constant
+}
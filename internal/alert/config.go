@@ -0,0 +1,316 @@
+package alert
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultInterval defines default interval of rules evaluation.
+	defaultInterval = 10 * time.Second
+	// defaultSeverity defines default severity of alerts.
+	defaultSeverity = "warning"
+
+	// SourceSystem defines source of rules based on system stats: load average, CPU, memory, disks and network usage.
+	SourceSystem = "system"
+	// SourceSummary defines source of rules based on summary activity stats: connections, vacuums, statements rate.
+	SourceSummary = "summary"
+
+	// spillRuleName defines name of predefined rule on rate of bytes spilled to disk by logical decoding.
+	spillRuleName = "logical_decoding_spill"
+)
+
+// Config defines alerting configuration: rules and receivers of notifications.
+type Config struct {
+	Interval  time.Duration `yaml:"interval"`   // interval of rules evaluation in top; record and exporter evaluate rules at every collecting
+	Rules     []Rule        `yaml:"rules"`      // alert rules
+	SpillRate string        `yaml:"spill_rate"` // bytes per second spilled to disk by logical decoding of a slot, e.g. '10MB', enables predefined rule
+	Receivers []Receiver    `yaml:"receivers"`  // receivers of notifications about fired and resolved alerts
+}
+
+// Rule defines alert rule. Metric is taken from stats view, system or summary stats, or from user-defined query. Alert
+// fires when condition is true (or metric deviates from its recent values, if anomaly detection is used) during
+// specified duration. Rows of views and queries are evaluated separately, each row is identified by values of label
+// columns.
+type Rule struct {
+	Name        string        `yaml:"name"`        // unique name of the rule
+	View        string        `yaml:"view"`        // name of stats view, 'system' or 'summary'
+	Query       string        `yaml:"query"`       // user-defined query, alternative to view
+	Metric      string        `yaml:"metric"`      // column of the view or query, or name of system/summary metric
+	Labels      []string      `yaml:"labels"`      // columns identifying rows of the view or query
+	Unit        string        `yaml:"unit"`        // unit of metric values, used when threshold is specified in bytes
+	Condition   string        `yaml:"condition"`   // comparison with threshold, e.g. '> 100MB'
+	Anomaly     *Anomaly      `yaml:"anomaly"`     // detection of anomalies, alternative to condition
+	Rate        bool          `yaml:"rate"`        // use rate per second of query's metric instead of its value
+	For         time.Duration `yaml:"for"`         // how long condition should be true before alert fires
+	Severity    string        `yaml:"severity"`    // severity of alert: critical, error, warning, info
+	Description string        `yaml:"description"` // description added to notifications
+
+	op        string  // comparison operator
+	threshold float64 // threshold in units of metric values
+}
+
+// Receiver defines destination of notifications.
+type Receiver struct {
+	Type       string            `yaml:"type"`        // type of receiver: webhook, slack, pagerduty
+	URL        string            `yaml:"url"`         // URL of webhook (PagerDuty Events API URL is used by default)
+	RoutingKey string            `yaml:"routing_key"` // integration key of PagerDuty service
+	Headers    map[string]string `yaml:"headers"`     // extra HTTP headers of webhook requests
+}
+
+// Enabled returns true if any alert rules are configured.
+func (c Config) Enabled() bool {
+	return len(c.Rules) > 0 || c.SpillRate != ""
+}
+
+// spillRule returns predefined rule which fires when logical decoding of a slot spills to disk more bytes per second
+// than specified threshold.
+func spillRule(threshold string) Rule {
+	return Rule{
+		Name:        spillRuleName,
+		View:        "replication_slots",
+		Metric:      "spill_bytes",
+		Unit:        "B",
+		Condition:   "> " + threshold,
+		Description: "Logical decoding spills large transactions to disk, consider higher logical_decoding_work_mem.",
+	}
+}
+
+var (
+	// conditionRE defines condition: comparison operator and threshold.
+	conditionRE = regexp.MustCompile(`^\s*(>=|<=|==|!=|>|<)\s*(\S+)\s*$`)
+	// thresholdRE defines threshold: number with optional suffix.
+	thresholdRE = regexp.MustCompile(`^(-?[0-9]*\.?[0-9]+(?:[eE][-+]?[0-9]+)?)(kB|KB|MB|GB|TB|K|M|B)?$`)
+	// durationRE defines threshold specified as duration, e.g. 90s, 5m, 1h30m.
+	durationRE = regexp.MustCompile(`^([0-9]*\.?[0-9]+(ns|us|ms|s|m|h))+$`)
+)
+
+// sizeUnits defines multipliers of size units.
+var sizeUnits = map[string]float64{
+	"B":  1,
+	"kB": 1 << 10,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+	"TB": 1 << 40,
+}
+
+// countSuffixes defines multipliers of counts suffixes.
+var countSuffixes = map[string]float64{
+	"K": 1e3,
+	"M": 1e6,
+	"B": 1e9,
+}
+
+// validate checks configuration, sets defaults and parses rules conditions.
+func (c *Config) validate() error {
+	if c.Interval == 0 {
+		c.Interval = defaultInterval
+	}
+	if c.Interval < time.Second {
+		return fmt.Errorf("alerts evaluation interval must be at least 1s")
+	}
+
+	// Predefined rule is evaluated along with others, its name must not be used by other rules. Threshold is cleared,
+	// hence repeated validation does not add the rule twice.
+	if c.SpillRate != "" {
+		c.Rules = append(c.Rules, spillRule(c.SpillRate))
+		c.SpillRate = ""
+	}
+
+	names := map[string]bool{}
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.Name == "" {
+			return fmt.Errorf("rule %d: name is not specified", i+1)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("rule '%s': duplicate name", r.Name)
+		}
+		names[r.Name] = true
+
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("rule '%s': %s", r.Name, err)
+		}
+	}
+
+	for i, rcv := range c.Receivers {
+		switch rcv.Type {
+		case "webhook", "slack":
+			if rcv.URL == "" {
+				return fmt.Errorf("receiver %d: url is not specified", i+1)
+			}
+		case "pagerduty":
+			if rcv.RoutingKey == "" {
+				return fmt.Errorf("receiver %d: routing_key is not specified", i+1)
+			}
+		default:
+			return fmt.Errorf("receiver %d: unknown type '%s', supported: webhook, slack, pagerduty", i+1, rcv.Type)
+		}
+	}
+
+	return nil
+}
+
+// validate checks rule, sets defaults and parses condition.
+func (r *Rule) validate() error {
+	if (r.View == "") == (r.Query == "") {
+		return fmt.Errorf("either view or query must be specified")
+	}
+	if r.Metric == "" {
+		return fmt.Errorf("metric is not specified")
+	}
+	if r.For < 0 {
+		return fmt.Errorf("negative duration")
+	}
+	if r.Rate && r.Query == "" {
+		return fmt.Errorf("rate is supported for queries only, rates of views are calculated automatically")
+	}
+
+	switch r.Severity {
+	case "":
+		r.Severity = defaultSeverity
+	case "critical", "error", "warning", "info":
+	default:
+		return fmt.Errorf("unknown severity '%s', supported: critical, error, warning, info", r.Severity)
+	}
+
+	switch r.View {
+	case SourceSystem:
+		unit, ok := systemMetricUnits[r.Metric]
+		if !ok {
+			return fmt.Errorf("unknown system metric '%s'", r.Metric)
+		}
+		if r.Unit == "" {
+			r.Unit = unit
+		}
+	case SourceSummary:
+		isSummary := map[string]bool{}
+		for _, name := range summaryMetrics {
+			isSummary[name] = true
+		}
+		if !isSummary[r.Metric] {
+			return fmt.Errorf("unknown summary metric '%s'", r.Metric)
+		}
+	}
+
+	if r.Unit == "" {
+		r.Unit = "B"
+	}
+	unit, ok := sizeUnits[r.Unit]
+	if !ok {
+		return fmt.Errorf("unknown unit '%s', supported: B, kB, MB, GB, TB", r.Unit)
+	}
+
+	// Anomalies are detected using metric's own values, threshold is not used.
+	if r.Anomaly != nil {
+		if r.Condition != "" {
+			return fmt.Errorf("either condition or anomaly must be specified")
+		}
+
+		// Settings are validated in place, use own copy of them.
+		a := *r.Anomaly
+		if err := a.validate(); err != nil {
+			return err
+		}
+		r.Anomaly, r.Condition = &a, a.condition()
+		return nil
+	}
+
+	op, threshold, bytes, err := parseCondition(r.Condition)
+	if err != nil {
+		return err
+	}
+
+	// Thresholds in bytes are converted to units of metric values.
+	if bytes {
+		threshold /= unit
+	}
+
+	r.op, r.threshold = op, threshold
+	return nil
+}
+
+// parseCondition parses condition and returns comparison operator, threshold and flag that threshold is in bytes.
+func parseCondition(s string) (string, float64, bool, error) {
+	m := conditionRE.FindStringSubmatch(s)
+	if m == nil {
+		return "", 0, false, fmt.Errorf("invalid condition '%s', must be in format '<operator> <threshold>'", s)
+	}
+
+	threshold, bytes, err := parseThreshold(m[2])
+	if err != nil {
+		return "", 0, false, err
+	}
+
+	return m[1], threshold, bytes, nil
+}
+
+// parseThreshold parses threshold, which could be a number with suffix of size (kB, MB, GB, TB), count (K - thousands,
+// M - millions, B - billions) or duration (converted to seconds). Returns flag that threshold is in bytes.
+func parseThreshold(s string) (float64, bool, error) {
+	if durationRE.MatchString(s) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid threshold '%s': %s", s, err)
+		}
+		return d.Seconds(), false, nil
+	}
+
+	m := thresholdRE.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false, fmt.Errorf("invalid threshold '%s'", s)
+	}
+
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid threshold '%s': %s", s, err)
+	}
+
+	if m[2] == "" {
+		return value, false, nil
+	}
+
+	if mult, ok := countSuffixes[m[2]]; ok {
+		return value * mult, false, nil
+	}
+
+	return value * sizeUnits[m[2]], true, nil
+}
+
+// compare returns true if value satisfies the condition of the rule.
+func (r *Rule) compare(value float64) bool {
+	switch r.op {
+	case ">":
+		return value > r.threshold
+	case ">=":
+		return value >= r.threshold
+	case "<":
+		return value < r.threshold
+	case "<=":
+		return value <= r.threshold
+	case "==":
+		return value == r.threshold
+	case "!=":
+		return value != r.threshold
+	}
+	return false
+}
+
+// labelsString returns labels in 'name=value' format.
+func labelsString(labels []Label) string {
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Name + "=" + l.Value
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatValue returns value rounded to two decimal places, without exponent.
+func formatValue(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,137 @@
+package alert
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestConfig_validate(t *testing.T) {
+	testcases := []struct {
+		valid  bool
+		config Config
+	}{
+		{valid: true, config: Config{}},
+		{valid: true, config: Config{
+			Rules: []Rule{
+				{Name: "lag", View: "replication", Metric: "total_lag", Unit: "kB", Condition: "> 100MB", For: 2 * time.Minute},
+				{Name: "xid", Query: "SELECT datname, age(datfrozenxid) AS age FROM pg_database", Metric: "age", Condition: "> 1.5B"},
+				{Name: "load", View: "system", Metric: "load1", Condition: ">= 10"},
+				{Name: "xact", View: "summary", Metric: "xact_max_time", Condition: "> 1h", Severity: "critical"},
+				{Name: "tps", View: "summary", Metric: "statements_per_second", Anomaly: &Anomaly{}},
+				{Name: "wal", Query: "SELECT pg_wal_lsn_diff(pg_current_wal_lsn(), '0/0') AS wal", Metric: "wal", Rate: true, Anomaly: &Anomaly{Direction: "up"}},
+			},
+			Receivers: []Receiver{
+				{Type: "webhook", URL: "http://127.0.0.1"}, {Type: "slack", URL: "http://127.0.0.1"}, {Type: "pagerduty", RoutingKey: "key"},
+			},
+		}},
+		{valid: false, config: Config{Interval: time.Millisecond}},
+		{valid: false, config: Config{Rules: []Rule{{View: "system", Metric: "load1", Condition: "> 1"}}}},
+		{valid: false, config: Config{Rules: []Rule{
+			{Name: "load", View: "system", Metric: "load1", Condition: "> 1"}, {Name: "load", View: "system", Metric: "load5", Condition: "> 1"},
+		}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", Metric: "m", Condition: "> 1"}}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", View: "databases", Query: "SELECT 1", Metric: "m", Condition: "> 1"}}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", View: "databases", Condition: "> 1"}}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", View: "databases", Metric: "m", Condition: "> 1", For: -time.Second}}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", View: "databases", Metric: "m", Condition: "> 1", Severity: "fatal"}}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", View: "databases", Metric: "m", Condition: "> 1", Unit: "PB"}}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", View: "databases", Metric: "m", Condition: "1"}}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", View: "system", Metric: "invalid", Condition: "> 1"}}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", View: "summary", Metric: "invalid", Condition: "> 1"}}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", View: "summary", Metric: "statements_per_second", Condition: "> 1", Anomaly: &Anomaly{}}}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", View: "summary", Metric: "statements_per_second", Anomaly: &Anomaly{Window: 1}}}}},
+		{valid: false, config: Config{Rules: []Rule{{Name: "r", View: "databases", Metric: "m", Condition: "> 1", Rate: true}}}},
+		{valid: false, config: Config{Receivers: []Receiver{{Type: "webhook"}}}},
+		{valid: false, config: Config{Receivers: []Receiver{{Type: "pagerduty"}}}},
+		{valid: false, config: Config{Receivers: []Receiver{{Type: "email", URL: "http://127.0.0.1"}}}},
+	}
+
+	for _, tc := range testcases {
+		err := tc.config.validate()
+		if tc.valid {
+			assert.NoError(t, err)
+			assert.Equal(t, defaultInterval, tc.config.Interval)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
+
+func TestRule_validate(t *testing.T) {
+	testcases := []struct {
+		rule      Rule
+		op        string
+		threshold float64
+		severity  string
+	}{
+		// Threshold in bytes is converted to units of metric.
+		{rule: Rule{View: "replication", Metric: "total_lag", Unit: "kB", Condition: "> 100MB"}, op: ">", threshold: 102400, severity: "warning"},
+		{rule: Rule{View: "replication", Metric: "total_lag", Condition: ">100MB"}, op: ">", threshold: 104857600, severity: "warning"},
+		// Units of system metrics are known.
+		{rule: Rule{View: "system", Metric: "mem_free", Condition: "< 1GB"}, op: "<", threshold: 1024, severity: "warning"},
+		{rule: Rule{Query: "SELECT 1", Metric: "age", Condition: ">= 1.5B", Severity: "critical"}, op: ">=", threshold: 1.5e9, severity: "critical"},
+		{rule: Rule{View: "summary", Metric: "xact_max_time", Condition: "> 5m"}, op: ">", threshold: 300, severity: "warning"},
+	}
+
+	for _, tc := range testcases {
+		assert.NoError(t, tc.rule.validate())
+		assert.Equal(t, tc.op, tc.rule.op)
+		assert.Equal(t, tc.threshold, tc.rule.threshold)
+		assert.Equal(t, tc.severity, tc.rule.Severity)
+	}
+}
+
+func Test_parseThreshold(t *testing.T) {
+	testcases := []struct {
+		valid bool
+		in    string
+		want  float64
+		bytes bool
+	}{
+		{valid: true, in: "100", want: 100},
+		{valid: true, in: "-0.5", want: -0.5},
+		{valid: true, in: "1e3", want: 1000},
+		{valid: true, in: "2K", want: 2000},
+		{valid: true, in: "3M", want: 3e6},
+		{valid: true, in: "1.5B", want: 1.5e9},
+		{valid: true, in: "10kB", want: 10240, bytes: true},
+		{valid: true, in: "1GB", want: 1 << 30, bytes: true},
+		{valid: true, in: "90s", want: 90},
+		{valid: true, in: "1h30m", want: 5400},
+		{valid: true, in: "500ms", want: 0.5},
+		{valid: false, in: "abc"},
+		{valid: false, in: "10PB"},
+		{valid: false, in: "1d"},
+	}
+
+	for _, tc := range testcases {
+		got, bytes, err := parseThreshold(tc.in)
+		if tc.valid {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.bytes, bytes)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
+
+func TestRule_compare(t *testing.T) {
+	testcases := []struct {
+		op   string
+		want [3]bool // results for values less, equal and greater than threshold
+	}{
+		{op: ">", want: [3]bool{false, false, true}},
+		{op: ">=", want: [3]bool{false, true, true}},
+		{op: "<", want: [3]bool{true, false, false}},
+		{op: "<=", want: [3]bool{true, true, false}},
+		{op: "==", want: [3]bool{false, true, false}},
+		{op: "!=", want: [3]bool{true, false, true}},
+	}
+
+	for _, tc := range testcases {
+		r := Rule{op: tc.op, threshold: 10}
+		assert.Equal(t, tc.want, [3]bool{r.compare(5), r.compare(10), r.compare(15)}, tc.op)
+	}
+}
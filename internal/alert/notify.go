@@ -0,0 +1,165 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// pagerDutyEventsURL defines default URL of PagerDuty Events API v2.
+	pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	// notifyTimeout defines timeout of sending a single notification.
+	notifyTimeout = 10 * time.Second
+)
+
+// Event defines notification about fired or resolved alert.
+type Event struct {
+	Status      string    `json:"status"` // firing or resolved
+	Rule        string    `json:"rule"`
+	Severity    string    `json:"severity"`
+	Description string    `json:"description,omitempty"`
+	Instance    string    `json:"instance"`
+	Labels      []Label   `json:"labels"`
+	Value       float64   `json:"value"`
+	Condition   string    `json:"condition"`
+	Deviation   float64   `json:"deviation,omitempty"` // deviation from the mean in standard deviations, for anomalies
+	Since       time.Time `json:"since"`               // when condition became true
+	Time        time.Time `json:"time"`
+}
+
+// Summary returns human-readable one-line summary of the event.
+func (e Event) Summary() string {
+	var labels string
+	if len(e.Labels) > 0 {
+		labels = " (" + labelsString(e.Labels) + ")"
+	}
+	return fmt.Sprintf("[%s] %s on %s%s: value %s, condition %s", strings.ToUpper(e.Status), e.Rule, e.Instance, labels, formatValue(e.Value), e.Condition)
+}
+
+// dedupKey returns key which identifies alert, it is the same for firing and resolved events.
+func (e Event) dedupKey() string {
+	h := sha256.Sum256([]byte(e.Rule + "\x00" + e.Instance + "\x00" + labelsString(e.Labels)))
+	return hex.EncodeToString(h[:16])
+}
+
+// notifier sends notifications about events.
+type notifier interface {
+	notify(ctx context.Context, e Event) error
+}
+
+// newNotifier creates notifier for specified receiver.
+func newNotifier(r Receiver) notifier {
+	switch r.Type {
+	case "slack":
+		return &slackNotifier{url: r.URL}
+	case "pagerduty":
+		url := r.URL
+		if url == "" {
+			url = pagerDutyEventsURL
+		}
+		return &pagerDutyNotifier{url: url, routingKey: r.RoutingKey}
+	default:
+		return &webhookNotifier{url: r.URL, headers: r.Headers}
+	}
+}
+
+// webhookNotifier sends events as JSON to webhook.
+type webhookNotifier struct {
+	url     string
+	headers map[string]string
+}
+
+// notify implements notifier interface.
+func (n *webhookNotifier) notify(ctx context.Context, e Event) error {
+	return postJSON(ctx, n.url, n.headers, e)
+}
+
+// slackNotifier sends events as messages to Slack incoming webhook.
+type slackNotifier struct {
+	url string
+}
+
+// notify implements notifier interface.
+func (n *slackNotifier) notify(ctx context.Context, e Event) error {
+	icon := ":rotating_light:"
+	if e.Status == StatusResolved {
+		icon = ":white_check_mark:"
+	}
+
+	text := icon + " " + e.Summary()
+	if e.Description != "" {
+		text += "\n" + e.Description
+	}
+
+	return postJSON(ctx, n.url, nil, map[string]string{"text": text})
+}
+
+// pagerDutyNotifier sends events to PagerDuty Events API v2. Firing and resolved events of the same alert are
+// deduplicated using the same key, hence PagerDuty incident is resolved automatically.
+type pagerDutyNotifier struct {
+	url        string
+	routingKey string
+}
+
+// notify implements notifier interface.
+func (n *pagerDutyNotifier) notify(ctx context.Context, e Event) error {
+	action := "trigger"
+	if e.Status == StatusResolved {
+		action = "resolve"
+	}
+
+	payload := map[string]interface{}{
+		"routing_key":  n.routingKey,
+		"event_action": action,
+		"dedup_key":    e.dedupKey(),
+		"payload": map[string]interface{}{
+			"summary":        e.Summary(),
+			"source":         e.Instance,
+			"severity":       e.Severity,
+			"timestamp":      e.Time.Format(time.RFC3339),
+			"component":      "postgres",
+			"class":          e.Rule,
+			"custom_details": e,
+		},
+	}
+
+	return postJSON(ctx, n.url, nil, payload)
+}
+
+// postJSON sends data as JSON in POST request and checks response status.
+func postJSON(ctx context.Context, url string, headers map[string]string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s responded %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testEvent returns event used in tests.
+func testEvent(status string) Event {
+	ts := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	return Event{
+		Status: status, Rule: "replication_lag", Severity: "critical", Instance: "127.0.0.1:5432/postgres",
+		Labels: []Label{{"client_addr", "10.0.0.2"}}, Value: 150, Condition: "> 100MB", Since: ts, Time: ts.Add(2 * time.Minute),
+	}
+}
+
+// receive runs test server, sends event using notifier created for receiver and returns received request body.
+func receive(t *testing.T, r Receiver, e Event, status int) (map[string]interface{}, http.Header, error) {
+	var body map[string]interface{}
+	var header http.Header
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		data, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(data, &body))
+		header = req.Header
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	r.URL = ts.URL
+	err := newNotifier(r).notify(context.Background(), e)
+	return body, header, err
+}
+
+func Test_webhookNotifier(t *testing.T) {
+	body, header, err := receive(t, Receiver{Type: "webhook", Headers: map[string]string{"X-Token": "secret"}}, testEvent(StatusFiring), http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", header.Get("X-Token"))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "firing", body["status"])
+	assert.Equal(t, "replication_lag", body["rule"])
+	assert.Equal(t, float64(150), body["value"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "client_addr", "value": "10.0.0.2"}}, body["labels"])
+
+	// Unsuccessful response.
+	_, _, err = receive(t, Receiver{Type: "webhook"}, testEvent(StatusFiring), http.StatusInternalServerError)
+	assert.Error(t, err)
+}
+
+func Test_slackNotifier(t *testing.T) {
+	body, _, err := receive(t, Receiver{Type: "slack"}, testEvent(StatusFiring), http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, ":rotating_light: [FIRING] replication_lag on 127.0.0.1:5432/postgres (client_addr=10.0.0.2): value 150, condition > 100MB", body["text"])
+
+	body, _, err = receive(t, Receiver{Type: "slack"}, testEvent(StatusResolved), http.StatusOK)
+	assert.NoError(t, err)
+	assert.Contains(t, body["text"], ":white_check_mark: [RESOLVED]")
+}
+
+func Test_pagerDutyNotifier(t *testing.T) {
+	firing, _, err := receive(t, Receiver{Type: "pagerduty", RoutingKey: "key"}, testEvent(StatusFiring), http.StatusAccepted)
+	assert.NoError(t, err)
+	assert.Equal(t, "key", firing["routing_key"])
+	assert.Equal(t, "trigger", firing["event_action"])
+	assert.Equal(t, "critical", firing["payload"].(map[string]interface{})["severity"])
+
+	resolved, _, err := receive(t, Receiver{Type: "pagerduty", RoutingKey: "key"}, testEvent(StatusResolved), http.StatusAccepted)
+	assert.NoError(t, err)
+	assert.Equal(t, "resolve", resolved["event_action"])
+
+	// Firing and resolved events of the same alert are deduplicated.
+	assert.Equal(t, firing["dedup_key"], resolved["dedup_key"])
+	assert.NotEqual(t, testEvent(StatusFiring).dedupKey(), Event{Rule: "other"}.dedupKey())
+}
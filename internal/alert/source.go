@@ -0,0 +1,217 @@
+package alert
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// systemMetricUnits defines names of system metrics and units of their values. Metrics of disks and network
+// interfaces are labeled by device and interface names.
+var systemMetricUnits = map[string]string{
+	"load1": "B", "load5": "B", "load15": "B",
+	"cpu_user": "B", "cpu_nice": "B", "cpu_system": "B", "cpu_idle": "B",
+	"cpu_iowait": "B", "cpu_irq": "B", "cpu_softirq": "B", "cpu_steal": "B",
+	"mem_total": "MB", "mem_free": "MB", "mem_used": "MB", "mem_cached": "MB", "mem_buffers": "MB",
+	"mem_dirty": "MB", "mem_writeback": "MB", "mem_slab": "MB", "swap_total": "MB", "swap_free": "MB", "swap_used": "MB",
+	"disk_utilization": "B", "disk_await_ms": "B", "disk_queue_size": "B",
+	"network_utilization": "B", "network_errors_per_second": "B",
+}
+
+// summaryMetrics defines names of summary activity metrics. Durations are in seconds.
+var summaryMetrics = []string{
+	"connections_total", "connections_idle", "connections_idle_xact", "connections_active", "connections_waiting",
+	"connections_other", "prepared_transactions", "autovacuums", "antiwraparound_vacuums", "user_vacuums",
+	"statements_per_second", "statements_avg_time_ms", "xact_max_time", "prepared_max_time", "vacuum_max_time",
+	"recovery",
+}
+
+// intervalRE defines Postgres interval in text format, e.g. '1 day 02:03:04.5' or '00:00:10'.
+var intervalRE = regexp.MustCompile(`^(-)?(?:(\d+) days? ?)?(?:(-)?(\d+):(\d{2}):(\d{2}(?:\.\d+)?))?$`)
+
+// Label defines name and value of alert's label.
+type Label struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// sample defines value of metric with particular labels.
+type sample struct {
+	labels []Label
+	value  float64
+}
+
+// systemSamples returns samples of system metric.
+func systemSamples(s stat.System, metric string) []sample {
+	values := map[string]float64{
+		"load1": s.LoadAvg.One, "load5": s.LoadAvg.Five, "load15": s.LoadAvg.Fifteen,
+		"cpu_user": s.CpuStat.User, "cpu_nice": s.CpuStat.Nice, "cpu_system": s.CpuStat.Sys, "cpu_idle": s.CpuStat.Idle,
+		"cpu_iowait": s.CpuStat.Iowait, "cpu_irq": s.CpuStat.Irq, "cpu_softirq": s.CpuStat.Softirq, "cpu_steal": s.CpuStat.Steal,
+		"mem_total": float64(s.Meminfo.MemTotal), "mem_free": float64(s.Meminfo.MemFree), "mem_used": float64(s.Meminfo.MemUsed),
+		"mem_cached": float64(s.Meminfo.MemCached), "mem_buffers": float64(s.Meminfo.MemBuffers),
+		"mem_dirty": float64(s.Meminfo.MemDirty), "mem_writeback": float64(s.Meminfo.MemWriteback), "mem_slab": float64(s.Meminfo.MemSlab),
+		"swap_total": float64(s.Meminfo.SwapTotal), "swap_free": float64(s.Meminfo.SwapFree), "swap_used": float64(s.Meminfo.SwapUsed),
+	}
+
+	if v, ok := values[metric]; ok {
+		return []sample{{value: v}}
+	}
+
+	var samples []sample
+	switch {
+	case strings.HasPrefix(metric, "disk_"):
+		for _, d := range s.Diskstats {
+			// Inactive devices are skipped by stats collector.
+			if d.Device == "" {
+				continue
+			}
+			v := map[string]float64{"disk_utilization": d.Util, "disk_await_ms": d.Await, "disk_queue_size": d.Tweighted}[metric]
+			samples = append(samples, sample{labels: []Label{{"device", d.Device}}, value: v})
+		}
+	case strings.HasPrefix(metric, "network_"):
+		for _, n := range s.Netdevs {
+			// Inactive interfaces are skipped by stats collector.
+			if n.Ifname == "" {
+				continue
+			}
+			v := map[string]float64{"network_utilization": n.Utilization, "network_errors_per_second": n.Rerrs + n.Terrs}[metric]
+			samples = append(samples, sample{labels: []Label{{"interface", n.Ifname}}, value: v})
+		}
+	}
+
+	return samples
+}
+
+// summarySamples returns sample of summary activity metric.
+func summarySamples(a stat.Activity, metric string) ([]sample, error) {
+	var value float64
+	switch metric {
+	case "connections_total":
+		value = float64(a.ConnTotal)
+	case "connections_idle":
+		value = float64(a.ConnIdle)
+	case "connections_idle_xact":
+		value = float64(a.ConnIdleXact)
+	case "connections_active":
+		value = float64(a.ConnActive)
+	case "connections_waiting":
+		value = float64(a.ConnWaiting)
+	case "connections_other":
+		value = float64(a.ConnOthers)
+	case "prepared_transactions":
+		value = float64(a.ConnPrepared)
+	case "autovacuums":
+		value = float64(a.AVWorkers)
+	case "antiwraparound_vacuums":
+		value = float64(a.AVAntiwrap)
+	case "user_vacuums":
+		value = float64(a.AVUser)
+	case "statements_per_second":
+		value = float64(a.CallsRate)
+	case "statements_avg_time_ms":
+		value = float64(a.StmtAvgTime)
+	case "recovery":
+		if strings.HasPrefix(a.Recovery, "t") {
+			value = 1
+		}
+	case "xact_max_time", "prepared_max_time", "vacuum_max_time":
+		s := map[string]string{"xact_max_time": a.XactMaxTime, "prepared_max_time": a.PrepMaxTime, "vacuum_max_time": a.AVMaxTime}[metric]
+		v, err := parseValue(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %s", metric, err)
+		}
+		value = v
+	default:
+		return nil, fmt.Errorf("unknown summary metric '%s'", metric)
+	}
+
+	return []sample{{value: value}}, nil
+}
+
+// resultSamples returns samples made of rows of view or query result. Rows are labeled by values of label columns, by
+// default all columns except metric are used as labels. NULL values are skipped.
+func resultSamples(res stat.PGresult, metric string, labelCols []string) ([]sample, error) {
+	colIdx := map[string]int{}
+	for i, col := range res.Cols {
+		colIdx[col] = i
+	}
+
+	idx, ok := colIdx[metric]
+	if !ok {
+		return nil, fmt.Errorf("column '%s' not found", metric)
+	}
+
+	if labelCols == nil {
+		for _, col := range res.Cols {
+			if col != metric {
+				labelCols = append(labelCols, col)
+			}
+		}
+	}
+
+	labelIdx := make([]int, len(labelCols))
+	for i, col := range labelCols {
+		j, ok := colIdx[col]
+		if !ok {
+			return nil, fmt.Errorf("label column '%s' not found", col)
+		}
+		labelIdx[i] = j
+	}
+
+	samples := make([]sample, 0, len(res.Values))
+	for _, row := range res.Values {
+		if !row[idx].Valid {
+			continue
+		}
+
+		value, err := parseValue(row[idx].String)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of '%s': %s", metric, err)
+		}
+
+		labels := make([]Label, len(labelIdx))
+		for i, j := range labelIdx {
+			labels[i] = Label{Name: labelCols[i], Value: row[j].String}
+		}
+
+		samples = append(samples, sample{labels: labels, value: value})
+	}
+
+	return samples, nil
+}
+
+// parseValue parses numeric value, or Postgres interval which is converted to seconds.
+func parseValue(s string) (float64, error) {
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+
+	m := intervalRE.FindStringSubmatch(s)
+	if m == nil || (m[2] == "" && m[4] == "") {
+		return 0, fmt.Errorf("'%s' is neither a number nor an interval", s)
+	}
+
+	// Sign of days and sign of time part are independent, e.g. '-1 days -02:00:00'.
+	var days, hours, minutes, seconds float64
+	if m[2] != "" {
+		days, _ = strconv.ParseFloat(m[2], 64)
+		if m[1] == "-" {
+			days = -days
+		}
+	}
+	if m[4] != "" {
+		hours, _ = strconv.ParseFloat(m[4], 64)
+		minutes, _ = strconv.ParseFloat(m[5], 64)
+		seconds, _ = strconv.ParseFloat(m[6], 64)
+	}
+
+	t := hours*3600 + minutes*60 + seconds
+	if m[3] == "-" || (m[2] == "" && m[1] == "-") {
+		t = -t
+	}
+	t += days * 86400
+
+	return t, nil
+}
@@ -0,0 +1,121 @@
+package alert
+
+import (
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_systemSamples(t *testing.T) {
+	s := stat.System{
+		LoadAvg: stat.LoadAvg{One: 1.5},
+		Meminfo: stat.Meminfo{MemFree: 512},
+		Diskstats: stat.Diskstats{
+			{Device: "sda", Util: 95}, {}, {Device: "sdb", Util: 5},
+		},
+		Netdevs: stat.Netdevs{
+			{Ifname: "eth0", Rerrs: 1, Terrs: 2},
+		},
+	}
+
+	assert.Equal(t, []sample{{value: 1.5}}, systemSamples(s, "load1"))
+	assert.Equal(t, []sample{{value: 512}}, systemSamples(s, "mem_free"))
+	assert.Equal(t, []sample{
+		{labels: []Label{{"device", "sda"}}, value: 95}, {labels: []Label{{"device", "sdb"}}, value: 5},
+	}, systemSamples(s, "disk_utilization"))
+	assert.Equal(t, []sample{{labels: []Label{{"interface", "eth0"}}, value: 3}}, systemSamples(s, "network_errors_per_second"))
+}
+
+func Test_summarySamples(t *testing.T) {
+	a := stat.Activity{ConnTotal: 10, ConnWaiting: 2, XactMaxTime: "01:00:05", Recovery: "t", StmtAvgTime: 1.5}
+
+	testcases := []struct {
+		metric string
+		want   float64
+	}{
+		{metric: "connections_total", want: 10},
+		{metric: "connections_waiting", want: 2},
+		{metric: "xact_max_time", want: 3605},
+		{metric: "recovery", want: 1},
+		{metric: "statements_avg_time_ms", want: 1.5},
+	}
+
+	for _, tc := range testcases {
+		got, err := summarySamples(a, tc.metric)
+		assert.NoError(t, err)
+		assert.Equal(t, []sample{{value: tc.want}}, got)
+	}
+
+	// Every known metric is supported.
+	for _, m := range summaryMetrics {
+		_, err := summarySamples(stat.Activity{XactMaxTime: "00:00:00", PrepMaxTime: "00:00:00", AVMaxTime: "00:00:00"}, m)
+		assert.NoError(t, err, m)
+	}
+
+	_, err := summarySamples(a, "invalid")
+	assert.Error(t, err)
+}
+
+func Test_resultSamples(t *testing.T) {
+	res := stat.PGresult{
+		Valid: true, Ncols: 3, Nrows: 3,
+		Cols: []string{"datname", "schema", "age"},
+		Values: [][]sql.NullString{
+			{{String: "db1", Valid: true}, {String: "public", Valid: true}, {String: "100", Valid: true}},
+			{{String: "db2", Valid: true}, {String: "public", Valid: true}, {String: "", Valid: false}},
+			{{String: "db3", Valid: true}, {String: "public", Valid: true}, {String: "00:01:00", Valid: true}},
+		},
+	}
+
+	got, err := resultSamples(res, "age", []string{"datname"})
+	assert.NoError(t, err)
+	assert.Equal(t, []sample{
+		{labels: []Label{{"datname", "db1"}}, value: 100},
+		{labels: []Label{{"datname", "db3"}}, value: 60},
+	}, got)
+
+	// All other columns are used as labels by default.
+	got, err = resultSamples(res, "age", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Label{{"datname", "db1"}, {"schema", "public"}}, got[0].labels)
+
+	_, err = resultSamples(res, "invalid", nil)
+	assert.Error(t, err)
+	_, err = resultSamples(res, "age", []string{"invalid"})
+	assert.Error(t, err)
+	_, err = resultSamples(res, "datname", []string{"schema"})
+	assert.Error(t, err)
+}
+
+func Test_parseValue(t *testing.T) {
+	testcases := []struct {
+		valid bool
+		in    string
+		want  float64
+	}{
+		{valid: true, in: "123", want: 123},
+		{valid: true, in: "-1.5", want: -1.5},
+		{valid: true, in: "00:00:05", want: 5},
+		{valid: true, in: "00:00:05.5", want: 5.5},
+		{valid: true, in: "-00:00:05", want: -5},
+		{valid: true, in: "01:02:03", want: 3723},
+		{valid: true, in: "1 day", want: 86400},
+		{valid: true, in: "2 days 00:00:10", want: 172810},
+		{valid: true, in: "-1 days -02:00:00", want: -93600},
+		{valid: true, in: "1 day -01:00:00", want: 82800},
+		{valid: false, in: ""},
+		{valid: false, in: "abc"},
+		{valid: false, in: "1 month"},
+	}
+
+	for _, tc := range testcases {
+		got, err := parseValue(tc.in)
+		if tc.valid {
+			assert.NoError(t, err, tc.in)
+			assert.Equal(t, tc.want, got, tc.in)
+		} else {
+			assert.Error(t, err, tc.in)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package audit implements audit log of actions which change state of Postgres, e.g. cancelled queries, terminated
+// backends or reloaded configuration. Records are appended to local file as JSON documents, one per line, hence the
+// file could be reviewed later or shipped to external log storages.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultFilename defines name of audit file created in user's home directory.
+const defaultFilename = ".pgcenter_audit.log"
+
+// Audited actions.
+const (
+	ActionCancel     = "cancel"
+	ActionTerminate  = "terminate"
+	ActionResetStats = "reset_stats"
+	ActionReload     = "reload_config"
+	ActionEditConfig = "edit_config"
+)
+
+// resultOK defines result of successful action.
+const resultOK = "ok"
+
+// Record defines action written into audit log.
+type Record struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	Instance string    `json:"instance,omitempty"` // Postgres instance where action has been performed
+	User     string    `json:"user,omitempty"`     // connected role
+	Role     string    `json:"role,omitempty"`     // role set at runtime using SET ROLE, empty if not set
+	Target   string    `json:"target,omitempty"`   // target of action, e.g. pid of backend or configuration file
+	Result   string    `json:"result"`             // 'ok' or error message
+}
+
+// NewRecord creates record of action performed at the current time. Error means action has failed.
+func NewRecord(action string, target string, err error) Record {
+	r := Record{Time: time.Now(), Action: action, Target: target, Result: resultOK}
+	if err != nil {
+		r.Result = err.Error()
+	}
+	return r
+}
+
+// Log appends records to audit file. File is created at the first record, hence no file is created if no actions
+// performed. Nil log is valid and does nothing.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewLog creates audit log written into specified file, if filename is not specified ~/.pgcenter_audit.log is used.
+func NewLog(filename string) (*Log, error) {
+	if filename == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("get audit file location failed: %s", err)
+		}
+		filename = filepath.Join(home, defaultFilename)
+	}
+
+	return &Log{path: filename}, nil
+}
+
+// Path returns location of audit file.
+func (l *Log) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// Add appends record to audit file.
+func (l *Log) Add(r Record) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		f, err := os.OpenFile(filepath.Clean(l.path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("open audit file failed: %s", err)
+		}
+		l.file = f
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("marshal audit record failed: %s", err)
+	}
+
+	_, err = l.file.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("write audit file failed: %s", err)
+	}
+
+	return nil
+}
+
+// Close closes audit file.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Read returns the last records of audit file, not more than limit. Not existing file has no records. Lines which
+// can't be parsed are skipped.
+func Read(filename string, limit int) ([]Record, error) {
+	f, err := os.Open(filepath.Clean(filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open audit file failed: %s", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+
+		records = append(records, r)
+		if limit > 0 && len(records) > limit {
+			records = records[1:]
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit file failed: %s", err)
+	}
+
+	return records, nil
+}
+
+// Format returns records in human-readable form, one record per line.
+func Format(records []Record) string {
+	var b strings.Builder
+	for _, r := range records {
+		user := r.User
+		if r.Role != "" {
+			user += " as " + r.Role
+		}
+
+		result := r.Result
+		if r.Target != "" {
+			result = r.Target + ": " + result
+		}
+
+		fmt.Fprintf(&b, "%s %-14s %-24s %-20s %s\n",
+			r.Time.Local().Format("2006-01-02 15:04:05"), r.Action, r.Instance, user, result)
+	}
+	return b.String()
+}
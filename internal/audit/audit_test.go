@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewRecord(t *testing.T) {
+	r := NewRecord(ActionCancel, "pid 123", nil)
+	assert.Equal(t, ActionCancel, r.Action)
+	assert.Equal(t, "pid 123", r.Target)
+	assert.Equal(t, "ok", r.Result)
+	assert.False(t, r.Time.IsZero())
+
+	r = NewRecord(ActionReload, "", fmt.Errorf("permission denied"))
+	assert.Equal(t, "permission denied", r.Result)
+}
+
+func TestNewLog(t *testing.T) {
+	l, err := NewLog("/tmp/audit.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/audit.log", l.Path())
+
+	l, err = NewLog("")
+	assert.NoError(t, err)
+	assert.Equal(t, defaultFilename, filepath.Base(l.Path()))
+
+	// Nil log does nothing.
+	var nl *Log
+	assert.Equal(t, "", nl.Path())
+	assert.NoError(t, nl.Add(NewRecord(ActionCancel, "pid 123", nil)))
+	assert.NoError(t, nl.Close())
+}
+
+func TestLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-audit-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	filename := filepath.Join(dir, "audit.log")
+	l, err := NewLog(filename)
+	assert.NoError(t, err)
+
+	// File is not created until the first record.
+	assert.NoError(t, l.Close())
+	_, err = os.Stat(filename)
+	assert.True(t, os.IsNotExist(err))
+
+	got, err := Read(filename, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	ts := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Time: ts, Action: ActionCancel, Instance: "127.0.0.1:5432/postgres", User: "postgres", Target: "pid 123", Result: "ok"},
+		{Time: ts, Action: ActionTerminate, Instance: "127.0.0.1:5432/postgres", User: "postgres", Role: "admin", Target: "group idle", Result: "ok"},
+		{Time: ts, Action: ActionReload, Instance: "127.0.0.1:5432/postgres", User: "postgres", Result: "permission denied"},
+	}
+	for _, r := range records {
+		assert.NoError(t, l.Add(r))
+	}
+	assert.NoError(t, l.Close())
+
+	info, err := os.Stat(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Records are appended to existing file, garbage lines are skipped.
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0600)
+	assert.NoError(t, err)
+	_, err = f.WriteString("invalid\n")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	l, err = NewLog(filename)
+	assert.NoError(t, err)
+	assert.NoError(t, l.Add(records[0]))
+	assert.NoError(t, l.Close())
+
+	got, err = Read(filename, 0)
+	assert.NoError(t, err)
+	assert.Len(t, got, 4)
+	for i, r := range got {
+		assert.True(t, ts.Equal(r.Time))
+		r.Time = ts
+		assert.Equal(t, records[i%3], r)
+	}
+
+	// Only the last records are returned.
+	got, err = Read(filename, 2)
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, ActionReload, got[0].Action)
+	assert.Equal(t, ActionCancel, got[1].Action)
+
+	// Unwritable file.
+	l, err = NewLog(filepath.Join(dir, "unknown", "audit.log"))
+	assert.NoError(t, err)
+	assert.Error(t, l.Add(records[0]))
+}
+
+func TestFormat(t *testing.T) {
+	ts := time.Date(2021, 3, 1, 12, 0, 0, 0, time.Local)
+	got := Format([]Record{
+		{Time: ts, Action: ActionCancel, Instance: "127.0.0.1:5432/postgres", User: "postgres", Target: "pid 123", Result: "ok"},
+		{Time: ts, Action: ActionResetStats, Instance: "127.0.0.1:5432/postgres", User: "postgres", Role: "admin", Result: "ok"},
+	})
+
+	assert.Equal(t,
+		"2021-03-01 12:00:00 cancel         127.0.0.1:5432/postgres  postgres             pid 123: ok\n"+
+			"2021-03-01 12:00:00 reset_stats    127.0.0.1:5432/postgres  postgres as admin    ok\n",
+		got,
+	)
+}
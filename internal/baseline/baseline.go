@@ -0,0 +1,223 @@
+// Package baseline implements capturing stats rates considered as normal and comparing live stats with them.
+package baseline
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Column defines name of column with change of values relative to baseline.
+	Column = "vs_base"
+	// DefaultThreshold defines default growth of values relative to baseline (in percents) considered as regression.
+	DefaultThreshold = 50
+
+	// valueNew defines value of rows which are not present in baseline.
+	valueNew = "new"
+)
+
+// Baseline defines per-second rates of stats views collected during an interval considered as normal.
+type Baseline struct {
+	Source string                   `json:"source"` // instance or stats file the baseline is derived from
+	Start  time.Time                `json:"start"`  // start of the interval
+	End    time.Time                `json:"end"`    // end of the interval
+	Views  map[string]stat.PGresult `json:"views"`  // rates of views keyed by view name
+}
+
+// snapshot defines stats of a view collected at particular time.
+type snapshot struct {
+	ts  time.Time
+	res stat.PGresult
+}
+
+// Builder accumulates stats snapshots and builds baseline using the first and the last snapshots of every view.
+type Builder struct {
+	first map[string]snapshot
+	last  map[string]snapshot
+}
+
+// NewBuilder creates new baseline builder.
+func NewBuilder() *Builder {
+	return &Builder{first: map[string]snapshot{}, last: map[string]snapshot{}}
+}
+
+// Add adds stats snapshot of the view.
+func (b *Builder) Add(name string, ts time.Time, res stat.PGresult) {
+	if _, ok := b.first[name]; !ok {
+		b.first[name] = snapshot{ts: ts, res: res}
+		return
+	}
+	b.last[name] = snapshot{ts: ts, res: res}
+}
+
+// Build calculates per-second rates of views between the first and the last snapshots. Views without rates (e.g.
+// activity) and views with a single snapshot are skipped.
+func (b *Builder) Build(source string, views view.Views) (Baseline, error) {
+	base := Baseline{Source: source, Views: map[string]stat.PGresult{}}
+
+	for name, last := range b.last {
+		v, ok := views[name]
+		if !ok || v.DiffIntvl == [2]int{0, 0} {
+			continue
+		}
+
+		first := b.first[name]
+		itv := int(last.ts.Sub(first.ts) / time.Second)
+		if itv < 1 {
+			continue
+		}
+
+		res, err := stat.Compare(last.res, first.res, itv, v)
+		if err != nil {
+			return Baseline{}, fmt.Errorf("calculate rates of %s failed: %s", name, err)
+		}
+
+		base.Views[name] = res
+
+		if base.Start.IsZero() || first.ts.Before(base.Start) {
+			base.Start = first.ts
+		}
+		if last.ts.After(base.End) {
+			base.End = last.ts
+		}
+	}
+
+	if len(base.Views) == 0 {
+		return Baseline{}, fmt.Errorf("not enough stats for baseline, at least two snapshots of views with rates are required")
+	}
+
+	return base, nil
+}
+
+// Load reads baseline from file.
+func Load(filename string) (*Baseline, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	var b Baseline
+	err = json.Unmarshal(data, &b)
+	if err != nil {
+		return nil, fmt.Errorf("parse baseline %s failed: %s", filename, err)
+	}
+
+	return &b, nil
+}
+
+// Save writes baseline into file.
+func (b Baseline) Save(filename string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, data, 0600)
+}
+
+// Compare returns stats of the view with appended column of changes relative to baseline. Change is calculated for
+// column with index col, rows are matched by value of unique key column ukey. Change is shown in percents, rows absent
+// in baseline are marked as 'new'. Stats are returned as-is if there is no baseline of the view.
+func (b *Baseline) Compare(name string, res stat.PGresult, col, ukey int) stat.PGresult {
+	base, ok := b.Views[name]
+	if !ok || col >= len(res.Cols) || ukey >= len(res.Cols) {
+		return res
+	}
+
+	baseCol, ok1 := columnIndex(base.Cols, res.Cols[col])
+	baseKey, ok2 := columnIndex(base.Cols, res.Cols[ukey])
+	if !ok1 || !ok2 {
+		return res
+	}
+
+	rates := make(map[string]string, len(base.Values))
+	for _, row := range base.Values {
+		if baseKey < len(row) && baseCol < len(row) {
+			rates[row[baseKey].String] = row[baseCol].String
+		}
+	}
+
+	values := make([][]sql.NullString, len(res.Values))
+	for i, row := range res.Values {
+		values[i] = make([]sql.NullString, len(row), len(row)+1)
+		copy(values[i], row)
+
+		var change string
+		if col < len(row) && ukey < len(row) {
+			baseValue, found := rates[row[ukey].String]
+			change = formatChange(row[col].String, baseValue, found)
+		}
+
+		values[i] = append(values[i], sql.NullString{String: change, Valid: change != ""})
+	}
+
+	res.Values = values
+	res.Cols = append(append([]string{}, res.Cols...), Column)
+	res.Ncols = len(res.Cols)
+
+	return res
+}
+
+// formatChange returns change of current value relative to baseline value in percents. Empty string is returned for
+// non-numeric values.
+func formatChange(curr, base string, found bool) string {
+	c, err := strconv.ParseFloat(curr, 64)
+	if err != nil {
+		return ""
+	}
+
+	if !found {
+		if c == 0 {
+			return ""
+		}
+		return valueNew
+	}
+
+	b, err := strconv.ParseFloat(base, 64)
+	if err != nil {
+		return ""
+	}
+
+	switch {
+	case b == 0 && c == 0:
+		return "0%"
+	case b == 0:
+		return valueNew
+	}
+
+	return fmt.Sprintf("%+.0f%%", (c-b)/math.Abs(b)*100)
+}
+
+// IsRegression returns true if change relative to baseline reaches threshold (in percents), or row is absent in
+// baseline.
+func IsRegression(change string, threshold float64) bool {
+	if change == valueNew {
+		return true
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimSuffix(change, "%"), 64)
+	if err != nil {
+		return false
+	}
+
+	return v >= threshold
+}
+
+// columnIndex returns index of column with specified name.
+func columnIndex(cols []string, name string) (int, bool) {
+	for i, c := range cols {
+		if c == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
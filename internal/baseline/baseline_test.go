@@ -0,0 +1,129 @@
+package baseline
+
+import (
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newResult creates stats of 'databases'-like view with specified names and values of commits.
+func newResult(rows ...[2]string) stat.PGresult {
+	res := stat.PGresult{Cols: []string{"datname", "commits"}, Ncols: 2, Nrows: len(rows), Valid: true}
+	for _, r := range rows {
+		res.Values = append(res.Values, []sql.NullString{{String: r[0], Valid: true}, {String: r[1], Valid: true}})
+	}
+	return res
+}
+
+func TestBuilder_Build(t *testing.T) {
+	views := view.Views{
+		"databases": {Name: "databases", DiffIntvl: [2]int{1, 1}, OrderDesc: true},
+		"activity":  {Name: "activity"},
+	}
+
+	ts := time.Date(2021, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	b := NewBuilder()
+	b.Add("databases", ts, newResult([2]string{"pgbench", "100"}, [2]string{"postgres", "10"}))
+	b.Add("activity", ts, newResult([2]string{"pgbench", "1"}))
+	b.Add("databases", ts.Add(5*time.Second), newResult([2]string{"pgbench", "150"}, [2]string{"postgres", "10"}))
+	b.Add("databases", ts.Add(10*time.Second), newResult([2]string{"pgbench", "200"}, [2]string{"postgres", "20"}))
+	b.Add("activity", ts.Add(10*time.Second), newResult([2]string{"pgbench", "2"}))
+
+	got, err := b.Build("stats.tar", views)
+	assert.NoError(t, err)
+	assert.Equal(t, "stats.tar", got.Source)
+	assert.Equal(t, ts, got.Start)
+	assert.Equal(t, ts.Add(10*time.Second), got.End)
+	assert.Len(t, got.Views, 1)
+	// Rows are ordered using order of the view.
+	assert.Equal(t, newResult([2]string{"postgres", "1"}, [2]string{"pgbench", "10"}).Values, got.Views["databases"].Values)
+
+	// Not enough snapshots.
+	b = NewBuilder()
+	b.Add("databases", ts, newResult([2]string{"pgbench", "100"}))
+	_, err = b.Build("stats.tar", views)
+	assert.Error(t, err)
+}
+
+func TestBaseline_SaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-baseline-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	want := Baseline{
+		Source: "127.0.0.1:5432/postgres",
+		Start:  time.Date(2021, 10, 15, 12, 0, 0, 0, time.UTC),
+		End:    time.Date(2021, 10, 15, 12, 1, 0, 0, time.UTC),
+		Views:  map[string]stat.PGresult{"databases": newResult([2]string{"pgbench", "10"})},
+	}
+
+	filename := filepath.Join(dir, "baseline.json")
+	assert.NoError(t, want.Save(filename))
+
+	got, err := Load(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, want, *got)
+
+	_, err = Load(filepath.Join(dir, "not-exists.json"))
+	assert.Error(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(filename, []byte("invalid"), 0600))
+	_, err = Load(filename)
+	assert.Error(t, err)
+}
+
+func TestBaseline_Compare(t *testing.T) {
+	b := &Baseline{Views: map[string]stat.PGresult{
+		"databases": newResult([2]string{"pgbench", "10"}, [2]string{"postgres", "0"}, [2]string{"template1", "4"}),
+	}}
+
+	res := newResult([2]string{"pgbench", "25"}, [2]string{"postgres", "0"}, [2]string{"template1", "3"}, [2]string{"orders", "5"}, [2]string{"idle", "0"})
+	got := b.Compare("databases", res, 1, 0)
+
+	assert.Equal(t, []string{"datname", "commits", Column}, got.Cols)
+	assert.Equal(t, 3, got.Ncols)
+
+	var changes []string
+	for _, row := range got.Values {
+		changes = append(changes, row[2].String)
+	}
+	assert.Equal(t, []string{"+150%", "0%", "-25%", "new", ""}, changes)
+
+	// Source stats are not modified.
+	assert.Len(t, res.Cols, 2)
+	assert.Len(t, res.Values[0], 2)
+
+	// Non-numeric column.
+	got = b.Compare("databases", res, 0, 0)
+	assert.Equal(t, "", got.Values[0][2].String)
+	assert.False(t, got.Values[0][2].Valid)
+
+	// Views without baseline are returned as-is.
+	assert.Equal(t, res, b.Compare("tables", res, 1, 0))
+}
+
+func TestIsRegression(t *testing.T) {
+	testcases := []struct {
+		change string
+		want   bool
+	}{
+		{change: "+150%", want: true},
+		{change: "+50%", want: true},
+		{change: "+49%", want: false},
+		{change: "-80%", want: false},
+		{change: "0%", want: false},
+		{change: "new", want: true},
+		{change: "", want: false},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, IsRegression(tc.change, DefaultThreshold), tc.change)
+	}
+}
@@ -0,0 +1,266 @@
+// Package completion implements completion of commands, flags and their values in shells.
+package completion
+
+import (
+	"bufio"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// annotationValues defines flag annotation with static list of values.
+	annotationValues = "pgcenter_completion_values"
+	// annotationKind defines flag annotation with kind of dynamic values.
+	annotationKind = "pgcenter_completion_kind"
+
+	// KindViews defines values which are names of stats views.
+	KindViews = "views"
+	// KindArchives defines values which are files with stats recorded by 'pgcenter record'.
+	KindArchives = "archives"
+	// KindBaselines defines values which are baseline files.
+	KindBaselines = "baselines"
+	// KindServices defines values which are names of connection services defined in pg_service.conf.
+	KindServices = "services"
+)
+
+// commonFlags defines values of flags which are shared by commands, e.g. connection options.
+var commonFlags = map[string][]string{
+	"service": {"@" + KindServices},
+	"sslmode": {"disable", "allow", "prefer", "require", "verify-ca", "verify-full"},
+	"auth":    {"password", "aws-rds-iam", "gcp-cloudsql-iam", "azure-ad"},
+}
+
+// StaticValues sets list of values used for completing flag's value.
+func StaticValues(cmd *cobra.Command, name string, values ...string) {
+	_ = cmd.Flags().SetAnnotation(name, annotationValues, values)
+}
+
+// DynamicValues sets kind of values used for completing flag's value, values are looked up during completion.
+func DynamicValues(cmd *cobra.Command, name string, kind string) {
+	_ = cmd.Flags().SetAnnotation(name, annotationKind, []string{kind})
+}
+
+// Complete returns candidates for completing command line. Args are words of command line after the program name,
+// the last word is the word being completed (possibly empty). Empty list means shell's default completion should be
+// used (e.g. file names).
+func Complete(root *cobra.Command, args []string) []string {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+
+	cur := args[len(args)-1]
+	words := args[:len(args)-1]
+
+	// Find sub-command, it is the first word which is not a flag.
+	cmd := root
+	for _, w := range words {
+		if strings.HasPrefix(w, "-") {
+			continue
+		}
+		if sub := findCommand(root, w); sub != nil {
+			cmd = sub
+		}
+		break
+	}
+
+	// Bash splits '--flag=value' into separate words.
+	if len(words) >= 2 && words[len(words)-1] == "=" {
+		words = words[:len(words)-1]
+	}
+
+	// Value of a flag specified in the same word, e.g. '--view=data'.
+	if strings.HasPrefix(cur, "--") && strings.Contains(cur, "=") {
+		parts := strings.SplitN(cur, "=", 2)
+		f := lookupFlag(cmd, parts[0])
+		if f == nil {
+			return nil
+		}
+		var res []string
+		for _, v := range flagValues(f, parts[1]) {
+			res = append(res, parts[0]+"="+v)
+		}
+		return res
+	}
+
+	// Value of a flag specified in the previous word.
+	if len(words) > 0 {
+		if f := lookupFlag(cmd, words[len(words)-1]); f != nil && f.NoOptDefVal == "" {
+			return flagValues(f, cur)
+		}
+	}
+
+	// Flags of the command.
+	if strings.HasPrefix(cur, "-") {
+		return flagNames(cmd, cur)
+	}
+
+	// Sub-commands.
+	if cmd == root {
+		var res []string
+		for _, c := range root.Commands() {
+			if c.IsAvailableCommand() && strings.HasPrefix(c.Name(), cur) {
+				res = append(res, c.Name())
+			}
+		}
+		return res
+	}
+
+	// Arguments of the command.
+	var res []string
+	for _, v := range cmd.ValidArgs {
+		if strings.HasPrefix(v, cur) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// findCommand returns sub-command with specified name or alias.
+func findCommand(root *cobra.Command, name string) *cobra.Command {
+	for _, c := range root.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// lookupFlag returns flag of the command specified in long or short form.
+func lookupFlag(cmd *cobra.Command, word string) *pflag.Flag {
+	switch {
+	case strings.HasPrefix(word, "--"):
+		return cmd.Flags().Lookup(strings.TrimPrefix(word, "--"))
+	case strings.HasPrefix(word, "-") && len(word) == 2:
+		return cmd.Flags().ShorthandLookup(word[1:])
+	}
+	return nil
+}
+
+// flagNames returns names of flags of the command which start with prefix.
+func flagNames(cmd *cobra.Command, prefix string) []string {
+	var res []string
+	add := func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		if name := "--" + f.Name; strings.HasPrefix(name, prefix) {
+			res = append(res, name)
+		}
+	}
+
+	cmd.Flags().VisitAll(add)
+	cmd.InheritedFlags().VisitAll(add)
+
+	sort.Strings(res)
+	return res
+}
+
+// flagValues returns values of the flag which start with prefix.
+func flagValues(f *pflag.Flag, prefix string) []string {
+	values := f.Annotations[annotationValues]
+	if kind := f.Annotations[annotationKind]; len(kind) > 0 {
+		values = []string{"@" + kind[0]}
+	}
+	if values == nil {
+		values = commonFlags[f.Name]
+	}
+
+	var res []string
+	for _, v := range values {
+		if strings.HasPrefix(v, "@") {
+			return dynamicValues(strings.TrimPrefix(v, "@"), prefix)
+		}
+		if strings.HasPrefix(v, prefix) {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+// dynamicValues looks up values of specified kind which start with prefix.
+func dynamicValues(kind string, prefix string) []string {
+	var values []string
+
+	switch kind {
+	case KindViews:
+		for name := range view.New() {
+			values = append(values, name)
+		}
+	case KindArchives:
+		return files(prefix, ".tar")
+	case KindBaselines:
+		return files(prefix, ".json")
+	case KindServices:
+		for _, path := range serviceFiles() {
+			values = append(values, readServices(path)...)
+		}
+	}
+
+	var res []string
+	seen := map[string]bool{}
+	for _, v := range values {
+		if strings.HasPrefix(v, prefix) && !seen[v] {
+			res = append(res, v)
+			seen[v] = true
+		}
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// files returns names of files with specified extension which start with prefix.
+func files(prefix string, ext string) []string {
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return nil
+	}
+
+	sort.Strings(matches)
+	return matches
+}
+
+// serviceFiles returns paths of connection service files the same way as libpq looks them up.
+func serviceFiles() []string {
+	var paths []string
+
+	if path := os.Getenv("PGSERVICEFILE"); path != "" {
+		paths = append(paths, path)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".pg_service.conf"))
+	}
+
+	if dir := os.Getenv("PGSYSCONFDIR"); dir != "" {
+		paths = append(paths, filepath.Join(dir, "pg_service.conf"))
+	} else {
+		paths = append(paths, "/etc/postgresql-common/pg_service.conf")
+	}
+
+	return paths
+}
+
+// readServices returns names of services defined in connection service file.
+func readServices(path string) []string {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	var res []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			res = append(res, strings.TrimSpace(line[1:len(line)-1]))
+		}
+	}
+
+	return res
+}
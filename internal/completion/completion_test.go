@@ -0,0 +1,101 @@
+package completion
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestCommand() *cobra.Command {
+	root := &cobra.Command{Use: "pgcenter"}
+
+	stat := &cobra.Command{Use: "stat", Run: func(*cobra.Command, []string) {}}
+	stat.Flags().StringP("view", "V", "", "")
+	stat.Flags().StringP("format", "", "", "")
+	stat.Flags().StringP("sslmode", "", "", "")
+	stat.Flags().BoolP("verbose", "v", false, "")
+	StaticValues(stat, "format", "table", "json", "csv")
+	DynamicValues(stat, "view", KindViews)
+
+	shell := &cobra.Command{Use: "completion", ValidArgs: []string{"bash", "zsh", "fish"}, Run: func(*cobra.Command, []string) {}}
+	hidden := &cobra.Command{Use: "__complete", Hidden: true, Run: func(*cobra.Command, []string) {}}
+
+	root.AddCommand(stat, shell, hidden)
+	return root
+}
+
+func TestComplete(t *testing.T) {
+	root := newTestCommand()
+
+	testcases := []struct {
+		args []string
+		want []string
+	}{
+		{args: []string{}, want: []string{"completion", "stat"}},
+		{args: []string{"st"}, want: []string{"stat"}},
+		{args: []string{"stat", "--f"}, want: []string{"--format"}},
+		{args: []string{"stat", "--format", "j"}, want: []string{"json"}},
+		{args: []string{"stat", "--format=c"}, want: []string{"--format=csv"}},
+		{args: []string{"stat", "--format", "=", "t"}, want: []string{"table"}},
+		{args: []string{"stat", "-V", "databa"}, want: []string{"databases"}},
+		{args: []string{"stat", "--view=databa"}, want: []string{"--view=databases"}},
+		{args: []string{"stat", "--sslmode", "verify"}, want: []string{"verify-ca", "verify-full"}},
+		{args: []string{"stat", "-v", "x"}, want: nil},
+		{args: []string{"stat", "--unknown=x"}, want: nil},
+		{args: []string{"completion", "z"}, want: []string{"zsh"}},
+		{args: []string{"completion", ""}, want: []string{"bash", "zsh", "fish"}},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, Complete(root, tc.args), tc.args)
+	}
+}
+
+func Test_files(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-completion-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	for _, name := range []string{"b.stat.tar", "a.stat.tar", "baseline.json", "other.txt"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+
+	assert.Equal(t, []string{filepath.Join(dir, "a.stat.tar"), filepath.Join(dir, "b.stat.tar")}, files(dir+"/", ".tar"))
+	assert.Equal(t, []string{filepath.Join(dir, "baseline.json")}, files(dir+"/b", ".json"))
+	assert.Nil(t, files(dir+"/x", ".tar"))
+}
+
+func Test_readServices(t *testing.T) {
+	f, err := ioutil.TempFile("", "pgcenter-completion-")
+	assert.NoError(t, err)
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = f.WriteString("# comment\n[production]\nhost=db1\n\n [ staging ] \nhost=db2\n")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	assert.Equal(t, []string{"production", "staging"}, readServices(f.Name()))
+	assert.Nil(t, readServices("/nonexistent"))
+}
+
+func Test_dynamicValues_services(t *testing.T) {
+	f, err := ioutil.TempFile("", "pgcenter-completion-")
+	assert.NoError(t, err)
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = f.WriteString("[production]\n[staging]\n[prod-replica]\n")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	assert.NoError(t, os.Setenv("PGSERVICEFILE", f.Name()))
+	assert.NoError(t, os.Setenv("PGSYSCONFDIR", "/nonexistent"))
+	defer func() {
+		_ = os.Unsetenv("PGSERVICEFILE")
+		_ = os.Unsetenv("PGSYSCONFDIR")
+	}()
+
+	assert.Equal(t, []string{"prod-replica", "production"}, dynamicValues(KindServices, "prod"))
+}
@@ -0,0 +1,61 @@
+package completion
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// bashScript defines completion script for bash, candidates are requested from the program. When there are no
+	// candidates, file names are completed.
+	bashScript = `# bash completion for {{name}}, load it with: source <({{name}} completion bash)
+_{{name}}() {
+    local IFS=$'\n'
+    COMPREPLY=($({{name}} {{command}} "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _{{name}} {{name}}
+`
+
+	// zshScript defines completion script for zsh.
+	zshScript = `#compdef {{name}}
+# zsh completion for {{name}}, load it with: source <({{name}} completion zsh)
+_{{name}}() {
+    local -a candidates
+    candidates=("${(@f)$({{name}} {{command}} "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -n "${candidates[1]}" ]]; then
+        compadd -- "${candidates[@]}"
+    else
+        _files
+    fi
+}
+compdef _{{name}} {{name}}
+`
+
+	// fishScript defines completion script for fish.
+	fishScript = `# fish completion for {{name}}, load it with: {{name}} completion fish | source
+function __{{name}}_complete
+    set -l args (commandline -opc)
+    set -l cur (commandline -ct)
+    {{name}} {{command}} $args[2..-1] "$cur" 2>/dev/null
+end
+complete -c {{name}} -a '(__{{name}}_complete)'
+`
+)
+
+// Script returns completion script for specified shell. Scripts call the program using specified command for getting
+// candidates.
+func Script(shell string, name string, command string) (string, error) {
+	var tmpl string
+	switch shell {
+	case "bash":
+		tmpl = bashScript
+	case "zsh":
+		tmpl = zshScript
+	case "fish":
+		tmpl = fishScript
+	default:
+		return "", fmt.Errorf("unknown shell '%s', supported: bash, zsh, fish", shell)
+	}
+
+	return strings.NewReplacer("{{name}}", name, "{{command}}", command).Replace(tmpl), nil
+}
@@ -0,0 +1,19 @@
+package completion
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		s, err := Script(shell, "pgcenter", "__complete")
+		assert.NoError(t, err)
+		assert.Contains(t, s, "pgcenter __complete")
+		assert.False(t, strings.Contains(s, "{{"))
+	}
+
+	_, err := Script("tcsh", "pgcenter", "__complete")
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package discovery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// dcsSource discovers cluster members using keys stored by Patroni in DCS: '<prefix>/leader' contains name of the
+// leader and '<prefix>/members/<name>' contain members' connection URLs and states.
+type dcsSource struct {
+	endpoints []*url.URL
+	client    *http.Client
+	read      func(ctx context.Context, client *http.Client, u *url.URL) (map[string][]byte, error) // reads keys of cluster
+}
+
+// dcsMember defines value of member key in DCS.
+type dcsMember struct {
+	ConnURL string `json:"conn_url"`
+	Role    string `json:"role"`
+	State   string `json:"state"`
+}
+
+// Members implements Source interface.
+func (s *dcsSource) Members(ctx context.Context) ([]Member, error) {
+	return firstResponding(ctx, s.endpoints, func(ctx context.Context, u *url.URL) ([]Member, error) {
+		keys, err := s.read(ctx, s.client, u)
+		if err != nil {
+			return nil, err
+		}
+		return parseDCSKeys(keys)
+	})
+}
+
+// parseDCSKeys makes members using cluster keys, keys are relative to the cluster prefix.
+func parseDCSKeys(keys map[string][]byte) ([]Member, error) {
+	leader := string(keys["leader"])
+
+	var members []Member
+	for k, v := range keys {
+		if !strings.HasPrefix(k, "members/") {
+			continue
+		}
+		name := strings.TrimPrefix(k, "members/")
+
+		var m dcsMember
+		err := json.Unmarshal(v, &m)
+		if err != nil {
+			return nil, fmt.Errorf("parse member '%s' failed: %s", name, err)
+		}
+
+		host, port, err := parseConnURL(m.ConnURL)
+		if err != nil {
+			return nil, fmt.Errorf("member '%s': %s", name, err)
+		}
+
+		// Leader key is authoritative, role in member key could be outdated during failover.
+		role := normalizeRole(m.Role)
+		if leader != "" {
+			role = RoleReplica
+			if name == leader {
+				role = RolePrimary
+			}
+		}
+
+		members = append(members, Member{Name: name, Host: host, Port: port, Role: role, State: m.State})
+	}
+
+	return members, nil
+}
+
+// parseConnURL returns host and port of Postgres connection URL.
+func parseConnURL(s string) (string, int, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Hostname() == "" {
+		return "", 0, fmt.Errorf("invalid conn_url '%s'", s)
+	}
+
+	port := 5432
+	if u.Port() != "" {
+		port, err = strconv.Atoi(u.Port())
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid port in conn_url '%s'", s)
+		}
+	}
+
+	return u.Hostname(), port, nil
+}
+
+// readEtcd reads cluster keys using etcd v3 JSON gateway.
+func readEtcd(ctx context.Context, client *http.Client, u *url.URL) (map[string][]byte, error) {
+	prefix := "/" + strings.Trim(u.Path, "/") + "/"
+
+	// Range end of prefix is the prefix with the last byte incremented, '/' becomes '0'.
+	rangeEnd := prefix[:len(prefix)-1] + "0"
+
+	body, err := json.Marshal(map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(prefix)),
+		"range_end": base64.StdEncoding.EncodeToString([]byte(rangeEnd)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/v3/kv/range"}
+	data, err := do(ctx, client, http.MethodPost, endpoint.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Kvs []struct {
+			Key   []byte `json:"key"` // base64-encoded values are decoded by json package
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("parse response failed: %s", err)
+	}
+
+	keys := map[string][]byte{}
+	for _, kv := range resp.Kvs {
+		keys[strings.TrimPrefix(string(kv.Key), prefix)] = kv.Value
+	}
+
+	return keys, nil
+}
+
+// readConsul reads cluster keys from Consul KV store.
+func readConsul(ctx context.Context, client *http.Client, u *url.URL) (map[string][]byte, error) {
+	prefix := strings.Trim(u.Path, "/") + "/"
+
+	endpoint := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/v1/kv/" + prefix, RawQuery: "recurse=true"}
+	data, err := get(ctx, client, endpoint.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var resp []struct {
+		Key   string `json:"Key"`
+		Value []byte `json:"Value"` // base64-encoded values are decoded by json package
+	}
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("parse response failed: %s", err)
+	}
+
+	keys := map[string][]byte{}
+	for _, kv := range resp {
+		keys[strings.TrimPrefix(kv.Key, prefix)] = kv.Value
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,123 @@
+// Package discovery implements discovery of Postgres cluster topology using Patroni REST API or Patroni's DCS (etcd,
+// Consul). Discovered members are used for cluster overview, the list of members is refreshed periodically, hence
+// failovers and switchovers are followed automatically.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RolePrimary defines role of cluster leader.
+	RolePrimary = "primary"
+	// RoleReplica defines role of cluster member which is not leader.
+	RoleReplica = "replica"
+
+	// requestTimeout defines timeout of a single request to Patroni or DCS.
+	requestTimeout = 5 * time.Second
+)
+
+// Member defines discovered member of Postgres cluster.
+type Member struct {
+	Name  string // member name in Patroni
+	Host  string
+	Port  int
+	Role  string // primary or replica, empty if unknown
+	State string // state reported by Patroni, e.g. running, streaming, stopped
+}
+
+// Target returns address of the member in host:port format.
+func (m Member) Target() string {
+	return net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+}
+
+// Source defines source of cluster topology.
+type Source interface {
+	// Members returns members of the cluster, leader is the first.
+	Members(ctx context.Context) ([]Member, error)
+}
+
+// NewSource creates source using specification in 'kind:url[,url...]' format. Supported kinds are 'patroni' (Patroni
+// REST API), 'etcd' (etcd v3 JSON gateway) and 'consul' (Consul KV store). For etcd and Consul the URL path is the
+// cluster's key prefix, e.g. 'etcd:http://etcd:2379/service/main'. When several URLs are specified, they are tried
+// in order until one of them responds.
+func NewSource(spec string) (Source, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid discovery '%s', must be in format kind:url[,url...]", spec)
+	}
+
+	var endpoints []*url.URL
+	for _, s := range strings.Split(parts[1], ",") {
+		u, err := url.Parse(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid discovery URL '%s': %s", s, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("invalid discovery URL '%s': must be http(s)://host[:port][/path]", s)
+		}
+		endpoints = append(endpoints, u)
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+
+	switch parts[0] {
+	case "patroni":
+		return &patroniSource{endpoints: endpoints, client: client}, nil
+	case "etcd", "consul":
+		for _, u := range endpoints {
+			if strings.Trim(u.Path, "/") == "" {
+				return nil, fmt.Errorf("invalid discovery URL '%s': cluster key prefix is not specified, e.g. /service/main", u)
+			}
+		}
+		if parts[0] == "etcd" {
+			return &dcsSource{endpoints: endpoints, client: client, read: readEtcd}, nil
+		}
+		return &dcsSource{endpoints: endpoints, client: client, read: readConsul}, nil
+	default:
+		return nil, fmt.Errorf("unknown discovery kind '%s', supported: patroni, etcd, consul", parts[0])
+	}
+}
+
+// firstResponding calls function for endpoints in order and returns result of the first successful call.
+func firstResponding(ctx context.Context, endpoints []*url.URL, fn func(ctx context.Context, u *url.URL) ([]Member, error)) ([]Member, error) {
+	var errs []string
+	for _, u := range endpoints {
+		members, err := fn(ctx, u)
+		if err == nil {
+			sortMembers(members)
+			return members, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", u.Host, err))
+	}
+
+	return nil, fmt.Errorf("discovery failed: %s", strings.Join(errs, "; "))
+}
+
+// sortMembers sorts members: primary is the first, the rest are ordered by name.
+func sortMembers(members []Member) {
+	sort.SliceStable(members, func(i, j int) bool {
+		if (members[i].Role == RolePrimary) != (members[j].Role == RolePrimary) {
+			return members[i].Role == RolePrimary
+		}
+		return members[i].Name < members[j].Name
+	})
+}
+
+// normalizeRole converts Patroni role to primary or replica.
+func normalizeRole(role string) string {
+	switch role {
+	case "leader", "master", "primary", "standby_leader":
+		return RolePrimary
+	default:
+		return RoleReplica
+	}
+}
@@ -0,0 +1,178 @@
+package discovery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewSource(t *testing.T) {
+	testcases := []struct {
+		spec  string
+		valid bool
+	}{
+		{spec: "patroni:http://10.0.0.1:8008", valid: true},
+		{spec: "patroni:http://10.0.0.1:8008,https://10.0.0.2:8008", valid: true},
+		{spec: "etcd:http://etcd:2379/service/main", valid: true},
+		{spec: "consul:http://consul:8500/service/main", valid: true},
+		{spec: "", valid: false},
+		{spec: "patroni", valid: false},
+		{spec: "patroni:", valid: false},
+		{spec: "patroni:10.0.0.1:8008", valid: false},
+		{spec: "etcd:http://etcd:2379", valid: false},
+		{spec: "zookeeper:http://zk:2181/service/main", valid: false},
+	}
+
+	for _, tc := range testcases {
+		_, err := NewSource(tc.spec)
+		if tc.valid {
+			assert.NoError(t, err, tc.spec)
+		} else {
+			assert.Error(t, err, tc.spec)
+		}
+	}
+}
+
+func Test_patroniSource_Members(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cluster", r.URL.Path)
+		_, _ = fmt.Fprint(w, `{"members": [
+			{"name": "pg2", "role": "replica", "state": "streaming", "host": "10.0.0.2", "port": 5433, "lag": "unknown"},
+			{"name": "pg3", "role": "sync_standby", "state": "streaming", "host": "10.0.0.3", "port": 5432, "lag": 0},
+			{"name": "pg1", "role": "leader", "state": "running", "host": "10.0.0.1", "port": 5432, "timeline": 2}
+		], "scope": "main"}`)
+	}))
+	defer ts.Close()
+
+	// The first endpoint is unavailable, the next one is used.
+	s, err := NewSource("patroni:http://127.0.0.1:1," + ts.URL)
+	assert.NoError(t, err)
+
+	got, err := s.Members(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []Member{
+		{Name: "pg1", Host: "10.0.0.1", Port: 5432, Role: RolePrimary, State: "running"},
+		{Name: "pg2", Host: "10.0.0.2", Port: 5433, Role: RoleReplica, State: "streaming"},
+		{Name: "pg3", Host: "10.0.0.3", Port: 5432, Role: RoleReplica, State: "streaming"},
+	}, got)
+	assert.Equal(t, "10.0.0.2:5433", got[1].Target())
+
+	// Errors.
+	ts2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts2.Close()
+
+	s, err = NewSource("patroni:" + ts2.URL)
+	assert.NoError(t, err)
+	_, err = s.Members(context.Background())
+	assert.Error(t, err)
+}
+
+// dcsKeys defines keys stored by Patroni in DCS used in tests. Member pg2 still has 'master' role in its key, but the
+// leader key points to pg1 after failover.
+var dcsKeys = map[string]string{
+	"leader":        "pg1",
+	"members/pg1":   `{"conn_url": "postgres://10.0.0.1:5432/postgres", "role": "master", "state": "running"}`,
+	"members/pg2":   `{"conn_url": "postgres://10.0.0.2/postgres", "role": "master", "state": "running"}`,
+	"config":        `{"ttl": 30}`,
+	"optime/leader": "67108864",
+	"initialize":    "6912345678901234567",
+	"status":        `{"optime": 67108864}`,
+}
+
+func Test_dcsSource_Members(t *testing.T) {
+	want := []Member{
+		{Name: "pg1", Host: "10.0.0.1", Port: 5432, Role: RolePrimary, State: "running"},
+		{Name: "pg2", Host: "10.0.0.2", Port: 5432, Role: RoleReplica, State: "running"},
+	}
+
+	t.Run("etcd", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v3/kv/range", r.URL.Path)
+
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+
+			var req map[string]string
+			assert.NoError(t, json.Unmarshal(body, &req))
+			assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("/service/main/")), req["key"])
+			assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("/service/main0")), req["range_end"])
+
+			var kvs []map[string]string
+			for k, v := range dcsKeys {
+				kvs = append(kvs, map[string]string{
+					"key":   base64.StdEncoding.EncodeToString([]byte("/service/main/" + k)),
+					"value": base64.StdEncoding.EncodeToString([]byte(v)),
+				})
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"kvs": kvs, "count": len(kvs)}))
+		}))
+		defer ts.Close()
+
+		s, err := NewSource("etcd:" + ts.URL + "/service/main")
+		assert.NoError(t, err)
+
+		got, err := s.Members(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("consul", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/kv/service/main/", r.URL.Path)
+			assert.Equal(t, "true", r.URL.Query().Get("recurse"))
+
+			var kvs []map[string]string
+			for k, v := range dcsKeys {
+				kvs = append(kvs, map[string]string{
+					"Key":   "service/main/" + k,
+					"Value": base64.StdEncoding.EncodeToString([]byte(v)),
+				})
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(kvs))
+		}))
+		defer ts.Close()
+
+		s, err := NewSource("consul:" + ts.URL + "/service/main/")
+		assert.NoError(t, err)
+
+		got, err := s.Members(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+}
+
+func Test_parseDCSKeys(t *testing.T) {
+	// Without leader key, roles from members keys are used.
+	got, err := parseDCSKeys(map[string][]byte{
+		"members/pg1": []byte(`{"conn_url": "postgres://10.0.0.1:5432/postgres", "role": "replica", "state": "running"}`),
+		"members/pg2": []byte(`{"conn_url": "postgres://10.0.0.2:5432/postgres", "role": "primary", "state": "running"}`),
+	})
+	assert.NoError(t, err)
+	sortMembers(got)
+	assert.Equal(t, "pg2", got[0].Name)
+	assert.Equal(t, RolePrimary, got[0].Role)
+	assert.Equal(t, RoleReplica, got[1].Role)
+
+	// Invalid members.
+	for _, v := range []string{`invalid`, `{"conn_url": ""}`, `{"conn_url": "postgres://10.0.0.1:port/postgres"}`} {
+		_, err := parseDCSKeys(map[string][]byte{"members/pg1": []byte(v)})
+		assert.Error(t, err)
+	}
+}
+
+func Test_normalizeRole(t *testing.T) {
+	for _, role := range []string{"leader", "master", "primary", "standby_leader"} {
+		assert.Equal(t, RolePrimary, normalizeRole(role))
+	}
+	for _, role := range []string{"replica", "sync_standby", "standby", ""} {
+		assert.Equal(t, RoleReplica, normalizeRole(role))
+	}
+}
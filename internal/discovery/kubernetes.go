@@ -0,0 +1,342 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// portForwardTimeout defines how long to wait until 'kubectl port-forward' starts forwarding.
+const portForwardTimeout = 10 * time.Second
+
+// roleLabels defines pods labels used by Postgres operators for marking role of instances: Zalando, CloudNativePG
+// (new and old labels) and Crunchy Data.
+var roleLabels = []string{"spilo-role", "cnpg.io/instanceRole", "role", "postgres-operator.crunchydata.com/role"}
+
+// forwardingRE defines line printed by 'kubectl port-forward' when forwarding is started.
+var forwardingRE = regexp.MustCompile(`^Forwarding from 127\.0\.0\.1:(\d+) -> \d+`)
+
+// KubernetesOptions defines options of discovery of Postgres pods in Kubernetes.
+type KubernetesOptions struct {
+	Selector    string // label selector of pods, e.g. 'app=postgres'
+	Namespace   string // namespace of pods, the current namespace of kubectl context is used if empty
+	Context     string // kubectl context, the current one is used if empty
+	PortForward bool   // connect through 'kubectl port-forward' instead of connecting to pods directly
+}
+
+// InCluster returns true if pgcenter is running inside Kubernetes pod, hence pods are reachable directly.
+func InCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+}
+
+// kubernetesSource discovers Postgres pods using kubectl, hence all kubectl authentication methods are supported.
+type kubernetesSource struct {
+	opts  KubernetesOptions
+	run   func(ctx context.Context, args ...string) ([]byte, error) // runs kubectl and returns its output
+	start func(args ...string) (*forwarder, error)                  // starts port forwarding
+
+	mu         sync.Mutex
+	forwarders map[string]*forwarder // port forwarders keyed by pod name
+}
+
+// NewKubernetesSource creates source which discovers Postgres pods in Kubernetes.
+func NewKubernetesSource(opts KubernetesOptions) (Source, error) {
+	if opts.Selector == "" {
+		return nil, fmt.Errorf("pods label selector is not specified")
+	}
+
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		return nil, fmt.Errorf("kubectl is required for Kubernetes discovery: %s", err)
+	}
+
+	return &kubernetesSource{
+		opts:       opts,
+		run:        runKubectl,
+		start:      startForwarder,
+		forwarders: map[string]*forwarder{},
+	}, nil
+}
+
+// Members implements Source interface.
+func (s *kubernetesSource) Members(ctx context.Context) ([]Member, error) {
+	args := append(s.globalArgs(), "get", "pods", "--selector", s.opts.Selector, "--output", "json")
+	out, err := s.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := parsePods(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.opts.PortForward {
+		members, err = s.forward(members)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sortMembers(members)
+	return members, nil
+}
+
+// Close stops all port forwarders.
+func (s *kubernetesSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, f := range s.forwarders {
+		f.stop()
+		delete(s.forwarders, name)
+	}
+	return nil
+}
+
+// globalArgs returns kubectl arguments which select context and namespace.
+func (s *kubernetesSource) globalArgs() []string {
+	var args []string
+	if s.opts.Context != "" {
+		args = append(args, "--context", s.opts.Context)
+	}
+	if s.opts.Namespace != "" {
+		args = append(args, "--namespace", s.opts.Namespace)
+	}
+	return args
+}
+
+// forward replaces pods addresses with addresses of local port forwarders. Forwarders are started for new pods and
+// restarted if they are exited; forwarders of pods which have gone are stopped.
+func (s *kubernetesSource) forward(members []Member) ([]Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := map[string]bool{}
+	var errs []string
+	forwarded := make([]Member, 0, len(members))
+
+	for _, m := range members {
+		seen[m.Name] = true
+
+		f, ok := s.forwarders[m.Name]
+		if !ok || f.exited() || f.remotePort != m.Port {
+			if ok {
+				f.stop()
+			}
+
+			args := append(s.globalArgs(), "port-forward", "pod/"+m.Name, ":"+strconv.Itoa(m.Port))
+			var err error
+			f, err = s.start(args...)
+			if err != nil {
+				delete(s.forwarders, m.Name)
+				errs = append(errs, fmt.Sprintf("port-forward to %s failed: %s", m.Name, err))
+				continue
+			}
+			f.remotePort = m.Port
+			s.forwarders[m.Name] = f
+		}
+
+		m.Host, m.Port = "127.0.0.1", f.localPort
+		forwarded = append(forwarded, m)
+	}
+
+	for name, f := range s.forwarders {
+		if !seen[name] {
+			f.stop()
+			delete(s.forwarders, name)
+		}
+	}
+
+	// Pods which can't be forwarded are skipped, it is an error only when there is nothing to show.
+	if len(forwarded) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	return forwarded, nil
+}
+
+// podList defines part of 'kubectl get pods' output used for discovery.
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name              string            `json:"name"`
+			Namespace         string            `json:"namespace"`
+			Labels            map[string]string `json:"labels"`
+			DeletionTimestamp *string           `json:"deletionTimestamp"`
+		} `json:"metadata"`
+		Spec struct {
+			Hostname   string `json:"hostname"`
+			Subdomain  string `json:"subdomain"`
+			Containers []struct {
+				Ports []struct {
+					Name          string `json:"name"`
+					ContainerPort int    `json:"containerPort"`
+				} `json:"ports"`
+			} `json:"containers"`
+		} `json:"spec"`
+		Status struct {
+			Phase string `json:"phase"`
+			PodIP string `json:"podIP"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// parsePods makes members using 'kubectl get pods' output. Only running pods are used. Pods of StatefulSets with
+// headless service are addressed using DNS names, other pods are addressed using their IP.
+func parsePods(data []byte) ([]Member, error) {
+	var list podList
+	err := json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, fmt.Errorf("parse pods list failed: %s", err)
+	}
+
+	var members []Member
+	for _, p := range list.Items {
+		if p.Status.Phase != "Running" || p.Metadata.DeletionTimestamp != nil || p.Status.PodIP == "" {
+			continue
+		}
+
+		host := p.Status.PodIP
+		if p.Spec.Hostname != "" && p.Spec.Subdomain != "" {
+			host = fmt.Sprintf("%s.%s.%s.svc", p.Spec.Hostname, p.Spec.Subdomain, p.Metadata.Namespace)
+		}
+
+		var role string
+		for _, l := range roleLabels {
+			if v, ok := p.Metadata.Labels[l]; ok {
+				role = normalizeRole(v)
+				break
+			}
+		}
+
+		// Use container port named as Postgres port, or the default port.
+		port := 5432
+	loop:
+		for _, c := range p.Spec.Containers {
+			for _, cp := range c.Ports {
+				if cp.Name == "postgresql" || cp.Name == "postgres" || cp.Name == "pg" {
+					port = cp.ContainerPort
+					break loop
+				}
+			}
+		}
+
+		members = append(members, Member{Name: p.Metadata.Name, Host: host, Port: port, Role: role, State: p.Status.Phase})
+	}
+
+	return members, nil
+}
+
+// runKubectl runs kubectl and returns its output.
+func runKubectl(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "kubectl", args...) // #nosec G204
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("kubectl failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// forwarder defines running 'kubectl port-forward' process.
+type forwarder struct {
+	cmd        *exec.Cmd
+	localPort  int
+	remotePort int
+	done       chan struct{} // closed when process exits
+}
+
+// startForwarder starts 'kubectl port-forward' and waits until forwarding is started.
+func startForwarder(args ...string) (*forwarder, error) {
+	cmd := exec.Command("kubectl", args...) // #nosec G204
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &forwarder{cmd: cmd, done: make(chan struct{})}
+	ports := make(chan int, 1)
+
+	go func() {
+		readForwardingPort(stdout, ports)
+		// Drain output until process exits.
+		_, _ = io.Copy(ioutil.Discard, stdout)
+	}()
+
+	go func() {
+		_ = cmd.Wait()
+		close(f.done)
+	}()
+
+	select {
+	case f.localPort = <-ports:
+		return f, nil
+	case <-f.done:
+		return nil, fmt.Errorf("kubectl exited: %s", strings.TrimSpace(stderr.String()))
+	case <-time.After(portForwardTimeout):
+		f.stop()
+		return nil, fmt.Errorf("forwarding has not been started in %s", portForwardTimeout)
+	}
+}
+
+// readForwardingPort reads output of 'kubectl port-forward' until forwarding line and sends local port to channel.
+func readForwardingPort(r io.Reader, ports chan<- int) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if port, ok := parseForwardingPort(scanner.Text()); ok {
+			ports <- port
+			return
+		}
+	}
+}
+
+// parseForwardingPort returns local port from 'kubectl port-forward' output line.
+func parseForwardingPort(line string) (int, bool) {
+	m := forwardingRE.FindStringSubmatch(line)
+	if m == nil {
+		return 0, false
+	}
+	port, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	return port, true
+}
+
+// exited returns true if port forwarding process has exited.
+func (f *forwarder) exited() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop stops port forwarding process.
+func (f *forwarder) stop() {
+	if f.cmd != nil && f.cmd.Process != nil && !f.exited() {
+		_ = f.cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,144 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// testPods defines 'kubectl get pods' output used in tests: Zalando's primary and replica, CloudNativePG instance
+// addressed through headless service, pending pod and terminating pod.
+const testPods = `{"items": [
+  {"metadata": {"name": "orders-1", "namespace": "prod", "labels": {"spilo-role": "replica"}},
+   "spec": {"containers": [{"ports": [{"name": "http", "containerPort": 8008}, {"name": "postgresql", "containerPort": 5432}]}]},
+   "status": {"phase": "Running", "podIP": "10.1.0.11"}},
+  {"metadata": {"name": "orders-0", "namespace": "prod", "labels": {"spilo-role": "master"}},
+   "spec": {"containers": [{"ports": [{"containerPort": 8008}]}]},
+   "status": {"phase": "Running", "podIP": "10.1.0.10"}},
+  {"metadata": {"name": "billing-1", "namespace": "prod", "labels": {"cnpg.io/instanceRole": "primary"}},
+   "spec": {"hostname": "billing-1", "subdomain": "billing-any", "containers": [{"ports": [{"name": "postgresql", "containerPort": 5433}]}]},
+   "status": {"phase": "Running", "podIP": "10.1.0.20"}},
+  {"metadata": {"name": "orders-2", "namespace": "prod", "labels": {"spilo-role": "replica"}},
+   "status": {"phase": "Pending"}},
+  {"metadata": {"name": "orders-3", "namespace": "prod", "deletionTimestamp": "2021-01-01T00:00:00Z"},
+   "status": {"phase": "Running", "podIP": "10.1.0.13"}},
+  {"metadata": {"name": "misc-0", "namespace": "prod"},
+   "status": {"phase": "Running", "podIP": "10.1.0.30"}}
+]}`
+
+func Test_parsePods(t *testing.T) {
+	got, err := parsePods([]byte(testPods))
+	assert.NoError(t, err)
+	assert.Equal(t, []Member{
+		{Name: "orders-1", Host: "10.1.0.11", Port: 5432, Role: RoleReplica, State: "Running"},
+		{Name: "orders-0", Host: "10.1.0.10", Port: 5432, Role: RolePrimary, State: "Running"},
+		{Name: "billing-1", Host: "billing-1.billing-any.prod.svc", Port: 5433, Role: RolePrimary, State: "Running"},
+		{Name: "misc-0", Host: "10.1.0.30", Port: 5432, Role: "", State: "Running"},
+	}, got)
+
+	_, err = parsePods([]byte("invalid"))
+	assert.Error(t, err)
+}
+
+func Test_kubernetesSource_Members(t *testing.T) {
+	var calls [][]string
+	s := &kubernetesSource{
+		opts: KubernetesOptions{Selector: "app=postgres", Namespace: "prod", Context: "prod-cluster"},
+		run: func(_ context.Context, args ...string) ([]byte, error) {
+			calls = append(calls, args)
+			return []byte(testPods), nil
+		},
+		forwarders: map[string]*forwarder{},
+	}
+
+	got, err := s.Members(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"--context", "prod-cluster", "--namespace", "prod", "get", "pods", "--selector", "app=postgres", "--output", "json"},
+	}, calls)
+
+	// Primaries are the first.
+	var names []string
+	for _, m := range got {
+		names = append(names, m.Name)
+	}
+	assert.Equal(t, []string{"billing-1", "orders-0", "misc-0", "orders-1"}, names)
+
+	// kubectl errors.
+	s.run = func(_ context.Context, _ ...string) ([]byte, error) { return nil, fmt.Errorf("kubectl failed") }
+	_, err = s.Members(context.Background())
+	assert.Error(t, err)
+}
+
+func Test_kubernetesSource_forward(t *testing.T) {
+	var started []string
+	port := 40000
+	s := &kubernetesSource{
+		opts: KubernetesOptions{Selector: "app=postgres", PortForward: true},
+		start: func(args ...string) (*forwarder, error) {
+			started = append(started, strings.Join(args, " "))
+			if args[1] == "pod/broken-0" {
+				return nil, fmt.Errorf("pod is not ready")
+			}
+			port++
+			return &forwarder{localPort: port, done: make(chan struct{})}, nil
+		},
+		forwarders: map[string]*forwarder{},
+	}
+
+	got, err := s.forward([]Member{
+		{Name: "orders-0", Host: "10.1.0.10", Port: 5432},
+		{Name: "orders-1", Host: "10.1.0.11", Port: 5432},
+		{Name: "broken-0", Host: "10.1.0.12", Port: 5432},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"port-forward pod/orders-0 :5432", "port-forward pod/orders-1 :5432", "port-forward pod/broken-0 :5432"}, started)
+	assert.Equal(t, []Member{
+		{Name: "orders-0", Host: "127.0.0.1", Port: 40001},
+		{Name: "orders-1", Host: "127.0.0.1", Port: 40002},
+	}, got)
+
+	// Running forwarders are reused, exited forwarders are restarted, forwarders of gone pods are stopped.
+	close(s.forwarders["orders-1"].done)
+	started = nil
+
+	got, err = s.forward([]Member{
+		{Name: "orders-0", Host: "10.1.0.10", Port: 5432},
+		{Name: "orders-1", Host: "10.1.0.11", Port: 5432},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"port-forward pod/orders-1 :5432"}, started)
+	assert.Equal(t, 40001, got[0].Port)
+	assert.Equal(t, 40003, got[1].Port)
+
+	got, err = s.forward([]Member{{Name: "orders-0", Host: "10.1.0.10", Port: 5432}})
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.Len(t, s.forwarders, 1)
+
+	// Nothing could be forwarded.
+	_, err = s.forward([]Member{{Name: "broken-0", Host: "10.1.0.12", Port: 5432}})
+	assert.Error(t, err)
+
+	assert.NoError(t, s.Close())
+	assert.Len(t, s.forwarders, 0)
+}
+
+func Test_parseForwardingPort(t *testing.T) {
+	port, ok := parseForwardingPort("Forwarding from 127.0.0.1:43567 -> 5432")
+	assert.True(t, ok)
+	assert.Equal(t, 43567, port)
+
+	_, ok = parseForwardingPort("Forwarding from [::1]:43567 -> 5432")
+	assert.False(t, ok)
+	_, ok = parseForwardingPort("Handling connection for 43567")
+	assert.False(t, ok)
+}
+
+func Test_readForwardingPort(t *testing.T) {
+	ports := make(chan int, 1)
+	readForwardingPort(strings.NewReader("Forwarding from 127.0.0.1:43567 -> 5432\nForwarding from [::1]:43567 -> 5432\n"), ports)
+	assert.Equal(t, 43567, <-ports)
+}
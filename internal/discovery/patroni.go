@@ -0,0 +1,98 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// patroniSource discovers cluster members using '/cluster' endpoint of Patroni REST API.
+type patroniSource struct {
+	endpoints []*url.URL
+	client    *http.Client
+}
+
+// patroniCluster defines response of '/cluster' endpoint.
+type patroniCluster struct {
+	Members []struct {
+		Name  string `json:"name"`
+		Role  string `json:"role"`
+		State string `json:"state"`
+		Host  string `json:"host"`
+		Port  int    `json:"port"`
+	} `json:"members"`
+}
+
+// Members implements Source interface.
+func (s *patroniSource) Members(ctx context.Context) ([]Member, error) {
+	return firstResponding(ctx, s.endpoints, s.request)
+}
+
+// request requests cluster members from Patroni.
+func (s *patroniSource) request(ctx context.Context, u *url.URL) ([]Member, error) {
+	endpoint := *u
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/cluster"
+
+	data, err := get(ctx, s.client, endpoint.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var c patroniCluster
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, fmt.Errorf("parse response failed: %s", err)
+	}
+
+	members := make([]Member, 0, len(c.Members))
+	for _, m := range c.Members {
+		if m.Host == "" {
+			continue
+		}
+		port := m.Port
+		if port == 0 {
+			port = 5432
+		}
+		members = append(members, Member{Name: m.Name, Host: m.Host, Port: port, Role: normalizeRole(m.Role), State: m.State})
+	}
+
+	return members, nil
+}
+
+// get sends GET request and returns response body.
+func get(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	return do(ctx, client, http.MethodGet, url, nil)
+}
+
+// do sends request and returns response body, responses with non-2xx statuses are considered as errors.
+func do(ctx context.Context, client *http.Client, method string, url string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("responded %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	return data, nil
+}
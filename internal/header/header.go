@@ -0,0 +1,94 @@
+// Package header implements configuration of summary lines shown in the header of 'top' program. Lines are arranged
+// into two columns, the left and the right one; which lines are shown and their order are configurable, hence the
+// header could be made shorter on small terminals.
+package header
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Summary lines of the header.
+const (
+	Load        = "load"        // current time and load average
+	CPU         = "cpu"         // CPU usage
+	Mem         = "mem"         // memory usage
+	Swap        = "swap"        // swap usage, dirty and writeback memory
+	IO          = "io"          // total IO of block devices
+	Net         = "net"         // total traffic of network interfaces
+	Postgres    = "postgres"    // connection details, version, uptime and recovery status
+	Activity    = "activity"    // state of client connections
+	Autovacuum  = "autovacuum"  // state of (auto)vacuum workers
+	Statements  = "statements"  // workload and duration of the longest transactions
+	Replication = "replication" // number of replicas and replication lag
+	Alerts      = "alerts"      // firing alerts
+)
+
+// lines defines all known summary lines in default order.
+var lines = []string{Load, CPU, Mem, Swap, IO, Net, Postgres, Activity, Autovacuum, Statements, Replication, Alerts}
+
+// Default lines of columns used when header is not configured.
+var (
+	defaultLeft  = []string{Load, CPU, Mem, Swap}
+	defaultRight = []string{Postgres, Activity, Autovacuum, Statements}
+)
+
+// Config defines summary lines shown in the left and the right columns of the header, top down. Default lines are
+// shown when none of columns are configured. When one of columns is empty, lines of the other one take full width.
+type Config struct {
+	Left  []string `yaml:"left"`  // lines of the left column
+	Right []string `yaml:"right"` // lines of the right column
+}
+
+// Validate checks lines of the header are known and each line is shown once.
+func (c Config) Validate() error {
+	seen := map[string]bool{}
+	for _, line := range append(append([]string{}, c.Left...), c.Right...) {
+		if !known(line) {
+			return fmt.Errorf("invalid header line '%s', allowed: %s", line, strings.Join(lines, ", "))
+		}
+		if seen[line] {
+			return fmt.Errorf("header line '%s' is specified more than once", line)
+		}
+		seen[line] = true
+	}
+	return nil
+}
+
+// Columns returns lines of the left and the right columns.
+func (c Config) Columns() ([]string, []string) {
+	if len(c.Left) == 0 && len(c.Right) == 0 {
+		return defaultLeft, defaultRight
+	}
+	return c.Left, c.Right
+}
+
+// Height returns number of screen lines occupied by the header.
+func (c Config) Height() int {
+	left, right := c.Columns()
+	if len(left) > len(right) {
+		return len(left)
+	}
+	return len(right)
+}
+
+// Has returns true if the line is shown in the header.
+func (c Config) Has(line string) bool {
+	left, right := c.Columns()
+	for _, l := range append(append([]string{}, left...), right...) {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+// known returns true if the line is a known summary line.
+func known(line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
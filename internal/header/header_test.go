@@ -0,0 +1,37 @@
+package header
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.NoError(t, Config{Left: []string{Load, IO, Net}, Right: []string{Postgres, Replication, Alerts}}.Validate())
+	assert.NoError(t, Config{Right: []string{Load, Activity}}.Validate())
+	assert.Error(t, Config{Left: []string{"disk"}}.Validate())
+	assert.Error(t, Config{Left: []string{Load}, Right: []string{Load}}.Validate())
+}
+
+func TestConfig_Columns(t *testing.T) {
+	left, right := Config{}.Columns()
+	assert.Equal(t, []string{Load, CPU, Mem, Swap}, left)
+	assert.Equal(t, []string{Postgres, Activity, Autovacuum, Statements}, right)
+
+	left, right = Config{Right: []string{Load, Activity}}.Columns()
+	assert.Empty(t, left)
+	assert.Equal(t, []string{Load, Activity}, right)
+}
+
+func TestConfig_Height(t *testing.T) {
+	assert.Equal(t, 4, Config{}.Height())
+	assert.Equal(t, 2, Config{Left: []string{Load, CPU}, Right: []string{Postgres}}.Height())
+	assert.Equal(t, 3, Config{Right: []string{Load, Postgres, Alerts}}.Height())
+}
+
+func TestConfig_Has(t *testing.T) {
+	assert.True(t, Config{}.Has(Swap))
+	assert.False(t, Config{}.Has(Replication))
+	assert.True(t, Config{Left: []string{Load}, Right: []string{Replication}}.Has(Replication))
+	assert.False(t, Config{Left: []string{Load}, Right: []string{Replication}}.Has(Swap))
+}
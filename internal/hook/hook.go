@@ -0,0 +1,218 @@
+// Package hook implements running of user-defined commands on events, e.g. lost connection or fired alert. Details
+// of event are passed to command's stdin as JSON document, hence commands could be used for custom notifications and
+// audit trails.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Events on which hooks are run.
+const (
+	ConnectionLost     = "connection_lost"
+	ConnectionRestored = "connection_restored"
+	AlertFiring        = "alert_firing"
+	AlertResolved      = "alert_resolved"
+	BackendCancelled   = "backend_cancelled"
+	BackendTerminated  = "backend_terminated"
+	RecordingRotated   = "recording_rotated"
+	ProfileFinished    = "profile_finished"
+)
+
+// defaultTimeout defines default duration of hook's execution.
+const defaultTimeout = 10 * time.Second
+
+// events defines all known events.
+var events = []string{
+	ConnectionLost, ConnectionRestored, AlertFiring, AlertResolved, BackendCancelled, BackendTerminated, RecordingRotated,
+	ProfileFinished,
+}
+
+// Config defines hook.
+type Config struct {
+	Events  []string      `yaml:"events"`  // events on which command is run, all events if empty
+	Command []string      `yaml:"command"` // command and its arguments
+	Timeout time.Duration `yaml:"timeout"` // maximum duration of command execution, default 10s
+}
+
+// Event defines event passed to hooks.
+type Event struct {
+	Event    string      `json:"event"`
+	Time     time.Time   `json:"time"`
+	Instance string      `json:"instance,omitempty"` // Postgres instance where event happened
+	Details  interface{} `json:"details,omitempty"`  // event-specific details
+}
+
+// Runner runs hooks on events. Nil runner is valid and does nothing, hence it could be used when no hooks configured.
+type Runner struct {
+	hooks     []Config
+	listeners []func(Event)                         // functions called on every event
+	logf      func(format string, a ...interface{}) // logs failed hooks
+	wg        sync.WaitGroup                        // running hooks
+}
+
+// NewRunner validates hooks configuration and creates runner. Nil runner is returned if no hooks configured.
+func NewRunner(configs []Config, logf func(format string, a ...interface{})) (*Runner, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	hooks := make([]Config, len(configs))
+	for i, c := range configs {
+		err := c.validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid hook %d: %s", i+1, err)
+		}
+
+		if c.Timeout == 0 {
+			c.Timeout = defaultTimeout
+		}
+		hooks[i] = c
+	}
+
+	return &Runner{hooks: hooks, logf: logf}, nil
+}
+
+// Listen adds function called on every event regardless of configured hooks, e.g. for notifying user in UI. Listeners
+// are called synchronously, hence they should not block. Runner is created if it's nil, returned runner should be used.
+func (r *Runner) Listen(fn func(Event)) *Runner {
+	if r == nil {
+		r = &Runner{logf: func(string, ...interface{}) {}}
+	}
+
+	r.listeners = append(r.listeners, fn)
+	return r
+}
+
+// Fire runs hooks subscribed to the event in background, hence slow hooks don't block caller.
+func (r *Runner) Fire(e Event) {
+	if r == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	for _, fn := range r.listeners {
+		fn(e)
+	}
+
+	if len(r.hooks) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		r.logf("hooks: marshal %s event failed: %s", e.Event, err)
+		return
+	}
+	data = append(data, '\n')
+
+	for _, h := range r.hooks {
+		if !h.subscribed(e.Event) {
+			continue
+		}
+
+		r.wg.Add(1)
+		go func(h Config) {
+			defer r.wg.Done()
+
+			if err := run(h, data); err != nil {
+				r.logf("hooks: run %s on %s failed: %s", h.Command[0], e.Event, err)
+			}
+		}(h)
+	}
+}
+
+// Wait waits until all running hooks are finished.
+func (r *Runner) Wait() {
+	if r == nil {
+		return
+	}
+
+	r.wg.Wait()
+}
+
+// InstanceName returns name of Postgres instance used in events.
+func InstanceName(c postgres.Config) string {
+	if c.Config == nil {
+		return ""
+	}
+	return net.JoinHostPort(c.Config.Host, strconv.Itoa(int(c.Config.Port))) + "/" + c.Config.Database
+}
+
+// validate checks hook configuration.
+func (c Config) validate() error {
+	if len(c.Command) == 0 || c.Command[0] == "" {
+		return fmt.Errorf("command is not specified")
+	}
+
+	if c.Timeout < 0 {
+		return fmt.Errorf("invalid timeout, must be positive")
+	}
+
+	for _, e := range c.Events {
+		if !Known(e) {
+			return fmt.Errorf("unknown event '%s', use one of: %s", e, strings.Join(events, ", "))
+		}
+	}
+
+	return nil
+}
+
+// subscribed returns true if hook should be run on the event.
+func (c Config) subscribed(event string) bool {
+	if len(c.Events) == 0 {
+		return true
+	}
+
+	for _, e := range c.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Known returns true if event is known.
+func Known(event string) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// run runs hook's command and writes event into its stdin.
+func run(h Config, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...) // #nosec G204
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s", h.Timeout)
+		}
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
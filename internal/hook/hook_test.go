@@ -0,0 +1,110 @@
+package hook
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewRunner(t *testing.T) {
+	r, err := NewRunner(nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+
+	// Nil runner does nothing.
+	r.Fire(Event{Event: ConnectionLost})
+	r.Wait()
+
+	r, err = NewRunner([]Config{{Command: []string{"true"}}, {Events: []string{AlertFiring}, Command: []string{"true"}, Timeout: time.Second}}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, r.hooks, 2)
+	assert.Equal(t, defaultTimeout, r.hooks[0].Timeout)
+	assert.Equal(t, time.Second, r.hooks[1].Timeout)
+
+	for _, c := range []Config{
+		{},
+		{Command: []string{""}},
+		{Command: []string{"true"}, Timeout: -time.Second},
+		{Command: []string{"true"}, Events: []string{"unknown"}},
+	} {
+		_, err := NewRunner([]Config{c}, nil)
+		assert.Error(t, err)
+	}
+}
+
+func TestRunner_Listen(t *testing.T) {
+	var got []string
+
+	// Runner is created when no hooks configured.
+	var r *Runner
+	r = r.Listen(func(e Event) { got = append(got, e.Event) })
+	assert.NotNil(t, r)
+
+	r.Fire(Event{Event: ConnectionLost})
+	r.Fire(Event{Event: ProfileFinished})
+	r.Wait()
+	assert.Equal(t, []string{ConnectionLost, ProfileFinished}, got)
+}
+
+func TestRunner_Fire(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-hook-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	all := filepath.Join(dir, "all.json")
+	alerts := filepath.Join(dir, "alerts.json")
+
+	var errs []string
+	logf := func(format string, a ...interface{}) { errs = append(errs, fmt.Sprintf(format, a...)) }
+
+	r, err := NewRunner([]Config{
+		{Command: []string{"sh", "-c", "cat >> " + all}},
+		{Events: []string{AlertFiring, AlertResolved}, Command: []string{"sh", "-c", "cat >> " + alerts}},
+	}, logf)
+	assert.NoError(t, err)
+
+	ts := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.Fire(Event{Event: BackendTerminated, Time: ts, Instance: "127.0.0.1:5432/postgres", Details: map[string]int{"pid": 123}})
+	r.Wait()
+
+	data, err := ioutil.ReadFile(all)
+	assert.NoError(t, err)
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, map[string]interface{}{
+		"event": "backend_terminated", "time": "2021-01-01T00:00:00Z", "instance": "127.0.0.1:5432/postgres",
+		"details": map[string]interface{}{"pid": float64(123)},
+	}, got)
+
+	// Hook subscribed to alerts only is not run.
+	_, err = os.Stat(alerts)
+	assert.True(t, os.IsNotExist(err))
+
+	r.Fire(Event{Event: AlertFiring, Time: ts})
+	r.Wait()
+	data, err = ioutil.ReadFile(alerts)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"event":"alert_firing","time":"2021-01-01T00:00:00Z"}`+"\n", string(data))
+	assert.Len(t, errs, 0)
+
+	// Failed hooks are logged.
+	r, err = NewRunner([]Config{{Command: []string{"sh", "-c", "echo failed >&2; exit 1"}}}, logf)
+	assert.NoError(t, err)
+	r.Fire(Event{Event: ConnectionLost})
+	r.Wait()
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0], "failed")
+
+	// Slow hooks are killed.
+	r, err = NewRunner([]Config{{Command: []string{"sleep", "10"}, Timeout: 100 * time.Millisecond}}, logf)
+	assert.NoError(t, err)
+	r.Fire(Event{Event: ConnectionLost})
+	r.Wait()
+	assert.Len(t, errs, 2)
+	assert.Contains(t, errs[1], "timed out")
+}
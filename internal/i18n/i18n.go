@@ -0,0 +1,139 @@
+// Package i18n implements translations of UI messages and configurable names of stats columns.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultLocale defines locale used when locale is not specified or not supported.
+	DefaultLocale = "en"
+)
+
+// catalogs defines built-in translations keyed by locale, English catalog contains all messages.
+var catalogs = map[string]map[string]string{
+	"en": messagesEN,
+	"ru": messagesRU,
+}
+
+// Config defines configuration of UI language.
+type Config struct {
+	Locale   string            `yaml:"locale"`   // locale of UI messages, by default LC_ALL, LC_MESSAGES or LANG is used
+	Messages map[string]string `yaml:"messages"` // messages which override messages of the locale, keyed by message ID
+	Columns  map[string]string `yaml:"columns"`  // names of columns shown in UI, keyed by 'view.column' or 'column'
+}
+
+// Catalog defines messages of selected locale.
+type Catalog struct {
+	locale   string
+	messages map[string]string
+	columns  map[string]string
+}
+
+// New creates catalog of messages using configuration. Locale specified in configuration must be supported, locale
+// taken from environment falls back to default one.
+func New(c Config) (*Catalog, error) {
+	locale := c.Locale
+	if locale != "" {
+		locale = parseLocale(locale)
+		if _, ok := catalogs[locale]; !ok {
+			return nil, fmt.Errorf("unsupported locale '%s', supported: %s", c.Locale, strings.Join(Locales(), ", "))
+		}
+	} else {
+		locale = envLocale()
+	}
+
+	for id := range c.Messages {
+		if _, ok := messagesEN[id]; !ok {
+			return nil, fmt.Errorf("unknown message '%s'", id)
+		}
+	}
+
+	return &Catalog{locale: locale, messages: c.Messages, columns: c.Columns}, nil
+}
+
+// Default returns catalog of default locale.
+func Default() *Catalog {
+	return &Catalog{locale: DefaultLocale}
+}
+
+// Locales returns list of supported locales.
+func Locales() []string {
+	res := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		res = append(res, l)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Locale returns locale of the catalog.
+func (c *Catalog) Locale() string {
+	return c.locale
+}
+
+// T returns message with specified ID. Messages missing in the locale are taken from default locale.
+func (c *Catalog) T(id string) string {
+	if m, ok := c.messages[id]; ok {
+		return m
+	}
+	if m, ok := catalogs[c.locale][id]; ok {
+		return m
+	}
+	if m, ok := messagesEN[id]; ok {
+		return m
+	}
+	return id
+}
+
+// Sprintf formats message with specified ID.
+func (c *Catalog) Sprintf(id string, a ...interface{}) string {
+	return fmt.Sprintf(c.T(id), a...)
+}
+
+// Column returns name of the view's column shown in UI. Mapping for the view's column takes precedence over mapping
+// for the column of any view.
+func (c *Catalog) Column(view string, name string) string {
+	if n, ok := c.columns[view+"."+name]; ok {
+		return n
+	}
+	if n, ok := c.columns[name]; ok {
+		return n
+	}
+	return name
+}
+
+// envLocale returns locale specified in environment variables in order of their priority, the same way as gettext does.
+func envLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+
+		locale := parseLocale(value)
+		if _, ok := catalogs[locale]; ok {
+			return locale
+		}
+		return DefaultLocale
+	}
+
+	return DefaultLocale
+}
+
+// parseLocale returns language of locale in format 'language[_territory][.codeset][@modifier]', e.g. 'ru_RU.UTF-8'.
+func parseLocale(s string) string {
+	if i := strings.IndexAny(s, "_.@"); i >= 0 {
+		s = s[:i]
+	}
+
+	s = strings.ToLower(s)
+	if s == "c" || s == "posix" {
+		return DefaultLocale
+	}
+
+	return s
+}
@@ -0,0 +1,96 @@
+package i18n
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	c, err := New(Config{Locale: "ru_RU.UTF-8"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ru", c.Locale())
+
+	c, err = New(Config{Locale: "C"})
+	assert.NoError(t, err)
+	assert.Equal(t, "en", c.Locale())
+
+	_, err = New(Config{Locale: "xx"})
+	assert.Error(t, err)
+
+	_, err = New(Config{Messages: map[string]string{"unknown": "message"}})
+	assert.Error(t, err)
+}
+
+func TestNew_environment(t *testing.T) {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value, ok := os.LookupEnv(name)
+		assert.NoError(t, os.Unsetenv(name))
+		if ok {
+			defer func(name, value string) { _ = os.Setenv(name, value) }(name, value)
+		}
+	}
+
+	testcases := []struct {
+		env  map[string]string
+		want string
+	}{
+		{env: map[string]string{}, want: "en"},
+		{env: map[string]string{"LANG": "ru_RU.UTF-8"}, want: "ru"},
+		{env: map[string]string{"LANG": "de_DE.UTF-8"}, want: "en"},
+		{env: map[string]string{"LANG": "ru_RU.UTF-8", "LC_MESSAGES": "en_US.UTF-8"}, want: "en"},
+		{env: map[string]string{"LANG": "en_US.UTF-8", "LC_ALL": "ru_RU"}, want: "ru"},
+	}
+
+	for _, tc := range testcases {
+		for k, v := range tc.env {
+			assert.NoError(t, os.Setenv(k, v))
+		}
+
+		c, err := New(Config{})
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, c.Locale())
+
+		for k := range tc.env {
+			assert.NoError(t, os.Unsetenv(k))
+		}
+	}
+}
+
+func TestCatalog_T(t *testing.T) {
+	c, err := New(Config{Locale: "ru", Messages: map[string]string{"dialog.filter": "Фильтр: "}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Фильтр: ", c.T("dialog.filter"))
+	assert.Equal(t, messagesRU["dialog.cancel_query"], c.T("dialog.cancel_query"))
+	assert.Equal(t, "unknown", c.T("unknown"))
+
+	assert.Equal(t, "PID to cancel: ", Default().T("dialog.cancel_query"))
+	assert.Equal(t, ", 1h since reset", Default().Sprintf("header.reset_age", "1h"))
+}
+
+func TestCatalog_Column(t *testing.T) {
+	c, err := New(Config{Locale: "en", Columns: map[string]string{"datname": "database", "tables.relname": "table"}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "database", c.Column("databases", "datname"))
+	assert.Equal(t, "table", c.Column("tables", "relname"))
+	assert.Equal(t, "relname", c.Column("indexes", "relname"))
+	assert.Equal(t, "relname", Default().Column("tables", "relname"))
+}
+
+func TestCatalogs(t *testing.T) {
+	// Translations should not contain messages unknown in reference catalog, and should have the same formatting verbs.
+	for locale, messages := range catalogs {
+		for id, m := range messages {
+			en, ok := messagesEN[id]
+			assert.True(t, ok, "%s: %s", locale, id)
+			assert.Equal(t, strings.Count(en, "%"), strings.Count(m, "%"), "%s: %s", locale, id)
+		}
+	}
+}
+
+func TestLocales(t *testing.T) {
+	assert.Equal(t, []string{"en", "ru"}, Locales())
+}
@@ -0,0 +1,196 @@
+package i18n
+
+// messagesEN defines English messages, it is the reference catalog which defines IDs of all messages.
+var messagesEN = map[string]string{
+	"help": `Help for interactive commands
+
+general actions:
+    a,c,d,f,r,u mode: 'a' activity, 'c' checkpoints, 'd' databases, 'f' functions, 'r' replication, 'u' roles,
+    s,t,T,i           's' tables sizes, 't' tables, 'T' tables IO, 'i' indexes.
+    S                 index advisor: tables with hot sequential scans, candidates for indexing.
+    Z                 data directory health of local Postgres: permissions, backup and recovery files, pg_control.
+    j                 stale statistics: tables modified since the last analyze and columns statistics targets.
+    F                 foreign servers: user mappings, foreign tables and postgres_fdw connections.
+    w                 parallel queries: leaders and number of their parallel workers.
+    o                 replication slots: retained WAL, bytes spilled to disk and streamed by logical decoding.
+    x,X               'x' pg_stat_statements switch, 'X' pg_stat_statements menu.
+    (,),Backspace     visited views: '(' back, ')' forward, 'Backspace' toggle the last view.
+    g                 group rows: pg_stat_statements by normalized query, activity by query fingerprint.
+    @                 show ages and timestamps relative to current time, e.g. '2m 13s ago'.
+    p,P               'p' pg_stat_progress_* switch, 'P' pg_stat_progress_* menu.
+    H                 active sessions history menu: sampled sessions by wait events, users and queries.
+    e                 plugins menu, views of external collectors defined in configuration file.
+    Left,Right,<,/    'Left,Right' change column sort, '<' desc/asc sort toggle, '/' set filter.
+    Up,Down           'Up' increase column width, 'Down' decrease column width.
+    M                 read more rows from Postgres, rows sorted by rates are always read entirely.
+    PgUp,PgDn,[,]     scroll: 'PgUp,PgDn' rows, '[,]' columns with frozen key column, 'Home' reset.
+    C,E,R       config: 'C' show config, 'E' edit configs, 'R' reload config.
+    ~                 start psql session.
+    l                 open log file with pager.
+
+extra stats actions:
+    B,N,L,D     'B' diskstat, 'N' nicstat, 'L' logtail, 'D' directories usage.
+
+activity actions:
+    -,_         '-' cancel backend by pid, '_' terminate backend by pid.
+    n,m         'n' set new mask, 'm' show current mask.
+    k,K         'k' cancel group of queries using mask, 'K' terminate group of backends using mask.
+    I           show IDLE connections toggle.
+    A           change activity age threshold.
+    b           show activity and statements of specified users and databases only, format: user@database.
+    G           get query report.
+    Y           show recent plan of query logged by auto_explain.
+    W           profile wait events of backend by pid.
+
+other actions:
+    , Q         ',' show system tables on/off, 'Q' reset postgresql statistics counters.
+    z           'z' set refresh interval.
+    v           peek pending changes of logical replication slot (in replication slots view).
+    O           show log of connection events (disconnects and reconnects).
+    V           show availability of views on connected Postgres and why some views are limited.
+    J           show audit log of cancelled queries, terminated backends, stats resets, reloads and config edits.
+    y           show SQL query of the current view with applied order, limit and filters, 'c' copies it.
+    U           set role of the session (SET ROLE), empty input resets it to the session user.
+    Tab         switch to the next instance connected with --instance option.
+    h,F1        show this tab.
+    q,Ctrl+Q    quit.
+
+In read-only mode (--read-only) cancel, terminate, reset, reload and config editing actions are disabled.
+Actions which require privileges the connected role doesn't have (e.g. showing logs without pg_monitor)
+are disabled too, privileges of the role are shown at startup. Confirmation of actions is defined by
+policies in 'actions' section of configuration file.
+
+Type 'q' or 'Esc' to continue.`,
+
+	"dialog.reload":            "Reload configuration files (y/n): ",
+	"dialog.filter":            "Set filter: ",
+	"dialog.cancel_query":      "PID to cancel: ",
+	"dialog.terminate_backend": "PID to terminate: ",
+	"dialog.cancel_group":      "Cancel group of queries.",
+	"dialog.terminate_group":   "Terminate group of backends.",
+	"dialog.set_mask":          "Set state mask for group backends [a: active, i: idle, x: idle_xact, w: waiting, o: others]: ",
+	"dialog.change_age":        "Enter new min age, format: HH:MM:SS[.NN]: ",
+	"dialog.query_report":      "Enter the queryid: ",
+	"dialog.change_refresh":    "Change refresh (min 1, max 300) to ",
+	"dialog.profile_backend":   "PID to profile: ",
+	"dialog.set_role":          "Set role (empty - reset to session user): ",
+	"dialog.explain_plan":      "Enter the queryid to show plan: ",
+	"dialog.peek_changes":      "Slot to peek changes: ",
+	"dialog.query_filter":      "Filter by user@database, lists are comma-separated (empty - show all): ",
+	"dialog.canceled":          "Do nothing. Operation canceled.",
+
+	"dialog.confirm":             " Confirm [Enter or y - yes, Esc or n - no]",
+	"dialog.confirm.type":        " Type '%s' to confirm: ",
+	"dialog.confirm.mismatch":    "Do nothing. Typed name doesn't match.",
+	"dialog.confirm.rejected":    "Do nothing. Operation is not confirmed.",
+	"dialog.confirm.cancel":      "Cancel query of backend %s.",
+	"dialog.confirm.terminate":   "Terminate backend %s.",
+	"dialog.confirm.reset_stats": "Reset statistics of database %s.",
+	"dialog.confirm.reload":      "Reload configuration files.",
+	"dialog.confirm.edit_config": "Edit %s.",
+	"dialog.confirm.peek":        "Decode pending changes of slot %s, it might take a while.",
+
+	"dialog.denied.kill":    "Terminate backends or cancel queries allowed in pg_stat_activity view only.",
+	"dialog.denied.mask":    "State mask setup allowed in pg_stat_activity view only.",
+	"dialog.denied.age":     "Changing queries age threshold allowed in pg_stat_activity view only.",
+	"dialog.denied.profile": "Profiling backends allowed in pg_stat_activity view only.",
+	"dialog.denied.report":  "Query reports allowed in pg_stat_statements views only.",
+	"dialog.denied.plan":    "Showing plans allowed in pg_stat_statements views only.",
+	"dialog.denied.peek":    "Peeking changes allowed in replication slots view only.",
+
+	"cmdline.rows_limit":     "Rows limit: %d",
+	"cmdline.rows_limit.all": "Rows limit: all rows are read, rows sorted by rates, grouped or filtered are not limited by Postgres",
+
+	"cmdline.read_only": "%s is disabled in read-only mode.",
+
+	"action.reset_stats": "Reset statistics",
+	"action.edit_config": "Editing configuration",
+	"action.reload":      "Reloading configuration",
+	"action.cancel":      "Cancelling queries",
+	"action.terminate":   "Terminating backends",
+	"action.psql":        "Running psql",
+	"action.set_role":    "Setting role",
+
+	"cmdline.requires":          "%s requires %s.",
+	"action.show_log":           "Showing log",
+	"action.show_plans":         "Showing plans",
+	"requirement.reset_stats":   "superuser or EXECUTE privilege on pg_stat_reset()",
+	"requirement.read_settings": "superuser or pg_read_all_settings role",
+	"requirement.read_logs":     "superuser or pg_monitor role",
+	"requirement.reload":        "superuser or EXECUTE privilege on pg_reload_conf()",
+	"privileges.superuser":      "Privileges: superuser, all features are available.",
+	"privileges.regular":        "Privileges: regular role",
+	"privileges.roles":          "Privileges: %s",
+	"privileges.available":      "%s, all features are available.",
+	"privileges.unavailable":    "%s; unavailable: %s.",
+	"privileges.all_stats":      "other roles' sessions details",
+	"privileges.logs":           "logs",
+	"privileges.read_settings":  "config editing",
+	"privileges.reset_stats":    "stats reset",
+	"privileges.reload":         "config reload",
+	"privileges.signal_all":     "signalling other roles' backends",
+
+	"cmdline.policy.disabled":   "%s is disabled by policy.",
+	"cmdline.policy.do_nothing": "Do nothing, action is disabled by policy.",
+	"action.peek":               "Peeking changes",
+
+	"peek.do_nothing":     "Peek: do nothing",
+	"peek.failed":         "Peek: %s",
+	"peek.timeout":        "Peek: decoding of changes cancelled, it takes longer than %s.",
+	"peek.not_found":      "Peek: slot %s doesn't exist.",
+	"peek.no_changes":     "Peek: no pending changes in slot %s",
+	"peek.physical":       "Peek: slot %s is physical, only changes of logical slots could be peeked.",
+	"peek.binary":         "Peek: slot %s uses %s plugin with binary output, only changes of text plugins (e.g. test_decoding, wal2json) could be peeked.",
+	"peek.other_database": "Peek: slot %s belongs to other database, connect to the slot's database to peek its changes.",
+	"peek.active":         "Peek: slot %s is active, changes of slot used by consumer can't be peeked.",
+
+	"signals.done":          "Signals: done",
+	"signals.do_nothing":    "Signals: do nothing, %s",
+	"signals.unknown_mode":  "Signals: do nothing, unknown mode",
+	"signals.empty_mask":    "Signals: do nothing, process mask is empty",
+	"signals.activity_only": "Signals: sending signals allowed in pg_stat_activity only",
+	"signals.failed":        "Signals: %s",
+	"signals.cancelled":     "Signals: cancelled %d queries.",
+	"signals.terminated":    "Signals: terminated %d backends.",
+
+	"audit.title": " Audit log %s (Esc or q - close) ",
+	"audit.empty": "No audited actions",
+
+	"cmdline.audit":                "%s Audit: %s",
+	"cmdline.audit.failed":         "Audit: %s",
+	"cmdline.audit.not_configured": "Audit log is not configured.",
+	"cmdline.edit_config.audit":    "Edit config: do nothing, %s",
+
+	"cmdline.group_statements.on":  "Group statements by normalized query: on.",
+	"cmdline.group_statements.off": "Group statements by normalized query: off.",
+
+	"cmdline.group_activity.on":  "Group activity by queries: on.",
+	"cmdline.group_activity.off": "Group activity by queries: off.",
+
+	"cmdline.relative_time.on":  "Relative time: on.",
+	"cmdline.relative_time.off": "Relative time: off.",
+
+	"cmdline.history.no_previous": "No previous views.",
+	"cmdline.history.no_next":     "No next views.",
+
+	"cmdline.view_unsupported": "NOTICE: %s view is not supported by connected Postgres, press 'V' for details",
+
+	"notice.stats_reset": "Stats reset detected, rates are calculated since reset.",
+	"notice.io_timing":   "track_io_timing is off: enable it to see time and average latency of blocks reads and writes (read_t, write_t, read_lat, write_lat)",
+
+	"header.load":        "pgcenter: %s, load average: %.2f, %.2f, %.2f",
+	"header.cpu":         "    %%cpu: %s us, %s sy, %s ni, %s id, %s wa, %s hi, %s si, %s st",
+	"header.mem":         " MiB mem: %s total, %s free, %s used, %s buff/cached",
+	"header.swap":        "MiB swap: %s total, %s free, %s used, %s dirty/writeback",
+	"header.io":          "      io: %s r/s, %s w/s, %s rMB/s, %s wMB/s, %s max %%util",
+	"header.net":         "     net: %s rMbps, %s wMbps, %s rPk/s, %s wPk/s, %s errs/s",
+	"header.activity":    "  activity:%s conns,%s prepared,%s idle,%s idle_xact,%s active,%s waiting,%s others",
+	"header.autovacuum":  "autovacuum: %s workers/max, %s manual, %s wraparound, %s vac_maxtime",
+	"header.statements":  "statements: %s stmt/s, %s stmt_avgtime, %s xact_maxtime, %s prep_maxtime",
+	"header.reset_age":   ", %s since reset",
+	"header.replication": "  replicas:%s connected, %s lag",
+	"header.alerts":      "    alerts: %s",
+	"header.alerts_none": "none firing",
+
+	"header.wraparound_badge": "  [anti-wraparound vacuum running, don't cancel it]",
+}
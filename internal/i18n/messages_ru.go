@@ -0,0 +1,196 @@
+package i18n
+
+// messagesRU defines Russian messages.
+var messagesRU = map[string]string{
+	"help": `Справка по интерактивным командам
+
+основные действия:
+    a,c,d,f,r,u режим: 'a' активность, 'c' контрольные точки, 'd' базы данных, 'f' функции, 'r' репликация, 'u' роли,
+    s,t,T,i            's' размеры таблиц, 't' таблицы, 'T' ввод-вывод таблиц, 'i' индексы.
+    S                  советник индексов: таблицы с частыми последовательными чтениями, кандидаты на индексы.
+    Z                  состояние каталога данных локального Postgres: права, файлы бэкапа и восстановления, pg_control.
+    j                  устаревшая статистика: изменения таблиц с последнего analyze и цели статистики столбцов.
+    F                  сторонние серверы: сопоставления пользователей, сторонние таблицы и соединения postgres_fdw.
+    w                  параллельные запросы: ведущие процессы и число их параллельных исполнителей.
+    o                  слоты репликации: удерживаемый WAL, объем сброшенных на диск и переданных потоком изменений.
+    x,X                'x' переключение pg_stat_statements, 'X' меню pg_stat_statements.
+    (,),Backspace      посещенные представления: '(' назад, ')' вперед, 'Backspace' предыдущее представление.
+    g                  группировать строки: pg_stat_statements и активность по нормализованному запросу.
+    @                  показывать возраст и метки времени относительно текущего времени, например '2m 13s ago'.
+    p,P                'p' переключение pg_stat_progress_*, 'P' меню pg_stat_progress_*.
+    H                  меню истории активных сессий: выборки сессий по событиям ожидания, пользователям и запросам.
+    e                  меню плагинов, представления внешних сборщиков из файла конфигурации.
+    Left,Right,<,/     'Left,Right' смена колонки сортировки, '<' порядок сортировки, '/' фильтр.
+    Up,Down            'Up' увеличить ширину колонки, 'Down' уменьшить ширину колонки.
+    M                  прочитать больше строк из Postgres, строки, отсортированные по скоростям, читаются целиком.
+    PgUp,PgDn,[,]      прокрутка: 'PgUp,PgDn' строки, '[,]' колонки с закрепленной ключевой, 'Home' сброс.
+    C,E,R       конфигурация: 'C' показать, 'E' редактировать, 'R' перечитать.
+    ~                  запустить сессию psql.
+    l                  открыть лог-файл в пейджере.
+
+дополнительная статистика:
+    B,N,L,D     'B' диски, 'N' сетевые интерфейсы, 'L' хвост лога, 'D' размер каталогов.
+
+действия с активностью:
+    -,_         '-' отменить запрос по pid, '_' завершить процесс по pid.
+    n,m         'n' задать маску, 'm' показать текущую маску.
+    k,K         'k' отменить группу запросов по маске, 'K' завершить группу процессов по маске.
+    I           показывать IDLE соединения.
+    A           изменить порог возраста активности.
+    b           показывать активность и запросы только указанных пользователей и баз, формат: user@database.
+    G           получить отчет по запросу.
+    Y           показать последний план запроса из лога auto_explain.
+    W           профилировать события ожидания процесса по pid.
+
+прочие действия:
+    , Q         ',' показывать системные таблицы, 'Q' сбросить счетчики статистики postgresql.
+    z           'z' задать интервал обновления.
+    v           просмотреть ожидающие изменения логического слота репликации (в представлении слотов).
+    O           показать журнал событий соединения (разрывы и переподключения).
+    V           показать доступность представлений на подключенном Postgres и причины ограничений.
+    J           показать журнал аудита: отмены запросов, завершения процессов, сбросы статистики, перечитывания и правки конфигурации.
+    y           показать SQL-запрос текущего представления с учетом сортировки, лимита и фильтров, 'c' копирует его.
+    U           задать роль сессии (SET ROLE), пустой ввод возвращает роль пользователя сессии.
+    Tab         переключиться на следующий экземпляр, подключенный через опцию --instance.
+    h,F1        показать эту справку.
+    q,Ctrl+Q    выход.
+
+В режиме только для чтения (--read-only) отмена, завершение, сброс, перечитывание и редактирование
+конфигурации отключены. Действия, требующие привилегий, которых нет у роли (например, просмотр логов
+без pg_monitor), также отключены, привилегии роли показываются при запуске. Подтверждение действий
+задается политиками в секции 'actions' файла конфигурации.
+
+Нажмите 'q' или 'Esc' для продолжения.`,
+
+	"dialog.reload":            "Перечитать файлы конфигурации (y/n): ",
+	"dialog.filter":            "Задать фильтр: ",
+	"dialog.cancel_query":      "PID для отмены: ",
+	"dialog.terminate_backend": "PID для завершения: ",
+	"dialog.cancel_group":      "Отменить группу запросов.",
+	"dialog.terminate_group":   "Завершить группу процессов.",
+	"dialog.set_mask":          "Маска состояний для группы процессов [a: active, i: idle, x: idle_xact, w: waiting, o: others]: ",
+	"dialog.change_age":        "Новый минимальный возраст, формат: HH:MM:SS[.NN]: ",
+	"dialog.query_report":      "Введите queryid: ",
+	"dialog.change_refresh":    "Интервал обновления (мин 1, макс 300): ",
+	"dialog.profile_backend":   "PID для профилирования: ",
+	"dialog.set_role":          "Задать роль (пусто - роль пользователя сессии): ",
+	"dialog.explain_plan":      "Введите queryid для показа плана: ",
+	"dialog.peek_changes":      "Слот для просмотра изменений: ",
+	"dialog.query_filter":      "Фильтр user@database, списки через запятую (пусто - показывать все): ",
+	"dialog.canceled":          "Ничего не сделано. Операция отменена.",
+
+	"dialog.confirm":             " Подтвердите [Enter или y - да, Esc или n - нет]",
+	"dialog.confirm.type":        " Введите '%s' для подтверждения: ",
+	"dialog.confirm.mismatch":    "Ничего не сделано. Введенное имя не совпадает.",
+	"dialog.confirm.rejected":    "Ничего не сделано. Операция не подтверждена.",
+	"dialog.confirm.cancel":      "Отменить запрос процесса %s.",
+	"dialog.confirm.terminate":   "Завершить процесс %s.",
+	"dialog.confirm.reset_stats": "Сбросить статистику базы данных %s.",
+	"dialog.confirm.reload":      "Перечитать файлы конфигурации.",
+	"dialog.confirm.edit_config": "Редактировать %s.",
+	"dialog.confirm.peek":        "Декодировать ожидающие изменения слота %s, это может занять время.",
+
+	"dialog.denied.kill":    "Завершение процессов и отмена запросов доступны только в представлении pg_stat_activity.",
+	"dialog.denied.mask":    "Маска состояний задается только в представлении pg_stat_activity.",
+	"dialog.denied.age":     "Порог возраста запросов изменяется только в представлении pg_stat_activity.",
+	"dialog.denied.profile": "Профилирование процессов доступно только в представлении pg_stat_activity.",
+	"dialog.denied.report":  "Отчеты по запросам доступны только в представлениях pg_stat_statements.",
+	"dialog.denied.plan":    "Планы запросов доступны только в представлениях pg_stat_statements.",
+	"dialog.denied.peek":    "Просмотр изменений доступен только в представлении слотов репликации.",
+
+	"cmdline.rows_limit":     "Лимит строк: %d",
+	"cmdline.rows_limit.all": "Лимит строк: читаются все строки, отсортированные по скоростям, сгруппированные и отфильтрованные строки не ограничиваются Postgres",
+
+	"cmdline.read_only": "Действие «%s» недоступно в режиме только для чтения.",
+
+	"action.reset_stats": "Сброс статистики",
+	"action.edit_config": "Редактирование конфигурации",
+	"action.reload":      "Перечитывание конфигурации",
+	"action.cancel":      "Отмена запросов",
+	"action.terminate":   "Завершение процессов",
+	"action.psql":        "Запуск psql",
+	"action.set_role":    "Смена роли",
+
+	"cmdline.requires":          "Действие «%s» требует: %s.",
+	"action.show_log":           "Просмотр лога",
+	"action.show_plans":         "Просмотр планов",
+	"requirement.reset_stats":   "суперпользователь или привилегия EXECUTE на pg_stat_reset()",
+	"requirement.read_settings": "суперпользователь или роль pg_read_all_settings",
+	"requirement.read_logs":     "суперпользователь или роль pg_monitor",
+	"requirement.reload":        "суперпользователь или привилегия EXECUTE на pg_reload_conf()",
+	"privileges.superuser":      "Привилегии: суперпользователь, доступны все функции.",
+	"privileges.regular":        "Привилегии: обычная роль",
+	"privileges.roles":          "Привилегии: %s",
+	"privileges.available":      "%s, доступны все функции.",
+	"privileges.unavailable":    "%s; недоступно: %s.",
+	"privileges.all_stats":      "детали сессий других ролей",
+	"privileges.logs":           "логи",
+	"privileges.read_settings":  "редактирование конфигурации",
+	"privileges.reset_stats":    "сброс статистики",
+	"privileges.reload":         "перечитывание конфигурации",
+	"privileges.signal_all":     "сигналы процессам других ролей",
+
+	"cmdline.policy.disabled":   "Действие «%s» запрещено политикой.",
+	"cmdline.policy.do_nothing": "Ничего не сделано, действие запрещено политикой.",
+	"action.peek":               "Просмотр изменений",
+
+	"peek.do_nothing":     "Просмотр: ничего не сделано",
+	"peek.failed":         "Просмотр: %s",
+	"peek.timeout":        "Просмотр: декодирование изменений отменено, оно занимает больше %s.",
+	"peek.not_found":      "Просмотр: слот %s не существует.",
+	"peek.no_changes":     "Просмотр: в слоте %s нет ожидающих изменений",
+	"peek.physical":       "Просмотр: слот %s физический, просматривать можно только изменения логических слотов.",
+	"peek.binary":         "Просмотр: слот %s использует плагин %s с двоичным выводом, просматривать можно только изменения текстовых плагинов (например, test_decoding, wal2json).",
+	"peek.other_database": "Просмотр: слот %s относится к другой базе данных, подключитесь к базе слота для просмотра его изменений.",
+	"peek.active":         "Просмотр: слот %s активен, изменения слота, используемого потребителем, просматривать нельзя.",
+
+	"signals.done":          "Сигналы: выполнено",
+	"signals.do_nothing":    "Сигналы: ничего не сделано, %s",
+	"signals.unknown_mode":  "Сигналы: ничего не сделано, неизвестный режим",
+	"signals.empty_mask":    "Сигналы: ничего не сделано, маска процессов пуста",
+	"signals.activity_only": "Сигналы: отправка сигналов доступна только в pg_stat_activity",
+	"signals.failed":        "Сигналы: %s",
+	"signals.cancelled":     "Сигналы: отменено запросов: %d.",
+	"signals.terminated":    "Сигналы: завершено процессов: %d.",
+
+	"audit.title": " Журнал аудита %s (Esc или q - закрыть) ",
+	"audit.empty": "Нет записей аудита",
+
+	"cmdline.audit":                "%s Аудит: %s",
+	"cmdline.audit.failed":         "Аудит: %s",
+	"cmdline.audit.not_configured": "Журнал аудита не настроен.",
+	"cmdline.edit_config.audit":    "Редактирование конфигурации: ничего не сделано, %s",
+
+	"cmdline.group_statements.on":  "Группировка запросов по нормализованному тексту: вкл.",
+	"cmdline.group_statements.off": "Группировка запросов по нормализованному тексту: выкл.",
+
+	"cmdline.group_activity.on":  "Группировка активности по запросам: вкл.",
+	"cmdline.group_activity.off": "Группировка активности по запросам: выкл.",
+
+	"cmdline.relative_time.on":  "Относительное время: вкл.",
+	"cmdline.relative_time.off": "Относительное время: выкл.",
+
+	"cmdline.history.no_previous": "Нет предыдущих представлений.",
+	"cmdline.history.no_next":     "Нет следующих представлений.",
+
+	"cmdline.view_unsupported": "ВНИМАНИЕ: представление %s не поддерживается подключенным Postgres, подробности по клавише 'V'",
+
+	"notice.stats_reset": "Обнаружен сброс статистики, скорости рассчитаны с момента сброса.",
+	"notice.io_timing":   "track_io_timing выключен: включите его, чтобы видеть время и среднюю задержку чтения и записи блоков (read_t, write_t, read_lat, write_lat)",
+
+	"header.load":        "pgcenter: %s, средняя нагрузка: %.2f, %.2f, %.2f",
+	"header.cpu":         "     %%цп: %s us, %s sy, %s ni, %s id, %s wa, %s hi, %s si, %s st",
+	"header.mem":         "  МиБ пам: %s всего, %s своб, %s занято, %s буферы/кэш",
+	"header.swap":        " МиБ своп: %s всего, %s своб, %s занято, %s dirty/writeback",
+	"header.io":          "    диски: %s чт/с, %s зап/с, %s чтМБ/с, %s запМБ/с, %s макс %%util",
+	"header.net":         "     сеть: %s прМбит/с, %s перМбит/с, %s прПак/с, %s перПак/с, %s ошибок/с",
+	"header.activity":    " активность:%s соедин,%s подгот,%s idle,%s idle_xact,%s active,%s waiting,%s прочие",
+	"header.autovacuum":  " автовакуум: %s процессы/макс, %s ручные, %s wraparound, %s vac_maxtime",
+	"header.statements":  "    запросы: %s запр/с, %s stmt_avgtime, %s xact_maxtime, %s prep_maxtime",
+	"header.reset_age":   ", %s после сброса",
+	"header.replication": "    реплики:%s подключено, %s отставание",
+	"header.alerts":      " оповещения: %s",
+	"header.alerts_none": "нет активных",
+
+	"header.wraparound_badge": "  [идет автовакуум против wraparound, не отменяйте его]",
+}
@@ -0,0 +1,29 @@
+// Package interrupt provides contexts which are cancelled by signals, hence queries in flight are cancelled when
+// user interrupts the program.
+package interrupt
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// Context returns copy of the parent context which is cancelled when one of specified signals is received. Calling
+// the returned cancel function stops relaying signals and releases resources associated with the context.
+func Context(parent context.Context, sigs ...os.Signal) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+		}
+		signal.Stop(ch)
+		cancel()
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,36 @@
+package interrupt
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestContext(t *testing.T) {
+	ctx, cancel := Context(context.Background(), syscall.SIGUSR1)
+	defer cancel()
+
+	assert.NoError(t, ctx.Err())
+
+	p, err := os.FindProcess(os.Getpid())
+	assert.NoError(t, err)
+	assert.NoError(t, p.Signal(syscall.SIGUSR1))
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context has not been cancelled by signal")
+	}
+
+	// Cancelling the parent context cancels the context too.
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel = Context(parent, syscall.SIGUSR1)
+	defer cancel()
+
+	cancelParent()
+	<-ctx.Done()
+	assert.Error(t, ctx.Err())
+}
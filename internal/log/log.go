@@ -0,0 +1,166 @@
+// Package log implements leveled logger which writes structured records in logfmt format, e.g.
+// 'time=2021-01-02T15:04:05.000Z level=warn msg="read link settings failed" iface=eth0 error="no such device"'.
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level defines severity of log records.
+type Level int
+
+const (
+	LevelDebug Level = iota // details useful for diagnosing issues, e.g. errors which are ignored by collectors
+	LevelInfo               // notable events, e.g. connection has been restored
+	LevelWarn               // errors which don't interrupt working of the program
+	LevelError              // errors which interrupt operation
+)
+
+// DefaultLevel defines level of records logged by default.
+const DefaultLevel = "warn"
+
+// levelNames defines names of levels used in flags and log records.
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// String returns name of the level.
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel returns level by its name.
+func ParseLevel(s string) (Level, error) {
+	for l, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return l, nil
+		}
+	}
+	return LevelWarn, fmt.Errorf("unknown log level '%s', allowed levels: debug, info, warn, error", s)
+}
+
+// Logger writes log records with severity not less than configured level.
+type Logger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	level  Level
+	stderr bool             // records are written to stderr
+	now    func() time.Time // used in tests
+}
+
+// New creates logger which writes records to the writer.
+func New(w io.Writer, level Level) *Logger {
+	return &Logger{out: w, level: level, stderr: w == os.Stderr, now: time.Now}
+}
+
+// std is the logger used by package-level functions. It writes warnings and errors to stderr until it is configured.
+var std = New(os.Stderr, LevelWarn)
+
+// Setup configures logger used by package-level functions. Records are appended to the file, or written to stderr
+// if filename is empty.
+func Setup(level string, filename string) error {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	if filename == "" {
+		SetLogger(New(os.Stderr, l))
+		return nil
+	}
+
+	f, err := os.OpenFile(filepath.Clean(filename), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("open log file failed: %s", err)
+	}
+
+	SetLogger(New(f, l))
+	return nil
+}
+
+// SetLogger replaces logger used by package-level functions.
+func SetLogger(l *Logger) {
+	std = l
+}
+
+// DisableStderr discards records if they are written to stderr, e.g. when terminal is used by full-screen UI. Records
+// written to log file are kept.
+func DisableStderr() {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+
+	if std.stderr {
+		std.out = ioutil.Discard
+	}
+}
+
+// Enabled returns true if records of the level are logged.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Log writes record with message and fields passed as key-value pairs.
+func (l *Logger) Log(level Level, msg string, kv ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("time=" + l.now().Format("2006-01-02T15:04:05.000Z07:00"))
+	buf.WriteString(" level=" + level.String())
+	buf.WriteString(" msg=" + formatValue(msg))
+
+	for i := 0; i < len(kv); i += 2 {
+		key := fmt.Sprint(kv[i])
+		var value interface{} = "(missing)"
+		if i+1 < len(kv) {
+			value = kv[i+1]
+		}
+		buf.WriteString(" " + key + "=" + formatValue(fmt.Sprint(value)))
+	}
+	buf.WriteByte('\n')
+
+	l.mu.Lock()
+	_, _ = l.out.Write(buf.Bytes())
+	l.mu.Unlock()
+}
+
+// formatValue quotes value if it is empty or contains spaces, quotes or equal signs.
+func formatValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// Debug writes debug record using default logger.
+func Debug(msg string, kv ...interface{}) {
+	std.Log(LevelDebug, msg, kv...)
+}
+
+// Info writes info record using default logger.
+func Info(msg string, kv ...interface{}) {
+	std.Log(LevelInfo, msg, kv...)
+}
+
+// Warn writes warning record using default logger.
+func Warn(msg string, kv ...interface{}) {
+	std.Log(LevelWarn, msg, kv...)
+}
+
+// Error writes error record using default logger.
+func Error(msg string, kv ...interface{}) {
+	std.Log(LevelError, msg, kv...)
+}
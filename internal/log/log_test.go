@@ -0,0 +1,80 @@
+package log
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseLevel(t *testing.T) {
+	testcases := []struct {
+		in    string
+		want  Level
+		valid bool
+	}{
+		{in: "debug", want: LevelDebug, valid: true},
+		{in: "INFO", want: LevelInfo, valid: true},
+		{in: "warn", want: LevelWarn, valid: true},
+		{in: "error", want: LevelError, valid: true},
+		{in: "invalid", want: LevelWarn, valid: false},
+	}
+
+	for _, tc := range testcases {
+		got, err := ParseLevel(tc.in)
+		if tc.valid {
+			assert.NoError(t, err)
+		} else {
+			assert.Error(t, err)
+		}
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestLogger_Log(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, LevelInfo)
+	l.now = func() time.Time { return time.Date(2021, 1, 2, 15, 4, 5, 0, time.UTC) }
+
+	l.Log(LevelDebug, "skipped")
+	assert.Equal(t, "", buf.String())
+
+	l.Log(LevelWarn, "read link settings failed", "iface", "eth0", "error", "no such device", "speed", 0, "odd")
+	assert.Equal(t,
+		"time=2021-01-02T15:04:05.000Z level=warn msg=\"read link settings failed\" iface=eth0 error=\"no such device\" speed=0 odd=(missing)\n",
+		buf.String(),
+	)
+}
+
+func TestSetup(t *testing.T) {
+	defer SetLogger(New(os.Stderr, LevelWarn))
+
+	assert.Error(t, Setup("invalid", ""))
+	assert.Error(t, Setup("info", "/nonexistent/pgcenter.log"))
+
+	f, err := ioutil.TempFile("", "pgcenter-log-test-")
+	assert.NoError(t, err)
+	_ = f.Close()
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	assert.NoError(t, Setup("info", f.Name()))
+	Debug("debug message")
+	Info("info message", "key", "value")
+
+	// Records written to file are not discarded.
+	DisableStderr()
+	Error("error message")
+
+	data, err := ioutil.ReadFile(f.Name())
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "debug message")
+	assert.Contains(t, string(data), "level=info msg=\"info message\" key=value\n")
+	assert.Contains(t, string(data), "level=error msg=\"error message\"\n")
+
+	// Records written to stderr are discarded.
+	assert.NoError(t, Setup("info", ""))
+	DisableStderr()
+	assert.Equal(t, ioutil.Discard, std.out)
+}
@@ -0,0 +1,153 @@
+// Package notify implements notifying user about events while 'top' program is running, e.g. firing alerts or lost
+// connections. Terminal bell is rung and desktop notifications are shown, hence events are noticed even when terminal
+// window is not focused.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Methods of showing desktop notifications.
+const (
+	OSC9       = "osc9"        // terminal escape sequence, works over SSH in terminals which support it
+	NotifySend = "notify-send" // notify-send command, works on the host where pgcenter is running
+)
+
+// notifySendTimeout defines maximum duration of notify-send execution.
+const notifySendTimeout = 5 * time.Second
+
+// defaultEvents defines events user is notified about by default.
+var defaultEvents = []string{hook.AlertFiring, hook.ConnectionLost, hook.ConnectionRestored, hook.ProfileFinished}
+
+// Config defines how user is notified and about which events.
+type Config struct {
+	Bell    bool     `yaml:"bell"`    // ring terminal bell
+	Desktop string   `yaml:"desktop"` // show desktop notifications: osc9 or notify-send, disabled if empty
+	Events  []string `yaml:"events"`  // events user is notified about, firing alerts, connection events and finished profiles if empty
+}
+
+// Enabled returns true if notifications are configured.
+func (c Config) Enabled() bool {
+	return c.Bell || c.Desktop != ""
+}
+
+// Validate checks notifications configuration.
+func (c Config) Validate() error {
+	switch c.Desktop {
+	case "", OSC9, NotifySend:
+	default:
+		return fmt.Errorf("invalid desktop notifications '%s', allowed: %s, %s", c.Desktop, OSC9, NotifySend)
+	}
+
+	for _, e := range c.Events {
+		if !hook.Known(e) {
+			return fmt.Errorf("unknown notifications event '%s'", e)
+		}
+	}
+
+	return nil
+}
+
+// subscribed returns true if user should be notified about the event.
+func (c Config) subscribed(event string) bool {
+	events := c.Events
+	if len(events) == 0 {
+		events = defaultEvents
+	}
+
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifier notifies user about events.
+type Notifier struct {
+	config Config
+	out    io.Writer                             // terminal where bell and escape sequences are written
+	logf   func(format string, a ...interface{}) // logs failed notifications
+}
+
+// New creates notifier which writes bell and escape sequences into terminal.
+func New(c Config, out io.Writer, logf func(format string, a ...interface{})) *Notifier {
+	return &Notifier{config: c, out: out, logf: logf}
+}
+
+// Notify notifies user about the event if user is subscribed to it. Desktop notifications shown by external command
+// are sent in background, hence Notify doesn't block.
+func (n *Notifier) Notify(e hook.Event) {
+	if !n.config.subscribed(e.Event) {
+		return
+	}
+
+	title, body := Message(e)
+
+	var seq string
+	if n.config.Bell {
+		seq += "\a"
+	}
+	if n.config.Desktop == OSC9 {
+		seq += "\x1b]9;" + sanitize(title+": "+body) + "\a"
+	}
+
+	if seq != "" {
+		if _, err := io.WriteString(n.out, seq); err != nil {
+			n.logf("notifications: write to terminal failed: %s", err)
+		}
+	}
+
+	if n.config.Desktop == NotifySend {
+		go func() {
+			if err := notifySend(title, body); err != nil {
+				n.logf("notifications: run notify-send failed: %s", err)
+			}
+		}()
+	}
+}
+
+// Message returns title and body of notification about the event.
+func Message(e hook.Event) (string, string) {
+	title := "pgcenter: " + strings.ReplaceAll(e.Event, "_", " ")
+
+	switch d := e.Details.(type) {
+	case alert.Event:
+		return title, d.Summary()
+	case map[string]string:
+		if msg, ok := d["error"]; ok {
+			return title, fmt.Sprintf("%s: %s", e.Instance, msg)
+		}
+	case map[string]interface{}:
+		if pid, ok := d["pid"]; ok {
+			return title, fmt.Sprintf("%s: process %v", e.Instance, pid)
+		}
+	}
+
+	return title, e.Instance
+}
+
+// sanitize removes control characters which could break escape sequence.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// notifySend shows desktop notification using notify-send command.
+func notifySend(title, body string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifySendTimeout)
+	defer cancel()
+
+	return exec.CommandContext(ctx, "notify-send", "--app-name=pgcenter", title, body).Run()
+}
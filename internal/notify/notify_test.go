@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"bytes"
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.NoError(t, Config{Bell: true, Desktop: OSC9, Events: []string{hook.AlertFiring, hook.ProfileFinished}}.Validate())
+	assert.NoError(t, Config{Desktop: NotifySend}.Validate())
+	assert.Error(t, Config{Desktop: "growl"}.Validate())
+	assert.Error(t, Config{Bell: true, Events: []string{"unknown"}}.Validate())
+}
+
+func TestConfig_Enabled(t *testing.T) {
+	assert.False(t, Config{}.Enabled())
+	assert.False(t, Config{Events: []string{hook.AlertFiring}}.Enabled())
+	assert.True(t, Config{Bell: true}.Enabled())
+	assert.True(t, Config{Desktop: OSC9}.Enabled())
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	var buf bytes.Buffer
+	logf := func(string, ...interface{}) {}
+
+	// Default events.
+	n := New(Config{Bell: true, Desktop: OSC9}, &buf, logf)
+	n.Notify(hook.Event{Event: hook.ConnectionLost, Instance: "127.0.0.1:5432/postgres", Details: map[string]string{"error": "EOF\n"}})
+	assert.Equal(t, "\a\x1b]9;pgcenter: connection lost: 127.0.0.1:5432/postgres: EOF \a", buf.String())
+
+	buf.Reset()
+	n.Notify(hook.Event{Event: hook.BackendTerminated})
+	assert.Equal(t, "", buf.String())
+
+	// Configured events, bell only.
+	n = New(Config{Bell: true, Events: []string{hook.BackendTerminated}}, &buf, logf)
+	n.Notify(hook.Event{Event: hook.ConnectionLost})
+	assert.Equal(t, "", buf.String())
+	n.Notify(hook.Event{Event: hook.BackendTerminated})
+	assert.Equal(t, "\a", buf.String())
+}
+
+func TestMessage(t *testing.T) {
+	testcases := []struct {
+		event hook.Event
+		title string
+		body  string
+	}{
+		{
+			event: hook.Event{Event: hook.AlertFiring, Instance: "db1:5432/postgres", Details: alert.Event{
+				Status: "firing", Rule: "xid_age", Instance: "db1:5432/postgres", Value: 1.6e9, Condition: "> 1500000000",
+			}},
+			title: "pgcenter: alert firing",
+			body:  "[FIRING] xid_age on db1:5432/postgres: value 1600000000, condition > 1500000000",
+		},
+		{
+			event: hook.Event{Event: hook.ConnectionLost, Instance: "db1:5432/postgres", Details: map[string]string{"error": "unexpected EOF"}},
+			title: "pgcenter: connection lost",
+			body:  "db1:5432/postgres: unexpected EOF",
+		},
+		{
+			event: hook.Event{Event: hook.ProfileFinished, Instance: "db1:5432/postgres", Details: map[string]interface{}{"pid": 1234}},
+			title: "pgcenter: profile finished",
+			body:  "db1:5432/postgres: process 1234",
+		},
+		{
+			event: hook.Event{Event: hook.ConnectionRestored, Instance: "db1:5432/postgres", Details: map[string]interface{}{"restarted": true}},
+			title: "pgcenter: connection restored",
+			body:  "db1:5432/postgres",
+		},
+	}
+
+	for _, tc := range testcases {
+		title, body := Message(tc.event)
+		assert.Equal(t, tc.title, title)
+		assert.Equal(t, tc.body, body)
+	}
+}
@@ -0,0 +1,145 @@
+// Package pgtime parses intervals and timestamps in text form returned by Postgres and formats them relative to the
+// current time, e.g. '2m 13s ago'.
+package pgtime
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockRe matches time part of interval, e.g. '02:03:04' or '-00:00:01.5'.
+var clockRe = regexp.MustCompile(`^(-?)(\d+):(\d{2}):(\d{2}(?:\.\d+)?)$`)
+
+// units defines durations of interval units used by Postgres, months and years are approximated.
+var units = map[string]time.Duration{
+	"year":  365 * 24 * time.Hour,
+	"years": 365 * 24 * time.Hour,
+	"mon":   30 * 24 * time.Hour,
+	"mons":  30 * 24 * time.Hour,
+	"day":   24 * time.Hour,
+	"days":  24 * time.Hour,
+}
+
+// timestampLayouts defines layouts of timestamps with and without time zone, fractional seconds are accepted by parser.
+var timestampLayouts = []string{"2006-01-02 15:04:05-07", "2006-01-02 15:04:05-07:00", "2006-01-02 15:04:05"}
+
+// ParseInterval parses interval in Postgres text form, e.g. '00:02:13', '1 day 02:03:04' or '3 days'.
+func ParseInterval(s string) (time.Duration, bool) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, false
+	}
+
+	var d time.Duration
+	for i := 0; i < len(fields); i++ {
+		if m := clockRe.FindStringSubmatch(fields[i]); m != nil {
+			h, _ := strconv.Atoi(m[2])
+			min, _ := strconv.Atoi(m[3])
+			sec, _ := strconv.ParseFloat(m[4], 64)
+			clock := time.Duration(h)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec*float64(time.Second))
+			if m[1] == "-" {
+				clock = -clock
+			}
+			d += clock
+			continue
+		}
+
+		// Other fields are pairs of number and unit.
+		if i+1 >= len(fields) {
+			return 0, false
+		}
+		n, err := strconv.Atoi(fields[i])
+		unit, ok := units[fields[i+1]]
+		if err != nil || !ok {
+			return 0, false
+		}
+		d += time.Duration(n) * unit
+		i++
+	}
+
+	return d, true
+}
+
+// ParseTimestamp parses timestamp in Postgres text form, e.g. '2021-03-04 05:06:07.123+03'. Timestamps without time
+// zone are considered local.
+func ParseTimestamp(s string) (time.Time, bool) {
+	for _, layout := range timestampLayouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Seconds returns interval in seconds or timestamp as Unix time in seconds, it is used for ordering values. Special
+// values 'infinity' and 'never' are greater than any other values, '-infinity' is lower.
+func Seconds(s string) (float64, bool) {
+	switch s {
+	case "infinity", "never":
+		return math.Inf(1), true
+	case "-infinity":
+		return math.Inf(-1), true
+	}
+
+	if d, ok := ParseInterval(s); ok {
+		return d.Seconds(), true
+	}
+	if t, ok := ParseTimestamp(s); ok {
+		return float64(t.UnixNano()) / float64(time.Second), true
+	}
+	return 0, false
+}
+
+// Relative formats age (interval between past event and now) or timestamp relative to now, e.g. '2m 13s ago' or
+// 'in 3d 4h'. False is returned if the value is neither interval nor timestamp.
+func Relative(s string, now time.Time) (string, bool) {
+	d, ok := ParseInterval(s)
+	if !ok {
+		t, ok := ParseTimestamp(s)
+		if !ok {
+			return "", false
+		}
+		d = now.Sub(t)
+	}
+
+	return relative(d), true
+}
+
+// Since formats time relative to now, e.g. '2m 13s ago' or 'in 3d 4h'.
+func Since(t time.Time, now time.Time) string {
+	return relative(now.Sub(t))
+}
+
+// relative formats duration between event and now, negative durations are formatted as future events.
+func relative(d time.Duration) string {
+	if d < 0 {
+		return "in " + formatDuration(-d)
+	}
+	return formatDuration(d) + " ago"
+}
+
+// formatDuration formats duration using two most significant units, e.g. '2m 13s' or '3d 4h'.
+func formatDuration(d time.Duration) string {
+	d = d.Truncate(time.Second)
+
+	days := d / (24 * time.Hour)
+	hours := d % (24 * time.Hour) / time.Hour
+	mins := d % time.Hour / time.Minute
+	secs := d % time.Minute / time.Second
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, mins)
+	case mins > 0:
+		return fmt.Sprintf("%dm %ds", mins, secs)
+	default:
+		return fmt.Sprintf("%ds", secs)
+	}
+}
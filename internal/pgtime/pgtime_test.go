@@ -0,0 +1,104 @@
+package pgtime
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseInterval(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{in: "00:02:13", want: 2*time.Minute + 13*time.Second, ok: true},
+		{in: "1 day 02:03:04", want: 26*time.Hour + 3*time.Minute + 4*time.Second, ok: true},
+		{in: "3 days", want: 72 * time.Hour, ok: true},
+		{in: "1 mon 2 days 00:00:01", want: 32*24*time.Hour + time.Second, ok: true},
+		{in: "-00:00:01.5", want: -1500 * time.Millisecond, ok: true},
+		{in: "1 day -01:00:00", want: 23 * time.Hour, ok: true},
+		{in: "", ok: false},
+		{in: "1234", ok: false},
+		{in: "never", ok: false},
+		{in: "2 weeks", ok: false},
+		{in: "2021-03-04 05:06:07", ok: false},
+	}
+
+	for _, tc := range testcases {
+		got, ok := ParseInterval(tc.in)
+		assert.Equal(t, tc.ok, ok, tc.in)
+		assert.Equal(t, tc.want, got, tc.in)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	got, ok := ParseTimestamp("2021-03-04 05:06:07.123+03")
+	assert.True(t, ok)
+	assert.True(t, time.Date(2021, 3, 4, 2, 6, 7, 123e6, time.UTC).Equal(got))
+
+	got, ok = ParseTimestamp("2021-03-04 05:06:07+05:30")
+	assert.True(t, ok)
+	assert.True(t, time.Date(2021, 3, 3, 23, 36, 7, 0, time.UTC).Equal(got))
+
+	got, ok = ParseTimestamp("2021-03-04 05:06:07")
+	assert.True(t, ok)
+	assert.True(t, time.Date(2021, 3, 4, 5, 6, 7, 0, time.Local).Equal(got))
+
+	_, ok = ParseTimestamp("00:02:13")
+	assert.False(t, ok)
+}
+
+func TestSeconds(t *testing.T) {
+	got, ok := Seconds("1 day 00:00:10")
+	assert.True(t, ok)
+	assert.Equal(t, 86410.0, got)
+
+	got, ok = Seconds("2021-03-04 05:06:07+00")
+	assert.True(t, ok)
+	assert.Equal(t, 1614834367.0, got)
+
+	got, ok = Seconds("never")
+	assert.True(t, ok)
+	assert.True(t, math.IsInf(got, 1))
+
+	got, ok = Seconds("-infinity")
+	assert.True(t, ok)
+	assert.True(t, math.IsInf(got, -1))
+
+	_, ok = Seconds("idle")
+	assert.False(t, ok)
+}
+
+func TestRelative(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	testcases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{in: "00:02:13", want: "2m 13s ago", ok: true},
+		{in: "00:00:05.7", want: "5s ago", ok: true},
+		{in: "1 day 02:03:04", want: "1d 2h ago", ok: true},
+		{in: "03:00:00", want: "3h 0m ago", ok: true},
+		{in: "-00:00:02", want: "in 2s", ok: true},
+		{in: "2021-03-04 05:04:00+00", want: "2m 7s ago", ok: true},
+		{in: "2021-03-08 05:06:07+00", want: "in 4d 0h", ok: true},
+		{in: "never", ok: false},
+		{in: "active", ok: false},
+	}
+
+	for _, tc := range testcases {
+		got, ok := Relative(tc.in, now)
+		assert.Equal(t, tc.ok, ok, tc.in)
+		assert.Equal(t, tc.want, got, tc.in)
+	}
+}
+
+func TestSince(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	assert.Equal(t, "1h 2m ago", Since(now.Add(-62*time.Minute), now))
+	assert.Equal(t, "in 10s", Since(now.Add(10*time.Second), now))
+}
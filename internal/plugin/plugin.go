@@ -0,0 +1,158 @@
+// Package plugin implements external collectors declared in configuration file. Collector is an executable which
+// prints stats rows in JSON, its output is shown as a view like built-in views.
+package plugin
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// defaultTimeout defines default duration of collector's execution.
+const defaultTimeout = 5 * time.Second
+
+// nameRE defines allowed names of plugins, names are used as views names and in names of files with recorded stats.
+var nameRE = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Config defines external collector.
+type Config struct {
+	Name        string        `yaml:"name"`        // name of the view
+	Description string        `yaml:"description"` // shown when switching to the view
+	Command     []string      `yaml:"command"`     // command and its arguments
+	Timeout     time.Duration `yaml:"timeout"`     // maximum duration of command execution, default 5s
+	Columns     []Column      `yaml:"columns"`     // columns of rows printed by command
+	OrderBy     string        `yaml:"order_by"`    // column used for sorting, the first column by default
+	Ascending   bool          `yaml:"ascending"`   // use ascending order, descending is used by default
+	UniqueKey   string        `yaml:"unique_key"`  // column which identifies rows when calculating rates, the first column by default
+}
+
+// Column defines column of rows printed by collector.
+type Column struct {
+	Name string `yaml:"name"`
+	Rate bool   `yaml:"rate"` // value is a counter, rate per second is shown instead of the value
+}
+
+// AddViews validates collectors configuration and adds views of collectors to views.
+func AddViews(views view.Views, configs []Config) error {
+	for _, c := range configs {
+		v, err := c.view()
+		if err != nil {
+			return fmt.Errorf("plugin '%s': %s", c.Name, err)
+		}
+
+		if _, ok := views[v.Name]; ok {
+			return fmt.Errorf("plugin '%s': view with the same name already exists", c.Name)
+		}
+
+		views[v.Name] = v
+	}
+
+	return nil
+}
+
+// Names returns sorted names of plugins views.
+func Names(views view.Views) []string {
+	var names []string
+	for name, v := range views {
+		if v.Plugin != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// view validates collector's configuration and makes view.
+func (c Config) view() (view.View, error) {
+	if !nameRE.MatchString(c.Name) {
+		return view.View{}, fmt.Errorf("name must consist of lowercase letters, digits and underscores")
+	}
+
+	if len(c.Command) == 0 || c.Command[0] == "" {
+		return view.View{}, fmt.Errorf("command is not specified")
+	}
+
+	if len(c.Columns) == 0 {
+		return view.View{}, fmt.Errorf("columns are not specified")
+	}
+
+	var (
+		cols   = make([]string, len(c.Columns))
+		colIdx = map[string]int{}
+		diff   [2]int
+		rates  int
+	)
+
+	for i, col := range c.Columns {
+		if col.Name == "" {
+			return view.View{}, fmt.Errorf("column %d: name is not specified", i+1)
+		}
+		if _, ok := colIdx[col.Name]; ok {
+			return view.View{}, fmt.Errorf("duplicate column '%s'", col.Name)
+		}
+		cols[i], colIdx[col.Name] = col.Name, i
+
+		if !col.Rate {
+			continue
+		}
+
+		// Rates are calculated over continuous interval of columns, the first column identifies rows.
+		if i == 0 {
+			return view.View{}, fmt.Errorf("the first column can't be a rate")
+		}
+		if rates > 0 && diff[1] != i-1 {
+			return view.View{}, fmt.Errorf("rate columns must be adjacent")
+		}
+		if rates == 0 {
+			diff[0] = i
+		}
+		diff[1] = i
+		rates++
+	}
+
+	orderKey, uniqueKey := 0, 0
+	if c.OrderBy != "" {
+		i, ok := colIdx[c.OrderBy]
+		if !ok {
+			return view.View{}, fmt.Errorf("unknown order_by column '%s'", c.OrderBy)
+		}
+		orderKey = i
+	}
+	if c.UniqueKey != "" {
+		i, ok := colIdx[c.UniqueKey]
+		if !ok {
+			return view.View{}, fmt.Errorf("unknown unique_key column '%s'", c.UniqueKey)
+		}
+		uniqueKey = i
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	msg := c.Description
+	if msg == "" {
+		msg = "Show " + c.Name + " statistics"
+	}
+
+	return view.View{
+		Name:      c.Name,
+		DiffIntvl: diff,
+		Cols:      cols,
+		Ncols:     len(cols),
+		OrderKey:  orderKey,
+		OrderDesc: !c.Ascending,
+		UniqueKey: uniqueKey,
+		ColsWidth: map[int]int{},
+		Msg:       msg,
+		Filters:   map[int]*regexp.Regexp{},
+		Plugin: &view.Plugin{
+			Command: append([]string(nil), c.Command...),
+			Timeout: timeout,
+			Columns: cols,
+		},
+	}, nil
+}
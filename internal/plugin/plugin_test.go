@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAddViews(t *testing.T) {
+	views := view.New()
+	configs := []Config{
+		{
+			Name:    "pgbouncer_pools",
+			Command: []string{"/usr/local/bin/pgbouncer-pools", "--port", "6432"},
+			Columns: []Column{{Name: "pool"}, {Name: "cl_active"}, {Name: "xacts", Rate: true}, {Name: "queries", Rate: true}},
+			OrderBy: "xacts",
+		},
+		{
+			Name:        "app_queues",
+			Description: "Show application queues",
+			Command:     []string{"app-queues"},
+			Timeout:     time.Second,
+			Columns:     []Column{{Name: "id"}, {Name: "queue"}, {Name: "length"}},
+			Ascending:   true,
+			UniqueKey:   "queue",
+		},
+	}
+
+	assert.NoError(t, AddViews(views, configs))
+
+	v := views["pgbouncer_pools"]
+	assert.Equal(t, "pgbouncer_pools", v.Name)
+	assert.Equal(t, [2]int{2, 3}, v.DiffIntvl)
+	assert.Equal(t, 4, v.Ncols)
+	assert.Equal(t, 2, v.OrderKey)
+	assert.True(t, v.OrderDesc)
+	assert.Equal(t, 0, v.UniqueKey)
+	assert.Equal(t, "Show pgbouncer_pools statistics", v.Msg)
+	assert.Equal(t, &view.Plugin{
+		Command: []string{"/usr/local/bin/pgbouncer-pools", "--port", "6432"},
+		Timeout: defaultTimeout,
+		Columns: []string{"pool", "cl_active", "xacts", "queries"},
+	}, v.Plugin)
+
+	v = views["app_queues"]
+	assert.Equal(t, [2]int{0, 0}, v.DiffIntvl)
+	assert.False(t, v.OrderDesc)
+	assert.Equal(t, 1, v.UniqueKey)
+	assert.Equal(t, "Show application queues", v.Msg)
+	assert.Equal(t, time.Second, v.Plugin.Timeout)
+
+	assert.Equal(t, []string{"app_queues", "pgbouncer_pools"}, Names(views))
+
+	// Plugin views remain after views configuring.
+	assert.NoError(t, views.Configure(query.NewOptions(130000, "f", "off", 256)))
+	assert.NotNil(t, views["app_queues"].Plugin)
+
+	// Name collisions.
+	assert.Error(t, AddViews(views, configs[:1]))
+	assert.Error(t, AddViews(view.New(), []Config{{Name: "activity", Command: []string{"true"}, Columns: []Column{{Name: "a"}}}}))
+}
+
+func TestConfig_view(t *testing.T) {
+	testcases := []Config{
+		{Name: "", Command: []string{"true"}, Columns: []Column{{Name: "a"}}},
+		{Name: "Invalid-Name", Command: []string{"true"}, Columns: []Column{{Name: "a"}}},
+		{Name: "test", Command: nil, Columns: []Column{{Name: "a"}}},
+		{Name: "test", Command: []string{""}, Columns: []Column{{Name: "a"}}},
+		{Name: "test", Command: []string{"true"}},
+		{Name: "test", Command: []string{"true"}, Columns: []Column{{Name: "a"}, {Name: ""}}},
+		{Name: "test", Command: []string{"true"}, Columns: []Column{{Name: "a"}, {Name: "a"}}},
+		{Name: "test", Command: []string{"true"}, Columns: []Column{{Name: "a", Rate: true}}},
+		{Name: "test", Command: []string{"true"}, Columns: []Column{{Name: "a"}, {Name: "b", Rate: true}, {Name: "c"}, {Name: "d", Rate: true}}},
+		{Name: "test", Command: []string{"true"}, Columns: []Column{{Name: "a"}}, OrderBy: "b"},
+		{Name: "test", Command: []string{"true"}, Columns: []Column{{Name: "a"}}, UniqueKey: "b"},
+	}
+
+	for _, tc := range testcases {
+		_, err := tc.view()
+		assert.Error(t, err)
+	}
+}
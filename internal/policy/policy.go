@@ -0,0 +1,86 @@
+// Package policy implements confirmation policies of actions which change state of Postgres, e.g. cancelling queries
+// or reloading configuration. Policies define which actions are performed immediately, which require confirmation,
+// which require typing name of the target (e.g. PID of backend), and which are disabled entirely.
+package policy
+
+import (
+	"fmt"
+)
+
+// Policies of actions.
+const (
+	None     = "none"     // action is performed immediately
+	Confirm  = "confirm"  // action is performed after confirmation
+	Type     = "type"     // action is performed after typing name of its target, e.g. PID of backend
+	Disabled = "disabled" // action is not allowed
+)
+
+// Actions which policies are configurable.
+const (
+	Cancel         = "cancel"
+	Terminate      = "terminate"
+	CancelGroup    = "cancel_group"
+	TerminateGroup = "terminate_group"
+	ResetStats     = "reset_stats"
+	ReloadConfig   = "reload_config"
+	EditConfig     = "edit_config"
+	PeekChanges    = "peek_changes"
+)
+
+// defaults defines policies of actions used when policy is not configured.
+var defaults = map[string]string{
+	Cancel:         None,
+	Terminate:      None,
+	CancelGroup:    Confirm,
+	TerminateGroup: Confirm,
+	ResetStats:     None,
+	ReloadConfig:   Confirm,
+	EditConfig:     None,
+	PeekChanges:    Confirm,
+}
+
+// Config defines policies of actions, default policy is used for actions which are not configured.
+type Config struct {
+	Cancel         string `yaml:"cancel"`          // cancel query of a single backend
+	Terminate      string `yaml:"terminate"`       // terminate a single backend
+	CancelGroup    string `yaml:"cancel_group"`    // cancel queries of group of backends
+	TerminateGroup string `yaml:"terminate_group"` // terminate group of backends
+	ResetStats     string `yaml:"reset_stats"`     // reset stats counters
+	ReloadConfig   string `yaml:"reload_config"`   // reload configuration of Postgres
+	EditConfig     string `yaml:"edit_config"`     // edit configuration files
+	PeekChanges    string `yaml:"peek_changes"`    // decode pending changes of logical replication slot
+}
+
+// Validate checks policies of all actions are known.
+func (c Config) Validate() error {
+	for action, p := range c.configured() {
+		switch p {
+		case "", None, Confirm, Type, Disabled:
+		default:
+			return fmt.Errorf("invalid policy '%s' of action %s, allowed: none, confirm, type, disabled", p, action)
+		}
+	}
+	return nil
+}
+
+// Policy returns policy of the action.
+func (c Config) Policy(action string) string {
+	if p := c.configured()[action]; p != "" {
+		return p
+	}
+	return defaults[action]
+}
+
+// configured returns policies specified in configuration.
+func (c Config) configured() map[string]string {
+	return map[string]string{
+		Cancel:         c.Cancel,
+		Terminate:      c.Terminate,
+		CancelGroup:    c.CancelGroup,
+		TerminateGroup: c.TerminateGroup,
+		ResetStats:     c.ResetStats,
+		ReloadConfig:   c.ReloadConfig,
+		EditConfig:     c.EditConfig,
+		PeekChanges:    c.PeekChanges,
+	}
+}
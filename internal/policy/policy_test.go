@@ -0,0 +1,33 @@
+package policy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.NoError(t, Config{Cancel: None, Terminate: Type, CancelGroup: Confirm, ResetStats: Disabled}.Validate())
+	assert.Error(t, Config{Terminate: "ask"}.Validate())
+}
+
+func TestConfig_Policy(t *testing.T) {
+	// Defaults.
+	c := Config{}
+	assert.Equal(t, None, c.Policy(Cancel))
+	assert.Equal(t, None, c.Policy(Terminate))
+	assert.Equal(t, Confirm, c.Policy(CancelGroup))
+	assert.Equal(t, Confirm, c.Policy(TerminateGroup))
+	assert.Equal(t, None, c.Policy(ResetStats))
+	assert.Equal(t, Confirm, c.Policy(ReloadConfig))
+	assert.Equal(t, None, c.Policy(EditConfig))
+	assert.Equal(t, Confirm, c.Policy(PeekChanges))
+	assert.Equal(t, "", c.Policy("unknown"))
+
+	// Configured policies.
+	c = Config{Terminate: Type, ReloadConfig: None, EditConfig: Disabled}
+	assert.Equal(t, None, c.Policy(Cancel))
+	assert.Equal(t, Type, c.Policy(Terminate))
+	assert.Equal(t, None, c.Policy(ReloadConfig))
+	assert.Equal(t, Disabled, c.Policy(EditConfig))
+}
@@ -0,0 +1,227 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AuthPassword defines authentication using static password (default).
+	AuthPassword = "password"
+	// AuthAWSRDSIAM defines authentication using AWS RDS IAM tokens.
+	AuthAWSRDSIAM = "aws-rds-iam"
+	// AuthGCPCloudSQLIAM defines authentication using Google Cloud SQL IAM (OAuth2 access tokens).
+	AuthGCPCloudSQLIAM = "gcp-cloudsql-iam"
+	// AuthAzureAD defines authentication using Azure Active Directory access tokens.
+	AuthAzureAD = "azure-ad"
+)
+
+const (
+	// tokenRefreshMargin defines how long before expiry the token is considered expired and refreshed.
+	tokenRefreshMargin = 2 * time.Minute
+	// tokenFetchTimeout defines timeout of getting token from cloud CLI tool or metadata service.
+	tokenFetchTimeout = 10 * time.Second
+	// cliTokenLifetime defines how long token printed by cloud CLI tool is used. CLI tools cache tokens and don't
+	// report expiration time, hence token is requested again after this interval.
+	cliTokenLifetime = 5 * time.Minute
+)
+
+// tokenProvider provides short-lived authentication tokens which are used instead of passwords.
+type tokenProvider interface {
+	// token returns token for connecting to specified host as specified user and time when the token expires.
+	token(ctx context.Context, host string, port uint16, user string) (string, time.Time, error)
+}
+
+// newTokenProvider creates token provider for specified authentication method. Returns nil for password authentication.
+func newTokenProvider(method string) (tokenProvider, error) {
+	switch method {
+	case "", AuthPassword:
+		return nil, nil
+	case AuthAWSRDSIAM:
+		return &awsRDSProvider{now: time.Now}, nil
+	case AuthGCPCloudSQLIAM:
+		host := "metadata.google.internal"
+		if h := os.Getenv("GCE_METADATA_HOST"); h != "" {
+			host = h
+		}
+		return &oauthProvider{
+			cli:            []string{"gcloud", "auth", "print-access-token"},
+			metadataURL:    "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token",
+			metadataHeader: [2]string{"Metadata-Flavor", "Google"},
+			now:            time.Now,
+		}, nil
+	case AuthAzureAD:
+		resource := "https://ossrdbms-aad.database.windows.net"
+		params := url.Values{"api-version": {"2018-02-01"}, "resource": {resource}}
+		// Client ID selects user-assigned managed identity.
+		if id := os.Getenv("AZURE_CLIENT_ID"); id != "" {
+			params.Set("client_id", id)
+		}
+		return &oauthProvider{
+			cli:            []string{"az", "account", "get-access-token", "--resource", resource, "--query", "accessToken", "--output", "tsv"},
+			metadataURL:    "http://169.254.169.254/metadata/identity/oauth2/token?" + params.Encode(),
+			metadataHeader: [2]string{"Metadata", "true"},
+			now:            time.Now,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown authentication method '%s', supported: %s, %s, %s, %s",
+			method, AuthPassword, AuthAWSRDSIAM, AuthGCPCloudSQLIAM, AuthAzureAD)
+	}
+}
+
+// cachedToken defines token received from provider.
+type cachedToken struct {
+	value   string
+	expires time.Time
+}
+
+// tokenAuth provides passwords using token provider. Tokens are cached and refreshed when they are about to expire,
+// hence reconnects always use valid tokens. It is shared by all copies of the config, hence access is synchronized.
+type tokenAuth struct {
+	mu       sync.Mutex
+	method   string
+	provider tokenProvider
+	tokens   map[string]cachedToken
+}
+
+// password returns token used as password for connecting to specified host as specified user.
+func (a *tokenAuth) password(host string, port uint16, user string, now time.Time) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := tokenKey(host, port, user)
+	if t, ok := a.tokens[key]; ok && now.Add(tokenRefreshMargin).Before(t.expires) {
+		return t.value, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tokenFetchTimeout)
+	defer cancel()
+
+	value, expires, err := a.provider.token(ctx, host, port, user)
+	if err != nil {
+		return "", fmt.Errorf("get %s token failed: %s", a.method, err)
+	}
+
+	a.tokens[key] = cachedToken{value: value, expires: expires}
+	return value, nil
+}
+
+// invalidate removes cached token, e.g. when it has been rejected by Postgres.
+func (a *tokenAuth) invalidate(host string, port uint16, user string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.tokens, tokenKey(host, port, user))
+}
+
+// tokenKey returns key of cached token.
+func tokenKey(host string, port uint16, user string) string {
+	return fmt.Sprintf("%s:%d:%s", host, port, user)
+}
+
+// setAuth configures authentication using tokens of specified method.
+func (c *Config) setAuth(method string) error {
+	provider, err := newTokenProvider(method)
+	if err != nil {
+		return err
+	}
+
+	if provider == nil {
+		return nil
+	}
+
+	c.auth = &tokenAuth{method: method, provider: provider, tokens: map[string]cachedToken{}}
+	return nil
+}
+
+// oauthProvider provides OAuth2 access tokens using cloud CLI tool (if it is installed) or metadata service of the
+// cloud VM (if CLI tool is not installed). The same token is used for all hosts and users.
+type oauthProvider struct {
+	cli            []string  // command which prints access token
+	metadataURL    string    // URL of metadata service endpoint which returns access token
+	metadataHeader [2]string // header required by metadata service
+	now            func() time.Time
+}
+
+// token implements tokenProvider interface.
+func (p *oauthProvider) token(ctx context.Context, _ string, _ uint16, _ string) (string, time.Time, error) {
+	if _, err := exec.LookPath(p.cli[0]); err == nil {
+		return p.cliToken(ctx)
+	}
+
+	return p.metadataToken(ctx)
+}
+
+// cliToken returns token printed by cloud CLI tool.
+func (p *oauthProvider) cliToken(ctx context.Context) (string, time.Time, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.cli[0], p.cli[1:]...) // #nosec G204
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s failed: %s: %s", p.cli[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", time.Time{}, fmt.Errorf("%s returned empty token", p.cli[0])
+	}
+
+	return token, p.now().Add(cliTokenLifetime), nil
+}
+
+// metadataToken requests token from metadata service.
+func (p *oauthProvider) metadataToken(ctx context.Context) (string, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.metadataURL, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set(p.metadataHeader[0], p.metadataHeader[1])
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s is not installed and metadata service is not available: %s", p.cli[0], err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("metadata service responded %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	// Lifetime is a number in Google Cloud, and a string in Azure.
+	var t struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	err = json.Unmarshal(body, &t)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid metadata service response: %s", err)
+	}
+
+	if t.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("metadata service returned empty token")
+	}
+
+	expiresIn, err := strconv.Atoi(t.ExpiresIn.String())
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid token lifetime '%s'", t.ExpiresIn)
+	}
+
+	return t.AccessToken, p.now().Add(time.Duration(expiresIn) * time.Second), nil
+}
@@ -0,0 +1,232 @@
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// rdsTokenLifetime defines lifetime of AWS RDS IAM token.
+	rdsTokenLifetime = 15 * time.Minute
+	// emptyPayloadHash is SHA-256 hash of empty request payload.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// rdsHostRE defines RDS endpoint host name, which includes region of the instance.
+var rdsHostRE = regexp.MustCompile(`\.([a-z0-9-]+)\.rds\.amazonaws\.com(\.cn)?$`)
+
+// awsCredentials defines AWS access keys.
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// awsRDSProvider provides AWS RDS IAM tokens. Token is a request presigned using Signature Version 4, it is generated
+// locally using AWS credentials from environment or shared credentials file.
+type awsRDSProvider struct {
+	now func() time.Time
+}
+
+// token implements tokenProvider interface.
+func (p *awsRDSProvider) token(_ context.Context, host string, port uint16, user string) (string, time.Time, error) {
+	region, err := awsRegion(host)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	creds, err := loadAWSCredentials()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := p.now()
+	return buildRDSAuthToken(host, port, region, user, creds, now), now.Add(rdsTokenLifetime), nil
+}
+
+// awsRegion returns region specified in environment, or region of RDS endpoint.
+func awsRegion(host string) (string, error) {
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(name); region != "" {
+			return region, nil
+		}
+	}
+
+	if m := rdsHostRE.FindStringSubmatch(host); m != nil {
+		return m[1], nil
+	}
+
+	return "", fmt.Errorf("AWS region is not specified (use AWS_REGION) and can't be determined from host '%s'", host)
+}
+
+// loadAWSCredentials returns AWS credentials specified in environment or in shared credentials file.
+func loadAWSCredentials() (awsCredentials, error) {
+	creds := awsCredentials{
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.accessKeyID != "" && creds.secretAccessKey != "" {
+		return creds, nil
+	}
+
+	filename := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if filename == "" {
+		u, err := user.Current()
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("AWS credentials not found: %s", err)
+		}
+		filename = filepath.Join(u.HomeDir, ".aws", "credentials")
+	}
+
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	return readAWSCredentialsFile(filename, profile)
+}
+
+// readAWSCredentialsFile reads credentials of specified profile from shared credentials file.
+func readAWSCredentialsFile(filename string, profile string) (awsCredentials, error) {
+	f, err := os.Open(filepath.Clean(filename))
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("AWS credentials not found in environment and in credentials file: %s", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var creds awsCredentials
+	var section string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		if section != profile {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "aws_access_key_id":
+			creds.accessKeyID = value
+		case "aws_secret_access_key":
+			creds.secretAccessKey = value
+		case "aws_session_token":
+			creds.sessionToken = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return awsCredentials{}, err
+	}
+
+	if creds.accessKeyID == "" || creds.secretAccessKey == "" {
+		return awsCredentials{}, fmt.Errorf("AWS credentials of profile '%s' not found in %s", profile, filename)
+	}
+
+	return creds, nil
+}
+
+// buildRDSAuthToken returns RDS IAM token, which is a presigned 'connect' request without scheme.
+func buildRDSAuthToken(host string, port uint16, region, user string, creds awsCredentials, now time.Time) string {
+	endpoint := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	query := url.Values{"Action": {"connect"}, "DBUser": {user}}
+
+	return endpoint + "/?" + awsPresign(endpoint, "/", query, region, "rds-db", emptyPayloadHash, creds, now, rdsTokenLifetime)
+}
+
+// awsPresign signs GET request using Signature Version 4 and returns query string with signature.
+func awsPresign(host, path string, query url.Values, region, service, payloadHash string, creds awsCredentials, now time.Time, expires time.Duration) string {
+	now = now.UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", creds.accessKeyID+"/"+scope)
+	q.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+	if creds.sessionToken != "" {
+		q.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	canonicalQuery := awsCanonicalQuery(q)
+	canonicalRequest := strings.Join([]string{
+		"GET", path, canonicalQuery, "host:" + host + "\n", "host", payloadHash,
+	}, "\n")
+
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", now.Format("20060102T150405Z"), scope, hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + creds.secretAccessKey)
+	for _, s := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+
+	return canonicalQuery + "&X-Amz-Signature=" + hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// awsCanonicalQuery returns query string with sorted parameters, encoded accordingly to RFC 3986.
+func awsCanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string{}, q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// awsEscape encodes string accordingly to RFC 3986, as required by Signature Version 4.
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// hmacSHA256 returns HMAC-SHA256 of data.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -1,13 +1,60 @@
 package postgres
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // ConnectionOptions defines connection options (used by all pgcenter subcommands).
 type ConnectionOptions struct {
-	Host   string
-	Port   int
-	User   string
-	Dbname string
+	Host    string
+	Port    int
+	User    string
+	Dbname  string
+	Service string // name of the service defined in connection service file
+	SSL     SSLOptions
+	SSH     SSHOptions
+	Auth    string // authentication method: password (default), aws-rds-iam, gcp-cloudsql-iam, azure-ad
+
+	StatementTimeout time.Duration // statement_timeout set for every pgcenter's connection
+	LockTimeout      time.Duration // lock_timeout set for every pgcenter's connection
+}
+
+// NewConfig creates connection config from connection options.
+func (c ConnectionOptions) NewConfig() (Config, error) {
+	return newConfig(c)
+}
+
+// WithTarget returns copy of options for connecting to another target, specified as host[:port] or as a connection
+// string. When host is specified, other options are inherited. Connection string replaces host, port, user, database
+// and service, while SSL, SSH and timeouts options are inherited.
+func (c ConnectionOptions) WithTarget(target string) (ConnectionOptions, error) {
+	if target == "" {
+		return ConnectionOptions{}, fmt.Errorf("empty target")
+	}
+
+	if isConnString(target) {
+		c.Host, c.Port, c.User, c.Dbname, c.Service = "", 0, "", target, ""
+		return c, nil
+	}
+
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		// Port is not specified.
+		c.Host = strings.Trim(target, "[]")
+		return c, nil
+	}
+
+	p, err := strconv.Atoi(port)
+	if err != nil || p <= 0 || p > 65535 {
+		return ConnectionOptions{}, fmt.Errorf("invalid port in target '%s'", target)
+	}
+
+	c.Host, c.Port = host, p
+	return c, nil
 }
 
 // ParseExtraArgs parses extra arguments passed in CLI and fills ConnectionOptions properties.
@@ -4,6 +4,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func TestConnectionOptions_ParseExtraArgs(t *testing.T) {
@@ -54,3 +55,69 @@ func TestConnectionOptions_ParseExtraArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestConnectionOptions_WithTarget(t *testing.T) {
+	base := ConnectionOptions{
+		Host: "primary", Port: 5433, User: "postgres", Dbname: "pgbench",
+		SSL: SSLOptions{Mode: "require"}, SSH: SSHOptions{Destination: "bastion"}, StatementTimeout: time.Second,
+	}
+
+	testcases := []struct {
+		target string
+		want   ConnectionOptions
+	}{
+		{
+			target: "standby",
+			want: ConnectionOptions{
+				Host: "standby", Port: 5433, User: "postgres", Dbname: "pgbench",
+				SSL: SSLOptions{Mode: "require"}, SSH: SSHOptions{Destination: "bastion"}, StatementTimeout: time.Second,
+			},
+		},
+		{
+			target: "standby:5434",
+			want: ConnectionOptions{
+				Host: "standby", Port: 5434, User: "postgres", Dbname: "pgbench",
+				SSL: SSLOptions{Mode: "require"}, SSH: SSHOptions{Destination: "bastion"}, StatementTimeout: time.Second,
+			},
+		},
+		{
+			target: "[::1]:5434",
+			want: ConnectionOptions{
+				Host: "::1", Port: 5434, User: "postgres", Dbname: "pgbench",
+				SSL: SSLOptions{Mode: "require"}, SSH: SSHOptions{Destination: "bastion"}, StatementTimeout: time.Second,
+			},
+		},
+		{
+			target: "/var/run/postgresql",
+			want: ConnectionOptions{
+				Host: "/var/run/postgresql", Port: 5433, User: "postgres", Dbname: "pgbench",
+				SSL: SSLOptions{Mode: "require"}, SSH: SSHOptions{Destination: "bastion"}, StatementTimeout: time.Second,
+			},
+		},
+		{
+			target: "host=standby port=5434 user=monitor",
+			want: ConnectionOptions{
+				Dbname: "host=standby port=5434 user=monitor",
+				SSL:    SSLOptions{Mode: "require"}, SSH: SSHOptions{Destination: "bastion"}, StatementTimeout: time.Second,
+			},
+		},
+		{
+			target: "postgres://monitor@standby:5434/pgbench",
+			want: ConnectionOptions{
+				Dbname: "postgres://monitor@standby:5434/pgbench",
+				SSL:    SSLOptions{Mode: "require"}, SSH: SSHOptions{Destination: "bastion"}, StatementTimeout: time.Second,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		got, err := base.WithTarget(tc.target)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+
+	for _, target := range []string{"", "standby:invalid", "standby:0", "standby:70000"} {
+		_, err := base.WithTarget(target)
+		assert.Error(t, err)
+	}
+}
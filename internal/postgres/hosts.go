@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	// TargetSessionAttrsAny defines connection to any host.
+	TargetSessionAttrsAny = "any"
+	// TargetSessionAttrsReadWrite defines connection to host which accepts read-write transactions by default.
+	TargetSessionAttrsReadWrite = "read-write"
+	// TargetSessionAttrsReadOnly defines connection to host which doesn't accept read-write transactions by default.
+	TargetSessionAttrsReadOnly = "read-only"
+	// TargetSessionAttrsPrimary defines connection to host which is not in hot standby mode.
+	TargetSessionAttrsPrimary = "primary"
+	// TargetSessionAttrsStandby defines connection to host which is in hot standby mode.
+	TargetSessionAttrsStandby = "standby"
+	// TargetSessionAttrsPreferStandby defines connection to host in hot standby mode, or to any host if there are no standbys.
+	TargetSessionAttrsPreferStandby = "prefer-standby"
+)
+
+// targetSessionAttrsRE defines target_session_attrs parameter in keyword/value connection string.
+var targetSessionAttrsRE = regexp.MustCompile(`(^|\s)target_session_attrs\s*=\s*('[^']*'|\S*)\s*`)
+
+// extractTargetSessionAttrs returns value of target_session_attrs specified in connection string (or in environment)
+// and connection string with the parameter removed. jackc/pgx driver supports 'any' and 'read-write' values only,
+// hence the parameter is handled separately.
+func extractTargetSessionAttrs(connStr string) (string, string, error) {
+	attrs := os.Getenv("PGTARGETSESSIONATTRS")
+
+	if strings.HasPrefix(connStr, "postgres://") || strings.HasPrefix(connStr, "postgresql://") {
+		u, err := url.Parse(connStr)
+		if err != nil {
+			return "", "", err
+		}
+
+		q := u.Query()
+		if v, ok := q["target_session_attrs"]; ok {
+			attrs = v[0]
+			q.Del("target_session_attrs")
+			u.RawQuery = q.Encode()
+			connStr = u.String()
+		}
+	} else if m := targetSessionAttrsRE.FindStringSubmatch(connStr); m != nil {
+		attrs = strings.Trim(m[2], "'")
+		connStr = strings.TrimSpace(targetSessionAttrsRE.ReplaceAllString(connStr, "$1"))
+	}
+
+	switch attrs {
+	case "":
+		attrs = TargetSessionAttrsAny
+	case TargetSessionAttrsAny, TargetSessionAttrsReadWrite, TargetSessionAttrsReadOnly,
+		TargetSessionAttrsPrimary, TargetSessionAttrsStandby, TargetSessionAttrsPreferStandby:
+	default:
+		return "", "", fmt.Errorf("invalid target_session_attrs value: %s", attrs)
+	}
+
+	// Override PGTARGETSESSIONATTRS used by the driver.
+	if os.Getenv("PGTARGETSESSIONATTRS") != "" {
+		connStr = mergeConnString(connStr, [][2]string{{"target_session_attrs", TargetSessionAttrsAny}})
+	}
+
+	return attrs, connStr, nil
+}
+
+// validators returns functions used for checking hosts accordingly to target_session_attrs. Hosts are checked in
+// several passes when multiple functions are returned.
+func (c Config) validators() []pgconn.ValidateConnectFunc {
+	switch c.targetSessionAttrs {
+	case TargetSessionAttrsReadWrite:
+		return []pgconn.ValidateConnectFunc{pgconn.ValidateConnectTargetSessionAttrsReadWrite}
+	case TargetSessionAttrsReadOnly:
+		return []pgconn.ValidateConnectFunc{validateReadOnly}
+	case TargetSessionAttrsPrimary:
+		return []pgconn.ValidateConnectFunc{validateRecovery(false)}
+	case TargetSessionAttrsStandby:
+		return []pgconn.ValidateConnectFunc{validateRecovery(true)}
+	case TargetSessionAttrsPreferStandby:
+		return []pgconn.ValidateConnectFunc{validateRecovery(true), nil}
+	default:
+		return []pgconn.ValidateConnectFunc{nil}
+	}
+}
+
+// validateReadOnly checks the session doesn't accept read-write transactions by default.
+func validateReadOnly(ctx context.Context, pgConn *pgconn.PgConn) error {
+	result := pgConn.ExecParams(ctx, "SHOW transaction_read_only", nil, nil, nil, nil).Read()
+	if result.Err != nil {
+		return result.Err
+	}
+
+	if string(result.Rows[0][0]) != "on" {
+		return errors.New("session is not read-only")
+	}
+
+	return nil
+}
+
+// validateRecovery returns function which checks the server is in (or not in) hot standby mode.
+func validateRecovery(standby bool) pgconn.ValidateConnectFunc {
+	return func(ctx context.Context, pgConn *pgconn.PgConn) error {
+		result := pgConn.ExecParams(ctx, "SELECT pg_is_in_recovery()", nil, nil, nil, nil).Read()
+		if result.Err != nil {
+			return result.Err
+		}
+
+		recovery := string(result.Rows[0][0]) == "t"
+		switch {
+		case standby && !recovery:
+			return errors.New("server is not in hot standby mode")
+		case !standby && recovery:
+			return errors.New("server is in hot standby mode")
+		}
+
+		return nil
+	}
+}
+
+// hosts returns all hosts specified in config in order they should be tried. The same host might be listed several
+// times with different TLS settings, e.g. when sslmode is 'prefer'.
+func (c Config) hosts() []*pgconn.FallbackConfig {
+	hosts := []*pgconn.FallbackConfig{{Host: c.Config.Host, Port: c.Config.Port, TLSConfig: c.Config.TLSConfig}}
+	return append(hosts, c.Config.Fallbacks...)
+}
+
+// preferHost returns copy of config where specified host is tried first and other hosts are kept as fallbacks, hence
+// subsequent connections (e.g. reconnects) go to the same host while it satisfies target_session_attrs.
+func (c Config) preferHost(host *pgconn.FallbackConfig) Config {
+	var first, rest []*pgconn.FallbackConfig
+	for _, h := range c.hosts() {
+		if h.Host == host.Host && h.Port == host.Port {
+			first = append(first, h)
+		} else {
+			rest = append(rest, h)
+		}
+	}
+
+	hosts := append(first, rest...)
+
+	config := c.Config.Copy()
+	config.Host, config.Port, config.TLSConfig = hosts[0].Host, hosts[0].Port, hosts[0].TLSConfig
+	config.Fallbacks = hosts[1:]
+
+	return Config{Config: config, targetSessionAttrs: c.targetSessionAttrs, tunnel: c.tunnel, role: c.role, auth: c.auth, prepare: c.prepare}
+}
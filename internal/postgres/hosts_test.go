@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func Test_extractTargetSessionAttrs(t *testing.T) {
+	testcases := []struct {
+		connStr     string
+		valid       bool
+		wantAttrs   string
+		wantConnStr string
+	}{
+		{connStr: "", valid: true, wantAttrs: "any", wantConnStr: ""},
+		{connStr: "host=h1,h2 dbname=test", valid: true, wantAttrs: "any", wantConnStr: "host=h1,h2 dbname=test"},
+		{connStr: "host=h1,h2 target_session_attrs=standby dbname=test", valid: true, wantAttrs: "standby", wantConnStr: "host=h1,h2 dbname=test"},
+		{connStr: "host=h1,h2 target_session_attrs = 'read-write'", valid: true, wantAttrs: "read-write", wantConnStr: "host=h1,h2"},
+		{connStr: "target_session_attrs=prefer-standby host=h1,h2", valid: true, wantAttrs: "prefer-standby", wantConnStr: "host=h1,h2"},
+		{
+			connStr: "postgres://h1:5432,h2:5433/test?target_session_attrs=primary&sslmode=disable", valid: true,
+			wantAttrs: "primary", wantConnStr: "postgres://h1:5432,h2:5433/test?sslmode=disable",
+		},
+		{connStr: "postgresql://h1,h2/test", valid: true, wantAttrs: "any", wantConnStr: "postgresql://h1,h2/test"},
+		{connStr: "host=h1,h2 target_session_attrs=invalid", valid: false},
+		{connStr: "postgres://h1,h2/test?target_session_attrs=invalid", valid: false},
+	}
+
+	for _, tc := range testcases {
+		attrs, connStr, err := extractTargetSessionAttrs(tc.connStr)
+		if tc.valid {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantAttrs, attrs)
+			assert.Equal(t, tc.wantConnStr, connStr)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+
+	// Value from environment is used, but it's not passed to the driver.
+	assert.NoError(t, os.Setenv("PGTARGETSESSIONATTRS", "standby"))
+	attrs, connStr, err := extractTargetSessionAttrs("host=h1,h2")
+	assert.NoError(t, err)
+	assert.Equal(t, "standby", attrs)
+	assert.Equal(t, "host=h1,h2 target_session_attrs=any", connStr)
+	assert.NoError(t, os.Unsetenv("PGTARGETSESSIONATTRS"))
+}
+
+func TestConfig_validators(t *testing.T) {
+	testcases := []struct {
+		attrs string
+		want  int
+	}{
+		{attrs: "", want: 1},
+		{attrs: "any", want: 1},
+		{attrs: "read-write", want: 1},
+		{attrs: "read-only", want: 1},
+		{attrs: "primary", want: 1},
+		{attrs: "standby", want: 1},
+		{attrs: "prefer-standby", want: 2},
+	}
+
+	for _, tc := range testcases {
+		got := Config{targetSessionAttrs: tc.attrs}.validators()
+		assert.Len(t, got, tc.want)
+		assert.Equal(t, tc.attrs == "" || tc.attrs == "any", got[0] == nil)
+	}
+}
+
+func TestConfig_preferHost(t *testing.T) {
+	config, err := ParseConfig("host=h1,h2,h3 port=5432,5433,5434 sslmode=prefer target_session_attrs=standby")
+	assert.NoError(t, err)
+
+	// Each host is listed twice with and without TLS.
+	hosts := config.hosts()
+	assert.Len(t, hosts, 6)
+
+	got := config.preferHost(hosts[2])
+	assert.Equal(t, "standby", got.targetSessionAttrs)
+	assert.Equal(t, "h2", got.Config.Host)
+	assert.Equal(t, uint16(5433), got.Config.Port)
+	assert.NotNil(t, got.Config.TLSConfig)
+
+	var order []string
+	for _, h := range got.hosts() {
+		order = append(order, h.Host)
+	}
+	assert.Equal(t, []string{"h2", "h2", "h1", "h1", "h3", "h3"}, order)
+
+	// Original config is not modified.
+	assert.Equal(t, "h1", config.Config.Host)
+	assert.Equal(t, "h2", config.Config.Fallbacks[1].Host)
+}
+
+func TestConnect_MultipleHosts(t *testing.T) {
+	testcases := []struct {
+		name    string
+		connStr string
+		valid   bool
+	}{
+		{name: "first unavailable", connStr: "host=127.0.0.1,127.0.0.1 port=1,21913 user=postgres dbname=pgcenter_fixtures", valid: true},
+		{name: "primary", connStr: "host=127.0.0.1,127.0.0.1 port=1,21913 user=postgres dbname=pgcenter_fixtures target_session_attrs=primary", valid: true},
+		{name: "prefer standby", connStr: "host=127.0.0.1 port=21913 user=postgres dbname=pgcenter_fixtures target_session_attrs=prefer-standby", valid: true},
+		{name: "no standby", connStr: "host=127.0.0.1 port=21913 user=postgres dbname=pgcenter_fixtures target_session_attrs=standby", valid: false},
+		{name: "all unavailable", connStr: "host=127.0.0.1,127.0.0.1 port=1,2 user=postgres dbname=pgcenter_fixtures", valid: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := ParseConfig(tc.connStr)
+			assert.NoError(t, err)
+
+			db, err := Connect(config)
+			if tc.valid {
+				assert.NoError(t, err)
+				assert.Equal(t, uint16(21913), db.Config.Config.Port)
+				db.Close()
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"github.com/jackc/pgpassfile"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// systemServiceDirs defines directories where system-wide connection service file is looked for, if PGSYSCONFDIR is
+// not set. Default location depends on how libpq is built, so the most common locations are checked.
+var systemServiceDirs = []string{"/etc/postgresql-common", "/etc/sysconfig/pgsql", "/etc"}
+
+// systemServiceFile returns path to system-wide connection service file (pg_service.conf), which should be used
+// instead of user's service file. Returns empty string if service file is specified explicitly by PGSERVICEFILE, user's
+// service file (~/.pg_service.conf) exists, or system-wide service file is not found.
+func systemServiceFile() string {
+	if os.Getenv("PGSERVICEFILE") != "" {
+		return ""
+	}
+
+	if u, err := user.Current(); err == nil {
+		if _, err := os.Stat(filepath.Join(u.HomeDir, ".pg_service.conf")); err == nil {
+			return ""
+		}
+	}
+
+	dirs := systemServiceDirs
+	if d := os.Getenv("PGSYSCONFDIR"); d != "" {
+		dirs = []string{d}
+	}
+
+	for _, d := range dirs {
+		f := filepath.Join(d, "pg_service.conf")
+		if _, err := os.Stat(f); err == nil {
+			return f
+		}
+	}
+
+	return ""
+}
+
+// passfilePath returns path to password file, specified by PGPASSFILE or located in user's home directory.
+func passfilePath() string {
+	if f := os.Getenv("PGPASSFILE"); f != "" {
+		return f
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(u.HomeDir, ".pgpass")
+}
+
+// lookupPassfile returns password for specified connection parameters from password file (.pgpass). Returns empty
+// string if password file doesn't exist or has no matching entries.
+func lookupPassfile(host string, port uint16, dbname string, user string) string {
+	path := passfilePath()
+	if path == "" {
+		return ""
+	}
+
+	passfile, err := pgpassfile.ReadPassfile(path)
+	if err != nil {
+		return ""
+	}
+
+	// Connections through UNIX sockets are matched by 'localhost', like libpq does.
+	if strings.HasPrefix(host, "/") {
+		host = "localhost"
+	}
+
+	return passfile.FindPassword(host, strconv.Itoa(int(port)), dbname, user)
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func Test_systemServiceFile(t *testing.T) {
+	if _, err := os.Stat(os.ExpandEnv("$HOME/.pg_service.conf")); err == nil {
+		t.Skip("user's service file exists")
+	}
+
+	assert.NoError(t, os.Setenv("PGSYSCONFDIR", "testdata"))
+	assert.Equal(t, "testdata/pg_service.conf", systemServiceFile())
+
+	assert.NoError(t, os.Setenv("PGSERVICEFILE", "testdata/pg_service.conf"))
+	assert.Equal(t, "", systemServiceFile())
+	assert.NoError(t, os.Unsetenv("PGSERVICEFILE"))
+
+	assert.NoError(t, os.Setenv("PGSYSCONFDIR", "testdata/invalid"))
+	assert.Equal(t, "", systemServiceFile())
+	assert.NoError(t, os.Unsetenv("PGSYSCONFDIR"))
+}
+
+func Test_lookupPassfile(t *testing.T) {
+	assert.NoError(t, os.Setenv("PGPASSFILE", "testdata/pgpass"))
+	defer func() { assert.NoError(t, os.Unsetenv("PGPASSFILE")) }()
+
+	testcases := []struct {
+		host   string
+		port   uint16
+		dbname string
+		user   string
+		want   string
+	}{
+		{host: "127.0.0.1", port: 21913, dbname: "pgcenter_fixtures", user: "postgres", want: "secret"},
+		{host: "/var/run/postgresql", port: 5432, dbname: "postgres", user: "postgres", want: "local"},
+		{host: "example.org", port: 6432, dbname: "test", user: "pgcenter", want: "any"},
+		{host: "127.0.0.1", port: 5432, dbname: "test", user: "postgres", want: ""},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, lookupPassfile(tc.host, tc.port, tc.dbname, tc.user))
+	}
+
+	assert.NoError(t, os.Setenv("PGPASSFILE", "testdata/invalid"))
+	assert.Equal(t, "", lookupPassfile("127.0.0.1", 21913, "pgcenter_fixtures", "postgres"))
+}
+
+func TestConnectionOptions_NewConfig_Service(t *testing.T) {
+	assert.NoError(t, os.Setenv("PGSERVICEFILE", "testdata/pg_service.conf"))
+	assert.NoError(t, os.Setenv("PGPASSFILE", "testdata/pgpass"))
+	defer func() {
+		assert.NoError(t, os.Unsetenv("PGSERVICEFILE"))
+		assert.NoError(t, os.Unsetenv("PGPASSFILE"))
+	}()
+
+	// Connection parameters and password are taken from service and password files.
+	got, err := ConnectionOptions{Service: "pgcenter"}.NewConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", got.Config.Host)
+	assert.Equal(t, uint16(21913), got.Config.Port)
+	assert.Equal(t, "postgres", got.Config.User)
+	assert.Equal(t, "pgcenter_fixtures", got.Config.Database)
+	assert.Equal(t, "secret", got.Config.Password)
+
+	// Explicitly specified options take precedence over service.
+	got, err = ConnectionOptions{Service: "pgcenter", Port: 5432, Dbname: "test"}.NewConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", got.Config.Host)
+	assert.Equal(t, uint16(5432), got.Config.Port)
+	assert.Equal(t, "test", got.Config.Database)
+
+	// Database is not specified, password is looked up using user name as database name.
+	got, err = ConnectionOptions{Host: "/var/run/postgresql", Port: 5432, User: "postgres"}.NewConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "local", got.Config.Password)
+
+	_, err = ConnectionOptions{Service: "invalid"}.NewConfig()
+	assert.Error(t, err)
+}
@@ -2,19 +2,27 @@ package postgres
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"golang.org/x/crypto/ssh/terminal"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config contains configuration suitable for used database driver.
 type Config struct {
-	Config *pgx.ConnConfig
+	Config             *pgx.ConnConfig
+	targetSessionAttrs string     // required properties of the host the connection is established to
+	tunnel             *sshTunnel // SSH tunnel used for connecting through a jump host
+	role               string     // role set after connecting, empty means session user
+	auth               *tokenAuth // provider of tokens used instead of password, nil means password authentication
+	prepare            bool       // use prepared statements for queries executed repeatedly
 }
 
 // DB describes connection settings to Postgres specified by user.
@@ -22,25 +30,110 @@ type DB struct {
 	Config Config
 	Conn   *pgx.Conn
 	Local  bool // is Postgres running on localhost?
+
+	prepared []string // names of statements prepared on the connection, from the oldest to the newest
 }
 
-// NewConfig checks connection parameters passed by user, assembles connection string and creates config.
+// NewConfig checks connection parameters passed by user, assembles connection string and creates config. Similarly to
+// psql, database name could be specified as a connection string in URI or keyword/value format, in this case other
+// specified parameters take precedence over parameters of the connection string.
 func NewConfig(host string, port int, user string, dbname string) (Config, error) {
+	return newConfig(ConnectionOptions{Host: host, Port: port, User: user, Dbname: dbname})
+}
+
+// newConfig creates config from connection options.
+func newConfig(c ConnectionOptions) (Config, error) {
+	var params [][2]string
+	if c.Service != "" {
+		params = append(params, [2]string{"service", c.Service})
+	}
+	if c.Host != "" {
+		params = append(params, [2]string{"host", c.Host})
+	}
+	if c.Port > 0 {
+		params = append(params, [2]string{"port", strconv.Itoa(c.Port)})
+	}
+	if c.User != "" {
+		params = append(params, [2]string{"user", c.User})
+	}
+
 	var connStr string
-	if host != "" {
-		connStr = "host=" + host
+	if isConnString(c.Dbname) {
+		connStr = c.Dbname
+	} else if c.Dbname != "" {
+		params = append(params, [2]string{"dbname", c.Dbname})
+	}
+
+	config, err := ParseConfigSSL(mergeConnString(connStr, params), c.SSL)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.setTimeouts(c.StatementTimeout, c.LockTimeout)
+
+	err = config.setTunnel(c.SSH)
+	if err != nil {
+		return Config{}, err
+	}
+
+	err = config.setAuth(c.Auth)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// isConnString returns true if passed string is a connection string in URI or keyword/value format.
+func isConnString(s string) bool {
+	return strings.HasPrefix(s, "postgresql://") || strings.HasPrefix(s, "postgres://") || strings.Contains(s, "=")
+}
+
+// mergeConnString appends parameters to connection string. Appended parameters override the same parameters
+// specified in the connection string.
+func mergeConnString(connStr string, params [][2]string) string {
+	if len(params) == 0 {
+		return connStr
+	}
+
+	// URI parameters specified in query string override parameters specified in URI's host and path.
+	if strings.HasPrefix(connStr, "postgresql://") || strings.HasPrefix(connStr, "postgres://") {
+		values := url.Values{}
+		for _, p := range params {
+			values.Set(p[0], p[1])
+		}
+
+		sep := "?"
+		if strings.Contains(connStr, "?") {
+			sep = "&"
+		}
+
+		return connStr + sep + values.Encode()
 	}
-	if port > 0 {
-		connStr = connStr + " port=" + strconv.Itoa(port)
+
+	// In keyword/value format, the last value of a keyword is used.
+	parts := make([]string, 0, len(params)+1)
+	if connStr != "" {
+		parts = append(parts, connStr)
 	}
-	if user != "" {
-		connStr = connStr + " user=" + user
+	for _, p := range params {
+		parts = append(parts, p[0]+"="+p[1])
 	}
-	if dbname != "" {
-		connStr = connStr + " dbname=" + dbname
+
+	return strings.Join(parts, " ")
+}
+
+// ParseConfig creates config from connection string in keyword/value or URI format.
+func ParseConfig(connStr string) (Config, error) {
+	targetSessionAttrs, connStr, err := extractTargetSessionAttrs(connStr)
+	if err != nil {
+		return Config{}, err
 	}
 
-	connStr = strings.TrimSpace(connStr)
+	// jackc/pgx driver looks for service file in user's home directory only, use system-wide service file if necessary.
+	if f := systemServiceFile(); f != "" && !strings.Contains(connStr, "servicefile") {
+		connStr = mergeConnString(connStr, [][2]string{{"servicefile", f}})
+	}
 
 	// pgx.ParseConfig produces config for connecting to Postgres even from empty string.
 	pgConfig, err := pgx.ParseConfig(connStr)
@@ -48,24 +141,155 @@ func NewConfig(host string, port int, user string, dbname string) (Config, error
 		return Config{}, err
 	}
 
+	// jackc/pgx driver looks for password in password file using empty database name if database is not specified,
+	// but Postgres uses user name as database name in this case.
+	if pgConfig.Password == "" && pgConfig.Database == "" {
+		pgConfig.Password = lookupPassfile(pgConfig.Host, pgConfig.Port, pgConfig.User, pgConfig.User)
+	}
+
 	// use PreferSimpleProtocol disables implicit prepared statement usage and enable compatibility with Pgbouncer.
+	// Queries executed repeatedly use explicitly prepared statements, unless they are disabled in connection string.
 	pgConfig.PreferSimpleProtocol = true
+	prepare := preferPrepared(pgConfig)
 
 	// process PGOPTIONS explicitly, because used jackc/pgx driver supports a limited set of libpq environment variables.
-	if options := os.Getenv("PGOPTIONS"); options != "" {
-		pgConfig.RuntimeParams["options"] = options
+	// Options specified in connection string take precedence over environment.
+	if _, ok := pgConfig.RuntimeParams["options"]; !ok {
+		if options := os.Getenv("PGOPTIONS"); options != "" {
+			pgConfig.RuntimeParams["options"] = options
+		}
 	}
 
 	return Config{
-		Config: pgConfig,
+		Config:             pgConfig,
+		targetSessionAttrs: targetSessionAttrs,
+		prepare:            prepare,
 	}, nil
 }
 
-// Connect connects to Postgres using provided config and returns DB object.
+// Connect connects to Postgres using provided config and returns DB object. When multiple hosts are specified, hosts
+// are tried in order until the connection to the host which satisfies target_session_attrs is established.
 func Connect(config Config) (*DB, error) {
+	return ConnectContext(context.Background(), config)
+}
+
+// ConnectContext is the same as Connect, but connecting is cancelled when context is done.
+func ConnectContext(ctx context.Context, config Config) (*DB, error) {
+	var lastErr error
+	for _, validate := range config.validators() {
+		for _, host := range config.hosts() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			conn, err := connectHost(ctx, config, host, validate)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			// Return established connection
+			return &DB{
+				Config: config.preferHost(host),
+				Conn:   conn,
+				Local:  strings.HasPrefix(host.Host, "/") && config.tunnel == nil,
+			}, nil
+		}
+	}
+
+	return nil, lastErr
+}
+
+// pingTimeout defines time limit of checking whether Postgres accepts connections.
+const pingTimeout = 5 * time.Second
+
+// Ping checks whether Postgres accepts connections. Connection is closed right after it is established and password is
+// never asked, failed authentication means Postgres accepts connections.
+func Ping(config Config) error {
+	var lastErr error
+	for _, host := range config.hosts() {
+		hostConfig := config.Config.Copy()
+		hostConfig.Host, hostConfig.Port, hostConfig.TLSConfig = host.Host, host.Port, host.TLSConfig
+		hostConfig.Fallbacks = nil
+
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		conn, err := pgx.ConnectConfig(ctx, hostConfig)
+		cancel()
+
+		if err == nil {
+			_ = conn.Close(context.Background())
+			return nil
+		}
+		if !Unreachable(err) {
+			return nil
+		}
+		lastErr = err
+	}
+
+	return lastErr
+}
+
+// Unreachable returns true if connection error means Postgres doesn't accept connections: it is down, starting up,
+// shutting down or not reachable over network. Errors of authentication and other errors reported by Postgres mean
+// Postgres is up.
+func Unreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 57P: admin_shutdown, crash_shutdown, cannot_connect_now.
+		return strings.HasPrefix(pgErr.Code, "57P")
+	}
+
+	return true
+}
+
+// LocalSocket returns directory of Unix socket and port of local Postgres which the config connects to. False is
+// returned if Postgres is connected over network or through SSH tunnel.
+func (c Config) LocalSocket() (string, uint16, bool) {
+	if c.tunnel != nil {
+		return "", 0, false
+	}
+
+	for _, host := range c.hosts() {
+		if strings.HasPrefix(host.Host, "/") {
+			return host.Host, host.Port, true
+		}
+	}
+
+	return "", 0, false
+}
+
+// connectHost connects to specified host and checks the connection using validate function (if specified).
+func connectHost(ctx context.Context, config Config, host *pgconn.FallbackConfig, validate pgconn.ValidateConnectFunc) (*pgx.Conn, error) {
+	hostConfig := config.Config.Copy()
+	hostConfig.Host, hostConfig.Port, hostConfig.TLSConfig = host.Host, host.Port, host.TLSConfig
+	hostConfig.Fallbacks = nil
+	hostConfig.ValidateConnect = validate
+
+	// Use token as password, token is refreshed if it is about to expire.
+	if config.auth != nil {
+		token, err := config.auth.password(host.Host, host.Port, hostConfig.User, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		hostConfig.Password = token
+	}
+
 	for {
 		// Make connection attempt
-		conn, err := pgx.ConnectConfig(context.TODO(), config.Config)
+		conn, err := pgx.ConnectConfig(ctx, hostConfig)
+
+		// Restore role set at runtime, e.g. after reconnect.
+		if err == nil && config.role != "" {
+			_, err = conn.Exec(ctx, setRoleQuery(config.role))
+			if err != nil {
+				_ = conn.Close(context.Background())
+				return nil, fmt.Errorf("set role failed: %s", err)
+			}
+		}
 
 		// Handle error if occurred.
 		if err != nil {
@@ -73,34 +297,47 @@ func Connect(config Config) (*DB, error) {
 			if errors.As(err, &pgErr) {
 				switch pgErr.Code {
 				case "28P01": // password authentication failed
+					// Token is rejected, don't ask password and request new token at the next attempt.
+					if config.auth != nil {
+						config.auth.invalidate(host.Host, host.Port, hostConfig.User)
+						return nil, fmt.Errorf("failed connection establishing using %s token: %s", config.auth.method, err)
+					}
+
 					fmt.Printf("Password for user %s: ", config.Config.User)
 					bytePassword, err := terminal.ReadPassword(0)
 					if err != nil {
 						return nil, err
 					}
 					config.Config.Password = string(bytePassword)
+					hostConfig.Password = config.Config.Password
 					fmt.Println()
 					continue
 				default:
-					return nil, fmt.Errorf("failed connection establishing: %s", err)
+					return nil, fmt.Errorf("failed connection establishing: %w", err)
 				}
 			} else {
 				return nil, err
 			}
 		}
 
-		// Return established connection
-		return &DB{
-			Config: config,
-			Conn:   conn,
-			Local:  strings.HasPrefix(config.Config.Host, "/"),
-		}, nil
+		return conn, nil
 	}
 }
 
-// Reconnect reconnects to Postgres using existing config and swaps failed DB connection.
-func Reconnect(db *DB) error {
-	newdb, err := Connect(db.Config)
+// Encrypted returns true if connection to Postgres is encrypted using SSL/TLS.
+func (db *DB) Encrypted() bool {
+	if db.Conn == nil {
+		return false
+	}
+
+	_, ok := db.Conn.PgConn().Conn().(*tls.Conn)
+	return ok
+}
+
+// Reconnect reconnects to Postgres using existing config and swaps failed DB connection. Reconnecting is cancelled when
+// context is done.
+func Reconnect(ctx context.Context, db *DB) error {
+	newdb, err := ConnectContext(ctx, db.Config)
 	if err != nil {
 		return err
 	}
@@ -112,22 +349,65 @@ func Reconnect(db *DB) error {
 
 // Exec is a wrapper over pgx.Exec.
 func (db *DB) Exec(query string, args ...interface{}) (pgconn.CommandTag, error) {
-	return db.Conn.Exec(context.TODO(), query, args...)
+	return db.ExecContext(context.Background(), query, args...)
 }
 
 // QueryRow is a wrapper over pgx.QueryRow.
 func (db *DB) QueryRow(query string, args ...interface{}) pgx.Row {
-	return db.Conn.QueryRow(context.TODO(), query, args...)
+	return db.QueryRowContext(context.Background(), query, args...)
 }
 
 // Query is a wrapper over pgx.Query.
 func (db *DB) Query(query string, args ...interface{}) (pgx.Rows, error) {
-	return db.Conn.Query(context.TODO(), query, args...)
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// ExecContext is the same as Exec, but query is cancelled when context is done.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	stop, err := db.watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	return db.Conn.Exec(context.Background(), query, args...)
+}
+
+// QueryRowContext is the same as QueryRow, but query is cancelled when context is done before row is scanned.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	stop, err := db.watch(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+
+	return watchedRow{Row: db.Conn.QueryRow(context.Background(), query, args...), stop: stop}
+}
+
+// QueryContext is the same as Query, but query is cancelled when context is done before rows are closed.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	stop, err := db.watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Conn.Query(context.Background(), query, args...)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	return &watchedRows{Rows: rows, stop: stop}, nil
 }
 
 // Close closes connection to Postgres.
 func (db *DB) Close() {
-	if err := db.Conn.Close(context.TODO()); err != nil {
+	db.CloseContext(context.Background())
+}
+
+// CloseContext is the same as Close, but waiting for graceful termination of the session is cancelled when context is
+// done. Connection is closed anyway.
+func (db *DB) CloseContext(ctx context.Context) {
+	if err := db.Conn.Close(ctx); err != nil && ctx.Err() == nil {
 		fmt.Printf("close connection failed: %s; ignore", err)
 	}
 }
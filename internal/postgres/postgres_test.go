@@ -1,11 +1,15 @@
 package postgres
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -128,6 +132,46 @@ func TestConnect(t *testing.T) {
 	}
 }
 
+func TestConnectContext(t *testing.T) {
+	config, err := pgx.ParseConfig("host=127.0.0.1 port=21913 user=postgres dbname=pgcenter_fixtures")
+	assert.NoError(t, err)
+
+	// Connecting is not started when context is already done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	db, err := ConnectContext(ctx, Config{Config: config})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Nil(t, db)
+}
+
+func TestPing(t *testing.T) {
+	config, err := pgx.ParseConfig("host=127.0.0.1 port=1 user=postgres dbname=pgcenter_fixtures")
+	assert.NoError(t, err)
+	assert.Error(t, Ping(Config{Config: config}))
+}
+
+func TestUnreachable(t *testing.T) {
+	assert.False(t, Unreachable(nil))
+	assert.True(t, Unreachable(errors.New("dial error")))
+	assert.True(t, Unreachable(fmt.Errorf("failed connection establishing: %w", &pgconn.PgError{Code: "57P03"})))
+	assert.False(t, Unreachable(fmt.Errorf("failed connection establishing: %w", &pgconn.PgError{Code: "28000"})))
+}
+
+func TestConfig_LocalSocket(t *testing.T) {
+	config, err := NewConfig("/var/run/postgresql", 5433, "postgres", "postgres")
+	assert.NoError(t, err)
+	dir, port, ok := config.LocalSocket()
+	assert.True(t, ok)
+	assert.Equal(t, "/var/run/postgresql", dir)
+	assert.Equal(t, uint16(5433), port)
+
+	config, err = NewConfig("127.0.0.1", 5432, "postgres", "postgres")
+	assert.NoError(t, err)
+	_, _, ok = config.LocalSocket()
+	assert.False(t, ok)
+}
+
 func TestReconnect(t *testing.T) {
 	c1, err := NewTestConnect()
 	assert.NoError(t, err)
@@ -144,7 +188,7 @@ func TestReconnect(t *testing.T) {
 	err = c1.PQstatus()
 	assert.Error(t, err)
 
-	err = Reconnect(c1)
+	err = Reconnect(context.Background(), c1)
 	assert.NoError(t, err)
 	assert.NoError(t, c1.QueryRow("SELECT pg_backend_pid()").Scan(&pid))
 	assert.Greater(t, pid, 0)
@@ -175,3 +219,102 @@ func TestDB_ALL(t *testing.T) {
 
 	conn.Close()
 }
+
+func TestNewConfig_ConnString(t *testing.T) {
+	testcases := []struct {
+		name        string
+		host        string
+		port        int
+		user        string
+		dbname      string
+		wantHost    string
+		wantPort    int
+		wantUser    string
+		wantDbname  string
+		wantParams  map[string]string
+		wantTimeout time.Duration
+	}{
+		{
+			name:     "URI",
+			dbname:   "postgresql://test@1.2.3.4:1234/testdb?application_name=pgcenter&connect_timeout=5&options=-c%20work_mem%3D100MB",
+			wantHost: "1.2.3.4", wantPort: 1234, wantUser: "test", wantDbname: "testdb",
+			wantParams: map[string]string{"application_name": "pgcenter", "options": "-c work_mem=100MB"}, wantTimeout: 5 * time.Second,
+		},
+		{
+			name: "URI with overridden parameters", host: "4.3.2.1", port: 4321, user: "example",
+			dbname:   "postgres://test@1.2.3.4:1234/testdb?application_name=pgcenter",
+			wantHost: "4.3.2.1", wantPort: 4321, wantUser: "example", wantDbname: "testdb",
+			wantParams: map[string]string{"application_name": "pgcenter"},
+		},
+		{
+			name:     "keyword/value",
+			dbname:   "host=1.2.3.4 port=1234 user=test dbname=testdb application_name=pgcenter connect_timeout=5 target_session_attrs=read-write",
+			wantHost: "1.2.3.4", wantPort: 1234, wantUser: "test", wantDbname: "testdb",
+			wantParams: map[string]string{"application_name": "pgcenter"}, wantTimeout: 5 * time.Second,
+		},
+		{
+			name: "keyword/value with overridden parameters", host: "4.3.2.1", user: "example",
+			dbname:   "host=1.2.3.4 port=1234 user=test dbname=testdb",
+			wantHost: "4.3.2.1", wantPort: 1234, wantUser: "example", wantDbname: "testdb",
+			wantParams: map[string]string{},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewConfig(tc.host, tc.port, tc.user, tc.dbname)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantHost, got.Config.Host)
+			assert.Equal(t, tc.wantPort, int(got.Config.Port))
+			assert.Equal(t, tc.wantUser, got.Config.User)
+			assert.Equal(t, tc.wantDbname, got.Config.Database)
+			assert.Equal(t, tc.wantTimeout, got.Config.ConnectTimeout)
+			for k, v := range tc.wantParams {
+				assert.Equal(t, v, got.Config.RuntimeParams[k])
+			}
+		})
+	}
+}
+
+func Test_mergeConnString(t *testing.T) {
+	testcases := []struct {
+		connStr string
+		params  [][2]string
+		want    string
+	}{
+		{connStr: "", params: nil, want: ""},
+		{connStr: "", params: [][2]string{{"host", "1.2.3.4"}, {"port", "1234"}}, want: "host=1.2.3.4 port=1234"},
+		{connStr: "dbname=test", params: [][2]string{{"host", "1.2.3.4"}}, want: "dbname=test host=1.2.3.4"},
+		{connStr: "postgres:///test", params: [][2]string{{"host", "/tmp"}}, want: "postgres:///test?host=%2Ftmp"},
+		{connStr: "postgres:///test?sslmode=disable", params: [][2]string{{"user", "test"}}, want: "postgres:///test?sslmode=disable&user=test"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, mergeConnString(tc.connStr, tc.params))
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	testcases := []struct {
+		connStr  string
+		valid    bool
+		wantHost string
+		wantDb   string
+	}{
+		{connStr: "host=127.0.0.1 dbname=pgcenter", valid: true, wantHost: "127.0.0.1", wantDb: "pgcenter"},
+		{connStr: "postgres://127.0.0.1:5432/pgcenter", valid: true, wantHost: "127.0.0.1", wantDb: "pgcenter"},
+		{connStr: "host=127.0.0.1 port=invalid", valid: false},
+	}
+
+	for _, tc := range testcases {
+		got, err := ParseConfig(tc.connStr)
+		if tc.valid {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantHost, got.Config.Host)
+			assert.Equal(t, tc.wantDb, got.Config.Database)
+			assert.True(t, got.Config.PreferSimpleProtocol)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
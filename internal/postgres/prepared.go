@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgconn/stmtcache"
+	"github.com/jackc/pgx/v4"
+	"hash/fnv"
+)
+
+// maxPreparedStatements defines how many prepared statements are kept per connection. The oldest statement is
+// deallocated when the limit is exceeded, e.g. when query of the view has been changed many times.
+const maxPreparedStatements = 32
+
+// preferPrepared returns true if statement cache of the driver config is not disabled and doesn't use 'describe'
+// mode (statement_cache_capacity=0 or statement_cache_mode=describe in connection string), e.g. for compatibility
+// with Pgbouncer in transaction pooling mode.
+func preferPrepared(c *pgx.ConnConfig) bool {
+	return c.BuildStatementCache != nil && c.BuildStatementCache(nil).Mode() == stmtcache.ModePrepare
+}
+
+// statementName returns name of prepared statement used for the query.
+func statementName(query string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(query))
+	return fmt.Sprintf("pgcenter_%x", h.Sum64())
+}
+
+// QueryPrepared executes query which is executed repeatedly (e.g. at every refresh) using prepared statement. The
+// statement is prepared at the first execution on the connection, hence Postgres doesn't parse and plan the query
+// again at every execution. After reconnect statements are prepared again. Query is executed as-is when prepared
+// statements are disabled.
+func (db *DB) QueryPrepared(query string, args ...interface{}) (pgx.Rows, error) {
+	return db.QueryPreparedContext(context.Background(), query, args...)
+}
+
+// QueryRowPrepared is the same as QueryPrepared, but returns single row.
+func (db *DB) QueryRowPrepared(query string, args ...interface{}) pgx.Row {
+	return db.QueryRowPreparedContext(context.Background(), query, args...)
+}
+
+// QueryPreparedContext is the same as QueryPrepared, but query is cancelled when context is done before rows are
+// closed.
+func (db *DB) QueryPreparedContext(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	if !db.Config.prepare {
+		return db.QueryContext(ctx, query, args...)
+	}
+
+	stop, err := db.watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := db.prepare(query)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	rows, err := db.Conn.Query(context.Background(), name, preparedArgs(args)...)
+	if err != nil {
+		stop()
+		db.checkPrepared(err)
+		return nil, err
+	}
+
+	return &preparedRows{Rows: &watchedRows{Rows: rows, stop: stop}, db: db}, nil
+}
+
+// QueryRowPreparedContext is the same as QueryRowPrepared, but query is cancelled when context is done before row is
+// scanned.
+func (db *DB) QueryRowPreparedContext(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	if !db.Config.prepare {
+		return db.QueryRowContext(ctx, query, args...)
+	}
+
+	stop, err := db.watch(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+
+	name, err := db.prepare(query)
+	if err != nil {
+		stop()
+		return errRow{err: err}
+	}
+
+	row := db.Conn.QueryRow(context.Background(), name, preparedArgs(args)...)
+	return preparedRow{Row: watchedRow{Row: row, stop: stop}, db: db}
+}
+
+// preparedArgs returns arguments for executing prepared statement. Values are returned in text format, the same as
+// values of queries executed using simple protocol.
+func preparedArgs(args []interface{}) []interface{} {
+	return append([]interface{}{pgx.QuerySimpleProtocol(false), pgx.QueryResultFormats{pgx.TextFormatCode}}, args...)
+}
+
+// prepare prepares statement for the query if it has not been prepared yet, and returns name of the statement.
+func (db *DB) prepare(query string) (string, error) {
+	name := statementName(query)
+	for _, n := range db.prepared {
+		if n == name {
+			return name, nil
+		}
+	}
+
+	if len(db.prepared) >= maxPreparedStatements {
+		_ = db.Conn.Deallocate(context.Background(), db.prepared[0])
+		db.prepared = db.prepared[1:]
+	}
+
+	_, err := db.Conn.Prepare(context.Background(), name, query)
+	if err != nil {
+		db.checkPrepared(err)
+		return "", err
+	}
+
+	db.prepared = append(db.prepared, name)
+	return name, nil
+}
+
+// checkPrepared disables prepared statements if they don't exist at execution. It happens when connected through
+// Pgbouncer in transaction pooling mode, statements are prepared and executed using different server connections.
+func (db *DB) checkPrepared(err error) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "26000" { // invalid_sql_statement_name
+		db.Config.prepare = false
+		db.prepared = nil
+	}
+}
+
+// preparedRows checks errors of executed prepared statement when rows are closed.
+type preparedRows struct {
+	pgx.Rows
+	db *DB
+}
+
+// Close closes rows and checks execution error.
+func (r *preparedRows) Close() {
+	r.Rows.Close()
+	r.db.checkPrepared(r.Rows.Err())
+}
+
+// preparedRow checks errors of executed prepared statement when row is scanned.
+type preparedRow struct {
+	pgx.Row
+	db *DB
+}
+
+// Scan reads values of the row and checks execution error.
+func (r preparedRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	r.db.checkPrepared(err)
+	return err
+}
+
+// errRow is the row which returns error occurred before query execution.
+type errRow struct {
+	err error
+}
+
+// Scan returns the error.
+func (r errRow) Scan(...interface{}) error {
+	return r.err
+}
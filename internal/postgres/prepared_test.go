@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseConfig_prepare(t *testing.T) {
+	testcases := []struct {
+		connStr string
+		want    bool
+	}{
+		{connStr: "host=127.0.0.1 user=postgres", want: true},
+		{connStr: "host=127.0.0.1 user=postgres statement_cache_mode=describe", want: false},
+		{connStr: "postgres://postgres@127.0.0.1/db?statement_cache_capacity=0", want: false},
+	}
+
+	for _, tc := range testcases {
+		config, err := ParseConfig(tc.connStr)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, config.prepare, tc.connStr)
+		assert.True(t, config.Config.PreferSimpleProtocol)
+	}
+}
+
+func Test_statementName(t *testing.T) {
+	assert.Equal(t, statementName("SELECT 1"), statementName("SELECT 1"))
+	assert.NotEqual(t, statementName("SELECT 1"), statementName("SELECT 2"))
+	assert.Regexp(t, `^pgcenter_[0-9a-f]+$`, statementName("SELECT 1"))
+}
+
+func TestDB_checkPrepared(t *testing.T) {
+	db := &DB{Config: Config{prepare: true}, prepared: []string{"pgcenter_1"}}
+
+	db.checkPrepared(nil)
+	db.checkPrepared(fmt.Errorf("connection reset"))
+	db.checkPrepared(&pgconn.PgError{Code: "42P01"})
+	assert.True(t, db.Config.prepare)
+	assert.Len(t, db.prepared, 1)
+
+	// Statement doesn't exist, e.g. when connected through Pgbouncer in transaction pooling mode.
+	db.checkPrepared(fmt.Errorf("scan failed: %w", &pgconn.PgError{Code: "26000"}))
+	assert.False(t, db.Config.prepare)
+	assert.Nil(t, db.prepared)
+}
+
+func TestDB_QueryPrepared(t *testing.T) {
+	db, err := NewTestConnect()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	assert.True(t, db.Config.prepare)
+
+	for i := 0; i < 2; i++ {
+		var n, s string
+		assert.NoError(t, db.QueryRowPrepared("SELECT 1.50::numeric, $1::text", "test").Scan(&n, &s))
+		assert.Equal(t, "1.50", n)
+		assert.Equal(t, "test", s)
+
+		rows, err := db.QueryPrepared("SELECT now() - now() AS interval")
+		assert.NoError(t, err)
+		assert.True(t, rows.Next())
+		values, err := rows.Values()
+		assert.NoError(t, err)
+		assert.Len(t, values, 1)
+		rows.Close()
+		assert.NoError(t, rows.Err())
+	}
+	assert.Len(t, db.prepared, 2)
+
+	// Statements are prepared again after reconnect.
+	assert.NoError(t, Reconnect(context.Background(), db))
+	assert.Len(t, db.prepared, 0)
+	assert.Error(t, db.QueryRowPrepared("SELECT invalid").Scan())
+	assert.Len(t, db.prepared, 0)
+
+	// The oldest statements are deallocated when limit is exceeded.
+	for i := 0; i < maxPreparedStatements+2; i++ {
+		var n int
+		assert.NoError(t, db.QueryRowPrepared(fmt.Sprintf("SELECT %d", i)).Scan(&n))
+		assert.Equal(t, i, n)
+	}
+	assert.Len(t, db.prepared, maxPreparedStatements)
+	assert.Equal(t, statementName("SELECT 2"), db.prepared[0])
+}
@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+)
+
+// setRoleQuery returns query which sets current role of the session, empty role resets it to the session user.
+func setRoleQuery(role string) string {
+	if role == "" {
+		return "RESET ROLE"
+	}
+	return "SET ROLE " + pgx.Identifier{role}.Sanitize()
+}
+
+// Role returns role set for the connections established using the config, empty string means session user.
+func (c Config) Role() string {
+	return c.role
+}
+
+// SetRole sets current role of the session using SET ROLE, empty role resets it to the session user. The role is kept
+// in the connection config, hence it is set again after reconnect. Query is cancelled when context is done.
+func (db *DB) SetRole(ctx context.Context, role string) error {
+	_, err := db.ExecContext(ctx, setRoleQuery(role))
+	if err != nil {
+		return fmt.Errorf("set role failed: %s", err)
+	}
+
+	db.Config.role = role
+	return nil
+}
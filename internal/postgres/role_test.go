@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_setRoleQuery(t *testing.T) {
+	assert.Equal(t, "RESET ROLE", setRoleQuery(""))
+	assert.Equal(t, `SET ROLE "pg_monitor"`, setRoleQuery("pg_monitor"))
+	assert.Equal(t, `SET ROLE "Admin""; DROP TABLE t"`, setRoleQuery(`Admin"; DROP TABLE t`))
+}
+
+func TestDB_SetRole(t *testing.T) {
+	db, err := NewTestConnect()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var user string
+	assert.NoError(t, db.SetRole(context.Background(), "pg_monitor"))
+	assert.Equal(t, "pg_monitor", db.Config.Role())
+	assert.NoError(t, db.QueryRow("SELECT current_user").Scan(&user))
+	assert.Equal(t, "pg_monitor", user)
+
+	// Role is restored after reconnect.
+	assert.NoError(t, Reconnect(context.Background(), db))
+	assert.NoError(t, db.QueryRow("SELECT current_user").Scan(&user))
+	assert.Equal(t, "pg_monitor", user)
+
+	assert.NoError(t, db.SetRole(context.Background(), ""))
+	assert.Equal(t, "", db.Config.Role())
+	assert.NoError(t, db.QueryRow("SELECT current_user").Scan(&user))
+	assert.Equal(t, "postgres", user)
+
+	// Unknown role, current role is kept.
+	assert.Error(t, db.SetRole(context.Background(), "pgcenter_unknown_role"))
+	assert.Equal(t, "", db.Config.Role())
+}
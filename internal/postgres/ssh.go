@@ -0,0 +1,276 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/crypto/ssh/terminal"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sshDialTimeout defines timeout of establishing connection to SSH jump host.
+const sshDialTimeout = 10 * time.Second
+
+// SSHOptions defines options of SSH tunnel used for connecting to Postgres through a jump host.
+type SSHOptions struct {
+	Destination string // jump host in user@host[:port] format
+	Key         string // file with private key used for authentication
+}
+
+// sshTunnel forwards connections to Postgres through SSH jump host. Connection to the jump host is established on the
+// first use and shared by all connections to Postgres.
+type sshTunnel struct {
+	mu     sync.Mutex
+	addr   string // address of the jump host
+	config *ssh.ClientConfig
+	client *ssh.Client
+}
+
+// newSSHTunnel creates SSH tunnel using specified options. Authentication is made using private keys (specified key or
+// default keys from ~/.ssh) and keys provided by SSH agent. Host key of the jump host is verified using known_hosts files.
+func newSSHTunnel(o SSHOptions) (*sshTunnel, error) {
+	username, addr, err := parseSSHDestination(o.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := sshAuthMethod(o.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	files := sshKnownHostsFiles()
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no known_hosts files found, host key of %s can't be verified", addr)
+	}
+
+	hostKeyCallback, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read known_hosts: %s", err)
+	}
+
+	return &sshTunnel{
+		addr: addr,
+		config: &ssh.ClientConfig{
+			User:            username,
+			Auth:            []ssh.AuthMethod{auth},
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         sshDialTimeout,
+		},
+	}, nil
+}
+
+// parseSSHDestination parses destination in user@host[:port] format and returns user name and address of the host. If
+// user name is not specified, name of the current OS user is used. If port is not specified, default SSH port is used.
+func parseSSHDestination(dest string) (string, string, error) {
+	var username, host string
+	if i := strings.LastIndex(dest, "@"); i >= 0 {
+		username, host = dest[:i], dest[i+1:]
+	} else {
+		host = dest
+	}
+
+	if host == "" {
+		return "", "", fmt.Errorf("invalid SSH destination '%s': host is not specified", dest)
+	}
+
+	if username == "" {
+		u, err := user.Current()
+		if err != nil {
+			return "", "", fmt.Errorf("invalid SSH destination '%s': user is not specified", dest)
+		}
+		username = u.Username
+	}
+
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "22")
+	}
+
+	return username, host, nil
+}
+
+// sshAuthMethod returns public key authentication method which offers specified key, keys provided by SSH agent and
+// default keys, in this order. If key is not specified, default keys with passphrase are skipped.
+func sshAuthMethod(keyFile string) (ssh.AuthMethod, error) {
+	var keys []ssh.Signer
+	if keyFile != "" {
+		signer, err := loadSSHKey(keyFile, true)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read SSH key: %s", err)
+		}
+		keys = append(keys, signer)
+	}
+
+	var agentClient agent.Agent
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			agentClient = agent.NewClient(conn)
+		}
+	}
+
+	var defaultKeys []ssh.Signer
+	if keyFile == "" {
+		for _, f := range defaultSSHKeys() {
+			if signer, err := loadSSHKey(f, false); err == nil {
+				defaultKeys = append(defaultKeys, signer)
+			}
+		}
+	}
+
+	if len(keys) == 0 && agentClient == nil && len(defaultKeys) == 0 {
+		return nil, fmt.Errorf("no SSH keys found and SSH agent is not available")
+	}
+
+	// Only one authentication method of each type is tried, hence all keys are offered by a single method.
+	return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		signers := append([]ssh.Signer{}, keys...)
+		if agentClient != nil {
+			if s, err := agentClient.Signers(); err == nil {
+				signers = append(signers, s...)
+			}
+		}
+		return append(signers, defaultKeys...), nil
+	}), nil
+}
+
+// loadSSHKey reads private key from file. If key is encrypted and prompt is allowed, passphrase is asked.
+func loadSSHKey(filename string, prompt bool) (ssh.Signer, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	if _, ok := err.(*ssh.PassphraseMissingError); ok && prompt {
+		fmt.Printf("Passphrase for key %s: ", filename)
+		passphrase, err := terminal.ReadPassword(0)
+		fmt.Println()
+		if err != nil {
+			return nil, err
+		}
+
+		return ssh.ParsePrivateKeyWithPassphrase(data, passphrase)
+	}
+
+	return signer, err
+}
+
+// defaultSSHKeys returns paths to default private keys in user's ~/.ssh directory.
+func defaultSSHKeys() []string {
+	u, err := user.Current()
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		files = append(files, filepath.Join(u.HomeDir, ".ssh", name))
+	}
+
+	return files
+}
+
+// sshKnownHostsFiles returns paths to existing user's and system-wide known_hosts files.
+func sshKnownHostsFiles() []string {
+	var candidates []string
+	if u, err := user.Current(); err == nil {
+		candidates = append(candidates, filepath.Join(u.HomeDir, ".ssh", "known_hosts"))
+	}
+	candidates = append(candidates, "/etc/ssh/ssh_known_hosts")
+
+	var files []string
+	for _, f := range candidates {
+		if _, err := os.Stat(f); err == nil {
+			files = append(files, f)
+		}
+	}
+
+	return files
+}
+
+// dial establishes connection to specified address through the jump host. It is used as dial function of the driver.
+func (t *sshTunnel) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		conn, err := t.dialTunnel(network, addr)
+		ch <- result{conn: conn, err: err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.conn, r.err
+	case <-ctx.Done():
+		// Close connection established after context has been cancelled.
+		go func() {
+			if r := <-ch; r.conn != nil {
+				_ = r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// dialTunnel establishes connection to specified address through the jump host. Connection to the jump host is
+// established if it doesn't exist yet or has been lost.
+func (t *sshTunnel) dialTunnel(network, addr string) (net.Conn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client == nil {
+		client, err := ssh.Dial("tcp", t.addr, t.config)
+		if err != nil {
+			return nil, fmt.Errorf("SSH connection to %s failed: %s", t.addr, err)
+		}
+		t.client = client
+	}
+
+	conn, err := t.client.Dial(network, addr)
+	if err != nil {
+		// Jump host rejected forwarding, but connection to the jump host is still alive. Otherwise, the connection is
+		// considered lost and it is reestablished at the next attempt.
+		if _, ok := err.(*ssh.OpenChannelError); !ok {
+			_ = t.client.Close()
+			t.client = nil
+		}
+		return nil, fmt.Errorf("SSH tunnel to %s through %s failed: %s", addr, t.addr, err)
+	}
+
+	return conn, nil
+}
+
+// lookup returns host as is, hence host names are resolved by the jump host. It is used as lookup function of the driver.
+func (t *sshTunnel) lookup(_ context.Context, host string) ([]string, error) {
+	return []string{host}, nil
+}
+
+// setTunnel configures connecting to Postgres through SSH tunnel.
+func (c *Config) setTunnel(o SSHOptions) error {
+	if o.Destination == "" {
+		return nil
+	}
+
+	tunnel, err := newSSHTunnel(o)
+	if err != nil {
+		return err
+	}
+
+	c.tunnel = tunnel
+	c.Config.DialFunc = tunnel.dial
+	c.Config.LookupFunc = tunnel.lookup
+
+	return nil
+}
@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func Test_parseSSHDestination(t *testing.T) {
+	testcases := []struct {
+		dest     string
+		wantUser string
+		wantAddr string
+	}{
+		{dest: "postgres@bastion", wantUser: "postgres", wantAddr: "bastion:22"},
+		{dest: "postgres@bastion:2222", wantUser: "postgres", wantAddr: "bastion:2222"},
+		{dest: "postgres@10.0.0.1", wantUser: "postgres", wantAddr: "10.0.0.1:22"},
+		{dest: "postgres@[::1]", wantUser: "postgres", wantAddr: "[::1]:22"},
+		{dest: "postgres@[::1]:2222", wantUser: "postgres", wantAddr: "[::1]:2222"},
+		{dest: "user@example.org@bastion", wantUser: "user@example.org", wantAddr: "bastion:22"},
+	}
+
+	for _, tc := range testcases {
+		gotUser, gotAddr, err := parseSSHDestination(tc.dest)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.wantUser, gotUser)
+		assert.Equal(t, tc.wantAddr, gotAddr)
+	}
+
+	// User name of the current user is used.
+	gotUser, gotAddr, err := parseSSHDestination("bastion")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "", gotUser)
+	assert.Equal(t, "bastion:22", gotAddr)
+
+	for _, dest := range []string{"", "postgres@"} {
+		_, _, err := parseSSHDestination(dest)
+		assert.Error(t, err)
+	}
+}
+
+func Test_loadSSHKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-ssh-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	_, plain := writeTestSSHKey(t, dir, "id_plain", "")
+	signer, err := loadSSHKey(plain, false)
+	assert.NoError(t, err)
+	assert.NotNil(t, signer)
+
+	// Passphrase is not asked.
+	_, encrypted := writeTestSSHKey(t, dir, "id_encrypted", "secret")
+	_, err = loadSSHKey(encrypted, false)
+	assert.IsType(t, &ssh.PassphraseMissingError{}, err)
+
+	_, err = loadSSHKey(filepath.Join(dir, "unknown"), false)
+	assert.Error(t, err)
+
+	_, err = sshAuthMethod(filepath.Join(dir, "unknown"))
+	assert.Error(t, err)
+}
+
+func Test_sshTunnel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-ssh-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	clientKey, keyFile := writeTestSSHKey(t, dir, "id_client", "")
+	hostKey, _ := writeTestSSHKey(t, dir, "id_host", "")
+	otherKey, _ := writeTestSSHKey(t, dir, "id_other", "")
+
+	sshAddr := startTestSSHServer(t, hostKey, clientKey.PublicKey())
+	echoAddr := startTestEchoServer(t)
+
+	auth, err := sshAuthMethod(keyFile)
+	assert.NoError(t, err)
+
+	newTunnel := func(key ssh.PublicKey) *sshTunnel {
+		f := filepath.Join(dir, "known_hosts")
+		assert.NoError(t, ioutil.WriteFile(f, []byte(knownhosts.Line([]string{sshAddr}, key)+"\n"), 0600))
+
+		callback, err := knownhosts.New(f)
+		assert.NoError(t, err)
+
+		return &sshTunnel{
+			addr:   sshAddr,
+			config: &ssh.ClientConfig{User: "postgres", Auth: []ssh.AuthMethod{auth}, HostKeyCallback: callback},
+		}
+	}
+
+	tunnel := newTunnel(hostKey.PublicKey())
+
+	conn, err := tunnel.dial(context.Background(), "tcp", echoAddr)
+	assert.NoError(t, err)
+
+	_, err = conn.Write([]byte("hello"))
+	assert.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+	assert.NoError(t, conn.Close())
+
+	// Forwarding rejected by jump host, connection to jump host is kept.
+	_, err = tunnel.dial(context.Background(), "tcp", "127.0.0.1:1")
+	assert.Error(t, err)
+	assert.NotNil(t, tunnel.client)
+
+	// Cancelled context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = tunnel.dial(ctx, "tcp", echoAddr)
+	assert.Error(t, err)
+
+	// Host key doesn't match known_hosts.
+	_, err = newTunnel(otherKey.PublicKey()).dial(context.Background(), "tcp", echoAddr)
+	assert.Error(t, err)
+
+	// Host names are resolved by jump host.
+	got, err := tunnel.lookup(context.Background(), "db.internal")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db.internal"}, got)
+}
+
+func TestConfig_setTunnel(t *testing.T) {
+	config, err := ParseConfig("host=127.0.0.1")
+	assert.NoError(t, err)
+
+	assert.NoError(t, config.setTunnel(SSHOptions{}))
+	assert.Nil(t, config.tunnel)
+
+	assert.Error(t, config.setTunnel(SSHOptions{Destination: "postgres@"}))
+	assert.Nil(t, config.tunnel)
+}
+
+// writeTestSSHKey generates private key and writes it into file, key is encrypted if passphrase is specified.
+func writeTestSSHKey(t *testing.T, dir string, name string, passphrase string) (ssh.Signer, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	der, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	block := &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	if passphrase != "" {
+		block, err = x509.EncryptPEMBlock(rand.Reader, block.Type, der, []byte(passphrase), x509.PEMCipherAES256) // nolint:staticcheck
+		assert.NoError(t, err)
+	}
+
+	filename := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(filename, pem.EncodeToMemory(block), 0600))
+
+	signer, err := ssh.NewSignerFromKey(key)
+	assert.NoError(t, err)
+
+	return signer, filename
+}
+
+// startTestSSHServer starts SSH server which accepts specified client key and forwards TCP connections.
+func startTestSSHServer(t *testing.T, hostKey ssh.Signer, clientKey ssh.PublicKey) string {
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(c, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+
+				for newCh := range chans {
+					var payload struct {
+						Addr     string
+						Port     uint32
+						OrigAddr string
+						OrigPort uint32
+					}
+					if newCh.ChannelType() != "direct-tcpip" || ssh.Unmarshal(newCh.ExtraData(), &payload) != nil {
+						_ = newCh.Reject(ssh.UnknownChannelType, "unsupported")
+						continue
+					}
+
+					target, err := net.Dial("tcp", net.JoinHostPort(payload.Addr, strconv.Itoa(int(payload.Port))))
+					if err != nil {
+						_ = newCh.Reject(ssh.ConnectionFailed, err.Error())
+						continue
+					}
+
+					ch, chReqs, err := newCh.Accept()
+					if err != nil {
+						_ = target.Close()
+						continue
+					}
+					go ssh.DiscardRequests(chReqs)
+
+					go func() {
+						_, _ = io.Copy(target, ch)
+						_ = target.Close()
+					}()
+					go func() {
+						_, _ = io.Copy(ch, target)
+						_ = ch.Close()
+					}()
+				}
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// startTestEchoServer starts TCP server which sends back everything it receives.
+func startTestEchoServer(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(c, c)
+				_ = c.Close()
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
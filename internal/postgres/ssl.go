@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// SSLOptions defines SSL/TLS connection options.
+type SSLOptions struct {
+	Mode     string // SSL mode: disable, allow, prefer, require, verify-ca, verify-full
+	RootCert string // file with root certificates used for verifying server certificate
+	Cert     string // file with client certificate
+	Key      string // file with client certificate's private key
+	Password string // password for decrypting client certificate's private key
+}
+
+// params returns connection string parameters defined by options. Client certificate with encrypted private key is
+// not passed to the driver, it is loaded separately.
+func (o SSLOptions) params() [][2]string {
+	var params [][2]string
+	if o.Mode != "" {
+		params = append(params, [2]string{"sslmode", o.Mode})
+	}
+	if o.RootCert != "" {
+		params = append(params, [2]string{"sslrootcert", o.RootCert})
+	}
+	if o.Password == "" {
+		if o.Cert != "" {
+			params = append(params, [2]string{"sslcert", o.Cert})
+		}
+		if o.Key != "" {
+			params = append(params, [2]string{"sslkey", o.Key})
+		}
+	}
+	return params
+}
+
+// ParseConfigSSL creates config from connection string and SSL options. SSL options take precedence over the same
+// parameters specified in connection string.
+func ParseConfigSSL(connStr string, o SSLOptions) (Config, error) {
+	config, err := ParseConfig(mergeConnString(connStr, o.params()))
+	if err != nil {
+		return Config{}, err
+	}
+
+	if o.Password == "" || (o.Cert == "" && o.Key == "") {
+		return config, nil
+	}
+
+	if o.Cert == "" || o.Key == "" {
+		return Config{}, fmt.Errorf("both sslcert and sslkey are required")
+	}
+
+	cert, err := loadKeyPair(o.Cert, o.Key, o.Password)
+	if err != nil {
+		return Config{}, fmt.Errorf("unable to read cert: %s", err)
+	}
+
+	// Configure certificate in all TLS configs, including configs of fallback connections.
+	if config.Config.TLSConfig != nil {
+		config.Config.TLSConfig.Certificates = []tls.Certificate{cert}
+	}
+	for _, fc := range config.Config.Fallbacks {
+		if fc.TLSConfig != nil {
+			fc.TLSConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+
+	return config, nil
+}
+
+// loadKeyPair reads client certificate and its private key encrypted with password.
+func loadKeyPair(certFile, keyFile, password string) (tls.Certificate, error) {
+	certPEM, err := ioutil.ReadFile(filepath.Clean(certFile))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	keyPEM, err := ioutil.ReadFile(filepath.Clean(keyFile))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return tls.Certificate{}, fmt.Errorf("failed to decode private key")
+	}
+
+	// Only legacy PEM encryption (as produced by 'openssl rsa -aes256') is supported by Go standard library.
+	if x509.IsEncryptedPEMBlock(block) { //nolint:staticcheck
+		der, err := x509.DecryptPEMBlock(block, []byte(password)) //nolint:staticcheck
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("failed to decrypt private key: %s", err)
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
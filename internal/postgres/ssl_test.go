@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSSLOptions_params(t *testing.T) {
+	testcases := []struct {
+		opts SSLOptions
+		want [][2]string
+	}{
+		{opts: SSLOptions{}, want: nil},
+		{
+			opts: SSLOptions{Mode: "verify-full", RootCert: "root.crt", Cert: "client.crt", Key: "client.key"},
+			want: [][2]string{{"sslmode", "verify-full"}, {"sslrootcert", "root.crt"}, {"sslcert", "client.crt"}, {"sslkey", "client.key"}},
+		},
+		{
+			opts: SSLOptions{Mode: "require", Cert: "client.crt", Key: "client.key", Password: "secret"},
+			want: [][2]string{{"sslmode", "require"}},
+		},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, tc.opts.params())
+	}
+}
+
+func TestParseConfigSSL(t *testing.T) {
+	testcases := []struct {
+		connStr   string
+		opts      SSLOptions
+		valid     bool
+		wantTLS   bool
+		wantCerts int
+	}{
+		{connStr: "host=127.0.0.1", opts: SSLOptions{Mode: "disable"}, valid: true, wantTLS: false},
+		{connStr: "host=127.0.0.1 sslmode=disable", opts: SSLOptions{Mode: "require"}, valid: true, wantTLS: true},
+		{connStr: "postgres://127.0.0.1/pgcenter", opts: SSLOptions{Mode: "require"}, valid: true, wantTLS: true},
+		{connStr: "host=127.0.0.1", opts: SSLOptions{Mode: "invalid"}, valid: false},
+		{connStr: "host=127.0.0.1", opts: SSLOptions{Mode: "verify-ca", RootCert: "testdata/invalid.crt"}, valid: false},
+		{
+			connStr: "host=127.0.0.1",
+			opts:    SSLOptions{Mode: "require", Cert: "testdata/client.crt", Key: "testdata/client.key", Password: "secret"},
+			valid:   true, wantTLS: true, wantCerts: 1,
+		},
+		{
+			connStr: "host=127.0.0.1",
+			opts:    SSLOptions{Mode: "require", Cert: "testdata/client.crt", Key: "testdata/client.key", Password: "invalid"},
+			valid:   false,
+		},
+		{
+			connStr: "host=127.0.0.1",
+			opts:    SSLOptions{Mode: "require", Key: "testdata/client.key", Password: "secret"},
+			valid:   false,
+		},
+	}
+
+	for _, tc := range testcases {
+		got, err := ParseConfigSSL(tc.connStr, tc.opts)
+		if tc.valid {
+			assert.NoError(t, err)
+			if tc.wantTLS {
+				assert.NotNil(t, got.Config.TLSConfig)
+				assert.Len(t, got.Config.TLSConfig.Certificates, tc.wantCerts)
+			} else {
+				assert.Nil(t, got.Config.TLSConfig)
+			}
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
+
+func Test_loadKeyPair(t *testing.T) {
+	_, err := loadKeyPair("testdata/client.crt", "testdata/client.key", "secret")
+	assert.NoError(t, err)
+
+	_, err = loadKeyPair("testdata/client.crt", "testdata/client.key", "invalid")
+	assert.Error(t, err)
+
+	_, err = loadKeyPair("testdata/invalid.crt", "testdata/client.key", "secret")
+	assert.Error(t, err)
+
+	_, err = loadKeyPair("testdata/client.crt", "testdata/client.crt", "secret")
+	assert.Error(t, err)
+}
@@ -15,11 +15,15 @@ func NewTestConnect() (*DB, error) {
 // NewTestConnectVersion connects to test Postgres.
 // Necessary Postgres instances have to be up and running on specified ports.
 func NewTestConnectVersion(version int) (*DB, error) {
-	if version < 90400 || version > 140000 {
+	if version < 90400 || version > 170000 {
 		return nil, fmt.Errorf("unsupported version selected")
 	}
 
 	ports := map[int]int{
+		170000: 21917,
+		160000: 21916,
+		150000: 21915,
+		140000: 21914,
 		130000: 21913,
 		120000: 21912,
 		110000: 21911,
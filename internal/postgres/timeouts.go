@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"sync"
+	"time"
+)
+
+// setTimeouts configures statement_timeout and lock_timeout which are set on every connection established using the
+// config, hence internal queries can't hang on locks or run too long. Zero values keep server's settings.
+func (c Config) setTimeouts(statementTimeout, lockTimeout time.Duration) {
+	var stmts []string
+	if statementTimeout > 0 {
+		stmts = append(stmts, fmt.Sprintf("SET statement_timeout TO %d", statementTimeout.Milliseconds()))
+	}
+	if lockTimeout > 0 {
+		stmts = append(stmts, fmt.Sprintf("SET lock_timeout TO %d", lockTimeout.Milliseconds()))
+	}
+
+	if len(stmts) == 0 {
+		return
+	}
+
+	q := strings.Join(stmts, "; ")
+	c.Config.AfterConnect = func(ctx context.Context, pgConn *pgconn.PgConn) error {
+		_, err := pgConn.Exec(ctx, q).ReadAll()
+		if err != nil {
+			return fmt.Errorf("set timeouts failed: %s", err)
+		}
+		return nil
+	}
+}
+
+// WatchContext cancels query executed by the connection when context is done. Unlike cancelling by the driver, query
+// is cancelled using cancel request, hence the connection remains usable. Returned function stops watching, it should
+// be called when query is finished. It waits until sent cancel request is completed, hence the request doesn't cancel
+// the next query.
+func (db *DB) WatchContext(ctx context.Context) func() {
+	pgConn := db.Conn.PgConn()
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			_ = pgConn.CancelRequest(context.Background())
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// watch starts watching context for the query. Error is returned if context is already done, query should not be
+// executed in this case. Contexts which are never done are not watched.
+func (db *DB) watch(ctx context.Context) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if ctx.Done() == nil {
+		return func() {}, nil
+	}
+
+	return db.WatchContext(ctx), nil
+}
+
+// watchedRows stops watching context of the query when rows are closed.
+type watchedRows struct {
+	pgx.Rows
+	stop func()
+	once sync.Once
+}
+
+// Close closes rows and stops watching context.
+func (r *watchedRows) Close() {
+	r.Rows.Close()
+	r.once.Do(r.stop)
+}
+
+// watchedRow stops watching context of the query when row is scanned.
+type watchedRow struct {
+	pgx.Row
+	stop func()
+}
+
+// Scan reads values of the row and stops watching context.
+func (r watchedRow) Scan(dest ...interface{}) error {
+	defer r.stop()
+	return r.Row.Scan(dest...)
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestConfig_setTimeouts(t *testing.T) {
+	config, err := NewConfig("127.0.0.1", 21913, "postgres", "pgcenter_fixtures")
+	assert.NoError(t, err)
+
+	config.setTimeouts(0, 0)
+	assert.Nil(t, config.Config.AfterConnect)
+
+	config.setTimeouts(30*time.Second, 0)
+	assert.NotNil(t, config.Config.AfterConnect)
+}
+
+func TestConnectionOptions_NewConfig_Timeouts(t *testing.T) {
+	config, err := ConnectionOptions{
+		Host: "127.0.0.1", Port: 21913, User: "postgres", Dbname: "pgcenter_fixtures",
+		StatementTimeout: 30 * time.Second, LockTimeout: 1500 * time.Millisecond,
+	}.NewConfig()
+	assert.NoError(t, err)
+
+	db, err := Connect(config)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var statementTimeout, lockTimeout string
+	assert.NoError(t, db.QueryRow("SELECT current_setting('statement_timeout'), current_setting('lock_timeout')").Scan(&statementTimeout, &lockTimeout))
+	assert.Equal(t, "30s", statementTimeout)
+	assert.Equal(t, "1500ms", lockTimeout)
+}
+
+func TestDB_WatchContext(t *testing.T) {
+	db, err := NewTestConnect()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	stop := db.WatchContext(ctx)
+	_, err = db.Exec("SELECT pg_sleep(10)")
+	stop()
+
+	var pgErr *pgconn.PgError
+	assert.True(t, errors.As(err, &pgErr))
+	assert.Equal(t, "57014", pgErr.Code)
+
+	// Connection is still usable.
+	assert.NoError(t, db.PQstatus())
+}
+
+func TestDB_watch(t *testing.T) {
+	// Queries are not executed when context is already done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	db := &DB{Config: Config{prepare: true}}
+
+	_, err := db.ExecContext(ctx, "SELECT 1")
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = db.QueryContext(ctx, "SELECT 1")
+	assert.Equal(t, context.Canceled, err)
+
+	assert.Equal(t, context.Canceled, db.QueryRowContext(ctx, "SELECT 1").Scan())
+
+	_, err = db.QueryPreparedContext(ctx, "SELECT 1")
+	assert.Equal(t, context.Canceled, err)
+
+	assert.Equal(t, context.Canceled, db.QueryRowPreparedContext(ctx, "SELECT 1").Scan())
+}
+
+func TestDB_QueryContext(t *testing.T) {
+	db, err := NewTestConnect()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var s string
+	err = db.QueryRowPreparedContext(ctx, "SELECT pg_sleep(10)::text").Scan(&s)
+
+	var pgErr *pgconn.PgError
+	assert.True(t, errors.As(err, &pgErr))
+	assert.Equal(t, "57014", pgErr.Code)
+
+	// Connection is still usable.
+	assert.NoError(t, db.PQstatus())
+}
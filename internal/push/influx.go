@@ -0,0 +1,107 @@
+package push
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// influxStdout defines name of file which means writing into stdout.
+const influxStdout = "-"
+
+var (
+	// measurementEscaper escapes special characters in measurements names.
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	// keyEscaper escapes special characters in tags keys and values, and in fields keys.
+	keyEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// influxSink writes metrics in InfluxDB line protocol into InfluxDB write API over HTTP, or into file.
+type influxSink struct {
+	url     string
+	headers map[string]string
+	file    string
+	prefix  string
+	stdout  io.Writer
+}
+
+// push implements sink interface.
+func (s *influxSink) push(ctx context.Context, b Batch) error {
+	data := []byte(formatInflux(s.prefix, b))
+
+	switch {
+	case s.url != "":
+		return post(ctx, s.url, "text/plain; charset=utf-8", s.headers, data)
+	case s.file == influxStdout:
+		_, err := s.stdout.Write(data)
+		return err
+	default:
+		f, err := os.OpenFile(filepath.Clean(s.file), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+
+		_, err = f.Write(data)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		return f.Close()
+	}
+}
+
+// formatInflux returns metrics in InfluxDB line protocol. Each view is a measurement, metrics of the same row of the
+// view are written as fields of a single point. Points are tagged by instance and by values of label columns.
+func formatInflux(prefix string, b Batch) string {
+	var buf strings.Builder
+	ts := strconv.FormatInt(b.Time.UnixNano(), 10)
+
+	for i := 0; i < len(b.Metrics); {
+		// Metrics of the same row are adjacent.
+		j := i + 1
+		for j < len(b.Metrics) && sameRow(b.Metrics[i], b.Metrics[j]) {
+			j++
+		}
+
+		m := b.Metrics[i]
+		buf.WriteString(measurementEscaper.Replace(prefix + "_" + m.View))
+		buf.WriteString(",instance=" + keyEscaper.Replace(b.Instance))
+		for _, l := range m.Labels {
+			// Empty tags values are not allowed.
+			if l.Value == "" {
+				continue
+			}
+			buf.WriteString("," + keyEscaper.Replace(l.Name) + "=" + keyEscaper.Replace(l.Value))
+		}
+
+		for k, f := range b.Metrics[i:j] {
+			if k == 0 {
+				buf.WriteByte(' ')
+			} else {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(keyEscaper.Replace(f.Name) + "=" + formatValue(f.Value))
+		}
+
+		buf.WriteString(" " + ts + "\n")
+		i = j
+	}
+
+	return buf.String()
+}
+
+// sameRow returns true if metrics belong to the same row of the same view.
+func sameRow(a, b Metric) bool {
+	if a.View != b.View || len(a.Labels) != len(b.Labels) {
+		return false
+	}
+	for i := range a.Labels {
+		if a.Labels[i] != b.Labels[i] {
+			return false
+		}
+	}
+	return true
+}
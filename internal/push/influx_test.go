@@ -0,0 +1,77 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_formatInflux(t *testing.T) {
+	b := Batch{
+		Instance: "127.0.0.1:5432/postgres",
+		Time:     time.Unix(1634300000, 0),
+		Metrics: []Metric{
+			{View: "databases", Name: "commits", Labels: []Label{{"datname", "pgbench"}}, Value: 150.5},
+			{View: "databases", Name: "rollbacks", Labels: []Label{{"datname", "pgbench"}}, Value: 1},
+			{View: "databases", Name: "commits", Labels: []Label{{"datname", "my db"}}, Value: 2},
+			{View: "system_load", Name: "load1", Value: 0.5},
+			{View: "system_network", Name: "errors_per_second", Labels: []Label{{"interface", "eth0"}}, Value: 0},
+			{View: "replication", Name: "lag", Labels: []Label{{"application_name", ""}}, Value: -1},
+		},
+	}
+
+	assert.Equal(t,
+		"pgcenter_databases,instance=127.0.0.1:5432/postgres,datname=pgbench commits=150.5,rollbacks=1 1634300000000000000\n"+
+			`pgcenter_databases,instance=127.0.0.1:5432/postgres,datname=my\ db commits=2 1634300000000000000`+"\n"+
+			"pgcenter_system_load,instance=127.0.0.1:5432/postgres load1=0.5 1634300000000000000\n"+
+			"pgcenter_system_network,instance=127.0.0.1:5432/postgres,interface=eth0 errors_per_second=0 1634300000000000000\n"+
+			"pgcenter_replication,instance=127.0.0.1:5432/postgres lag=-1 1634300000000000000\n",
+		formatInflux("pgcenter", b),
+	)
+}
+
+func Test_influxSink_push(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	want := formatInflux("pg", testBatch())
+
+	// HTTP.
+	var got, token string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		got, token = string(data), r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	s := &influxSink{url: ts.URL + "/api/v2/write?org=pg&bucket=pgcenter", headers: map[string]string{"Authorization": "Token secret"}, prefix: "pg"}
+	assert.NoError(t, s.push(ctx, testBatch()))
+	assert.Equal(t, want, got)
+	assert.Equal(t, "Token secret", token)
+
+	// File, points are appended.
+	dir, err := ioutil.TempDir("", "pgcenter-push-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	s = &influxSink{file: filepath.Join(dir, "stats.lp"), prefix: "pg"}
+	assert.NoError(t, s.push(ctx, testBatch()))
+	assert.NoError(t, s.push(ctx, testBatch()))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "stats.lp"))
+	assert.NoError(t, err)
+	assert.Equal(t, want+want, string(data))
+
+	// Stdout.
+	var buf bytes.Buffer
+	s = &influxSink{file: influxStdout, prefix: "pg", stdout: &buf}
+	assert.NoError(t, s.push(ctx, testBatch()))
+	assert.Equal(t, want, buf.String())
+}
@@ -0,0 +1,161 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultOTLPURL defines default endpoint of OpenTelemetry collector receiving metrics over OTLP/HTTP.
+const defaultOTLPURL = "http://127.0.0.1:4318/v1/metrics"
+
+// otlpSink pushes metrics as gauges to OpenTelemetry collector using OTLP/HTTP protocol with JSON encoding.
+type otlpSink struct {
+	url        string
+	prefix     string
+	headers    map[string]string
+	attributes map[string]string // extra resource attributes
+}
+
+// push implements sink interface.
+func (s *otlpSink) push(ctx context.Context, b Batch) error {
+	hostname, _ := os.Hostname()
+
+	body, err := json.Marshal(newOTLPRequest(s.prefix, hostname, s.attributes, b))
+	if err != nil {
+		return err
+	}
+
+	return post(ctx, s.url, "application/json", s.headers, body)
+}
+
+// OTLP metrics request, only fields used for pushing gauges are defined.
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpMetric struct {
+		Name  string    `json:"name"`
+		Gauge otlpGauge `json:"gauge"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	}
+	otlpDataPoint struct {
+		Attributes   []otlpAttribute `json:"attributes,omitempty"`
+		TimeUnixNano string          `json:"timeUnixNano"`
+		AsDouble     float64         `json:"asDouble"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// newOTLPRequest makes OTLP request of metrics. Postgres instance and host where pgcenter runs are described by resource
+// attributes, rows of views are identified by data points attributes. Metrics are named as prefix.view.column.
+func newOTLPRequest(prefix, hostname string, attributes map[string]string, b Batch) otlpRequest {
+	resAttrs := map[string]string{
+		"service.name":        "pgcenter",
+		"service.instance.id": b.Instance,
+		"host.name":           hostname,
+		"db.system":           "postgresql",
+	}
+	for k, v := range attributes {
+		resAttrs[k] = v
+	}
+
+	ts := strconv.FormatInt(b.Time.UnixNano(), 10)
+
+	var metrics []otlpMetric
+	index := map[string]int{}
+	for _, m := range b.Metrics {
+		name := prefix + "." + m.View + "." + m.Name
+		i, ok := index[name]
+		if !ok {
+			i = len(metrics)
+			index[name] = i
+			metrics = append(metrics, otlpMetric{Name: name})
+		}
+
+		var attrs []otlpAttribute
+		for _, l := range m.Labels {
+			attrs = append(attrs, otlpAttribute{Key: l.Name, Value: otlpValue{StringValue: l.Value}})
+		}
+
+		metrics[i].Gauge.DataPoints = append(metrics[i].Gauge.DataPoints, otlpDataPoint{
+			Attributes: attrs, TimeUnixNano: ts, AsDouble: m.Value,
+		})
+	}
+
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource:     otlpResource{Attributes: otlpAttributes(resAttrs)},
+		ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: "pgcenter"}, Metrics: metrics}},
+	}}}
+}
+
+// otlpAttributes converts map into attributes sorted by keys. Attributes with empty values are skipped.
+func otlpAttributes(m map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(m))
+	for k, v := range m {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	attrs := make([]otlpAttribute, len(keys))
+	for i, k := range keys {
+		attrs[i] = otlpAttribute{Key: k, Value: otlpValue{StringValue: m[k]}}
+	}
+	return attrs
+}
+
+// post sends body in POST request and checks response status.
+func post(ctx context.Context, url, contentType string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s responded %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_newOTLPRequest(t *testing.T) {
+	b := testBatch()
+	b.Metrics = append(b.Metrics, Metric{View: "databases", Name: "commits", Labels: []Label{{"datname", "postgres"}}, Value: 2})
+
+	got := newOTLPRequest("pgcenter", "db1", map[string]string{"deployment.environment": "production"}, b)
+	assert.Len(t, got.ResourceMetrics, 1)
+
+	rm := got.ResourceMetrics[0]
+	assert.Equal(t, []otlpAttribute{
+		{Key: "db.system", Value: otlpValue{StringValue: "postgresql"}},
+		{Key: "deployment.environment", Value: otlpValue{StringValue: "production"}},
+		{Key: "host.name", Value: otlpValue{StringValue: "db1"}},
+		{Key: "service.instance.id", Value: otlpValue{StringValue: "127.0.0.1:5432/postgres"}},
+		{Key: "service.name", Value: otlpValue{StringValue: "pgcenter"}},
+	}, rm.Resource.Attributes)
+
+	metrics := rm.ScopeMetrics[0].Metrics
+	assert.Len(t, metrics, 3)
+	assert.Equal(t, "pgcenter.databases.commits", metrics[0].Name)
+	assert.Equal(t, []otlpDataPoint{
+		{Attributes: []otlpAttribute{{Key: "datname", Value: otlpValue{StringValue: "pgbench"}}}, TimeUnixNano: "1634300000000000000", AsDouble: 150.5},
+		{Attributes: []otlpAttribute{{Key: "datname", Value: otlpValue{StringValue: "postgres"}}}, TimeUnixNano: "1634300000000000000", AsDouble: 2},
+	}, metrics[0].Gauge.DataPoints)
+	assert.Equal(t, "pgcenter.tables.n_live_tup", metrics[1].Name)
+	assert.Equal(t, "pgcenter.replication.lag", metrics[2].Name)
+}
+
+func Test_otlpSink_push(t *testing.T) {
+	var got otlpRequest
+	var token string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("Authorization")
+		data, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &got); err != nil || r.URL.Path != "/v1/metrics" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	s := &otlpSink{url: ts.URL + "/v1/metrics", prefix: "pg", headers: map[string]string{"Authorization": "Bearer secret"}}
+	assert.NoError(t, s.push(ctx, testBatch()))
+	assert.Equal(t, "Bearer secret", token)
+	assert.Len(t, got.ResourceMetrics[0].ScopeMetrics[0].Metrics, 3)
+
+	s.url = ts.URL + "/invalid"
+	assert.Error(t, s.push(ctx, testBatch()))
+}
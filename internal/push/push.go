@@ -0,0 +1,438 @@
+// Package push implements pushing of stats rates to external time-series storages. Stats of selected views are
+// collected periodically using dedicated connection to Postgres, rates are calculated and pushed to configured sinks.
+package push
+
+import (
+	"context"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultInterval defines default interval of pushing stats.
+	defaultInterval = 10 * time.Second
+	// defaultPrefix defines default prefix of metrics names.
+	defaultPrefix = "pgcenter"
+	// pushTimeout defines maximum duration of pushing stats to a sink.
+	pushTimeout = 10 * time.Second
+)
+
+// Config defines configuration of pushing stats: views which stats are pushed and sinks where stats are pushed to.
+type Config struct {
+	Interval time.Duration `yaml:"interval"` // interval of collecting and pushing stats
+	Prefix   string        `yaml:"prefix"`   // prefix of metrics names
+	Views    []View        `yaml:"views"`    // views which stats are pushed
+	Sinks    []Sink        `yaml:"sinks"`    // destinations of stats
+}
+
+// View defines stats view which stats are pushed.
+type View struct {
+	Name    string   `yaml:"name"`    // name of stats view, or 'system'
+	Columns []string `yaml:"columns"` // columns pushed as metrics, rate columns of the view by default
+	Labels  []string `yaml:"labels"`  // columns identifying rows, the view's unique key column by default
+}
+
+// Sink defines destination of stats.
+type Sink struct {
+	Type       string            `yaml:"type"`       // type of sink: statsd, graphite, otlp, influx
+	Address    string            `yaml:"address"`    // host:port of StatsD or Graphite
+	URL        string            `yaml:"url"`        // URL of OTLP/HTTP metrics endpoint or InfluxDB write API
+	File       string            `yaml:"file"`       // file where InfluxDB line protocol is written, '-' means stdout
+	Headers    map[string]string `yaml:"headers"`    // extra HTTP headers, e.g. for authentication
+	Attributes map[string]string `yaml:"attributes"` // extra OpenTelemetry resource attributes
+}
+
+// target returns address or URL where sink's stats are pushed to.
+func (s Sink) target() string {
+	switch {
+	case s.URL != "":
+		return s.URL
+	case s.File != "":
+		return s.File
+	default:
+		return s.Address
+	}
+}
+
+// Enabled returns true if pushing of stats is configured.
+func (c Config) Enabled() bool {
+	return len(c.Sinks) > 0
+}
+
+// Stdout returns true if any sink writes stats into stdout.
+func (c Config) Stdout() bool {
+	for _, s := range c.Sinks {
+		if s.Type == "influx" && s.File == influxStdout {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks configuration and sets defaults.
+func (c *Config) validate() error {
+	if c.Interval == 0 {
+		c.Interval = defaultInterval
+	}
+	if c.Interval < time.Second {
+		return fmt.Errorf("push interval must be at least 1s")
+	}
+	if c.Prefix == "" {
+		c.Prefix = defaultPrefix
+	}
+
+	if len(c.Views) == 0 {
+		return fmt.Errorf("views are not specified")
+	}
+	for i, v := range c.Views {
+		if v.Name == "" {
+			return fmt.Errorf("view %d: name is not specified", i+1)
+		}
+	}
+
+	for i := range c.Sinks {
+		s := &c.Sinks[i]
+		switch s.Type {
+		case "statsd", "graphite":
+			if s.Address == "" {
+				return fmt.Errorf("sink %d: address is not specified", i+1)
+			}
+		case "otlp":
+			if s.URL == "" {
+				s.URL = defaultOTLPURL
+			}
+		case "influx":
+			if (s.URL == "") == (s.File == "") {
+				return fmt.Errorf("sink %d: either url or file must be specified", i+1)
+			}
+		default:
+			return fmt.Errorf("sink %d: unknown type '%s', supported: statsd, graphite, otlp, influx", i+1, s.Type)
+		}
+	}
+
+	return nil
+}
+
+// Label defines name and value of metric's label.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Metric defines value of view's column in a particular row.
+type Metric struct {
+	View   string  // name of view
+	Name   string  // name of column
+	Labels []Label // values of columns identifying the row
+	Value  float64
+}
+
+// Batch defines metrics collected at once.
+type Batch struct {
+	Instance string // name of Postgres instance
+	Time     time.Time
+	Metrics  []Metric
+}
+
+// sink defines destination where metrics are pushed to.
+type sink interface {
+	push(ctx context.Context, b Batch) error
+}
+
+// newSink creates sink.
+func newSink(s Sink, prefix string) sink {
+	switch s.Type {
+	case "graphite":
+		return &graphiteSink{address: s.Address, prefix: prefix}
+	case "otlp":
+		return &otlpSink{url: s.URL, prefix: prefix, headers: s.Headers, attributes: s.Attributes}
+	case "influx":
+		return &influxSink{url: s.URL, headers: s.Headers, file: s.File, prefix: prefix, stdout: os.Stdout}
+	default:
+		return &statsdSink{address: s.Address, prefix: prefix}
+	}
+}
+
+// Pusher periodically collects stats of configured views and pushes rates to sinks.
+type Pusher struct {
+	config Config
+	sinks  []sink
+	logf   func(format string, a ...interface{}) // logs errors of collecting and pushing stats
+
+	filter    stat.Filter // filter of devices which stats are skipped
+	collector *stat.Collector
+	props     stat.PostgresProperties
+	views     view.Views
+	prev      map[string]stat.PGresult // previous snapshots of views, used for calculating rates
+}
+
+// NewPusher validates configuration and creates pusher.
+func NewPusher(config Config, logf func(format string, a ...interface{})) (*Pusher, error) {
+	// Sinks are validated in place, use own copy of them because config could be shared by several pushers.
+	config.Sinks = append([]Sink(nil), config.Sinks...)
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid push configuration: %s", err)
+	}
+
+	p := &Pusher{config: config, logf: logf, filter: stat.DefaultFilter(), prev: map[string]stat.PGresult{}}
+	for _, s := range config.Sinks {
+		p.sinks = append(p.sinks, newSink(s, config.Prefix))
+	}
+
+	return p, nil
+}
+
+// SetFilter remembers devices filter, it is applied to collector created at every (re)connect.
+func (p *Pusher) SetFilter(f stat.Filter) {
+	p.filter = f
+}
+
+// Run collects and pushes stats with configured interval until context is done. Connection to Postgres is established
+// using specified config and reestablished when it is lost.
+func (p *Pusher) Run(ctx context.Context, dbConfig postgres.Config) {
+	instance := hook.InstanceName(dbConfig)
+
+	t := time.NewTicker(p.config.Interval)
+	defer t.Stop()
+
+	var db *postgres.DB
+	defer func() {
+		if db != nil {
+			db.Close()
+		}
+	}()
+
+	for {
+		var err error
+		if db == nil {
+			db, err = p.connect(dbConfig)
+		} else {
+			err = p.update(ctx, db, instance, time.Now())
+		}
+		if err != nil {
+			p.logf("push: %s", err)
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// connect connects to Postgres and configures views which stats are pushed.
+func (p *Pusher) connect(dbConfig postgres.Config) (*postgres.DB, error) {
+	db, err := postgres.Connect(dbConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	p.collector, err = stat.NewCollector(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	p.collector.SetFilter(p.filter)
+
+	p.props = p.collector.Properties()
+	views := view.New()
+	err = views.Configure(p.props.QueryOptions(0))
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	for _, v := range p.config.Views {
+		if _, ok := views[v.Name]; !ok && v.Name != viewSystem {
+			db.Close()
+			return nil, fmt.Errorf("unknown view '%s'", v.Name)
+		}
+	}
+
+	p.views = views
+
+	// Collect stats, hence rates are available at the next update.
+	_, err = p.collect(context.Background(), db)
+	return db, err
+}
+
+// update collects stats and pushes them to all sinks. Lost connection is reestablished.
+func (p *Pusher) update(ctx context.Context, db *postgres.DB, instance string, now time.Time) error {
+	if err := db.PQstatus(); err != nil {
+		err = postgres.Reconnect(ctx, db)
+		if err != nil {
+			return fmt.Errorf("connection lost, reconnect failed: %s", err)
+		}
+
+		restarted, err := p.collector.Reconnected(db)
+		if err != nil {
+			return err
+		}
+
+		// Counters have been reset, rates can't be calculated using previous snapshots.
+		if restarted {
+			p.prev = map[string]stat.PGresult{}
+		}
+		p.props = p.collector.Properties()
+	}
+
+	metrics, err := p.collect(ctx, db)
+
+	// Push metrics which have been collected successfully, even if collecting of other views failed.
+	if len(metrics) > 0 {
+		b := Batch{Instance: instance, Time: now, Metrics: metrics}
+		for i, s := range p.sinks {
+			pctx, cancel := context.WithTimeout(ctx, pushTimeout)
+			if perr := s.push(pctx, b); perr != nil {
+				p.logf("push: send stats to %s %s failed: %s", p.config.Sinks[i].Type, p.config.Sinks[i].target(), perr)
+			}
+			cancel()
+		}
+	}
+
+	return err
+}
+
+// collect collects stats of configured views and returns metrics. Views with rates are skipped until the previous
+// snapshot of the view is available. Errors of particular views are combined into single error.
+func (p *Pusher) collect(ctx context.Context, db *postgres.DB) ([]Metric, error) {
+	var (
+		errs    []string
+		metrics []Metric
+	)
+
+	for _, cfg := range p.config.Views {
+		if cfg.Name == viewSystem {
+			if !db.Local && !p.props.SchemaPgcenterAvail {
+				errs = append(errs, "system stats are not available, Postgres is remote and pgcenter stats schema is not installed")
+				continue
+			}
+			s, err := p.collector.UpdateSystem(ctx, db)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("system stats: %s", err))
+				continue
+			}
+			metrics = append(metrics, systemMetrics(s)...)
+			continue
+		}
+
+		v := p.views[cfg.Name]
+
+		res, err := stat.NewPGresultContext(ctx, db, v.Query, v.Args...)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("view '%s': %s", cfg.Name, err))
+			continue
+		}
+
+		prev := p.prev[v.Name]
+		p.prev[v.Name] = res
+
+		if !prev.Valid && v.DiffIntvl != [2]int{0, 0} {
+			continue
+		}
+
+		delta, err := stat.Compare(res, prev, int(p.config.Interval/time.Second), v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("view '%s': %s", cfg.Name, err))
+			continue
+		}
+
+		m, err := resultMetrics(delta, v, cfg)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("view '%s': %s", cfg.Name, err))
+			continue
+		}
+		metrics = append(metrics, m...)
+	}
+
+	if len(errs) > 0 {
+		return metrics, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	return metrics, nil
+}
+
+// resultMetrics makes metrics of view's result. By default rate columns of the view are used as metrics (or all
+// columns if the view has no rates), and rows are labeled by the view's unique key column. NULL and non-numeric values
+// are skipped.
+func resultMetrics(res stat.PGresult, v view.View, cfg View) ([]Metric, error) {
+	colIdx := map[string]int{}
+	for i, col := range res.Cols {
+		colIdx[col] = i
+	}
+
+	labelCols := cfg.Labels
+	if labelCols == nil && len(res.Cols) > 0 {
+		labelCols = []string{res.Cols[v.UniqueKey]}
+	}
+
+	labelIdx := make([]int, len(labelCols))
+	for i, col := range labelCols {
+		j, ok := colIdx[col]
+		if !ok {
+			return nil, fmt.Errorf("label column '%s' not found", col)
+		}
+		labelIdx[i] = j
+	}
+
+	var valueIdx []int
+	switch {
+	case cfg.Columns != nil:
+		for _, col := range cfg.Columns {
+			j, ok := colIdx[col]
+			if !ok {
+				return nil, fmt.Errorf("column '%s' not found", col)
+			}
+			valueIdx = append(valueIdx, j)
+		}
+	case v.DiffIntvl != [2]int{0, 0}:
+		for j := v.DiffIntvl[0]; j <= v.DiffIntvl[1] && j < len(res.Cols); j++ {
+			valueIdx = append(valueIdx, j)
+		}
+	default:
+		for j := range res.Cols {
+			if !intInSlice(j, labelIdx) {
+				valueIdx = append(valueIdx, j)
+			}
+		}
+	}
+
+	var metrics []Metric
+	for _, row := range res.Values {
+		labels := make([]Label, len(labelIdx))
+		for i, j := range labelIdx {
+			labels[i] = Label{Name: labelCols[i], Value: row[j].String}
+		}
+
+		for _, j := range valueIdx {
+			if !row[j].Valid {
+				continue
+			}
+			value, err := strconv.ParseFloat(row[j].String, 64)
+			if err != nil {
+				continue
+			}
+			metrics = append(metrics, Metric{View: v.Name, Name: res.Cols[j], Labels: labels, Value: value})
+		}
+	}
+
+	return metrics, nil
+}
+
+// intInSlice returns true if slice contains the value.
+func intInSlice(v int, list []int) bool {
+	for _, i := range list {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
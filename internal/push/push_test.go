@@ -0,0 +1,98 @@
+package push
+
+import (
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewPusher(t *testing.T) {
+	p, err := NewPusher(Config{
+		Views: []View{{Name: "databases"}},
+		Sinks: []Sink{{Type: "statsd", Address: "127.0.0.1:8125"}, {Type: "graphite", Address: "127.0.0.1:2003"}, {Type: "otlp"}, {Type: "influx", File: "-"}},
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultInterval, p.config.Interval)
+	assert.Equal(t, defaultPrefix, p.config.Prefix)
+	assert.IsType(t, &statsdSink{}, p.sinks[0])
+	assert.IsType(t, &graphiteSink{}, p.sinks[1])
+	assert.Equal(t, &otlpSink{url: defaultOTLPURL, prefix: defaultPrefix}, p.sinks[2])
+	assert.IsType(t, &influxSink{}, p.sinks[3])
+	assert.True(t, p.config.Stdout())
+
+	for _, c := range []Config{
+		{Interval: time.Millisecond, Views: []View{{Name: "databases"}}, Sinks: []Sink{{Type: "statsd", Address: "127.0.0.1:8125"}}},
+		{Sinks: []Sink{{Type: "statsd", Address: "127.0.0.1:8125"}}},
+		{Views: []View{{}}, Sinks: []Sink{{Type: "statsd", Address: "127.0.0.1:8125"}}},
+		{Views: []View{{Name: "databases"}}, Sinks: []Sink{{Type: "statsd"}}},
+		{Views: []View{{Name: "databases"}}, Sinks: []Sink{{Type: "unknown", Address: "127.0.0.1:8125"}}},
+		{Views: []View{{Name: "databases"}}, Sinks: []Sink{{Type: "influx"}}},
+		{Views: []View{{Name: "databases"}}, Sinks: []Sink{{Type: "influx", URL: "http://127.0.0.1:8086/api/v2/write", File: "-"}}},
+	} {
+		_, err := NewPusher(c, nil)
+		assert.Error(t, err)
+	}
+}
+
+func Test_resultMetrics(t *testing.T) {
+	res := stat.PGresult{
+		Valid: true, Ncols: 4, Nrows: 2,
+		Cols: []string{"datname", "commits", "rollbacks", "stats_age"},
+		Values: [][]sql.NullString{
+			{{String: "pgbench", Valid: true}, {String: "150", Valid: true}, {String: "1.5", Valid: true}, {String: "01:00:00", Valid: true}},
+			{{String: "postgres", Valid: true}, {String: "2", Valid: true}, {}, {String: "01:00:00", Valid: true}},
+		},
+	}
+	v := view.View{Name: "databases", DiffIntvl: [2]int{1, 2}}
+
+	// Rate columns labeled by unique key.
+	got, err := resultMetrics(res, v, View{Name: "databases"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Metric{
+		{View: "databases", Name: "commits", Labels: []Label{{"datname", "pgbench"}}, Value: 150},
+		{View: "databases", Name: "rollbacks", Labels: []Label{{"datname", "pgbench"}}, Value: 1.5},
+		{View: "databases", Name: "commits", Labels: []Label{{"datname", "postgres"}}, Value: 2},
+	}, got)
+
+	// Selected columns, non-numeric values are skipped.
+	got, err = resultMetrics(res, v, View{Name: "databases", Columns: []string{"rollbacks", "stats_age"}, Labels: []string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, []Metric{{View: "databases", Name: "rollbacks", Labels: []Label{}, Value: 1.5}}, got)
+
+	// All columns of views without rates.
+	got, err = resultMetrics(res, view.View{Name: "databases"}, View{Name: "databases"})
+	assert.NoError(t, err)
+	assert.Len(t, got, 3)
+
+	_, err = resultMetrics(res, v, View{Name: "databases", Columns: []string{"unknown"}})
+	assert.Error(t, err)
+	_, err = resultMetrics(res, v, View{Name: "databases", Labels: []string{"unknown"}})
+	assert.Error(t, err)
+}
+
+func Test_systemMetrics(t *testing.T) {
+	s := stat.System{
+		LoadAvg:   stat.LoadAvg{One: 1, Five: 0.5, Fifteen: 0.25},
+		Diskstats: stat.Diskstats{{Device: "sda", Util: 10}, {}},
+		Netdevs:   stat.Netdevs{{Ifname: "eth0", Rerrs: 1, Terrs: 2}, {}},
+	}
+
+	got := systemMetrics(s)
+	assert.Len(t, got, 3+8+11+7+6)
+	assert.Equal(t, Metric{View: "system_load", Name: "load1", Value: 1}, got[0])
+
+	var disk, net []Metric
+	for _, m := range got {
+		switch m.View {
+		case "system_disk":
+			disk = append(disk, m)
+		case "system_network":
+			net = append(net, m)
+		}
+	}
+	assert.Equal(t, Metric{View: "system_disk", Name: "utilization_percent", Labels: []Label{{"device", "sda"}}, Value: 10}, disk[6])
+	assert.Equal(t, Metric{View: "system_network", Name: "errors_per_second", Labels: []Label{{"interface", "eth0"}}, Value: 3}, net[4])
+}
@@ -0,0 +1,139 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// statsdPacketSize defines maximum size of UDP packet sent to StatsD, it fits into Ethernet MTU.
+const statsdPacketSize = 1432
+
+// pathRE defines characters which are not allowed in components of metrics paths.
+var pathRE = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// statsdSink pushes metrics as gauges to StatsD over UDP.
+type statsdSink struct {
+	address string
+	prefix  string
+}
+
+// push implements sink interface.
+func (s *statsdSink) push(ctx context.Context, b Batch) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", s.address)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	for _, p := range statsdPackets(formatStatsd(s.prefix, b), statsdPacketSize) {
+		_, err := conn.Write(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// formatStatsd returns metrics in StatsD format, one metric per line. StatsD treats signed gauges as increments of the
+// previous value, hence negative gauges are set by resetting them to zero first.
+func formatStatsd(prefix string, b Batch) []string {
+	lines := make([]string, 0, len(b.Metrics))
+	for _, m := range b.Metrics {
+		path := metricPath(prefix, b.Instance, m)
+		if m.Value < 0 {
+			lines = append(lines, path+":0|g")
+		}
+		lines = append(lines, path+":"+formatValue(m.Value)+"|g")
+	}
+	return lines
+}
+
+// statsdPackets joins lines into packets which are not larger than specified size. Lines larger than the size are
+// sent in separate packets.
+func statsdPackets(lines []string, size int) [][]byte {
+	var packets [][]byte
+	var buf bytes.Buffer
+
+	for _, l := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(l) > size {
+			packets = append(packets, append([]byte(nil), buf.Bytes()...))
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(l)
+	}
+
+	if buf.Len() > 0 {
+		packets = append(packets, buf.Bytes())
+	}
+
+	return packets
+}
+
+// graphiteSink pushes metrics to Graphite (Carbon) using plaintext protocol over TCP.
+type graphiteSink struct {
+	address string
+	prefix  string
+}
+
+// push implements sink interface.
+func (s *graphiteSink) push(ctx context.Context, b Batch) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = conn.Write([]byte(formatGraphite(s.prefix, b)))
+	return err
+}
+
+// formatGraphite returns metrics in Graphite plaintext format: path, value and timestamp, one metric per line.
+func formatGraphite(prefix string, b Batch) string {
+	var buf strings.Builder
+	ts := strconv.FormatInt(b.Time.Unix(), 10)
+	for _, m := range b.Metrics {
+		buf.WriteString(metricPath(prefix, b.Instance, m) + " " + formatValue(m.Value) + " " + ts + "\n")
+	}
+	return buf.String()
+}
+
+// metricPath returns dot-separated path of metric: prefix, instance, view, values of labels and column name.
+func metricPath(prefix, instance string, m Metric) string {
+	parts := make([]string, 0, len(m.Labels)+4)
+	parts = append(parts, prefix, pathComponent(instance), pathComponent(m.View))
+	for _, l := range m.Labels {
+		parts = append(parts, pathComponent(l.Value))
+	}
+	parts = append(parts, pathComponent(m.Name))
+	return strings.Join(parts, ".")
+}
+
+// pathComponent replaces characters not allowed in metrics paths with underscores.
+func pathComponent(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return pathRE.ReplaceAllString(s, "_")
+}
+
+// formatValue formats value of metric.
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,89 @@
+package push
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBatch() Batch {
+	return Batch{
+		Instance: "127.0.0.1:5432/postgres",
+		Time:     time.Unix(1634300000, 0),
+		Metrics: []Metric{
+			{View: "databases", Name: "commits", Labels: []Label{{"datname", "pgbench"}}, Value: 150.5},
+			{View: "tables", Name: "n_live_tup", Labels: []Label{{"relname", "public.accounts"}}, Value: -10},
+			{View: "replication", Name: "lag", Labels: []Label{{"application_name", ""}}, Value: 0},
+		},
+	}
+}
+
+func Test_formatStatsd(t *testing.T) {
+	assert.Equal(t, []string{
+		"pgcenter.127_0_0_1_5432_postgres.databases.pgbench.commits:150.5|g",
+		"pgcenter.127_0_0_1_5432_postgres.tables.public_accounts.n_live_tup:0|g",
+		"pgcenter.127_0_0_1_5432_postgres.tables.public_accounts.n_live_tup:-10|g",
+		"pgcenter.127_0_0_1_5432_postgres.replication._.lag:0|g",
+	}, formatStatsd("pgcenter", testBatch()))
+}
+
+func Test_statsdPackets(t *testing.T) {
+	assert.Nil(t, statsdPackets(nil, 10))
+	assert.Equal(t, [][]byte{[]byte("aaa\nbbb"), []byte("cccccccccccc"), []byte("d")},
+		statsdPackets([]string{"aaa", "bbb", "cccccccccccc", "d"}, 10))
+}
+
+func Test_formatGraphite(t *testing.T) {
+	assert.Equal(t, "pgcenter.127_0_0_1_5432_postgres.databases.pgbench.commits 150.5 1634300000\n"+
+		"pgcenter.127_0_0_1_5432_postgres.tables.public_accounts.n_live_tup -10 1634300000\n"+
+		"pgcenter.127_0_0_1_5432_postgres.replication._.lag 0 1634300000\n",
+		formatGraphite("pgcenter", testBatch()))
+}
+
+func Test_statsdSink_push(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	s := &statsdSink{address: conn.LocalAddr().String(), prefix: "pg"}
+	assert.NoError(t, s.push(context.Background(), testBatch()))
+
+	buf := make([]byte, statsdPacketSize)
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join(formatStatsd("pg", testBatch()), "\n"), string(buf[:n]))
+}
+
+func Test_graphiteSink_push(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	received := make(chan string, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		data, _ := ioutil.ReadAll(c)
+		_ = c.Close()
+		received <- string(data)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	s := &graphiteSink{address: ln.Addr().String(), prefix: "pg"}
+	assert.NoError(t, s.push(ctx, testBatch()))
+	assert.Equal(t, formatGraphite("pg", testBatch()), <-received)
+
+	// Unreachable sink.
+	s = &graphiteSink{address: "127.0.0.1:1", prefix: "pg"}
+	assert.Error(t, s.push(ctx, testBatch()))
+}
@@ -0,0 +1,41 @@
+package push
+
+import (
+	"github.com/lesovsky/pgcenter/internal/stat"
+)
+
+// viewSystem defines pseudo-view of system stats: load average, CPU, memory, disks and network usage.
+const viewSystem = "system"
+
+// systemMetrics returns metrics of system stats. Metrics are grouped into views by kind of stats, disks and network
+// interfaces are labeled by device and interface names. Names are the same as used by exporter.
+func systemMetrics(s stat.System) []Metric {
+	var metrics []Metric
+	add := func(view string, labels []Label, values []stat.SystemValue) {
+		for _, v := range values {
+			metrics = append(metrics, Metric{View: view, Name: v.Name, Labels: labels, Value: v.Value})
+		}
+	}
+
+	add(stat.SystemLoad, nil, stat.LoadValues(s.LoadAvg))
+	add(stat.SystemCPU, nil, stat.CPUValues(s.CpuStat))
+	add(stat.SystemMemory, nil, stat.MemoryValues(s.Meminfo))
+
+	for _, d := range s.Diskstats {
+		// Inactive devices are skipped by stats collector.
+		if d.Device == "" {
+			continue
+		}
+		add(stat.SystemDisk, []Label{{stat.SystemDiskLabel, d.Device}}, stat.DiskValues(d))
+	}
+
+	for _, n := range s.Netdevs {
+		// Inactive interfaces are skipped by stats collector.
+		if n.Ifname == "" {
+			continue
+		}
+		add(stat.SystemNetwork, []Label{{stat.SystemNetworkLabel, n.Ifname}}, stat.NetworkValues(n))
+	}
+
+	return metrics
+}
@@ -1,6 +1,11 @@
 package query
 
 const (
+	// activityFilter limits activity to specified databases and users, it is pushed down into queries instead of
+	// filtering rows on the client side. Lists of databases and users are passed as query parameters.
+	activityFilter = "{{ if .Databases }} AND datname = ANY({{ param .Databases }}::text[]){{ end }}" +
+		"{{ if .Users }} AND usename = ANY({{ param .Users }}::text[]){{ end }}"
+
 	// PgStatActivityDefault is the default query for getting stats from pg_stat_activity view
 	// { Name: "pg_stat_activity", Query: common.PgStatActivityQueryDefault, DiffIntvl: [2]int{99,99}, Ncols: 14, OrderKey: 0, OrderDesc: true }
 	// regexp_replace() removes extra spaces, tabs and newlines from queries
@@ -14,7 +19,7 @@ const (
 		"FROM pg_stat_activity " +
 		"WHERE ((clock_timestamp() - xact_start) > '{{.QueryAgeThresh}}'::interval " +
 		"OR (clock_timestamp() - query_start) > '{{.QueryAgeThresh}}'::interval) " +
-		"{{ if .ShowNoIdle }} AND state != 'idle' {{ end }} ORDER BY pid DESC"
+		"{{ if .ShowNoIdle }} AND state != 'idle' {{ end }}" + activityFilter + " ORDER BY pid DESC"
 
 	// PgStatActivity96 queries for getting stats from pg_stat_activity view for versions 9.6.*
 	// { Name: "pg_stat_activity", Query: common.PgStatActivityQuery96, DiffIntvl: [2]int{99,99}, Ncols: 13, OrderKey: 0, OrderDesc: true }
@@ -28,7 +33,7 @@ const (
 		"FROM pg_stat_activity " +
 		"WHERE ((clock_timestamp() - xact_start) > '{{.QueryAgeThresh}}'::interval " +
 		"OR (clock_timestamp() - query_start) > '{{.QueryAgeThresh}}'::interval) " +
-		"{{ if .ShowNoIdle }} AND state != 'idle' {{ end }} ORDER BY pid DESC"
+		"{{ if .ShowNoIdle }} AND state != 'idle' {{ end }}" + activityFilter + " ORDER BY pid DESC"
 
 	// PgStatActivity95 queries activity stats from pg_stat_activity view from versions for 9.5.* and later
 	// { Name: "pg_stat_activity", Query: common.PgStatActivityQuery95, DiffIntvl: [2]int{99,99}, Ncols: 12, OrderKey: 0, OrderDesc: true }
@@ -42,16 +47,5 @@ const (
 		"FROM pg_stat_activity " +
 		"WHERE ((clock_timestamp() - xact_start) > '{{.QueryAgeThresh}}'::interval " +
 		"OR (clock_timestamp() - query_start) > '{{.QueryAgeThresh}}'::interval) " +
-		"{{ if .ShowNoIdle }} AND state != 'idle' {{ end }} ORDER BY pid DESC"
+		"{{ if .ShowNoIdle }} AND state != 'idle' {{ end }}" + activityFilter + " ORDER BY pid DESC"
 )
-
-func SelectStatActivityQuery(version int) (string, int) {
-	switch {
-	case version < 90600:
-		return PgStatActivity95, 12
-	case version < 100000:
-		return PgStatActivity96, 13
-	default:
-		return PgStatActivityDefault, 14
-	}
-}
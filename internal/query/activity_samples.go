@@ -0,0 +1,30 @@
+package query
+
+const (
+	// PgStatActivitySamplesDefault queries active client sessions sampled for building active sessions history (ASH).
+	// Sessions which are not waiting for anything are considered working on CPU.
+	// regexp_replace() removes extra spaces, tabs and newlines from queries
+	PgStatActivitySamplesDefault = "SELECT coalesce(usename, '') AS usename, coalesce(datname, '') AS datname, " +
+		"coalesce(wait_event_type || ': ' || wait_event, 'CPU') AS wait, " +
+		`regexp_replace(regexp_replace(left(query, 2048),E'( |\t)+', ' ', 'g'),E'\n', ' ', 'g') AS query ` +
+		"FROM pg_stat_activity " +
+		"WHERE state = 'active' AND backend_type = 'client backend' AND pid != pg_backend_pid()"
+
+	// PgStatActivitySamplesPG96 queries active client sessions sampled for building active sessions history (ASH), for
+	// versions 9.6.*, where backend_type is not available.
+	// regexp_replace() removes extra spaces, tabs and newlines from queries
+	PgStatActivitySamplesPG96 = "SELECT coalesce(usename, '') AS usename, coalesce(datname, '') AS datname, " +
+		"coalesce(wait_event_type || ': ' || wait_event, 'CPU') AS wait, " +
+		`regexp_replace(regexp_replace(left(query, 2048),E'( |\t)+', ' ', 'g'),E'\n', ' ', 'g') AS query ` +
+		"FROM pg_stat_activity " +
+		"WHERE state = 'active' AND pid != pg_backend_pid()"
+
+	// PgStatActivitySamplesPG95 queries active client sessions sampled for building active sessions history (ASH), for
+	// versions 9.5.*, where only flag of waiting for a lock is available instead of wait events.
+	// regexp_replace() removes extra spaces, tabs and newlines from queries
+	PgStatActivitySamplesPG95 = "SELECT coalesce(usename, '') AS usename, coalesce(datname, '') AS datname, " +
+		"CASE WHEN waiting THEN 'Lock' ELSE 'CPU' END AS wait, " +
+		`regexp_replace(regexp_replace(left(query, 2048),E'( |\t)+', ' ', 'g'),E'\n', ' ', 'g') AS query ` +
+		"FROM pg_stat_activity " +
+		"WHERE state = 'active' AND pid != pg_backend_pid()"
+)
@@ -0,0 +1,47 @@
+package query
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSelect_activitySamples(t *testing.T) {
+	testcases := []struct {
+		version int
+		want    string
+	}{
+		{version: 90500, want: PgStatActivitySamplesPG95},
+		{version: 90600, want: PgStatActivitySamplesPG96},
+		{version: 100000, want: PgStatActivitySamplesDefault},
+		{version: 170000, want: PgStatActivitySamplesDefault},
+	}
+
+	for _, tc := range testcases {
+		got, ok := Select("activity_samples", Options{Version: tc.version})
+		assert.True(t, ok)
+		assert.Equal(t, tc.want, got.Query)
+	}
+}
+
+func Test_ActivitySamplesQueries(t *testing.T) {
+	versions := []int{90500, 90600, 100000, 110000, 120000, 130000}
+
+	for _, version := range versions {
+		t.Run(fmt.Sprintf("activity_samples/%d", version), func(t *testing.T) {
+			opts := NewOptions(version, "f", "off", 256)
+			v, _ := Select("activity_samples", opts)
+			q, _, err := Format(v.Query, opts)
+			assert.NoError(t, err)
+
+			conn, err := postgres.NewTestConnectVersion(version)
+			assert.NoError(t, err)
+
+			_, err = conn.Exec(q)
+			assert.NoError(t, err)
+
+			conn.Close()
+		})
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"testing"
 )
 
-func TestSelectStatActivityQuery(t *testing.T) {
+func TestSelect_activity(t *testing.T) {
 	testcases := []struct {
 		version int
 		wantQ   string
@@ -19,9 +19,10 @@ func TestSelectStatActivityQuery(t *testing.T) {
 	}
 
 	for _, tc := range testcases {
-		gotQ, gotN := SelectStatActivityQuery(tc.version)
-		assert.Equal(t, tc.wantQ, gotQ)
-		assert.Equal(t, tc.wantN, gotN)
+		got, ok := Select("activity", Options{Version: tc.version})
+		assert.True(t, ok)
+		assert.Equal(t, tc.wantQ, got.Query)
+		assert.Equal(t, tc.wantN, got.Ncols)
 	}
 }
 
@@ -30,10 +31,9 @@ func Test_StatActivityQueries(t *testing.T) {
 
 	for _, version := range versions {
 		t.Run(fmt.Sprintf("pg_stat_activity/%d", version), func(t *testing.T) {
-			tmpl, _ := SelectStatActivityQuery(version)
-
 			opts := NewOptions(version, "f", "off", 256)
-			q, err := Format(tmpl, opts)
+			v, _ := Select("activity", opts)
+			q, _, err := Format(v.Query, opts)
 			assert.NoError(t, err)
 
 			conn, err := postgres.NewTestConnectVersion(version)
@@ -0,0 +1,29 @@
+package query
+
+const (
+	// PgStatCheckpointsDefault is the default query for getting checkpoints stats from pg_stat_checkpointer view
+	// { Name: "pg_stat_checkpointer", Query: common.PgStatCheckpointsDefault, DiffIntvl: [2]int{0,0}, Ncols: 9, OrderKey: 0, OrderDesc: true }
+	// Time of the last checkpoint is taken from control file, 'timeout_used_%' shows how much of checkpoint_timeout is
+	// elapsed since the last checkpoint.
+	PgStatCheckpointsDefault = "SELECT current_setting('max_wal_size') AS max_wal_size, " +
+		"current_setting('checkpoint_timeout') AS timeout, c.num_timed AS timed, c.num_requested AS requested, " +
+		`round(100.0 * c.num_requested / greatest(c.num_timed + c.num_requested, 1), 2)::text AS "req_%", ` +
+		"date_trunc('milliseconds', c.write_time / greatest(c.num_timed + c.num_requested, 1) * '1 millisecond'::interval)::text AS avg_write, " +
+		"date_trunc('milliseconds', c.sync_time / greatest(c.num_timed + c.num_requested, 1) * '1 millisecond'::interval)::text AS avg_sync, " +
+		"date_trunc('seconds', now() - k.checkpoint_time)::text AS last_age, " +
+		"round((100 * extract(epoch FROM now() - k.checkpoint_time) / " +
+		`extract(epoch FROM current_setting('checkpoint_timeout')::interval))::numeric, 2)::text AS "timeout_used_%" ` +
+		"FROM pg_stat_checkpointer c, pg_control_checkpoint() k"
+
+	// PgStatCheckpointsPG16 is the query for getting checkpoints stats from pg_stat_bgwriter view for Postgres 9.6-16.
+	// { Name: "pg_stat_bgwriter", Query: common.PgStatCheckpointsPG16, DiffIntvl: [2]int{0,0}, Ncols: 9, OrderKey: 0, OrderDesc: true }
+	PgStatCheckpointsPG16 = "SELECT current_setting('max_wal_size') AS max_wal_size, " +
+		"current_setting('checkpoint_timeout') AS timeout, c.checkpoints_timed AS timed, c.checkpoints_req AS requested, " +
+		`round(100.0 * c.checkpoints_req / greatest(c.checkpoints_timed + c.checkpoints_req, 1), 2)::text AS "req_%", ` +
+		"date_trunc('milliseconds', c.checkpoint_write_time / greatest(c.checkpoints_timed + c.checkpoints_req, 1) * '1 millisecond'::interval)::text AS avg_write, " +
+		"date_trunc('milliseconds', c.checkpoint_sync_time / greatest(c.checkpoints_timed + c.checkpoints_req, 1) * '1 millisecond'::interval)::text AS avg_sync, " +
+		"date_trunc('seconds', now() - k.checkpoint_time)::text AS last_age, " +
+		"round((100 * extract(epoch FROM now() - k.checkpoint_time) / " +
+		`extract(epoch FROM current_setting('checkpoint_timeout')::interval))::numeric, 2)::text AS "timeout_used_%" ` +
+		"FROM pg_stat_bgwriter c, pg_control_checkpoint() k"
+)
@@ -0,0 +1,49 @@
+package query
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSelect_checkpoints(t *testing.T) {
+	testcases := []struct {
+		version int
+		want    string
+		ok      bool
+	}{
+		{version: 90500, ok: false},
+		{version: 90600, want: PgStatCheckpointsPG16, ok: true},
+		{version: 160000, want: PgStatCheckpointsPG16, ok: true},
+		{version: 170000, want: PgStatCheckpointsDefault, ok: true},
+	}
+
+	for _, tc := range testcases {
+		got, ok := Select("checkpoints", Options{Version: tc.version})
+		assert.Equal(t, tc.ok, ok)
+		assert.Equal(t, tc.want, got.Query)
+	}
+}
+
+func Test_StatCheckpointsQueries(t *testing.T) {
+	versions := []int{90600, 100000, 110000, 120000, 130000}
+
+	for _, version := range versions {
+		t.Run(fmt.Sprintf("pg_stat_bgwriter/%d", version), func(t *testing.T) {
+			tmpl := PgStatCheckpointsPG16
+
+			opts := NewOptions(version, "f", "off", 256)
+			q, _, err := Format(tmpl, opts)
+			assert.NoError(t, err)
+
+			conn, err := postgres.NewTestConnectVersion(version)
+			assert.NoError(t, err)
+
+			_, err = conn.Exec(q)
+			assert.NoError(t, err)
+
+			conn.Close()
+		})
+	}
+}
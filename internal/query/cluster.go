@@ -0,0 +1,34 @@
+package query
+
+const (
+	// ClusterOverviewDefault is the default query for getting overview of instance in cluster mode: version, recovery
+	// status, transactions counter, number of active backends and replicas, and replication lag in bytes. Lag is the
+	// replay lag of standby, or the maximal lag of replicas connected to primary.
+	ClusterOverviewDefault = "SELECT current_setting('server_version') AS version, pg_is_in_recovery() AS recovery, " +
+		"(SELECT coalesce(sum(xact_commit + xact_rollback), 0) FROM pg_stat_database)::bigint AS xacts, " +
+		"(SELECT count(*) FROM pg_stat_activity WHERE state = 'active' AND pid <> pg_backend_pid()) AS active, " +
+		"(SELECT count(*) FROM pg_stat_replication) AS replicas, " +
+		"(CASE WHEN pg_is_in_recovery() THEN coalesce(pg_wal_lsn_diff(pg_last_wal_receive_lsn(), pg_last_wal_replay_lsn()), 0) " +
+		"ELSE (SELECT coalesce(max(pg_wal_lsn_diff(pg_current_wal_lsn(), replay_lsn)), 0) FROM pg_stat_replication) END)::bigint AS lag"
+
+	// ClusterOverview96 is the query for getting overview of instance in cluster mode for versions 9.6 and older.
+	ClusterOverview96 = "SELECT current_setting('server_version') AS version, pg_is_in_recovery() AS recovery, " +
+		"(SELECT coalesce(sum(xact_commit + xact_rollback), 0) FROM pg_stat_database)::bigint AS xacts, " +
+		"(SELECT count(*) FROM pg_stat_activity WHERE state = 'active' AND pid <> pg_backend_pid()) AS active, " +
+		"(SELECT count(*) FROM pg_stat_replication) AS replicas, " +
+		"(CASE WHEN pg_is_in_recovery() THEN coalesce(pg_xlog_location_diff(pg_last_xlog_receive_location(), pg_last_xlog_replay_location()), 0) " +
+		"ELSE (SELECT coalesce(max(pg_xlog_location_diff(pg_current_xlog_location(), replay_location)), 0) FROM pg_stat_replication) END)::bigint AS lag"
+
+	// ClusterDatabasesSize is the query for getting total size of databases in cluster mode. Databases which can't be
+	// connected to by the current role are skipped.
+	ClusterDatabasesSize = "SELECT coalesce(sum(pg_database_size(oid)), 0)::bigint FROM pg_database " +
+		"WHERE datallowconn AND has_database_privilege(oid, 'CONNECT')"
+)
+
+// SelectClusterOverviewQuery returns query for getting overview of instance depending on Postgres version.
+func SelectClusterOverviewQuery(version int) string {
+	if version < 100000 {
+		return ClusterOverview96
+	}
+	return ClusterOverviewDefault
+}
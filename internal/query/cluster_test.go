@@ -0,0 +1,33 @@
+package query
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_ClusterQueries(t *testing.T) {
+	versions := []int{90500, 90600, 100000, 110000, 120000, 130000}
+
+	for _, version := range versions {
+		t.Run(fmt.Sprintf("cluster/%d", version), func(t *testing.T) {
+			conn, err := postgres.NewTestConnectVersion(version)
+			assert.NoError(t, err)
+
+			_, err = conn.Exec(SelectClusterOverviewQuery(version))
+			assert.NoError(t, err)
+
+			_, err = conn.Exec(ClusterDatabasesSize)
+			assert.NoError(t, err)
+
+			conn.Close()
+		})
+	}
+}
+
+func TestSelectClusterOverviewQuery(t *testing.T) {
+	assert.Equal(t, ClusterOverview96, SelectClusterOverviewQuery(90600))
+	assert.Equal(t, ClusterOverviewDefault, SelectClusterOverviewQuery(100000))
+	assert.Equal(t, ClusterOverviewDefault, SelectClusterOverviewQuery(130000))
+}
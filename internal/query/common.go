@@ -5,12 +5,36 @@ const (
 	GetSetting = "SELECT current_setting($1)"
 	// GetRecoveryStatus queries current Postgres recovery status.
 	GetRecoveryStatus = "SELECT pg_is_in_recovery()"
+	// GetBackendPid queries process ID of the backend serving the current session.
+	GetBackendPid = "SELECT pg_backend_pid()"
+	// GetMaxWalSize queries value and unit of max_wal_size setting, unit depends on version.
+	GetMaxWalSize = "SELECT setting, coalesce(unit, '') FROM pg_settings WHERE name = 'max_wal_size'"
 	// GetUptime queries Postgres uptime.
 	GetUptime = "SELECT date_trunc('seconds', now() - pg_postmaster_start_time())"
+	// GetStatsResetAge queries number of seconds since stats of the current database have been reset, -1 if never.
+	GetStatsResetAge = "SELECT coalesce(extract(epoch FROM now() - stats_reset)::bigint, -1) FROM pg_stat_database WHERE datname = current_database()"
+	// GetStatementsResetAge queries number of seconds since pg_stat_statements have been reset, -1 if never.
+	//   Notes: pg_stat_statements_info introduced in pg_stat_statements 1.9 (Postgres 14)
+	GetStatementsResetAge = "SELECT coalesce(extract(epoch FROM now() - stats_reset)::bigint, -1) FROM pg_stat_statements_info"
+	// GetStatementByQueryID queries text of the statement with specified queryid from pg_stat_statements.
+	GetStatementByQueryID = "SELECT query FROM pg_stat_statements WHERE queryid = $1 LIMIT 1"
+	// GetSlotProperties queries properties of replication slot which define whether its changes could be peeked.
+	GetSlotProperties = "SELECT slot_type, coalesce(plugin, ''), active, coalesce(database = current_database(), false) " +
+		"FROM pg_replication_slots WHERE slot_name = $1"
+	// PeekSlotChanges decodes pending changes of logical replication slot without consuming them.
+	//   Notes: decoding stops at the end of transaction which exceeds the limit, hence more changes could be returned.
+	PeekSlotChanges = "SELECT lsn::text, xid::text, data FROM pg_logical_slot_peek_changes($1, NULL, $2)"
 	// CheckSchemaExists checks schema exists in the database.
-	CheckSchemaExists = "SELECT EXISTS (SELECT 1 FROM information_schema.schemata WHERE schema_name = $1)"
+	CheckSchemaExists = "SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1 AND has_schema_privilege(oid, 'USAGE'))"
+	// CheckFunctionExists checks function exists in the database.
+	CheckFunctionExists = "SELECT to_regproc($1) IS NOT NULL"
+	// CheckFunctionSecurityDefiner checks function is executed with privileges of the owner.
+	CheckFunctionSecurityDefiner = "SELECT coalesce((SELECT prosecdef FROM pg_proc WHERE oid = to_regproc($1)), false)"
 	// CheckExtensionExists checks extension is installed in the database.
 	CheckExtensionExists = "SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = $1)"
+
+	// GetExtensionVersion returns version of installed extension.
+	GetExtensionVersion = "SELECT extversion FROM pg_extension WHERE extname = $1"
 	// GetAllSettings queries current Postgres configuration
 	GetAllSettings = "SELECT name, setting, unit, category FROM pg_settings ORDER BY 4"
 	// GetCurrentLogfile queries current Postgres logfile
@@ -26,13 +50,13 @@ const (
 		"FROM pg_stat_activity WHERE {{.BackendState}} " +
 		"AND ((clock_timestamp() - xact_start) > '{{.QueryAgeThresh}}'::interval " +
 		"OR (clock_timestamp() - query_start) > '{{.QueryAgeThresh}}'::interval) " +
-		"AND pid != pg_backend_pid()"
+		"AND pid != pg_backend_pid(){{ if .OwnBackendsOnly }} AND pg_has_role(usesysid, 'MEMBER'){{ end }}"
 	// ExecTerminateBackendGroup terminate a group of backends based on specified criteria
 	ExecTerminateBackendGroup = "SELECT count(pg_terminate_backend(pid)) " +
 		"FROM pg_stat_activity WHERE {{.BackendState}} " +
 		"AND ((clock_timestamp() - xact_start) > '{{.QueryAgeThresh}}'::interval " +
 		"OR (clock_timestamp() - query_start) > '{{.QueryAgeThresh}}'::interval) " +
-		"AND pid != pg_backend_pid()"
+		"AND pid != pg_backend_pid(){{ if .OwnBackendsOnly }} AND pg_has_role(usesysid, 'MEMBER'){{ end }}"
 	// ExecResetStats resets statistics counter in the current database
 	ExecResetStats = "SELECT pg_stat_reset()"
 	// ExecResetPgStatStatements resets pg_stat_statements statistics
@@ -117,8 +141,38 @@ const (
 	SelectActivityStatementsPG12   = "SELECT (sum(total_time) / sum(calls))::numeric(20,2) AS avg_query, sum(calls) AS total_calls FROM pg_stat_statements"
 	SelectActivityStatementsLatest = "SELECT (sum(total_exec_time) / sum(calls))::numeric(20,2) AS avg_query, sum(calls) AS total_calls FROM pg_stat_statements"
 
+	// SelectReplicationSummaryDefault queries number of connected replicas and replication lag in bytes. Lag is the
+	// replay lag of standby, or the maximal replay lag of replicas connected to primary.
+	SelectReplicationSummaryDefault = "SELECT (SELECT count(*) FROM pg_stat_replication)::int AS replicas, " +
+		"(CASE WHEN pg_is_in_recovery() THEN coalesce(pg_wal_lsn_diff(pg_last_wal_receive_lsn(), pg_last_wal_replay_lsn()), 0) " +
+		"ELSE (SELECT coalesce(max(pg_wal_lsn_diff(pg_current_wal_lsn(), replay_lsn)), 0) FROM pg_stat_replication) END)::bigint AS lag"
+
+	// SelectReplicationSummaryPG96 queries number of replicas and replication lag for versions 9.6 and older.
+	//   Postgres 10: xlog functions and columns have been renamed to wal.
+	SelectReplicationSummaryPG96 = "SELECT (SELECT count(*) FROM pg_stat_replication)::int AS replicas, " +
+		"(CASE WHEN pg_is_in_recovery() THEN coalesce(pg_xlog_location_diff(pg_last_xlog_receive_location(), pg_last_xlog_replay_location()), 0) " +
+		"ELSE (SELECT coalesce(max(pg_xlog_location_diff(pg_current_xlog_location(), replay_location)), 0) FROM pg_stat_replication) END)::bigint AS lag"
+
+	// SelectRolePrivileges queries privileges of the current role required for seeing stats of other roles and for
+	// performing administrative actions. Functions which don't exist in older Postgres versions are considered allowed.
+	SelectRolePrivileges = "SELECT rolsuper, " +
+		"coalesce((SELECT pg_has_role(current_user, oid, 'MEMBER') FROM pg_roles WHERE rolname = 'pg_monitor'), false), " +
+		"coalesce((SELECT pg_has_role(current_user, oid, 'MEMBER') FROM pg_roles WHERE rolname = 'pg_read_all_stats'), false), " +
+		"coalesce((SELECT pg_has_role(current_user, oid, 'MEMBER') FROM pg_roles WHERE rolname = 'pg_read_all_settings'), false), " +
+		"coalesce((SELECT pg_has_role(current_user, oid, 'MEMBER') FROM pg_roles WHERE rolname = 'pg_signal_backend'), false), " +
+		"has_function_privilege('pg_stat_reset()', 'EXECUTE'), " +
+		"has_function_privilege('pg_reload_conf()', 'EXECUTE'), " +
+		"coalesce((SELECT has_function_privilege(oid, 'EXECUTE') FROM pg_proc WHERE proname = 'pg_current_logfile' AND pronargs = 0), true) " +
+		"FROM pg_roles WHERE rolname = current_user"
+
+	// SelectStatSchemaName queries name of the schema where stats functions and views are installed. The default
+	// schema is preferred if stats schema is installed several times.
+	SelectStatSchemaName = "SELECT n.nspname FROM pg_proc p JOIN pg_namespace n ON p.pronamespace = n.oid " +
+		"WHERE p.proname = 'get_sys_clk_ticks' AND EXISTS (SELECT 1 FROM pg_class c WHERE c.relnamespace = n.oid AND c.relname = 'sys_proc_stat') " +
+		"ORDER BY n.nspname <> '" + DefaultStatSchema + "', n.nspname LIMIT 1"
+
 	// SelectRemoteProcSysTicks queries system timer's frequency from Postgres instance
-	SelectRemoteProcSysTicks = "SELECT pgcenter.get_sys_clk_ticks()::float"
+	SelectRemoteProcSysTicks = "SELECT {{.Schema}}.get_sys_clk_ticks()::float"
 )
 
 // SelectActivityActivityQuery returns activity main query depending on used version.
@@ -145,6 +199,16 @@ func SelectActivityAutovacuumQuery(version int) string {
 	}
 }
 
+// SelectReplicationSummaryQuery returns replication summary query depending on used version.
+func SelectReplicationSummaryQuery(version int) string {
+	switch {
+	case version < 100000:
+		return SelectReplicationSummaryPG96
+	default:
+		return SelectReplicationSummaryDefault
+	}
+}
+
 // SelectActivityStatementsQuery returns statements activity query depending on used version.
 func SelectActivityStatementsQuery(version int) string {
 	switch {
@@ -3,6 +3,7 @@ package query
 import (
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -60,6 +61,36 @@ func TestSelectActivityStatementsQuery(t *testing.T) {
 	}
 }
 
+func TestSelectReplicationSummaryQuery(t *testing.T) {
+	testcases := []struct {
+		version int
+		want    string
+	}{
+		{version: 90600, want: SelectReplicationSummaryPG96},
+		{version: 100000, want: SelectReplicationSummaryDefault},
+		{version: 130000, want: SelectReplicationSummaryDefault},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, SelectReplicationSummaryQuery(tc.version))
+	}
+}
+
+func TestFormat_SignalGroup(t *testing.T) {
+	for _, tmpl := range []string{ExecCancelQueryGroup, ExecTerminateBackendGroup} {
+		opts := Options{BackendState: "state = 'active'", QueryAgeThresh: "00:00:00.0"}
+
+		got, _, err := Format(tmpl, opts)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasSuffix(got, "AND pid != pg_backend_pid()"))
+
+		opts.OwnBackendsOnly = true
+		got, _, err = Format(tmpl, opts)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasSuffix(got, "AND pid != pg_backend_pid() AND pg_has_role(usesysid, 'MEMBER')"))
+	}
+}
+
 func Test_CommonQueries(t *testing.T) {
 	versions := []int{90500, 90600, 100000, 110000, 120000, 130000}
 
@@ -71,7 +102,11 @@ func Test_CommonQueries(t *testing.T) {
 		{query: GetRecoveryStatus},
 		{query: GetUptime},
 		{query: CheckSchemaExists, args: []interface{}{"public"}},
+		{query: CheckFunctionExists, args: []interface{}{"pg_catalog.now"}},
+		{query: CheckFunctionSecurityDefiner, args: []interface{}{"pg_catalog.now"}},
 		{query: CheckExtensionExists, args: []interface{}{"plpgsql"}},
+		{query: SelectRolePrivileges},
+		{query: SelectStatSchemaName},
 		{query: GetAllSettings},
 		{query: ExecReloadConf},
 		{query: ExecResetStats},
@@ -128,4 +163,16 @@ func Test_CommonQueries(t *testing.T) {
 		}
 	})
 
+	t.Run("replication_summary_queries", func(t *testing.T) {
+		for _, version := range versions {
+			conn, err := postgres.NewTestConnectVersion(version)
+			assert.NoError(t, err)
+
+			_, err = conn.Exec(SelectReplicationSummaryQuery(version))
+			assert.NoError(t, err)
+
+			conn.Close()
+		}
+	})
+
 }
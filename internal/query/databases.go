@@ -2,7 +2,10 @@ package query
 
 const (
 	// PgStatDatabaseDefault is the default query for getting databases' stats from pg_stat_database view
-	// { Name: "pg_stat_database", Query: common.PgStatDatabaseQueryDefault, DiffIntvl: [2]int{1,16}, Ncols: 18, OrderKey: 0, OrderDesc: true }
+	// { Name: "pg_stat_database", Query: common.PgStatDatabaseQueryDefault, DiffIntvl: [2]int{1,16}, Ncols: 25, OrderKey: 0, OrderDesc: true }
+	// Columns after the diff interval are not diffed: cache hit ratio, average read latency and shares of sessions time
+	// are calculated since stats reset, 'idle_xacts' is the current number of backends idle in transaction. Read latency
+	// is available when track_io_timing is on.
 	PgStatDatabaseDefault = "SELECT datname, " +
 		"coalesce(xact_commit, 0) AS commits, coalesce(xact_rollback, 0) AS rollbacks, " +
 		"coalesce(blks_read * (SELECT current_setting('block_size')::int / 1024), 0) AS reads, " +
@@ -13,11 +16,38 @@ const (
 		"coalesce(checksum_failures, 0) AS csum_fails, coalesce(temp_files, 0) AS temp_files, " +
 		"coalesce(temp_bytes, 0) AS temp_bytes, coalesce(blk_read_time, 0)::numeric(20,2) AS read_t, " +
 		"coalesce(blk_write_time, 0)::numeric(20,2) AS write_t, " +
+		`round(100.0 * blks_hit / nullif(blks_hit + blks_read, 0), 2)::text AS "hit_%", ` +
+		"round((blk_read_time / nullif(blks_read, 0))::numeric, 3)::text AS read_lat, " +
+		"(SELECT count(*) FROM pg_stat_activity a WHERE a.datid = d.datid " +
+		"AND a.state IN ('idle in transaction', 'idle in transaction (aborted)')) AS idle_xacts, " +
+		"coalesce(sessions, 0) AS sessions, " +
+		`round((100 * active_time / greatest(session_time, 1))::numeric, 2)::text AS "active_%", ` +
+		`round((100 * idle_in_transaction_time / greatest(session_time, 1))::numeric, 2)::text AS "idle_xact_%", ` +
+		"coalesce(sessions_abandoned + sessions_fatal + sessions_killed, 0) AS sess_lost, " +
 		"date_trunc('seconds', now() - stats_reset)::text AS stats_age " +
-		"FROM pg_stat_database ORDER BY datname DESC"
+		"FROM pg_stat_database d ORDER BY datname DESC"
+
+	// PgStatDatabasePG13 is the query for getting databases' stats from pg_stat_database view for versions 12 and 13.
+	// { Name: "pg_stat_database", Query: common.PgStatDatabaseQuery13, DiffIntvl: [2]int{1,16}, Ncols: 21, OrderKey: 0, OrderDesc: true }
+	PgStatDatabasePG13 = "SELECT datname, " +
+		"coalesce(xact_commit, 0) AS commits, coalesce(xact_rollback, 0) AS rollbacks, " +
+		"coalesce(blks_read * (SELECT current_setting('block_size')::int / 1024), 0) AS reads, " +
+		"coalesce(blks_hit, 0) AS hits, coalesce(tup_returned, 0) AS returned, " +
+		"coalesce(tup_fetched, 0) AS fetched, coalesce(tup_inserted, 0) AS inserts, " +
+		"coalesce(tup_updated, 0) AS updates, coalesce(tup_deleted, 0) AS deletes, " +
+		"coalesce(conflicts, 0) AS conflicts, coalesce(deadlocks, 0) AS deadlocks, " +
+		"coalesce(checksum_failures, 0) AS csum_fails, coalesce(temp_files, 0) AS temp_files, " +
+		"coalesce(temp_bytes, 0) AS temp_bytes, coalesce(blk_read_time, 0)::numeric(20,2) AS read_t, " +
+		"coalesce(blk_write_time, 0)::numeric(20,2) AS write_t, " +
+		`round(100.0 * blks_hit / nullif(blks_hit + blks_read, 0), 2)::text AS "hit_%", ` +
+		"round((blk_read_time / nullif(blks_read, 0))::numeric, 3)::text AS read_lat, " +
+		"(SELECT count(*) FROM pg_stat_activity a WHERE a.datid = d.datid " +
+		"AND a.state IN ('idle in transaction', 'idle in transaction (aborted)')) AS idle_xacts, " +
+		"date_trunc('seconds', now() - stats_reset)::text AS stats_age " +
+		"FROM pg_stat_database d ORDER BY datname DESC"
 
 	// PgStatDatabasePG11 is the query for getting databases' stats from pg_stat_database view for versions 11 and older.
-	// { Name: "pg_stat_database", Query: common.PgStatDatabaseQuery11, DiffIntvl: [2]int{1,15}, Ncols: 17, OrderKey: 0, OrderDesc: true }
+	// { Name: "pg_stat_database", Query: common.PgStatDatabaseQuery11, DiffIntvl: [2]int{1,15}, Ncols: 20, OrderKey: 0, OrderDesc: true }
 	PgStatDatabasePG11 = "SELECT datname, " +
 		"coalesce(xact_commit, 0) AS commits, coalesce(xact_rollback, 0) AS rollbacks, " +
 		"coalesce(blks_read * (SELECT current_setting('block_size')::int / 1024), 0) AS reads, " +
@@ -28,15 +58,10 @@ const (
 		"coalesce(temp_files, 0) AS temp_files, coalesce(temp_bytes, 0) AS temp_bytes, " +
 		"coalesce(blk_read_time, 0)::numeric(20,2) AS read_t, " +
 		"coalesce(blk_write_time, 0)::numeric(20,2) AS write_t, " +
+		`round(100.0 * blks_hit / nullif(blks_hit + blks_read, 0), 2)::text AS "hit_%", ` +
+		"round((blk_read_time / nullif(blks_read, 0))::numeric, 3)::text AS read_lat, " +
+		"(SELECT count(*) FROM pg_stat_activity a WHERE a.datid = d.datid " +
+		"AND a.state IN ('idle in transaction', 'idle in transaction (aborted)')) AS idle_xacts, " +
 		"date_trunc('seconds', now() - stats_reset)::text AS stats_age " +
-		"FROM pg_stat_database ORDER BY datname DESC"
+		"FROM pg_stat_database d ORDER BY datname DESC"
 )
-
-func SelectStatDatabaseQuery(version int) (string, int, [2]int) {
-	switch {
-	case version < 120000:
-		return PgStatDatabasePG11, 17, [2]int{1, 15}
-	default:
-		return PgStatDatabaseDefault, 18, [2]int{1, 16}
-	}
-}
@@ -7,26 +7,28 @@ import (
 	"testing"
 )
 
-func TestSelectStatDatabaseQuery(t *testing.T) {
+func TestSelect_databases(t *testing.T) {
 	testcases := []struct {
 		version int
 		wantQ   string
 		wantN   int
 		wantD   [2]int
 	}{
-		{version: 90500, wantQ: PgStatDatabasePG11, wantN: 17, wantD: [2]int{1, 15}},
-		{version: 90600, wantQ: PgStatDatabasePG11, wantN: 17, wantD: [2]int{1, 15}},
-		{version: 100000, wantQ: PgStatDatabasePG11, wantN: 17, wantD: [2]int{1, 15}},
-		{version: 110000, wantQ: PgStatDatabasePG11, wantN: 17, wantD: [2]int{1, 15}},
-		{version: 120000, wantQ: PgStatDatabaseDefault, wantN: 18, wantD: [2]int{1, 16}},
-		{version: 130000, wantQ: PgStatDatabaseDefault, wantN: 18, wantD: [2]int{1, 16}},
+		{version: 90500, wantQ: PgStatDatabasePG11, wantN: 20, wantD: [2]int{1, 15}},
+		{version: 90600, wantQ: PgStatDatabasePG11, wantN: 20, wantD: [2]int{1, 15}},
+		{version: 100000, wantQ: PgStatDatabasePG11, wantN: 20, wantD: [2]int{1, 15}},
+		{version: 110000, wantQ: PgStatDatabasePG11, wantN: 20, wantD: [2]int{1, 15}},
+		{version: 120000, wantQ: PgStatDatabasePG13, wantN: 21, wantD: [2]int{1, 16}},
+		{version: 130000, wantQ: PgStatDatabasePG13, wantN: 21, wantD: [2]int{1, 16}},
+		{version: 140000, wantQ: PgStatDatabaseDefault, wantN: 25, wantD: [2]int{1, 16}},
 	}
 
 	for _, tc := range testcases {
-		gotQ, gotN, gotD := SelectStatDatabaseQuery(tc.version)
-		assert.Equal(t, tc.wantQ, gotQ)
-		assert.Equal(t, tc.wantN, gotN)
-		assert.Equal(t, tc.wantD, gotD)
+		got, ok := Select("databases", Options{Version: tc.version})
+		assert.True(t, ok)
+		assert.Equal(t, tc.wantQ, got.Query)
+		assert.Equal(t, tc.wantN, got.Ncols)
+		assert.Equal(t, tc.wantD, got.DiffIntvl)
 	}
 }
 
@@ -35,10 +37,9 @@ func Test_StatDatabaseQueries(t *testing.T) {
 
 	for _, version := range versions {
 		t.Run(fmt.Sprintf("pg_stat_database/%d", version), func(t *testing.T) {
-			tmpl, _, _ := SelectStatDatabaseQuery(version)
-
 			opts := NewOptions(version, "f", "off", 256)
-			q, err := Format(tmpl, opts)
+			v, _ := Select("databases", opts)
+			q, _, err := Format(v.Query, opts)
 			assert.NoError(t, err)
 
 			conn, err := postgres.NewTestConnectVersion(version)
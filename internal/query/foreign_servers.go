@@ -0,0 +1,30 @@
+package query
+
+const (
+	// PgForeignServersDefault is the default query for getting foreign servers, their user mappings, foreign tables and
+	// connections opened by postgres_fdw.
+	// { Name: "fdw", Query: common.PgForeignServersDefault, DiffIntvl: [2]int{0,0}, Ncols: 9, OrderKey: 0, OrderDesc: true }
+	//   Notes: postgres_fdw_get_connections() introduced in postgres_fdw shipped with Postgres 14. The function returns
+	//   connections opened by the current session only.
+	PgForeignServersDefault = "SELECT s.srvname AS server, w.fdwname AS fdw, pg_get_userbyid(s.srvowner) AS owner, " +
+		"coalesce(array_to_string(s.srvoptions, ','), '') AS options, " +
+		"(SELECT count(*) FROM pg_user_mappings m WHERE m.srvid = s.oid) AS mappings, " +
+		"coalesce((SELECT string_agg(m.usename, ',' ORDER BY m.usename) FROM pg_user_mappings m WHERE m.srvid = s.oid), '') AS users, " +
+		"(SELECT count(*) FROM pg_foreign_table t WHERE t.ftserver = s.oid) AS ftables, " +
+		"coalesce(c.conns, 0) AS conns, coalesce(c.invalid, 0) AS invalid " +
+		"FROM pg_foreign_server s JOIN pg_foreign_data_wrapper w ON w.oid = s.srvfdw " +
+		"LEFT JOIN (SELECT server_name, count(*) AS conns, count(*) FILTER (WHERE NOT valid) AS invalid " +
+		"FROM postgres_fdw_get_connections() GROUP BY server_name) c ON c.server_name = s.srvname " +
+		"ORDER BY s.srvname DESC"
+
+	// PgForeignServersNoConns queries foreign servers when postgres_fdw connections are not available: Postgres is older
+	// than 14 or postgres_fdw is not installed. Connections columns are empty.
+	PgForeignServersNoConns = "SELECT s.srvname AS server, w.fdwname AS fdw, pg_get_userbyid(s.srvowner) AS owner, " +
+		"coalesce(array_to_string(s.srvoptions, ','), '') AS options, " +
+		"(SELECT count(*) FROM pg_user_mappings m WHERE m.srvid = s.oid) AS mappings, " +
+		"coalesce((SELECT string_agg(m.usename, ',' ORDER BY m.usename) FROM pg_user_mappings m WHERE m.srvid = s.oid), '') AS users, " +
+		"(SELECT count(*) FROM pg_foreign_table t WHERE t.ftserver = s.oid) AS ftables, " +
+		"NULL::bigint AS conns, NULL::bigint AS invalid " +
+		"FROM pg_foreign_server s JOIN pg_foreign_data_wrapper w ON w.oid = s.srvfdw " +
+		"ORDER BY s.srvname DESC"
+)
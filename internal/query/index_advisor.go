@@ -0,0 +1,17 @@
+package query
+
+const (
+	// PgIndexAdvisorDefault is the default query for index advisor, it is based on tables' stats from pg_stat_user_tables.
+	// { Name: "index_advisor", Query: common.PgIndexAdvisorDefault, DiffIntvl: [2]int{3,4}, Ncols: 7, OrderKey: 4, OrderDesc: true }
+	// Column 'avg_read' is the average number of rows read by a sequential scan since stats reset. Column 'score' is the
+	// rate of sequential scans multiplied by 'avg_read', and 'advice' flags candidates for indexing, both are calculated
+	// by pgcenter after diff, the query returns total number of rows read by sequential scans and empty advice.
+	PgIndexAdvisorDefault = "SELECT t.schemaname || '.' || t.relname AS relation, " +
+		"coalesce(t.n_live_tup, 0) AS live, " +
+		"coalesce(round(t.seq_tup_read::numeric / nullif(t.seq_scan, 0), 2), 0) AS avg_read, " +
+		"coalesce(t.seq_scan, 0)::numeric(20,2) AS seq_scan, " +
+		"coalesce(t.seq_tup_read, 0)::numeric(20,2) AS score, " +
+		"'' AS advice, " +
+		"coalesce((SELECT string_agg(x.indexrelname, ',' ORDER BY x.indexrelname) FROM pg_stat_user_indexes x WHERE x.relid = t.relid), '') AS indexes " +
+		"FROM pg_stat_user_tables t ORDER BY (t.schemaname || '.' || t.relname) DESC"
+)
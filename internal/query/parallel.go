@@ -0,0 +1,16 @@
+package query
+
+const (
+	// PgStatParallelDefault queries leaders of parallel queries with number of their parallel workers from
+	// pg_stat_activity, and total number of parallel workers compared to max_parallel_workers.
+	// { Name: "parallel", Query: common.PgStatParallelDefault, DiffIntvl: [2]int{0,0}, Ncols: 9, OrderKey: 0, OrderDesc: true }
+	//   Notes: leader_pid introduced in Postgres 13.
+	// regexp_replace() removes extra spaces, tabs and newlines from queries
+	PgStatParallelDefault = "SELECT l.pid, l.datname, l.usename, l.state, count(w.pid) AS workers, " +
+		"(SELECT count(*) FROM pg_stat_activity WHERE backend_type = 'parallel worker') AS all_workers, " +
+		"current_setting('max_parallel_workers')::int AS max_workers, " +
+		"date_trunc('seconds', clock_timestamp() - l.query_start)::text AS query_age, " +
+		`regexp_replace(regexp_replace(l.query,E'( |\t)+', ' ', 'g'),E'\n', ' ', 'g') AS query ` +
+		"FROM pg_stat_activity l JOIN pg_stat_activity w ON w.leader_pid = l.pid AND w.backend_type = 'parallel worker' " +
+		"GROUP BY l.pid, l.datname, l.usename, l.state, l.query_start, l.query ORDER BY l.pid DESC"
+)
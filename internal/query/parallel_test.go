@@ -0,0 +1,37 @@
+package query
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSelect_parallel(t *testing.T) {
+	_, ok := Select("parallel", Options{Version: 120000})
+	assert.False(t, ok)
+
+	got, ok := Select("parallel", Options{Version: 130000})
+	assert.True(t, ok)
+	assert.Equal(t, PgStatParallelDefault, got.Query)
+}
+
+func Test_ParallelQueries(t *testing.T) {
+	versions := []int{130000}
+
+	for _, version := range versions {
+		t.Run(fmt.Sprintf("parallel/%d", version), func(t *testing.T) {
+			opts := NewOptions(version, "f", "off", 256)
+			q, _, err := Format(PgStatParallelDefault, opts)
+			assert.NoError(t, err)
+
+			conn, err := postgres.NewTestConnectVersion(version)
+			assert.NoError(t, err)
+
+			_, err = conn.Exec(q)
+			assert.NoError(t, err)
+
+			conn.Close()
+		})
+	}
+}
@@ -6,12 +6,62 @@ package query
 // organized in sequential set of SQL commands. At schema installation, this set of SQL commands is executed within single
 // transaction.
 
+import (
+	"github.com/jackc/pgx/v4"
+	"regexp"
+)
+
+// StatSchemaVersion defines version of stats schema expected by pgCenter. Version should be incremented when
+// schema functions or views are changed. Schemas installed before versioning has been introduced have version 1.
+const StatSchemaVersion = 3
+
+// DefaultStatSchema defines name of the schema where stats functions and views are installed by default.
+const DefaultStatSchema = "pgcenter"
+
+// plainIdentifierRE defines identifiers which could be used in queries without quoting.
+var plainIdentifierRE = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// SchemaOptions contains settings used for customizing stats schema installation.
+type SchemaOptions struct {
+	Version int    // Version of the stats schema
+	Schema  string // Quoted name of the schema where stats functions and views are installed
+	Role    string // Quoted name of the role which is granted to use stats schema
+	// Functions are executed with privileges of the owner, files which could be read by functions are restricted.
+	Restricted bool
+}
+
+// NewSchemaOptions creates options used for stats schema installation. Default schema is used if schema is not specified.
+func NewSchemaOptions(schema string, role string) SchemaOptions {
+	if schema == "" {
+		schema = DefaultStatSchema
+	}
+
+	opts := SchemaOptions{Version: StatSchemaVersion, Schema: quoteIdentifier(schema)}
+	if role != "" {
+		opts.Role = pgx.Identifier{role}.Sanitize()
+	}
+	return opts
+}
+
+// quoteIdentifier quotes identifier if it contains characters which are not allowed in plain identifiers.
+func quoteIdentifier(name string) string {
+	if plainIdentifierRE.MatchString(name) {
+		return name
+	}
+	return pgx.Identifier{name}.Sanitize()
+}
+
+// FormatSchema transforms stats schema query's template to a particular query.
+func FormatSchema(tmpl string, o SchemaOptions) (string, error) {
+	return format(tmpl, o, nil)
+}
+
 const (
 	// Name: pgcenter; Type: SCHEMA; Schema: -
-	StatSchemaCreateSchema = `CREATE SCHEMA IF NOT EXISTS pgcenter`
+	StatSchemaCreateSchema = `CREATE SCHEMA IF NOT EXISTS {{.Schema}}`
 
 	// Name: get_netdev_link_settings(character varying); Type: FUNCTION; Schema: pgcenter
-	StatSchemaCreateFunction1 = `CREATE OR REPLACE FUNCTION pgcenter.get_netdev_link_settings(INOUT iface CHARACTER VARYING, OUT speed BIGINT, OUT duplex INTEGER) RETURNS RECORD
+	StatSchemaCreateFunction1 = `CREATE OR REPLACE FUNCTION {{.Schema}}.get_netdev_link_settings(INOUT iface CHARACTER VARYING, OUT speed BIGINT, OUT duplex INTEGER) RETURNS RECORD
 LANGUAGE plperlu
 AS $$
 use Linux::Ethtool::Settings;
@@ -25,7 +75,7 @@ if (my $settings = Linux::Ethtool::Settings->new($_[0])) {
 $$;`
 
 	// Name: get_sys_clk_ticks(); Type: FUNCTION; Schema: pgcenter
-	StatSchemaCreateFunction2 = `CREATE OR REPLACE FUNCTION pgcenter.get_sys_clk_ticks() RETURNS integer
+	StatSchemaCreateFunction2 = `CREATE OR REPLACE FUNCTION {{.Schema}}.get_sys_clk_ticks() RETURNS integer
 LANGUAGE plperlu
 AS $$
 use POSIX;
@@ -34,10 +84,12 @@ return $clock_ticks;
 $$;`
 
 	// Name: get_proc_stats(character varying, character varying, character varying, integer); Type: FUNCTION; Schema: pgcenter
-	StatSchemaCreateFunction3 = `CREATE OR REPLACE FUNCTION pgcenter.get_proc_stats(character varying, character varying, character varying, integer) RETURNS SETOF record
+	StatSchemaCreateFunction3 = `CREATE OR REPLACE FUNCTION {{.Schema}}.get_proc_stats(character varying, character varying, character varying, integer) RETURNS SETOF record
 LANGUAGE plperlu
 AS $$
-open FILE, $_[0];
+{{if .Restricted}}# allow reading only files used by pgcenter, function is executed with privileges of the owner.
+die "pgcenter: reading $_[0] is not allowed\n" unless $_[0] =~ m{^/proc/(diskstats|loadavg|meminfo|net/dev|stat|uptime)$};
+{{end}}open FILE, $_[0];
 my @cntn = (); $i = 0;
 while (<FILE>) {
 	# skip header if required.
@@ -57,10 +109,39 @@ while (<FILE>) {
 }
 close FILE;
 return \@cntn;
+$$;`
+
+	// Name: get_netdevs_link_settings(); Type: FUNCTION; Schema: pgcenter
+	StatSchemaCreateFunction4 = `CREATE OR REPLACE FUNCTION {{.Schema}}.get_netdevs_link_settings(OUT iface CHARACTER VARYING, OUT speed BIGINT, OUT duplex INTEGER) RETURNS SETOF RECORD
+LANGUAGE plperlu
+AS $$
+use Linux::Ethtool::Settings;
+open FILE, '/proc/net/dev' or die "pgcenter: failed to open /proc/net/dev: $!\n";
+my $i = 0;
+while (<FILE>) {
+	# skip header.
+	if ($i < 2) { $i++; next; }
+	next unless /^\s*([^:\s]+):/;
+	my $iface = $1;
+	if (my $settings = Linux::Ethtool::Settings->new($iface)) {
+		return_next({iface => $iface, speed => $settings->speed(), duplex => $settings->duplex() ? 1 : 0});
+	} else {
+		return_next({iface => $iface, speed => 0, duplex => -1});
+	}
+}
+close FILE;
+return undef;
+$$;`
+
+	// Name: get_schema_version(); Type: FUNCTION; Schema: pgcenter
+	StatSchemaCreateVersionFunction = `CREATE OR REPLACE FUNCTION {{.Schema}}.get_schema_version() RETURNS integer
+LANGUAGE sql IMMUTABLE
+AS $$
+SELECT {{.Version}};
 $$;`
 
 	// Name: sys_proc_diskstats; Type: VIEW; Schema: pgcenter
-	StatSchemaCreateView1 = `CREATE OR REPLACE VIEW pgcenter.sys_proc_diskstats AS
+	StatSchemaCreateView1 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_diskstats AS
 SELECT get_proc_stats.col0 AS maj,
 get_proc_stats.col1 AS min,
 get_proc_stats.col2 AS dev,
@@ -81,28 +162,28 @@ COALESCE(get_proc_stats.col16, (0)::double precision) AS dsectors,
 COALESCE(get_proc_stats.col17, (0)::double precision) AS dspent,
 COALESCE(get_proc_stats.col18, (0)::double precision) AS flushes,
 COALESCE(get_proc_stats.col19, (0)::double precision) AS fspent
-FROM pgcenter.get_proc_stats('/proc/diskstats'::character varying, ' '::character varying, ''::character varying, 0) get_proc_stats(col0 integer, col1 integer, col2 character varying, col3 double precision, col4 double precision, col5 double precision, col6 double precision, col7 double precision, col8 double precision, col9 double precision, col10 double precision, col11 double precision, col12 double precision, col13 double precision, col14 double precision, col15 double precision, col16 double precision, col17 double precision, col18 double precision, col19 double precision);`
+FROM {{.Schema}}.get_proc_stats('/proc/diskstats'::character varying, ' '::character varying, ''::character varying, 0) get_proc_stats(col0 integer, col1 integer, col2 character varying, col3 double precision, col4 double precision, col5 double precision, col6 double precision, col7 double precision, col8 double precision, col9 double precision, col10 double precision, col11 double precision, col12 double precision, col13 double precision, col14 double precision, col15 double precision, col16 double precision, col17 double precision, col18 double precision, col19 double precision);`
 
 	// Name: sys_proc_loadavg; Type: VIEW; Schema: pgcenter
-	StatSchemaCreateView2 = `CREATE OR REPLACE VIEW pgcenter.sys_proc_loadavg AS
+	StatSchemaCreateView2 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_loadavg AS
 SELECT get_proc_stats.col0 AS min1,
 get_proc_stats.col1 AS min5,
 get_proc_stats.col2 AS min15,
 get_proc_stats.col3 AS procnum,
 get_proc_stats.col4 AS last_pid
-FROM pgcenter.get_proc_stats('/proc/loadavg'::character varying, ' '::character varying, ''::character varying, 0)
+FROM {{.Schema}}.get_proc_stats('/proc/loadavg'::character varying, ' '::character varying, ''::character varying, 0)
 AS (col0 double precision, col1 double precision, col2 double precision, col3 character varying, col4 integer);`
 
 	// Name: sys_proc_meminfo; Type: VIEW; Schema: pgcenter
-	StatSchemaCreateView3 = `CREATE OR REPLACE VIEW pgcenter.sys_proc_meminfo AS
+	StatSchemaCreateView3 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_meminfo AS
 SELECT get_proc_stats.col0 AS metric,
 get_proc_stats.col1 AS metric_value,
 get_proc_stats.col2 AS unit
-FROM pgcenter.get_proc_stats('/proc/meminfo'::character varying, ' '::character varying, ''::character varying, 0)
+FROM {{.Schema}}.get_proc_stats('/proc/meminfo'::character varying, ' '::character varying, ''::character varying, 0)
 AS (col0 character varying, col1 bigint, col2 character varying);`
 
 	// Name: sys_proc_netdev; Type: VIEW; Schema: pgcenter
-	StatSchemaCreateView4 = `CREATE OR REPLACE VIEW pgcenter.sys_proc_netdev AS
+	StatSchemaCreateView4 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_netdev AS
 SELECT get_proc_stats.col0 AS iface,
 get_proc_stats.col1 AS recv_bytes,
 get_proc_stats.col2 AS recv_pckts,
@@ -120,11 +201,11 @@ get_proc_stats.col13 AS sent_fifo,
 get_proc_stats.col14 AS sent_colls,
 get_proc_stats.col15 AS sent_carrier,
 get_proc_stats.col16 AS sent_cmpr
-FROM pgcenter.get_proc_stats('/proc/net/dev'::character varying, ' '::character varying, ''::character varying, 2)
+FROM {{.Schema}}.get_proc_stats('/proc/net/dev'::character varying, ' '::character varying, ''::character varying, 2)
 AS (col0 character varying, col1 float, col2 float, col3 float, col4 float, col5 float, col6 float, col7 float, col8 float, col9 float, col10 float, col11 float, col12 float, col13 float, col14 float, col15 float, col16 float)`
 
 	// Name: sys_proc_stat; Type: VIEW; Schema: pgcenter
-	StatSchemaCreateView5 = `CREATE OR REPLACE VIEW pgcenter.sys_proc_stat AS
+	StatSchemaCreateView5 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_stat AS
 SELECT get_proc_stats.col0 AS cpu,
 get_proc_stats.col1 AS us_time,
 get_proc_stats.col2 AS ni_time,
@@ -136,16 +217,47 @@ get_proc_stats.col7 AS si_time,
 get_proc_stats.col8 AS st_time,
 get_proc_stats.col9 AS quest_time,
 get_proc_stats.col10 AS guest_ni_time
-FROM pgcenter.get_proc_stats('/proc/stat'::character varying, ' '::character varying, 'cpu'::character varying, 0)
+FROM {{.Schema}}.get_proc_stats('/proc/stat'::character varying, ' '::character varying, 'cpu'::character varying, 0)
 AS (col0 character varying, col1 bigint, col2 bigint, col3 bigint, col4 bigint, col5 bigint, col6 bigint, col7 bigint, col8 bigint, col9 bigint, col10 bigint);`
 
 	// Name: sys_proc_uptime; Type: VIEW; Schema: pgcenter
-	StatSchemaCreateView6 = `CREATE OR REPLACE VIEW pgcenter.sys_proc_uptime AS
+	StatSchemaCreateView6 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_uptime AS
 SELECT get_proc_stats.col0 AS seconds_total,
 get_proc_stats.col1 AS seconds_idle
-FROM pgcenter.get_proc_stats('/proc/uptime'::character varying, ' '::character varying, ''::character varying, 0)
+FROM {{.Schema}}.get_proc_stats('/proc/uptime'::character varying, ' '::character varying, ''::character varying, 0)
 AS (col0 numeric, col1 numeric);`
 
+	// Restricted mode: functions are executed with privileges of the owner (superuser) and are not available to PUBLIC.
+	// Name: get_netdev_link_settings(character varying); Type: FUNCTION; Schema: pgcenter
+	StatSchemaSecureFunction1 = `ALTER FUNCTION {{.Schema}}.get_netdev_link_settings(character varying) SECURITY DEFINER SET search_path = pg_catalog, pg_temp`
+
+	// Name: get_sys_clk_ticks(); Type: FUNCTION; Schema: pgcenter
+	StatSchemaSecureFunction2 = `ALTER FUNCTION {{.Schema}}.get_sys_clk_ticks() SECURITY DEFINER SET search_path = pg_catalog, pg_temp`
+
+	// Name: get_proc_stats(character varying, character varying, character varying, integer); Type: FUNCTION; Schema: pgcenter
+	StatSchemaSecureFunction3 = `ALTER FUNCTION {{.Schema}}.get_proc_stats(character varying, character varying, character varying, integer) SECURITY DEFINER SET search_path = pg_catalog, pg_temp`
+
+	// Name: get_netdevs_link_settings(); Type: FUNCTION; Schema: pgcenter
+	StatSchemaSecureFunction4 = `ALTER FUNCTION {{.Schema}}.get_netdevs_link_settings() SECURITY DEFINER SET search_path = pg_catalog, pg_temp`
+
+	// Name: pgcenter; Type: ACL; Schema: -
+	StatSchemaRevokeFunctions = `REVOKE ALL ON ALL FUNCTIONS IN SCHEMA {{.Schema}} FROM PUBLIC`
+
+	// Name: get_schema_version(); Type: ACL; Schema: pgcenter
+	StatSchemaGrantVersionFunction = `GRANT EXECUTE ON FUNCTION {{.Schema}}.get_schema_version() TO PUBLIC`
+
+	// Name: pgcenter; Type: ACL; Schema: -
+	StatSchemaGrantSchema = `GRANT USAGE ON SCHEMA {{.Schema}} TO {{.Role}}`
+
+	// Name: pgcenter; Type: ACL; Schema: -
+	StatSchemaGrantFunctions = `GRANT EXECUTE ON ALL FUNCTIONS IN SCHEMA {{.Schema}} TO {{.Role}}`
+
+	// Name: pgcenter; Type: ACL; Schema: -
+	StatSchemaGrantViews = `GRANT SELECT ON ALL TABLES IN SCHEMA {{.Schema}} TO {{.Role}}`
+
 	// Name: pgcenter; Type: SCHEMA; Schema: -
-	StatSchemaDropSchema = "DROP SCHEMA IF EXISTS pgcenter CASCADE"
+	StatSchemaDropSchema = "DROP SCHEMA IF EXISTS {{.Schema}} CASCADE"
+
+	// SelectStatSchemaVersion queries version of installed stats schema.
+	SelectStatSchemaVersion = "SELECT {{.Schema}}.get_schema_version()"
 )
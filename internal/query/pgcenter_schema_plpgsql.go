@@ -0,0 +1,144 @@
+package query
+
+// pgCenter statistics schema, PL/pgSQL flavor.
+// This flavor doesn't depend on untrusted PL/Perl and reads stats files using pg_read_file() function. Functions and
+// views have the same names and return the same columns as functions and views of the default plperlu flavor.
+// Reading files from /proc using pg_read_file() requires Postgres 13 or newer.
+
+const (
+	// Name: get_netdev_link_settings(character varying); Type: FUNCTION; Schema: pgcenter
+	StatSchemaPlpgsqlCreateFunction1 = `CREATE OR REPLACE FUNCTION {{.Schema}}.get_netdev_link_settings(INOUT iface CHARACTER VARYING, OUT speed BIGINT, OUT duplex INTEGER) RETURNS RECORD
+LANGUAGE plpgsql
+AS $$
+BEGIN
+	IF iface !~ '^[A-Za-z0-9_.:@-]+$' THEN
+		RAISE EXCEPTION 'pgcenter: invalid interface name %', iface;
+	END IF;
+	speed := trim(pg_read_file('/sys/class/net/' || iface || '/speed'))::bigint;
+	duplex := CASE trim(pg_read_file('/sys/class/net/' || iface || '/duplex')) WHEN 'full' THEN 1 ELSE 0 END;
+EXCEPTION
+	WHEN OTHERS THEN
+		speed := 0;
+		duplex := -1;
+END;
+$$;`
+
+	// Name: get_sys_clk_ticks(); Type: FUNCTION; Schema: pgcenter
+	// Clock ticks can't be obtained using SQL, use USER_HZ value used by the most of Linux systems.
+	StatSchemaPlpgsqlCreateFunction2 = `CREATE OR REPLACE FUNCTION {{.Schema}}.get_sys_clk_ticks() RETURNS integer
+LANGUAGE sql
+AS $$
+SELECT 100;
+$$;`
+
+	// Name: get_proc_lines(character varying, integer, character varying); Type: FUNCTION; Schema: pgcenter
+	StatSchemaPlpgsqlCreateFunction3 = `CREATE OR REPLACE FUNCTION {{.Schema}}.get_proc_lines(character varying, integer, character varying) RETURNS SETOF text[]
+LANGUAGE plpgsql
+AS $$
+BEGIN
+	-- allow reading only files used by pgcenter.
+	IF $1 !~ '^/proc/(diskstats|loadavg|meminfo|net/dev|stat|uptime)$' THEN
+		RAISE EXCEPTION 'pgcenter: reading % is not allowed', $1;
+	END IF;
+	-- skip header if required, use filter if required.
+	RETURN QUERY
+	SELECT regexp_split_to_array(trim(regexp_replace(t.line, ':', ': ')), '\s+')
+	FROM unnest(string_to_array(pg_read_file($1), E'\n')) WITH ORDINALITY AS t(line, n)
+	WHERE t.n > $2 AND trim(t.line) <> '' AND ($3 = '' OR split_part(trim(t.line), ' ', 1) ~ $3);
+END;
+$$;`
+
+	// Name: get_netdevs_link_settings(); Type: FUNCTION; Schema: pgcenter
+	StatSchemaPlpgsqlCreateFunction4 = `CREATE OR REPLACE FUNCTION {{.Schema}}.get_netdevs_link_settings(OUT iface CHARACTER VARYING, OUT speed BIGINT, OUT duplex INTEGER) RETURNS SETOF RECORD
+LANGUAGE sql
+AS $$
+SELECT s.iface, s.speed, s.duplex
+FROM {{.Schema}}.get_proc_lines('/proc/net/dev'::character varying, 2, ''::character varying) AS l,
+LATERAL {{.Schema}}.get_netdev_link_settings(rtrim(l[1], ':')::character varying) AS s;
+$$;`
+
+	// Name: sys_proc_diskstats; Type: VIEW; Schema: pgcenter
+	StatSchemaPlpgsqlCreateView1 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_diskstats AS
+SELECT l[1]::integer AS maj,
+l[2]::integer AS min,
+l[3]::character varying AS dev,
+l[4]::double precision AS reads,
+l[5]::double precision AS rmerges,
+l[6]::double precision AS rsects,
+l[7]::double precision AS rspent,
+l[8]::double precision AS writes,
+l[9]::double precision AS wmerges,
+l[10]::double precision AS wsects,
+l[11]::double precision AS wspent,
+l[12]::double precision AS inprog,
+l[13]::double precision AS spent,
+l[14]::double precision AS weighted,
+COALESCE(l[15]::double precision, (0)::double precision) AS discards,
+COALESCE(l[16]::double precision, (0)::double precision) AS dmerges,
+COALESCE(l[17]::double precision, (0)::double precision) AS dsectors,
+COALESCE(l[18]::double precision, (0)::double precision) AS dspent,
+COALESCE(l[19]::double precision, (0)::double precision) AS flushes,
+COALESCE(l[20]::double precision, (0)::double precision) AS fspent
+FROM {{.Schema}}.get_proc_lines('/proc/diskstats'::character varying, 0, ''::character varying) AS l;`
+
+	// Name: sys_proc_loadavg; Type: VIEW; Schema: pgcenter
+	StatSchemaPlpgsqlCreateView2 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_loadavg AS
+SELECT l[1]::double precision AS min1,
+l[2]::double precision AS min5,
+l[3]::double precision AS min15,
+l[4]::character varying AS procnum,
+l[5]::integer AS last_pid
+FROM {{.Schema}}.get_proc_lines('/proc/loadavg'::character varying, 0, ''::character varying) AS l;`
+
+	// Name: sys_proc_meminfo; Type: VIEW; Schema: pgcenter
+	StatSchemaPlpgsqlCreateView3 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_meminfo AS
+SELECT l[1]::character varying AS metric,
+l[2]::bigint AS metric_value,
+l[3]::character varying AS unit
+FROM {{.Schema}}.get_proc_lines('/proc/meminfo'::character varying, 0, ''::character varying) AS l;`
+
+	// Name: sys_proc_netdev; Type: VIEW; Schema: pgcenter
+	StatSchemaPlpgsqlCreateView4 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_netdev AS
+SELECT l[1]::character varying AS iface,
+l[2]::float AS recv_bytes,
+l[3]::float AS recv_pckts,
+l[4]::float AS recv_err,
+l[5]::float AS recv_drop,
+l[6]::float AS recv_fifo,
+l[7]::float AS recv_frame,
+l[8]::float AS recv_cmpr,
+l[9]::float AS recv_mcast,
+l[10]::float AS sent_bytes,
+l[11]::float AS sent_pckts,
+l[12]::float AS sent_err,
+l[13]::float AS sent_drop,
+l[14]::float AS sent_fifo,
+l[15]::float AS sent_colls,
+l[16]::float AS sent_carrier,
+l[17]::float AS sent_cmpr
+FROM {{.Schema}}.get_proc_lines('/proc/net/dev'::character varying, 2, ''::character varying) AS l;`
+
+	// Name: sys_proc_stat; Type: VIEW; Schema: pgcenter
+	StatSchemaPlpgsqlCreateView5 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_stat AS
+SELECT l[1]::character varying AS cpu,
+l[2]::bigint AS us_time,
+l[3]::bigint AS ni_time,
+l[4]::bigint AS sy_time,
+l[5]::bigint AS id_time,
+l[6]::bigint AS wa_time,
+l[7]::bigint AS hi_time,
+l[8]::bigint AS si_time,
+l[9]::bigint AS st_time,
+l[10]::bigint AS quest_time,
+l[11]::bigint AS guest_ni_time
+FROM {{.Schema}}.get_proc_lines('/proc/stat'::character varying, 0, 'cpu'::character varying) AS l;`
+
+	// Name: sys_proc_uptime; Type: VIEW; Schema: pgcenter
+	StatSchemaPlpgsqlCreateView6 = `CREATE OR REPLACE VIEW {{.Schema}}.sys_proc_uptime AS
+SELECT l[1]::numeric AS seconds_total,
+l[2]::numeric AS seconds_idle
+FROM {{.Schema}}.get_proc_lines('/proc/uptime'::character varying, 0, ''::character varying) AS l;`
+
+	// Name: get_proc_lines(character varying, integer, character varying); Type: FUNCTION; Schema: pgcenter
+	StatSchemaPlpgsqlSecureFunction3 = `ALTER FUNCTION {{.Schema}}.get_proc_lines(character varying, integer, character varying) SECURITY DEFINER SET search_path = pg_catalog, pg_temp`
+)
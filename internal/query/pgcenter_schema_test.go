@@ -0,0 +1,42 @@
+package query
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewSchemaOptions(t *testing.T) {
+	testcases := []struct {
+		schema string
+		role   string
+		want   SchemaOptions
+	}{
+		{want: SchemaOptions{Version: StatSchemaVersion, Schema: "pgcenter"}},
+		{schema: "monitoring", role: "monitor", want: SchemaOptions{Version: StatSchemaVersion, Schema: "monitoring", Role: `"monitor"`}},
+		{schema: "Monitoring", want: SchemaOptions{Version: StatSchemaVersion, Schema: `"Monitoring"`}},
+		{schema: `mon"itoring`, want: SchemaOptions{Version: StatSchemaVersion, Schema: `"mon""itoring"`}},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, NewSchemaOptions(tc.schema, tc.role))
+	}
+}
+
+func TestFormatSchema(t *testing.T) {
+	testcases := []struct {
+		tmpl string
+		opts SchemaOptions
+		want string
+	}{
+		{tmpl: StatSchemaCreateSchema, opts: NewSchemaOptions("", ""), want: "CREATE SCHEMA IF NOT EXISTS pgcenter"},
+		{tmpl: StatSchemaDropSchema, opts: NewSchemaOptions("monitoring", ""), want: "DROP SCHEMA IF EXISTS monitoring CASCADE"},
+		{tmpl: StatSchemaGrantSchema, opts: NewSchemaOptions("monitoring", "monitor"), want: `GRANT USAGE ON SCHEMA monitoring TO "monitor"`},
+		{tmpl: SelectStatSchemaVersion, opts: NewSchemaOptions("Monitoring", ""), want: `SELECT "Monitoring".get_schema_version()`},
+	}
+
+	for _, tc := range testcases {
+		got, err := FormatSchema(tc.tmpl, tc.opts)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+}
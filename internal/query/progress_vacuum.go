@@ -2,14 +2,38 @@ package query
 
 const (
 	// PgStatProgressVacuumDefault is the default query for getting stats from pg_stat_progress_vacuum view
-	// { Name: "pg_stat_vacuum", Query: common.PgStatVacuumQueryDefault, DiffIntvl: [2]int{10,11}, Ncols: 13, OrderKey: 0, OrderDesc: true }
+	// { Name: "pg_stat_vacuum", Query: common.PgStatVacuumQueryDefault, DiffIntvl: [2]int{10,11}, Ncols: 18, OrderKey: 0, OrderDesc: true }
+	// Columns 'pages' and 'dead_tup' contain counters which are converted to rates, 'eta' is estimated using rate of pages
+	// and number of pages left until the current phase is finished.
 	PgStatProgressVacuumDefault = "SELECT a.pid, date_trunc('seconds', clock_timestamp() - xact_start)::text AS xact_age, " +
 		"v.datname, v.relid::regclass AS relation, a.state, coalesce((a.wait_event_type ||'.'|| a.wait_event), 'f') AS waiting, " +
 		"v.phase, v.heap_blks_total * (SELECT current_setting('block_size')::int / 1024) AS t_size, " +
 		`round(100 * v.heap_blks_scanned / v.heap_blks_total, 2)::text AS "t_scanned_%", ` +
 		`round(100 * v.heap_blks_vacuumed / v.heap_blks_total, 2)::text AS "t_vacuumed_%", ` +
 		"coalesce(v.heap_blks_scanned * (SELECT current_setting('block_size')::int / 1024), 0) AS scanned, " +
-		"coalesce(v.heap_blks_vacuumed * (SELECT current_setting('block_size')::int / 1024), 0) AS vacuumed, a.query " +
+		"coalesce(v.heap_blks_vacuumed * (SELECT current_setting('block_size')::int / 1024), 0) AS vacuumed, " +
+		"v.index_vacuum_count AS idx_cycles, " +
+		"CASE v.phase WHEN 'scanning heap' THEN v.heap_blks_scanned WHEN 'vacuuming heap' THEN v.heap_blks_vacuumed END AS pages, " +
+		"CASE v.phase WHEN 'scanning heap' THEN v.heap_blks_total - v.heap_blks_scanned " +
+		"WHEN 'vacuuming heap' THEN v.heap_blks_total - v.heap_blks_vacuumed END AS pages_left, " +
+		"v.num_dead_item_ids AS dead_tup, NULL::text AS eta, a.query " +
+		"FROM pg_stat_progress_vacuum v RIGHT JOIN pg_stat_activity a ON v.pid = a.pid " +
+		"WHERE (a.query ~* '^autovacuum:' OR a.query ~* '^vacuum') AND a.pid <> pg_backend_pid() ORDER BY a.pid DESC"
+
+	// PgStatProgressVacuumPG16 is the query for getting stats from pg_stat_progress_vacuum view for Postgres 9.6-16.
+	// { Name: "pg_stat_vacuum", Query: common.PgStatVacuumQueryDefault, DiffIntvl: [2]int{10,11}, Ncols: 18, OrderKey: 0, OrderDesc: true }
+	PgStatProgressVacuumPG16 = "SELECT a.pid, date_trunc('seconds', clock_timestamp() - xact_start)::text AS xact_age, " +
+		"v.datname, v.relid::regclass AS relation, a.state, coalesce((a.wait_event_type ||'.'|| a.wait_event), 'f') AS waiting, " +
+		"v.phase, v.heap_blks_total * (SELECT current_setting('block_size')::int / 1024) AS t_size, " +
+		`round(100 * v.heap_blks_scanned / v.heap_blks_total, 2)::text AS "t_scanned_%", ` +
+		`round(100 * v.heap_blks_vacuumed / v.heap_blks_total, 2)::text AS "t_vacuumed_%", ` +
+		"coalesce(v.heap_blks_scanned * (SELECT current_setting('block_size')::int / 1024), 0) AS scanned, " +
+		"coalesce(v.heap_blks_vacuumed * (SELECT current_setting('block_size')::int / 1024), 0) AS vacuumed, " +
+		"v.index_vacuum_count AS idx_cycles, " +
+		"CASE v.phase WHEN 'scanning heap' THEN v.heap_blks_scanned WHEN 'vacuuming heap' THEN v.heap_blks_vacuumed END AS pages, " +
+		"CASE v.phase WHEN 'scanning heap' THEN v.heap_blks_total - v.heap_blks_scanned " +
+		"WHEN 'vacuuming heap' THEN v.heap_blks_total - v.heap_blks_vacuumed END AS pages_left, " +
+		"v.num_dead_tuples AS dead_tup, NULL::text AS eta, a.query " +
 		"FROM pg_stat_progress_vacuum v RIGHT JOIN pg_stat_activity a ON v.pid = a.pid " +
 		"WHERE (a.query ~* '^autovacuum:' OR a.query ~* '^vacuum') AND a.pid <> pg_backend_pid() ORDER BY a.pid DESC"
 )
@@ -7,15 +7,32 @@ import (
 	"testing"
 )
 
+func TestSelect_progress_vacuum(t *testing.T) {
+	testcases := []struct {
+		version int
+		want    string
+	}{
+		{version: 90600, want: PgStatProgressVacuumPG16},
+		{version: 160000, want: PgStatProgressVacuumPG16},
+		{version: 170000, want: PgStatProgressVacuumDefault},
+	}
+
+	for _, tc := range testcases {
+		got, ok := Select("progress_vacuum", Options{Version: tc.version})
+		assert.True(t, ok)
+		assert.Equal(t, tc.want, got.Query)
+	}
+}
+
 func Test_StatProgressVacuumQueries(t *testing.T) {
 	versions := []int{90600, 100000, 110000, 120000, 130000}
 
 	for _, version := range versions {
 		t.Run(fmt.Sprintf("pg_stat_progress_vacuum/%d", version), func(t *testing.T) {
-			tmpl := PgStatProgressVacuumDefault
+			tmpl := PgStatProgressVacuumPG16
 
 			opts := NewOptions(version, "f", "off", 256)
-			q, err := Format(tmpl, opts)
+			q, _, err := Format(tmpl, opts)
 			assert.NoError(t, err)
 
 			conn, err := postgres.NewTestConnectVersion(version)
@@ -3,22 +3,28 @@ package query
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 	"text/template"
 )
 
 // Options contains queries' settings that used depending on user preferences.
 type Options struct {
-	Version          int    // Postgres version (numeric format)
-	Recovery         string // Recovery state
-	GucTrackCommitTS string // Value of track_commit_timestamp GUC
-	ViewType         string // Show stats including system tables/indexes
-	WalFunction1     string // Use old pg_xlog_* or newer pg_wal_* functions
-	WalFunction2     string // Use old pg_xlog_* or newer pg_wal_* functions
-	QueryAgeThresh   string // Show only queries with duration more than specified
-	BackendState     string // Backend state's selector for cancel/terminate function
-	ShowNoIdle       bool   // don't show IDLEs, background workers)
-	PgSSQueryLen     int    // Specify the length of query to show in pg_stat_statements
-	PgSSQueryLenFn   string // Specify exact func to truncating query
+	Version          int      // Postgres version (numeric format)
+	Recovery         string   // Recovery state
+	GucTrackCommitTS string   // Value of track_commit_timestamp GUC
+	ViewType         string   // Show stats including system tables/indexes
+	WalFunction1     string   // Use old pg_xlog_* or newer pg_wal_* functions
+	WalFunction2     string   // Use old pg_xlog_* or newer pg_wal_* functions
+	QueryAgeThresh   string   // Show only queries with duration more than specified
+	BackendState     string   // Backend state's selector for cancel/terminate function
+	OwnBackendsOnly  bool     // cancel/terminate only backends of roles the current role is member of
+	ShowNoIdle       bool     // don't show IDLEs, background workers)
+	PgSSQueryLen     int      // Specify the length of query to show in pg_stat_statements
+	PgSSQueryLenFn   string   // Specify exact func to truncating query
+	PgSSVersion      int      // Version of installed pg_stat_statements, e.g. 110 for 1.10, zero if unknown
+	PostgresFdw      bool     // postgres_fdw extension is installed
+	Databases        []string // Show activity and statements of specified databases only, all if empty
+	Users            []string // Show activity and statements of specified users only, all if empty
 }
 
 // NewOptions creates query options used for queries customization depending on Postgres version and other important settings.
@@ -69,18 +75,52 @@ func selectWalFunctions(version int, recovery string) (string, string) {
 	return fn1, fn2
 }
 
-// Format transforms query's template to a particular query.
-func Format(tmpl string, o Options) (string, error) {
-	t, err := template.New("query").Parse(tmpl)
+// Format transforms query's template to a particular query. Values passed to 'param' function of the template (e.g.
+// filters of databases and users) are not pasted into the query, they are replaced with placeholders and returned as
+// arguments of the query in order of placeholders.
+func Format(tmpl string, o Options) (string, []interface{}, error) {
+	var args []interface{}
+	param := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	q, err := format(tmpl, o, template.FuncMap{"param": param})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return q, args, nil
+}
+
+// format executes query's template using passed data.
+func format(tmpl string, data interface{}, funcs template.FuncMap) (string, error) {
+	t, err := template.New("query").Funcs(funcs).Parse(tmpl)
 	if err != nil {
 		return "", err
 	}
 
 	buf := &bytes.Buffer{}
-	err = t.Execute(buf, o)
+	err = t.Execute(buf, data)
 	if err != nil {
 		return "", err
 	}
 
 	return buf.String(), nil
 }
+
+// Limit wraps query into a query which returns only first 'limit' rows ordered by the column with specified index
+// (zero-based). Rows are ordered using values of the column as they are returned by the query. Rows with equal values
+// are ordered by unique key column, hence the same rows are returned by subsequent queries when values are tied.
+func Limit(q string, key int, desc bool, ukey int, limit int) string {
+	order := "ASC"
+	if desc {
+		order = "DESC"
+	}
+
+	if ukey == key {
+		return fmt.Sprintf("SELECT * FROM (%s) AS l ORDER BY %d %s NULLS LAST LIMIT %d", q, key+1, order, limit)
+	}
+
+	return fmt.Sprintf("SELECT * FROM (%s) AS l ORDER BY %d %s NULLS LAST, %d LIMIT %d", q, key+1, order, ukey+1, limit)
+}
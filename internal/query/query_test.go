@@ -2,6 +2,7 @@ package query
 
 import (
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -10,7 +11,7 @@ func TestFormat(t *testing.T) {
 		WalFunction1: "pg_wal_lsn_diff",
 		WalFunction2: "pg_current_wal_lsn",
 	}
-	got, err := Format(PgStatReplicationDefault, opts)
+	got, _, err := Format(PgStatReplicationDefault, opts)
 	assert.NoError(t, err)
 	assert.Equal(
 		t,
@@ -18,10 +19,89 @@ func TestFormat(t *testing.T) {
 		got,
 	)
 
-	_, err = Format("{{", opts)
+	_, _, err = Format("{{", opts)
 	assert.Error(t, err)
 
-	_, err = Format("{{ .Invalid }}", opts)
+	_, _, err = Format("{{ .Invalid }}", opts)
+	assert.Error(t, err)
+}
+
+func TestFormat_filters(t *testing.T) {
+	opts := NewOptions(130000, "f", "off", 256)
+
+	// No filters.
+	got, _, err := Format(PgStatActivityDefault, opts)
+	assert.NoError(t, err)
+	assert.NotContains(t, got, "datname = ANY")
+	assert.NotContains(t, got, "usename = ANY")
+
+	got, _, err = Format(PgStatStatementsGeneralDefault, opts)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(got, "FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid"))
+
+	// Filters are pushed down into queries as parameters, values are never pasted into queries.
+	opts.Databases, opts.Users = []string{"shop", "o'brien"}, []string{`a\'pp`}
+
+	got, _, err = Format(PgStatActivityDefault, opts)
+	assert.NoError(t, err)
+	assert.Contains(t, got, "AND state != 'idle'  AND datname = ANY($1::text[]) AND usename = ANY($2::text[]) ORDER BY pid DESC")
+	assert.NotContains(t, got, "brien")
+	assert.NotContains(t, got, `a\'pp`)
+
+	got, _, err = Format(PgStatStatementsGeneralDefault, opts)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(got, "FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid AND d.datname = ANY($1::text[]) "+
+		"AND p.userid IN (SELECT oid FROM pg_roles WHERE rolname = ANY($2::text[]))"))
+
+	got, _, err = Format(PgStatStatementsParallelDefault, opts)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(got, "AND p.userid IN (SELECT oid FROM pg_roles WHERE rolname = ANY($2::text[])) WHERE p.parallel_workers_to_launch > 0"))
+
+	// Users are the first parameter when databases are not filtered.
+	opts.Databases = nil
+
+	got, _, err = Format(PgStatActivityDefault, opts)
+	assert.NoError(t, err)
+	assert.Contains(t, got, "AND state != 'idle'  AND usename = ANY($1::text[]) ORDER BY pid DESC")
+	assert.NotContains(t, got, "datname = ANY")
+}
+
+func TestFormat_params(t *testing.T) {
+	databases, users := []string{"shop", "o'brien"}, []string{`a\'pp`}
+
+	testcases := []struct {
+		tmpl     string
+		opts     Options
+		want     string
+		wantArgs []interface{}
+	}{
+		{tmpl: PgStatDatabaseDefault, opts: Options{Databases: databases, Users: users}, wantArgs: nil},
+		{tmpl: "{{/* .Databases */}}SELECT 1", opts: Options{Databases: databases}, want: "SELECT 1", wantArgs: nil},
+		{
+			tmpl: "SELECT 1{{ if .Users }} WHERE u = ANY({{ param .Users }}){{ end }}{{ if .Databases }} AND d = ANY({{ param .Databases }}){{ end }}",
+			opts: Options{Databases: databases, Users: users}, want: "SELECT 1 WHERE u = ANY($1) AND d = ANY($2)",
+			wantArgs: []interface{}{users, databases},
+		},
+		{
+			tmpl: "SELECT 1{{ if .Users }} WHERE u = ANY({{ param .Users }}){{ end }}{{ if .Databases }} AND d = ANY({{ param .Databases }}){{ end }}",
+			opts: Options{Databases: databases}, want: "SELECT 1 AND d = ANY($1)", wantArgs: []interface{}{databases},
+		},
+		{tmpl: PgStatActivityDefault, opts: Options{}, wantArgs: nil},
+		{tmpl: PgStatActivityDefault, opts: Options{Databases: databases, Users: users}, wantArgs: []interface{}{databases, users}},
+		{tmpl: PgStatStatementsGeneralDefault, opts: Options{Users: users}, wantArgs: []interface{}{users}},
+	}
+
+	for _, tc := range testcases {
+		got, args, err := Format(tc.tmpl, tc.opts)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.wantArgs, args)
+		if tc.want != "" {
+			assert.Equal(t, tc.want, got)
+		}
+	}
+
+	// Parameters are not supported in templates of stats schema.
+	_, err := FormatSchema("{{ param .Schema }}", SchemaOptions{Schema: "pgcenter"})
 	assert.Error(t, err)
 }
 
@@ -98,3 +178,18 @@ func Test_selectWalFunctions(t *testing.T) {
 		assert.Equal(t, tc.want2, fn2)
 	}
 }
+
+func TestLimit(t *testing.T) {
+	assert.Equal(t,
+		"SELECT * FROM (SELECT a, b FROM t) AS l ORDER BY 2 DESC NULLS LAST, 1 LIMIT 50",
+		Limit("SELECT a, b FROM t", 1, true, 0, 50),
+	)
+	assert.Equal(t,
+		"SELECT * FROM (SELECT a, b FROM t) AS l ORDER BY 1 ASC NULLS LAST LIMIT 10",
+		Limit("SELECT a, b FROM t", 0, false, 0, 10),
+	)
+	assert.Equal(t,
+		"SELECT * FROM (SELECT a, b, c FROM t) AS l ORDER BY 1 ASC NULLS LAST, 3 LIMIT 10",
+		Limit("SELECT a, b, c FROM t", 0, false, 2, 10),
+	)
+}
@@ -0,0 +1,197 @@
+package query
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Variant defines query used for particular versions of Postgres and its extensions.
+type Variant struct {
+	MinVersion     int    // minimal version of Postgres, zero means any version
+	MinPgSSVersion int    // minimal version of pg_stat_statements (e.g. 108 for 1.8), MinVersion is used if installed version is unknown
+	TrackCommitTS  bool   // query requires enabled track_commit_timestamp
+	PostgresFdw    bool   // query requires installed postgres_fdw extension
+	Query          string // query template
+	Ncols          int    // number of columns returned by query
+	DiffIntvl      [2]int // range of columns which values are diffed
+}
+
+// registry defines queries of stats views keyed by view name. Variants are ordered from the newest to the oldest, the
+// first suitable variant is used. Views which are available since particular version (e.g. pg_stat_io since Postgres 16)
+// don't have variants for older versions.
+var registry = map[string][]Variant{
+	"activity": {
+		{MinVersion: 100000, Query: PgStatActivityDefault, Ncols: 14},
+		{MinVersion: 90600, Query: PgStatActivity96, Ncols: 13},
+		{Query: PgStatActivity95, Ncols: 12},
+	},
+	"activity_samples": {
+		{MinVersion: 100000, Query: PgStatActivitySamplesDefault, Ncols: 4},
+		{MinVersion: 90600, Query: PgStatActivitySamplesPG96, Ncols: 4},
+		{Query: PgStatActivitySamplesPG95, Ncols: 4},
+	},
+	"replication": {
+		{MinVersion: 100000, TrackCommitTS: true, Query: PgStatReplicationExtended, Ncols: 17, DiffIntvl: [2]int{6, 6}},
+		{MinVersion: 100000, Query: PgStatReplicationDefault, Ncols: 15, DiffIntvl: [2]int{6, 6}},
+		{TrackCommitTS: true, Query: PgStatReplication96Extended, Ncols: 14, DiffIntvl: [2]int{6, 6}},
+		{Query: PgStatReplication96, Ncols: 12, DiffIntvl: [2]int{6, 6}},
+	},
+	"replication_slots": {
+		{MinVersion: 140000, Query: PgReplicationSlotsDefault, Ncols: 11, DiffIntvl: [2]int{6, 10}},
+		{Query: PgReplicationSlotsPG13, Ncols: 6},
+	},
+	"databases": {
+		{MinVersion: 140000, Query: PgStatDatabaseDefault, Ncols: 25, DiffIntvl: [2]int{1, 16}},
+		{MinVersion: 120000, Query: PgStatDatabasePG13, Ncols: 21, DiffIntvl: [2]int{1, 16}},
+		{Query: PgStatDatabasePG11, Ncols: 20, DiffIntvl: [2]int{1, 15}},
+	},
+	"tables": {
+		{Query: PgStatTablesDefault, Ncols: 19, DiffIntvl: [2]int{1, 18}},
+	},
+	"tables_io": {
+		{Query: PgStatTablesIODefault, Ncols: 13, DiffIntvl: [2]int{1, 9}},
+	},
+	"indexes": {
+		{Query: PgStatIndexesDefault, Ncols: 6, DiffIntvl: [2]int{1, 5}},
+	},
+	"index_advisor": {
+		{Query: PgIndexAdvisorDefault, Ncols: 7, DiffIntvl: [2]int{3, 4}},
+	},
+	"stale_stats": {
+		{Query: PgStaleStatsDefault, Ncols: 8},
+	},
+	"sizes": {
+		{Query: PgTablesSizesDefault, Ncols: 7, DiffIntvl: [2]int{4, 6}},
+	},
+	"fdw": {
+		{MinVersion: 140000, PostgresFdw: true, Query: PgForeignServersDefault, Ncols: 9},
+		{Query: PgForeignServersNoConns, Ncols: 9},
+	},
+	"functions": {
+		{Query: PgStatFunctionsDefault, Ncols: 8, DiffIntvl: [2]int{3, 3}},
+	},
+	"roles": {
+		{MinVersion: 100000, Query: PgRolesDefault, Ncols: 10},
+		{Query: PgRolesPG96, Ncols: 10},
+	},
+	"checkpoints": {
+		{MinVersion: 170000, Query: PgStatCheckpointsDefault, Ncols: 9},
+		{MinVersion: 90600, Query: PgStatCheckpointsPG16, Ncols: 9},
+	},
+	"statements_timings": {
+		{MinVersion: 170000, MinPgSSVersion: 111, Query: PgStatStatementsTimingDefault, Ncols: 13, DiffIntvl: [2]int{6, 10}},
+		{MinVersion: 130000, MinPgSSVersion: 108, Query: PgStatStatementsTimingPG16, Ncols: 13, DiffIntvl: [2]int{6, 10}},
+		{Query: PgStatStatementsTimingPG12, Ncols: 13, DiffIntvl: [2]int{6, 10}},
+	},
+	"statements_general": {
+		{Query: PgStatStatementsGeneralDefault, Ncols: 8, DiffIntvl: [2]int{4, 5}},
+	},
+	"statements_io": {
+		{Query: PgStatStatementsIoDefault, Ncols: 13, DiffIntvl: [2]int{6, 10}},
+	},
+	"statements_temp": {
+		{Query: PgStatStatementsTempDefault, Ncols: 9, DiffIntvl: [2]int{4, 6}},
+	},
+	"statements_local": {
+		{Query: PgStatStatementsLocalDefault, Ncols: 13, DiffIntvl: [2]int{6, 10}},
+	},
+	"statements_parallel": {
+		{MinVersion: 180000, MinPgSSVersion: 112, Query: PgStatStatementsParallelDefault, Ncols: 10, DiffIntvl: [2]int{4, 7}},
+	},
+	"statements_jit": {
+		{MinVersion: 170000, MinPgSSVersion: 111, Query: PgStatStatementsJitDefault, Ncols: 16, DiffIntvl: [2]int{5, 13}},
+		{MinVersion: 150000, MinPgSSVersion: 110, Query: PgStatStatementsJitPG16, Ncols: 16, DiffIntvl: [2]int{5, 13}},
+	},
+	"statements_latency": {
+		{MinVersion: 170000, MinPgSSVersion: 111, Query: PgStatStatementsLatencyDefault, Ncols: 13, DiffIntvl: [2]int{6, 10}},
+		{Query: PgStatStatementsLatencyPG16, Ncols: 13, DiffIntvl: [2]int{6, 10}},
+	},
+	"statements_report": {
+		{MinVersion: 170000, MinPgSSVersion: 111, Query: PgStatStatementsReportQueryDefault},
+		{MinVersion: 130000, MinPgSSVersion: 108, Query: PgStatStatementsReportQueryPG16},
+		{Query: PgStatStatementsReportQueryPG12},
+	},
+	"parallel": {
+		{MinVersion: 130000, Query: PgStatParallelDefault, Ncols: 9},
+	},
+	"progress_vacuum": {
+		{MinVersion: 170000, Query: PgStatProgressVacuumDefault, Ncols: 18, DiffIntvl: [2]int{10, 11}},
+		{MinVersion: 90600, Query: PgStatProgressVacuumPG16, Ncols: 18, DiffIntvl: [2]int{10, 11}},
+	},
+	"progress_cluster": {
+		{MinVersion: 120000, Query: PgStatProgressClusterDefault, Ncols: 13, DiffIntvl: [2]int{10, 11}},
+	},
+	"progress_index": {
+		{MinVersion: 120000, Query: PgStatProgressCreateIndexDefault, Ncols: 19},
+	},
+}
+
+// Select returns variant of the query suitable for Postgres described by options. False is returned if query is
+// unknown or not supported by Postgres.
+func Select(name string, opts Options) (Variant, bool) {
+	for _, v := range registry[name] {
+		if v.suitable(opts) {
+			return v, true
+		}
+	}
+	return Variant{}, false
+}
+
+// MinVersion returns the minimal version of Postgres supported by registered query. Zero is returned if query is
+// supported by any version or unknown.
+func MinVersion(name string) int {
+	res := 0
+	for i, v := range registry[name] {
+		if i == 0 || v.MinVersion < res {
+			res = v.MinVersion
+		}
+	}
+	return res
+}
+
+// Registered returns names of all registered queries.
+func Registered() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// suitable returns true if variant could be used with Postgres described by options.
+func (v Variant) suitable(opts Options) bool {
+	if v.TrackCommitTS && opts.GucTrackCommitTS != "on" {
+		return false
+	}
+
+	if v.PostgresFdw && !opts.PostgresFdw {
+		return false
+	}
+
+	// Set of pg_stat_statements columns depends on version of installed extension, it might be older than version
+	// shipped with Postgres, e.g. when extension has not been updated after upgrade of Postgres.
+	if v.MinPgSSVersion > 0 && opts.PgSSVersion > 0 {
+		return opts.PgSSVersion >= v.MinPgSSVersion
+	}
+
+	return opts.Version >= v.MinVersion
+}
+
+// ParseExtensionVersion returns numeric representation of extension version, e.g. 110 for '1.10'. Zero is returned if
+// version could not be parsed.
+func ParseExtensionVersion(s string) int {
+	parts := strings.SplitN(s, ".", 3)
+	if len(parts) < 2 {
+		return 0
+	}
+
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || minor > 99 {
+		return 0
+	}
+
+	return major*100 + minor
+}
@@ -0,0 +1,189 @@
+package query
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// shippedPgSSVersions defines versions of pg_stat_statements shipped with Postgres versions.
+var shippedPgSSVersions = map[int]int{
+	90600: 104, 100000: 105, 110000: 106, 120000: 107, 130000: 108, 140000: 109, 150000: 110, 160000: 110, 170000: 111,
+}
+
+func TestSelect(t *testing.T) {
+	testcases := []struct {
+		name string
+		opts Options
+		want string
+		ok   bool
+	}{
+		{name: "activity", opts: Options{Version: 170000}, want: PgStatActivityDefault, ok: true},
+		{name: "progress_cluster", opts: Options{Version: 120000}, want: PgStatProgressClusterDefault, ok: true},
+		{name: "progress_cluster", opts: Options{Version: 110000}, ok: false},
+		{name: "progress_vacuum", opts: Options{Version: 90500}, ok: false},
+		{name: "unknown", opts: Options{Version: 130000}, ok: false},
+		// Version of installed extension takes precedence over version of Postgres.
+		{name: "statements_timings", opts: Options{Version: 130000, PgSSVersion: 107}, want: PgStatStatementsTimingPG12, ok: true},
+		{name: "statements_timings", opts: Options{Version: 120000, PgSSVersion: 108}, want: PgStatStatementsTimingPG16, ok: true},
+		// Connections of postgres_fdw are available since Postgres 14 with installed extension.
+		{name: "fdw", opts: Options{Version: 140000, PostgresFdw: true}, want: PgForeignServersDefault, ok: true},
+		{name: "fdw", opts: Options{Version: 140000}, want: PgForeignServersNoConns, ok: true},
+		{name: "fdw", opts: Options{Version: 130000, PostgresFdw: true}, want: PgForeignServersNoConns, ok: true},
+		// Stats of logical decoding are available since Postgres 14.
+		{name: "replication_slots", opts: Options{Version: 140000}, want: PgReplicationSlotsDefault, ok: true},
+		{name: "replication_slots", opts: Options{Version: 130000}, want: PgReplicationSlotsPG13, ok: true},
+	}
+
+	for _, tc := range testcases {
+		got, ok := Select(tc.name, tc.opts)
+		assert.Equal(t, tc.ok, ok)
+		assert.Equal(t, tc.want, got.Query)
+	}
+}
+
+func TestMinVersion(t *testing.T) {
+	assert.Equal(t, 0, MinVersion("activity"))
+	assert.Equal(t, 90600, MinVersion("checkpoints"))
+	assert.Equal(t, 120000, MinVersion("progress_index"))
+	assert.Equal(t, 0, MinVersion("unknown"))
+}
+
+func TestRegistered(t *testing.T) {
+	got := Registered()
+	assert.Contains(t, got, "activity")
+	assert.Contains(t, got, "statements_report")
+	assert.Len(t, got, len(registry))
+}
+
+// Test_registryMatrix checks queries of all views are selected and built for all supported versions of Postgres, and
+// number of columns in queries matches number of columns in registry.
+func Test_registryMatrix(t *testing.T) {
+	versions := []int{90600, 100000, 110000, 120000, 130000, 140000, 150000, 160000, 170000}
+
+	for _, version := range versions {
+		for _, track := range []string{"off", "on"} {
+			for _, pgss := range []int{0, shippedPgSSVersions[version]} {
+				opts := NewOptions(version, "f", track, 256)
+				opts.PgSSVersion = pgss
+
+				for _, name := range Registered() {
+					v, ok := Select(name, opts)
+					if !ok {
+						// Only views introduced in newer versions are allowed to be missing.
+						assert.Greater(t, MinVersion(name), version, "%s/%d", name, version)
+						continue
+					}
+
+					q, _, err := Format(v.Query, opts)
+					assert.NoError(t, err, "%s/%d", name, version)
+
+					if v.Ncols == 0 {
+						continue
+					}
+
+					assert.Equal(t, v.Ncols, countColumns(q), "%s/%d/track %s/pgss %d", name, version, track, pgss)
+					assert.Less(t, v.DiffIntvl[1], v.Ncols, "%s/%d", name, version)
+					assert.LessOrEqual(t, v.DiffIntvl[0], v.DiffIntvl[1], "%s/%d", name, version)
+				}
+			}
+		}
+	}
+}
+
+// Test_RegistryQueries checks queries of all views are executed with no errors on all supported versions of Postgres.
+func Test_RegistryQueries(t *testing.T) {
+	versions := []int{90600, 100000, 110000, 120000, 130000, 140000, 150000, 160000, 170000}
+
+	for _, version := range versions {
+		t.Run(fmt.Sprintf("registry/%d", version), func(t *testing.T) {
+			conn, err := postgres.NewTestConnectVersion(version)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer conn.Close()
+
+			opts := NewOptions(version, "f", "off", 256)
+
+			var extversion string
+			if conn.QueryRow(GetExtensionVersion, "pg_stat_statements").Scan(&extversion) == nil {
+				opts.PgSSVersion = ParseExtensionVersion(extversion)
+			}
+
+			for _, name := range Registered() {
+				v, ok := Select(name, opts)
+				if !ok {
+					continue
+				}
+
+				q, _, err := Format(v.Query, opts)
+				assert.NoError(t, err)
+
+				var args []interface{}
+				if strings.Contains(q, "$1") {
+					args = append(args, "1234567890") // use fake query_id in statements report
+				}
+
+				_, err = conn.Exec(q, args...)
+				assert.NoError(t, err, name)
+			}
+		})
+	}
+}
+
+func TestParseExtensionVersion(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want int
+	}{
+		{in: "1.4", want: 104},
+		{in: "1.10", want: 110},
+		{in: "1.11", want: 111},
+		{in: "2.0.1", want: 200},
+		{in: "1", want: 0},
+		{in: "", want: 0},
+		{in: "1.x", want: 0},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, ParseExtensionVersion(tc.in), tc.in)
+	}
+}
+
+// countColumns returns number of columns in the top-level select list of the query.
+func countColumns(q string) int {
+	q = strings.TrimPrefix(q, "SELECT ")
+
+	var (
+		depth  int
+		quote  rune
+		ncols  = 1
+		prev   rune
+		runes  = []rune(q)
+		prefix = []rune(" FROM ")
+	)
+
+	for i, r := range runes {
+		switch {
+		case quote != 0:
+			if r == quote && prev != '\\' {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			ncols++
+		case depth == 0 && strings.HasPrefix(string(runes[i:]), string(prefix)):
+			return ncols
+		}
+		prev = r
+	}
+
+	return ncols
+}
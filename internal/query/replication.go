@@ -55,18 +55,3 @@ const (
 		"date_trunc('seconds', (pg_last_committed_xact()).timestamp - pg_xact_commit_timestamp(backend_xmin)) as time_age " +
 		"FROM pg_stat_replication ORDER BY pid DESC"
 )
-
-func SelectStatReplicationQuery(version int, track bool) (string, int) {
-	switch {
-	case version < 100000:
-		if track {
-			return PgStatReplication96Extended, 14
-		}
-		return PgStatReplication96, 12
-	default:
-		if track {
-			return PgStatReplicationExtended, 17
-		}
-		return PgStatReplicationDefault, 15
-	}
-}
@@ -0,0 +1,24 @@
+package query
+
+const (
+	// PgReplicationSlotsDefault is the default query for getting replication slots, WAL retained by slots and stats of
+	// logical decoding: transactions spilled to disk and streamed to subscribers.
+	// { Name: "replication_slots", Query: common.PgReplicationSlotsDefault, DiffIntvl: [2]int{6,10}, Ncols: 11, OrderKey: 0, OrderDesc: true }
+	//   Notes: pg_stat_replication_slots introduced in Postgres 14.
+	PgReplicationSlotsDefault = "SELECT s.slot_name AS slot, coalesce(s.plugin, '') AS plugin, s.slot_type AS type, " +
+		"coalesce(s.database, '') AS database, s.active::text AS active, " +
+		"coalesce(({{.WalFunction1}}({{.WalFunction2}}(), s.restart_lsn) / 1024)::bigint, 0) AS retained, " +
+		"coalesce(st.spill_txns, 0) AS spill_txns, coalesce(st.spill_count, 0) AS spill_count, " +
+		"coalesce(st.spill_bytes, 0) AS spill_bytes, coalesce(st.stream_bytes, 0) AS stream_bytes, " +
+		"coalesce(st.total_bytes, 0) AS total_bytes " +
+		"FROM pg_replication_slots s LEFT JOIN pg_stat_replication_slots st ON st.slot_name = s.slot_name " +
+		"ORDER BY s.slot_name DESC"
+
+	// PgReplicationSlotsPG13 queries replication slots for versions 13 and older, stats of logical decoding are not
+	// available.
+	// { Name: "replication_slots", Query: common.PgReplicationSlotsPG13, DiffIntvl: [2]int{0,0}, Ncols: 6, OrderKey: 0, OrderDesc: true }
+	PgReplicationSlotsPG13 = "SELECT s.slot_name AS slot, coalesce(s.plugin, '') AS plugin, s.slot_type AS type, " +
+		"coalesce(s.database, '') AS database, s.active::text AS active, " +
+		"coalesce(({{.WalFunction1}}({{.WalFunction2}}(), s.restart_lsn) / 1024)::bigint, 0) AS retained " +
+		"FROM pg_replication_slots s ORDER BY s.slot_name DESC"
+)
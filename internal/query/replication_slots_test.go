@@ -0,0 +1,33 @@
+package query
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_ReplicationSlotsQueries(t *testing.T) {
+	versions := []int{90500, 90600, 100000, 110000, 120000, 130000, 140000, 150000, 160000, 170000}
+
+	for _, version := range versions {
+		t.Run(fmt.Sprintf("replication_slots/%d", version), func(t *testing.T) {
+			tmpl := PgReplicationSlotsPG13
+			if version >= 140000 {
+				tmpl = PgReplicationSlotsDefault
+			}
+
+			opts := NewOptions(version, "f", "off", 256)
+			q, _, err := Format(tmpl, opts)
+			assert.NoError(t, err)
+
+			conn, err := postgres.NewTestConnectVersion(version)
+			assert.NoError(t, err)
+
+			_, err = conn.Exec(q)
+			assert.NoError(t, err)
+
+			conn.Close()
+		})
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"testing"
 )
 
-func TestSelectStatReplicationQuery(t *testing.T) {
+func TestSelect_replication(t *testing.T) {
 	testcases := []struct {
 		version int
 		track   bool
@@ -29,9 +29,15 @@ func TestSelectStatReplicationQuery(t *testing.T) {
 	}
 
 	for _, tc := range testcases {
-		gotQ, gotN := SelectStatReplicationQuery(tc.version, tc.track)
-		assert.Equal(t, tc.wantQ, gotQ)
-		assert.Equal(t, tc.wantN, gotN)
+		opts := Options{Version: tc.version, GucTrackCommitTS: "off"}
+		if tc.track {
+			opts.GucTrackCommitTS = "on"
+		}
+
+		got, ok := Select("replication", opts)
+		assert.True(t, ok)
+		assert.Equal(t, tc.wantQ, got.Query)
+		assert.Equal(t, tc.wantN, got.Ncols)
 	}
 }
 
@@ -40,14 +46,14 @@ func Test_StatReplicationQueries(t *testing.T) {
 
 	for _, version := range versions {
 		t.Run(fmt.Sprintf("pg_stat_replication/%d", version), func(t *testing.T) {
-			tmpl1, _ := SelectStatReplicationQuery(version, false)
-			tmpl2, _ := SelectStatReplicationQuery(version, true)
-
 			opts := NewOptions(version, "f", "off", 256)
-			q1, err := Format(tmpl1, opts)
+			v1, _ := Select("replication", opts)
+			v2, _ := Select("replication", Options{Version: version, GucTrackCommitTS: "on"})
+
+			q1, _, err := Format(v1.Query, opts)
 			assert.NoError(t, err)
 
-			q2, err := Format(tmpl2, opts)
+			q2, _, err := Format(v2.Query, opts)
 			assert.NoError(t, err)
 
 			conn, err := postgres.NewTestConnectVersion(version)
@@ -0,0 +1,34 @@
+package query
+
+const (
+	// PgRolesDefault is the default query for getting roles' connections stats from pg_roles and pg_stat_activity views
+	// { Name: "pg_roles", Query: common.PgRolesDefault, DiffIntvl: [2]int{0,0}, Ncols: 10, OrderKey: 3, OrderDesc: true }
+	// Roles which can login or have connections are shown. 'conns_%' is the usage of the role's connection limit,
+	// 'expire_days' is the number of days until the role's password expires.
+	PgRolesDefault = "SELECT r.rolname AS role, r.rolsuper AS super, r.rolcanlogin AS login, " +
+		"coalesce(a.conns, 0) AS conns, r.rolconnlimit AS conn_limit, " +
+		`CASE WHEN r.rolconnlimit >= 0 THEN round(100.0 * coalesce(a.conns, 0) / greatest(r.rolconnlimit, 1), 2)::text END AS "conns_%", ` +
+		"coalesce(a.active, 0) AS active, coalesce(a.idle_xact, 0) AS idle_xact, " +
+		"date_trunc('seconds', r.rolvaliduntil)::text AS valid_until, " +
+		"CASE WHEN isfinite(r.rolvaliduntil) THEN floor(extract(epoch FROM r.rolvaliduntil - now()) / 86400)::int END AS expire_days " +
+		"FROM pg_roles r LEFT JOIN (SELECT usesysid, count(*) AS conns, " +
+		"count(*) FILTER (WHERE state = 'active') AS active, " +
+		"count(*) FILTER (WHERE state IN ('idle in transaction', 'idle in transaction (aborted)')) AS idle_xact " +
+		"FROM pg_stat_activity WHERE backend_type = 'client backend' GROUP BY usesysid) a ON a.usesysid = r.oid " +
+		"WHERE r.rolcanlogin OR a.conns > 0 ORDER BY r.rolname DESC"
+
+	// PgRolesPG96 is the query for getting roles' connections stats for versions 9.6 and older.
+	//   Postgres 10: The 'backend_type' has been introduced.
+	// { Name: "pg_roles", Query: common.PgRolesPG96, DiffIntvl: [2]int{0,0}, Ncols: 10, OrderKey: 3, OrderDesc: true }
+	PgRolesPG96 = "SELECT r.rolname AS role, r.rolsuper AS super, r.rolcanlogin AS login, " +
+		"coalesce(a.conns, 0) AS conns, r.rolconnlimit AS conn_limit, " +
+		`CASE WHEN r.rolconnlimit >= 0 THEN round(100.0 * coalesce(a.conns, 0) / greatest(r.rolconnlimit, 1), 2)::text END AS "conns_%", ` +
+		"coalesce(a.active, 0) AS active, coalesce(a.idle_xact, 0) AS idle_xact, " +
+		"date_trunc('seconds', r.rolvaliduntil)::text AS valid_until, " +
+		"CASE WHEN isfinite(r.rolvaliduntil) THEN floor(extract(epoch FROM r.rolvaliduntil - now()) / 86400)::int END AS expire_days " +
+		"FROM pg_roles r LEFT JOIN (SELECT usesysid, count(*) AS conns, " +
+		"count(*) FILTER (WHERE state = 'active') AS active, " +
+		"count(*) FILTER (WHERE state IN ('idle in transaction', 'idle in transaction (aborted)')) AS idle_xact " +
+		"FROM pg_stat_activity WHERE usesysid IS NOT NULL GROUP BY usesysid) a ON a.usesysid = r.oid " +
+		"WHERE r.rolcanlogin OR a.conns > 0 ORDER BY r.rolname DESC"
+)
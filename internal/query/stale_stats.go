@@ -0,0 +1,26 @@
+package query
+
+const (
+	// PgStaleStatsDefault is the default query for getting tables which planner statistics might be stale, it is based
+	// on tables' stats from pg_stat_user_tables.
+	// { Name: "stale_stats", Query: common.PgStaleStatsDefault, DiffIntvl: [2]int{0,0}, Ncols: 8, OrderKey: 4, OrderDesc: true }
+	// Column 'threshold' is the number of modified rows which triggers autoanalyze, per-table storage parameters take
+	// precedence over settings. Column 'mod_%' is the number of rows modified since the last analyze in percents of the
+	// threshold. Column 'targets' lists columns with non-default statistics target.
+	//   Notes: n_mod_since_analyze introduced in Postgres 9.4; since Postgres 17 attstattarget is NULL by default.
+	PgStaleStatsDefault = "SELECT s.schemaname || '.' || s.relname AS relation, " +
+		"coalesce(s.n_live_tup, 0) AS live, coalesce(s.n_mod_since_analyze, 0) AS modified, " +
+		"t.threshold::bigint AS threshold, " +
+		`round(100.0 * coalesce(s.n_mod_since_analyze, 0) / greatest(t.threshold, 1), 2)::text AS "mod_%", ` +
+		"coalesce(date_trunc('seconds', now() - greatest(s.last_analyze, s.last_autoanalyze))::text, 'never') AS analyze_age, " +
+		"coalesce(s.analyze_count + s.autoanalyze_count, 0) AS analyzes, " +
+		"coalesce((SELECT string_agg(a.attname || '=' || a.attstattarget, ',' ORDER BY a.attnum) FROM pg_attribute a " +
+		"WHERE a.attrelid = s.relid AND a.attnum > 0 AND NOT a.attisdropped AND coalesce(a.attstattarget, -1) >= 0), '') AS targets " +
+		"FROM pg_stat_user_tables s JOIN pg_class c ON c.oid = s.relid " +
+		"CROSS JOIN LATERAL (SELECT " +
+		"coalesce((SELECT option_value FROM pg_options_to_table(c.reloptions) WHERE option_name = 'autovacuum_analyze_threshold')::numeric, " +
+		"current_setting('autovacuum_analyze_threshold')::numeric) + " +
+		"coalesce((SELECT option_value FROM pg_options_to_table(c.reloptions) WHERE option_name = 'autovacuum_analyze_scale_factor')::numeric, " +
+		"current_setting('autovacuum_analyze_scale_factor')::numeric) * greatest(c.reltuples, 0) AS threshold) t " +
+		"ORDER BY (s.schemaname || '.' || s.relname) DESC"
+)
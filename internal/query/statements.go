@@ -3,10 +3,31 @@ package query
 const (
 	// NOTES:
 	// 1. regexp_replace() removes extra spaces, tabs and newlines from queries
+	// 2. statementsFilter is a part of join condition, hence it is used in queries with and without WHERE clause
+
+	// statementsFilter limits statements to specified databases and users, it is pushed down into queries instead of
+	// filtering rows on the client side. Lists of databases and users are passed as query parameters.
+	statementsFilter = "{{ if .Databases }} AND d.datname = ANY({{ param .Databases }}::text[]){{ end }}" +
+		"{{ if .Users }} AND p.userid IN (SELECT oid FROM pg_roles WHERE rolname = ANY({{ param .Users }}::text[])){{ end }}"
 
 	// PgStatStatementsTimingDefault is the default query for getting timings stats from pg_stat_statements view
 	// { Name: "pg_stat_statements_timing", Query: common.PgStatStatementsTimingQueryDefault, DiffIntvl: [2]int{6,10}, Ncols: 13, OrderKey: 0, OrderDesc: true }
 	PgStatStatementsTimingDefault = "SELECT pg_get_userbyid(p.userid) AS user, d.datname AS database, " +
+		"date_trunc('seconds', round(p.total_plan_time + p.total_exec_time) / 1000 * '1 second'::interval)::text AS t_all_t, " +
+		"date_trunc('seconds', round(p.shared_blk_read_time + p.local_blk_read_time) / 1000 * '1 second'::interval)::text AS t_read_t, " +
+		"date_trunc('seconds', round(p.shared_blk_write_time + p.local_blk_write_time) / 1000 * '1 second'::interval)::text AS t_write_t, " +
+		"date_trunc('seconds', round((p.total_plan_time + p.total_exec_time) - (p.shared_blk_read_time + p.local_blk_read_time + p.shared_blk_write_time + p.local_blk_write_time)) / 1000 * '1 second'::interval)::text AS t_cpu_t, " +
+		"round(p.total_plan_time + p.total_exec_time) AS all_t, " +
+		"round(p.shared_blk_read_time + p.local_blk_read_time) AS read_t, round(p.shared_blk_write_time + p.local_blk_write_time) AS write_t, " +
+		"round((p.total_plan_time + p.total_exec_time) - (p.shared_blk_read_time + p.local_blk_read_time + p.shared_blk_write_time + p.local_blk_write_time)) AS cpu_t, " +
+		"p.calls AS calls, left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
+		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter
+
+	// PgStatStatementsTimingPG16 is the query for getting timings stats from pg_stat_statements view for Postgres 13-16
+	// (pg_stat_statements 1.8-1.10).
+	// { Name: "pg_stat_statements_timing", Query: common.PgStatStatementsTimingQueryDefault, DiffIntvl: [2]int{6,10}, Ncols: 13, OrderKey: 0, OrderDesc: true }
+	PgStatStatementsTimingPG16 = "SELECT pg_get_userbyid(p.userid) AS user, d.datname AS database, " +
 		"date_trunc('seconds', round(p.total_plan_time + p.total_exec_time) / 1000 * '1 second'::interval)::text AS t_all_t, " +
 		"date_trunc('seconds', round(p.blk_read_time) / 1000 * '1 second'::interval)::text AS t_read_t, " +
 		"date_trunc('seconds', round(p.blk_write_time) / 1000 * '1 second'::interval)::text AS t_write_t, " +
@@ -16,7 +37,7 @@ const (
 		"round((p.total_plan_time + p.total_exec_time) - (p.blk_read_time + p.blk_write_time)) AS cpu_t, " +
 		"p.calls AS calls, left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
 		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
-		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid"
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter
 
 	// pg_stat_statements timing query for Postgres 12 and older.
 	PgStatStatementsTimingPG12 = "SELECT pg_get_userbyid(p.userid) AS user, d.datname AS database, " +
@@ -28,14 +49,14 @@ const (
 		"round(p.total_time - (p.blk_read_time + p.blk_write_time)) AS cpu_t, p.calls AS calls, " +
 		"left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
 		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
-		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid"
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter
 
 	// PgStatStatementsGeneralDefault is the default query for getting general stats from pg_stat_statements
 	// { Name: "pg_stat_statements_general", Query: common.PgStatStatementsGeneralQueryDefault, DiffIntvl: [2]int{4,5}, Ncols: 8, OrderKey: 0, OrderDesc: true }
 	PgStatStatementsGeneralDefault = "SELECT pg_get_userbyid(p.userid) AS user, d.datname AS database, p.calls AS t_calls, " +
 		"p.rows AS t_rows, p.calls AS calls, p.rows AS rows, left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
 		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
-		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid"
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter
 
 	// PgStatStatementsIoDefault is the default query for getting IO stats from pg_stat_statements
 	// { Name: "pg_stat_statements_io", Query: common.PgStatStatementsIoQueryDefault, DiffIntvl: [2]int{6,10}, Ncols: 13, OrderKey: 0, OrderDesc: true }
@@ -50,7 +71,7 @@ const (
 		"(p.shared_blks_written + p.local_blks_written) * (SELECT current_setting('block_size')::int / 1024) AS written, " +
 		"p.calls AS calls, left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
 		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
-		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid"
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter
 
 	// PgStatStatementsTempDefault is the default query for getting stats about temp files IO from pg_stat_statements
 	// { Name: "pg_stat_statements_temp", Query: common.PgStatStatementsTempQueryDefault, DiffIntvl: [2]int{4,6}, Ncols: 9, OrderKey: 0, OrderDesc: true }
@@ -61,7 +82,7 @@ const (
 		"p.temp_blks_written * (SELECT current_setting('block_size')::int / 1024) AS tmp_write, " +
 		"p.calls AS calls, left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
 		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
-		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid"
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter
 
 	// PgStatStatementsLocalDefault is the default query for getting stats about local buffers IO from pg_stat_statements
 	// { Name: "pg_stat_statements_local", Query: common.PgStatStatementsLocalQueryDefault, DiffIntvl: [2]int{6,10}, Ncols: 13, OrderKey: 0, OrderDesc: true }
@@ -75,10 +96,137 @@ const (
 		"p.local_blks_written * (SELECT current_setting('block_size')::int / 1024) AS lo_written, " +
 		"p.calls AS calls, left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
 		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
-		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid"
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter
+
+	// PgStatStatementsParallelDefault is the default query for getting stats about parallel workers planned and
+	// launched by statements from pg_stat_statements, only statements which planned parallel workers are shown.
+	// { Name: "pg_stat_statements_parallel", Query: common.PgStatStatementsParallelDefault, DiffIntvl: [2]int{4,7}, Ncols: 10, OrderKey: 0, OrderDesc: true }
+	//   Notes: parallel_workers_to_launch and parallel_workers_launched introduced in pg_stat_statements 1.12 (Postgres 18)
+	PgStatStatementsParallelDefault = "SELECT pg_get_userbyid(p.userid) AS user, d.datname AS database, " +
+		"p.parallel_workers_to_launch AS t_to_launch, p.parallel_workers_launched AS t_launched, " +
+		"p.parallel_workers_to_launch AS to_launch, p.parallel_workers_launched AS launched, " +
+		"p.parallel_workers_to_launch - p.parallel_workers_launched AS not_launched, " +
+		"p.calls AS calls, left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
+		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter + " WHERE p.parallel_workers_to_launch > 0"
+
+	// PgStatStatementsJitDefault is the default query for getting stats about JIT compilation from pg_stat_statements
+	// { Name: "pg_stat_statements_jit", Query: common.PgStatStatementsJitDefault, DiffIntvl: [2]int{5,13}, Ncols: 16, OrderKey: 0, OrderDesc: true }
+	// Column 'jit_%' is the share of JIT compilation time in total time of statement, it is recalculated by pgcenter
+	// using diffed values.
+	//   Notes: jit_deform_time introduced in pg_stat_statements 1.11 (Postgres 17)
+	PgStatStatementsJitDefault = "SELECT pg_get_userbyid(p.userid) AS user, d.datname AS database, " +
+		"date_trunc('seconds', round(p.total_plan_time + p.total_exec_time) / 1000 * '1 second'::interval)::text AS t_all_t, " +
+		"date_trunc('seconds', round(p.jit_generation_time + p.jit_inlining_time + p.jit_optimization_time + p.jit_emission_time + p.jit_deform_time) / 1000 * '1 second'::interval)::text AS t_jit_t, " +
+		"coalesce(round((100 * (p.jit_generation_time + p.jit_inlining_time + p.jit_optimization_time + p.jit_emission_time + p.jit_deform_time) / nullif(p.total_plan_time + p.total_exec_time, 0))::numeric, 2), 0) AS \"jit_%\", " +
+		"round(p.total_plan_time + p.total_exec_time) AS all_t, round(p.jit_generation_time + p.jit_inlining_time + p.jit_optimization_time + p.jit_emission_time + p.jit_deform_time) AS jit_t, " +
+		"round(p.jit_generation_time) AS gen_t, round(p.jit_inlining_time) AS inl_t, " +
+		"round(p.jit_optimization_time) AS opt_t, round(p.jit_emission_time) AS emit_t, round(p.jit_deform_time) AS deform_t, " +
+		"p.jit_functions AS jit_funcs, " +
+		"p.calls AS calls, left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
+		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter
+
+	// PgStatStatementsJitPG16 is the query for getting stats about JIT compilation from pg_stat_statements for
+	// Postgres 15-16 (pg_stat_statements 1.10).
+	//   Notes: JIT counters introduced in pg_stat_statements 1.10 (Postgres 15)
+	PgStatStatementsJitPG16 = "SELECT pg_get_userbyid(p.userid) AS user, d.datname AS database, " +
+		"date_trunc('seconds', round(p.total_plan_time + p.total_exec_time) / 1000 * '1 second'::interval)::text AS t_all_t, " +
+		"date_trunc('seconds', round(p.jit_generation_time + p.jit_inlining_time + p.jit_optimization_time + p.jit_emission_time) / 1000 * '1 second'::interval)::text AS t_jit_t, " +
+		"coalesce(round((100 * (p.jit_generation_time + p.jit_inlining_time + p.jit_optimization_time + p.jit_emission_time) / nullif(p.total_plan_time + p.total_exec_time, 0))::numeric, 2), 0) AS \"jit_%\", " +
+		"round(p.total_plan_time + p.total_exec_time) AS all_t, round(p.jit_generation_time + p.jit_inlining_time + p.jit_optimization_time + p.jit_emission_time) AS jit_t, " +
+		"round(p.jit_generation_time) AS gen_t, round(p.jit_inlining_time) AS inl_t, " +
+		"round(p.jit_optimization_time) AS opt_t, round(p.jit_emission_time) AS emit_t, 0 AS deform_t, " +
+		"p.jit_functions AS jit_funcs, " +
+		"p.calls AS calls, left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
+		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter
+
+	// PgStatStatementsLatencyDefault is the default query for getting stats about latency of blocks reads and writes
+	// from pg_stat_statements.
+	// { Name: "pg_stat_statements_latency", Query: common.PgStatStatementsLatencyDefault, DiffIntvl: [2]int{6,10}, Ncols: 13, OrderKey: 0, OrderDesc: true }
+	// Columns 'read_lat' and 'write_lat' are average time of reading and writing a block, in milliseconds, they are
+	// recalculated by pgcenter using diffed values. Time is collected when track_io_timing is on.
+	//   Notes: shared_blk_read_time and local_blk_read_time introduced in pg_stat_statements 1.11 (Postgres 17)
+	PgStatStatementsLatencyDefault = "SELECT pg_get_userbyid(p.userid) AS user, d.datname AS database, " +
+		"date_trunc('seconds', round(p.shared_blk_read_time + p.local_blk_read_time) / 1000 * '1 second'::interval)::text AS t_read_t, " +
+		"date_trunc('seconds', round(p.shared_blk_write_time + p.local_blk_write_time) / 1000 * '1 second'::interval)::text AS t_write_t, " +
+		"coalesce(round(((p.shared_blk_read_time + p.local_blk_read_time) / nullif(p.shared_blks_read + p.local_blks_read, 0))::numeric, 3), 0) AS read_lat, " +
+		"coalesce(round(((p.shared_blk_write_time + p.local_blk_write_time) / nullif(p.shared_blks_written + p.local_blks_written, 0))::numeric, 3), 0) AS write_lat, " +
+		"round((p.shared_blk_read_time + p.local_blk_read_time)::numeric, 2) AS read_t, " +
+		"round((p.shared_blk_write_time + p.local_blk_write_time)::numeric, 2) AS write_t, " +
+		"p.shared_blks_read + p.local_blks_read AS read_blks, p.shared_blks_written + p.local_blks_written AS write_blks, " +
+		"p.calls AS calls, left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
+		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter
+
+	// PgStatStatementsLatencyPG16 is the query for getting stats about latency of blocks reads and writes from
+	// pg_stat_statements for Postgres 16 and older (pg_stat_statements 1.10 and older).
+	PgStatStatementsLatencyPG16 = "SELECT pg_get_userbyid(p.userid) AS user, d.datname AS database, " +
+		"date_trunc('seconds', round(p.blk_read_time) / 1000 * '1 second'::interval)::text AS t_read_t, " +
+		"date_trunc('seconds', round(p.blk_write_time) / 1000 * '1 second'::interval)::text AS t_write_t, " +
+		"coalesce(round((p.blk_read_time / nullif(p.shared_blks_read + p.local_blks_read, 0))::numeric, 3), 0) AS read_lat, " +
+		"coalesce(round((p.blk_write_time / nullif(p.shared_blks_written + p.local_blks_written, 0))::numeric, 3), 0) AS write_lat, " +
+		"round(p.blk_read_time::numeric, 2) AS read_t, round(p.blk_write_time::numeric, 2) AS write_t, " +
+		"p.shared_blks_read + p.local_blks_read AS read_blks, p.shared_blks_written + p.local_blks_written AS write_blks, " +
+		"p.calls AS calls, left(md5(p.userid::text || p.dbid::text || p.queryid::text), 10) AS queryid, " +
+		`regexp_replace({{.PgSSQueryLenFn}}, E'\\s+', ' ', 'g') AS query ` +
+		"FROM pg_stat_statements p JOIN pg_database d ON d.oid=p.dbid" + statementsFilter
 
 	// PgStatStatementsReportQuery defines query used for calculating per-statement report based on pg_stat_statements.
 	PgStatStatementsReportQueryDefault = "WITH totals AS (SELECT " +
+		"sum(calls) AS total_calls," +
+		"sum(rows) AS total_rows," +
+		"sum(total_plan_time + total_exec_time) AS total_all_time," +
+		"sum(total_plan_time) AS total_plan_time," +
+		"sum(total_exec_time - (shared_blk_read_time + local_blk_read_time) + (shared_blk_write_time + local_blk_write_time)) AS total_cpu_time," +
+		"sum(shared_blk_read_time + local_blk_read_time + shared_blk_write_time + local_blk_write_time) AS total_io_time " +
+		"FROM pg_stat_statements)," +
+		"stmt AS (" +
+		"SELECT " +
+		"query, queryid, userid, dbid," +
+		"calls AS calls," +
+		"rows AS rows," +
+		"total_plan_time + total_exec_time AS all_time," +
+		"total_plan_time AS plan_time," +
+		"total_exec_time - (shared_blk_read_time + local_blk_read_time) + (shared_blk_write_time + local_blk_write_time) AS cpu_time," +
+		"shared_blk_read_time + local_blk_read_time + shared_blk_write_time + local_blk_write_time AS io_time " +
+		"FROM pg_stat_statements " +
+		"WHERE left(md5(userid::text || dbid::text || queryid::text), 10) = $1) " +
+		"SELECT s.query, s.queryid::text AS queryid, s.userid::regrole AS usename, d.datname," +
+		"to_char((SELECT total_calls FROM totals), 'FM999,999,999,990') AS total_calls," +
+		"to_char((SELECT total_rows FROM totals), 'FM999,999,999,990') AS total_rows," +
+		"to_char(interval '1 millisecond' * (SELECT total_all_time FROM totals), 'HH24:MI:SS') AS total_all_time," +
+		"to_char(interval '1 millisecond' * (SELECT coalesce(nullif(total_plan_time, 0), 1) FROM totals), 'HH24:MI:SS') AS total_plan_time," +
+		"to_char(100 * (SELECT total_plan_time FROM totals) / (SELECT coalesce(nullif(total_all_time, 0), 1) FROM totals), 'FM990.00') AS total_plan_time_dist_ratio," +
+		"to_char(interval '1 millisecond' * (SELECT total_cpu_time FROM totals), 'HH24:MI:SS') AS total_cpu_time," +
+		"to_char(100 * (SELECT total_cpu_time FROM totals) / (SELECT coalesce(nullif(total_all_time, 0), 1) FROM totals), 'FM990.00') AS total_cpu_time_dist_ratio," +
+		"to_char(interval '1 millisecond' * (SELECT total_io_time FROM totals), 'HH24:MI:SS') AS total_io_time," +
+		"to_char(100 * (SELECT total_io_time FROM totals) / (SELECT coalesce(nullif(total_all_time, 0), 1) FROM totals), 'FM990.00') AS total_io_time_dist_ratio," +
+		"to_char(s.calls, 'FM999,999,999,990') AS calls," +
+		"to_char(100*s.calls/(SELECT total_calls FROM totals), 'FM990.00') AS calls_ratio," +
+		"to_char(s.rows, 'FM999,999,999,990') AS rows," +
+		"to_char(100*s.rows/(SELECT coalesce(nullif(total_rows, 0), 1) FROM totals), 'FM990.00') AS rows_ratio," +
+		"to_char(interval '1 millisecond' * s.all_time, 'HH24:MI:SS.MS') AS all_time," +
+		"to_char(100*s.all_time/(SELECT coalesce(nullif(total_all_time, 0), 1) FROM totals), 'FM990.00') AS all_time_ratio," +
+		"to_char(interval '1 millisecond' * s.plan_time, 'HH24:MI:SS.MS') AS plan_time," +
+		"to_char(100*s.plan_time/(SELECT coalesce(nullif(total_plan_time, 0), 1) FROM totals), 'FM990.00') AS plan_time_ratio," +
+		"to_char(interval '1 millisecond' * s.cpu_time, 'HH24:MI:SS.MS') AS cpu_time," +
+		"to_char(100*s.cpu_time/(SELECT coalesce(nullif(total_cpu_time, 0), 1) FROM totals), 'FM990.00') AS cpu_time_ratio," +
+		"to_char(interval '1 millisecond' * s.io_time, 'HH24:MI:SS.MS') AS io_time," +
+		"to_char(100*s.io_time/(SELECT coalesce(nullif(total_io_time, 0), 1) FROM totals), 'FM990.00') AS io_time_ratio," +
+		"(s.all_time / s.calls)::numeric(20,2) AS avg_all_time," +
+		"(s.plan_time / s.calls)::numeric(20,2) AS avg_plan_time," +
+		"(s.cpu_time / s.calls)::numeric(20,2) AS avg_cpu_time," +
+		"(s.io_time / s.calls)::numeric(20,2) AS avg_io_time," +
+		"to_char(100*s.plan_time / s.all_time, 'FM990.00') AS plan_time_dist_ratio," +
+		"to_char(100*s.cpu_time / s.all_time, 'FM990.00') AS cpu_time_dist_ratio," +
+		"to_char(100*s.io_time / s.all_time, 'FM990.00') AS io_time_dist_ratio " +
+		"FROM stmt s JOIN pg_database d ON d.oid=s.dbid LIMIT 1"
+
+	// PgStatStatementsReportQueryPG16 defines query used for calculating per-statement report based on pg_stat_statements
+	// for Postgres 13-16 (pg_stat_statements 1.8-1.10).
+	PgStatStatementsReportQueryPG16 = "WITH totals AS (SELECT " +
 		"sum(calls) AS total_calls," +
 		"sum(rows) AS total_rows," +
 		"sum(total_plan_time + total_exec_time) AS total_all_time," +
@@ -179,23 +327,3 @@ const (
 		"to_char(100*s.io_time / s.all_time, 'FM990.00') AS io_time_dist_ratio " +
 		"FROM stmt s JOIN pg_database d ON d.oid=s.dbid LIMIT 1"
 )
-
-// SelectStatStatementsTimingQuery returns proper statements_timing query depending on Postgres version.
-func SelectStatStatementsTimingQuery(version int) string {
-	switch {
-	case version < 130000:
-		return PgStatStatementsTimingPG12
-	default:
-		return PgStatStatementsTimingDefault
-	}
-}
-
-// SelectQueryReportQuery returns proper query report query depending on Postgres version.
-func SelectQueryReportQuery(version int) string {
-	switch {
-	case version < 130000:
-		return PgStatStatementsReportQueryPG12
-	default:
-		return PgStatStatementsReportQueryDefault
-	}
-}
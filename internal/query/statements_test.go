@@ -7,9 +7,10 @@ import (
 	"testing"
 )
 
-func TestSelectStatStatementsTimingQuery(t *testing.T) {
+func TestSelect_statements_timings(t *testing.T) {
 	testcases := []struct {
 		version int
+		pgss    int
 		want    string
 	}{
 		{version: 90500, want: PgStatStatementsTimingPG12},
@@ -17,12 +18,78 @@ func TestSelectStatStatementsTimingQuery(t *testing.T) {
 		{version: 100000, want: PgStatStatementsTimingPG12},
 		{version: 110000, want: PgStatStatementsTimingPG12},
 		{version: 120000, want: PgStatStatementsTimingPG12},
-		{version: 130000, want: PgStatStatementsTimingDefault},
+		{version: 130000, want: PgStatStatementsTimingPG16},
+		{version: 160000, want: PgStatStatementsTimingPG16},
+		{version: 170000, want: PgStatStatementsTimingDefault},
+		{version: 130000, pgss: 107, want: PgStatStatementsTimingPG12},
+		{version: 170000, pgss: 110, want: PgStatStatementsTimingPG16},
+		{version: 170000, pgss: 111, want: PgStatStatementsTimingDefault},
 	}
 
 	for _, tc := range testcases {
-		got := SelectStatStatementsTimingQuery(tc.version)
-		assert.Equal(t, tc.want, got)
+		got, ok := Select("statements_timings", Options{Version: tc.version, PgSSVersion: tc.pgss})
+		assert.True(t, ok)
+		assert.Equal(t, tc.want, got.Query)
+	}
+}
+
+func TestSelect_statements_parallel(t *testing.T) {
+	testcases := []struct {
+		version int
+		pgss    int
+		ok      bool
+	}{
+		{version: 170000, ok: false},
+		{version: 180000, ok: true},
+		{version: 180000, pgss: 111, ok: false},
+		{version: 180000, pgss: 112, ok: true},
+	}
+
+	for _, tc := range testcases {
+		got, ok := Select("statements_parallel", Options{Version: tc.version, PgSSVersion: tc.pgss})
+		assert.Equal(t, tc.ok, ok)
+		if ok {
+			assert.Equal(t, PgStatStatementsParallelDefault, got.Query)
+		}
+	}
+}
+
+func TestSelect_statements_jit(t *testing.T) {
+	testcases := []struct {
+		version int
+		pgss    int
+		want    string
+	}{
+		{version: 140000, pgss: 109},
+		{version: 150000, want: PgStatStatementsJitPG16},
+		{version: 160000, pgss: 110, want: PgStatStatementsJitPG16},
+		{version: 170000, want: PgStatStatementsJitDefault},
+		{version: 170000, pgss: 110, want: PgStatStatementsJitPG16},
+	}
+
+	for _, tc := range testcases {
+		got, ok := Select("statements_jit", Options{Version: tc.version, PgSSVersion: tc.pgss})
+		assert.Equal(t, tc.want != "", ok)
+		assert.Equal(t, tc.want, got.Query)
+	}
+}
+
+func TestSelect_statements_latency(t *testing.T) {
+	testcases := []struct {
+		version int
+		pgss    int
+		want    string
+	}{
+		{version: 90500, want: PgStatStatementsLatencyPG16},
+		{version: 160000, want: PgStatStatementsLatencyPG16},
+		{version: 170000, want: PgStatStatementsLatencyDefault},
+		{version: 170000, pgss: 110, want: PgStatStatementsLatencyPG16},
+	}
+
+	for _, tc := range testcases {
+		got, ok := Select("statements_latency", Options{Version: tc.version, PgSSVersion: tc.pgss})
+		assert.True(t, ok)
+		assert.Equal(t, tc.want, got.Query)
 	}
 }
 
@@ -40,7 +107,7 @@ func Test_StatStatementsQueries(t *testing.T) {
 		t.Run(fmt.Sprintf("pg_stat_statements/%d", version), func(t *testing.T) {
 			for _, query := range queries {
 				opts := NewOptions(version, "f", "off", 256)
-				q, err := Format(query, opts)
+				q, _, err := Format(query, opts)
 				assert.NoError(t, err)
 
 				conn, err := postgres.NewTestConnectVersion(version)
@@ -56,9 +123,9 @@ func Test_StatStatementsQueries(t *testing.T) {
 
 	t.Run("pg_stat_statements_timing", func(t *testing.T) {
 		for _, version := range versions {
-			tmpl := SelectStatStatementsTimingQuery(version)
 			opts := NewOptions(version, "f", "off", 256)
-			q, err := Format(tmpl, opts)
+			v, _ := Select("statements_timings", opts)
+			q, _, err := Format(v.Query, opts)
 			assert.NoError(t, err)
 
 			conn, err := postgres.NewTestConnectVersion(version)
@@ -72,7 +139,7 @@ func Test_StatStatementsQueries(t *testing.T) {
 	})
 }
 
-func TestSelectQueryReportQuery(t *testing.T) {
+func TestSelect_statements_report(t *testing.T) {
 	testcases := []struct {
 		version int
 		want    string
@@ -82,12 +149,14 @@ func TestSelectQueryReportQuery(t *testing.T) {
 		{version: 100000, want: PgStatStatementsReportQueryPG12},
 		{version: 110000, want: PgStatStatementsReportQueryPG12},
 		{version: 120000, want: PgStatStatementsReportQueryPG12},
-		{version: 130000, want: PgStatStatementsReportQueryDefault},
+		{version: 130000, want: PgStatStatementsReportQueryPG16},
+		{version: 170000, want: PgStatStatementsReportQueryDefault},
 	}
 
 	for _, tc := range testcases {
-		got := SelectQueryReportQuery(tc.version)
-		assert.Equal(t, tc.want, got)
+		got, ok := Select("statements_report", Options{Version: tc.version})
+		assert.True(t, ok)
+		assert.Equal(t, tc.want, got.Query)
 	}
 }
 
@@ -95,9 +164,9 @@ func Test_StatStatementsReportQueries(t *testing.T) {
 	versions := []int{90500, 90600, 100000, 110000, 120000, 130000}
 
 	for _, version := range versions {
-		tmpl := SelectQueryReportQuery(version)
 		opts := NewOptions(version, "f", "off", 256)
-		q, err := Format(tmpl, opts)
+		v, _ := Select("statements_report", opts)
+		q, _, err := Format(v.Query, opts)
 		assert.NoError(t, err)
 
 		conn, err := postgres.NewTestConnectVersion(version)
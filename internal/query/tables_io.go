@@ -0,0 +1,25 @@
+package query
+
+const (
+	// PgStatTablesIODefault is the default query for getting tables' IO stats from pg_statio_all_tables view
+	// { Name: "pg_statio_tables", Query: common.PgStatTablesIODefault, DiffIntvl: [2]int{1,9}, Ncols: 13, OrderKey: 1, OrderDesc: true }
+	// Column 'reads' is the total amount of data read from disk by the table, its indexes and TOAST. Hit ratios are
+	// calculated since stats reset.
+	PgStatTablesIODefault = "SELECT schemaname || '.' || relname AS relation, " +
+		"(coalesce(heap_blks_read, 0) + coalesce(idx_blks_read, 0) + coalesce(toast_blks_read, 0) + coalesce(tidx_blks_read, 0)) " +
+		"* (SELECT current_setting('block_size')::int / 1024) AS reads, " +
+		"coalesce(heap_blks_read * (SELECT current_setting('block_size')::int / 1024), 0) AS heap_read, " +
+		"coalesce(heap_blks_hit, 0) AS heap_hit, " +
+		"coalesce(idx_blks_read * (SELECT current_setting('block_size')::int / 1024), 0) AS idx_read, " +
+		"coalesce(idx_blks_hit, 0) AS idx_hit, " +
+		"coalesce(toast_blks_read * (SELECT current_setting('block_size')::int / 1024), 0) AS toast_read, " +
+		"coalesce(toast_blks_hit, 0) AS toast_hit, " +
+		"coalesce(tidx_blks_read * (SELECT current_setting('block_size')::int / 1024), 0) AS tidx_read, " +
+		"coalesce(tidx_blks_hit, 0) AS tidx_hit, " +
+		`round(100.0 * heap_blks_hit / nullif(heap_blks_hit + heap_blks_read, 0), 2)::text AS "heap_hit_%", ` +
+		`round(100.0 * idx_blks_hit / nullif(idx_blks_hit + idx_blks_read, 0), 2)::text AS "idx_hit_%", ` +
+		"round(100.0 * (coalesce(heap_blks_hit, 0) + coalesce(idx_blks_hit, 0) + coalesce(toast_blks_hit, 0) + coalesce(tidx_blks_hit, 0)) / " +
+		"nullif(coalesce(heap_blks_hit, 0) + coalesce(idx_blks_hit, 0) + coalesce(toast_blks_hit, 0) + coalesce(tidx_blks_hit, 0) + " +
+		`coalesce(heap_blks_read, 0) + coalesce(idx_blks_read, 0) + coalesce(toast_blks_read, 0) + coalesce(tidx_blks_read, 0), 0), 2)::text AS "hit_%" ` +
+		"FROM pg_statio_{{.ViewType}}_tables ORDER BY (schemaname || '.' || relname) DESC"
+)
@@ -15,7 +15,7 @@ func Test_StatTablesQueries(t *testing.T) {
 			tmpl := PgStatTablesDefault
 
 			opts := NewOptions(version, "f", "off", 256)
-			q, err := Format(tmpl, opts)
+			q, _, err := Format(tmpl, opts)
 			assert.NoError(t, err)
 
 			conn, err := postgres.NewTestConnectVersion(version)
@@ -0,0 +1,74 @@
+// Package settings implements loading of pgcenter configuration file.
+package settings
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/lesovsky/pgcenter/internal/header"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/notify"
+	"github.com/lesovsky/pgcenter/internal/plugin"
+	"github.com/lesovsky/pgcenter/internal/policy"
+	"github.com/lesovsky/pgcenter/internal/push"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// defaultFilename defines name of configuration file looked up in user's home directory.
+const defaultFilename = ".pgcenter.yaml"
+
+// Settings defines pgcenter configuration file.
+type Settings struct {
+	Alerts  alert.Config      `yaml:"alerts"`        // alert rules and receivers of notifications
+	Plugins []plugin.Config   `yaml:"plugins"`       // external collectors shown as views
+	Hooks   []hook.Config     `yaml:"hooks"`         // user commands run on events
+	Push    push.Config       `yaml:"push"`          // pushing stats rates to external storages
+	UI      i18n.Config       `yaml:"ui"`            // language of UI messages and names of columns
+	Actions policy.Config     `yaml:"actions"`       // confirmation policies of actions which change state of Postgres
+	Header  header.Config     `yaml:"header"`        // summary lines shown in the header of 'top' and their order
+	Notify  notify.Config     `yaml:"notifications"` // terminal bell and desktop notifications about events in 'top'
+	Devices stat.FilterConfig `yaml:"devices"`       // patterns of block devices and network interfaces which stats are skipped
+}
+
+// Load reads configuration from specified file. If filename is not specified, PGCENTER_CONFIG environment variable is
+// used, otherwise ~/.pgcenter.yaml is read if it exists.
+func Load(filename string) (Settings, error) {
+	if filename == "" {
+		filename = os.Getenv("PGCENTER_CONFIG")
+	}
+
+	if filename == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Settings{}, nil
+		}
+
+		filename = filepath.Join(home, defaultFilename)
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			return Settings{}, nil
+		}
+	}
+
+	return readFile(filename)
+}
+
+// readFile reads and parses configuration file.
+func readFile(filename string) (Settings, error) {
+	var s Settings
+
+	data, err := ioutil.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return s, fmt.Errorf("read config file failed: %s", err)
+	}
+
+	err = yaml.UnmarshalStrict(data, &s)
+	if err != nil {
+		return s, fmt.Errorf("parse config file %s failed: %s", filename, err)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,142 @@
+package settings
+
+import (
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/lesovsky/pgcenter/internal/header"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/notify"
+	"github.com/lesovsky/pgcenter/internal/plugin"
+	"github.com/lesovsky/pgcenter/internal/policy"
+	"github.com/lesovsky/pgcenter/internal/push"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-settings-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	filename := filepath.Join(dir, "pgcenter.yaml")
+	data := `
+alerts:
+  interval: 5s
+  rules:
+    - name: replication_lag
+      view: replication
+      metric: total_lag
+      unit: kB
+      condition: "> 100MB"
+      for: 2m
+  receivers:
+    - type: webhook
+      url: http://127.0.0.1/alerts
+      headers:
+        X-Token: secret
+plugins:
+  - name: pgbouncer_pools
+    command: ["/usr/local/bin/pgbouncer-pools", "--port", "6432"]
+    timeout: 2s
+    columns:
+      - name: pool
+      - name: cl_active
+      - name: xacts
+        rate: true
+hooks:
+  - events: [connection_lost, connection_restored]
+    command: ["/usr/local/bin/notify-oncall"]
+    timeout: 30s
+push:
+  interval: 15s
+  views:
+    - name: databases
+      columns: [commits, rollbacks]
+  sinks:
+    - type: graphite
+      address: 127.0.0.1:2003
+ui:
+  locale: ru
+  messages:
+    dialog.filter: "Filter: "
+  columns:
+    databases.datname: database
+actions:
+  terminate: type
+  reset_stats: disabled
+header:
+  left: [load, cpu, io]
+  right: [postgres, activity, replication]
+notifications:
+  bell: true
+  desktop: osc9
+  events: [alert_firing, profile_finished]
+devices:
+  diskstats: "^(ram|loop|fd|dm-)"
+`
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(data), 0600))
+
+	got, err := Load(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, Settings{Alerts: alert.Config{
+		Interval: 5 * time.Second,
+		Rules: []alert.Rule{
+			{Name: "replication_lag", View: "replication", Metric: "total_lag", Unit: "kB", Condition: "> 100MB", For: 2 * time.Minute},
+		},
+		Receivers: []alert.Receiver{
+			{Type: "webhook", URL: "http://127.0.0.1/alerts", Headers: map[string]string{"X-Token": "secret"}},
+		},
+	}, Plugins: []plugin.Config{
+		{
+			Name: "pgbouncer_pools", Command: []string{"/usr/local/bin/pgbouncer-pools", "--port", "6432"}, Timeout: 2 * time.Second,
+			Columns: []plugin.Column{{Name: "pool"}, {Name: "cl_active"}, {Name: "xacts", Rate: true}},
+		},
+	}, Hooks: []hook.Config{
+		{Events: []string{"connection_lost", "connection_restored"}, Command: []string{"/usr/local/bin/notify-oncall"}, Timeout: 30 * time.Second},
+	}, Push: push.Config{
+		Interval: 15 * time.Second,
+		Views:    []push.View{{Name: "databases", Columns: []string{"commits", "rollbacks"}}},
+		Sinks:    []push.Sink{{Type: "graphite", Address: "127.0.0.1:2003"}},
+	}, UI: i18n.Config{
+		Locale:   "ru",
+		Messages: map[string]string{"dialog.filter": "Filter: "},
+		Columns:  map[string]string{"databases.datname": "database"},
+	}, Actions: policy.Config{
+		Terminate: "type", ResetStats: "disabled",
+	}, Header: header.Config{
+		Left: []string{"load", "cpu", "io"}, Right: []string{"postgres", "activity", "replication"},
+	}, Notify: notify.Config{
+		Bell: true, Desktop: "osc9", Events: []string{"alert_firing", "profile_finished"},
+	}, Devices: stat.FilterConfig{
+		Diskstats: "^(ram|loop|fd|dm-)",
+	}}, got)
+
+	// Config file from environment.
+	assert.NoError(t, os.Setenv("PGCENTER_CONFIG", filename))
+	got, err = Load("")
+	assert.NoError(t, err)
+	assert.Equal(t, "replication_lag", got.Alerts.Rules[0].Name)
+	assert.NoError(t, os.Unsetenv("PGCENTER_CONFIG"))
+
+	// Unknown fields are not allowed.
+	assert.NoError(t, ioutil.WriteFile(filename, []byte("alerts:\n  ruless: []\n"), 0600))
+	_, err = Load(filename)
+	assert.Error(t, err)
+
+	// Missing file.
+	_, err = Load(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	// Missing default file is not an error.
+	home := os.Getenv("HOME")
+	assert.NoError(t, os.Setenv("HOME", dir))
+	got, err = Load("")
+	assert.NoError(t, err)
+	assert.Equal(t, Settings{}, got)
+	assert.NoError(t, os.Setenv("HOME", home))
+}
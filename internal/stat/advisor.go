@@ -0,0 +1,50 @@
+package stat
+
+import (
+	"strconv"
+)
+
+const (
+	// advisorMinRows defines minimal number of live rows of tables considered as large.
+	advisorMinRows = 10000
+	// advisorMinScans defines minimal rate of sequential scans per second considered as hot.
+	advisorMinScans = 1
+)
+
+// adviseIndexes calculates score of tables in index advisor view: rate of sequential scans multiplied by average
+// number of rows read by a scan. Hot sequential scans on large tables are flagged as candidates for indexing. Rows are
+// left as-is if required columns are not found.
+func adviseIndexes(res *PGresult) {
+	idx := map[string]int{}
+	for i, name := range res.Cols {
+		idx[name] = i
+	}
+
+	for _, name := range []string{"live", "avg_read", "seq_scan", "score", "advice", "indexes"} {
+		if _, ok := idx[name]; !ok {
+			return
+		}
+	}
+
+	for _, row := range res.Values {
+		live, err1 := strconv.ParseFloat(row[idx["live"]].String, 64)
+		avg, err2 := strconv.ParseFloat(row[idx["avg_read"]].String, 64)
+		scans, err3 := strconv.ParseFloat(row[idx["seq_scan"]].String, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+
+		row[idx["score"]].String = strconv.FormatFloat(scans*avg, 'f', 2, 64)
+		row[idx["score"]].Valid = true
+
+		var advice string
+		if live >= advisorMinRows && scans >= advisorMinScans {
+			advice = "index candidate"
+			if row[idx["indexes"]].String == "" {
+				advice = "index candidate, no indexes"
+			}
+		}
+		row[idx["advice"]].String = advice
+		row[idx["advice"]].Valid = true
+	}
+}
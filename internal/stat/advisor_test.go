@@ -0,0 +1,55 @@
+package stat
+
+import (
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_adviseIndexes(t *testing.T) {
+	cols := []string{"relation", "live", "avg_read", "seq_scan", "score", "advice", "indexes"}
+	row := func(values ...string) []sql.NullString {
+		r := make([]sql.NullString, len(values))
+		for i := range values {
+			r[i] = sql.NullString{String: values[i], Valid: true}
+		}
+		return r
+	}
+
+	prev := PGresult{
+		Valid: true, Ncols: 7, Nrows: 4, Cols: cols,
+		Values: [][]sql.NullString{
+			row("public.orders", "100000", "100000.00", "10.00", "1000000.00", "", "orders_pkey"),
+			row("public.events", "500000", "500000.00", "100.00", "50000000.00", "", ""),
+			row("public.small", "100", "100.00", "100.00", "10000.00", "", ""),
+			row("public.rare", "100000", "100000.00", "1.00", "100000.00", "", ""),
+		},
+	}
+	curr := PGresult{
+		Valid: true, Ncols: 7, Nrows: 4, Cols: cols,
+		Values: [][]sql.NullString{
+			row("public.orders", "100000", "100000.00", "15.00", "1500000.00", "", "orders_pkey"),
+			row("public.events", "500000", "500000.00", "102.00", "51000000.00", "", ""),
+			row("public.small", "100", "100.00", "200.00", "20000.00", "", ""),
+			row("public.rare", "100000", "100000.00", "1.00", "100000.00", "", ""),
+		},
+	}
+
+	v := view.New()[view.IndexAdvisor]
+	got, err := calculateDelta(curr, prev, 1, v)
+	assert.NoError(t, err)
+
+	want := [][]sql.NullString{
+		row("public.events", "500000", "500000.00", "2.00", "1000000.00", "index candidate, no indexes", ""),
+		row("public.orders", "100000", "100000.00", "5.00", "500000.00", "index candidate", "orders_pkey"),
+		row("public.small", "100", "100.00", "100.00", "10000.00", "", ""),
+		row("public.rare", "100000", "100000.00", "0.00", "0.00", "", ""),
+	}
+	assert.Equal(t, want, got.Values)
+
+	// Rows are left as-is when columns are not found.
+	res := PGresult{Valid: true, Ncols: 2, Nrows: 1, Cols: []string{"relation", "live"}, Values: [][]sql.NullString{row("public.t", "1")}}
+	adviseIndexes(&res)
+	assert.Equal(t, [][]sql.NullString{row("public.t", "1")}, res.Values)
+}
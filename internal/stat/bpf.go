@@ -0,0 +1,352 @@
+package stat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// bpfWindow defines number of tracing intervals (seconds) over which latency percentiles are calculated.
+	bpfWindow = 10
+
+	// Capabilities required for loading BPF programs: CAP_SYS_ADMIN on older kernels, or CAP_BPF with CAP_PERFMON
+	// since Linux 5.8.
+	capSysAdmin = 21
+	capPerfmon  = 38
+	capBPF      = 39
+
+	// bpfScript is bpftrace program which measures latency (in microseconds) of block I/O requests issued by Postgres
+	// processes and duration of futex waits of Postgres processes. Latencies are aggregated into per-process log2
+	// histograms which are printed and cleared every second.
+	bpfScript = `
+tracepoint:block:block_rq_issue /comm == "postgres"/ {
+	@issued[args->dev, args->sector] = nsecs;
+	@issuer[args->dev, args->sector] = pid;
+}
+tracepoint:block:block_rq_complete /@issued[args->dev, args->sector]/ {
+	@io[@issuer[args->dev, args->sector]] = hist((nsecs - @issued[args->dev, args->sector]) / 1000);
+	delete(@issued[args->dev, args->sector]);
+	delete(@issuer[args->dev, args->sector]);
+}
+tracepoint:syscalls:sys_enter_futex /comm == "postgres"/ {
+	@futex_start[tid] = nsecs;
+}
+tracepoint:syscalls:sys_exit_futex /@futex_start[tid]/ {
+	@futex[pid] = hist((nsecs - @futex_start[tid]) / 1000);
+	delete(@futex_start[tid]);
+}
+interval:s:1 {
+	print(@io);
+	print(@futex);
+	clear(@io);
+	clear(@futex);
+}
+END {
+	clear(@issued);
+	clear(@issuer);
+	clear(@futex_start);
+	clear(@io);
+	clear(@futex);
+}`
+)
+
+// BackendLatency defines latency percentiles of a Postgres backend measured with BPF, in milliseconds.
+type BackendLatency struct {
+	IOCount    uint64  // number of completed block I/O requests
+	IOP50      float64 // median latency of block I/O
+	IOP99      float64 // 99th percentile latency of block I/O
+	FutexCount uint64  // number of futex waits (e.g. waits on LWLocks and spinlocks)
+	FutexP99   float64 // 99th percentile duration of futex waits
+}
+
+// histogram defines log2 histogram of latencies: number of events keyed by upper bound of bucket in microseconds.
+type histogram map[int64]uint64
+
+// BPFTracer traces Postgres processes using bpftrace and keeps latency histograms of the recent tracing intervals.
+type BPFTracer struct {
+	logf func(format string, a ...interface{})
+
+	mu      sync.Mutex
+	windows map[string][]map[int]histogram // recent histograms per pid, keyed by bpftrace map name
+}
+
+// NewBPFTracer checks BPF tracing could be used and creates tracer. Errors of tracing are reported using logf.
+func NewBPFTracer(logf func(format string, a ...interface{})) (*BPFTracer, error) {
+	if _, err := exec.LookPath("bpftrace"); err != nil {
+		return nil, fmt.Errorf("bpftrace is required for BPF tracing: %s", err)
+	}
+
+	status, err := ioutil.ReadFile("/proc/self/status")
+	if err != nil {
+		return nil, fmt.Errorf("read process capabilities failed: %s", err)
+	}
+
+	if os.Geteuid() != 0 && !hasBPFCapabilities(status) {
+		return nil, fmt.Errorf("BPF tracing requires root, or CAP_BPF and CAP_PERFMON (CAP_SYS_ADMIN on kernels older than 5.8)")
+	}
+
+	return &BPFTracer{logf: logf, windows: map[string][]map[int]histogram{}}, nil
+}
+
+// Run runs bpftrace until context is done.
+func (t *BPFTracer) Run(ctx context.Context) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "bpftrace", "-f", "json", "-e", bpfScript) // #nosec G204
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.logf("BPF tracing failed: %s", err)
+		return
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		t.logf("BPF tracing failed: %s", err)
+		return
+	}
+
+	err = t.read(stdout)
+	if err != nil {
+		t.logf("BPF tracing failed: %s", err)
+	}
+
+	err = cmd.Wait()
+	if err != nil && ctx.Err() == nil {
+		t.logf("BPF tracing failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+}
+
+// Latency returns latency percentiles of Postgres backends over the recent tracing intervals, keyed by pid.
+func (t *BPFTracer) Latency() map[int]BackendLatency {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := map[int]BackendLatency{}
+
+	for pid, h := range mergeHistograms(t.windows["@io"]) {
+		l := res[pid]
+		l.IOCount, l.IOP50, l.IOP99 = h.count(), h.percentile(0.5), h.percentile(0.99)
+		res[pid] = l
+	}
+
+	for pid, h := range mergeHistograms(t.windows["@futex"]) {
+		l := res[pid]
+		l.FutexCount, l.FutexP99 = h.count(), h.percentile(0.99)
+		res[pid] = l
+	}
+
+	return res
+}
+
+// read reads histograms printed by bpftrace in JSON format.
+func (t *BPFTracer) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		hists, err := parseBPFHistograms(scanner.Bytes())
+		if err != nil {
+			return err
+		}
+
+		t.add(hists)
+	}
+
+	return scanner.Err()
+}
+
+// add adds histograms of tracing interval, histograms of the oldest interval are removed.
+func (t *BPFTracer) add(hists map[string]map[int]histogram) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for name, h := range hists {
+		w := append(t.windows[name], h)
+		if len(w) > bpfWindow {
+			w = w[len(w)-bpfWindow:]
+		}
+		t.windows[name] = w
+	}
+}
+
+// bpfBucket defines bucket of histogram printed by bpftrace.
+type bpfBucket struct {
+	Min   *int64 `json:"min"`
+	Max   *int64 `json:"max"`
+	Count uint64 `json:"count"`
+}
+
+// parseBPFHistograms parses line printed by bpftrace in JSON format and returns histograms keyed by map name and pid.
+// Lines other than histograms are ignored.
+func parseBPFHistograms(line []byte) (map[string]map[int]histogram, error) {
+	var msg struct {
+		Type string                     `json:"type"`
+		Data map[string]json.RawMessage `json:"data"`
+	}
+
+	err := json.Unmarshal(line, &msg)
+	if err != nil {
+		return nil, fmt.Errorf("parse bpftrace output failed: %s", err)
+	}
+
+	if msg.Type != "hist" {
+		return nil, nil
+	}
+
+	res := map[string]map[int]histogram{}
+	for name, raw := range msg.Data {
+		var keyed map[string][]bpfBucket
+		err := json.Unmarshal(raw, &keyed)
+		if err != nil {
+			// Empty maps are printed without keys.
+			keyed = nil
+		}
+
+		res[name] = map[int]histogram{}
+		for key, buckets := range keyed {
+			pid, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, fmt.Errorf("parse bpftrace output failed: invalid pid '%s'", key)
+			}
+
+			h := histogram{}
+			for _, b := range buckets {
+				// Negative values (the bucket without lower bound) are not expected for durations.
+				if b.Max == nil || b.Min == nil {
+					continue
+				}
+				h[*b.Max] += b.Count
+			}
+			res[name][pid] = h
+		}
+	}
+
+	return res, nil
+}
+
+// mergeHistograms merges per-pid histograms of several intervals.
+func mergeHistograms(window []map[int]histogram) map[int]histogram {
+	res := map[int]histogram{}
+	for _, hists := range window {
+		for pid, h := range hists {
+			if res[pid] == nil {
+				res[pid] = histogram{}
+			}
+			for k, v := range h {
+				res[pid][k] += v
+			}
+		}
+	}
+	return res
+}
+
+// count returns number of events in histogram.
+func (h histogram) count() uint64 {
+	var n uint64
+	for _, v := range h {
+		n += v
+	}
+	return n
+}
+
+// percentile returns estimated percentile of latency in milliseconds - upper bound of the bucket where the percentile
+// falls into.
+func (h histogram) percentile(p float64) float64 {
+	total := h.count()
+	if total == 0 {
+		return 0
+	}
+
+	bounds := make([]int64, 0, len(h))
+	for k := range h {
+		bounds = append(bounds, k)
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	var cum uint64
+	for _, b := range bounds {
+		cum += h[b]
+		if float64(cum) >= p*float64(total) {
+			return float64(b+1) / 1000
+		}
+	}
+
+	return float64(bounds[len(bounds)-1]+1) / 1000
+}
+
+// hasBPFCapabilities returns true if effective capabilities listed in /proc/<pid>/status allow loading BPF programs.
+func hasBPFCapabilities(status []byte) bool {
+	for _, line := range strings.Split(string(status), "\n") {
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+
+		caps, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		if err != nil {
+			return false
+		}
+
+		has := func(c uint) bool { return caps&(1<<c) != 0 }
+		return has(capSysAdmin) || (has(capBPF) && has(capPerfmon))
+	}
+
+	return false
+}
+
+// AnnotateActivity returns activity stats with appended latency percentiles of backends measured with BPF: 'io_p50',
+// 'io_p99' and 'futex_p99' in milliseconds. Rows are matched using 'pid' column, values of backends without measured
+// events are empty.
+func AnnotateActivity(res PGresult, latency map[int]BackendLatency) PGresult {
+	pidCol := -1
+	for i, c := range res.Cols {
+		if c == "pid" {
+			pidCol = i
+			break
+		}
+	}
+
+	if pidCol < 0 {
+		return res
+	}
+
+	format := func(v float64, n uint64) sql.NullString {
+		if n == 0 {
+			return sql.NullString{}
+		}
+		return sql.NullString{String: strconv.FormatFloat(v, 'f', 2, 64), Valid: true}
+	}
+
+	values := make([][]sql.NullString, len(res.Values))
+	for i, row := range res.Values {
+		values[i] = make([]sql.NullString, len(row), len(row)+3)
+		copy(values[i], row)
+
+		var l BackendLatency
+		if pidCol < len(row) {
+			if pid, err := strconv.Atoi(row[pidCol].String); err == nil {
+				l = latency[pid]
+			}
+		}
+
+		values[i] = append(values[i], format(l.IOP50, l.IOCount), format(l.IOP99, l.IOCount), format(l.FutexP99, l.FutexCount))
+	}
+
+	res.Values = values
+	res.Cols = append(append([]string{}, res.Cols...), "io_p50", "io_p99", "futex_p99")
+	res.Ncols = len(res.Cols)
+
+	return res
+}
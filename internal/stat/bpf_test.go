@@ -0,0 +1,112 @@
+package stat
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_parseBPFHistograms(t *testing.T) {
+	got, err := parseBPFHistograms([]byte(`{"type": "hist", "data": {"@io": {"123": [{"min": 64, "max": 127, "count": 3}, {"min": 128, "max": 255, "count": 1}], "456": [{"max": -1, "count": 1}, {"min": 0, "max": 0, "count": 2}]}}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[int]histogram{
+		"@io": {123: {127: 3, 255: 1}, 456: {0: 2}},
+	}, got)
+
+	// Empty map.
+	got, err = parseBPFHistograms([]byte(`{"type": "hist", "data": {"@futex": {}}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[int]histogram{"@futex": {}}, got)
+
+	// Other messages are ignored.
+	got, err = parseBPFHistograms([]byte(`{"type": "attached_probes", "data": {"probes": 5}}`))
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	// Invalid input.
+	for _, s := range []string{`invalid`, `{"type": "hist", "data": {"@io": {"invalid": [{"min": 1, "max": 1, "count": 1}]}}}`} {
+		_, err = parseBPFHistograms([]byte(s))
+		assert.Error(t, err)
+	}
+}
+
+func Test_histogram_percentile(t *testing.T) {
+	h := histogram{127: 90, 1023: 9, 16383: 1}
+	assert.Equal(t, uint64(100), h.count())
+	assert.Equal(t, 0.128, h.percentile(0.5))
+	assert.Equal(t, 1.024, h.percentile(0.99))
+	assert.Equal(t, 16.384, h.percentile(1))
+
+	assert.Equal(t, float64(0), histogram{}.percentile(0.99))
+}
+
+func TestBPFTracer_Latency(t *testing.T) {
+	tracer := &BPFTracer{windows: map[string][]map[int]histogram{}}
+
+	input := strings.Join([]string{
+		`{"type": "attached_probes", "data": {"probes": 5}}`,
+		`{"type": "hist", "data": {"@io": {"123": [{"min": 64, "max": 127, "count": 1}]}}}`,
+		`{"type": "hist", "data": {"@futex": {"123": [{"min": 8, "max": 15, "count": 5}], "456": [{"min": 1024, "max": 2047, "count": 1}]}}}`,
+		`{"type": "hist", "data": {"@io": {"123": [{"min": 1024, "max": 2047, "count": 1}]}}}`,
+		`{"type": "hist", "data": {"@futex": {}}}`,
+	}, "\n")
+
+	assert.NoError(t, tracer.read(strings.NewReader(input)))
+	assert.Equal(t, map[int]BackendLatency{
+		123: {IOCount: 2, IOP50: 0.128, IOP99: 2.048, FutexCount: 5, FutexP99: 0.016},
+		456: {FutexCount: 1, FutexP99: 2.048},
+	}, tracer.Latency())
+
+	// Only recent intervals are kept.
+	for i := 0; i < bpfWindow; i++ {
+		tracer.add(map[string]map[int]histogram{"@io": {}, "@futex": {}})
+	}
+	assert.Equal(t, map[int]BackendLatency{}, tracer.Latency())
+}
+
+func Test_hasBPFCapabilities(t *testing.T) {
+	testcases := []struct {
+		status string
+		want   bool
+	}{
+		{status: "Name:\tpgcenter\nCapEff:\t000001ffffffffff\n", want: true},
+		{status: "CapEff:\t0000000000200000\n", want: true},  // CAP_SYS_ADMIN
+		{status: "CapEff:\t000000c000000000\n", want: true},  // CAP_BPF, CAP_PERFMON
+		{status: "CapEff:\t0000008000000000\n", want: false}, // CAP_BPF only
+		{status: "CapEff:\t0000000000000000\n", want: false}, // no capabilities
+		{status: "Name:\tpgcenter\n", want: false},           // no capabilities info
+		{status: "CapEff:\tinvalid\n", want: false},          // invalid
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, hasBPFCapabilities([]byte(tc.status)))
+	}
+}
+
+func TestAnnotateActivity(t *testing.T) {
+	res := PGresult{
+		Values: [][]sql.NullString{
+			{{String: "123", Valid: true}, {String: "select 1", Valid: true}},
+			{{String: "456", Valid: true}, {String: "select 2", Valid: true}},
+		},
+		Cols: []string{"pid", "query"}, Ncols: 2, Nrows: 2, Valid: true,
+	}
+
+	got := AnnotateActivity(res, map[int]BackendLatency{123: {IOCount: 2, IOP50: 0.128, IOP99: 2.048, FutexCount: 5, FutexP99: 0.016}})
+	assert.Equal(t, PGresult{
+		Values: [][]sql.NullString{
+			{{String: "123", Valid: true}, {String: "select 1", Valid: true}, {String: "0.13", Valid: true}, {String: "2.05", Valid: true}, {String: "0.02", Valid: true}},
+			{{String: "456", Valid: true}, {String: "select 2", Valid: true}, {}, {}, {}},
+		},
+		Cols: []string{"pid", "query", "io_p50", "io_p99", "futex_p99"}, Ncols: 5, Nrows: 2, Valid: true,
+	}, got)
+
+	// Source result is not modified.
+	assert.Equal(t, []string{"pid", "query"}, res.Cols)
+	assert.Len(t, res.Values[0], 2)
+
+	// Results without pid column are not annotated.
+	res = PGresult{Cols: []string{"datname"}, Ncols: 1}
+	assert.Equal(t, res, AnnotateActivity(res, map[int]BackendLatency{}))
+}
@@ -0,0 +1,181 @@
+package stat
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"sort"
+	"strings"
+)
+
+const (
+	// Levels of views availability.
+	AvailableFull = iota
+	AvailablePartial
+	AvailableNone
+)
+
+// capabilitySettings defines settings which affect availability of stats views.
+var capabilitySettings = []string{"track_activities", "track_counts", "track_io_timing", "track_functions"}
+
+// Capability describes availability of stats view on the connected Postgres.
+type Capability struct {
+	View    string   // name of the view
+	Level   int      // level of availability
+	Reasons []string // reasons why view is not fully available
+}
+
+// GetCapabilities probes settings of Postgres and returns availability of stats views. Settings which can't be read
+// are considered enabled.
+func GetCapabilities(db *postgres.DB, props PostgresProperties) []Capability {
+	settings := map[string]string{}
+	for _, name := range capabilitySettings {
+		var value string
+		if err := db.QueryRow(query.GetSetting, name).Scan(&value); err == nil {
+			settings[name] = value
+		}
+	}
+
+	return capabilities(view.New(), props, settings)
+}
+
+// capabilities returns availability of views depending on properties and settings of Postgres.
+func capabilities(views view.Views, props PostgresProperties, settings map[string]string) []Capability {
+	opts := props.QueryOptions(0)
+
+	names := make([]string, 0, len(views))
+	for name := range views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := make([]Capability, 0, len(names))
+	for _, name := range names {
+		c := Capability{View: name}
+
+		// Adds reason which limits the view.
+		limit := func(level int, reason string) {
+			if level > c.Level {
+				c.Level = level
+			}
+			c.Reasons = append(c.Reasons, reason)
+		}
+
+		if _, ok := query.Select(name, opts); !ok {
+			limit(AvailableNone, fmt.Sprintf("requires Postgres %s or newer", FormatVersion(query.MinVersion(name))))
+		}
+
+		switch {
+		case views[name].IsStatements():
+			if !props.ExtPGSSAvail {
+				limit(AvailableNone, "pg_stat_statements not installed")
+			}
+			if !props.Privileges.AllStats() {
+				limit(AvailablePartial, "queries of other roles are hidden")
+			}
+			if (name == "statements_timings" || name == "statements_latency") && settings["track_io_timing"] == "off" {
+				limit(AvailablePartial, "track_io_timing is off, read_t and write_t are zero")
+			}
+		case name == "activity":
+			if settings["track_activities"] == "off" {
+				limit(AvailablePartial, "track_activities is off, queries are not shown")
+			}
+			if !props.Privileges.AllStats() {
+				limit(AvailablePartial, "queries of other roles are hidden")
+			}
+		case name == "replication":
+			if props.Recovery == "t" {
+				limit(AvailablePartial, "standby, only cascading replicas are shown")
+			}
+			if !props.Privileges.AllStats() {
+				limit(AvailablePartial, "positions of replicas are hidden")
+			}
+		case name == "replication_slots":
+			if props.VersionNum < 140000 {
+				limit(AvailablePartial, "spill and streaming stats of logical decoding require Postgres 14 or newer")
+			}
+		case name == "databases":
+			if settings["track_counts"] == "off" {
+				limit(AvailablePartial, "track_counts is off, counters are not updated")
+			}
+			if settings["track_io_timing"] == "off" {
+				limit(AvailablePartial, "track_io_timing is off, read_t and write_t are zero")
+			}
+		case name == "tables", name == "tables_io", name == "indexes", name == view.IndexAdvisor, name == "stale_stats":
+			if settings["track_counts"] == "off" {
+				limit(AvailableNone, "track_counts is off")
+			}
+		case name == "fdw":
+			if props.ExtPostgresFdwAvail && props.VersionNum < 140000 {
+				limit(AvailablePartial, "connections of postgres_fdw require Postgres 14 or newer")
+			}
+		case name == "functions":
+			if settings["track_functions"] == "none" {
+				limit(AvailableNone, "track_functions is none")
+			}
+		}
+
+		res = append(res, c)
+	}
+
+	return res
+}
+
+// CapabilitiesSummary returns one-line summary of views which are not fully available. Empty string is returned if
+// all views are available.
+func CapabilitiesSummary(caps []Capability) string {
+	var partial, none int
+	for _, c := range caps {
+		switch c.Level {
+		case AvailablePartial:
+			partial++
+		case AvailableNone:
+			none++
+		}
+	}
+
+	var parts []string
+	if none > 0 {
+		parts = append(parts, fmt.Sprintf("%d views not available", none))
+	}
+	if partial > 0 {
+		parts = append(parts, fmt.Sprintf("%d views partially available", partial))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return strings.Join(parts, ", ") + "."
+}
+
+// FormatCapabilities returns matrix of views availability, one view per line.
+func FormatCapabilities(caps []Capability) string {
+	var b strings.Builder
+	for _, c := range caps {
+		var level string
+		switch c.Level {
+		case AvailableFull:
+			level = "full"
+		case AvailablePartial:
+			level = "partial"
+		default:
+			level = "none"
+		}
+
+		line := fmt.Sprintf("%-20s %-8s", c.View, level)
+		if len(c.Reasons) > 0 {
+			line += " " + strings.Join(c.Reasons, "; ")
+		}
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	return b.String()
+}
+
+// FormatVersion converts numeric representation of Postgres version to a string, e.g. '9.6' or '13'.
+func FormatVersion(num int) string {
+	if num >= 100000 {
+		return fmt.Sprintf("%d", num/10000)
+	}
+	return fmt.Sprintf("%d.%d", num/10000, num/100%100)
+}
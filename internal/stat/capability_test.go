@@ -0,0 +1,110 @@
+package stat
+
+import (
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	conn, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	props, err := GetPostgresProperties(conn)
+	assert.NoError(t, err)
+
+	got := GetCapabilities(conn, props)
+	assert.Len(t, got, len(view.New()))
+}
+
+func Test_capabilities(t *testing.T) {
+	levels := func(caps []Capability) map[string]int {
+		res := map[string]int{}
+		for _, c := range caps {
+			res[c.View] = c.Level
+		}
+		return res
+	}
+
+	// All views are available.
+	props := PostgresProperties{VersionNum: 180000, Recovery: "f", ExtPGSSAvail: true, Privileges: Privileges{Superuser: true}}
+	settings := map[string]string{"track_activities": "on", "track_counts": "on", "track_io_timing": "on", "track_functions": "pl"}
+
+	got := capabilities(view.New(), props, settings)
+	assert.Len(t, got, len(view.New()))
+	for _, c := range got {
+		assert.Equal(t, AvailableFull, c.Level, c.View)
+		assert.Empty(t, c.Reasons, c.View)
+	}
+
+	// Old standby without pg_stat_statements, connected as a regular role.
+	props = PostgresProperties{VersionNum: 90600, Recovery: "t"}
+	settings = map[string]string{"track_activities": "on", "track_counts": "on", "track_io_timing": "off", "track_functions": "none"}
+
+	got = capabilities(view.New(), props, settings)
+	l := levels(got)
+	assert.Equal(t, AvailablePartial, l["activity"])
+	assert.Equal(t, AvailablePartial, l["replication"])
+	assert.Equal(t, AvailablePartial, l["databases"])
+	assert.Equal(t, AvailableFull, l["tables"])
+	assert.Equal(t, AvailableFull, l["checkpoints"])
+	assert.Equal(t, AvailableNone, l["functions"])
+	assert.Equal(t, AvailableNone, l["statements_timings"])
+	assert.Equal(t, AvailableFull, l["progress_vacuum"])
+	assert.Equal(t, AvailableNone, l["progress_index"])
+
+	for _, c := range got {
+		switch c.View {
+		case "progress_index":
+			assert.Equal(t, []string{"requires Postgres 12 or newer"}, c.Reasons)
+		case "statements_io":
+			assert.Equal(t, []string{"pg_stat_statements not installed", "queries of other roles are hidden"}, c.Reasons)
+		}
+	}
+
+	// Disabled track_counts.
+	settings = map[string]string{"track_counts": "off"}
+	l = levels(capabilities(view.New(), PostgresProperties{VersionNum: 170000, Privileges: Privileges{Superuser: true}}, settings))
+	assert.Equal(t, AvailableNone, l["tables"])
+	assert.Equal(t, AvailableNone, l["tables_io"])
+	assert.Equal(t, AvailableNone, l["indexes"])
+	assert.Equal(t, AvailableNone, l["stale_stats"])
+	assert.Equal(t, AvailablePartial, l["databases"])
+
+	// Connections of postgres_fdw are not available on old Postgres.
+	l = levels(capabilities(view.New(), PostgresProperties{VersionNum: 130000, ExtPostgresFdwAvail: true}, settings))
+	assert.Equal(t, AvailablePartial, l["fdw"])
+	l = levels(capabilities(view.New(), PostgresProperties{VersionNum: 130000}, settings))
+	assert.Equal(t, AvailableFull, l["fdw"])
+	assert.Equal(t, AvailablePartial, l["replication_slots"])
+}
+
+func TestCapabilitiesSummary(t *testing.T) {
+	assert.Equal(t, "", CapabilitiesSummary([]Capability{{View: "activity"}}))
+	assert.Equal(t, "1 views not available, 2 views partially available.", CapabilitiesSummary([]Capability{
+		{View: "activity", Level: AvailablePartial},
+		{View: "functions", Level: AvailableNone},
+		{View: "replication", Level: AvailablePartial},
+	}))
+}
+
+func TestFormatCapabilities(t *testing.T) {
+	got := FormatCapabilities([]Capability{
+		{View: "activity", Level: AvailablePartial, Reasons: []string{"track_activities is off", "queries of other roles are hidden"}},
+		{View: "functions", Level: AvailableNone, Reasons: []string{"track_functions is none"}},
+		{View: "tables", Level: AvailableFull},
+	})
+	assert.Equal(t,
+		"activity             partial  track_activities is off; queries of other roles are hidden\n"+
+			"functions            none     track_functions is none\n"+
+			"tables               full\n",
+		got,
+	)
+}
+
+func TestFormatVersion(t *testing.T) {
+	assert.Equal(t, "9.5", FormatVersion(90500))
+	assert.Equal(t, "13", FormatVersion(130000))
+}
@@ -0,0 +1,149 @@
+package stat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/pgtime"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// controlTimeLayout defines layout of times printed by pg_controldata in C locale.
+const controlTimeLayout = "Mon Jan _2 15:04:05 2006"
+
+// controlData describes state of the cluster kept in its control file (pg_control). The file is read by pg_controldata,
+// hence it is available even when Postgres is down, e.g. during crash recovery.
+type controlData struct {
+	state          string    // state of the cluster, e.g. 'in production' or 'in crash recovery'
+	checkpoint     string    // location of the latest checkpoint
+	redo           string    // REDO location of the latest checkpoint, crash recovery starts from this location
+	redoWAL        string    // WAL segment which contains REDO location
+	timeline       string    // timeline of the latest checkpoint
+	prevTimeline   string    // previous timeline of the latest checkpoint, differs from timeline after promotion
+	checkpointTime string    // time of the latest checkpoint as printed by pg_controldata
+	checkpointAt   time.Time // time of the latest checkpoint, zero if it could not be parsed
+	minRecoveryEnd string    // location which recovery must reach before the standby is consistent
+	checksums      string    // version of data page checksums, zero if checksums are disabled
+}
+
+// parseControldata parses output of pg_controldata. Output is expected in C locale, names of fields are translated.
+func parseControldata(out string) controlData {
+	fields := map[string]string{}
+
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		fields[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	c := controlData{
+		state:          fields["Database cluster state"],
+		checkpoint:     fields["Latest checkpoint location"],
+		redo:           fields["Latest checkpoint's REDO location"],
+		redoWAL:        fields["Latest checkpoint's REDO WAL file"],
+		timeline:       fields["Latest checkpoint's TimeLineID"],
+		prevTimeline:   fields["Latest checkpoint's PrevTimeLineID"],
+		checkpointTime: fields["Time of latest checkpoint"],
+		minRecoveryEnd: fields["Minimum recovery ending location"],
+		checksums:      fields["Data page checksum version"],
+	}
+
+	if t, err := time.ParseInLocation(controlTimeLayout, c.checkpointTime, time.Local); err == nil {
+		c.checkpointAt = t
+	}
+
+	return c
+}
+
+// checkControldata checks control file of the cluster using pg_controldata: state of the cluster, the latest
+// checkpoint and its age, timeline and data checksums.
+func (i datadirInspector) checkControldata(ctx context.Context) []datadirCheck {
+	out, err := i.controldata(ctx, i.dirs.bin, i.dirs.data)
+	if err != nil {
+		return []datadirCheck{{name: "pg_control", status: checkError, details: err.Error()}}
+	}
+
+	control := parseControldata(out)
+
+	state := datadirCheck{name: "cluster_state", status: checkOK, details: control.state}
+	switch control.state {
+	case "in production":
+	case "in archive recovery":
+		if control.minRecoveryEnd != "" && control.minRecoveryEnd != "0/0" {
+			state.details += fmt.Sprintf(", consistent at %s", control.minRecoveryEnd)
+		}
+	case "":
+		state.status, state.details = checkError, "cluster state not found in pg_controldata output"
+	default:
+		state.status = checkWarning
+	}
+
+	checkpoint := datadirCheck{name: "checkpoint", status: checkInfo}
+	switch {
+	case control.checkpoint == "":
+		checkpoint.status, checkpoint.details = checkError, "checkpoint location not found in pg_controldata output"
+	default:
+		checkpoint.details = fmt.Sprintf("redo %s", control.redo)
+		if control.redoWAL != "" {
+			checkpoint.details += fmt.Sprintf(" (%s)", control.redoWAL)
+		}
+		checkpoint.details += fmt.Sprintf(", location %s, time %s", control.checkpoint, control.checkpointTime)
+		if !control.checkpointAt.IsZero() {
+			checkpoint.details += fmt.Sprintf(" (%s)", pgtime.Since(control.checkpointAt, i.now))
+		}
+	}
+
+	timeline := datadirCheck{name: "timeline", status: checkInfo, details: control.timeline}
+	switch {
+	case control.timeline == "":
+		timeline.status, timeline.details = checkError, "timeline not found in pg_controldata output"
+	case control.prevTimeline != "" && control.prevTimeline != control.timeline:
+		timeline.details += fmt.Sprintf(", switched from %s at the latest checkpoint", control.prevTimeline)
+	}
+
+	checksums := datadirCheck{name: "data_checksums", status: checkOK}
+	switch control.checksums {
+	case "0":
+		checksums.status, checksums.details = checkInfo, "disabled, corruption of data pages is not detected"
+	case "":
+		checksums.status, checksums.details = checkError, "checksum version not found in pg_controldata output"
+	default:
+		checksums.details = fmt.Sprintf("enabled, version %s", control.checksums)
+	}
+
+	return []datadirCheck{state, checkpoint, timeline, checksums}
+}
+
+// runControldata runs pg_controldata for the data directory. The executable is looked up in the directory of
+// Postgres executables, and then in PATH. Output is requested in C locale, because names of fields are translated.
+func runControldata(ctx context.Context, bin, data string) (string, error) {
+	name := "pg_controldata"
+	if bin != "" {
+		if _, err := os.Stat(filepath.Join(bin, name)); err == nil {
+			name = filepath.Join(bin, name)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, "-D", data)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return "", fmt.Errorf("run pg_controldata failed: %s", err)
+		}
+		return "", fmt.Errorf("run pg_controldata failed: %s: %s", err, msg)
+	}
+
+	return stdout.String(), nil
+}
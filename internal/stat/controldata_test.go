@@ -0,0 +1,66 @@
+package stat
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const testControldata = `pg_control version number:            1300
+Catalog version number:               202007201
+Database system identifier:           6912345678901234567
+Database cluster state:               in production
+pg_control last modified:             Thu Mar  4 05:06:07 2021
+Latest checkpoint location:           0/3000148
+Latest checkpoint's REDO location:    0/3000110
+Latest checkpoint's REDO WAL file:    000000020000000000000003
+Latest checkpoint's TimeLineID:       2
+Latest checkpoint's PrevTimeLineID:   1
+Time of latest checkpoint:            Thu Mar  4 05:06:07 2021
+Minimum recovery ending location:     0/0
+Data page checksum version:           1
+`
+
+func Test_parseControldata(t *testing.T) {
+	assert.Equal(t, controlData{
+		state:          "in production",
+		checkpoint:     "0/3000148",
+		redo:           "0/3000110",
+		redoWAL:        "000000020000000000000003",
+		timeline:       "2",
+		prevTimeline:   "1",
+		checkpointTime: "Thu Mar  4 05:06:07 2021",
+		checkpointAt:   time.Date(2021, 3, 4, 5, 6, 7, 0, time.Local),
+		minRecoveryEnd: "0/0",
+		checksums:      "1",
+	}, parseControldata(testControldata))
+
+	// Time of checkpoint printed in other locale is not parsed.
+	got := parseControldata("Time of latest checkpoint:            Thu 04 Mar 2021 05:06:07 AM UTC\n")
+	assert.Equal(t, "Thu 04 Mar 2021 05:06:07 AM UTC", got.checkpointTime)
+	assert.True(t, got.checkpointAt.IsZero())
+}
+
+func Test_datadirInspector_checkControldata(t *testing.T) {
+	i := datadirInspector{
+		now: time.Date(2021, 3, 4, 6, 6, 7, 0, time.Local),
+		controldata: func(_ context.Context, _, _ string) (string, error) {
+			return "Database cluster state:               in archive recovery\n" +
+				"Latest checkpoint location:           0/5000060\n" +
+				"Latest checkpoint's REDO location:    0/5000028\n" +
+				"Latest checkpoint's TimeLineID:       1\n" +
+				"Latest checkpoint's PrevTimeLineID:   1\n" +
+				"Time of latest checkpoint:            Thu Mar  4 05:06:07 2021\n" +
+				"Minimum recovery ending location:     0/5000110\n" +
+				"Data page checksum version:           0\n", nil
+		},
+	}
+
+	assert.Equal(t, []datadirCheck{
+		{name: "cluster_state", status: "ok", details: "in archive recovery, consistent at 0/5000110"},
+		{name: "checkpoint", status: "info", details: "redo 0/5000028, location 0/5000060, time Thu Mar  4 05:06:07 2021 (1h 0m ago)"},
+		{name: "timeline", status: "info", details: "1"},
+		{name: "data_checksums", status: "info", details: "disabled, corruption of data pages is not detected"},
+	}, i.checkControldata(context.Background()))
+}
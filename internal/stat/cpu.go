@@ -4,6 +4,7 @@ package stat
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"io"
@@ -29,11 +30,12 @@ type CpuStat struct {
 }
 
 // readCpuStat returns CPU stats based on type of passed DB connection.
-func readCpuStat(db *postgres.DB, schemaExists bool) (CpuStat, error) {
+// Remote stats are read only if stats schema is specified.
+func readCpuStat(ctx context.Context, db *postgres.DB, schema string) (CpuStat, error) {
 	if db.Local {
 		return readCpuStatLocal("/proc/stat")
-	} else if schemaExists {
-		return readCpuStatRemote(db)
+	} else if schema != "" {
+		return readCpuStatRemote(ctx, db, schema)
 	}
 
 	return CpuStat{}, nil
@@ -88,10 +90,10 @@ func readCpuStatLocal(statfile string) (CpuStat, error) {
 }
 
 // readCpuStatRemote returns CPU stats from SQL stats schema.
-func readCpuStatRemote(db *postgres.DB) (CpuStat, error) {
+func readCpuStatRemote(ctx context.Context, db *postgres.DB, schema string) (CpuStat, error) {
 	var stat CpuStat
-	q := `SELECT cpu,us_time::numeric,ni_time::numeric,sy_time::numeric,id_time::numeric,wa_time::numeric,hi_time::numeric,si_time::numeric,st_time::numeric,quest_time::numeric,guest_ni_time::numeric FROM pgcenter.sys_proc_stat WHERE cpu = 'cpu'`
-	err := db.QueryRow(q).Scan(&stat.Entry, &stat.User, &stat.Nice, &stat.Sys, &stat.Idle,
+	q := `SELECT cpu,us_time::numeric,ni_time::numeric,sy_time::numeric,id_time::numeric,wa_time::numeric,hi_time::numeric,si_time::numeric,st_time::numeric,quest_time::numeric,guest_ni_time::numeric FROM %s.sys_proc_stat WHERE cpu = 'cpu'`
+	err := db.QueryRowPreparedContext(ctx, schemaQuery(q, schema)).Scan(&stat.Entry, &stat.User, &stat.Nice, &stat.Sys, &stat.Idle,
 		&stat.Iowait, &stat.Irq, &stat.Softirq, &stat.Steal, &stat.Guest, &stat.GstNice)
 	if err != nil {
 		return stat, err
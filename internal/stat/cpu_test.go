@@ -1,6 +1,7 @@
 package stat
 
 import (
+	"context"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -13,18 +14,18 @@ func Test_readCpuStat(t *testing.T) {
 
 	// test "local" reading
 	conn.Local = true
-	got, err := readCpuStat(conn, false)
+	got, err := readCpuStat(context.Background(), conn, "")
 	assert.NoError(t, err)
 	assert.Greater(t, got.Total, float64(0))
 
 	// test "remote" reading
 	conn.Local = false
-	got, err = readCpuStat(conn, true)
+	got, err = readCpuStat(context.Background(), conn, "pgcenter")
 	assert.NoError(t, err)
 	assert.Greater(t, got.Total, float64(0))
 
 	// test "remote", but when schema is not available
-	got, err = readCpuStat(conn, false)
+	got, err = readCpuStat(context.Background(), conn, "")
 	assert.NoError(t, err)
 	assert.Equal(t, got.Total, float64(0))
 }
@@ -71,14 +72,14 @@ func Test_readCpuStatRemote(t *testing.T) {
 	conn, err := postgres.NewTestConnect()
 	assert.NoError(t, err)
 
-	got, err := readCpuStatRemote(conn)
+	got, err := readCpuStatRemote(context.Background(), conn, "pgcenter")
 	assert.NoError(t, err)
 	assert.Greater(t, got.Total, float64(0))
 	assert.Greater(t, got.User, float64(0))
 	assert.Greater(t, got.Sys, float64(0))
 
 	conn.Close()
-	_, err = readCpuStatRemote(conn)
+	_, err = readCpuStatRemote(context.Background(), conn, "pgcenter")
 	assert.Error(t, err)
 }
 
@@ -0,0 +1,311 @@
+package stat
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Statuses of data directory health checks.
+const (
+	checkOK       = "ok"
+	checkInfo     = "info"
+	checkWarning  = "warning"
+	checkCritical = "critical"
+	checkError    = "error"
+)
+
+// datadirHealthCols defines columns of data directory health view.
+var datadirHealthCols = []string{"check", "status", "details"}
+
+// reTempFile matches names of temporary files and filesets of shared temporary files, the first group is the process
+// ID of the backend which created the file.
+var reTempFile = regexp.MustCompile(`^pgsql_tmp(\d+)\.`)
+
+// datadirCheck describes result of a single check of data directory.
+type datadirCheck struct {
+	name    string
+	status  string
+	details string
+}
+
+// datadirInspector inspects data directory of local Postgres. Inspection is read-only, neither files are changed nor
+// Postgres is asked to do something.
+type datadirInspector struct {
+	dirs    storageDirs
+	version int
+	now     time.Time // time of inspection, ages of events are calculated relative to it
+	// processAlive returns true if process with specified ID is running, temporary files of not running processes
+	// are orphaned.
+	processAlive func(pid int) bool
+	// controldata returns output of pg_controldata for the data directory.
+	controldata func(ctx context.Context, bin, data string) (string, error)
+}
+
+// isDatadirHealth returns true if rows of the view are built from inspecting data directory instead of reading stats.
+func isDatadirHealth(v view.View) bool {
+	return v.Name == view.DatadirHealth
+}
+
+// readDatadirHealth inspects data directory of local Postgres and returns results of checks as rows of stats.
+func readDatadirHealth(ctx context.Context, dirs storageDirs, version int) (PGresult, error) {
+	i := datadirInspector{
+		dirs:         dirs,
+		version:      version,
+		now:          time.Now(),
+		processAlive: processAlive,
+		controldata:  runControldata,
+	}
+
+	return i.inspect(ctx)
+}
+
+// inspect runs all checks of data directory.
+func (i datadirInspector) inspect(ctx context.Context) (PGresult, error) {
+	if i.dirs.err != nil {
+		return PGresult{}, i.dirs.err
+	}
+
+	var checks []datadirCheck
+	checks = append(checks, i.checkPermissions())
+	checks = append(checks, i.checkBackupLabel()...)
+	checks = append(checks, i.checkRecovery())
+	checks = append(checks, i.checkTempFiles())
+	checks = append(checks, i.checkLostFound()...)
+	checks = append(checks, i.checkControldata(ctx)...)
+
+	if ctx.Err() != nil {
+		return PGresult{}, ctx.Err()
+	}
+
+	values := make([][]sql.NullString, 0, len(checks))
+	for _, c := range checks {
+		values = append(values, []sql.NullString{
+			{String: c.name, Valid: true},
+			{String: c.status, Valid: true},
+			{String: c.details, Valid: true},
+		})
+	}
+
+	return PGresult{
+		Valid:  true,
+		Ncols:  len(datadirHealthCols),
+		Nrows:  len(values),
+		Cols:   datadirHealthCols,
+		Values: values,
+		Time:   i.now,
+	}, nil
+}
+
+// checkPermissions checks permissions of data directory. Postgres requires 0700, or 0750 when group access is allowed
+// (Postgres 11 and newer), permissions changed after start are noticed by Postgres only at the next start.
+func (i datadirInspector) checkPermissions() datadirCheck {
+	c := datadirCheck{name: "permissions"}
+
+	info, err := os.Stat(i.dirs.data)
+	if err != nil {
+		c.status, c.details = checkError, err.Error()
+		return c
+	}
+
+	mode := info.Mode().Perm()
+	c.details = fmt.Sprintf("%s mode %04o", i.dirs.data, mode)
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		c.details += fmt.Sprintf(", owner uid %d", st.Uid)
+	}
+
+	switch {
+	case mode == 0700:
+		c.status = checkOK
+	case mode == 0750 && i.version >= 110000:
+		c.status = checkOK
+		c.details += ", group access allowed"
+	default:
+		c.status = checkWarning
+		c.details += ", expected 0700 or 0750, Postgres will refuse to start"
+	}
+
+	return c
+}
+
+// checkBackupLabel checks presence of backup_label and tablespace_map files. These files exist during exclusive backup
+// or in restored backup; label left after restore makes Postgres start recovery from the wrong checkpoint.
+func (i datadirInspector) checkBackupLabel() []datadirCheck {
+	label := datadirCheck{name: "backup_label", status: checkOK, details: "not present"}
+
+	data, err := ioutil.ReadFile(filepath.Join(i.dirs.data, "backup_label"))
+	switch {
+	case err == nil:
+		label.status = checkWarning
+		label.details = "present: exclusive backup is in progress or label of restored backup has not been removed"
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "START TIME:") {
+				label.details += ", backup start time" + strings.TrimPrefix(line, "START TIME:")
+			}
+		}
+	case !os.IsNotExist(err):
+		label.status, label.details = checkError, err.Error()
+	}
+
+	checks := []datadirCheck{label}
+
+	if _, err := os.Stat(filepath.Join(i.dirs.data, "tablespace_map")); err == nil {
+		checks = append(checks, datadirCheck{name: "tablespace_map", status: checkWarning, details: "present: exclusive backup is in progress or map of restored backup has not been removed"})
+	}
+
+	return checks
+}
+
+// checkRecovery checks presence of files which make Postgres to start in recovery. Since Postgres 12 recovery is
+// requested by signal files, presence of recovery.conf prevents Postgres from starting.
+func (i datadirInspector) checkRecovery() datadirCheck {
+	c := datadirCheck{name: "recovery", status: checkOK, details: "no recovery files"}
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(i.dirs.data, name))
+		return err == nil
+	}
+
+	if i.version < 120000 {
+		if exists("recovery.conf") {
+			c.status, c.details = checkInfo, "recovery.conf present: standby or recovery mode"
+		}
+		return c
+	}
+
+	var found []string
+	for _, name := range []string{"standby.signal", "recovery.signal"} {
+		if exists(name) {
+			found = append(found, name)
+		}
+	}
+
+	switch {
+	case exists("recovery.conf"):
+		c.status, c.details = checkCritical, "recovery.conf present: it is not supported since Postgres 12, Postgres will refuse to start"
+	case len(found) > 0:
+		c.status = checkInfo
+		c.details = strings.Join(found, ", ") + " present: "
+		if found[0] == "standby.signal" {
+			c.details += "standby mode"
+		} else {
+			c.details += "targeted recovery mode"
+		}
+	}
+
+	return c
+}
+
+// checkTempFiles checks temporary files in default and other tablespaces. Files are removed by backends which created
+// them, files of not running backends are orphaned (e.g. left after crash) and remain until the next restart.
+func (i datadirInspector) checkTempFiles() datadirCheck {
+	c := datadirCheck{name: "temp_files", status: checkOK}
+
+	dirs := []string{filepath.Join(i.dirs.data, "base", "pgsql_tmp")}
+	tblspc, _ := filepath.Glob(filepath.Join(i.dirs.data, "pg_tblspc", "*", "PG_*", "pgsql_tmp"))
+	dirs = append(dirs, tblspc...)
+
+	var (
+		files, orphans int
+		size           int64
+	)
+
+	for _, dir := range dirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			c.status, c.details = checkError, err.Error()
+			return c
+		}
+
+		for _, e := range entries {
+			m := reTempFile.FindStringSubmatch(e.Name())
+			if m == nil {
+				continue
+			}
+			files++
+
+			pid, err := strconv.Atoi(m[1])
+			if err != nil || i.processAlive(pid) {
+				continue
+			}
+
+			orphans++
+			if e.IsDir() {
+				s, _, err := dirUsage(context.Background(), filepath.Join(dir, e.Name()), false)
+				if err == nil {
+					size += s
+				}
+			} else {
+				size += e.Size()
+			}
+		}
+	}
+
+	if orphans > 0 {
+		c.status = checkWarning
+		c.details = fmt.Sprintf("%d orphaned of %d temporary files (%d bytes) created by not running processes, removed at the next restart", orphans, files, size)
+		return c
+	}
+
+	c.details = fmt.Sprintf("%d temporary files, no orphaned files", files)
+	return c
+}
+
+// checkLostFound checks lost+found directories in data directory and locations of tablespaces. Such directories exist
+// at roots of filesystems, i.e. directories are mount points which is not recommended; files in lost+found are
+// fragments recovered by fsck after filesystem corruption.
+func (i datadirInspector) checkLostFound() []datadirCheck {
+	locations := []string{i.dirs.data}
+
+	links, _ := filepath.Glob(filepath.Join(i.dirs.data, "pg_tblspc", "*"))
+	for _, link := range links {
+		if target, err := os.Readlink(link); err == nil {
+			locations = append(locations, target)
+		}
+	}
+
+	var checks []datadirCheck
+	for _, loc := range locations {
+		path := filepath.Join(loc, "lost+found")
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		c := datadirCheck{name: "lost+found", status: checkInfo, details: fmt.Sprintf("%s exists: %s is a mount point", path, loc)}
+
+		entries, err := ioutil.ReadDir(path)
+		switch {
+		case err != nil:
+			c.details += fmt.Sprintf(", content is not readable: %s", err)
+		case len(entries) > 0:
+			c.status = checkWarning
+			c.details = fmt.Sprintf("%s contains %d files recovered by fsck, filesystem might have been corrupted", path, len(entries))
+		}
+
+		checks = append(checks, c)
+	}
+
+	if len(checks) == 0 {
+		checks = append(checks, datadirCheck{name: "lost+found", status: checkOK, details: "not present"})
+	}
+
+	return checks
+}
+
+// processAlive returns true if local process with specified ID is running.
+func processAlive(pid int) bool {
+	_, err := os.Stat(fmt.Sprintf("/proc/%d", pid))
+	return err == nil
+}
@@ -0,0 +1,138 @@
+package stat
+
+import (
+	"context"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_datadirInspector_inspect(t *testing.T) {
+	data, err := ioutil.TempDir("", "pgcenter-datadir-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(data) }()
+	assert.NoError(t, os.Chmod(data, 0700))
+
+	tblspc, err := ioutil.TempDir("", "pgcenter-tblspc-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tblspc) }()
+
+	write := func(path string, size int) {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		assert.NoError(t, ioutil.WriteFile(path, make([]byte, size), 0600))
+	}
+
+	i := datadirInspector{
+		dirs:         storageDirs{data: data},
+		version:      130000,
+		now:          time.Date(2021, 3, 4, 5, 8, 10, 0, time.Local),
+		processAlive: func(pid int) bool { return pid == 100 },
+		controldata: func(_ context.Context, _, _ string) (string, error) {
+			return testControldata, nil
+		},
+	}
+
+	// Healthy data directory.
+	got, err := i.inspect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, datadirHealthCols, got.Cols)
+	assert.Equal(t, [][]string{
+		{"permissions", "ok", fmt.Sprintf("%s mode 0700, owner uid %d", data, os.Getuid())},
+		{"backup_label", "ok", "not present"},
+		{"recovery", "ok", "no recovery files"},
+		{"temp_files", "ok", "0 temporary files, no orphaned files"},
+		{"lost+found", "ok", "not present"},
+		{"cluster_state", "ok", "in production"},
+		{"checkpoint", "info", "redo 0/3000110 (000000020000000000000003), location 0/3000148, time Thu Mar  4 05:06:07 2021 (2m 3s ago)"},
+		{"timeline", "info", "2, switched from 1 at the latest checkpoint"},
+		{"data_checksums", "ok", "enabled, version 1"},
+	}, resultValues(got))
+
+	// Unhealthy data directory.
+	assert.NoError(t, os.Chmod(data, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(data, "backup_label"), []byte("START WAL LOCATION: 0/2000028\nSTART TIME: 2021-03-04 05:06:07 UTC\n"), 0600))
+	write(filepath.Join(data, "standby.signal"), 0)
+	write(filepath.Join(data, "recovery.conf"), 0)
+	write(filepath.Join(data, "base", "pgsql_tmp", "pgsql_tmp100.0"), 100)
+	write(filepath.Join(data, "base", "pgsql_tmp", "pgsql_tmp200.0"), 200)
+	write(filepath.Join(data, "base", "pgsql_tmp", "pgsql_tmp300.1.sharedfileset", "0.0"), 300)
+	write(filepath.Join(tblspc, "lost+found", "#12345"), 10)
+	assert.NoError(t, os.MkdirAll(filepath.Join(data, "pg_tblspc"), 0700))
+	assert.NoError(t, os.Symlink(tblspc, filepath.Join(data, "pg_tblspc", "16384")))
+
+	i.controldata = func(_ context.Context, _, _ string) (string, error) {
+		return "Database cluster state:               in crash recovery\nData page checksum version:           0\n", nil
+	}
+
+	got, err = i.inspect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"permissions", "warning", fmt.Sprintf("%s mode 0755, owner uid %d, expected 0700 or 0750, Postgres will refuse to start", data, os.Getuid())},
+		{"backup_label", "warning", "present: exclusive backup is in progress or label of restored backup has not been removed, backup start time 2021-03-04 05:06:07 UTC"},
+		{"recovery", "critical", "recovery.conf present: it is not supported since Postgres 12, Postgres will refuse to start"},
+		{"temp_files", "warning", "2 orphaned of 3 temporary files (500 bytes) created by not running processes, removed at the next restart"},
+		{"lost+found", "warning", fmt.Sprintf("%s contains 1 files recovered by fsck, filesystem might have been corrupted", filepath.Join(tblspc, "lost+found"))},
+		{"cluster_state", "warning", "in crash recovery"},
+		{"checkpoint", "error", "checkpoint location not found in pg_controldata output"},
+		{"timeline", "error", "timeline not found in pg_controldata output"},
+		{"data_checksums", "info", "disabled, corruption of data pages is not detected"},
+	}, resultValues(got))
+
+	// Failed pg_controldata.
+	i.controldata = func(_ context.Context, _, _ string) (string, error) {
+		return "", fmt.Errorf("run pg_controldata failed: exit status 1")
+	}
+	got, err = i.inspect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pg_control", "error", "run pg_controldata failed: exit status 1"}, resultValues(got)[got.Nrows-1])
+
+	// Error occurred during resolving directories.
+	i.dirs = storageDirs{err: os.ErrPermission}
+	_, err = i.inspect(context.Background())
+	assert.Error(t, err)
+}
+
+func Test_datadirInspector_checkRecovery(t *testing.T) {
+	data, err := ioutil.TempDir("", "pgcenter-datadir-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(data) }()
+
+	i := datadirInspector{dirs: storageDirs{data: data}, version: 110000}
+	assert.Equal(t, datadirCheck{name: "recovery", status: "ok", details: "no recovery files"}, i.checkRecovery())
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(data, "recovery.conf"), nil, 0600))
+	assert.Equal(t, datadirCheck{name: "recovery", status: "info", details: "recovery.conf present: standby or recovery mode"}, i.checkRecovery())
+
+	assert.NoError(t, os.Remove(filepath.Join(data, "recovery.conf")))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(data, "recovery.signal"), nil, 0600))
+	i.version = 140000
+	assert.Equal(t, datadirCheck{name: "recovery", status: "info", details: "recovery.signal present: targeted recovery mode"}, i.checkRecovery())
+}
+
+func Test_readDatadirHealth(t *testing.T) {
+	_, err := readDatadirHealth(context.Background(), storageDirs{err: os.ErrPermission}, 130000)
+	assert.Error(t, err)
+}
+
+func Test_isDatadirHealth(t *testing.T) {
+	assert.True(t, isDatadirHealth(view.DatadirHealthView()))
+	assert.False(t, isDatadirHealth(view.SessionsHistory(time.Minute)))
+}
+
+// resultValues returns values of the result as strings.
+func resultValues(r PGresult) [][]string {
+	values := make([][]string, 0, len(r.Values))
+	for _, row := range r.Values {
+		v := make([]string, 0, len(row))
+		for _, col := range row {
+			v = append(v, col.String)
+		}
+		values = append(values, v)
+	}
+	return values
+}
@@ -2,17 +2,18 @@ package stat
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"time"
 )
 
 const (
 	// pgProcDiskstatsQuery is the SQL for retrieving IO stats from Postgres instance
-	pgProcDiskstatsQuery = "SELECT * FROM pgcenter.sys_proc_diskstats ORDER BY (maj,min)"
+	pgProcDiskstatsQuery = "SELECT * FROM %s.sys_proc_diskstats ORDER BY (maj,min)"
 )
 
 // Diskstat describes pre-device IO statistics based on /proc/diskstats.
@@ -39,8 +40,8 @@ type Diskstat struct {
 	Fcompleted   float64 // 19 - flush requests completed successfully
 	Fspent       float64 // 20 - time spent flushing
 	/* extended, based on basic */
-	// System uptime, used for usage calculation.
-	Uptime float64
+	// Time of reading stats, used for usage calculation.
+	Time time.Time
 	// Total number of completed read and write requests.
 	Completed float64
 	// Average time (ms) of read requests issued to the device to be served.
@@ -63,32 +64,30 @@ type Diskstat struct {
 // Diskstats is the container for all stats related to all block devices.
 type Diskstats []Diskstat
 
-// readDiskstats returns block devices stats depending on type of passed DB connection.
-func readDiskstats(db *postgres.DB, config Config) (Diskstats, error) {
+// readDiskstats returns block devices stats depending on type of passed DB connection. Stats are read into the buffer
+// if its capacity is enough, buffer should not be used by the caller anymore.
+func readDiskstats(ctx context.Context, db *postgres.DB, config Config, buf Diskstats) (Diskstats, error) {
 	if db.Local {
-		return readDiskstatsLocal("/proc/diskstats", config.ticks)
+		return readDiskstatsLocal("/proc/diskstats", config.filter, buf)
 	} else if config.SchemaPgcenterAvail {
-		return readDiskstatsRemote(db)
+		return readDiskstatsRemote(ctx, db, config.SchemaName, config.filter, buf)
 	}
 
 	return Diskstats{}, nil
 }
 
-// readDiskstatsLocal return block devices stats read from local proc file.
-func readDiskstatsLocal(statfile string, ticks float64) (Diskstats, error) {
-	var stat Diskstats
+// readDiskstatsLocal return block devices stats read from local proc file into the buffer.
+func readDiskstatsLocal(statfile string, filter Filter, buf Diskstats) (Diskstats, error) {
 	f, err := os.Open(filepath.Clean(statfile))
 	if err != nil {
-		return stat, err
+		return nil, err
 	}
 	defer func() {
 		_ = f.Close()
 	}()
 
-	uptime, err := readUptimeLocal("/proc/uptime", ticks)
-	if err != nil {
-		return nil, err
-	}
+	ts := time.Now()
+	stat := buf[:0]
 
 	scanner := bufio.NewScanner(f)
 
@@ -133,33 +132,28 @@ func readDiskstatsLocal(statfile string, ticks float64) (Diskstats, error) {
 		}
 
 		// skip pseudo block devices.
-		re := regexp.MustCompile(`^(ram|loop|fd)`)
-		if re.MatchString(d.Device) {
+		if filter.skipDiskstat(d.Device) {
 			continue
 		}
 
-		d.Uptime = uptime
+		d.Time = ts
 		stat = append(stat, d)
 	}
 
 	return stat, nil
 }
 
-// readDiskstatsRemote returns block devices stats from SQL stats schema.
-func readDiskstatsRemote(db *postgres.DB) (Diskstats, error) {
-	var uptime float64
-	err := db.QueryRow(pgProcUptimeQuery).Scan(&uptime)
-	if err != nil {
-		return nil, err
-	}
-
-	rows, err := db.Query(pgProcDiskstatsQuery)
+// readDiskstatsRemote returns block devices stats from SQL stats schema into the buffer.
+func readDiskstatsRemote(ctx context.Context, db *postgres.DB, schema string, filter Filter, buf Diskstats) (Diskstats, error) {
+	rows, err := db.QueryPreparedContext(ctx, schemaQuery(pgProcDiskstatsQuery, schema))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var stat Diskstats
+	ts := time.Now()
+
+	stat := buf[:0]
 	for rows.Next() {
 		var d = Diskstat{}
 
@@ -174,12 +168,11 @@ func readDiskstatsRemote(db *postgres.DB) (Diskstats, error) {
 		}
 
 		// skip pseudo block devices.
-		re := regexp.MustCompile(`^(ram|loop|fd)`)
-		if re.MatchString(d.Device) {
+		if filter.skipDiskstat(d.Device) {
 			continue
 		}
 
-		d.Uptime = uptime
+		d.Time = ts
 		stat = append(stat, d)
 	}
 
@@ -187,13 +180,11 @@ func readDiskstatsRemote(db *postgres.DB) (Diskstats, error) {
 }
 
 // countDiskstatsUsage compares block devices stats snapshots and returns devices usage stats over time interval.
-func countDiskstatsUsage(prev Diskstats, curr Diskstats, ticks float64) Diskstats {
-	if len(curr) != len(prev) {
-		// do nothing and return
-		return nil
-	}
-
-	stat := make([]Diskstat, len(curr))
+// Devices could be added or removed between snapshots (e.g. hotplug, creating LVM snapshots or iSCSI login), hence
+// devices are matched by name. Usage of added devices is calculated since the next snapshot. Usage is written into
+// passed buffer, it is resized in place when number of devices changes.
+func countDiskstatsUsage(stat Diskstats, prev Diskstats, curr Diskstats, ticks float64) Diskstats {
+	stat = resizeDiskstats(stat, len(curr))
 
 	for i := 0; i < len(curr); i++ {
 		// Skip inactive devices.
@@ -204,46 +195,85 @@ func countDiskstatsUsage(prev Diskstats, curr Diskstats, ticks float64) Diskstat
 		stat[i].Major = curr[i].Major
 		stat[i].Minor = curr[i].Minor
 		stat[i].Device = curr[i].Device
-		itv := curr[i].Uptime - prev[i].Uptime
-
 		stat[i].Completed = curr[i].Rcompleted + curr[i].Wcompleted
 
-		stat[i].Util = sValue(prev[i].Tspent, curr[i].Tspent, itv, ticks) / 10
+		j, ok := findDiskstat(prev, curr[i].Device, i)
+		if !ok {
+			continue // device added since previous snapshot.
+		}
+		p := prev[j]
+
+		// Counters decreased, device has been re-created with the same name since previous snapshot.
+		if p.Rcompleted+p.Wcompleted > stat[i].Completed {
+			continue
+		}
+
+		itv := elapsed(p.Time, curr[i].Time, 0) * ticks
 
-		if ((curr[i].Rcompleted + curr[i].Wcompleted) - (prev[i].Rcompleted + prev[i].Wcompleted)) > 0 {
-			stat[i].Await = ((curr[i].Rspent - prev[i].Rspent) + (curr[i].Wspent - prev[i].Wspent)) /
-				((curr[i].Rcompleted + curr[i].Wcompleted) - (prev[i].Rcompleted + prev[i].Wcompleted))
+		stat[i].Util = sValue(p.Tspent, curr[i].Tspent, itv, ticks) / 10
+
+		if ((curr[i].Rcompleted + curr[i].Wcompleted) - (p.Rcompleted + p.Wcompleted)) > 0 {
+			stat[i].Await = ((curr[i].Rspent - p.Rspent) + (curr[i].Wspent - p.Wspent)) /
+				((curr[i].Rcompleted + curr[i].Wcompleted) - (p.Rcompleted + p.Wcompleted))
 		} else {
 			stat[i].Await = 0
 		}
 
-		if ((curr[i].Rcompleted + curr[i].Wcompleted) - (prev[i].Rcompleted + prev[i].Wcompleted)) > 0 {
-			stat[i].Arqsz = ((curr[i].Rsectors - prev[i].Rsectors) + (curr[i].Wsectors - prev[i].Wsectors)) /
-				((curr[i].Rcompleted + curr[i].Wcompleted) - (prev[i].Rcompleted + prev[i].Wcompleted))
+		if ((curr[i].Rcompleted + curr[i].Wcompleted) - (p.Rcompleted + p.Wcompleted)) > 0 {
+			stat[i].Arqsz = ((curr[i].Rsectors - p.Rsectors) + (curr[i].Wsectors - p.Wsectors)) /
+				((curr[i].Rcompleted + curr[i].Wcompleted) - (p.Rcompleted + p.Wcompleted))
 		} else {
 			stat[i].Arqsz = 0
 		}
 
-		if (curr[i].Rcompleted - prev[i].Rcompleted) > 0 {
-			stat[i].Rawait = (curr[i].Rspent - prev[i].Rspent) / (curr[i].Rcompleted - prev[i].Rcompleted)
+		if (curr[i].Rcompleted - p.Rcompleted) > 0 {
+			stat[i].Rawait = (curr[i].Rspent - p.Rspent) / (curr[i].Rcompleted - p.Rcompleted)
 		} else {
 			stat[i].Rawait = 0
 		}
 
-		if (curr[i].Wcompleted - prev[i].Wcompleted) > 0 {
-			stat[i].Wawait = (curr[i].Wspent - prev[i].Wspent) / (curr[i].Wcompleted - prev[i].Wcompleted)
+		if (curr[i].Wcompleted - p.Wcompleted) > 0 {
+			stat[i].Wawait = (curr[i].Wspent - p.Wspent) / (curr[i].Wcompleted - p.Wcompleted)
 		} else {
 			stat[i].Wawait = 0
 		}
 
-		stat[i].Rmerged = sValue(prev[i].Rmerged, curr[i].Rmerged, itv, ticks)
-		stat[i].Wmerged = sValue(prev[i].Wmerged, curr[i].Wmerged, itv, ticks)
-		stat[i].Rcompleted = sValue(prev[i].Rcompleted, curr[i].Rcompleted, itv, ticks)
-		stat[i].Wcompleted = sValue(prev[i].Wcompleted, curr[i].Wcompleted, itv, ticks)
-		stat[i].Rsectors = sValue(prev[i].Rsectors, curr[i].Rsectors, itv, ticks) / 2048
-		stat[i].Wsectors = sValue(prev[i].Wsectors, curr[i].Wsectors, itv, ticks) / 2048
-		stat[i].Tweighted = sValue(prev[i].Tweighted, curr[i].Tweighted, itv, ticks) / 1000
+		stat[i].Rmerged = sValue(p.Rmerged, curr[i].Rmerged, itv, ticks)
+		stat[i].Wmerged = sValue(p.Wmerged, curr[i].Wmerged, itv, ticks)
+		stat[i].Rcompleted = sValue(p.Rcompleted, curr[i].Rcompleted, itv, ticks)
+		stat[i].Wcompleted = sValue(p.Wcompleted, curr[i].Wcompleted, itv, ticks)
+		stat[i].Rsectors = sValue(p.Rsectors, curr[i].Rsectors, itv, ticks) / 2048
+		stat[i].Wsectors = sValue(p.Wsectors, curr[i].Wsectors, itv, ticks) / 2048
+		stat[i].Tweighted = sValue(p.Tweighted, curr[i].Tweighted, itv, ticks) / 1000
 	}
 
 	return stat
 }
+
+// resizeDiskstats returns buffer of n zeroed devices stats, buffer is reallocated only when its capacity is not enough.
+func resizeDiskstats(stat Diskstats, n int) Diskstats {
+	if cap(stat) < n {
+		return make(Diskstats, n)
+	}
+
+	stat = stat[:n]
+	for i := range stat {
+		stat[i] = Diskstat{}
+	}
+	return stat
+}
+
+// findDiskstat returns index of the device in the snapshot. Devices usually keep their positions between snapshots,
+// hence the hinted position is checked first.
+func findDiskstat(stats Diskstats, device string, hint int) (int, bool) {
+	if hint < len(stats) && stats[hint].Device == device {
+		return hint, true
+	}
+
+	for i := range stats {
+		if stats[i].Device == device {
+			return i, true
+		}
+	}
+	return 0, false
+}
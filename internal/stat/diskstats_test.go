@@ -1,9 +1,11 @@
 package stat
 
 import (
+	"context"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func Test_readDiskstats(t *testing.T) {
@@ -11,30 +13,30 @@ func Test_readDiskstats(t *testing.T) {
 	assert.NoError(t, err)
 	defer conn.Close()
 
-	ticks, err := getSysticksLocal()
+	ticks, err := GetSysticksLocal()
 	assert.NoError(t, err)
 	assert.NotEqual(t, float64(0), ticks)
 
 	// test "local" reading
 	conn.Local = true
-	got, err := readDiskstats(conn, Config{ticks: ticks, PostgresProperties: PostgresProperties{SchemaPgcenterAvail: false}})
+	got, err := readDiskstats(context.Background(), conn, Config{ticks: ticks, PostgresProperties: PostgresProperties{SchemaPgcenterAvail: false}}, nil)
 	assert.NoError(t, err)
 	assert.Greater(t, len(got), 0)
 
 	// test "remote" reading
 	conn.Local = false
-	got, err = readDiskstats(conn, Config{PostgresProperties: PostgresProperties{SchemaPgcenterAvail: true}})
+	got, err = readDiskstats(context.Background(), conn, Config{PostgresProperties: PostgresProperties{SchemaPgcenterAvail: true, SchemaName: "pgcenter"}}, nil)
 	assert.NoError(t, err)
 	assert.Greater(t, len(got), 0)
 
 	// test "remote", but when schema is not available
-	got, err = readDiskstats(conn, Config{PostgresProperties: PostgresProperties{SchemaPgcenterAvail: false}})
+	got, err = readDiskstats(context.Background(), conn, Config{PostgresProperties: PostgresProperties{SchemaPgcenterAvail: false}}, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, len(got), 0)
 }
 
 func Test_readDiskstatsLocal(t *testing.T) {
-	ticks, err := getSysticksLocal()
+	ticks, err := GetSysticksLocal()
 	assert.NoError(t, err)
 	assert.NotEqual(t, float64(0), ticks)
 
@@ -108,11 +110,11 @@ func Test_readDiskstatsLocal(t *testing.T) {
 	}
 
 	for _, tc := range testcases {
-		got, err := readDiskstatsLocal(tc.statfile, ticks)
+		got, err := readDiskstatsLocal(tc.statfile, DefaultFilter(), nil)
 		if tc.valid {
-			// as a workaround copy Uptime value from 'got' because it's read from real /proc/stat.
+			// as a workaround copy Time value from 'got' because it's the time of reading.
 			for i := range got {
-				tc.want[i].Uptime = got[i].Uptime
+				tc.want[i].Time = got[i].Time
 			}
 
 			assert.NoError(t, err)
@@ -127,7 +129,7 @@ func Test_readDiskstatsRemote(t *testing.T) {
 	conn, err := postgres.NewTestConnect()
 	assert.NoError(t, err)
 
-	got, err := readDiskstatsRemote(conn)
+	got, err := readDiskstatsRemote(context.Background(), conn, "pgcenter", DefaultFilter(), nil)
 	assert.NoError(t, err)
 	assert.Greater(t, len(got), 0)
 
@@ -137,28 +139,29 @@ func Test_readDiskstatsRemote(t *testing.T) {
 	}
 
 	conn.Close()
-	_, err = readDiskstatsRemote(conn)
+	_, err = readDiskstatsRemote(context.Background(), conn, "pgcenter", DefaultFilter(), nil)
 	assert.Error(t, err)
 }
 
 func Test_countDiskstatsUsage(t *testing.T) {
-	ticks, err := getSysticksLocal()
+	ticks, err := GetSysticksLocal()
 	assert.NoError(t, err)
 	assert.NotEqual(t, float64(0), ticks)
 
-	prev, err := readDiskstatsLocal("testdata/proc/diskstats.v2.golden", ticks)
+	prev, err := readDiskstatsLocal("testdata/proc/diskstats.v2.golden", DefaultFilter(), nil)
 	assert.NoError(t, err)
 
-	curr, err := readDiskstatsLocal("testdata/proc/diskstats.v2.2.golden", ticks)
+	curr, err := readDiskstatsLocal("testdata/proc/diskstats.v2.2.golden", DefaultFilter(), nil)
 	assert.NoError(t, err)
 
-	// as a workaround copy Uptime value from 'got' because it's read from real /proc/stat.
+	// snapshots are read with 1 second interval.
+	ts := time.Now()
 	for i := range prev {
-		prev[i].Uptime = 0
-		curr[i].Uptime = 100
+		prev[i].Time = ts
+		curr[i].Time = ts.Add(time.Second)
 	}
 
-	got := countDiskstatsUsage(prev, curr, 100)
+	got := countDiskstatsUsage(nil, prev, curr, 100)
 
 	want := Diskstats{
 		Diskstat{
@@ -180,4 +183,36 @@ func Test_countDiskstatsUsage(t *testing.T) {
 	}
 
 	assert.Equal(t, want, got)
+
+	// Devices added, removed and re-created between snapshots.
+	prev = Diskstats{
+		{Device: "sda", Wcompleted: 100, Tspent: 100, Time: ts},
+		{Device: "sdb", Wcompleted: 100, Tspent: 100, Time: ts},
+		{Device: "dm-0", Wcompleted: 100, Tspent: 100, Time: ts},
+	}
+	curr = Diskstats{
+		{Device: "sda", Wcompleted: 200, Tspent: 200, Time: ts.Add(time.Second)},
+		{Device: "dm-1", Wcompleted: 10, Tspent: 10, Time: ts.Add(time.Second)},
+		{Device: "dm-0", Wcompleted: 10, Tspent: 10, Time: ts.Add(time.Second)},
+	}
+
+	got = countDiskstatsUsage(nil, prev, curr, 100)
+	assert.Equal(t, Diskstats{
+		{Device: "sda", Completed: 200, Wcompleted: 100, Util: 10},
+		{Device: "dm-1", Completed: 10},
+		{Device: "dm-0", Completed: 10},
+	}, got)
+}
+
+func Benchmark_readDiskstatsLocal(b *testing.B) {
+	b.ReportAllocs()
+	var buf Diskstats
+	filter := DefaultFilter()
+	for i := 0; i < b.N; i++ {
+		var err error
+		buf, err = readDiskstatsLocal("testdata/proc/diskstats.v3.golden", filter, buf)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
 }
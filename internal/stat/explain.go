@@ -0,0 +1,189 @@
+package stat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ExplainLogLines defines how many recent lines of log are read from log readers when looking for plans.
+	ExplainLogLines = 50000
+	// explainLogSize defines how many recent bytes of log file are read when looking for plans.
+	explainLogSize = 16 * 1024 * 1024
+)
+
+var (
+	// reExplainStart matches the first line of plan logged by auto_explain in text format.
+	reExplainStart = regexp.MustCompile(`duration: ([\d.]+ ms)\s+plan:\s*$`)
+	// reExplainNode matches the first line of plan's tree, e.g. 'Seq Scan on t  (cost=0.00..35.50 rows=2550 width=4)'.
+	reExplainNode = regexp.MustCompile(`\((cost|actual (time|rows))=`)
+	// reExplainQueryID matches query identifier printed in verbose plans since Postgres 14.
+	reExplainQueryID = regexp.MustCompile(`^\s*Query Identifier: (-?\d+)\s*$`)
+)
+
+// ExplainPlan describes execution plan logged by auto_explain.
+type ExplainPlan struct {
+	Prefix   string // prefix of the log line, usually contains time and pid of the backend
+	Duration string // duration of the query, e.g. '12.345 ms'
+	QueryID  string // query identifier, logged with auto_explain.log_verbose since Postgres 14
+	Query    string // text of the query
+	Plan     string // text of the plan
+}
+
+// ParseExplainPlans parses plans logged by auto_explain in text format. Lines of plan follow the line with duration
+// and start with tab, prefixes of lines added by journald or syslog are allowed.
+func ParseExplainPlans(buf []byte) []ExplainPlan {
+	var (
+		plans   []ExplainPlan
+		current *ExplainPlan
+		lines   []string // lines of the current plan, query text and plan's tree
+	)
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		splitExplainLines(current, lines)
+		plans = append(plans, *current)
+		current, lines = nil, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := reExplainStart.FindStringSubmatch(line); m != nil {
+			finish()
+
+			prefix := line[:strings.Index(line, "duration:")]
+			if i := strings.Index(prefix, "LOG:"); i >= 0 {
+				prefix = prefix[:i]
+			}
+			current = &ExplainPlan{Prefix: strings.TrimSpace(prefix), Duration: m[1]}
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		i := strings.Index(line, "\t")
+		if i < 0 {
+			finish()
+			continue
+		}
+		lines = append(lines, line[i+1:])
+	}
+	finish()
+
+	return plans
+}
+
+// splitExplainLines splits lines of logged plan into query text, query identifier and plan's tree.
+func splitExplainLines(p *ExplainPlan, lines []string) {
+	var query, plan []string
+	inPlan := false
+
+	for _, line := range lines {
+		if m := reExplainQueryID.FindStringSubmatch(line); m != nil {
+			p.QueryID = m[1]
+			continue
+		}
+
+		if !inPlan && strings.HasPrefix(line, "Query Text: ") {
+			query = append(query, strings.TrimPrefix(line, "Query Text: "))
+			continue
+		}
+
+		if !inPlan && (reExplainNode.MatchString(line) || len(query) == 0) {
+			inPlan = true
+		}
+
+		if inPlan {
+			plan = append(plan, line)
+		} else {
+			query = append(query, line)
+		}
+	}
+
+	p.Query = strings.TrimSpace(strings.Join(query, "\n"))
+	p.Plan = strings.Join(plan, "\n")
+}
+
+// FindExplainPlan returns the most recent plan of the statement. Plans are matched by query identifier if it is
+// logged, otherwise by fingerprint of query text.
+func FindExplainPlan(plans []ExplainPlan, queryid string, statement string) (ExplainPlan, bool) {
+	fp := explainFingerprint(statement)
+
+	for i := len(plans) - 1; i >= 0; i-- {
+		p := plans[i]
+		if p.QueryID != "" {
+			if p.QueryID == queryid {
+				return p, true
+			}
+			continue
+		}
+
+		if statement != "" && explainFingerprint(p.Query) == fp {
+			return p, true
+		}
+	}
+
+	return ExplainPlan{}, false
+}
+
+// explainFingerprint returns fingerprint of the query with collapsed whitespaces, because logged query text and text
+// of the statement in pg_stat_statements could be formatted differently, e.g. trailing semicolon or newlines.
+func explainFingerprint(q string) string {
+	return strings.Join(strings.Fields(strings.TrimSuffix(strings.TrimSpace(Fingerprint(q)), ";")), " ")
+}
+
+// ReadExplainPlans reads plans logged by auto_explain from the recent part of the log file.
+func ReadExplainPlans(path string) ([]ExplainPlan, error) {
+	buf, err := readFileTail(path, explainLogSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseExplainPlans(buf), nil
+}
+
+// readFileTail returns not more than size of the last bytes of the file, the first incomplete line is skipped.
+func readFileTail(path string, size int64) ([]byte, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("open log file failed: %s", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat log file failed: %s", err)
+	}
+
+	offset := info.Size() - size
+	if offset < 0 {
+		offset = 0
+	}
+
+	buf := make([]byte, info.Size()-offset)
+	_, err = f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read log file failed: %s", err)
+	}
+
+	if offset > 0 {
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			buf = buf[i+1:]
+		}
+	}
+
+	return buf, nil
+}
@@ -0,0 +1,113 @@
+package stat
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// explainLog defines log with plans logged by auto_explain.
+const explainLog = `2021-03-01 12:00:00.100 UTC [1234] LOG:  checkpoint starting: time
+2021-03-01 12:00:01.200 UTC [2345] LOG:  duration: 12.345 ms  plan:
+	Query Text: SELECT * FROM orders WHERE id = 42
+	Index Scan using orders_pkey on orders  (cost=0.29..8.31 rows=1 width=36) (actual time=0.010..0.011 rows=1 loops=1)
+	  Index Cond: (id = 42)
+2021-03-01 12:00:02.300 UTC [2345] LOG:  duration: 150.000 ms  plan:
+	Query Text: SELECT o.id
+	FROM orders o
+	WHERE o.status IN ('new', 'paid');
+	Seq Scan on orders o  (cost=0.00..35.50 rows=2550 width=4) (actual time=0.005..140.000 rows=2000 loops=1)
+	  Filter: (status = ANY ('{new,paid}'::text[]))
+2021-03-01 12:00:03.400 UTC [3456] ERROR:  relation "unknown" does not exist
+2021-03-01 12:00:04.500 UTC [2345] LOG:  duration: 10.000 ms  plan:
+	Query Text: SELECT * FROM orders WHERE id = 43
+	Index Scan using orders_pkey on orders  (cost=0.29..8.31 rows=1 width=36) (actual time=0.010..0.011 rows=1 loops=1)
+	  Index Cond: (id = 43)
+2021-03-01 12:00:05.600 UTC [4567] LOG:  duration: 5.000 ms  plan:
+	Query Text: UPDATE orders SET status = 'paid' WHERE id = 44
+	Update on public.orders  (cost=0.29..8.31 rows=0 width=0)
+	  ->  Index Scan using orders_pkey on public.orders  (cost=0.29..8.31 rows=1 width=42)
+	Query Identifier: -7071264834541342216
+`
+
+func TestParseExplainPlans(t *testing.T) {
+	got := ParseExplainPlans([]byte(explainLog))
+	assert.Len(t, got, 4)
+
+	assert.Equal(t, ExplainPlan{
+		Prefix:   "2021-03-01 12:00:01.200 UTC [2345]",
+		Duration: "12.345 ms",
+		Query:    "SELECT * FROM orders WHERE id = 42",
+		Plan: "Index Scan using orders_pkey on orders  (cost=0.29..8.31 rows=1 width=36) (actual time=0.010..0.011 rows=1 loops=1)\n" +
+			"  Index Cond: (id = 42)",
+	}, got[0])
+
+	assert.Equal(t, "SELECT o.id\nFROM orders o\nWHERE o.status IN ('new', 'paid');", got[1].Query)
+	assert.True(t, strings.HasPrefix(got[1].Plan, "Seq Scan on orders o"))
+	assert.Equal(t, "", got[1].QueryID)
+
+	assert.Equal(t, "-7071264834541342216", got[3].QueryID)
+	assert.Equal(t, "UPDATE orders SET status = 'paid' WHERE id = 44", got[3].Query)
+	assert.Equal(t, 2, strings.Count(got[3].Plan, "\n")+1)
+
+	// Plans in journal with prefixes of lines.
+	journal := "2021-03-01T12:00:01+0000 db1 postgres[2345]: [5-1] LOG:  duration: 1.000 ms  plan:\n" +
+		"2021-03-01T12:00:01+0000 db1 postgres[2345]: [5-2] \tQuery Text: SELECT 1\n" +
+		"2021-03-01T12:00:01+0000 db1 postgres[2345]: [5-3] \tResult  (cost=0.00..0.01 rows=1 width=4)\n"
+	got = ParseExplainPlans([]byte(journal))
+	assert.Len(t, got, 1)
+	assert.Equal(t, "SELECT 1", got[0].Query)
+	assert.Equal(t, "Result  (cost=0.00..0.01 rows=1 width=4)", got[0].Plan)
+
+	// No plans.
+	assert.Empty(t, ParseExplainPlans([]byte("2021-03-01 12:00:00.100 UTC [1234] LOG:  checkpoint starting: time\n")))
+}
+
+func TestFindExplainPlan(t *testing.T) {
+	plans := ParseExplainPlans([]byte(explainLog))
+
+	// The most recent plan is matched by fingerprint.
+	got, ok := FindExplainPlan(plans, "123", "SELECT * FROM orders WHERE id = $1")
+	assert.True(t, ok)
+	assert.Equal(t, "SELECT * FROM orders WHERE id = 43", got.Query)
+
+	got, ok = FindExplainPlan(plans, "124", "SELECT o.id FROM orders o WHERE o.status IN ($1, $2)")
+	assert.True(t, ok)
+	assert.Equal(t, "150.000 ms", got.Duration)
+
+	// Plan with query identifier is matched by identifier only.
+	got, ok = FindExplainPlan(plans, "-7071264834541342216", "")
+	assert.True(t, ok)
+	assert.Equal(t, "4567", got.Prefix[strings.Index(got.Prefix, "[")+1:len(got.Prefix)-1])
+
+	_, ok = FindExplainPlan(plans, "125", "UPDATE orders SET status = $1 WHERE id = $2")
+	assert.False(t, ok)
+
+	_, ok = FindExplainPlan(plans, "126", "DELETE FROM orders")
+	assert.False(t, ok)
+}
+
+func TestReadExplainPlans(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-explain-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	filename := filepath.Join(dir, "postgresql.log")
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(explainLog), 0600))
+
+	got, err := ReadExplainPlans(filename)
+	assert.NoError(t, err)
+	assert.Len(t, got, 4)
+
+	// Only the tail of file is read, incomplete line is skipped.
+	buf, err := readFileTail(filename, 100)
+	assert.NoError(t, err)
+	assert.True(t, len(buf) < 100)
+	assert.True(t, strings.HasPrefix(string(buf), "\tQuery Identifier") || strings.HasPrefix(string(buf), "\t  ->"))
+
+	_, err = ReadExplainPlans(filepath.Join(dir, "missing.log"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package stat
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	// DefaultDiskstatsFilter defines pattern of pseudo block devices skipped by collectors.
+	DefaultDiskstatsFilter = `^(ram|loop|fd)`
+	// DefaultNetdevsFilter defines pattern of virtual network interfaces skipped by collectors.
+	DefaultNetdevsFilter = `docker|virbr|veth`
+)
+
+// FilterConfig defines patterns of devices which stats are skipped, default patterns are used if patterns are empty.
+type FilterConfig struct {
+	Diskstats string `yaml:"diskstats"` // block devices
+	Netdevs   string `yaml:"netdevs"`   // network interfaces
+}
+
+// Filter defines compiled patterns of devices which stats are skipped by collectors. Patterns are compiled once at
+// startup and filter is passed to collectors by value.
+type Filter struct {
+	Diskstats *regexp.Regexp // block devices
+	Netdevs   *regexp.Regexp // network interfaces
+}
+
+// DefaultFilter returns filter with default patterns.
+func DefaultFilter() Filter {
+	return Filter{
+		Diskstats: regexp.MustCompile(DefaultDiskstatsFilter),
+		Netdevs:   regexp.MustCompile(DefaultNetdevsFilter),
+	}
+}
+
+// NewFilter compiles patterns of skipped devices, default pattern is used if pattern is empty.
+func NewFilter(config FilterConfig) (Filter, error) {
+	diskstats, netdevs := config.Diskstats, config.Netdevs
+	if diskstats == "" {
+		diskstats = DefaultDiskstatsFilter
+	}
+	if netdevs == "" {
+		netdevs = DefaultNetdevsFilter
+	}
+
+	d, err := regexp.Compile(diskstats)
+	if err != nil {
+		return Filter{}, fmt.Errorf("invalid block devices filter: %s", err)
+	}
+
+	n, err := regexp.Compile(netdevs)
+	if err != nil {
+		return Filter{}, fmt.Errorf("invalid network interfaces filter: %s", err)
+	}
+
+	return Filter{Diskstats: d, Netdevs: n}, nil
+}
+
+// skipDiskstat returns true if stats of block device should be skipped.
+func (f Filter) skipDiskstat(device string) bool {
+	return f.Diskstats != nil && f.Diskstats.MatchString(device)
+}
+
+// skipNetdev returns true if stats of network interface should be skipped.
+func (f Filter) skipNetdev(ifname string) bool {
+	return f.Netdevs != nil && f.Netdevs.MatchString(ifname)
+}
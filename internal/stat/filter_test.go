@@ -0,0 +1,64 @@
+package stat
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewFilter(t *testing.T) {
+	testcases := []struct {
+		diskstats string
+		netdevs   string
+		valid     bool
+		skipDisk  []string
+		skipNet   []string
+		keepDisk  []string
+		keepNet   []string
+	}{
+		{valid: true, skipDisk: []string{"loop0", "ram1"}, skipNet: []string{"veth681540b", "docker0"}, keepDisk: []string{"sda"}, keepNet: []string{"eth0"}},
+		{diskstats: "^(loop|dm-)", netdevs: "^lo$", valid: true, skipDisk: []string{"dm-0"}, skipNet: []string{"lo"}, keepDisk: []string{"ram1"}, keepNet: []string{"veth681540b"}},
+		{diskstats: "(", valid: false},
+		{netdevs: "[", valid: false},
+	}
+
+	for _, tc := range testcases {
+		got, err := NewFilter(FilterConfig{Diskstats: tc.diskstats, Netdevs: tc.netdevs})
+		if !tc.valid {
+			assert.Error(t, err)
+			continue
+		}
+
+		assert.NoError(t, err)
+		for _, s := range tc.skipDisk {
+			assert.True(t, got.skipDiskstat(s), s)
+		}
+		for _, s := range tc.keepDisk {
+			assert.False(t, got.skipDiskstat(s), s)
+		}
+		for _, s := range tc.skipNet {
+			assert.True(t, got.skipNetdev(s), s)
+		}
+		for _, s := range tc.keepNet {
+			assert.False(t, got.skipNetdev(s), s)
+		}
+	}
+
+	// Filter without patterns skips nothing.
+	assert.False(t, Filter{}.skipDiskstat("loop0"))
+	assert.False(t, Filter{}.skipNetdev("veth681540b"))
+}
+
+func TestCollector_SetFilter(t *testing.T) {
+	f, err := NewFilter(FilterConfig{Netdevs: "^(br|wlx)"})
+	assert.NoError(t, err)
+
+	c := &Collector{config: Config{filter: DefaultFilter()}}
+	c.SetFilter(f)
+
+	got, err := readNetdevsLocal("testdata/proc/netdev.v1.golden", c.config.filter, nil)
+	assert.NoError(t, err)
+	for _, n := range got {
+		assert.NotRegexp(t, "^(br|wlx)", n.Ifname)
+	}
+	assert.Greater(t, len(got), 0)
+}
@@ -0,0 +1,113 @@
+package stat
+
+import (
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// reStringLiteral matches quoted string literals, e.g. 'abc' or 'it''s'.
+	reStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	// reNumberLiteral matches numeric literals which are not parts of identifiers or parameters placeholders.
+	reNumberLiteral = regexp.MustCompile(`(^|[^\w$.])-?\d+(?:\.\d+)?\b`)
+)
+
+// activityGroupedCols defines columns of activity rows grouped by fingerprints of queries.
+var activityGroupedCols = []string{"count", "datname", "usename", "state", "min_age", "max_age", "query"}
+
+// group groups rows of the view with enabled grouping. Rows of views which don't support grouping are returned as-is.
+func group(res PGresult, v view.View) (PGresult, error) {
+	switch {
+	case v.IsStatements():
+		return groupStatements(res, v)
+	case v.Name == view.ActivityGrouped:
+		return groupActivity(res), nil
+	default:
+		return res, nil
+	}
+}
+
+// Fingerprint returns text of the query where literals are replaced with parameters placeholders, and then query is
+// normalized, e.g. 'id IN (1, 2)' and 'id IN (3, 4, 5)' have the same fingerprint 'id IN (...)'.
+func Fingerprint(q string) string {
+	q = reStringLiteral.ReplaceAllString(q, "$$0")
+	q = reNumberLiteral.ReplaceAllString(q, "${1}$$0")
+	return NormalizeQuery(q)
+}
+
+// groupActivity groups rows of activity view by fingerprint of query, database, user and state of backends. Grouped
+// row contains number of backends, minimal and maximal age of their queries. Rows are returned as-is if required
+// columns are not found.
+func groupActivity(res PGresult) PGresult {
+	idx := map[string]int{}
+	for i, name := range res.Cols {
+		idx[name] = i
+	}
+
+	for _, name := range []string{"datname", "usename", "state", "query_age", "query"} {
+		if _, ok := idx[name]; !ok {
+			return res
+		}
+	}
+
+	type activityGroup struct {
+		count    int
+		min, max int64
+		aged     bool // at least one query has known age
+		row      []sql.NullString
+	}
+
+	var (
+		groups = map[string]*activityGroup{}
+		order  []*activityGroup
+	)
+
+	for _, row := range res.Values {
+		fp := Fingerprint(row[idx["query"]].String)
+		key := fp + "\x00" + row[idx["datname"]].String + "\x00" + row[idx["usename"]].String + "\x00" + row[idx["state"]].String
+
+		g, ok := groups[key]
+		if !ok {
+			g = &activityGroup{row: []sql.NullString{
+				{}, row[idx["datname"]], row[idx["usename"]], row[idx["state"]], {}, {}, {String: fp, Valid: true},
+			}}
+			groups[key] = g
+			order = append(order, g)
+		}
+
+		g.count++
+
+		age, ok := parseInterval(row[idx["query_age"]].String)
+		if !ok {
+			continue
+		}
+		if !g.aged || age < g.min {
+			g.min = age
+		}
+		if !g.aged || age > g.max {
+			g.max = age
+		}
+		g.aged = true
+	}
+
+	values := make([][]sql.NullString, 0, len(order))
+	for _, g := range order {
+		g.row[0] = sql.NullString{String: strconv.Itoa(g.count), Valid: true}
+		if g.aged {
+			g.row[4] = sql.NullString{String: formatInterval(g.min), Valid: true}
+			g.row[5] = sql.NullString{String: formatInterval(g.max), Valid: true}
+		}
+		values = append(values, g.row)
+	}
+
+	return PGresult{
+		Valid:  res.Valid,
+		Ncols:  len(activityGroupedCols),
+		Nrows:  len(values),
+		Cols:   activityGroupedCols,
+		Values: values,
+		Time:   res.Time,
+	}
+}
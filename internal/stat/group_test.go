@@ -0,0 +1,72 @@
+package stat
+
+import (
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want string
+	}{
+		{in: "SELECT * FROM t1 WHERE id = 10", want: "SELECT * FROM t1 WHERE id = $1"},
+		{in: "SELECT * FROM t WHERE id IN (1, 2, 3) AND name = 'it''s'", want: "SELECT * FROM t WHERE id IN (...) AND name = $1"},
+		{in: "UPDATE t SET x = x + 1.5 WHERE id = $1", want: "UPDATE t SET x = x + $1 WHERE id = $2"},
+		{in: "SELECT * FROM t WHERE d > '2021-01-01' LIMIT 10", want: "SELECT * FROM t WHERE d > $1 LIMIT $2"},
+		{in: "", want: ""},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, Fingerprint(tc.in))
+	}
+}
+
+func Test_groupActivity(t *testing.T) {
+	row := func(values ...string) []sql.NullString {
+		r := make([]sql.NullString, len(values))
+		for i := range values {
+			r[i] = sql.NullString{String: values[i], Valid: values[i] != ""}
+		}
+		return r
+	}
+
+	res := PGresult{
+		Valid: true, Ncols: 6, Nrows: 4,
+		Cols: []string{"pid", "datname", "usename", "state", "query_age", "query"},
+		Values: [][]sql.NullString{
+			row("1", "db", "alice", "active", "00:00:05", "SELECT * FROM t WHERE id = 1"),
+			row("2", "db", "alice", "active", "00:01:00", "SELECT * FROM t WHERE id = 2"),
+			row("3", "db", "alice", "idle", "00:00:01", "SELECT * FROM t WHERE id = 3"),
+			row("4", "db", "alice", "active", "", "SELECT * FROM t WHERE id = 4"),
+		},
+	}
+
+	got := groupActivity(res)
+	assert.Equal(t, activityGroupedCols, got.Cols)
+	assert.Equal(t, 2, got.Nrows)
+	assert.Equal(t, row("3", "db", "alice", "active", "00:00:05", "00:01:00", "SELECT * FROM t WHERE id = $1"), got.Values[0])
+	assert.Equal(t, row("1", "db", "alice", "idle", "00:00:01", "00:00:01", "SELECT * FROM t WHERE id = $1"), got.Values[1])
+
+	// required columns are not found
+	res.Cols[5] = "unknown"
+	assert.Equal(t, res, groupActivity(res))
+}
+
+func Test_group(t *testing.T) {
+	res := PGresult{
+		Valid: true, Ncols: 6, Nrows: 1,
+		Cols:   []string{"pid", "datname", "usename", "state", "query_age", "query"},
+		Values: [][]sql.NullString{{{String: "1", Valid: true}, {}, {}, {}, {}, {}}},
+	}
+
+	got, err := group(res, view.GroupActivity(view.New()["activity"]))
+	assert.NoError(t, err)
+	assert.Equal(t, activityGroupedCols, got.Cols)
+
+	got, err = group(res, view.New()["activity"])
+	assert.NoError(t, err)
+	assert.Equal(t, res, got)
+}
@@ -28,6 +28,7 @@ const (
 				regardless of the log_temp_files setting.
 - read_t	blk_read_time	Time spent reading data file blocks by backends in this database, in milliseconds
 - write_t	blk_write_time	Time spent writing data file blocks by backends in this database, in milliseconds
+- read_lat*	blk_read_time,blks_read	Average time of reading a block since stats reset, in milliseconds (requires track_io_timing)
 - stats_age*	stats_reset	Age of collected statistics in the moment when stats are taken from this database
 
 * - extended value, based on origin and calculated using additional functions.
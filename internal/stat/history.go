@@ -0,0 +1,156 @@
+package stat
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"strconv"
+	"time"
+)
+
+// sessionsHistoryCols defines columns of active sessions history aggregated by wait events, users and queries.
+var sessionsHistoryCols = []string{"samples", "aas", "samples_%", "wait", "usename", "datname", "query"}
+
+// sampledSession describes active session taken by sampling.
+type sampledSession struct {
+	usename string
+	datname string
+	wait    string // wait event type and wait event, or 'CPU' if session doesn't wait
+	query   string // fingerprint of the query
+}
+
+// sessionsSample describes active sessions sampled at the same time.
+type sessionsSample struct {
+	time     time.Time
+	sessions []sampledSession
+}
+
+// SessionsHistory is a ring buffer of sampled active sessions used for building active sessions history (ASH). When
+// buffer is full the oldest samples are overwritten.
+type SessionsHistory struct {
+	samples []sessionsSample // ring of samples
+	next    int              // index of the slot for the next sample
+	count   int              // number of stored samples
+}
+
+// NewSessionsHistory creates sessions history which keeps specified number of samples.
+func NewSessionsHistory(capacity int) *SessionsHistory {
+	return &SessionsHistory{samples: make([]sessionsSample, capacity)}
+}
+
+// add adds sample into the history replacing the oldest one if the history is full.
+func (h *SessionsHistory) add(s sessionsSample) {
+	if len(h.samples) == 0 {
+		return
+	}
+
+	h.samples[h.next] = s
+	h.next = (h.next + 1) % len(h.samples)
+	if h.count < len(h.samples) {
+		h.count++
+	}
+}
+
+// sample reads active sessions from Postgres and adds them into the history.
+func (h *SessionsHistory) sample(ctx context.Context, db *postgres.DB, q string, args ...interface{}) error {
+	res, err := NewPGresultContext(ctx, db, q, args...)
+	if err != nil {
+		return err
+	}
+
+	if res.Ncols < 4 {
+		return fmt.Errorf("sample sessions failed: unexpected number of columns %d", res.Ncols)
+	}
+
+	s := sessionsSample{time: res.Time, sessions: make([]sampledSession, 0, len(res.Values))}
+
+	for _, row := range res.Values {
+		s.sessions = append(s.sessions, sampledSession{
+			usename: row[0].String, datname: row[1].String, wait: row[2].String, query: Fingerprint(row[3].String),
+		})
+	}
+
+	h.add(s)
+	return nil
+}
+
+// aggregate returns sessions sampled within the window until specified time, sessions are grouped by wait event, user,
+// database and query. Grouped row contains number of samples, average number of active sessions (the number of samples
+// divided by the number of sampling attempts) and percent of all samples within the window.
+func (h *SessionsHistory) aggregate(window time.Duration, now time.Time) PGresult {
+	type group struct {
+		samples int
+		row     []sql.NullString
+	}
+
+	var (
+		groups  = map[sampledSession]*group{}
+		order   []*group
+		samples int // number of sampling attempts within the window
+		total   int // number of sampled sessions within the window
+	)
+
+	since := now.Add(-window)
+	for i := 0; i < h.count; i++ {
+		s := h.samples[(h.next-h.count+i+len(h.samples))%len(h.samples)]
+		if s.time.Before(since) || s.time.After(now) {
+			continue
+		}
+
+		samples++
+		for _, sess := range s.sessions {
+			g, ok := groups[sess]
+			if !ok {
+				g = &group{row: []sql.NullString{
+					{}, {}, {},
+					{String: sess.wait, Valid: true},
+					{String: sess.usename, Valid: true},
+					{String: sess.datname, Valid: true},
+					{String: sess.query, Valid: true},
+				}}
+				groups[sess] = g
+				order = append(order, g)
+			}
+			g.samples++
+			total++
+		}
+	}
+
+	values := make([][]sql.NullString, 0, len(order))
+	for _, g := range order {
+		g.row[0] = sql.NullString{String: strconv.Itoa(g.samples), Valid: true}
+		g.row[1] = sql.NullString{String: strconv.FormatFloat(float64(g.samples)/float64(samples), 'f', 2, 64), Valid: true}
+		g.row[2] = sql.NullString{String: strconv.FormatFloat(float64(g.samples)*100/float64(total), 'f', 2, 64), Valid: true}
+		values = append(values, g.row)
+	}
+
+	return PGresult{
+		Valid:  true,
+		Ncols:  len(sessionsHistoryCols),
+		Nrows:  len(values),
+		Cols:   sessionsHistoryCols,
+		Values: values,
+		Time:   now,
+	}
+}
+
+// isSessionsHistory returns true if rows of the view are built from sessions history instead of reading stats.
+func isSessionsHistory(v view.View) bool {
+	return v.Name == view.ASH
+}
+
+// sessionsSamplesQuery returns query and its arguments used for sampling active sessions of Postgres described by
+// properties.
+func sessionsSamplesQuery(props PostgresProperties) (string, []interface{}, error) {
+	opts := props.QueryOptions(0)
+
+	v, ok := query.Select("activity_samples", opts)
+	if !ok {
+		return "", nil, fmt.Errorf("sampling sessions is not supported by Postgres %s", props.Version)
+	}
+
+	return query.Format(v.Query, opts)
+}
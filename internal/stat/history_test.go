@@ -0,0 +1,93 @@
+package stat
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSessionsHistory_sample(t *testing.T) {
+	conn, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	props, err := GetPostgresProperties(conn)
+	assert.NoError(t, err)
+
+	q, args, err := sessionsSamplesQuery(props)
+	assert.NoError(t, err)
+
+	h := NewSessionsHistory(10)
+	assert.NoError(t, h.sample(context.Background(), conn, q, args...))
+	assert.Equal(t, 1, h.count)
+}
+
+func TestSessionsHistory_add(t *testing.T) {
+	h := NewSessionsHistory(3)
+	ts := time.Now()
+
+	for i := 0; i < 5; i++ {
+		h.add(sessionsSample{time: ts.Add(time.Duration(i) * time.Second)})
+	}
+
+	// The oldest samples are overwritten.
+	assert.Equal(t, 3, h.count)
+	assert.Equal(t, 2, h.next)
+	assert.Equal(t, ts.Add(4*time.Second), h.samples[1].time)
+	assert.Equal(t, ts.Add(2*time.Second), h.samples[2].time)
+
+	// History without capacity keeps nothing.
+	h = NewSessionsHistory(0)
+	h.add(sessionsSample{time: ts})
+	assert.Equal(t, 0, h.count)
+}
+
+func TestSessionsHistory_aggregate(t *testing.T) {
+	h := NewSessionsHistory(10)
+	ts := time.Now()
+
+	cpu := sampledSession{usename: "alice", datname: "shop", wait: "CPU", query: "SELECT $1"}
+	lock := sampledSession{usename: "bob", datname: "shop", wait: "Lock: tuple", query: "UPDATE t SET v = $1"}
+
+	h.add(sessionsSample{time: ts.Add(-10 * time.Minute), sessions: []sampledSession{lock, lock}}) // out of window
+	h.add(sessionsSample{time: ts.Add(-3 * time.Second), sessions: []sampledSession{cpu, lock}})
+	h.add(sessionsSample{time: ts.Add(-2 * time.Second), sessions: []sampledSession{cpu, cpu, lock}})
+	h.add(sessionsSample{time: ts.Add(-1 * time.Second)})
+	h.add(sessionsSample{time: ts, sessions: []sampledSession{cpu}})
+
+	got := h.aggregate(time.Minute, ts)
+	assert.True(t, got.Valid)
+	assert.Equal(t, sessionsHistoryCols, got.Cols)
+	assert.Equal(t, 2, got.Nrows)
+
+	// 4 sampling attempts, 6 sampled sessions.
+	assert.Equal(t, []string{"4", "1.00", "66.67", "CPU", "alice", "shop", "SELECT $1"}, rowStrings(got.Values[0]))
+	assert.Equal(t, []string{"2", "0.50", "33.33", "Lock: tuple", "bob", "shop", "UPDATE t SET v = $1"}, rowStrings(got.Values[1]))
+
+	// Sampled sessions older than window are aggregated with wider window.
+	got = h.aggregate(time.Hour, ts)
+	assert.Equal(t, []string{"4", "0.80", "50.00", "Lock: tuple", "bob", "shop", "UPDATE t SET v = $1"}, rowStrings(got.Values[0]))
+
+	// Empty history.
+	got = NewSessionsHistory(10).aggregate(time.Minute, ts)
+	assert.True(t, got.Valid)
+	assert.Equal(t, 0, got.Nrows)
+}
+
+func Test_isSessionsHistory(t *testing.T) {
+	assert.True(t, isSessionsHistory(view.SessionsHistory(time.Minute)))
+	assert.False(t, isSessionsHistory(view.New()["activity"]))
+}
+
+// rowStrings returns values of the row as strings.
+func rowStrings(row []sql.NullString) []string {
+	res := make([]string, len(row))
+	for i, v := range row {
+		res[i] = v.String
+	}
+	return res
+}
@@ -3,6 +3,7 @@
 package stat
 
 import (
+	"context"
 	"fmt"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"io/ioutil"
@@ -19,11 +20,12 @@ type LoadAvg struct {
 }
 
 // readLoadAverage returns load average stats based on type of passed DB connection.
-func readLoadAverage(db *postgres.DB, schemaExists bool) (LoadAvg, error) {
+// Remote stats are read only if stats schema is specified.
+func readLoadAverage(ctx context.Context, db *postgres.DB, schema string) (LoadAvg, error) {
 	if db.Local {
 		return readLoadAverageLocal("/proc/loadavg")
-	} else if schemaExists {
-		return readLoadAverageRemote(db)
+	} else if schema != "" {
+		return readLoadAverageRemote(ctx, db, schema)
 	}
 
 	return LoadAvg{}, nil
@@ -58,9 +60,9 @@ func readLoadAverageLocal(statfile string) (LoadAvg, error) {
 }
 
 // readLoadAverageRemote returns load average stats from SQL stats schema.
-func readLoadAverageRemote(db *postgres.DB) (LoadAvg, error) {
+func readLoadAverageRemote(ctx context.Context, db *postgres.DB, schema string) (LoadAvg, error) {
 	var stat LoadAvg
-	err := db.QueryRow("SELECT min1, min5, min15 FROM pgcenter.sys_proc_loadavg").Scan(&stat.One, &stat.Five, &stat.Fifteen)
+	err := db.QueryRowPreparedContext(ctx, schemaQuery("SELECT min1, min5, min15 FROM %s.sys_proc_loadavg", schema)).Scan(&stat.One, &stat.Five, &stat.Fifteen)
 	if err != nil {
 		return stat, err
 	}
@@ -1,6 +1,7 @@
 package stat
 
 import (
+	"context"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -13,18 +14,18 @@ func Test_readLoadAverage(t *testing.T) {
 
 	// test "local" reading
 	conn.Local = true
-	got, err := readLoadAverage(conn, false)
+	got, err := readLoadAverage(context.Background(), conn, "")
 	assert.NoError(t, err)
 	assert.Greater(t, got.One, float64(0))
 
 	// test "remote" reading
 	conn.Local = false
-	got, err = readLoadAverage(conn, true)
+	got, err = readLoadAverage(context.Background(), conn, "pgcenter")
 	assert.NoError(t, err)
 	assert.Greater(t, got.One, float64(0))
 
 	// test "remote", but when schema is not available
-	got, err = readLoadAverage(conn, false)
+	got, err = readLoadAverage(context.Background(), conn, "")
 	assert.NoError(t, err)
 	assert.Equal(t, got.One, float64(0))
 }
@@ -54,13 +55,13 @@ func Test_readLoadAverageRemote(t *testing.T) {
 	conn, err := postgres.NewTestConnect()
 	assert.NoError(t, err)
 
-	got, err := readLoadAverageRemote(conn)
+	got, err := readLoadAverageRemote(context.Background(), conn, "pgcenter")
 	assert.NoError(t, err)
 	assert.Greater(t, got.One, float64(0))
 	assert.Greater(t, got.Five, float64(0))
 	assert.Greater(t, got.Fifteen, float64(0))
 
 	conn.Close()
-	_, err = readLoadAverageRemote(conn)
+	_, err = readLoadAverageRemote(context.Background(), conn, "pgcenter")
 	assert.Error(t, err)
 }
@@ -0,0 +1,216 @@
+package stat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LogSourceFile defines Postgres log files written by logging collector.
+	LogSourceFile = "file"
+	// LogSourceJournald defines systemd journal, used when Postgres logs into stderr without logging collector.
+	LogSourceJournald = "journald"
+	// LogSourceSyslog defines syslog messages received over UDP, e.g. forwarded by rsyslog from remote hosts.
+	LogSourceSyslog = "syslog"
+
+	// DefaultJournaldUnit defines pattern of systemd units of Postgres used by popular distributions.
+	DefaultJournaldUnit = "postgresql*"
+
+	// journalctlTimeout defines maximum duration of reading the journal.
+	journalctlTimeout = 5 * time.Second
+	// SyslogLinesMax defines how many recent syslog messages are kept.
+	SyslogLinesMax = 1000
+)
+
+// syslogPriRE defines PRI part and optional version of syslog message (RFC 3164 and RFC 5424).
+var syslogPriRE = regexp.MustCompile(`^<\d{1,3}>(1 )?`)
+
+// LogReader reads recent lines of Postgres log from sources other than log files.
+type LogReader interface {
+	Name() string                   // description of the source shown above log lines
+	Tail(lines int) ([]byte, error) // returns specified number of recent lines
+	Remote() bool                   // true if source could provide logs of remote hosts
+	Close() error
+}
+
+// NewLogReader creates reader of Postgres log using specification of the source: 'journald[:UNIT]' or
+// 'syslog:ADDRESS'. Nil reader is returned for log files, they are handled using Logfile.
+func NewLogReader(spec string) (LogReader, error) {
+	kind, arg := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		kind, arg = spec[:i], spec[i+1:]
+	}
+
+	switch kind {
+	case "", LogSourceFile:
+		return nil, nil
+	case LogSourceJournald:
+		if arg == "" {
+			arg = DefaultJournaldUnit
+		}
+		r, err := NewJournalReader(arg)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	case LogSourceSyslog:
+		if arg == "" {
+			return nil, fmt.Errorf("syslog listen address is not specified, use syslog:[HOST]:PORT")
+		}
+		r, err := NewSyslogReader(arg)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf("unknown log source '%s', use one of: file, journald[:UNIT], syslog:[HOST]:PORT", kind)
+	}
+}
+
+// JournalReader reads Postgres log from systemd journal using journalctl.
+type JournalReader struct {
+	unit string
+	run  func(ctx context.Context, args ...string) ([]byte, error) // runs journalctl and returns its output
+}
+
+// NewJournalReader creates reader of journal of specified systemd unit (glob patterns are allowed).
+func NewJournalReader(unit string) (*JournalReader, error) {
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		return nil, fmt.Errorf("journalctl is required for reading systemd journal: %s", err)
+	}
+
+	return &JournalReader{unit: unit, run: runJournalctl}, nil
+}
+
+// Name implements LogReader interface.
+func (r *JournalReader) Name() string {
+	return "journald: " + r.unit
+}
+
+// Tail implements LogReader interface.
+func (r *JournalReader) Tail(lines int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), journalctlTimeout)
+	defer cancel()
+
+	return r.run(ctx, "--unit", r.unit, "--lines", strconv.Itoa(lines), "--no-pager", "--quiet", "--output", "short-iso")
+}
+
+// Remote implements LogReader interface.
+func (r *JournalReader) Remote() bool { return false }
+
+// Close implements LogReader interface.
+func (r *JournalReader) Close() error { return nil }
+
+// PagerCommand returns command which opens the journal in pager.
+func (r *JournalReader) PagerCommand() *exec.Cmd {
+	return exec.Command("journalctl", "--unit", r.unit, "--pager-end") // #nosec G204
+}
+
+// runJournalctl runs journalctl and returns its output.
+func runJournalctl(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "journalctl", args...) // #nosec G204
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("journalctl failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// SyslogReader receives syslog messages over UDP and keeps the most recent of them.
+type SyslogReader struct {
+	conn net.PacketConn
+
+	mu    sync.Mutex
+	lines []string // recent messages, the oldest first
+}
+
+// NewSyslogReader starts listening on specified UDP address for syslog messages.
+func NewSyslogReader(address string) (*SyslogReader, error) {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("listen for syslog messages failed: %s", err)
+	}
+
+	r := &SyslogReader{conn: conn}
+	go r.receive()
+
+	return r, nil
+}
+
+// Name implements LogReader interface.
+func (r *SyslogReader) Name() string {
+	return "syslog: udp " + r.conn.LocalAddr().String()
+}
+
+// Tail implements LogReader interface.
+func (r *SyslogReader) Tail(lines int) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recent := r.lines
+	if len(recent) > lines {
+		recent = recent[len(recent)-lines:]
+	}
+
+	var buf bytes.Buffer
+	for _, l := range recent {
+		buf.WriteString(l + "\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Remote implements LogReader interface.
+func (r *SyslogReader) Remote() bool { return true }
+
+// Close implements LogReader interface.
+func (r *SyslogReader) Close() error {
+	return r.conn.Close()
+}
+
+// receive receives messages until listener is closed.
+func (r *SyslogReader) receive() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		r.add(parseSyslogMessage(buf[:n]))
+	}
+}
+
+// add adds lines of message, the oldest lines are removed.
+func (r *SyslogReader) add(lines []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines = append(r.lines, lines...)
+	if len(r.lines) > SyslogLinesMax {
+		r.lines = append([]string(nil), r.lines[len(r.lines)-SyslogLinesMax:]...)
+	}
+}
+
+// parseSyslogMessage strips priority and version from syslog message and splits it into lines. Header of the message
+// (timestamp, host and program) is kept, hence messages of different hosts could be distinguished.
+func parseSyslogMessage(data []byte) []string {
+	msg := syslogPriRE.ReplaceAllString(strings.TrimRight(string(data), "\r\n\x00"), "")
+	if msg == "" {
+		return nil
+	}
+
+	return strings.Split(msg, "\n")
+}
@@ -0,0 +1,110 @@
+package stat
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewLogReader(t *testing.T) {
+	r, err := NewLogReader("")
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+
+	r, err = NewLogReader("file")
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+
+	r, err = NewLogReader("syslog:127.0.0.1:0")
+	assert.NoError(t, err)
+	assert.NotNil(t, r)
+	assert.True(t, r.Remote())
+	assert.NoError(t, r.Close())
+
+	for _, spec := range []string{"syslog", "syslog:", "syslog:invalid", "invalid"} {
+		r, err = NewLogReader(spec)
+		assert.Error(t, err)
+		assert.Nil(t, r)
+	}
+}
+
+func TestJournalReader_Tail(t *testing.T) {
+	var got []string
+	r := &JournalReader{
+		unit: "postgresql@14-main",
+		run: func(_ context.Context, args ...string) ([]byte, error) {
+			got = args
+			return []byte("2021-10-15T12:00:00+0000 db postgres[123]: LOG:  checkpoint starting: time\n"), nil
+		},
+	}
+
+	buf, err := r.Tail(10)
+	assert.NoError(t, err)
+	assert.Equal(t, "2021-10-15T12:00:00+0000 db postgres[123]: LOG:  checkpoint starting: time\n", string(buf))
+	assert.Equal(t, []string{"--unit", "postgresql@14-main", "--lines", "10", "--no-pager", "--quiet", "--output", "short-iso"}, got)
+	assert.Equal(t, "journald: postgresql@14-main", r.Name())
+	assert.False(t, r.Remote())
+
+	r.run = func(_ context.Context, _ ...string) ([]byte, error) { return nil, fmt.Errorf("failed") }
+	_, err = r.Tail(10)
+	assert.Error(t, err)
+}
+
+func TestSyslogReader(t *testing.T) {
+	r, err := NewSyslogReader("127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = r.Close() }()
+
+	conn, err := net.Dial("udp", r.conn.LocalAddr().String())
+	assert.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	for i := 0; i < 3; i++ {
+		_, err = fmt.Fprintf(conn, "<134>Oct 15 12:00:0%d db1 postgres[123]: LOG:  message %d\n", i, i)
+		assert.NoError(t, err)
+	}
+
+	assert.Eventually(t, func() bool {
+		buf, _ := r.Tail(2)
+		return string(buf) == "Oct 15 12:00:01 db1 postgres[123]: LOG:  message 1\nOct 15 12:00:02 db1 postgres[123]: LOG:  message 2\n"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSyslogReader_add(t *testing.T) {
+	r := &SyslogReader{}
+	for i := 0; i < SyslogLinesMax+10; i++ {
+		r.add([]string{fmt.Sprintf("line %d", i)})
+	}
+
+	assert.Len(t, r.lines, SyslogLinesMax)
+	assert.Equal(t, "line 10", r.lines[0])
+
+	buf, err := r.Tail(1)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("line %d\n", SyslogLinesMax+9), string(buf))
+
+	buf, err = r.Tail(SyslogLinesMax * 2)
+	assert.NoError(t, err)
+	assert.Equal(t, SyslogLinesMax, strings.Count(string(buf), "\n"))
+}
+
+func Test_parseSyslogMessage(t *testing.T) {
+	testcases := []struct {
+		data string
+		want []string
+	}{
+		{data: "<134>Oct 15 12:00:00 db1 postgres[123]: LOG:  checkpoint complete\n", want: []string{"Oct 15 12:00:00 db1 postgres[123]: LOG:  checkpoint complete"}},
+		{data: "<134>1 2021-10-15T12:00:00Z db1 postgres 123 - - LOG:  checkpoint complete", want: []string{"2021-10-15T12:00:00Z db1 postgres 123 - - LOG:  checkpoint complete"}},
+		{data: "<11>Oct 15 12:00:00 db1 postgres[123]: ERROR:  syntax error\n\tSTATEMENT:  selec 1\n", want: []string{"Oct 15 12:00:00 db1 postgres[123]: ERROR:  syntax error", "\tSTATEMENT:  selec 1"}},
+		{data: "no priority", want: []string{"no priority"}},
+		{data: "<134>\n", want: nil},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, parseSyslogMessage([]byte(tc.data)))
+	}
+}
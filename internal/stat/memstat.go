@@ -4,6 +4,8 @@ package stat
 
 import (
 	"bufio"
+	"context"
+	"github.com/lesovsky/pgcenter/internal/log"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"os"
 	"path/filepath"
@@ -27,11 +29,12 @@ type Meminfo struct {
 }
 
 // readMeminfo returns memory/swap stats based on type of passed DB connection.
-func readMeminfo(db *postgres.DB, schemaExists bool) (Meminfo, error) {
+// Remote stats are read only if stats schema is specified.
+func readMeminfo(ctx context.Context, db *postgres.DB, schema string) (Meminfo, error) {
 	if db.Local {
 		return readMeminfoLocal("/proc/meminfo")
-	} else if schemaExists {
-		return readMeminfoRemote(db)
+	} else if schema != "" {
+		return readMeminfoRemote(ctx, db, schema)
 	}
 
 	return Meminfo{}, nil
@@ -56,13 +59,13 @@ func readMeminfoLocal(statfile string) (Meminfo, error) {
 
 		fields := strings.Fields(line)
 		if len(fields) < 3 {
-			// TODO: log error to stderr
+			log.Debug("skip malformed line of meminfo", "line", line)
 			continue
 		}
 
 		value, err := strconv.ParseUint(fields[1], 10, 64)
 		if err != nil {
-			// TODO: log error to stderr
+			log.Debug("skip malformed line of meminfo", "line", line, "error", err)
 			continue
 		}
 
@@ -94,15 +97,15 @@ func readMeminfoLocal(statfile string) (Meminfo, error) {
 }
 
 // readMeminfoRemote returns memory/swap stats from SQL stats schema.
-func readMeminfoRemote(db *postgres.DB) (Meminfo, error) {
+func readMeminfoRemote(ctx context.Context, db *postgres.DB, schema string) (Meminfo, error) {
 	var stat Meminfo
 
 	query := `SELECT metric, metric_value
-		FROM pgcenter.sys_proc_meminfo
+		FROM %s.sys_proc_meminfo
 		WHERE metric IN ('MemTotal:','MemFree:','SwapTotal:','SwapFree:', 'Cached:','Dirty:','Writeback:','Buffers:','Slab:')
 		ORDER BY 1`
 
-	rows, err := db.Query(query)
+	rows, err := db.QueryPreparedContext(ctx, schemaQuery(query, schema))
 	if err != nil {
 		return stat, err
 	}
@@ -112,7 +115,7 @@ func readMeminfoRemote(db *postgres.DB) (Meminfo, error) {
 	var value uint64
 	for rows.Next() {
 		if err := rows.Scan(&name, &value); err != nil {
-			// TODO: log error to stderr
+			log.Debug("skip malformed row of remote meminfo", "error", err)
 			continue
 		}
 
@@ -1,6 +1,7 @@
 package stat
 
 import (
+	"context"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -13,18 +14,18 @@ func Test_readMeminfo(t *testing.T) {
 
 	// test "local" reading
 	conn.Local = true
-	got, err := readMeminfo(conn, false)
+	got, err := readMeminfo(context.Background(), conn, "")
 	assert.NoError(t, err)
 	assert.Greater(t, got.MemTotal, uint64(0))
 
 	// test "remote" reading
 	conn.Local = false
-	got, err = readMeminfo(conn, true)
+	got, err = readMeminfo(context.Background(), conn, "pgcenter")
 	assert.NoError(t, err)
 	assert.Greater(t, got.MemTotal, uint64(0))
 
 	// test "remote", but when schema is not available
-	got, err = readMeminfo(conn, false)
+	got, err = readMeminfo(context.Background(), conn, "")
 	assert.NoError(t, err)
 	assert.Equal(t, got.MemTotal, uint64(0))
 }
@@ -58,13 +59,13 @@ func Test_readMeminfoRemote(t *testing.T) {
 	conn, err := postgres.NewTestConnect()
 	assert.NoError(t, err)
 
-	got, err := readMeminfoRemote(conn)
+	got, err := readMeminfoRemote(context.Background(), conn, "pgcenter")
 	assert.NoError(t, err)
 	assert.Greater(t, got.MemTotal, uint64(0))
 	assert.Greater(t, got.MemCached, uint64(0))
 	assert.Greater(t, got.MemUsed, uint64(0))
 
 	conn.Close()
-	_, err = readMeminfoRemote(conn)
+	_, err = readMeminfoRemote(context.Background(), conn, "pgcenter")
 	assert.Error(t, err)
 }
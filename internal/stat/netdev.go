@@ -4,20 +4,30 @@ package stat
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"github.com/lesovsky/pgcenter/internal/log"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"math"
 	"os"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
 	// pgProcLinkSettingsQuery quering network interfaces' details from Postgres instance
-	pgProcLinkSettingsQuery = "SELECT speed::bigint * 1000000, duplex::bigint FROM pgcenter.get_netdev_link_settings($1);"
+	pgProcLinkSettingsQuery = "SELECT speed::bigint * 1000000, duplex::bigint FROM %s.get_netdev_link_settings($1);"
 	// pgProcNetdevQuery queries network interfaces stats from Postgres instance
-	pgProcNetdevQuery = "SELECT left(iface,-1),* FROM pgcenter.sys_proc_netdev ORDER BY iface"
+	pgProcNetdevQuery = "SELECT left(iface,-1),* FROM %s.sys_proc_netdev ORDER BY iface"
+	// pgProcNetdevLinkQuery queries network interfaces stats joined with interfaces' details from Postgres instance
+	pgProcNetdevLinkQuery = "WITH links AS (SELECT * FROM %[1]s.get_netdevs_link_settings()) " +
+		"SELECT left(n.iface,-1), n.*, coalesce(l.speed, 0)::bigint * 1000000, coalesce(l.duplex, -1)::bigint " +
+		"FROM %[1]s.sys_proc_netdev n LEFT JOIN links l ON l.iface = left(n.iface,-1) ORDER BY n.iface"
+
+	// netdevLinkSchemaVersion defines version of stats schema which returns details of all interfaces in single call.
+	netdevLinkSchemaVersion = 3
 )
 
 // Netdev describes network interfaces stats based on /proc/net/dev proc file.
@@ -44,105 +54,179 @@ type Netdev struct {
 	Tcarrier    float64 /* total number of carrier losses */
 	Tcompressed float64 /* total number of received multicast packets */
 	// extended stats
-	Packets     float64 /* total number of received or transmitted packets */
-	Raverage    float64 /* average size of received packets */
-	Taverage    float64 /* average size of transmitted packets */
-	Saturation  float64 /* saturation - the number of errors/second seen for the interface */
-	Rutil       float64 /* percentage utilization for bytes received */
-	Tutil       float64 /* percentage utilization for bytes transmitted */
-	Utilization float64 /* percentage utilization of the interface */
-	Uptime      float64 /* system uptime */
+	Packets     float64   /* total number of received or transmitted packets */
+	Raverage    float64   /* average size of received packets */
+	Taverage    float64   /* average size of transmitted packets */
+	Saturation  float64   /* saturation - the number of errors/second seen for the interface */
+	Rutil       float64   /* percentage utilization for bytes received */
+	Tutil       float64   /* percentage utilization for bytes transmitted */
+	Utilization float64   /* percentage utilization of the interface */
+	Time        time.Time /* time of reading stats */
 }
 
 // Netdevs is the container for all stats of all network interfaces
 type Netdevs []Netdev
 
-// readNetdevs returns network interfaces stats based on type of passed DB connection.
-func readNetdevs(db *postgres.DB, config Config) (Netdevs, error) {
+// readNetdevs returns network interfaces stats based on type of passed DB connection. Stats are read into the buffer
+// if its capacity is enough, buffer should not be used by the caller anymore.
+func readNetdevs(ctx context.Context, db *postgres.DB, config Config, buf Netdevs) (Netdevs, error) {
 	if db.Local {
-		return readNetdevsLocal("/proc/net/dev", config.ticks)
+		return readNetdevsLocal("/proc/net/dev", config.filter, buf)
 	} else if config.SchemaPgcenterAvail {
-		return readNetdevsRemote(db)
+		return readNetdevsRemote(ctx, db, config.SchemaName, config.SchemaVersion, config.filter, buf)
 	}
 
 	return Netdevs{}, nil
 }
 
-// readNetdevsLocal returns network interfaces stats read from local proc file.
-func readNetdevsLocal(statfile string, ticks float64) (Netdevs, error) {
-	var stat Netdevs
+// readNetdevsLocal returns network interfaces stats read from local proc file into the buffer.
+func readNetdevsLocal(statfile string, filter Filter, buf Netdevs) (Netdevs, error) {
 	f, err := os.Open(filepath.Clean(statfile))
 	if err != nil {
-		return stat, err
+		return nil, err
 	}
 	defer func() {
 		_ = f.Close()
 	}()
 
-	uptime, err := readUptimeLocal("/proc/uptime", ticks)
-	if err != nil {
-		return nil, err
-	}
+	ts := time.Now()
+	stat := buf[:0]
 
 	scanner := bufio.NewScanner(f)
 	// skip header
 	_ = scanner.Scan()
 	_ = scanner.Scan()
 
+	var lines, malformed int
 	for scanner.Scan() {
 		line := scanner.Text()
-		values := strings.Fields(line)
-
-		if len(values) != 17 {
-			return nil, fmt.Errorf("%s bad content: unknown file format, wrong number of columns in line: %s", statfile, line)
-		}
-
-		var n = Netdev{}
+		lines++
 
-		_, err = fmt.Sscanln(line,
-			&n.Ifname,
-			&n.Rbytes, &n.Rpackets, &n.Rerrs, &n.Rdrop, &n.Rfifo, &n.Rframe, &n.Rcompressed, &n.Rmulticast,
-			&n.Tbytes, &n.Tpackets, &n.Terrs, &n.Tdrop, &n.Tfifo, &n.Tcolls, &n.Tcarrier, &n.Tcompressed)
+		n, err := parseNetdevLine(line)
 		if err != nil {
-			return nil, fmt.Errorf("%s bad content", statfile)
+			// Skip only malformed line, stats of other interfaces are still valid.
+			log.Warn("skip malformed line of network interfaces stats", "file", statfile, "line", line, "error", err)
+			malformed++
+			continue
 		}
 
-		// skip pseudo block devices.
-		re := regexp.MustCompile(`docker|virbr|veth`)
-		if re.MatchString(n.Ifname) {
+		// skip virtual network interfaces.
+		if filter.skipNetdev(n.Ifname) {
 			continue
 		}
 
-		n.Ifname = strings.TrimRight(n.Ifname, ":")
 		n.Saturation = n.Rerrs + n.Rdrop + n.Tdrop + n.Tfifo + n.Tcolls + n.Tcarrier
-		n.Uptime = uptime
+		n.Time = ts
 
-		// Get interface's speed and duplex
+		// Get interface's speed and duplex, use zeros if settings are not available (e.g. for loopback).
 		// TODO: perhaps it's too expensive to poll interface in every execution of the function.
-		// TODO: log errors.
-		n.Speed, n.Duplex, _ = getLinkSettings(n.Ifname) // ignore errors, just use zeros if any
+		n.Speed, n.Duplex, err = getLinkSettings(n.Ifname)
+		if err != nil {
+			log.Debug("read link settings failed", "iface", n.Ifname, "error", err)
+		}
 
 		stat = append(stat, n)
 	}
 
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if lines > 0 && malformed == lines {
+		return nil, fmt.Errorf("%s bad content: unknown file format", statfile)
+	}
+
 	return stat, nil
 }
 
-// readNetdevsRemote returns network interfaces stats from SQL stats schema.
-func readNetdevsRemote(db *postgres.DB) (Netdevs, error) {
-	var uptime float64
-	err := db.QueryRow(pgProcUptimeQuery).Scan(&uptime)
+// parseNetdevLine parses line of /proc/net/dev. Interface name is separated from counters by colon, there could be no
+// spaces between name and the first counter when counter is large. Fields following known counters are ignored, they
+// could be added by newer kernels.
+func parseNetdevLine(line string) (Netdev, error) {
+	var n Netdev
+
+	idx := strings.LastIndex(line, ":")
+	if idx < 0 {
+		return n, fmt.Errorf("no interface name")
+	}
+
+	n.Ifname = strings.TrimSpace(line[:idx])
+	if n.Ifname == "" {
+		return n, fmt.Errorf("empty interface name")
+	}
+
+	values := strings.Fields(line[idx+1:])
+	counters := []*float64{
+		&n.Rbytes, &n.Rpackets, &n.Rerrs, &n.Rdrop, &n.Rfifo, &n.Rframe, &n.Rcompressed, &n.Rmulticast,
+		&n.Tbytes, &n.Tpackets, &n.Terrs, &n.Tdrop, &n.Tfifo, &n.Tcolls, &n.Tcarrier, &n.Tcompressed,
+	}
+
+	if len(values) < len(counters) {
+		return n, fmt.Errorf("wrong number of columns: %d", len(values))
+	}
+
+	for i := range counters {
+		v, err := strconv.ParseFloat(values[i], 64)
+		if err != nil {
+			return n, fmt.Errorf("invalid value of column %d: %s", i+1, values[i])
+		}
+		*counters[i] = v
+	}
+
+	return n, nil
+}
+
+// readNetdevsRemote returns network interfaces stats from SQL stats schema. Schemas older than netdevLinkSchemaVersion
+// don't return details of all interfaces at once, details are queried for every interface.
+func readNetdevsRemote(ctx context.Context, db *postgres.DB, schema string, version int, filter Filter, buf Netdevs) (Netdevs, error) {
+	if version < netdevLinkSchemaVersion {
+		return readNetdevsRemoteLegacy(ctx, db, schema, filter, buf)
+	}
+
+	rows, err := db.QueryPreparedContext(ctx, schemaQuery(pgProcNetdevLinkQuery, schema))
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	ts := time.Now()
+
+	stat := buf[:0]
+	var dummy string
+	for rows.Next() {
+		var n = Netdev{}
+		err := rows.Scan(&n.Ifname, &dummy,
+			&n.Rbytes, &n.Rpackets, &n.Rerrs, &n.Rdrop, &n.Rfifo, &n.Rframe, &n.Rcompressed, &n.Rmulticast,
+			&n.Tbytes, &n.Tpackets, &n.Terrs, &n.Tdrop, &n.Tfifo, &n.Tcolls, &n.Tcarrier, &n.Tcompressed,
+			&n.Speed, &n.Duplex)
+		if err != nil {
+			return nil, err
+		}
+
+		// skip virtual network interfaces.
+		if filter.skipNetdev(n.Ifname) {
+			continue
+		}
+
+		n.Time = ts
+		stat = append(stat, n)
+	}
+
+	return stat, rows.Err()
+}
 
-	rows, err := db.Query(pgProcNetdevQuery)
+// readNetdevsRemoteLegacy returns network interfaces stats from SQL stats schema, details of interfaces are queried
+// for every interface separately.
+func readNetdevsRemoteLegacy(ctx context.Context, db *postgres.DB, schema string, filter Filter, buf Netdevs) (Netdevs, error) {
+	rows, err := db.QueryPreparedContext(ctx, schemaQuery(pgProcNetdevQuery, schema))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var stat Netdevs
+	ts := time.Now()
+
+	stat := buf[:0]
 	var dummy string
 	for rows.Next() {
 		var n = Netdev{}
@@ -154,19 +238,18 @@ func readNetdevsRemote(db *postgres.DB) (Netdevs, error) {
 		}
 
 		// skip virtual network interfaces.
-		re := regexp.MustCompile(`docker|virbr|veth`)
-		if re.MatchString(n.Ifname) {
+		if filter.skipNetdev(n.Ifname) {
 			continue
 		}
 
-		n.Uptime = uptime
+		n.Time = ts
 		stat = append(stat, n)
 	}
 
 	// Get interface's speed and duplex
 	// TODO: perhaps it's too expensive to poll interface in every execution of the function.
 	for i := range stat {
-		err = db.QueryRow(pgProcLinkSettingsQuery, stat[i].Ifname).Scan(&stat[i].Speed, &stat[i].Duplex)
+		err = db.QueryRowPreparedContext(ctx, schemaQuery(pgProcLinkSettingsQuery, schema), stat[i].Ifname).Scan(&stat[i].Speed, &stat[i].Duplex)
 		if err != nil {
 			return nil, err
 		}
@@ -175,14 +258,15 @@ func readNetdevsRemote(db *postgres.DB) (Netdevs, error) {
 	return stat, nil
 }
 
-// countNetdevsUsage compares two network interfaces stats snapshots and return usage stats over time interval.
-func countNetdevsUsage(prev Netdevs, curr Netdevs, ticks float64) Netdevs {
+// countNetdevsUsage compares two network interfaces stats snapshots and return usage stats over time interval. Usage
+// is written into passed buffer, it is resized in place when number of interfaces changes.
+func countNetdevsUsage(stat Netdevs, prev Netdevs, curr Netdevs, ticks float64) Netdevs {
 	if len(curr) != len(prev) {
 		// do nothing and return
 		return nil
 	}
 
-	stat := make([]Netdev, len(curr))
+	stat = resizeNetdevs(stat, len(curr))
 
 	for i := 0; i < len(curr); i++ {
 		// Skip inactive interfaces
@@ -190,7 +274,7 @@ func countNetdevsUsage(prev Netdevs, curr Netdevs, ticks float64) Netdevs {
 			continue
 		}
 
-		itv := curr[i].Uptime - prev[i].Uptime
+		itv := elapsed(prev[i].Time, curr[i].Time, 0) * ticks
 		stat[i].Ifname = curr[i].Ifname
 		stat[i].Rbytes = sValue(prev[i].Rbytes, curr[i].Rbytes, itv, ticks)
 		stat[i].Tbytes = sValue(prev[i].Tbytes, curr[i].Tbytes, itv, ticks)
@@ -237,3 +321,16 @@ func countNetdevsUsage(prev Netdevs, curr Netdevs, ticks float64) Netdevs {
 
 	return stat
 }
+
+// resizeNetdevs returns buffer of n zeroed interfaces stats, buffer is reallocated only when its capacity is not enough.
+func resizeNetdevs(stat Netdevs, n int) Netdevs {
+	if cap(stat) < n {
+		return make(Netdevs, n)
+	}
+
+	stat = stat[:n]
+	for i := range stat {
+		stat[i] = Netdev{}
+	}
+	return stat
+}
@@ -0,0 +1,209 @@
+package stat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// offlineLogDirs defines default locations of log directory inside data directory: since Postgres 10 it is 'log',
+// before it was 'pg_log'.
+var offlineLogDirs = []string{"log", "pg_log"}
+
+// Offline defines stats of local Postgres collected without connection, e.g. when Postgres is down.
+type Offline struct {
+	System             // load average, CPU and memory stats, and usage of Postgres directories
+	SystemError  error // error occurred during reading load average, memory or CPU stats
+	StorageError error // error occurred during reading usage of directories
+	Datadir      PGresult
+	DatadirError error  // error occurred during inspecting data directory
+	Logfile      string // the recent log file
+	Logtail      []byte // the recent lines of the log file
+	LogError     error  // error occurred during reading log file
+}
+
+// OfflineCollector collects stats of local Postgres which is not reachable. System stats are read from procfs, usage of
+// directories, data directory health and log tail are read from data directory.
+type OfflineCollector struct {
+	collector *Collector // keeps previous snapshots used for calculating rates
+	dirs      storageDirs
+	version   int
+}
+
+// NewOfflineCollector creates collector of stats of local Postgres with specified data directory. Locations of
+// directories and version of Postgres are taken from data directory. Empty data directory means it is unknown, in this
+// case only system stats are collected.
+func NewOfflineCollector(datadir string) (*OfflineCollector, error) {
+	systicks, err := GetSysticksLocal()
+	if err != nil {
+		return nil, fmt.Errorf("get systicks failed: %s", err)
+	}
+
+	c := &OfflineCollector{
+		collector: &Collector{config: Config{ticks: systicks}},
+	}
+	c.dirs, c.version = offlineDirs(datadir)
+
+	return c, nil
+}
+
+// offlineDirs returns locations of Postgres directories and version of Postgres found in data directory.
+func offlineDirs(datadir string) (storageDirs, int) {
+	if datadir == "" {
+		return storageDirs{err: fmt.Errorf("data directory is unknown, specify it using --pgdata or PGDATA")}, 0
+	}
+
+	version, err := readDataVersion(datadir)
+	if err != nil {
+		return storageDirs{err: err}, 0
+	}
+
+	dirs := storageDirs{data: datadir, wal: "pg_wal"}
+	if version < 100000 {
+		dirs.wal = "pg_xlog"
+	}
+
+	for _, name := range offlineLogDirs {
+		path := filepath.Join(datadir, name)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dirs.log = path
+			break
+		}
+	}
+
+	return dirs, version
+}
+
+// readDataVersion returns version of Postgres which data directory belongs to, e.g. 90600 for '9.6' or 130000 for '13'.
+func readDataVersion(datadir string) (int, error) {
+	data, err := ioutil.ReadFile(filepath.Join(datadir, "PG_VERSION"))
+	if err != nil {
+		return 0, fmt.Errorf("read version of data directory failed: %s", err)
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(string(data)), ".", 2)
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid version of data directory: %s", strings.TrimSpace(string(data)))
+	}
+
+	if len(parts) == 2 {
+		minor, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid version of data directory: %s", strings.TrimSpace(string(data)))
+		}
+		return major*10000 + minor*100, nil
+	}
+
+	return major * 10000, nil
+}
+
+// Update collects stats, log tail is limited by specified number of lines. Failure of collecting particular stats is
+// saved into related error and doesn't prevent collecting of others.
+func (c *OfflineCollector) Update(ctx context.Context, lines int) Offline {
+	var s Offline
+
+	s.SystemError = func() error {
+		loadavg, err := readLoadAverageLocal("/proc/loadavg")
+		if err != nil {
+			return err
+		}
+		meminfo, err := readMeminfoLocal("/proc/meminfo")
+		if err != nil {
+			return err
+		}
+		cpustat, err := readCpuStatLocal("/proc/stat")
+		if err != nil {
+			return err
+		}
+
+		s.LoadAvg, s.Meminfo, s.CpuStat = loadavg, meminfo, c.collector.countCpuStat(cpustat)
+		return nil
+	}()
+
+	storage, err := readStorage(ctx, c.dirs)
+	if err != nil {
+		s.StorageError = err
+	} else {
+		s.Storage = c.collector.countStorage(storage, time.Now())
+	}
+
+	s.Datadir, s.DatadirError = readDatadirHealth(ctx, c.dirs, c.version)
+
+	s.Logfile, s.Logtail, s.LogError = c.readLogtail(lines)
+
+	return s
+}
+
+// readLogtail reads the recent lines of the most recently modified file in log directory.
+func (c *OfflineCollector) readLogtail(lines int) (string, []byte, error) {
+	if c.dirs.err != nil {
+		return "", nil, c.dirs.err
+	}
+	if c.dirs.log == "" {
+		return "", nil, fmt.Errorf("log directory not found in %s, log might be written to syslog or journald", c.dirs.data)
+	}
+
+	entries, err := ioutil.ReadDir(c.dirs.log)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var latest os.FileInfo
+	for _, e := range entries {
+		if e.Mode().IsRegular() && (latest == nil || e.ModTime().After(latest.ModTime())) {
+			latest = e
+		}
+	}
+	if latest == nil {
+		return "", nil, fmt.Errorf("no log files in %s", c.dirs.log)
+	}
+
+	logfile := Logfile{Path: filepath.Join(c.dirs.log, latest.Name())}
+	if latest.Size() == 0 {
+		return logfile.Path, nil, nil
+	}
+
+	if err := logfile.Open(); err != nil {
+		return logfile.Path, nil, err
+	}
+	defer func() { _ = logfile.Close() }()
+
+	buf, err := logfile.Read(lines, lines*1024)
+	if err != nil {
+		return logfile.Path, nil, err
+	}
+
+	// Buffer is not filled completely when the file is smaller than buffer.
+	return logfile.Path, bytes.TrimRight(buf, "\x00"), nil
+}
+
+// LocateDataDirectory returns data directory of local Postgres listening on Unix socket in specified directory and port.
+// Data directory is written into lock file of the socket, the file is kept until Postgres is shut down, e.g. it is left
+// after crash.
+func LocateDataDirectory(socketDir string, port uint16) (string, error) {
+	path := filepath.Join(socketDir, fmt.Sprintf(".s.PGSQL.%d.lock", port))
+
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("locate data directory failed: %s", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// The first line is PID of postmaster, the second line is data directory.
+	scanner := bufio.NewScanner(f)
+	for i := 0; i < 2 && scanner.Scan(); i++ {
+		if i == 1 && scanner.Text() != "" {
+			return scanner.Text(), nil
+		}
+	}
+
+	return "", fmt.Errorf("locate data directory failed: data directory not found in %s", path)
+}
@@ -0,0 +1,125 @@
+package stat
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_offlineDirs(t *testing.T) {
+	data, err := ioutil.TempDir("", "pgcenter-offline-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(data) }()
+
+	// Unknown data directory.
+	dirs, version := offlineDirs("")
+	assert.Error(t, dirs.err)
+	assert.Equal(t, 0, version)
+
+	// Not a data directory.
+	dirs, _ = offlineDirs(data)
+	assert.Error(t, dirs.err)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(data, "PG_VERSION"), []byte("9.6\n"), 0600))
+	assert.NoError(t, os.Mkdir(filepath.Join(data, "pg_log"), 0700))
+	dirs, version = offlineDirs(data)
+	assert.Equal(t, storageDirs{data: data, wal: "pg_xlog", log: filepath.Join(data, "pg_log")}, dirs)
+	assert.Equal(t, 90600, version)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(data, "PG_VERSION"), []byte("13\n"), 0600))
+	assert.NoError(t, os.Mkdir(filepath.Join(data, "log"), 0700))
+	dirs, version = offlineDirs(data)
+	assert.Equal(t, storageDirs{data: data, wal: "pg_wal", log: filepath.Join(data, "log")}, dirs)
+	assert.Equal(t, 130000, version)
+}
+
+func Test_readDataVersion(t *testing.T) {
+	data, err := ioutil.TempDir("", "pgcenter-offline-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(data) }()
+
+	testcases := []struct {
+		content string
+		want    int
+		valid   bool
+	}{
+		{content: "14\n", want: 140000, valid: true},
+		{content: "9.5\n", want: 90500, valid: true},
+		{content: "invalid\n", valid: false},
+		{content: "9.x\n", valid: false},
+	}
+
+	for _, tc := range testcases {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(data, "PG_VERSION"), []byte(tc.content), 0600))
+		got, err := readDataVersion(data)
+		if tc.valid {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
+
+func TestOfflineCollector_Update(t *testing.T) {
+	data, err := ioutil.TempDir("", "pgcenter-offline-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(data) }()
+
+	write := func(path string, content string) {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+
+	write(filepath.Join(data, "PG_VERSION"), "13\n")
+	write(filepath.Join(data, "pg_wal", "000000010000000000000001"), "wal")
+	write(filepath.Join(data, "log", "postgresql-Mon.log"), "old\n")
+	write(filepath.Join(data, "log", "postgresql-Tue.log"), "line 1\nline 2\nline 3\n")
+	past := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(data, "log", "postgresql-Mon.log"), past, past))
+
+	c, err := NewOfflineCollector(data)
+	assert.NoError(t, err)
+
+	s := c.Update(context.Background(), 2)
+	assert.NoError(t, s.SystemError)
+	assert.NoError(t, s.StorageError)
+	assert.Equal(t, int64(3), s.Storage[0].Size)
+	assert.NoError(t, s.DatadirError)
+	assert.Equal(t, datadirHealthCols, s.Datadir.Cols)
+	assert.NoError(t, s.LogError)
+	assert.Equal(t, filepath.Join(data, "log", "postgresql-Tue.log"), s.Logfile)
+	assert.Equal(t, "line 2\nline 3\n", string(s.Logtail))
+
+	// Unknown data directory, only system stats are collected.
+	c, err = NewOfflineCollector("")
+	assert.NoError(t, err)
+
+	s = c.Update(context.Background(), 2)
+	assert.NoError(t, s.SystemError)
+	assert.Error(t, s.StorageError)
+	assert.Error(t, s.DatadirError)
+	assert.Error(t, s.LogError)
+}
+
+func TestLocateDataDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-socket-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	_, err = LocateDataDirectory(dir, 5432)
+	assert.Error(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".s.PGSQL.5432.lock"), []byte("1234\n/var/lib/postgresql/13/main\n1614834367\n5432\n"), 0600))
+	got, err := LocateDataDirectory(dir, 5432)
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/lib/postgresql/13/main", got)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".s.PGSQL.5433.lock"), []byte("1234\n"), 0600))
+	_, err = LocateDataDirectory(dir, 5433)
+	assert.Error(t, err)
+}
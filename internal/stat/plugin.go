@@ -0,0 +1,162 @@
+package stat
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewViewResult collects stats of the view: runs view's query, or runs plugin's command for views declared as plugins.
+func NewViewResult(db *postgres.DB, v view.View) (PGresult, error) {
+	return NewViewResultContext(context.Background(), db, v)
+}
+
+// NewViewResultContext is the same as NewViewResult, but query is cancelled (or plugin's command is killed) when
+// context is done.
+func NewViewResultContext(ctx context.Context, db *postgres.DB, v view.View) (PGresult, error) {
+	if v.Plugin != nil {
+		return newPluginResult(ctx, db, v.Plugin)
+	}
+
+	return NewPGresultContext(ctx, db, v.LimitedQuery(), v.Args...)
+}
+
+// NewPluginResult runs plugin's command and wraps rows printed by the command into PGresult. Connection parameters of
+// Postgres are passed to the command using PGHOST, PGPORT, PGUSER and PGDATABASE environment variables.
+func NewPluginResult(db *postgres.DB, p *view.Plugin) (PGresult, error) {
+	return newPluginResult(context.Background(), db, p)
+}
+
+// newPluginResult runs plugin's command which is killed when context is done or plugin's timeout expires.
+func newPluginResult(ctx context.Context, db *postgres.DB, p *view.Plugin) (PGresult, error) {
+	if len(p.Command) == 0 {
+		return PGresult{}, fmt.Errorf("no command defined")
+	}
+
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...) // #nosec G204
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	cmd.Env = os.Environ()
+
+	if db != nil && db.Config.Config != nil {
+		c := db.Config.Config
+		cmd.Env = append(cmd.Env,
+			"PGHOST="+c.Host, "PGPORT="+strconv.Itoa(int(c.Port)), "PGUSER="+c.User, "PGDATABASE="+c.Database,
+		)
+	}
+
+	err := cmd.Run()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return PGresult{}, fmt.Errorf("plugin %s: timed out after %s", p.Command[0], p.Timeout)
+		}
+		return PGresult{}, fmt.Errorf("plugin %s: %s: %s", p.Command[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	res, err := parsePluginOutput(&stdout, p.Columns)
+	if err != nil {
+		return PGresult{}, fmt.Errorf("plugin %s: %s", p.Command[0], err)
+	}
+
+	res.Time = time.Now()
+
+	return res, nil
+}
+
+// parsePluginOutput parses rows printed by plugin. Output is a JSON array of rows, or a sequence of JSON objects each
+// of which is a row (one per line). Rows are objects keyed by columns names, or arrays of values in columns order.
+// Missing values and nulls are NULLs.
+func parsePluginOutput(r io.Reader, columns []string) (PGresult, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var rows []interface{}
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return PGresult{}, fmt.Errorf("parse output failed: %s", err)
+		}
+
+		switch t := v.(type) {
+		case []interface{}:
+			rows = append(rows, t...)
+		case map[string]interface{}:
+			rows = append(rows, t)
+		default:
+			return PGresult{}, fmt.Errorf("parse output failed: expected array or object, got %s", pluginValue(v).String)
+		}
+	}
+
+	values := make([][]sql.NullString, 0, len(rows))
+	for i, row := range rows {
+		rowValues := make([]sql.NullString, len(columns))
+
+		switch t := row.(type) {
+		case map[string]interface{}:
+			for j, col := range columns {
+				if v, ok := t[col]; ok {
+					rowValues[j] = pluginValue(v)
+				}
+			}
+		case []interface{}:
+			if len(t) != len(columns) {
+				return PGresult{}, fmt.Errorf("row %d has %d values, expected %d", i+1, len(t), len(columns))
+			}
+			for j, v := range t {
+				rowValues[j] = pluginValue(v)
+			}
+		default:
+			return PGresult{}, fmt.Errorf("row %d is neither object nor array", i+1)
+		}
+
+		values = append(values, rowValues)
+	}
+
+	return PGresult{
+		Nrows:  len(values),
+		Ncols:  len(columns),
+		Cols:   append([]string(nil), columns...),
+		Values: values,
+		Valid:  true,
+	}, nil
+}
+
+// pluginValue converts JSON value into text value. Nested objects and arrays are kept as JSON.
+func pluginValue(v interface{}) sql.NullString {
+	switch t := v.(type) {
+	case nil:
+		return sql.NullString{}
+	case string:
+		return sql.NullString{String: t, Valid: true}
+	case json.Number:
+		return sql.NullString{String: t.String(), Valid: true}
+	case bool:
+		return sql.NullString{String: strconv.FormatBool(t), Valid: true}
+	default:
+		data, err := json.Marshal(t)
+		if err != nil {
+			return sql.NullString{}
+		}
+		return sql.NullString{String: string(data), Valid: true}
+	}
+}
@@ -0,0 +1,110 @@
+package stat
+
+import (
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPluginResult(t *testing.T) {
+	p := &view.Plugin{
+		Command: []string{"sh", "-c", `echo '[{"pool": "pgbench", "active": 4, "xacts": 1500}, {"pool": "app", "active": null}]'`},
+		Timeout: 5 * time.Second,
+		Columns: []string{"pool", "active", "xacts"},
+	}
+
+	got, err := NewPluginResult(nil, p)
+	assert.NoError(t, err)
+	assert.False(t, got.Time.IsZero())
+
+	got.Time = time.Time{}
+	assert.Equal(t, PGresult{
+		Valid: true, Ncols: 3, Nrows: 2, Cols: []string{"pool", "active", "xacts"},
+		Values: [][]sql.NullString{
+			{{String: "pgbench", Valid: true}, {String: "4", Valid: true}, {String: "1500", Valid: true}},
+			{{String: "app", Valid: true}, {}, {}},
+		},
+	}, got)
+
+	// Views based on plugins are collected using plugins.
+	got, err = NewViewResult(nil, view.View{Plugin: p})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, got.Nrows)
+
+	// Failed command.
+	_, err = NewPluginResult(nil, &view.Plugin{Command: []string{"sh", "-c", "echo failure >&2; exit 1"}, Columns: []string{"a"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failure")
+
+	// Timeout.
+	_, err = NewPluginResult(nil, &view.Plugin{Command: []string{"sleep", "10"}, Timeout: 100 * time.Millisecond, Columns: []string{"a"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+
+	// Invalid output.
+	_, err = NewPluginResult(nil, &view.Plugin{Command: []string{"echo", "invalid"}, Columns: []string{"a"}})
+	assert.Error(t, err)
+
+	// No command.
+	_, err = NewPluginResult(nil, &view.Plugin{})
+	assert.Error(t, err)
+}
+
+func Test_parsePluginOutput(t *testing.T) {
+	columns := []string{"name", "value", "flag", "details"}
+
+	testcases := []struct {
+		output string
+		want   [][]sql.NullString
+	}{
+		{
+			// Array of objects.
+			output: `[{"name": "a", "value": 1.5, "flag": true, "details": {"k": [1, 2]}, "extra": "skipped"}]`,
+			want: [][]sql.NullString{
+				{{String: "a", Valid: true}, {String: "1.5", Valid: true}, {String: "true", Valid: true}, {String: `{"k":[1,2]}`, Valid: true}},
+			},
+		},
+		{
+			// Objects, one per line.
+			output: "{\"name\": \"a\", \"value\": 10000000000}\n{\"name\": \"b\", \"flag\": false}\n",
+			want: [][]sql.NullString{
+				{{String: "a", Valid: true}, {String: "10000000000", Valid: true}, {}, {}},
+				{{String: "b", Valid: true}, {}, {String: "false", Valid: true}, {}},
+			},
+		},
+		{
+			// Array of arrays.
+			output: `[["a", 1, null, "x"], ["b", 2, false, "y"]]`,
+			want: [][]sql.NullString{
+				{{String: "a", Valid: true}, {String: "1", Valid: true}, {}, {String: "x", Valid: true}},
+				{{String: "b", Valid: true}, {String: "2", Valid: true}, {String: "false", Valid: true}, {String: "y", Valid: true}},
+			},
+		},
+		{
+			// No rows.
+			output: `[]`,
+			want:   [][]sql.NullString{},
+		},
+		{
+			output: ``,
+			want:   [][]sql.NullString{},
+		},
+	}
+
+	for _, tc := range testcases {
+		got, err := parsePluginOutput(strings.NewReader(tc.output), columns)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got.Values)
+		assert.Equal(t, len(tc.want), got.Nrows)
+		assert.Equal(t, columns, got.Cols)
+	}
+
+	// Invalid outputs.
+	for _, output := range []string{`invalid`, `"string"`, `[1, 2]`, `[["a", 1]]`, `{"name": "a"} [`} {
+		_, err := parsePluginOutput(strings.NewReader(output), columns)
+		assert.Error(t, err, output)
+	}
+}
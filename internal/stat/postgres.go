@@ -2,44 +2,85 @@ package stat
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/lesovsky/pgcenter/internal/log"
+	"github.com/lesovsky/pgcenter/internal/pgtime"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Pgstat describes collected Postgres stats.
 type Pgstat struct {
-	Activity Activity
-	Result   PGresult
+	Activity      Activity
+	ActivityError error // error occurred during reading activity stats
+	Result        PGresult
 }
 
-// collectPostgresStat collect Postgres activity stats and stats returned by passed query.
-func collectPostgresStat(db *postgres.DB, version int, pgss bool, itv int, query string, prev Pgstat) (Pgstat, error) {
+// collectPostgresStat collect Postgres activity stats and stats of passed view. Each of them is collected within
+// specified timeout. Failed collecting of activity stats doesn't prevent collecting stats of the view, the error is
+// saved in returned stats.
+func collectPostgresStat(ctx context.Context, db *postgres.DB, version int, pgss bool, itv int, v view.View, prev Pgstat, timeout time.Duration) (Pgstat, error) {
 	var pgstat Pgstat
 
-	activity, err := collectActivityStat(db, version, pgss, itv, prev)
-	if err != nil {
-		pgstat.Activity = activity
-		return pgstat, err
+	pgstat.ActivityError = withTimeout(ctx, timeout, func(ctx context.Context) error {
+		var err error
+		pgstat.Activity, err = collectActivityStat(ctx, db, version, pgss, itv, prev)
+		return err
+	})
+
+	// Collecting has been interrupted, there is no reason to continue.
+	if ctx.Err() != nil {
+		return pgstat, ctx.Err()
 	}
 
-	pgstat.Activity = activity
+	// Rows of sessions history view are built by collector from sampled sessions.
+	if isSessionsHistory(v) {
+		return pgstat, nil
+	}
+
+	// Rows of data directory health view are built by collector from inspecting data directory.
+	if isDatadirHealth(v) {
+		return pgstat, nil
+	}
 
 	// Read stat
-	res, err := NewPGresult(db, query)
+	err := withTimeout(ctx, timeout, func(ctx context.Context) error {
+		var err error
+		pgstat.Result, err = NewViewResultContext(ctx, db, v)
+		return err
+	})
 	if err != nil {
 		return pgstat, err
 	}
 
-	pgstat.Result = res
-
 	return pgstat, nil
 }
 
+// withTimeout runs function which executes queries using passed context, queries are cancelled when context is done or
+// timeout expires.
+func withTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := fn(ctx)
+
+	// Return more descriptive error than the error of cancelled query.
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s: timeout %s exceeded", err, timeout)
+	}
+
+	return err
+}
+
 // Activity describes Postgres' current activity stats.
 type Activity struct {
 	State        string  // state of Postgres - up or down
@@ -61,31 +102,50 @@ type Activity struct {
 	Recovery     string  // Postgres recovery state
 	Calls        int     // Number of calls
 	CallsRate    int     // Number of calls per refresh interval
+
+	StatsResetAge      int64 // seconds since stats of the current database have been reset, -1 if unknown
+	StatementsResetAge int64 // seconds since pg_stat_statements have been reset, -1 if unknown
+
+	Replicas       int   // number of connected replicas, -1 if unknown; collected only when required by summary
+	ReplicationLag int64 // replay lag of standby or maximal replay lag of replicas in bytes, -1 if unknown
+
+	Time time.Time // time of reading stats, used for calculating rates over elapsed time
 }
 
 // collectActivityStat collects Postgres runtime activity about connected clients and workload.
-func collectActivityStat(db *postgres.DB, version int, pgss bool, itv int, prev Pgstat) (Activity, error) {
+func collectActivityStat(ctx context.Context, db *postgres.DB, version int, pgss bool, itv int, prev Pgstat) (Activity, error) {
 	var s Activity
 
-	if err := db.QueryRow(query.GetUptime).Scan(&s.Uptime); err != nil {
+	if err := db.QueryRowPreparedContext(ctx, query.GetUptime).Scan(&s.Uptime); err != nil {
 		s.Uptime = "--:--:--"
 	}
 
-	if err := db.QueryRow(query.GetRecoveryStatus).Scan(&s.Recovery); err != nil {
+	if err := db.QueryRowPreparedContext(ctx, query.GetRecoveryStatus).Scan(&s.Recovery); err != nil {
 		return s, err
 	}
 
+	if err := db.QueryRowPreparedContext(ctx, query.GetStatsResetAge).Scan(&s.StatsResetAge); err != nil {
+		s.StatsResetAge = -1
+	}
+
+	s.StatementsResetAge = -1
+	if pgss && version >= 140000 {
+		if err := db.QueryRowPreparedContext(ctx, query.GetStatementsResetAge).Scan(&s.StatementsResetAge); err != nil {
+			s.StatementsResetAge = -1
+		}
+	}
+
 	// Depending on Postgres version select proper queries.
 	queryActivity := query.SelectActivityActivityQuery(version)
 	queryAutovacuum := query.SelectActivityAutovacuumQuery(version)
 
-	err := db.QueryRow(queryActivity).Scan(
+	err := db.QueryRowPreparedContext(ctx, queryActivity).Scan(
 		&s.ConnTotal, &s.ConnIdle, &s.ConnIdleXact, &s.ConnActive, &s.ConnWaiting, &s.ConnOthers, &s.ConnPrepared)
 	if err != nil {
 		return s, err
 	}
 
-	err = db.QueryRow(queryAutovacuum).Scan(&s.AVWorkers, &s.AVAntiwrap, &s.AVUser, &s.AVMaxTime)
+	err = db.QueryRowPreparedContext(ctx, queryAutovacuum).Scan(&s.AVWorkers, &s.AVAntiwrap, &s.AVUser, &s.AVMaxTime)
 	if err != nil {
 		return s, err
 	}
@@ -93,14 +153,15 @@ func collectActivityStat(db *postgres.DB, version int, pgss bool, itv int, prev
 	// read pg_stat_statements only if it's available
 	if pgss {
 		q := query.SelectActivityStatementsQuery(version)
-		err := db.QueryRow(q).Scan(&s.StmtAvgTime, &s.Calls)
+		err := db.QueryRowPreparedContext(ctx, q).Scan(&s.StmtAvgTime, &s.Calls)
 		if err != nil {
 			return s, err
 		}
-		s.CallsRate = (s.Calls - prev.Activity.Calls) / itv
+		s.Time = time.Now()
+		s.CallsRate = int(float64(s.Calls-prev.Activity.Calls) / elapsed(prev.Activity.Time, s.Time, float64(itv)))
 	}
 
-	err = db.QueryRow(query.SelectActivityTimes).Scan(&s.XactMaxTime, &s.PrepMaxTime)
+	err = db.QueryRowPreparedContext(ctx, query.SelectActivityTimes).Scan(&s.XactMaxTime, &s.PrepMaxTime)
 	if err != nil {
 		return s, err
 	}
@@ -109,19 +170,36 @@ func collectActivityStat(db *postgres.DB, version int, pgss bool, itv int, prev
 	return s, nil
 }
 
+// collectReplicationStat returns number of connected replicas and replication lag in bytes. Lag is the replay lag of
+// standby, or the maximal replay lag of replicas connected to primary. Failures are not errors, -1 is returned instead.
+func collectReplicationStat(ctx context.Context, db *postgres.DB, version int) (int, int64) {
+	var replicas int
+	var lag int64
+	if err := db.QueryRowPreparedContext(ctx, query.SelectReplicationSummaryQuery(version)).Scan(&replicas, &lag); err != nil {
+		return -1, -1
+	}
+	return replicas, lag
+}
+
 // PostgresProperties is the container for details about Postgres
 type PostgresProperties struct {
-	VersionNum              int     // Numeric representation of Postgres version, e.g. XXYYZZ
-	Version                 string  // String representation of Postgres version, e.g. X.Y.Z
-	StartTime               float64 // Postgres start time
-	Recovery                string  // Recovery state
-	GucTrackCommitTimestamp string  // value of track_commit_timestamp GUC
-	GucAVMaxWorkers         int     // value of autovacuum_max_workers GUC
-	GucMaxConnections       int     // value of max_connections GUC
-	GucMaxPrepXacts         int     // value of max_prepared_transactions GUC
-	ExtPGSSAvail            bool    // is 'pg_stat_statements' extension installed?
-	SchemaPgcenterAvail     bool    // is 'pgcenter' schema installed?
-	SysTicks                float64 // ad-hoc implementation of GET_CLK for cases when Postgres is remote
+	VersionNum              int        // Numeric representation of Postgres version, e.g. XXYYZZ
+	Version                 string     // String representation of Postgres version, e.g. X.Y.Z
+	StartTime               float64    // Postgres start time
+	Recovery                string     // Recovery state
+	GucTrackCommitTimestamp string     // value of track_commit_timestamp GUC
+	GucTrackIOTiming        string     // value of track_io_timing GUC
+	GucAVMaxWorkers         int        // value of autovacuum_max_workers GUC
+	GucMaxConnections       int        // value of max_connections GUC
+	GucMaxPrepXacts         int        // value of max_prepared_transactions GUC
+	ExtPGSSAvail            bool       // is 'pg_stat_statements' extension installed?
+	ExtPGSSVersion          int        // version of 'pg_stat_statements' extension, e.g. 110 for 1.10, zero if unknown
+	ExtPostgresFdwAvail     bool       // is 'postgres_fdw' extension installed?
+	SchemaPgcenterAvail     bool       // is 'pgcenter' schema installed?
+	SchemaName              string     // name of the schema where stats functions and views are installed
+	SchemaVersion           int        // version of installed 'pgcenter' schema, zero if unknown
+	SysTicks                float64    // ad-hoc implementation of GET_CLK for cases when Postgres is remote
+	Privileges              Privileges // privileges of the role used for connecting
 }
 
 // GetPostgresProperties queries necessary properties from Postgres about it.
@@ -140,23 +218,90 @@ func GetPostgresProperties(db *postgres.DB) (PostgresProperties, error) {
 		return PostgresProperties{}, err
 	}
 
+	err = db.QueryRow(query.GetSetting, "track_io_timing").Scan(&props.GucTrackIOTiming)
+	if err != nil {
+		return PostgresProperties{}, err
+	}
+
+	props.Privileges, err = GetPrivileges(db)
+	if err != nil {
+		return PostgresProperties{}, err
+	}
+
 	// Is pg_stat_statement available?
 	props.ExtPGSSAvail = isExtensionExists(db, "pg_stat_statements")
+	if props.ExtPGSSAvail {
+		props.ExtPGSSVersion = getExtensionVersion(db, "pg_stat_statements")
+	}
+
+	props.ExtPostgresFdwAvail = isExtensionExists(db, "postgres_fdw")
 
 	// In case of remote Postgres we should to know remote CLK_TCK
 	if !db.Local {
-		if isSchemaExists(db, "pgcenter") {
+		if name := getStatSchemaName(db); name != "" && isSchemaExists(db, name) {
 			props.SchemaPgcenterAvail = true
-			err := db.QueryRow(query.SelectRemoteProcSysTicks).Scan(&props.SysTicks)
+			props.SchemaName = name
+
+			q, err := query.FormatSchema(query.SelectRemoteProcSysTicks, query.NewSchemaOptions(name, ""))
 			if err != nil {
 				return PostgresProperties{}, err
 			}
+
+			err = db.QueryRow(q).Scan(&props.SysTicks)
+			if err != nil {
+				return PostgresProperties{}, err
+			}
+
+			// Failed version check should not prevent from using the schema.
+			props.SchemaVersion, err = GetStatSchemaVersion(db, name)
+			if err != nil {
+				log.Warn("check version of stats schema failed", "schema", name, "error", err)
+			}
 		}
 	}
 
 	return props, nil
 }
 
+// GetStatSchemaVersion returns version of stats schema installed into specified schema.
+func GetStatSchemaVersion(db *postgres.DB, schema string) (int, error) {
+	var exists bool
+	err := db.QueryRow(query.CheckFunctionExists, pgx.Identifier{schema, "get_schema_version"}.Sanitize()).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+
+	// Schemas installed before versioning has been introduced don't have version function.
+	if !exists {
+		return 1, nil
+	}
+
+	q, err := query.FormatSchema(query.SelectStatSchemaVersion, query.NewSchemaOptions(schema, ""))
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	err = db.QueryRow(q).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// CheckStatSchemaVersion returns warning message if installed stats schema is older than expected.
+func CheckStatSchemaVersion(props PostgresProperties) string {
+	if !props.SchemaPgcenterAvail || props.SchemaVersion == 0 || props.SchemaVersion >= query.StatSchemaVersion {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"WARNING: pgcenter schema %s version %d is older than expected %d, upgrade it using 'pgcenter config --upgrade --schema %s'",
+		props.SchemaName, props.SchemaVersion, query.StatSchemaVersion, props.SchemaName,
+	)
+}
+
 // PGresult is the container for basic Postgres stats collected from pg_stat_* views
 type PGresult struct {
 	Values [][]sql.NullString /* values */
@@ -164,19 +309,32 @@ type PGresult struct {
 	Ncols  int                /* numbers of columns in Result */
 	Nrows  int                /* number of rows in Result */
 	Valid  bool               /* Used for result invalidations, on context switching for example */
+	Time   time.Time          `json:"-"` /* Time of reading stats, used for calculating rates over elapsed time */
+	Reset  bool               `json:"-"` /* Counters of some rows have been reset since previous snapshot */
+}
+
+// NewPGresult does query with passed arguments and wraps returned result into PGresult.
+func NewPGresult(db *postgres.DB, query string, args ...interface{}) (PGresult, error) {
+	return NewPGresultContext(context.Background(), db, query, args...)
 }
 
-// NewPGresult does query and wraps returned result into PGresult.
-func NewPGresult(db *postgres.DB, query string) (PGresult, error) {
+// valuesChunkRows defines number of rows which values are allocated at once when reading query result.
+const valuesChunkRows = 64
+
+// NewPGresultContext is the same as NewPGresult, but query is cancelled when context is done.
+func NewPGresultContext(ctx context.Context, db *postgres.DB, query string, args ...interface{}) (PGresult, error) {
 	if query == "" {
 		return PGresult{}, fmt.Errorf("no query defined")
 	}
 
-	rows, err := db.Query(query)
+	rows, err := db.QueryPreparedContext(ctx, query, args...)
 	if err != nil {
 		return PGresult{}, err
 	}
 
+	// Remember time when stats have been read, it contains monotonic clock reading.
+	ts := time.Now()
+
 	var (
 		descs = rows.FieldDescriptions()
 		ncols = len(descs)
@@ -188,11 +346,18 @@ func NewPGresult(db *postgres.DB, query string) (PGresult, error) {
 	// Next values from 'pointers' associated with type-strict slice - 'values'. When Scan is writing to the 'pointers' it
 	// also writing to the 'values' under the hood. When all pointers/values have been scanned, put them into 'rowsStore'.
 	// Finally we get queryResult iterable store with data and information about stored rows, columns and columns names.
+	// Slice of pointers is reused for all rows. Values of rows are cut from a shared chunk, which is allocated when
+	// previous one is exhausted, hence values are not allocated for every row. Rows are not reused between queries,
+	// results are kept by callers (e.g. as previous snapshots or in cache).
 	var rowsStore = make([][]sql.NullString, 0, 10)
+	var pointers = make([]interface{}, ncols)
+	var chunk []sql.NullString
 
 	for rows.Next() {
-		pointers := make([]interface{}, ncols)
-		values := make([]sql.NullString, ncols)
+		if len(chunk) < ncols {
+			chunk = make([]sql.NullString, ncols*valuesChunkRows)
+		}
+		values := chunk[:ncols:ncols]
 
 		for i := range pointers {
 			pointers[i] = &values[i]
@@ -200,10 +365,11 @@ func NewPGresult(db *postgres.DB, query string) (PGresult, error) {
 
 		err = rows.Scan(pointers...)
 		if err != nil {
-			//log.Warnf("skip collecting stats: %s", err) // TODO: add error handling and notification
+			log.Warn("skip row of stats", "error", err)
 			continue
 		}
 		rowsStore = append(rowsStore, values)
+		chunk = chunk[ncols:]
 		nrows++
 	}
 
@@ -221,18 +387,22 @@ func NewPGresult(db *postgres.DB, query string) (PGresult, error) {
 		Cols:   colnames,
 		Values: rowsStore,
 		Valid:  true,
+		Time:   ts,
 	}, nil
 }
 
 // Compare is public wrapper around calculateDelta.
-func Compare(curr, prev PGresult, itv int, interval [2]int, skey int, desc bool, ukey int) (PGresult, error) {
-	return calculateDelta(curr, prev, itv, interval, skey, desc, ukey)
+func Compare(curr, prev PGresult, itv int, v view.View) (PGresult, error) {
+	return calculateDelta(curr, prev, itv, v)
 }
 
-// calculateDelta compares two PGresult structs and returns ordered delta PGresult.
-func calculateDelta(curr, prev PGresult, itv int, interval [2]int, skey int, desc bool, ukey int) (PGresult, error) {
+// calculateDelta compares two PGresult structs and returns delta PGresult ordered according to the view.
+func calculateDelta(curr, prev PGresult, itv int, v view.View) (PGresult, error) {
 	// Make prev snapshot using current snap, at startup or at context switching
 	if !prev.Valid {
+		if v.Group {
+			return group(curr, v)
+		}
 		return curr, nil
 	}
 
@@ -240,25 +410,60 @@ func calculateDelta(curr, prev PGresult, itv int, interval [2]int, skey int, des
 	var err error
 
 	// Diff previous and current stats snapshot
-	if interval != [2]int{0, 0} {
-		delta, err = diff(curr, prev, itv, interval, ukey)
+	if v.DiffIntvl != [2]int{0, 0} {
+		delta, err = diff(curr, prev, itv, v.DiffIntvl, v.UniqueKey, v.Gauges)
 		if err != nil {
 			return PGresult{}, fmt.Errorf("diff failed: %s", err)
 		}
+		estimateProgress(&delta, curr, prev, v.UniqueKey, elapsed(prev.Time, curr.Time, float64(itv)))
+		if v.Name == view.IndexAdvisor {
+			adviseIndexes(&delta)
+		}
+		if v.IsStatements() {
+			countRatios(&delta)
+		}
 	} else {
 		delta = curr
+		if _, ok := progressIndex(curr); ok {
+			// Progress is estimated in place, but current snapshot is used as the previous one at the next update.
+			delta = copyResult(curr)
+			estimateProgress(&delta, curr, prev, v.UniqueKey, elapsed(prev.Time, curr.Time, float64(itv)))
+		}
+	}
+
+	if v.Group {
+		delta, err = group(delta, v)
+		if err != nil {
+			return PGresult{}, err
+		}
 	}
 
-	delta.sort(skey, desc)
+	delta.Sort(v.OrderKey, v.OrderDesc)
 
 	return delta, nil
 }
 
-// diff compares two PGresult values and produces new differential PGresult.
-func diff(curr PGresult, prev PGresult, itv int, interval [2]int, ukey int) (PGresult, error) {
+// diff compares two PGresult values and produces new differential PGresult. Decreased values of counters mean
+// counters have been reset (e.g. using pg_stat_reset()), in this case values accumulated since reset are used and
+// result is marked as reset. Values of gauges could decrease, they are diffed as-is. Gauges are specified by names of
+// columns.
+func diff(curr PGresult, prev PGresult, itv int, interval [2]int, ukey int, gauges []string) (PGresult, error) {
 	var diff PGresult
 	var found bool
 
+	isGauge := make([]bool, curr.Ncols)
+	for l, col := range curr.Cols {
+		for _, g := range gauges {
+			if col == g && l < curr.Ncols {
+				isGauge[l] = true
+			}
+		}
+	}
+
+	// Use actual interval elapsed between reading snapshots, collecting could be delayed by slow queries. Specified
+	// interval is used when time of snapshots is unknown, e.g. when snapshots are read from file.
+	seconds := elapsed(prev.Time, curr.Time, float64(itv))
+
 	diff.Values = make([][]sql.NullString, curr.Nrows)
 	diff.Cols = curr.Cols
 	diff.Ncols = len(curr.Cols)
@@ -295,7 +500,11 @@ func diff(curr PGresult, prev PGresult, itv int, interval [2]int, ukey int) (PGr
 							if err != nil {
 								return diff, fmt.Errorf("failed to convert prev to float [%d:%d]: %s", j, l, err)
 							}
-							diff.Values[i][l].String = strconv.FormatFloat((cv-pv)/float64(itv), 'f', 2, 64)
+							if cv < pv && !isGauge[l] {
+								pv = 0
+								diff.Reset = true
+							}
+							diff.Values[i][l].String = strconv.FormatFloat((cv-pv)/seconds, 'f', 2, 64)
 							diff.Values[i][l].Valid = true
 						} else {
 							cv, err := strconv.ParseInt(curr.Values[i][l].String, 10, 64)
@@ -306,7 +515,11 @@ func diff(curr PGresult, prev PGresult, itv int, interval [2]int, ukey int) (PGr
 							if err != nil {
 								return diff, fmt.Errorf("failed to convert prev to integer [%d:%d]: %s", j, l, err)
 							}
-							diff.Values[i][l].String = strconv.FormatInt((cv-pv)/int64(itv), 10)
+							if cv < pv && !isGauge[l] {
+								pv = 0
+								diff.Reset = true
+							}
+							diff.Values[i][l].String = strconv.FormatInt(int64(float64(cv-pv)/seconds), 10)
 							diff.Values[i][l].Valid = true
 						}
 					}
@@ -328,8 +541,8 @@ func diff(curr PGresult, prev PGresult, itv int, interval [2]int, ukey int) (PGr
 	return diff, nil
 }
 
-// sort performs sorting of PGresult using order key and order.
-func (r *PGresult) sort(key int, desc bool) {
+// Sort performs sorting of PGresult using order key and order.
+func (r *PGresult) Sort(key int, desc bool) {
 	if r.Nrows == 0 {
 		return /* nothing to sort */
 	}
@@ -346,6 +559,16 @@ func (r *PGresult) sort(key int, desc bool) {
 			}
 			return l < r /* asc order: 0 -> 10 */
 		})
+	} else if isTemporal(r.Values, key) {
+		// values are intervals or timestamps, compare them as durations and points in time instead of text
+		sort.Slice(r.Values, func(i, j int) bool {
+			l := temporalSeconds(r.Values[i][key].String)
+			r := temporalSeconds(r.Values[j][key].String)
+			if desc {
+				return l > r
+			}
+			return l < r
+		})
 	} else {
 		// value is string
 		sort.Slice(r.Values, func(i, j int) bool {
@@ -357,6 +580,30 @@ func (r *PGresult) sort(key int, desc bool) {
 	}
 }
 
+// isTemporal returns true if all values of the column are intervals or timestamps, empty values are allowed.
+func isTemporal(values [][]sql.NullString, key int) bool {
+	var found bool
+	for _, row := range values {
+		if row[key].String == "" {
+			continue
+		}
+		if _, ok := pgtime.Seconds(row[key].String); !ok {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// temporalSeconds returns interval or timestamp in seconds, empty values are lower than any other values.
+func temporalSeconds(s string) float64 {
+	v, ok := pgtime.Seconds(s)
+	if !ok {
+		return math.Inf(-1)
+	}
+	return v
+}
+
 // Fprint prints content of PGresult container to buffer.
 func (r *PGresult) Fprint(buf *bytes.Buffer) error {
 	// do simple ad-hoc aligning for current PGresult, do align using the longest value in the column
@@ -407,21 +654,50 @@ func isExtensionExists(db *postgres.DB, name string) bool {
 	var exists bool
 	err := db.QueryRow(query.CheckExtensionExists, name).Scan(&exists)
 	if err != nil {
-		// TODO: enable when proper logging will be implemented
-		//fmt.Println("failed to check extensions in pg_extension: ", err)
+		log.Warn("check extension failed", "extension", name, "error", err)
 		exists = false
 	}
 
 	return exists
 }
 
+// getExtensionVersion returns numeric version of installed extension, zero is returned if version is unknown.
+func getExtensionVersion(db *postgres.DB, name string) int {
+	var version string
+	err := db.QueryRow(query.GetExtensionVersion, name).Scan(&version)
+	if err != nil {
+		return 0
+	}
+
+	return query.ParseExtensionVersion(version)
+}
+
+// QueryOptions returns options used for formatting queries depending on Postgres properties.
+func (p PostgresProperties) QueryOptions(querylen int) query.Options {
+	opts := query.NewOptions(p.VersionNum, p.Recovery, p.GucTrackCommitTimestamp, querylen)
+	opts.PgSSVersion = p.ExtPGSSVersion
+	opts.PostgresFdw = p.ExtPostgresFdwAvail
+	return opts
+}
+
+// getStatSchemaName returns name of the schema where stats functions and views are installed, or empty string if
+// stats schema is not found.
+func getStatSchemaName(db *postgres.DB) string {
+	var name string
+	err := db.QueryRow(query.SelectStatSchemaName).Scan(&name)
+	if err != nil {
+		return ""
+	}
+
+	return name
+}
+
 // isSchemaExists returns 'true' if requested schema exists in the database, and 'false' if not.
 func isSchemaExists(db *postgres.DB, name string) bool {
 	var exists bool
 	err := db.QueryRow(query.CheckSchemaExists, name).Scan(&exists)
 	if err != nil {
-		// TODO: enable when proper logging will be implemented
-		//fmt.Println("failed to check schema in information_schema: ", err)
+		log.Warn("check schema failed", "schema", name, "error", err)
 		exists = false
 	}
 
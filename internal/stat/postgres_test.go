@@ -2,12 +2,15 @@ package stat
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/view"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 // newTestPGresult return PGresult with test content for test purposes.
@@ -53,15 +56,28 @@ func Test_collectPostgresStat(t *testing.T) {
 	prev := Pgstat{Activity: Activity{Calls: 0}}
 
 	version := 1000000 // suppose to use PG 100.0
-	got, err := collectPostgresStat(conn, version, true, 1, query.PgStatDatabaseDefault, prev)
+	got, err := collectPostgresStat(context.Background(), conn, version, true, 1, view.View{Query: query.PgStatDatabaseDefault}, prev, time.Second)
 	assert.NoError(t, err)
+	assert.NoError(t, got.ActivityError)
 	assert.Equal(t, "ok", got.Activity.State)
 	assert.Greater(t, got.Result.Nrows, 0)
 
+	// failed query of the view doesn't affect activity stats
+	got, err = collectPostgresStat(context.Background(), conn, version, true, 1, view.View{Query: "SELECT qq"}, prev, time.Second)
+	assert.Error(t, err)
+	assert.NoError(t, got.ActivityError)
+	assert.Equal(t, "ok", got.Activity.State)
+
+	// query of the view exceeds timeout
+	_, err = collectPostgresStat(context.Background(), conn, version, true, 1, view.View{Query: "SELECT pg_sleep(1)"}, prev, 100*time.Millisecond)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout 100ms exceeded")
+
 	// testing with already closed conn
 	conn.Close()
-	_, err = collectPostgresStat(conn, 0, true, 1, "SELECT qq", prev)
+	got, err = collectPostgresStat(context.Background(), conn, 0, true, 1, view.View{Query: "SELECT qq"}, prev, time.Second)
 	assert.Error(t, err)
+	assert.Error(t, got.ActivityError)
 }
 
 func Test_collectActivityStat(t *testing.T) {
@@ -71,7 +87,7 @@ func Test_collectActivityStat(t *testing.T) {
 	prev := Pgstat{Activity: Activity{Calls: 0}}
 
 	version := 1000000 // suppose to use PG 100.0
-	got, err := collectActivityStat(conn, version, true, 1, prev)
+	got, err := collectActivityStat(context.Background(), conn, version, true, 1, prev)
 	assert.NoError(t, err)
 	assert.Equal(t, "ok", got.State)
 	assert.NotEqual(t, "", got.Uptime)
@@ -84,7 +100,7 @@ func Test_collectActivityStat(t *testing.T) {
 
 	// testing with already closed conn
 	conn.Close()
-	_, err = collectActivityStat(conn, 0, true, 1, prev)
+	_, err = collectActivityStat(context.Background(), conn, 0, true, 1, prev)
 	assert.Error(t, err)
 }
 
@@ -104,6 +120,8 @@ func TestGetPostgresProperties(t *testing.T) {
 	assert.NotEqual(t, "", got.Recovery)
 	assert.NotEqual(t, "", got.StartTime)
 	assert.NotEqual(t, 0, got.SysTicks)
+	assert.Equal(t, query.StatSchemaVersion, got.SchemaVersion)
+	assert.True(t, got.Privileges.Superuser)
 
 	// testing with already closed conn
 	conn.Close()
@@ -111,6 +129,22 @@ func TestGetPostgresProperties(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestCheckStatSchemaVersion(t *testing.T) {
+	testcases := []struct {
+		props PostgresProperties
+		want  bool
+	}{
+		{props: PostgresProperties{SchemaPgcenterAvail: false}, want: false},
+		{props: PostgresProperties{SchemaPgcenterAvail: true, SchemaVersion: 0}, want: false},
+		{props: PostgresProperties{SchemaPgcenterAvail: true, SchemaVersion: 1}, want: true},
+		{props: PostgresProperties{SchemaPgcenterAvail: true, SchemaVersion: query.StatSchemaVersion}, want: false},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, CheckStatSchemaVersion(tc.props) != "")
+	}
+}
+
 func TestNewPGresult(t *testing.T) {
 	conn, err := postgres.NewTestConnect()
 	assert.NoError(t, err)
@@ -128,6 +162,16 @@ func TestNewPGresult(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, want, got)
 
+	// testing query with arguments, values with quotes and backslashes are passed as is
+	want = PGresult{
+		Valid: true, Ncols: 1, Nrows: 2, Cols: []string{"name"},
+		Values: [][]sql.NullString{{{String: "o'brien", Valid: true}}, {{String: `a\pp`, Valid: true}}},
+	}
+	got, err = NewPGresult(conn, "SELECT name FROM (VALUES ('o''brien'), (E'a\\\\pp'), ('app')) AS t (name) WHERE name = ANY($1::text[])",
+		[]string{"o'brien", `a\pp`, "'); SELECT 1; --"})
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
 	// testing empty query
 	_, err = NewPGresult(conn, "")
 	assert.Error(t, err)
@@ -189,22 +233,22 @@ func Test_calculateDelta(t *testing.T) {
 	}
 
 	// calculate delta with ASC sort
-	got, err := calculateDelta(curr, prev, 1, [2]int{1, 3}, 1, false, 0)
+	got, err := calculateDelta(curr, prev, 1, view.View{DiffIntvl: [2]int{1, 3}, OrderKey: 1, OrderDesc: false})
 	assert.NoError(t, err)
 	assert.Equal(t, wantAsc, got)
 
 	// calculate delta with DESC sort
-	got, err = calculateDelta(curr, prev, 1, [2]int{1, 3}, 1, true, 0)
+	got, err = calculateDelta(curr, prev, 1, view.View{DiffIntvl: [2]int{1, 3}, OrderKey: 1, OrderDesc: true})
 	assert.NoError(t, err)
 	assert.Equal(t, wantDesc, got)
 
 	// calculate delta with zero diff-interval, just return current value
-	got, err = calculateDelta(curr, prev, 1, [2]int{0, 0}, 1, true, 0)
+	got, err = calculateDelta(curr, prev, 1, view.View{DiffIntvl: [2]int{0, 0}, OrderKey: 1, OrderDesc: true})
 	assert.NoError(t, err)
 	assert.Equal(t, curr, got)
 
 	// calculate with invalid input data
-	_, err = calculateDelta(currInvalid, prev, 1, [2]int{1, 3}, 1, true, 0)
+	_, err = calculateDelta(currInvalid, prev, 1, view.View{DiffIntvl: [2]int{1, 3}, OrderKey: 1, OrderDesc: true})
 	assert.Error(t, err)
 }
 
@@ -242,12 +286,48 @@ func Test_diff(t *testing.T) {
 		},
 	}
 
-	got, err := diff(curr, prev, 1, [2]int{1, 3}, 0)
+	got, err := diff(curr, prev, 1, [2]int{1, 3}, 0, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, want, got)
+
+	// Actual interval between snapshots takes precedence over specified interval.
+	ts := time.Now()
+	prev.Time, curr.Time = ts, ts.Add(2*time.Second)
+
+	got, err = diff(curr, prev, 1, [2]int{1, 3}, 0, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "15.25", got.Values[0][1].String)
+	assert.Equal(t, "25", got.Values[0][2].String)
+	assert.Equal(t, "10", got.Values[1][3].String)
+	assert.False(t, got.Reset)
+
+	// Decreased counters are considered as reset, values accumulated since reset are used.
+	prev.Time, curr.Time = time.Time{}, time.Time{}
+	curr.Values[1][1].String, curr.Values[1][2].String = "25", "12.5"
+
+	got, err = diff(curr, prev, 1, [2]int{1, 3}, 0, nil)
+	assert.NoError(t, err)
+	assert.True(t, got.Reset)
+	assert.Equal(t, "25", got.Values[1][1].String)
+	assert.Equal(t, "12.50", got.Values[1][2].String)
+	assert.Equal(t, "30.50", got.Values[0][1].String)
+
+	// Decreased gauges are diffed as-is.
+	got, err = diff(curr, prev, 1, [2]int{1, 3}, 0, []string{"col2", "col3"})
+	assert.NoError(t, err)
+	assert.False(t, got.Reset)
+	assert.Equal(t, "-375", got.Values[1][1].String)
+	assert.Equal(t, "-187.50", got.Values[1][2].String)
+
+	// Gauges are marked per column, decreased counters next to gauges are still considered as reset.
+	got, err = diff(curr, prev, 1, [2]int{1, 3}, 0, []string{"col2"})
+	assert.NoError(t, err)
+	assert.True(t, got.Reset)
+	assert.Equal(t, "-375", got.Values[1][1].String)
+	assert.Equal(t, "12.50", got.Values[1][2].String)
 }
 
-func Test_sort(t *testing.T) {
+func TestPGresult_Sort(t *testing.T) {
 	res := newTestPGresult()
 	testcases := []struct {
 		name string
@@ -311,14 +391,23 @@ func Test_sort(t *testing.T) {
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			res.sort(tc.key, tc.desc)
+			res.Sort(tc.key, tc.desc)
 			assert.Equal(t, tc.want, res.Values)
 		})
 	}
 
+	// test sorting of intervals, text order differs from order of durations.
+	ageRes := PGresult{Valid: true, Ncols: 1, Nrows: 4, Cols: []string{"xact_age"}, Values: [][]sql.NullString{
+		{{String: "10 days", Valid: true}}, {{String: "00:10:00", Valid: true}}, {{String: "never", Valid: true}}, {{String: "2 days 01:00:00", Valid: true}},
+	}}
+	ageRes.Sort(0, true)
+	assert.Equal(t, [][]sql.NullString{
+		{{String: "never", Valid: true}}, {{String: "10 days", Valid: true}}, {{String: "2 days 01:00:00", Valid: true}}, {{String: "00:10:00", Valid: true}},
+	}, ageRes.Values)
+
 	// test sorting of empty PGresult.
 	emptyRes := PGresult{Valid: true, Ncols: 1, Nrows: 0, Cols: []string{"col1"}, Values: [][]sql.NullString{}}
-	emptyRes.sort(0, false)
+	emptyRes.Sort(0, false)
 	assert.Equal(t, emptyRes.Values, [][]sql.NullString{})
 }
 
@@ -0,0 +1,114 @@
+package stat
+
+import (
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"strings"
+)
+
+// Privileges describes privileges of the role used for connecting to Postgres.
+type Privileges struct {
+	Superuser         bool // role is a superuser
+	PgMonitor         bool // role is a member of pg_monitor
+	PgReadAllStats    bool // role is a member of pg_read_all_stats
+	PgReadAllSettings bool // role is a member of pg_read_all_settings
+	PgSignalBackend   bool // role is a member of pg_signal_backend
+	ResetStats        bool // role is allowed to execute pg_stat_reset()
+	ReloadConf        bool // role is allowed to execute pg_reload_conf()
+	CurrentLogfile    bool // role is allowed to execute pg_current_logfile(), always true for Postgres 9.x
+}
+
+// GetPrivileges queries privileges of the current role.
+func GetPrivileges(db *postgres.DB) (Privileges, error) {
+	var p Privileges
+	err := db.QueryRow(query.SelectRolePrivileges).Scan(
+		&p.Superuser, &p.PgMonitor, &p.PgReadAllStats, &p.PgReadAllSettings, &p.PgSignalBackend,
+		&p.ResetStats, &p.ReloadConf, &p.CurrentLogfile,
+	)
+	if err != nil {
+		return Privileges{}, err
+	}
+
+	return p, nil
+}
+
+// AllStats returns true if role is allowed to see queries and details of other roles' sessions.
+func (p Privileges) AllStats() bool {
+	return p.Superuser || p.PgMonitor || p.PgReadAllStats
+}
+
+// ReadSettings returns true if role is allowed to see settings with locations of data directory and config files.
+func (p Privileges) ReadSettings() bool {
+	return p.Superuser || p.PgMonitor || p.PgReadAllSettings
+}
+
+// ReadLogs returns true if role is allowed to get location of Postgres log.
+func (p Privileges) ReadLogs() bool {
+	return p.ReadSettings() && p.CurrentLogfile
+}
+
+// SignalAll returns true if role is allowed to cancel queries and terminate backends of other roles.
+func (p Privileges) SignalAll() bool {
+	return p.Superuser || p.PgSignalBackend
+}
+
+// CanResetStats returns true if role is allowed to reset statistics.
+func (p Privileges) CanResetStats() bool {
+	return p.ResetStats
+}
+
+// CanReloadConf returns true if role is allowed to reload configuration.
+func (p Privileges) CanReloadConf() bool {
+	return p.ReloadConf
+}
+
+// Summary returns one-line description of role's capabilities using messages of the catalog.
+func (p Privileges) Summary(messages *i18n.Catalog) string {
+	if p.Superuser {
+		return messages.T("privileges.superuser")
+	}
+
+	var roles []string
+	for _, r := range []struct {
+		name   string
+		member bool
+	}{
+		{"pg_monitor", p.PgMonitor},
+		{"pg_read_all_stats", p.PgReadAllStats},
+		{"pg_read_all_settings", p.PgReadAllSettings},
+		{"pg_signal_backend", p.PgSignalBackend},
+	} {
+		if r.member {
+			roles = append(roles, r.name)
+		}
+	}
+
+	var unavailable []string
+	for _, f := range []struct {
+		id        string
+		available bool
+	}{
+		{"privileges.all_stats", p.AllStats()},
+		{"privileges.logs", p.ReadLogs()},
+		{"privileges.read_settings", p.ReadSettings()},
+		{"privileges.reset_stats", p.ResetStats},
+		{"privileges.reload", p.ReloadConf},
+		{"privileges.signal_all", p.SignalAll()},
+	} {
+		if !f.available {
+			unavailable = append(unavailable, messages.T(f.id))
+		}
+	}
+
+	msg := messages.T("privileges.regular")
+	if len(roles) > 0 {
+		msg = messages.Sprintf("privileges.roles", strings.Join(roles, ", "))
+	}
+
+	if len(unavailable) == 0 {
+		return messages.Sprintf("privileges.available", msg)
+	}
+
+	return messages.Sprintf("privileges.unavailable", msg, strings.Join(unavailable, ", "))
+}
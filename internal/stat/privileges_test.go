@@ -0,0 +1,72 @@
+package stat
+
+import (
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetPrivileges(t *testing.T) {
+	conn, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+
+	got, err := GetPrivileges(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, Privileges{
+		Superuser: true, PgMonitor: true, PgReadAllStats: true, PgReadAllSettings: true, PgSignalBackend: true,
+		ResetStats: true, ReloadConf: true, CurrentLogfile: true,
+	}, got)
+
+	// testing with already closed conn
+	conn.Close()
+	_, err = GetPrivileges(conn)
+	assert.Error(t, err)
+}
+
+func TestPrivileges(t *testing.T) {
+	testcases := []struct {
+		p            Privileges
+		allStats     bool
+		readSettings bool
+		readLogs     bool
+		signalAll    bool
+		summary      string
+	}{
+		{
+			p:        Privileges{Superuser: true, ResetStats: true, ReloadConf: true, CurrentLogfile: true},
+			allStats: true, readSettings: true, readLogs: true, signalAll: true,
+			summary: "Privileges: superuser, all features are available.",
+		},
+		{
+			p:        Privileges{PgMonitor: true, PgReadAllStats: true, PgReadAllSettings: true, CurrentLogfile: true},
+			allStats: true, readSettings: true, readLogs: true, signalAll: false,
+			summary: "Privileges: pg_monitor, pg_read_all_stats, pg_read_all_settings; unavailable: stats reset, config reload, signalling other roles' backends.",
+		},
+		{
+			p:        Privileges{PgReadAllStats: true, PgSignalBackend: true, ResetStats: true, ReloadConf: true},
+			allStats: true, readSettings: false, readLogs: false, signalAll: true,
+			summary: "Privileges: pg_read_all_stats, pg_signal_backend; unavailable: logs, config editing.",
+		},
+		{
+			p:        Privileges{PgReadAllSettings: true, PgSignalBackend: true, ResetStats: true, ReloadConf: true, CurrentLogfile: true},
+			allStats: false, readSettings: true, readLogs: true, signalAll: true,
+			summary: "Privileges: pg_read_all_settings, pg_signal_backend; unavailable: other roles' sessions details.",
+		},
+		{
+			p:        Privileges{CurrentLogfile: true},
+			allStats: false, readSettings: false, readLogs: false, signalAll: false,
+			summary: "Privileges: regular role; unavailable: other roles' sessions details, logs, config editing, stats reset, config reload, signalling other roles' backends.",
+		},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.allStats, tc.p.AllStats())
+		assert.Equal(t, tc.readSettings, tc.p.ReadSettings())
+		assert.Equal(t, tc.readLogs, tc.p.ReadLogs())
+		assert.Equal(t, tc.signalAll, tc.p.SignalAll())
+		assert.Equal(t, tc.p.ResetStats, tc.p.CanResetStats())
+		assert.Equal(t, tc.p.ReloadConf, tc.p.CanReloadConf())
+		assert.Equal(t, tc.summary, tc.p.Summary(i18n.Default()))
+	}
+}
@@ -0,0 +1,110 @@
+package stat
+
+import (
+	"database/sql"
+	"math"
+	"strconv"
+)
+
+// progressPhases defines phases of operations, which time left until the phase is finished could be estimated in. Number
+// of pages left is reported by progress views in these phases only.
+var progressPhases = map[string]bool{
+	"scanning heap":                    true,
+	"vacuuming heap":                   true,
+	"building index: scanning table":   true,
+	"index validation: scanning table": true,
+}
+
+// progressIndex returns indexes of columns used for estimating progress. False is returned if result has no such
+// columns. Column with dead tuples is optional.
+func progressIndex(res PGresult) (map[string]int, bool) {
+	idx := map[string]int{}
+	for i, name := range res.Cols {
+		idx[name] = i
+	}
+
+	for _, name := range []string{"phase", "pages", "pages_left", "eta"} {
+		if _, ok := idx[name]; !ok {
+			return nil, false
+		}
+	}
+
+	return idx, true
+}
+
+// copyResult returns copy of the result which values could be changed without changing the origin.
+func copyResult(res PGresult) PGresult {
+	values := make([][]sql.NullString, len(res.Values))
+	for i, row := range res.Values {
+		values[i] = append([]sql.NullString{}, row...)
+	}
+	res.Values = values
+	return res
+}
+
+// estimateProgress calculates throughput and estimates time left of operations reported by progress views (e.g.
+// pg_stat_progress_vacuum). Counters of processed pages and dead tuples are converted to rates, then time left until
+// the current phase is finished is estimated using rate of pages and number of pages left. Rates are calculated only
+// for operations which are in the same phase as in the previous snapshot, e.g. number of dead tuples is reset after
+// every cycle of vacuuming indexes. Results without progress columns are not changed.
+func estimateProgress(delta *PGresult, curr, prev PGresult, ukey int, seconds float64) {
+	idx, ok := progressIndex(curr)
+	if !ok {
+		return
+	}
+
+	prevRows := make(map[string][]sql.NullString, len(prev.Values))
+	for _, row := range prev.Values {
+		prevRows[row[ukey].String] = row
+	}
+
+	phase, pages, eta := idx["phase"], idx["pages"], idx["eta"]
+	dead, hasDead := idx["dead_tup"]
+
+	for i, row := range curr.Values {
+		out := delta.Values[i]
+		out[pages], out[eta] = sql.NullString{}, sql.NullString{}
+		if hasDead {
+			out[dead] = sql.NullString{}
+		}
+
+		p, ok := prevRows[row[ukey].String]
+		if !ok || !row[phase].Valid || p[phase].String != row[phase].String || seconds <= 0 {
+			continue
+		}
+
+		pagesRate, pagesOK := counterRate(p[pages].String, row[pages].String, seconds)
+		if pagesOK {
+			out[pages] = sql.NullString{String: strconv.FormatInt(int64(pagesRate), 10), Valid: true}
+		}
+		if hasDead {
+			if rate, ok := counterRate(p[dead].String, row[dead].String, seconds); ok {
+				out[dead] = sql.NullString{String: strconv.FormatInt(int64(rate), 10), Valid: true}
+			}
+		}
+
+		if !progressPhases[row[phase].String] || !pagesOK || pagesRate <= 0 {
+			continue
+		}
+
+		left, err := strconv.ParseFloat(row[idx["pages_left"]].String, 64)
+		if err != nil || left <= 0 {
+			continue
+		}
+
+		out[eta] = sql.NullString{String: formatInterval(int64(math.Ceil(left / pagesRate))), Valid: true}
+	}
+}
+
+// counterRate returns rate of counter per second. False is returned if values are not numbers or counter decreased.
+func counterRate(prev, curr string, seconds float64) (float64, bool) {
+	p, err := strconv.ParseFloat(prev, 64)
+	if err != nil {
+		return 0, false
+	}
+	c, err := strconv.ParseFloat(curr, 64)
+	if err != nil || c < p {
+		return 0, false
+	}
+	return (c - p) / seconds, true
+}
@@ -0,0 +1,87 @@
+package stat
+
+import (
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_estimateProgress(t *testing.T) {
+	row := func(values ...string) []sql.NullString {
+		r := make([]sql.NullString, len(values))
+		for i := range values {
+			r[i] = sql.NullString{String: values[i], Valid: values[i] != ""}
+		}
+		return r
+	}
+
+	cols := []string{"pid", "phase", "t_scanned_%", "t_vacuumed_%", "pages", "pages_left", "dead_tup", "eta"}
+	prev := PGresult{Valid: true, Ncols: 8, Nrows: 4, Cols: cols, Values: [][]sql.NullString{
+		row("1", "scanning heap", "10.00", "0.00", "1000", "9000", "500", ""),
+		row("2", "vacuuming indexes", "100.00", "0.00", "", "", "900", ""),
+		row("3", "scanning heap", "50.00", "0.00", "5000", "5000", "100", ""),
+		row("5", "scanning heap", "1.00", "0.00", "100", "9900", "0", ""),
+	}}
+	curr := PGresult{Valid: true, Ncols: 8, Nrows: 5, Cols: cols, Values: [][]sql.NullString{
+		row("1", "scanning heap", "20.00", "0.00", "2000", "8000", "700", ""),
+		row("2", "vacuuming heap", "100.00", "10.00", "100", "9900", "900", ""),
+		row("3", "scanning heap", "50.00", "0.00", "5000", "5000", "50", ""),
+		row("4", "scanning heap", "1.00", "0.00", "10", "990", "0", ""),
+		row("5", "scanning heap", "1.00", "0.00", "150", "9850", "0", ""),
+	}}
+
+	delta := PGresult{Valid: true, Ncols: 8, Nrows: 5, Cols: cols, Values: make([][]sql.NullString, 5)}
+	for i := range curr.Values {
+		delta.Values[i] = append([]sql.NullString{}, curr.Values[i]...)
+	}
+
+	estimateProgress(&delta, curr, prev, 0, 10)
+
+	// 8000 pages left, 100 pages per second.
+	assert.Equal(t, row("1", "scanning heap", "20.00", "0.00", "100", "8000", "20", "00:01:20"), delta.Values[0])
+	// phase has been changed, rates are unknown.
+	assert.Equal(t, row("2", "vacuuming heap", "100.00", "10.00", "", "9900", "", ""), delta.Values[1])
+	// no progress, dead tuples have been reset.
+	assert.Equal(t, row("3", "scanning heap", "50.00", "0.00", "0", "5000", "", ""), delta.Values[2])
+	// not found in previous snapshot.
+	assert.Equal(t, row("4", "scanning heap", "1.00", "0.00", "", "990", "", ""), delta.Values[3])
+	// early phase with rounded percent, 9850 pages left, 5 pages per second.
+	assert.Equal(t, row("5", "scanning heap", "1.00", "0.00", "5", "9850", "0", "00:32:50"), delta.Values[4])
+
+	// results without progress columns are not changed.
+	res := PGresult{Valid: true, Ncols: 2, Nrows: 1, Cols: []string{"pid", "phase"}, Values: [][]sql.NullString{row("1", "x")}}
+	estimateProgress(&res, res, res, 0, 10)
+	assert.Equal(t, row("1", "x"), res.Values[0])
+}
+
+func Test_estimateProgress_createIndex(t *testing.T) {
+	row := func(values ...string) []sql.NullString {
+		r := make([]sql.NullString, len(values))
+		for i := range values {
+			r[i] = sql.NullString{String: values[i], Valid: values[i] != ""}
+		}
+		return r
+	}
+
+	cols := []string{"pid", "phase", "locker_pid", "size_total/done_%", "pages", "pages_left", "eta"}
+	prev := PGresult{Valid: true, Ncols: 7, Nrows: 2, Cols: cols, Values: [][]sql.NullString{
+		row("1", "building index: scanning table", "0", "80000/10.00", "1000", "9000", ""),
+		row("2", "waiting for old snapshots", "3", "0/0.00", "0", "0", ""),
+	}}
+	curr := PGresult{Valid: true, Ncols: 7, Nrows: 2, Cols: cols, Values: [][]sql.NullString{
+		row("1", "building index: scanning table", "0", "80000/20.00", "2000", "8000", ""),
+		row("2", "waiting for old snapshots", "3", "0/0.00", "0", "0", ""),
+	}}
+
+	res, err := calculateDelta(curr, prev, 10, view.View{Name: "progress_index", DiffIntvl: [2]int{0, 0}})
+	assert.NoError(t, err)
+
+	// 8000 pages left, 100 pages per second.
+	assert.Equal(t, row("1", "building index: scanning table", "0", "80000/20.00", "100", "8000", "00:01:20"), res.Values[0])
+	// waiting for the locker, no progress.
+	assert.Equal(t, row("2", "waiting for old snapshots", "3", "0/0.00", "0", "0", ""), res.Values[1])
+
+	// current snapshot is not changed, it is used as previous one at the next update.
+	assert.Equal(t, "2000", curr.Values[0][4].String)
+}
@@ -0,0 +1,186 @@
+package stat
+
+import (
+	"context"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"time"
+)
+
+// Sampler collects system stats, activity stats and stats of views using single connection to Postgres. Rates of views
+// are calculated using their previous snapshots. Samples are shared by all consumers of stats (e.g. metrics, alerts),
+// hence queries are executed once per collecting regardless of number of consumers. Lost connection is reestablished,
+// previous snapshots are dropped when Postgres has been restarted.
+type Sampler struct {
+	db        *postgres.DB
+	collector *Collector
+	interval  time.Duration       // interval of collecting, rates are calculated over this interval
+	views     view.Views          // views which stats are collected
+	rates     map[string]bool     // views which rates are calculated
+	system    bool                // collect system stats
+	activity  bool                // collect activity stats
+	prev      map[string]PGresult // previous snapshots of views, used for calculating rates
+}
+
+// Sample defines stats collected by sampler at once.
+type Sample struct {
+	Time          time.Time
+	Duration      time.Duration         // time spent on collecting
+	Reset         bool                  // previous snapshots have been dropped, e.g. because Postgres has been restarted
+	System        *System               // nil when system stats are not collected or not available
+	SystemError   error                 // error occurred during collecting system stats
+	Activity      *Activity             // nil when activity stats are not collected
+	ActivityError error                 // error occurred during collecting activity stats
+	Views         map[string]ViewSample // stats of views keyed by names of views
+}
+
+// ViewSample defines stats of the view collected at once.
+type ViewSample struct {
+	Current PGresult // values read from Postgres
+	Result  PGresult // rates (when calculated) or current values
+	Rates   bool     // rates have been calculated using the previous snapshot of the view
+	Err     error    // error occurred during collecting stats of the view
+}
+
+// NewSampler creates sampler which collects stats using specified connection with specified interval. No stats are
+// collected until they are requested.
+func NewSampler(db *postgres.DB, interval time.Duration) (*Sampler, error) {
+	collector, err := NewCollector(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sampler{
+		db:        db,
+		collector: collector,
+		interval:  interval,
+		views:     view.Views{},
+		rates:     map[string]bool{},
+		prev:      map[string]PGresult{},
+	}, nil
+}
+
+// SetFilter forwards devices filter to the underlying collector.
+func (s *Sampler) SetFilter(f Filter) {
+	s.collector.SetFilter(f)
+}
+
+// Properties returns properties of Postgres the sampler is connected to.
+func (s *Sampler) Properties() PostgresProperties {
+	return s.collector.Properties()
+}
+
+// AddView requests collecting stats of the view. Rates of the view are calculated if requested by any consumer,
+// otherwise only current values are collected.
+func (s *Sampler) AddView(v view.View, rates bool) {
+	s.views[v.Name] = v
+	s.rates[v.Name] = s.rates[v.Name] || rates
+}
+
+// CollectSystem requests collecting system stats. System stats are available for local Postgres, or when stats schema
+// is installed.
+func (s *Sampler) CollectSystem() {
+	s.system = true
+}
+
+// CollectActivity requests collecting activity stats.
+func (s *Sampler) CollectActivity() {
+	s.activity = true
+}
+
+// Run collects stats with configured interval until context is done. Collected samples are passed to the handler.
+func (s *Sampler) Run(ctx context.Context, handler func(Sample, error)) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+
+	for {
+		handler(s.Collect(ctx))
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Collect collects requested stats. Lost connection is reestablished, an error is returned if it's failed. Failures
+// of collecting particular stats are kept in the sample and don't prevent collecting of other stats.
+func (s *Sampler) Collect(ctx context.Context) (Sample, error) {
+	start := time.Now()
+
+	var reset bool
+	if err := s.db.PQstatus(); err != nil {
+		err = postgres.Reconnect(ctx, s.db)
+		if err != nil {
+			return Sample{}, fmt.Errorf("connection lost, reconnect failed: %s", err)
+		}
+
+		restarted, err := s.collector.Reconnected(s.db)
+		if err != nil {
+			return Sample{}, err
+		}
+
+		// Counters have been reset, rates can't be calculated using previous snapshots.
+		if restarted {
+			s.prev = map[string]PGresult{}
+			reset = true
+		}
+	}
+
+	sample := Sample{Time: start, Reset: reset, Views: make(map[string]ViewSample, len(s.views))}
+
+	props := s.collector.Properties()
+	if s.system && (s.db.Local || props.SchemaPgcenterAvail) {
+		system, err := s.collector.UpdateSystem(ctx, s.db)
+		if err != nil {
+			sample.SystemError = fmt.Errorf("collect system stats failed: %s", err)
+		} else {
+			// Samples could be kept by handlers, usage stats are copied because collector reuses their buffers.
+			system.Diskstats = append(Diskstats(nil), system.Diskstats...)
+			system.Netdevs = append(Netdevs(nil), system.Netdevs...)
+			sample.System = &system
+		}
+	}
+
+	if s.activity {
+		activity, err := s.collector.UpdateActivity(ctx, s.db, s.interval)
+		if err != nil {
+			sample.ActivityError = fmt.Errorf("collect activity stats failed: %s", err)
+		} else {
+			sample.Activity = &activity
+		}
+	}
+
+	itv := int(s.interval / time.Second)
+	for name, v := range s.views {
+		sample.Views[name] = s.collectView(ctx, v, itv)
+	}
+
+	sample.Duration = time.Since(start)
+
+	return sample, nil
+}
+
+// collectView collects stats of the view and calculates rates using the previous snapshot of the view, if requested.
+func (s *Sampler) collectView(ctx context.Context, v view.View, itv int) ViewSample {
+	res, err := NewViewResultContext(ctx, s.db, v)
+	if err != nil {
+		return ViewSample{Err: fmt.Errorf("collect %s stats failed: %s", v.Name, err)}
+	}
+
+	if !s.rates[v.Name] {
+		return ViewSample{Current: res, Result: res}
+	}
+
+	prev := s.prev[v.Name]
+	s.prev[v.Name] = res
+
+	delta, err := Compare(res, prev, itv, v)
+	if err != nil {
+		return ViewSample{Current: res, Err: fmt.Errorf("calculate %s stats failed: %s", v.Name, err)}
+	}
+
+	return ViewSample{Current: res, Result: delta, Rates: prev.Valid}
+}
@@ -0,0 +1,49 @@
+package stat
+
+import (
+	"context"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSampler_Collect(t *testing.T) {
+	conn, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	s, err := NewSampler(conn, time.Second)
+	assert.NoError(t, err)
+
+	views := view.New()
+	assert.NoError(t, views.Configure(s.Properties().QueryOptions(0)))
+
+	s.CollectActivity()
+	s.AddView(views["databases"], true)
+	s.AddView(views["activity"], false)
+
+	// Rates are calculated since the second collecting.
+	sample, err := s.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, sample.Activity)
+	assert.Nil(t, sample.System)
+	assert.Len(t, sample.Views, 2)
+	assert.NoError(t, sample.Views["databases"].Err)
+	assert.False(t, sample.Views["databases"].Rates)
+	assert.Greater(t, sample.Views["activity"].Current.Nrows, 0)
+
+	sample, err = s.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, sample.Views["databases"].Rates)
+	assert.False(t, sample.Views["activity"].Rates)
+	assert.False(t, sample.Reset)
+
+	// Lost connection is reestablished, Postgres has not been restarted hence rates are still calculated.
+	conn.Close()
+	sample, err = s.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, sample.Views["databases"].Rates)
+	assert.False(t, sample.Reset)
+}
@@ -0,0 +1,49 @@
+package stat
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/view"
+)
+
+// SnapshotFormat defines version of format of recorded stats snapshots. Snapshots recorded by older versions of
+// pgcenter have no version, it is read as zero. Format 1 snapshots keep layout of their columns.
+const SnapshotFormat = 1
+
+// Layout defines properties of columns of stats needed for calculating deltas between snapshots.
+type Layout struct {
+	DiffIntvl [2]int   `json:"diff_intvl"`       // Columns interval for diff
+	UniqueKey int      `json:"unique_key"`       // Index of column used as unique key of rows
+	Gauges    []string `json:"gauges,omitempty"` // Names of diffed columns with gauges, their decrease is not a reset of counters
+}
+
+// Validate checks columns of the layout exist in stats with specified number of columns.
+func (l Layout) Validate(ncols int) error {
+	if l.DiffIntvl[0] < 0 || l.DiffIntvl[0] > l.DiffIntvl[1] || l.DiffIntvl[1] >= ncols || l.UniqueKey < 0 || l.UniqueKey >= ncols {
+		return fmt.Errorf("invalid layout of columns: diff interval %v, unique key %d, number of columns %d", l.DiffIntvl, l.UniqueKey, ncols)
+	}
+	return nil
+}
+
+// Apply returns the view with layout of columns taken from the layout.
+func (l Layout) Apply(v view.View) view.View {
+	v.DiffIntvl, v.UniqueKey, v.Gauges = l.DiffIntvl, l.UniqueKey, l.Gauges
+	return v
+}
+
+// Snapshot defines stats of a view recorded into file. Snapshot keeps layout of its columns, hence it could be read
+// by newer versions of pgcenter even when columns of the view have been changed. Fields of stats are stored at the
+// top level, older versions of pgcenter read snapshots as plain stats.
+type Snapshot struct {
+	Format int     `json:"format"`
+	Layout *Layout `json:"layout,omitempty"`
+	PGresult
+}
+
+// NewSnapshot creates snapshot of current format from stats of the view.
+func NewSnapshot(res PGresult, v view.View) Snapshot {
+	return Snapshot{
+		Format:   SnapshotFormat,
+		Layout:   &Layout{DiffIntvl: v.DiffIntvl, UniqueKey: v.UniqueKey, Gauges: v.Gauges},
+		PGresult: res,
+	}
+}
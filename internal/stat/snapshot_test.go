@@ -0,0 +1,33 @@
+package stat
+
+import (
+	"encoding/json"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLayout_Validate(t *testing.T) {
+	assert.NoError(t, Layout{}.Validate(1))
+	assert.NoError(t, Layout{DiffIntvl: [2]int{1, 3}, UniqueKey: 4}.Validate(5))
+	assert.Error(t, Layout{}.Validate(0))
+	assert.Error(t, Layout{DiffIntvl: [2]int{1, 5}}.Validate(5))
+	assert.Error(t, Layout{DiffIntvl: [2]int{3, 1}}.Validate(5))
+	assert.Error(t, Layout{UniqueKey: 5}.Validate(5))
+}
+
+func TestLayout_Apply(t *testing.T) {
+	v := Layout{DiffIntvl: [2]int{1, 2}, UniqueKey: 3, Gauges: []string{"b"}}.Apply(view.View{Name: "test"})
+	assert.Equal(t, view.View{Name: "test", DiffIntvl: [2]int{1, 2}, UniqueKey: 3, Gauges: []string{"b"}}, v)
+}
+
+func TestNewSnapshot(t *testing.T) {
+	s := NewSnapshot(PGresult{Valid: true, Ncols: 1, Cols: []string{"a"}}, view.View{DiffIntvl: [2]int{1, 2}, UniqueKey: 3})
+
+	data, err := json.Marshal(s)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"format":1,"layout":{"diff_intvl":[1,2],"unique_key":3},"Values":null,"Cols":["a"],"Ncols":1,"Nrows":0,"Valid":true}`,
+		string(data),
+	)
+}
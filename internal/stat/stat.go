@@ -1,38 +1,39 @@
 package stat
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"fmt"
+	"github.com/jackc/pgx/v4"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/view"
-	"io/ioutil"
 	"os/exec"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 )
 
 const (
-	// pgProcUptimeQuery is the SQL for querying system uptime from Postgres instance
-	pgProcUptimeQuery = `SELECT
-		(seconds_total * pgcenter.get_sys_clk_ticks()) +
-		((seconds_total - floor(seconds_total)) * pgcenter.get_sys_clk_ticks() / 100)
-		FROM pgcenter.sys_proc_uptime`
-
 	// collect flags specifies what kind of extra stats should be collected.
 	CollectNone = iota
 	CollectDiskstats
 	CollectNetdev
 	CollectLogtail
+	CollectStorage
+)
+
+const (
+	// minCollectTimeout defines minimal time limit for a single collector, e.g. system stats or stats of the view.
+	minCollectTimeout = 5 * time.Second
 )
 
 // Stat defines all stats collected during single reading.
 type Stat struct {
-	System       // system-related stats
-	Pgstat       // postgres-related stats
-	Error  error // error occurred during reading stats
+	System             // system-related stats
+	Pgstat             // postgres-related stats
+	Error        error // error occurred during reading stats of the view
+	SystemError  error // error occurred during reading load average, memory or CPU stats
+	ExtraError   error // error occurred during reading extra stats (disks or network interfaces)
+	SummaryError error // error occurred during reading disks or network interfaces stats required by summary
 }
 
 // System defines system-related stats.
@@ -42,6 +43,7 @@ type System struct {
 	CpuStat
 	Diskstats
 	Netdevs
+	Storage
 }
 
 // Collector defines container for stats objects.
@@ -56,9 +58,23 @@ type Collector struct {
 	// network interfaces snapshots for previous and current intervals
 	prevNetdevs Netdevs
 	currNetdevs Netdevs
+	// buffers used for reading next snapshots, these are the outdated snapshots which are not used anymore
+	nextDiskstats Diskstats
+	nextNetdevs   Netdevs
+	// buffers for usage stats of the current and previous updates, usage of the previous update is still used by
+	// caller when the current one is calculated, buffers are swapped at every update
+	diskstatsUsage     Diskstats
+	prevDiskstatsUsage Diskstats
+	netdevsUsage       Netdevs
+	prevNetdevsUsage   Netdevs
+	// usage of Postgres directories and time of reading, used for calculating growth rates
+	prevStorage     Storage
+	prevStorageTime time.Time
 	// postgres stats snapshots for previous and current intervals
 	prevPgStat Pgstat
 	currPgStat Pgstat
+	// sampled active sessions, nil if sampling is disabled
+	history *SessionsHistory
 }
 
 // Config defines collector's runtime configuration.
@@ -67,13 +83,19 @@ type Config struct {
 	ticks float64
 	// flag specifies that collecting extra stats required.
 	collectExtra int
+	// optional stats required by summary, they are collected regardless of extra stats.
+	summary Summary
+	// filter of devices which stats are skipped.
+	filter Filter
+	// locations of Postgres directories, resolved when usage of directories is collected for the first time.
+	storage *storageDirs
 	// Postgres properties necessary for different purposes.
 	PostgresProperties
 }
 
 // NewCollector creates new collector.
 func NewCollector(db *postgres.DB) (*Collector, error) {
-	systicks, err := getSysticksLocal()
+	systicks, err := GetSysticksLocal()
 	if err != nil {
 		return nil, fmt.Errorf("get systicks failed: %s", err)
 	}
@@ -87,187 +109,536 @@ func NewCollector(db *postgres.DB) (*Collector, error) {
 	return &Collector{
 		config: Config{
 			ticks:              systicks,
+			filter:             DefaultFilter(),
 			PostgresProperties: props,
 		},
 	}, nil
 }
 
+// ConnError describes lost connection to Postgres, connection should be reestablished before collecting stats.
+type ConnError struct {
+	Err error
+}
+
+// Error implements error interface.
+func (e *ConnError) Error() string {
+	return fmt.Sprintf("connection lost: %s", e.Err)
+}
+
+// Unwrap returns underlying error.
+func (e *ConnError) Unwrap() error {
+	return e.Err
+}
+
+// Reconnected updates Postgres properties after connection has been reestablished. Stats snapshots are kept for
+// calculating deltas across reconnection, unless Postgres has been restarted (or another Postgres is connected, e.g.
+// after failover) - counters are reset in this case. Returns true if snapshots have been reset.
+func (c *Collector) Reconnected(db *postgres.DB) (bool, error) {
+	props, err := GetPostgresProperties(db)
+	if err != nil {
+		return false, fmt.Errorf("read postgres properties failed: %s", err)
+	}
+
+	restarted := props.StartTime != c.config.StartTime
+	c.config.PostgresProperties = props
+
+	// Another Postgres might be connected, e.g. after failover, its directories are resolved again.
+	c.config.storage = nil
+
+	if restarted {
+		c.Reset()
+	}
+
+	return restarted, nil
+}
+
+// SetSessionsHistory enables sampling of active sessions into the history at every update. History is not cleared
+// when Postgres is restarted, thus it is kept by caller and could be passed to collectors created later.
+func (c *Collector) SetSessionsHistory(h *SessionsHistory) {
+	c.history = h
+}
+
+// Summary defines optional stats shown in summary (e.g. in header of 'top' program), they are not collected by default.
+type Summary struct {
+	Diskstats   bool // total IO of block devices
+	Netdevs     bool // total traffic of network interfaces
+	Replication bool // number of replicas and replication lag
+}
+
+// SetSummary enables collecting of optional stats shown in summary.
+func (c *Collector) SetSummary(s Summary) {
+	c.config.summary = s
+}
+
+// SetFilter sets filter of block devices and network interfaces which stats are skipped.
+func (c *Collector) SetFilter(f Filter) {
+	c.config.filter = f
+}
+
 // Reset clears stats snapshots.
 func (c *Collector) Reset() {
 	c.prevPgStat = Pgstat{}
 	c.currPgStat = Pgstat{}
 }
 
-// Update implements stats collecting.
+// Update implements stats collecting. It is the same as UpdateContext with background context.
 func (c *Collector) Update(db *postgres.DB, view view.View, refresh time.Duration) (Stat, error) {
+	return c.UpdateContext(context.Background(), db, view, refresh)
+}
+
+// UpdateContext implements stats collecting. System stats and Postgres stats are collected independently, each of them
+// within its own timeout. Failure of one collector is saved into related error of returned stats and doesn't prevent
+// collecting of others. Returned error describes failure of collecting stats of the view. Disks and network interfaces
+// usage share buffers with the next but one update, hence they have to be copied to be kept longer.
+func (c *Collector) UpdateContext(ctx context.Context, db *postgres.DB, view view.View, refresh time.Duration) (Stat, error) {
 	var s Stat
 
-	// Collect load average stats.
-	loadavg, err := readLoadAverage(db, c.config.SchemaPgcenterAvail)
+	// Check connection before collecting stats, lost connection should be reestablished by caller.
+	err := db.PQstatus()
 	if err != nil {
-		return s, err
+		s.Pgstat.Activity.State = "down"
+		return s, &ConnError{Err: err}
 	}
 
-	s.LoadAvg = loadavg
+	timeout := collectTimeout(refresh)
 
-	// Collect memory/swap usage stats.
-	meminfo, err := readMeminfo(db, c.config.SchemaPgcenterAvail)
-	if err != nil {
-		return s, err
+	sysctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Local system stats are read from procfs concurrently with collecting Postgres stats. Remote system stats are
+	// read using the same connection as Postgres stats, queries can't be executed concurrently over single connection.
+	sysCh := make(chan systemSnapshot, 1)
+	buf := c.takeBuffers()
+
+	// Locations of Postgres directories are resolved using the connection before system stats are read concurrently.
+	if (c.config.collectExtra == CollectStorage || isDatadirHealth(view)) && c.config.storage == nil && db.Local {
+		dirs := resolveStorageDirs(ctx, db, c.config.VersionNum)
+		// Resolving interrupted by context is not a failure, it is retried at the next update.
+		if ctx.Err() == nil {
+			c.config.storage = &dirs
+		}
 	}
 
-	s.Meminfo = meminfo
+	if db.Local {
+		config := c.config
+		go func() { sysCh <- readSystem(sysctx, db, config, buf) }()
+	} else {
+		sysCh <- readSystem(sysctx, db, c.config, buf)
+	}
+
+	// Take refresh interval from view
+	itv := int(refresh / time.Second)
+
+	// Collect Postgres stats.
+	pgstat, err := collectPostgresStat(ctx, db, c.config.VersionNum, c.config.ExtPGSSAvail, itv, view, c.prevPgStat, timeout)
+
+	// Replication stats are not necessary for views, they are collected only when required by summary.
+	if c.config.summary.Replication && pgstat.ActivityError == nil && ctx.Err() == nil {
+		_ = withTimeout(ctx, timeout, func(ctx context.Context) error {
+			pgstat.Activity.Replicas, pgstat.Activity.ReplicationLag = collectReplicationStat(ctx, db, c.config.VersionNum)
+			return nil
+		})
+	}
+
+	s.Pgstat.Activity = pgstat.Activity
+	s.Pgstat.ActivityError = pgstat.ActivityError
+
+	// Wait for system stats.
+	snap, sysErr := waitSystem(sysctx, sysCh)
+	if sysErr != nil {
+		s.SystemError = sysErr
+	} else {
+		c.updateSystem(&s, snap)
+	}
 
-	// Collect CPU usage stats
-	cpustat, err := readCpuStat(db, c.config.SchemaPgcenterAvail)
 	if err != nil {
 		return s, err
 	}
 
-	c.prevCpuStat = c.currCpuStat
-	c.currCpuStat = cpustat
-	s.CpuStat = countCpuUsage(c.prevCpuStat, c.currCpuStat, c.config.ticks)
+	// Sample active sessions. Failed sampling is an error only for sessions history view, for other views the sample
+	// is just missed.
+	if c.history != nil {
+		err = withTimeout(ctx, timeout, func(ctx context.Context) error {
+			q, args, err := sessionsSamplesQuery(c.config.PostgresProperties)
+			if err != nil {
+				return err
+			}
+			return c.history.sample(ctx, db, q, args...)
+		})
+
+		if isSessionsHistory(view) {
+			if err != nil {
+				return s, err
+			}
+			pgstat.Result = c.history.aggregate(view.Window, time.Now())
+		}
+	}
 
-	// Collect extra stats if required.
-	var diskstats Diskstats
-	var netdevs Netdevs
+	// Inspect data directory, it is available for local Postgres only.
+	if isDatadirHealth(view) {
+		if !db.Local {
+			return s, fmt.Errorf("inspecting data directory is not supported for remote hosts")
+		}
+		// Locations are not resolved only when resolving has been interrupted.
+		if c.config.storage == nil {
+			return s, ctx.Err()
+		}
 
-	switch c.config.collectExtra {
-	case CollectDiskstats:
-		diskstats, err = c.collectDiskstats(db)
+		err = withTimeout(ctx, timeout, func(ctx context.Context) error {
+			var err error
+			pgstat.Result, err = readDatadirHealth(ctx, *c.config.storage, c.config.VersionNum)
+			return err
+		})
 		if err != nil {
 			return s, err
 		}
-		s.Diskstats = diskstats
+	}
+
+	c.prevPgStat = c.currPgStat
+	c.currPgStat = pgstat
+
+	// Compare previous and current Postgres stats snapshots and calculate delta.
+	diff, err := calculateDelta(c.currPgStat.Result, c.prevPgStat.Result, itv, view)
+	if err != nil {
+		return s, err
+	}
+
+	s.Pgstat.Result = diff
+
+	return s, nil
+}
+
+// collectTimeout returns time limit for a single collector. The limit is equal to refresh interval, but not less than
+// minimal limit.
+func collectTimeout(refresh time.Duration) time.Duration {
+	if refresh > minCollectTimeout {
+		return refresh
+	}
+	return minCollectTimeout
+}
+
+// systemSnapshot defines system stats read during single collecting.
+type systemSnapshot struct {
+	loadavg    LoadAvg
+	meminfo    Meminfo
+	cpustat    CpuStat
+	diskstats  Diskstats
+	netdevs    Netdevs
+	storage    Storage
+	storageAt  time.Time // time of reading usage of directories
+	err        error     // error occurred during reading load average, memory or CPU stats
+	extraErr   error     // error occurred during reading extra stats
+	summaryErr error     // error occurred during reading stats required by summary
+}
+
+// readSystem reads system stats, extra stats are read if required by configuration. Disks and network interfaces stats
+// are read into buffers of passed snapshot. It doesn't change collector's state, hence it could be run concurrently
+// with collecting Postgres stats. Reading is stopped when context is done, remote stats queries are cancelled, local
+// stats files which have not been read yet are skipped.
+func readSystem(ctx context.Context, db *postgres.DB, config Config, buf systemSnapshot) systemSnapshot {
+	var snap systemSnapshot
+
+	snap.loadavg, snap.err = readLoadAverage(ctx, db, config.SchemaName)
+	if snap.err == nil {
+		snap.err = ctx.Err()
+	}
+	if snap.err == nil {
+		snap.meminfo, snap.err = readMeminfo(ctx, db, config.SchemaName)
+	}
+	if snap.err == nil {
+		snap.err = ctx.Err()
+	}
+	if snap.err == nil {
+		snap.cpustat, snap.err = readCpuStat(ctx, db, config.SchemaName)
+	}
+
+	if config.collectExtra != CollectNone && ctx.Err() != nil {
+		snap.extraErr = ctx.Err()
+		return snap
+	}
+
+	switch config.collectExtra {
+	case CollectDiskstats:
+		snap.diskstats, snap.extraErr = readDiskstats(ctx, db, config, buf.diskstats)
 	case CollectNetdev:
-		netdevs, err = c.collectNetdevs(db)
-		if err != nil {
-			return s, err
+		snap.netdevs, snap.extraErr = readNetdevs(ctx, db, config, buf.netdevs)
+	case CollectStorage:
+		if !db.Local || config.storage == nil {
+			snap.extraErr = fmt.Errorf("usage of directories is available only for local Postgres")
+			break
 		}
-		s.Netdevs = netdevs
+		snap.storage, snap.extraErr = readStorage(ctx, *config.storage)
+		snap.storageAt = time.Now()
 	}
 
-	// Take refresh interval from view
+	// Disks and network interfaces stats required by summary are read unless they have been read as extra stats.
+	if config.summary.Diskstats && config.collectExtra != CollectDiskstats {
+		snap.diskstats, snap.summaryErr = readDiskstats(ctx, db, config, buf.diskstats)
+	}
+	if config.summary.Netdevs && config.collectExtra != CollectNetdev && snap.summaryErr == nil {
+		snap.netdevs, snap.summaryErr = readNetdevs(ctx, db, config, buf.netdevs)
+	}
+
+	return snap
+}
+
+// takeBuffers returns buffers for reading the next system stats snapshot. Buffers are taken away from collector, hence
+// they are not shared with reading which has not been finished in time and still continues in background.
+func (c *Collector) takeBuffers() systemSnapshot {
+	buf := systemSnapshot{diskstats: c.nextDiskstats, netdevs: c.nextNetdevs}
+	c.nextDiskstats, c.nextNetdevs = nil, nil
+	return buf
+}
+
+// waitSystem waits for system stats snapshot until context is done. Already read snapshot is returned even if context
+// is done.
+func waitSystem(ctx context.Context, ch <-chan systemSnapshot) (systemSnapshot, error) {
+	select {
+	case snap := <-ch:
+		return snap, nil
+	default:
+	}
+
+	select {
+	case snap := <-ch:
+		return snap, nil
+	case <-ctx.Done():
+		return systemSnapshot{}, fmt.Errorf("read system stats failed: %s", ctx.Err())
+	}
+}
+
+// updateSystem calculates system stats using read snapshot and saves them into stats.
+func (c *Collector) updateSystem(s *Stat, snap systemSnapshot) {
+	if snap.err != nil {
+		s.SystemError = snap.err
+	} else {
+		s.LoadAvg = snap.loadavg
+		s.Meminfo = snap.meminfo
+		s.CpuStat = c.countCpuStat(snap.cpustat)
+	}
+
+	s.SummaryError = snap.summaryErr
+	if snap.summaryErr == nil {
+		if c.config.summary.Diskstats && c.config.collectExtra != CollectDiskstats {
+			s.Diskstats = c.countDiskstats(snap.diskstats)
+		}
+		if c.config.summary.Netdevs && c.config.collectExtra != CollectNetdev {
+			s.Netdevs = c.countNetdevs(snap.netdevs)
+		}
+	}
+
+	if snap.extraErr != nil {
+		s.ExtraError = snap.extraErr
+		return
+	}
+
+	switch c.config.collectExtra {
+	case CollectDiskstats:
+		s.Diskstats = c.countDiskstats(snap.diskstats)
+	case CollectNetdev:
+		s.Netdevs = c.countNetdevs(snap.netdevs)
+	case CollectStorage:
+		s.Storage = c.countStorage(snap.storage, snap.storageAt)
+	}
+}
+
+// UpdateSystem collects system stats. Unlike Update, both disks and network interfaces stats are collected. Collecting
+// is interrupted when context is done. As in Update, disks and network interfaces usage share buffers with the next but
+// one update.
+func (c *Collector) UpdateSystem(ctx context.Context, db *postgres.DB) (System, error) {
+	s, err := c.collectBasic(ctx, db)
+	if err != nil {
+		return s, err
+	}
+
+	s.Diskstats, err = c.collectDiskstats(ctx, db)
+	if err != nil {
+		return s, err
+	}
+
+	s.Netdevs, err = c.collectNetdevs(ctx, db)
+	if err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+// UpdateActivity collects Postgres activity stats, rates are calculated over refresh interval. It is used when
+// activity stats are required separately from stats views, thus it shouldn't be mixed with Update. Queries are cancelled
+// when context is done.
+func (c *Collector) UpdateActivity(ctx context.Context, db *postgres.DB, refresh time.Duration) (Activity, error) {
 	itv := int(refresh / time.Second)
 
-	err = db.PQstatus()
+	activity, err := collectActivityStat(ctx, db, c.config.VersionNum, c.config.ExtPGSSAvail, itv, c.currPgStat)
 	if err != nil {
-		err = postgres.Reconnect(db)
-		if err != nil {
-			s.Pgstat.Activity.State = "down"
-			return s, err
-		}
+		return activity, err
 	}
 
-	// Collect Postgres stats.
-	pgstat, err := collectPostgresStat(db, c.config.VersionNum, c.config.ExtPGSSAvail, itv, view.Query, c.prevPgStat)
+	// Rate can't be calculated without previous stats.
+	if c.currPgStat.Activity.State == "" {
+		activity.CallsRate = 0
+	}
+
+	c.currPgStat.Activity = activity
+	return activity, nil
+}
+
+// Properties returns properties of Postgres the collector is configured for.
+func (c *Collector) Properties() PostgresProperties {
+	return c.config.PostgresProperties
+}
+
+// collectBasic collects load average, memory/swap and CPU usage stats.
+func (c *Collector) collectBasic(ctx context.Context, db *postgres.DB) (System, error) {
+	var s System
+
+	// Collect load average stats.
+	loadavg, err := readLoadAverage(ctx, db, c.config.SchemaName)
 	if err != nil {
-		s.Pgstat.Activity = pgstat.Activity
 		return s, err
 	}
 
-	s.Pgstat.Activity = pgstat.Activity
+	s.LoadAvg = loadavg
 
-	c.prevPgStat = c.currPgStat
-	c.currPgStat = pgstat
+	// Collect memory/swap usage stats.
+	meminfo, err := readMeminfo(ctx, db, c.config.SchemaName)
+	if err != nil {
+		return s, err
+	}
 
-	// Compare previous and current Postgres stats snapshots and calculate delta.
-	diff, err := calculateDelta(c.currPgStat.Result, c.prevPgStat.Result, itv, view.DiffIntvl, view.OrderKey, view.OrderDesc, view.UniqueKey)
+	s.Meminfo = meminfo
+
+	// Collect CPU usage stats
+	cpustat, err := readCpuStat(ctx, db, c.config.SchemaName)
 	if err != nil {
 		return s, err
 	}
 
-	s.Pgstat.Result = diff
+	s.CpuStat = c.countCpuStat(cpustat)
 
 	return s, nil
 }
 
+// countCpuStat saves CPU stats snapshot and calculates CPU usage since previous snapshot.
+func (c *Collector) countCpuStat(cpustat CpuStat) CpuStat {
+	c.prevCpuStat = c.currCpuStat
+	c.currCpuStat = cpustat
+	return countCpuUsage(c.prevCpuStat, c.currCpuStat, c.config.ticks)
+}
+
 // ToggleCollectExtra toggle collector's setting related to extra stats.
 func (c *Collector) ToggleCollectExtra(e int) {
 	c.config.collectExtra = e
 }
 
+// countStorage saves usage of directories and calculates growth rates since previous snapshot.
+func (c *Collector) countStorage(stats Storage, ts time.Time) Storage {
+	var seconds float64
+	if !c.prevStorageTime.IsZero() {
+		seconds = ts.Sub(c.prevStorageTime).Seconds()
+	}
+
+	stats = countStorageRates(c.prevStorage, stats, seconds)
+	c.prevStorage, c.prevStorageTime = stats, ts
+	return stats
+}
+
 // collectDiskstats implements collecting of disk devices stats.
-func (c *Collector) collectDiskstats(db *postgres.DB) (Diskstats, error) {
-	stats, err := readDiskstats(db, c.config)
+func (c *Collector) collectDiskstats(ctx context.Context, db *postgres.DB) (Diskstats, error) {
+	buf := c.takeBuffers()
+	stats, err := readDiskstats(ctx, db, c.config, buf.diskstats)
 	if err != nil {
 		return nil, err
 	}
 
+	return c.countDiskstats(stats), nil
+}
+
+// countDiskstats saves disk devices stats snapshot and calculates usage since previous snapshot. Outdated snapshot is
+// kept for reading the next one. Returned usage is valid until the next but one update, callers which keep usage
+// longer have to copy it.
+func (c *Collector) countDiskstats(stats Diskstats) Diskstats {
+	c.nextDiskstats = c.prevDiskstats[:0]
 	c.prevDiskstats = c.currDiskstats
 	c.currDiskstats = stats
 
-	// If number of block devices changed just replace previous snapshot with current one and continue.
-	if len(c.prevDiskstats) != len(c.currDiskstats) {
-		c.prevDiskstats = c.currDiskstats
-	}
-
-	usage := countDiskstatsUsage(c.prevDiskstats, c.currDiskstats, c.config.ticks)
+	usage := countDiskstatsUsage(c.prevDiskstatsUsage, c.prevDiskstats, c.currDiskstats, c.config.ticks)
+	c.prevDiskstatsUsage, c.diskstatsUsage = c.diskstatsUsage, usage
 
-	return usage, nil
+	return usage
 }
 
 // collectNetdevs implements collecting network interfaces stats.
-func (c *Collector) collectNetdevs(db *postgres.DB) (Netdevs, error) {
-	stats, err := readNetdevs(db, c.config)
+func (c *Collector) collectNetdevs(ctx context.Context, db *postgres.DB) (Netdevs, error) {
+	buf := c.takeBuffers()
+	stats, err := readNetdevs(ctx, db, c.config, buf.netdevs)
 	if err != nil {
 		return nil, err
 	}
 
+	return c.countNetdevs(stats), nil
+}
+
+// countNetdevs saves network interfaces stats snapshot and calculates usage since previous snapshot. Outdated snapshot
+// is kept for reading the next one. Returned usage is valid until the next but one update, callers which keep usage
+// longer have to copy it.
+func (c *Collector) countNetdevs(stats Netdevs) Netdevs {
+	c.nextNetdevs = c.prevNetdevs[:0]
 	c.prevNetdevs = c.currNetdevs
 	c.currNetdevs = stats
 
-	// If number of network devices changed just replace previous snapshot with current one and continue.
+	// If number of network devices changed just replace previous snapshot with current one and continue. Snapshot is
+	// copied, snapshots must not share memory because outdated snapshot is reused.
 	if len(c.prevNetdevs) != len(c.currNetdevs) {
-		c.prevNetdevs = c.currNetdevs
+		c.prevNetdevs = append(c.prevNetdevs[:0], c.currNetdevs...)
 	}
 
-	usage := countNetdevsUsage(c.prevNetdevs, c.currNetdevs, c.config.ticks)
+	usage := countNetdevsUsage(c.prevNetdevsUsage, c.prevNetdevs, c.currNetdevs, c.config.ticks)
+	c.prevNetdevsUsage, c.netdevsUsage = c.netdevsUsage, usage
 
-	return usage, nil
+	return usage
 }
 
-// readUptimeLocal returns uptime value from passed specified procfile.
-func readUptimeLocal(procfile string, ticks float64) (float64, error) {
-	var sec, csec int64
+// schemaQuery returns query where stats schema placeholders are replaced with specified schema name.
+func schemaQuery(q string, schema string) string {
+	return fmt.Sprintf(q, pgx.Identifier{schema}.Sanitize())
+}
 
-	content, err := ioutil.ReadFile(filepath.Clean(procfile))
+// GetSysticksLocal returns local value of ticks returned by 'getconf CLK_TCK' command.
+func GetSysticksLocal() (float64, error) {
+	cmdOutput, err := exec.Command("getconf", "CLK_TCK").Output()
 	if err != nil {
 		return 0, err
 	}
 
-	reader := bufio.NewReader(bytes.NewBuffer(content))
-
-	line, _, err := reader.ReadLine()
-	if err != nil {
-		return 0, err
-	}
-	_, err = fmt.Sscanf(string(line), "%d.%d", &sec, &csec)
+	systicks, err := strconv.ParseFloat(strings.TrimSpace(string(cmdOutput)), 64)
 	if err != nil {
 		return 0, err
 	}
 
-	return (float64(sec) * ticks) + (float64(csec) * ticks / 100), nil
+	return systicks, nil
 }
 
-// getSysticksLocal return local value of ticks returned by 'getconf CLK_TCK' command.
-func getSysticksLocal() (float64, error) {
-	cmdOutput, err := exec.Command("getconf", "CLK_TCK").Output()
-	if err != nil {
-		return 0, err
+// elapsed returns interval in seconds elapsed between two snapshots. Monotonic clock readings of snapshots are used,
+// hence the interval is not affected by changes of wall clock. Fallback interval is returned when time of any snapshot
+// is unknown.
+func elapsed(prev, curr time.Time, fallback float64) float64 {
+	if prev.IsZero() || curr.IsZero() {
+		return fallback
 	}
 
-	systicks, err := strconv.ParseFloat(strings.TrimSpace(string(cmdOutput)), 64)
-	if err != nil {
-		return 0, err
+	d := curr.Sub(prev).Seconds()
+	if d <= 0 {
+		return fallback
 	}
 
-	return systicks, nil
+	return d
 }
 
 // sValue calculates delta within specified time interval.
 func sValue(prev, curr, itv, ticks float64) float64 {
-	if curr > prev {
+	if curr > prev && itv > 0 {
 		return (curr - prev) / itv * ticks
 	}
 	return 0
@@ -1,6 +1,8 @@
 package stat
 
 import (
+	"context"
+	"fmt"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/query"
 	"github.com/lesovsky/pgcenter/internal/view"
@@ -68,6 +70,36 @@ func TestCollector_Update(t *testing.T) {
 	assert.NotEqual(t, 0, len(stat.Pgstat.Result.Cols))
 }
 
+func TestCollector_UpdateSystem(t *testing.T) {
+	conn, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	c, err := NewCollector(conn)
+	assert.NoError(t, err)
+
+	stat, err := c.UpdateSystem(context.Background(), conn)
+	assert.NoError(t, err)
+	assert.NotEqual(t, float64(0), stat.LoadAvg.One)
+	assert.NotEqual(t, float64(0), stat.Meminfo.MemUsed)
+	assert.NotEqual(t, 0, len(stat.Diskstats))
+	assert.NotEqual(t, 0, len(stat.Netdevs))
+}
+
+func TestCollector_UpdateActivity(t *testing.T) {
+	conn, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	c, err := NewCollector(conn)
+	assert.NoError(t, err)
+
+	activity, err := c.UpdateActivity(context.Background(), conn, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", activity.State)
+	assert.NotEqual(t, 0, activity.ConnTotal)
+}
+
 func TestCollector_collectDiskstats(t *testing.T) {
 	conn, err := postgres.NewTestConnect()
 	assert.NoError(t, err)
@@ -77,7 +109,7 @@ func TestCollector_collectDiskstats(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, c)
 
-	diskstats, err := c.collectDiskstats(conn)
+	diskstats, err := c.collectDiskstats(context.Background(), conn)
 	assert.NoError(t, err)
 	assert.NotNil(t, diskstats)
 	assert.Greater(t, len(diskstats), 0)
@@ -92,31 +124,155 @@ func TestCollector_collectNetdevs(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, c)
 
-	netdevs, err := c.collectNetdevs(conn)
+	netdevs, err := c.collectNetdevs(context.Background(), conn)
 	assert.NoError(t, err)
 	assert.NotNil(t, netdevs)
 	assert.Greater(t, len(netdevs), 0)
 }
 
-func Test_readUptimeLocal(t *testing.T) {
-	ticks, err := getSysticksLocal()
+func Test_readSystem(t *testing.T) {
+	ticks, err := GetSysticksLocal()
 	assert.NoError(t, err)
-	assert.NotEqual(t, float64(0), ticks)
 
-	got, err := readUptimeLocal("testdata/proc/uptime.golden", ticks)
+	db := &postgres.DB{Local: true}
+
+	snap := readSystem(context.Background(), db, Config{ticks: ticks, collectExtra: CollectDiskstats}, systemSnapshot{})
+	assert.NoError(t, snap.err)
+	assert.NoError(t, snap.extraErr)
+	assert.NotEqual(t, float64(0), snap.meminfo.MemTotal)
+	assert.NotEqual(t, float64(0), snap.cpustat.Total)
+	assert.Nil(t, snap.netdevs)
+}
+
+func Test_waitSystem(t *testing.T) {
+	ch := make(chan systemSnapshot, 1)
+
+	// snapshot is received
+	ch <- systemSnapshot{loadavg: LoadAvg{One: 1}}
+	snap, err := waitSystem(context.Background(), ch)
 	assert.NoError(t, err)
-	assert.Equal(t, float64(170191868), got)
+	assert.Equal(t, float64(1), snap.loadavg.One)
 
-	_, err = readUptimeLocal("testdata/proc/stat.golden", ticks)
+	// snapshot is not received in time
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = waitSystem(ctx, ch)
 	assert.Error(t, err)
+
+	// already read snapshot is received even if context is done
+	ch <- systemSnapshot{loadavg: LoadAvg{One: 2}}
+	snap, err = waitSystem(ctx, ch)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), snap.loadavg.One)
 }
 
-func Test_getSysticksLocal(t *testing.T) {
-	ticks, err := getSysticksLocal()
+func TestCollector_updateSystem(t *testing.T) {
+	c := &Collector{config: Config{ticks: 100, collectExtra: CollectDiskstats}}
+
+	// failed extra stats don't affect basic stats
+	var s Stat
+	c.updateSystem(&s, systemSnapshot{loadavg: LoadAvg{One: 1}, extraErr: fmt.Errorf("permission denied")})
+	assert.NoError(t, s.SystemError)
+	assert.Error(t, s.ExtraError)
+	assert.Equal(t, float64(1), s.LoadAvg.One)
+
+	// failed basic stats don't affect extra stats
+	s = Stat{}
+	c.updateSystem(&s, systemSnapshot{err: fmt.Errorf("permission denied"), diskstats: Diskstats{{Device: "sda"}}})
+	assert.Error(t, s.SystemError)
+	assert.NoError(t, s.ExtraError)
+	assert.Len(t, s.Diskstats, 1)
+}
+
+func TestCollector_countNetdevs(t *testing.T) {
+	c := &Collector{config: Config{ticks: 100}}
+
+	// Outdated snapshots are reused for reading the next ones.
+	for i := 0; i < 4; i++ {
+		buf := c.takeBuffers()
+		stats, err := readNetdevsLocal("testdata/proc/netdev.v1.golden", DefaultFilter(), buf.netdevs)
+		assert.NoError(t, err)
+		if i > 2 {
+			assert.Same(t, &buf.netdevs[:1][0], &stats[0])
+		}
+		_ = c.countNetdevs(stats)
+	}
+
+	// Changed number of interfaces: snapshots don't share memory.
+	buf := c.takeBuffers()
+	stats, err := readNetdevsLocal("testdata/proc/netdev.v2.golden", DefaultFilter(), buf.netdevs)
+	assert.NoError(t, err)
+	_ = c.countNetdevs(stats[:1])
+	assert.NotSame(t, &c.prevNetdevs[0], &c.currNetdevs[0])
+	assert.Equal(t, c.currNetdevs, c.prevNetdevs)
+}
+
+func TestCollector_countUsageAllocs(t *testing.T) {
+	c := &Collector{config: Config{ticks: 100}}
+	devices := []string{"sda", "sdb", "dm-0", "dm-1"}
+	ts := time.Now()
+	n := len(devices)
+	var disksUsage Diskstats
+	var netdevsUsage Netdevs
+
+	// update imitates reading of the next snapshots into buffers taken from collector.
+	update := func() {
+		ts = ts.Add(time.Second)
+		buf := c.takeBuffers()
+		disks, netdevs := buf.diskstats[:0], buf.netdevs[:0]
+		for i := 0; i < n; i++ {
+			disks = append(disks, Diskstat{Device: devices[i], Wcompleted: float64(ts.Unix()), Time: ts})
+			netdevs = append(netdevs, Netdev{Ifname: devices[i], Rpackets: float64(ts.Unix()), Time: ts})
+		}
+		disksUsage, netdevsUsage = c.countDiskstats(disks), c.countNetdevs(netdevs)
+	}
+
+	// Buffers are allocated during the first updates only.
+	for i := 0; i < 3; i++ {
+		update()
+	}
+	assert.Equal(t, float64(0), testing.AllocsPerRun(100, update))
+	assert.Len(t, disksUsage, n)
+	assert.Len(t, netdevsUsage, n)
+	assert.Equal(t, float64(1), disksUsage[0].Wcompleted)
+	assert.Equal(t, float64(1), netdevsUsage[0].Rpackets)
+
+	// Number of devices decreased: buffers are resized in place.
+	n = 2
+	update()
+	assert.Equal(t, float64(0), testing.AllocsPerRun(100, update))
+	assert.Len(t, disksUsage, n)
+	assert.Len(t, netdevsUsage, n)
+}
+
+func Test_collectTimeout(t *testing.T) {
+	assert.Equal(t, minCollectTimeout, collectTimeout(time.Second))
+	assert.Equal(t, time.Minute, collectTimeout(time.Minute))
+}
+
+func Test_schemaQuery(t *testing.T) {
+	assert.Equal(t, `SELECT * FROM "pgcenter".sys_proc_loadavg`, schemaQuery("SELECT * FROM %s.sys_proc_loadavg", "pgcenter"))
+	assert.Equal(t,
+		`SELECT "Monitoring".get_sys_clk_ticks() FROM "Monitoring".sys_proc_uptime`,
+		schemaQuery("SELECT %[1]s.get_sys_clk_ticks() FROM %[1]s.sys_proc_uptime", "Monitoring"),
+	)
+}
+
+func Test_GetSysticksLocal(t *testing.T) {
+	ticks, err := GetSysticksLocal()
 	assert.NoError(t, err)
 	assert.NotEqual(t, float64(0), ticks)
 }
 
+func Test_elapsed(t *testing.T) {
+	ts := time.Now()
+
+	assert.Equal(t, float64(2), elapsed(ts, ts.Add(2*time.Second), 1))
+	assert.Equal(t, 0.5, elapsed(ts, ts.Add(500*time.Millisecond), 1))
+	assert.Equal(t, float64(1), elapsed(time.Time{}, ts, 1)) // time of snapshot is unknown
+	assert.Equal(t, float64(1), elapsed(ts, ts, 1))          // the same snapshot
+}
+
 func Test_sValue(t *testing.T) {
 	testcases := []struct {
 		prev  float64
@@ -129,6 +285,7 @@ func Test_sValue(t *testing.T) {
 		{prev: 1000, curr: 5000, itv: 100, ticks: 100, want: 4000}, // delta 4000 per second within 1 second
 		{prev: 1000, curr: 5000, itv: 400, ticks: 100, want: 1000}, // delta 1000 per second within 4 second
 		{prev: 2000, curr: 1000, want: 0},                          // nothing, current less than previous
+		{prev: 1000, curr: 2000, itv: 0, ticks: 100, want: 0},      // nothing, interval is unknown
 	}
 
 	for _, tc := range testcases {
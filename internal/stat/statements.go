@@ -0,0 +1,225 @@
+package stat
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// reInList matches IN lists of parameters, e.g. 'IN ($1, $2, $3)'.
+	reInList = regexp.MustCompile(`(?i)\bIN\s*\(\s*\$\d+(?:\s*,\s*\$\d+)*\s*\)`)
+	// reArrayList matches arrays of parameters, e.g. 'ANY(ARRAY[$1, $2])'.
+	reArrayList = regexp.MustCompile(`(?i)\bARRAY\s*\[\s*\$\d+(?:\s*,\s*\$\d+)*\s*\]`)
+	// reValuesList matches lists of rows in VALUES clause, e.g. 'VALUES ($1, $2), ($3, $4)'.
+	reValuesList = regexp.MustCompile(`(?i)\bVALUES\s*\([^()]*\)(?:\s*,\s*\([^()]*\))*`)
+	// reParam matches parameters placeholders.
+	reParam = regexp.MustCompile(`\$\d+`)
+	// reInterval matches intervals formatted by Postgres, e.g. '01:02:03' or '2 days 01:02:03'.
+	reInterval = regexp.MustCompile(`^(?:(\d+) days? )?(\d+):(\d{2}):(\d{2})$`)
+)
+
+// ratio defines column calculated as numerator column divided by denominator column and multiplied by scale.
+type ratio struct {
+	num   string // numerator column
+	den   string // denominator column
+	scale float64
+	prec  int // number of digits after decimal point
+}
+
+// statementsRatios defines ratio columns of pg_stat_statements views: percentages, e.g. share of JIT time in total
+// time, and averages, e.g. time of reading a block. Ratios could not be diffed or summed, they are calculated using
+// diffed or summed values.
+var statementsRatios = map[string]ratio{
+	"jit_%":     {num: "jit_t", den: "all_t", scale: 100, prec: 2},
+	"read_lat":  {num: "read_t", den: "read_blks", scale: 1, prec: 3},
+	"write_lat": {num: "write_t", den: "write_blks", scale: 1, prec: 3},
+}
+
+// NormalizeQuery returns text of the statement where lists of parameters of different length are collapsed and
+// parameters are renumbered, e.g. 'id IN ($1, $2)' and 'id IN ($1, $2, $3)' are normalized to 'id IN (...)'.
+func NormalizeQuery(q string) string {
+	q = reInList.ReplaceAllString(q, "IN (...)")
+	q = reArrayList.ReplaceAllString(q, "ARRAY[...]")
+	q = reValuesList.ReplaceAllString(q, "VALUES (...)")
+
+	var n int
+	return reParam.ReplaceAllStringFunc(q, func(string) string {
+		n++
+		return "$" + strconv.Itoa(n)
+	})
+}
+
+// groupStatements groups rows of pg_stat_statements view by normalized text of statements, hence the same statement
+// executed by different users, in different databases or with lists of different length is shown as a single row.
+// Values of grouped rows are summed, user and database are replaced with '*' if they differ, queryid of the first
+// row is used.
+func groupStatements(res PGresult, v view.View) (PGresult, error) {
+	if res.Ncols < 4 {
+		return res, nil
+	}
+
+	var (
+		querycol = res.Ncols - 1
+		groups   = map[string]int{}
+		values   = make([][]sql.NullString, 0, res.Nrows)
+	)
+
+	for _, row := range res.Values {
+		key := NormalizeQuery(row[querycol].String)
+
+		i, ok := groups[key]
+		if !ok {
+			grouped := make([]sql.NullString, res.Ncols)
+			copy(grouped, row)
+			grouped[querycol].String = key
+			groups[key] = len(values)
+			values = append(values, grouped)
+			continue
+		}
+
+		grouped := values[i]
+		for l := 0; l < querycol; l++ {
+			switch {
+			case l == v.UniqueKey:
+				continue // keep queryid of the first row
+			case l < 2:
+				// user and database
+				if grouped[l].String != row[l].String {
+					grouped[l] = sql.NullString{String: "*", Valid: true}
+				}
+			default:
+				sum, err := sumValues(grouped[l].String, row[l].String)
+				if err != nil {
+					return res, fmt.Errorf("group statements failed, column %d: %s", l, err)
+				}
+				grouped[l].String = sum
+				grouped[l].Valid = grouped[l].Valid || row[l].Valid
+			}
+		}
+	}
+
+	res.Values = values
+	res.Nrows = len(values)
+	countRatios(&res)
+	return res, nil
+}
+
+// countRatios calculates ratio columns of pg_stat_statements views using current values of numerator and denominator
+// columns. Zero ratio is used when denominator is zero. Rows are left as-is if columns are not found.
+func countRatios(res *PGresult) {
+	idx := map[string]int{}
+	for i, name := range res.Cols {
+		idx[name] = i
+	}
+
+	for name, ratio := range statementsRatios {
+		r, ok1 := idx[name]
+		n, ok2 := idx[ratio.num]
+		d, ok3 := idx[ratio.den]
+		if !ok1 || !ok2 || !ok3 {
+			continue
+		}
+
+		for _, row := range res.Values {
+			num, err1 := strconv.ParseFloat(row[n].String, 64)
+			den, err2 := strconv.ParseFloat(row[d].String, 64)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+
+			var value float64
+			if den > 0 {
+				value = ratio.scale * num / den
+			}
+			row[r] = sql.NullString{String: strconv.FormatFloat(value, 'f', ratio.prec, 64), Valid: true}
+		}
+	}
+}
+
+// sumValues returns sum of two values which are integers, floats or intervals formatted by Postgres.
+// Empty values (NULLs) are ignored.
+func sumValues(a, b string) (string, error) {
+	if a == "" {
+		return b, nil
+	}
+	if b == "" {
+		return a, nil
+	}
+
+	if ai, err := strconv.ParseInt(a, 10, 64); err == nil {
+		if bi, err := strconv.ParseInt(b, 10, 64); err == nil {
+			return strconv.FormatInt(ai+bi, 10), nil
+		}
+	}
+
+	if af, err := strconv.ParseFloat(a, 64); err == nil {
+		if bf, err := strconv.ParseFloat(b, 64); err == nil {
+			return strconv.FormatFloat(af+bf, 'f', floatPrecision(a, b), 64), nil
+		}
+	}
+
+	as, aok := parseInterval(a)
+	bs, bok := parseInterval(b)
+	if aok && bok {
+		return formatInterval(as + bs), nil
+	}
+
+	return "", fmt.Errorf("unsupported values '%s' and '%s'", a, b)
+}
+
+// floatPrecision returns the largest number of decimal places of values, hence sum of values is not re-rounded.
+// Returns -1 (the smallest number of digits necessary) if any value is formatted in exponent notation.
+func floatPrecision(values ...string) int {
+	var prec int
+	for _, v := range values {
+		if strings.ContainsAny(v, "eE") {
+			return -1
+		}
+		if i := strings.IndexByte(v, '.'); i >= 0 && len(v)-i-1 > prec {
+			prec = len(v) - i - 1
+		}
+	}
+	return prec
+}
+
+// parseInterval returns number of seconds of interval formatted by Postgres.
+func parseInterval(s string) (int64, bool) {
+	m := reInterval.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, false
+	}
+
+	var seconds int64
+	for i, mult := range []int64{86400, 3600, 60, 1} {
+		if m[i+1] == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		seconds += v * mult
+	}
+
+	return seconds, true
+}
+
+// formatInterval formats number of seconds the same way as Postgres formats intervals.
+func formatInterval(seconds int64) string {
+	days := seconds / 86400
+	seconds %= 86400
+	hms := fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+
+	switch days {
+	case 0:
+		return hms
+	case 1:
+		return "1 day " + hms
+	default:
+		return fmt.Sprintf("%d days %s", days, hms)
+	}
+}
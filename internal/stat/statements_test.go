@@ -0,0 +1,145 @@
+package stat
+
+import (
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want string
+	}{
+		{in: "SELECT * FROM t WHERE id = $1", want: "SELECT * FROM t WHERE id = $1"},
+		{in: "SELECT * FROM t WHERE id IN ($1, $2, $3) AND x = $4", want: "SELECT * FROM t WHERE id IN (...) AND x = $1"},
+		{in: "SELECT * FROM t WHERE id in ($1) AND x = $2", want: "SELECT * FROM t WHERE id IN (...) AND x = $1"},
+		{in: "SELECT * FROM t WHERE id = ANY(ARRAY[$1,$2]) AND x = $3", want: "SELECT * FROM t WHERE id = ANY(ARRAY[...]) AND x = $1"},
+		{in: "INSERT INTO t (a, b) VALUES ($1, $2), ($3, $4) RETURNING id", want: "INSERT INTO t (a, b) VALUES (...) RETURNING id"},
+		{in: "SELECT * FROM t WHERE id IN (SELECT id FROM t2 WHERE x = $1)", want: "SELECT * FROM t WHERE id IN (SELECT id FROM t2 WHERE x = $1)"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, NormalizeQuery(tc.in))
+	}
+}
+
+func Test_groupStatements(t *testing.T) {
+	v := view.New()["statements_timings"]
+	v.Group = true
+
+	row := func(values ...string) []sql.NullString {
+		r := make([]sql.NullString, len(values))
+		for i := range values {
+			r[i] = sql.NullString{String: values[i], Valid: true}
+		}
+		return r
+	}
+
+	res := PGresult{
+		Valid: true, Ncols: 13, Nrows: 3,
+		Cols: []string{"user", "database", "t_all_t", "t_read_t", "t_write_t", "t_cpu_t", "all_t", "read_t", "write_t", "cpu_t", "calls", "queryid", "query"},
+		Values: [][]sql.NullString{
+			row("alice", "db1", "23:00:00", "00:00:01", "00:00:00", "00:00:10", "10", "1.50", "0", "8", "5", "aaa", "SELECT * FROM t WHERE id IN ($1, $2)"),
+			row("bob", "db1", "02:00:00", "00:00:02", "00:00:00", "00:00:20", "20", "2.50", "0", "16", "7", "bbb", "SELECT * FROM t WHERE id IN ($1, $2, $3)"),
+			row("alice", "db2", "00:00:05", "00:00:00", "00:00:00", "00:00:05", "5", "0", "0", "5", "1", "ccc", "SELECT 1"),
+		},
+	}
+
+	got, err := groupStatements(res, v)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, got.Nrows)
+	assert.Equal(t, row("*", "db1", "1 day 01:00:00", "00:00:03", "00:00:00", "00:00:30", "30", "4.00", "0", "24", "12", "aaa", "SELECT * FROM t WHERE id IN (...)"), got.Values[0])
+	assert.Equal(t, res.Values[2], got.Values[1])
+
+	// source rows are not changed
+	assert.Equal(t, "alice", res.Values[0][0].String)
+
+	// rows of other views are not grouped
+	got, err = group(res, view.New()["activity"])
+	assert.NoError(t, err)
+	assert.Equal(t, res, got)
+}
+
+func Test_countRatios(t *testing.T) {
+	row := func(values ...string) []sql.NullString {
+		r := make([]sql.NullString, len(values))
+		for i := range values {
+			r[i] = sql.NullString{String: values[i], Valid: true}
+		}
+		return r
+	}
+
+	res := PGresult{
+		Valid: true, Ncols: 5, Nrows: 3,
+		Cols: []string{"user", "jit_%", "all_t", "jit_t", "query"},
+		Values: [][]sql.NullString{
+			row("alice", "1.00", "200", "50", "SELECT 1"),
+			row("bob", "1.00", "0", "0", "SELECT 2"),
+			row("carol", "1.00", "invalid", "0", "SELECT 3"),
+		},
+	}
+
+	countRatios(&res)
+	assert.Equal(t, "25.00", res.Values[0][1].String)
+	assert.Equal(t, "0.00", res.Values[1][1].String)
+	assert.Equal(t, "1.00", res.Values[2][1].String)
+
+	// Ratios of grouped rows are calculated using summed values.
+	v := view.New()["statements_jit"]
+	v.Group = true
+	res = PGresult{
+		Valid: true, Ncols: 16, Nrows: 2,
+		Cols: []string{"user", "database", "t_all_t", "t_jit_t", "jit_%", "all_t", "jit_t", "gen_t", "inl_t", "opt_t", "emit_t", "deform_t", "jit_funcs", "calls", "queryid", "query"},
+		Values: [][]sql.NullString{
+			row("alice", "db1", "00:00:10", "00:00:01", "10.00", "100", "10", "5", "0", "0", "5", "0", "4", "10", "aaa", "SELECT * FROM t WHERE id = $1"),
+			row("bob", "db1", "00:00:30", "00:00:09", "30.00", "300", "90", "45", "0", "0", "45", "0", "4", "10", "bbb", "SELECT * FROM t WHERE id = $1"),
+		},
+	}
+	got, err := groupStatements(res, v)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got.Nrows)
+	assert.Equal(t, "25.00", got.Values[0][4].String)
+	assert.Equal(t, "400", got.Values[0][5].String)
+
+	// Latencies are averages in milliseconds.
+	res = PGresult{
+		Valid: true, Ncols: 7, Nrows: 1,
+		Cols: []string{"read_lat", "write_lat", "read_t", "write_t", "read_blks", "write_blks", "query"},
+		Values: [][]sql.NullString{
+			row("0.100", "0.200", "12.50", "3.00", "500.00", "0.00", "SELECT 1"),
+		},
+	}
+	countRatios(&res)
+	assert.Equal(t, "0.025", res.Values[0][0].String)
+	assert.Equal(t, "0.000", res.Values[0][1].String)
+}
+
+func Test_sumValues(t *testing.T) {
+	testcases := []struct {
+		a, b  string
+		want  string
+		valid bool
+	}{
+		{a: "1", b: "2", want: "3", valid: true},
+		{a: "1", b: "2.5", want: "3.5", valid: true},
+		{a: "0.1", b: "0.2", want: "0.3", valid: true},
+		{a: "1.125", b: "2.5", want: "3.625", valid: true},
+		{a: "1.2345678", b: "0.0000001", want: "1.2345679", valid: true},
+		{a: "1e+06", b: "0.5", want: "1000000.5", valid: true},
+		{a: "", b: "2", want: "2", valid: true},
+		{a: "01:00:00", b: "2 days 23:30:00", want: "3 days 00:30:00", valid: true},
+		{a: "abc", b: "1", valid: false},
+	}
+
+	for _, tc := range testcases {
+		got, err := sumValues(tc.a, tc.b)
+		if tc.valid {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package stat
+
+import (
+	"context"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// reWalSegment matches names of WAL segments files.
+var reWalSegment = regexp.MustCompile(`^[0-9A-F]{24}$`)
+
+// reSettingUnit matches units of memory settings, e.g. 'kB', '8kB' or '16MB'.
+var reSettingUnit = regexp.MustCompile(`^(\d*)(B|kB|MB|GB|TB)$`)
+
+// DirUsage describes usage of Postgres directory.
+type DirUsage struct {
+	Name  string  // name of the directory, e.g. pg_wal
+	Path  string  // location of the directory
+	Size  int64   // total size of files in bytes
+	Files int     // number of files, for WAL directory it is the number of WAL segments
+	Limit int64   // size which directory is not expected to exceed, zero if unknown
+	Rate  float64 // growth of size in bytes per second, negative if directory has shrunk
+}
+
+// Storage defines usage of Postgres directories.
+type Storage []DirUsage
+
+// storageDirs defines locations of Postgres directories. Locations are resolved using Postgres connection, but usage of
+// directories is read from local filesystem.
+type storageDirs struct {
+	data       string // data directory
+	wal        string // name of WAL directory inside data directory
+	log        string // log directory, empty if unknown
+	maxWalSize int64  // value of max_wal_size in bytes, zero if unknown
+	bin        string // directory of Postgres executables, empty if unknown
+	err        error  // error occurred during resolving locations
+}
+
+// resolveStorageDirs returns locations of Postgres directories. Reading data_directory setting requires superuser or
+// pg_read_all_settings role, in this case working directory of the backend process is used, it is the data directory.
+func resolveStorageDirs(ctx context.Context, db *postgres.DB, version int) storageDirs {
+	var dirs storageDirs
+
+	var pid int
+	pidErr := db.QueryRowContext(ctx, query.GetBackendPid).Scan(&pid)
+
+	// Executable of the backend is the postgres binary, other executables (e.g. pg_controldata) are placed near it.
+	if pidErr == nil {
+		if exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid)); err == nil {
+			dirs.bin = filepath.Dir(exe)
+		}
+	}
+
+	if err := db.QueryRowContext(ctx, query.GetSetting, "data_directory").Scan(&dirs.data); err != nil {
+		if pidErr != nil {
+			dirs.err = fmt.Errorf("get data directory failed: %s", pidErr)
+			return dirs
+		}
+
+		dirs.data, err = os.Readlink(fmt.Sprintf("/proc/%d/cwd", pid))
+		if err != nil {
+			dirs.err = fmt.Errorf("get data directory failed: %s", err)
+			return dirs
+		}
+	}
+
+	dirs.wal = "pg_wal"
+	if version < 100000 {
+		dirs.wal = "pg_xlog"
+	}
+
+	var setting, unit string
+	if err := db.QueryRowContext(ctx, query.GetMaxWalSize).Scan(&setting, &unit); err == nil {
+		dirs.maxWalSize, _ = parseSettingBytes(setting, unit)
+	}
+
+	var logdir string
+	if err := db.QueryRowContext(ctx, query.GetSetting, "log_directory").Scan(&logdir); err == nil && logdir != "" {
+		if !filepath.IsAbs(logdir) {
+			logdir = filepath.Join(dirs.data, logdir)
+		}
+		dirs.log = logdir
+	}
+
+	return dirs
+}
+
+// readStorage reads usage of Postgres directories from local filesystem: WAL directory, directories of temporary
+// files in default and other tablespaces, and log directory.
+func readStorage(ctx context.Context, dirs storageDirs) (Storage, error) {
+	if dirs.err != nil {
+		return nil, dirs.err
+	}
+
+	tmp := []string{filepath.Join(dirs.data, "base", "pgsql_tmp")}
+	tblspc, err := filepath.Glob(filepath.Join(dirs.data, "pg_tblspc", "*", "PG_*", "pgsql_tmp"))
+	if err != nil {
+		return nil, err
+	}
+	tmp = append(tmp, tblspc...)
+
+	type storageItem struct {
+		name  string   // name of the directory
+		paths []string // locations of the directory, usage of all locations is summed
+		limit int64    // size which directory is not expected to exceed
+	}
+
+	items := []storageItem{
+		{name: dirs.wal, paths: []string{filepath.Join(dirs.data, dirs.wal)}, limit: dirs.maxWalSize},
+		{name: "pgsql_tmp", paths: tmp},
+	}
+	if dirs.log != "" {
+		items = append(items, storageItem{name: "log", paths: []string{dirs.log}})
+	}
+
+	stats := make(Storage, 0, len(items))
+	for _, item := range items {
+		u := DirUsage{Name: item.name, Path: item.paths[0], Limit: item.limit}
+
+		for _, path := range item.paths {
+			size, files, err := dirUsage(ctx, path, item.name == dirs.wal)
+			if err != nil {
+				return nil, err
+			}
+			u.Size += size
+			u.Files += files
+		}
+
+		stats = append(stats, u)
+	}
+
+	return stats, nil
+}
+
+// dirUsage returns total size and number of files in directory and its subdirectories. If segments is true, only WAL
+// segments are counted as files. Not existing directory is considered empty, e.g. pgsql_tmp is created on demand.
+func dirUsage(ctx context.Context, path string, segments bool) (int64, int, error) {
+	var (
+		size  int64
+		files int
+	)
+
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Files might be removed during walking, e.g. recycled WAL segments or temporary files.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		size += info.Size()
+		if !segments || reWalSegment.MatchString(info.Name()) {
+			files++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("read directory usage failed: %s", err)
+	}
+
+	return size, files, nil
+}
+
+// countStorageRates calculates growth rates of directories since the previous snapshot.
+func countStorageRates(prev, curr Storage, seconds float64) Storage {
+	if seconds <= 0 {
+		return curr
+	}
+
+	prevSizes := make(map[string]int64, len(prev))
+	for _, u := range prev {
+		prevSizes[u.Path] = u.Size
+	}
+
+	for i := range curr {
+		if size, ok := prevSizes[curr[i].Path]; ok {
+			curr[i].Rate = float64(curr[i].Size-size) / seconds
+		}
+	}
+
+	return curr
+}
+
+// parseSettingBytes returns value of memory setting in bytes, e.g. 64 with unit '16MB' is 1GB. False is returned if
+// value or unit could not be parsed.
+func parseSettingBytes(setting string, unit string) (int64, bool) {
+	value, err := strconv.ParseInt(setting, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	m := reSettingUnit.FindStringSubmatch(unit)
+	if m == nil {
+		return 0, false
+	}
+
+	mult := int64(1)
+	if m[1] != "" {
+		mult, err = strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+	}
+
+	switch m[2] {
+	case "kB":
+		mult *= 1024
+	case "MB":
+		mult *= 1024 * 1024
+	case "GB":
+		mult *= 1024 * 1024 * 1024
+	case "TB":
+		mult *= 1024 * 1024 * 1024 * 1024
+	}
+
+	return value * mult, true
+}
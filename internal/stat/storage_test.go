@@ -0,0 +1,88 @@
+package stat
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_readStorage(t *testing.T) {
+	data, err := ioutil.TempDir("", "pgcenter-storage-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(data) }()
+
+	write := func(path string, size int) {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		assert.NoError(t, ioutil.WriteFile(path, make([]byte, size), 0600))
+	}
+
+	write(filepath.Join(data, "pg_wal", "000000010000000000000001"), 1024)
+	write(filepath.Join(data, "pg_wal", "000000010000000000000002"), 1024)
+	write(filepath.Join(data, "pg_wal", "archive_status", "000000010000000000000001.done"), 0)
+	write(filepath.Join(data, "base", "pgsql_tmp", "pgsql_tmp1234.0"), 512)
+	write(filepath.Join(data, "pg_tblspc", "16384", "PG_13_202007201", "pgsql_tmp", "pgsql_tmp1234.1"), 256)
+	write(filepath.Join(data, "log", "postgresql.log"), 100)
+
+	dirs := storageDirs{data: data, wal: "pg_wal", log: filepath.Join(data, "log"), maxWalSize: 4096}
+
+	got, err := readStorage(context.Background(), dirs)
+	assert.NoError(t, err)
+	assert.Equal(t, Storage{
+		{Name: "pg_wal", Path: filepath.Join(data, "pg_wal"), Size: 2048, Files: 2, Limit: 4096},
+		{Name: "pgsql_tmp", Path: filepath.Join(data, "base", "pgsql_tmp"), Size: 768, Files: 2},
+		{Name: "log", Path: filepath.Join(data, "log"), Size: 100, Files: 1},
+	}, got)
+
+	// Not existing directories are considered empty.
+	got, err = readStorage(context.Background(), storageDirs{data: filepath.Join(data, "unknown"), wal: "pg_xlog"})
+	assert.NoError(t, err)
+	assert.Equal(t, Storage{
+		{Name: "pg_xlog", Path: filepath.Join(data, "unknown", "pg_xlog")},
+		{Name: "pgsql_tmp", Path: filepath.Join(data, "unknown", "base", "pgsql_tmp")},
+	}, got)
+
+	// Error occurred during resolving directories.
+	_, err = readStorage(context.Background(), storageDirs{err: os.ErrPermission})
+	assert.Error(t, err)
+}
+
+func Test_countStorageRates(t *testing.T) {
+	prev := Storage{{Path: "/wal", Size: 1000}, {Path: "/tmp", Size: 500}}
+	curr := Storage{{Path: "/wal", Size: 3000}, {Path: "/tmp", Size: 0}, {Path: "/log", Size: 100}}
+
+	got := countStorageRates(prev, curr, 2)
+	assert.Equal(t, Storage{
+		{Path: "/wal", Size: 3000, Rate: 1000},
+		{Path: "/tmp", Size: 0, Rate: -250},
+		{Path: "/log", Size: 100},
+	}, got)
+
+	// Zero interval, rates are not counted.
+	got = countStorageRates(prev, Storage{{Path: "/wal", Size: 3000}}, 0)
+	assert.Equal(t, Storage{{Path: "/wal", Size: 3000}}, got)
+}
+
+func Test_parseSettingBytes(t *testing.T) {
+	testcases := []struct {
+		setting string
+		unit    string
+		want    int64
+		valid   bool
+	}{
+		{setting: "1024", unit: "MB", want: 1 << 30, valid: true},
+		{setting: "64", unit: "16MB", want: 1 << 30, valid: true},
+		{setting: "131072", unit: "8kB", want: 1 << 30, valid: true},
+		{setting: "100", unit: "B", want: 100, valid: true},
+		{setting: "invalid", unit: "MB"},
+		{setting: "100", unit: "ms"},
+	}
+
+	for _, tc := range testcases {
+		got, ok := parseSettingBytes(tc.setting, tc.unit)
+		assert.Equal(t, tc.valid, ok)
+		assert.Equal(t, tc.want, got)
+	}
+}
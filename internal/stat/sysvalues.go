@@ -0,0 +1,89 @@
+package stat
+
+// Kinds of system stats exposed to external systems, e.g. exported as Prometheus metrics or pushed to external storages.
+// Kinds are used as prefixes of names of metrics, hence metrics of different outputs are named consistently.
+const (
+	SystemLoad    = "system_load"
+	SystemCPU     = "system_cpu"
+	SystemMemory  = "system_memory"
+	SystemDisk    = "system_disk"
+	SystemNetwork = "system_network"
+)
+
+// Names of labels of devices in system stats exposed to external systems.
+const (
+	SystemDiskLabel    = "device"
+	SystemNetworkLabel = "interface"
+)
+
+// SystemValue defines a single value of system stats exposed to external systems.
+type SystemValue struct {
+	Name  string // name of the value, unique within its kind of stats
+	Help  string // description of the value
+	Value float64
+}
+
+// LoadValues returns values of load average.
+func LoadValues(l LoadAvg) []SystemValue {
+	return []SystemValue{
+		{"load1", "System load average over 1 minute.", l.One},
+		{"load5", "System load average over 5 minutes.", l.Five},
+		{"load15", "System load average over 15 minutes.", l.Fifteen},
+	}
+}
+
+// CPUValues returns usage of CPU by modes, in percents.
+func CPUValues(c CpuStat) []SystemValue {
+	return []SystemValue{
+		{"user", "CPU time spent in user mode, in percents.", c.User},
+		{"nice", "CPU time spent in user mode with low priority, in percents.", c.Nice},
+		{"system", "CPU time spent in system mode, in percents.", c.Sys},
+		{"idle", "CPU time spent in idle, in percents.", c.Idle},
+		{"iowait", "CPU time spent waiting for I/O, in percents.", c.Iowait},
+		{"irq", "CPU time spent servicing interrupts, in percents.", c.Irq},
+		{"softirq", "CPU time spent servicing softirqs, in percents.", c.Softirq},
+		{"steal", "CPU time stolen by other virtual machines, in percents.", c.Steal},
+	}
+}
+
+// MemoryValues returns usage of memory and swap by types, in megabytes.
+func MemoryValues(m Meminfo) []SystemValue {
+	return []SystemValue{
+		{"mem_total", "Total memory, in megabytes.", float64(m.MemTotal)},
+		{"mem_free", "Free memory, in megabytes.", float64(m.MemFree)},
+		{"mem_used", "Used memory, in megabytes.", float64(m.MemUsed)},
+		{"mem_cached", "Memory used by page cache, in megabytes.", float64(m.MemCached)},
+		{"mem_buffers", "Memory used by buffers, in megabytes.", float64(m.MemBuffers)},
+		{"mem_dirty", "Memory waiting to be written back to disks, in megabytes.", float64(m.MemDirty)},
+		{"mem_writeback", "Memory being written back to disks, in megabytes.", float64(m.MemWriteback)},
+		{"mem_slab", "Memory used by kernel data structures, in megabytes.", float64(m.MemSlab)},
+		{"swap_total", "Total swap, in megabytes.", float64(m.SwapTotal)},
+		{"swap_free", "Free swap, in megabytes.", float64(m.SwapFree)},
+		{"swap_used", "Used swap, in megabytes.", float64(m.SwapUsed)},
+	}
+}
+
+// DiskValues returns usage of the block device.
+func DiskValues(d Diskstat) []SystemValue {
+	return []SystemValue{
+		{"reads_per_second", "Read requests completed per second.", d.Rcompleted},
+		{"writes_per_second", "Write requests completed per second.", d.Wcompleted},
+		{"read_megabytes_per_second", "Megabytes read per second.", d.Rsectors},
+		{"written_megabytes_per_second", "Megabytes written per second.", d.Wsectors},
+		{"await_milliseconds", "Average time of serving I/O requests, in milliseconds.", d.Await},
+		{"queue_size", "Average queue length of I/O requests.", d.Tweighted},
+		{"utilization_percent", "Percentage of time during which I/O requests were issued to the device.", d.Util},
+	}
+}
+
+// NetworkValues returns usage of the network interface.
+func NetworkValues(n Netdev) []SystemValue {
+	return []SystemValue{
+		{"received_bytes_per_second", "Bytes received per second.", n.Rbytes},
+		{"transmitted_bytes_per_second", "Bytes transmitted per second.", n.Tbytes},
+		{"received_packets_per_second", "Packets received per second.", n.Rpackets},
+		{"transmitted_packets_per_second", "Packets transmitted per second.", n.Tpackets},
+		{"errors_per_second", "Receive and transmit errors per second.", n.Rerrs + n.Terrs},
+		{"utilization_percent", "Utilization of the interface, in percents.", n.Utilization},
+	}
+}
@@ -0,0 +1,26 @@
+package stat
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSystemValues(t *testing.T) {
+	assert.Equal(t, SystemValue{"load5", "System load average over 5 minutes.", 0.5}, LoadValues(LoadAvg{Five: 0.5})[1])
+	assert.Equal(t, "steal", CPUValues(CpuStat{Steal: 1})[7].Name)
+	assert.Equal(t, float64(512), MemoryValues(Meminfo{MemUsed: 512})[2].Value)
+	assert.Equal(t, float64(10), DiskValues(Diskstat{Util: 10})[6].Value)
+	assert.Equal(t, float64(3), NetworkValues(Netdev{Rerrs: 1, Terrs: 2})[4].Value)
+
+	// Names are used as names of metrics, hence they must be unique within kind of stats.
+	for _, values := range [][]SystemValue{
+		LoadValues(LoadAvg{}), CPUValues(CpuStat{}), MemoryValues(Meminfo{}), DiskValues(Diskstat{}), NetworkValues(Netdev{}),
+	} {
+		names := map[string]bool{}
+		for _, v := range values {
+			assert.False(t, names[v.Name], v.Name)
+			assert.NotEmpty(t, v.Help)
+			names[v.Name] = true
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package view
 
 import (
+	"fmt"
 	"github.com/lesovsky/pgcenter/internal/query"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -11,7 +13,9 @@ type View struct {
 	Name      string                 // View name
 	QueryTmpl string                 // Query template used for making particular query.
 	Query     string                 // Query based on template and runtime options.
+	Args      []interface{}          // Arguments of query parameters, e.g. lists of filtered databases and users.
 	DiffIntvl [2]int                 // Columns interval for diff
+	Gauges    []string               // Names of diffed columns with gauges (e.g. sizes), their decrease is not a reset of counters
 	Cols      []string               // Columns names
 	Ncols     int                    // Number of columns returned by query, used as a right border for OrderKey
 	OrderKey  int                    // Index of column used for order
@@ -23,8 +27,103 @@ type View struct {
 	Filters   map[int]*regexp.Regexp // Filter patterns: key is the column index, value - regexp pattern
 	Refresh   time.Duration          // Number of seconds between update view.
 	ShowExtra int                    // Specifies extra stats should be enabled on the view.
+	Plugin    *Plugin                // External command used instead of query, nil for views based on queries.
+	Limit     int                    // Maximum number of rows read from Postgres, zero means all rows are read.
+	Group     bool                   // Rows of pg_stat_statements views are grouped by normalized text of statements.
+	Window    time.Duration          // Period of sampled sessions shown by active sessions history view.
 }
 
+// Plugin describes external command which prints stats in JSON, it is used by views declared in configuration file.
+type Plugin struct {
+	Command []string      // Command and its arguments.
+	Timeout time.Duration // Maximum duration of command execution.
+	Columns []string      // Names of columns, values of rows are taken in this order.
+}
+
+// LimitedQuery returns query which reads only Limit rows ordered by the view's order key, ties are ordered by the view's
+// unique key. Rows ordered by diffed values (e.g. rates) or grouped rows could not be limited by Postgres, all rows are
+// read in this case: top rows by cumulative counters are not top rows by their rates.
+func (v View) LimitedQuery() string {
+	if v.Limit <= 0 || v.Plugin != nil || v.Group {
+		return v.Query
+	}
+
+	if v.DiffIntvl != [2]int{0, 0} && v.OrderKey >= v.DiffIntvl[0] && v.OrderKey <= v.DiffIntvl[1] {
+		return v.Query
+	}
+
+	return query.Limit(v.Query, v.OrderKey, v.OrderDesc, v.UniqueKey, v.Limit)
+}
+
+// IsStatements returns true if the view shows pg_stat_statements stats.
+func (v View) IsStatements() bool {
+	return strings.HasPrefix(v.Name, "statements_")
+}
+
+// ActivityGrouped is the name of activity view which rows are grouped by fingerprints of queries.
+const ActivityGrouped = "activity_grouped"
+
+// GroupActivity returns view based on activity view, which rows are grouped by fingerprints of queries. Grouped rows
+// have their own columns: number of backends, database, user, state, minimal and maximal age of queries and query.
+func GroupActivity(activity View) View {
+	return View{
+		Name:      ActivityGrouped,
+		QueryTmpl: activity.QueryTmpl,
+		Query:     activity.Query,
+		Args:      activity.Args,
+		DiffIntvl: [2]int{0, 0},
+		Ncols:     7,
+		OrderKey:  0,
+		OrderDesc: true,
+		ColsWidth: map[int]int{},
+		Msg:       "Show activity grouped by queries",
+		Filters:   map[int]*regexp.Regexp{},
+		Group:     true,
+	}
+}
+
+// ASH is the name of active sessions history view, which rows are built from sessions sampled by collector.
+const ASH = "ash"
+
+// SessionsHistory returns view of active sessions sampled within specified window and grouped by wait events, users,
+// databases and queries. The view has no query, rows are built by collector which samples sessions.
+func SessionsHistory(window time.Duration) View {
+	return View{
+		Name:      ASH,
+		DiffIntvl: [2]int{0, 0},
+		Ncols:     7,
+		OrderKey:  0,
+		OrderDesc: true,
+		ColsWidth: map[int]int{},
+		Msg:       fmt.Sprintf("Show active sessions history for the last %d min", int(window.Minutes())),
+		Filters:   map[int]*regexp.Regexp{},
+		Window:    window,
+	}
+}
+
+// DatadirHealth is the name of data directory health view, which rows are results of inspecting data directory of local
+// Postgres.
+const DatadirHealth = "datadir"
+
+// DatadirHealthView returns view of data directory health checks. The view has no query, rows are built by collector
+// which inspects data directory.
+func DatadirHealthView() View {
+	return View{
+		Name:      DatadirHealth,
+		DiffIntvl: [2]int{0, 0},
+		Ncols:     3,
+		OrderKey:  0,
+		OrderDesc: false,
+		ColsWidth: map[int]int{},
+		Msg:       "Show data directory health",
+		Filters:   map[int]*regexp.Regexp{},
+	}
+}
+
+// IndexAdvisor is the name of index advisor view, which ranks tables by rate of sequential scans multiplied by average
+// number of rows read by a scan. Score and advice are calculated by collector after diff.
+const IndexAdvisor = "index_advisor"
+
 // Views is a list of all used context units.
 type Views map[string]View
 
@@ -53,11 +152,22 @@ func New() Views {
 			Msg:       "Show replication statistics",
 			Filters:   map[int]*regexp.Regexp{},
 		},
+		"replication_slots": {
+			Name:      "replication_slots",
+			QueryTmpl: query.PgReplicationSlotsDefault,
+			DiffIntvl: [2]int{6, 10},
+			Ncols:     11,
+			OrderKey:  0,
+			OrderDesc: true,
+			ColsWidth: map[int]int{},
+			Msg:       "Show replication slots statistics",
+			Filters:   map[int]*regexp.Regexp{},
+		},
 		"databases": {
 			Name:      "databases",
 			QueryTmpl: query.PgStatDatabaseDefault,
 			DiffIntvl: [2]int{1, 16},
-			Ncols:     18,
+			Ncols:     25,
 			OrderKey:  0,
 			OrderDesc: true,
 			ColsWidth: map[int]int{},
@@ -68,6 +178,7 @@ func New() Views {
 			Name:      "tables",
 			QueryTmpl: query.PgStatTablesDefault,
 			DiffIntvl: [2]int{1, 18},
+			Gauges:    []string{"live", "dead"},
 			Ncols:     19,
 			OrderKey:  0,
 			OrderDesc: true,
@@ -75,6 +186,17 @@ func New() Views {
 			Msg:       "Show tables statistics",
 			Filters:   map[int]*regexp.Regexp{},
 		},
+		"tables_io": {
+			Name:      "tables_io",
+			QueryTmpl: query.PgStatTablesIODefault,
+			DiffIntvl: [2]int{1, 9},
+			Ncols:     13,
+			OrderKey:  1,
+			OrderDesc: true,
+			ColsWidth: map[int]int{},
+			Msg:       "Show tables IO statistics",
+			Filters:   map[int]*regexp.Regexp{},
+		},
 		"indexes": {
 			Name:      "indexes",
 			QueryTmpl: query.PgStatIndexesDefault,
@@ -90,6 +212,7 @@ func New() Views {
 			Name:      "sizes",
 			QueryTmpl: query.PgTablesSizesDefault,
 			DiffIntvl: [2]int{4, 6},
+			Gauges:    []string{"total_change", "rel_change", "idx_change"},
 			Ncols:     7,
 			OrderKey:  0,
 			OrderDesc: true,
@@ -97,6 +220,39 @@ func New() Views {
 			Msg:       "Show tables sizes statistics",
 			Filters:   map[int]*regexp.Regexp{},
 		},
+		IndexAdvisor: {
+			Name:      IndexAdvisor,
+			QueryTmpl: query.PgIndexAdvisorDefault,
+			DiffIntvl: [2]int{3, 4},
+			Ncols:     7,
+			OrderKey:  4,
+			OrderDesc: true,
+			ColsWidth: map[int]int{},
+			Msg:       "Show index advisor, tables with hot sequential scans",
+			Filters:   map[int]*regexp.Regexp{},
+		},
+		"stale_stats": {
+			Name:      "stale_stats",
+			QueryTmpl: query.PgStaleStatsDefault,
+			DiffIntvl: [2]int{0, 0},
+			Ncols:     8,
+			OrderKey:  4,
+			OrderDesc: true,
+			ColsWidth: map[int]int{},
+			Msg:       "Show tables with stale planner statistics",
+			Filters:   map[int]*regexp.Regexp{},
+		},
+		"fdw": {
+			Name:      "fdw",
+			QueryTmpl: query.PgForeignServersNoConns,
+			DiffIntvl: [2]int{0, 0},
+			Ncols:     9,
+			OrderKey:  0,
+			OrderDesc: true,
+			ColsWidth: map[int]int{},
+			Msg:       "Show foreign servers statistics",
+			Filters:   map[int]*regexp.Regexp{},
+		},
 		"functions": {
 			Name:      "functions",
 			QueryTmpl: query.PgStatFunctionsDefault,
@@ -108,6 +264,28 @@ func New() Views {
 			Msg:       "Show functions statistics",
 			Filters:   map[int]*regexp.Regexp{},
 		},
+		"roles": {
+			Name:      "roles",
+			QueryTmpl: query.PgRolesDefault,
+			DiffIntvl: [2]int{0, 0},
+			Ncols:     10,
+			OrderKey:  3,
+			OrderDesc: true,
+			ColsWidth: map[int]int{},
+			Msg:       "Show roles statistics",
+			Filters:   map[int]*regexp.Regexp{},
+		},
+		"checkpoints": {
+			Name:      "checkpoints",
+			QueryTmpl: query.PgStatCheckpointsDefault,
+			DiffIntvl: [2]int{0, 0},
+			Ncols:     9,
+			OrderKey:  0,
+			OrderDesc: true,
+			ColsWidth: map[int]int{},
+			Msg:       "Show checkpoints statistics",
+			Filters:   map[int]*regexp.Regexp{},
+		},
 		"statements_timings": {
 			Name:      "statements_timings",
 			QueryTmpl: query.PgStatStatementsTimingDefault,
@@ -168,11 +346,58 @@ func New() Views {
 			Msg:       "Show statements temp tables statistics (local IO)",
 			Filters:   map[int]*regexp.Regexp{},
 		},
+		"statements_parallel": {
+			Name:      "statements_parallel",
+			QueryTmpl: query.PgStatStatementsParallelDefault,
+			DiffIntvl: [2]int{4, 7},
+			Ncols:     10,
+			OrderKey:  0,
+			OrderDesc: true,
+			UniqueKey: 8,
+			ColsWidth: map[int]int{},
+			Msg:       "Show statements parallel workers statistics",
+			Filters:   map[int]*regexp.Regexp{},
+		},
+		"statements_jit": {
+			Name:      "statements_jit",
+			QueryTmpl: query.PgStatStatementsJitDefault,
+			DiffIntvl: [2]int{5, 13},
+			Ncols:     16,
+			OrderKey:  0,
+			OrderDesc: true,
+			UniqueKey: 14,
+			ColsWidth: map[int]int{},
+			Msg:       "Show statements JIT compilation statistics",
+			Filters:   map[int]*regexp.Regexp{},
+		},
+		"statements_latency": {
+			Name:      "statements_latency",
+			QueryTmpl: query.PgStatStatementsLatencyDefault,
+			DiffIntvl: [2]int{6, 10},
+			Ncols:     13,
+			OrderKey:  0,
+			OrderDesc: true,
+			UniqueKey: 11,
+			ColsWidth: map[int]int{},
+			Msg:       "Show statements IO latency statistics",
+			Filters:   map[int]*regexp.Regexp{},
+		},
+		"parallel": {
+			Name:      "parallel",
+			QueryTmpl: query.PgStatParallelDefault,
+			DiffIntvl: [2]int{0, 0},
+			Ncols:     9,
+			OrderKey:  0,
+			OrderDesc: true,
+			ColsWidth: map[int]int{},
+			Msg:       "Show parallel queries and their workers",
+			Filters:   map[int]*regexp.Regexp{},
+		},
 		"progress_vacuum": {
 			Name:      "progress_vacuum",
 			QueryTmpl: query.PgStatProgressVacuumDefault,
 			DiffIntvl: [2]int{10, 11},
-			Ncols:     13,
+			Ncols:     18,
 			OrderKey:  0,
 			OrderDesc: true,
 			ColsWidth: map[int]int{},
@@ -194,7 +419,7 @@ func New() Views {
 			Name:      "progress_index",
 			QueryTmpl: query.PgStatProgressCreateIndexDefault,
 			DiffIntvl: [2]int{0, 0},
-			Ncols:     14,
+			Ncols:     19,
 			OrderKey:  0,
 			OrderDesc: true,
 			ColsWidth: map[int]int{},
@@ -204,38 +429,20 @@ func New() Views {
 	}
 }
 
-// Configure performs adjusting of queries accordingly to Postgres version.
-//   IN opts Options: struct with additional Postgres properties required for formatting necessary queries
-//   IN gucTrackCommitTS string: value of track_commit_timestamp GUC (on/off)
+// Configure performs adjusting of queries accordingly to Postgres version and installed extensions, queries are taken
+// from the query registry. Views which are not supported by Postgres keep their default queries.
 func (v Views) Configure(opts query.Options) error {
-	var track bool
-	if opts.GucTrackCommitTS == "on" {
-		track = true
-	}
 	for k, view := range v {
-		switch k {
-		case "activity":
-			view.QueryTmpl, view.Ncols = query.SelectStatActivityQuery(opts.Version)
-			v[k] = view
-		case "replication":
-			view.QueryTmpl, view.Ncols = query.SelectStatReplicationQuery(opts.Version, track)
-			v[k] = view
-		case "databases":
-			view.QueryTmpl, view.Ncols, view.DiffIntvl = query.SelectStatDatabaseQuery(opts.Version)
-			v[k] = view
-		case "statements_timings":
-			view.QueryTmpl = query.SelectStatStatementsTimingQuery(opts.Version)
-			v[k] = view
+		if q, ok := query.Select(k, opts); ok {
+			view.QueryTmpl, view.Ncols, view.DiffIntvl = q.Query, q.Ncols, q.DiffIntvl
 		}
-	}
 
-	// Build query texts based on templates.
-	for k, view := range v {
-		q, err := query.Format(view.QueryTmpl, opts)
+		// Build query texts based on templates.
+		q, args, err := query.Format(view.QueryTmpl, opts)
 		if err != nil {
 			return err
 		}
-		view.Query = q
+		view.Query, view.Args = q, args
 		v[k] = view
 	}
 
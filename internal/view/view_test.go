@@ -4,11 +4,12 @@ import (
 	"github.com/lesovsky/pgcenter/internal/query"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
 	v := New()
-	assert.Equal(t, 15, len(v)) // 15 is the total number of views have to be returned
+	assert.Equal(t, 26, len(v)) // 26 is the total number of views have to be returned
 }
 
 func TestViews_Configure(t *testing.T) {
@@ -104,7 +105,7 @@ func TestViews_Configure(t *testing.T) {
 				assert.Equal(t, query.PgStatReplicationDefault, views["replication"].QueryTmpl)
 			}
 			assert.Equal(t, query.PgStatDatabasePG11, views["databases"].QueryTmpl)
-			assert.Equal(t, 17, views["databases"].Ncols)
+			assert.Equal(t, 20, views["databases"].Ncols)
 			assert.Equal(t, [2]int{1, 15}, views["databases"].DiffIntvl)
 		case 90600:
 			if tc.trackCommit == "on" {
@@ -126,3 +127,73 @@ func TestViews_Configure(t *testing.T) {
 		}
 	}
 }
+
+func TestNew_registry(t *testing.T) {
+	// Defaults of views should match queries for the latest Postgres registered in query registry.
+	opts := query.NewOptions(180000, "f", "off", 0)
+	opts.PgSSVersion = 112
+
+	for name, v := range New() {
+		q, ok := query.Select(name, opts)
+		assert.True(t, ok, name)
+		assert.Equal(t, q.Query, v.QueryTmpl, name)
+		assert.Equal(t, q.Ncols, v.Ncols, name)
+		assert.Equal(t, q.DiffIntvl, v.DiffIntvl, name)
+	}
+}
+
+func TestView_LimitedQuery(t *testing.T) {
+	testcases := []struct {
+		view View
+		want string
+	}{
+		{view: View{Query: "SELECT a, b, c FROM t", DiffIntvl: [2]int{1, 2}}, want: "SELECT a, b, c FROM t"},
+		{view: View{Query: "SELECT a, b, c FROM t", DiffIntvl: [2]int{1, 2}, OrderKey: 0, OrderDesc: true, Limit: 20}, want: "SELECT * FROM (SELECT a, b, c FROM t) AS l ORDER BY 1 DESC NULLS LAST LIMIT 20"},
+		{view: View{Query: "SELECT a, b, c FROM t", DiffIntvl: [2]int{1, 2}, OrderKey: 2, Limit: 20}, want: "SELECT a, b, c FROM t"},
+		{view: View{Query: "SELECT a, b, c FROM t", OrderKey: 2, Limit: 20}, want: "SELECT * FROM (SELECT a, b, c FROM t) AS l ORDER BY 3 ASC NULLS LAST, 1 LIMIT 20"},
+		{view: View{Query: "SELECT a, b, c FROM t", OrderKey: 2, UniqueKey: 1, Limit: 20}, want: "SELECT * FROM (SELECT a, b, c FROM t) AS l ORDER BY 3 ASC NULLS LAST, 2 LIMIT 20"},
+		{view: View{Query: "SELECT a, b, c FROM t", Limit: 20, Plugin: &Plugin{}}, want: "SELECT a, b, c FROM t"},
+		{view: View{Query: "SELECT a, b, c FROM t", Limit: 20, Group: true}, want: "SELECT a, b, c FROM t"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, tc.view.LimitedQuery())
+	}
+}
+
+func TestGroupActivity(t *testing.T) {
+	activity := New()["activity"]
+	activity.Query = "SELECT 1"
+	activity.OrderKey = 10
+
+	v := GroupActivity(activity)
+	assert.Equal(t, ActivityGrouped, v.Name)
+	assert.Equal(t, "SELECT 1", v.Query)
+	assert.Equal(t, 0, v.OrderKey)
+	assert.True(t, v.Group)
+	assert.Equal(t, "SELECT 1", v.LimitedQuery())
+}
+
+func TestSessionsHistory(t *testing.T) {
+	v := SessionsHistory(15 * time.Minute)
+	assert.Equal(t, ASH, v.Name)
+	assert.Equal(t, 15*time.Minute, v.Window)
+	assert.Equal(t, "Show active sessions history for the last 15 min", v.Msg)
+	assert.Equal(t, "", v.Query)
+}
+
+func TestDatadirHealthView(t *testing.T) {
+	v := DatadirHealthView()
+	assert.Equal(t, DatadirHealth, v.Name)
+	assert.Equal(t, 3, v.Ncols)
+	assert.False(t, v.OrderDesc)
+	assert.Equal(t, "", v.Query)
+}
+
+func TestView_IsStatements(t *testing.T) {
+	views := New()
+	assert.True(t, views["statements_timings"].IsStatements())
+	assert.True(t, views["statements_local"].IsStatements())
+	assert.False(t, views["activity"].IsStatements())
+	assert.False(t, views["progress_vacuum"].IsStatements())
+}
@@ -0,0 +1,208 @@
+// Profiling of multiple backends which match specified filters.
+
+package profile
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// backendSample describes wait event of a single active backend retrieved from pg_stat_activity view.
+type backendSample struct {
+	pid       int    // backend PID
+	user      string // name of the user logged into backend
+	database  string // name of the database backend is connected to
+	appname   string // name of the application connected to backend
+	waitEntry string // wait_event_type/wait_event
+	queryText string // query executed by backend
+}
+
+// filter defines criteria used for choosing backends to profile.
+type filter struct {
+	user     string
+	database string
+	appname  string
+	query    *regexp.Regexp
+}
+
+// newFilter creates filter from config.
+func newFilter(cfg Config) (filter, error) {
+	f := filter{user: cfg.User, database: cfg.Database, appname: cfg.AppName}
+
+	if cfg.QueryRegex != "" {
+		re, err := regexp.Compile(cfg.QueryRegex)
+		if err != nil {
+			return filter{}, fmt.Errorf("invalid query regex: %s", err)
+		}
+		f.query = re
+	}
+
+	return f, nil
+}
+
+// match returns true if sample satisfies filter.
+func (f filter) match(s backendSample) bool {
+	return f.query == nil || f.query.MatchString(s.queryText)
+}
+
+// String returns human-readable description of the filter.
+func (f filter) String() string {
+	var parts []string
+	if f.user != "" {
+		parts = append(parts, "user="+f.user)
+	}
+	if f.database != "" {
+		parts = append(parts, "database="+f.database)
+	}
+	if f.appname != "" {
+		parts = append(parts, "application_name="+f.appname)
+	}
+	if f.query != nil {
+		parts = append(parts, "query~"+f.query.String())
+	}
+
+	if len(parts) == 0 {
+		return "all backends"
+	}
+
+	return "backends with " + strings.Join(parts, ", ")
+}
+
+// profileBackendsLoop samples wait events of all backends matching the filter and prints aggregated profile at exit.
+func profileBackendsLoop(out io.Writer, conn *postgres.DB, cfg Config, cpu *cpuTracker, doQuit chan os.Signal) error {
+	f, err := newFilter(cfg)
+	if err != nil {
+		return err
+	}
+
+	w := textWriter(out, cfg)
+	sess := newSession(cfg.Strsize, cfg.PerStatement, cfg.GroupBy)
+
+	_, err = fmt.Fprintf(w, "LOG: Profiling %s with %s sampling\n", f, cfg.Frequency)
+	if err != nil {
+		return err
+	}
+
+	s := newStatsStore()
+	backends := map[int]struct{}{}
+	last := time.Now().Add(-cfg.Frequency)
+
+	smp, reason, err := sampleLoop(cfg, doQuit, func() (bool, string, error) {
+		curr, err := getBackendsSnapshot(conn, f)
+		if err != nil {
+			return false, "", err
+		}
+
+		// Every sampled backend spent the time passed since previous snapshot in its current wait event.
+		now := time.Now()
+		s = countBackendsWaitings(s, curr, now.Sub(last).Seconds())
+		last = now
+
+		events := make(map[int]string, len(curr))
+		for _, b := range curr {
+			backends[b.pid] = struct{}{}
+			sess.add(b.pid, b.queryText, b.waitEntry)
+			events[b.pid] = waitEntryName(b.waitEntry)
+
+			err := cpu.update(b.pid)
+			if err != nil {
+				return false, "", err
+			}
+		}
+		sess.observe(now, events)
+
+		return len(backends) > 0 && len(curr) == 0, "", nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = printBackendsStat(w, s, smp.samples, len(backends))
+	if err != nil {
+		return err
+	}
+
+	err = printCPUTimes(w, cpu)
+	if err != nil {
+		return err
+	}
+
+	return printSummary(out, w, cfg, sess, smp, reason)
+}
+
+// getBackendsSnapshot returns wait events of active backends which match the filter.
+func getBackendsSnapshot(conn *postgres.DB, f filter) ([]backendSample, error) {
+	query := "SELECT pid, coalesce(usename, ''), coalesce(datname, ''), coalesce(application_name, ''), " +
+		"coalesce(wait_event_type ||'.'|| wait_event, '') AS wait_entry, coalesce(query, '') " +
+		"FROM pg_stat_activity WHERE state = 'active' AND pid <> pg_backend_pid() " +
+		"AND ($1 = '' OR usename = $1) AND ($2 = '' OR datname = $2) AND ($3 = '' OR application_name = $3) " +
+		"/* pgcenter profile */"
+
+	rows, err := conn.Query(query, f.user, f.database, f.appname)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []backendSample
+	for rows.Next() {
+		var s backendSample
+		err := rows.Scan(&s.pid, &s.user, &s.database, &s.appname, &s.waitEntry, &s.queryText)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.match(s) {
+			samples = append(samples, s)
+		}
+	}
+
+	return samples, rows.Err()
+}
+
+// countBackendsWaitings adds time spent by sampled backends to durations of their wait events and recalculates
+// percent ratios accordingly to total time spent by all backends.
+func countBackendsWaitings(s stats, samples []backendSample, interval float64) stats {
+	for _, b := range samples {
+		if b.waitEntry == "" {
+			s.durations[runningEntry] += interval
+		} else {
+			s.durations[b.waitEntry] += interval
+		}
+	}
+
+	var total float64
+	for _, v := range s.durations {
+		total += v
+	}
+
+	for k, v := range s.durations {
+		s.ratios[k] = (100 * v) / total
+	}
+
+	return s
+}
+
+// printBackendsStat prints aggregated profile of all sampled backends.
+func printBackendsStat(w io.Writer, s stats, samples int, backends int) error {
+	_, err := fmt.Fprintf(w, "LOG: Collected %d samples of %d backends\n", samples, backends)
+	if err != nil {
+		return err
+	}
+
+	if len(s.durations) == 0 {
+		return nil
+	}
+
+	_, err = fmt.Fprintf(w, "------ ------------ -----------------------------\n%% time      seconds wait_event\n------ ------------ -----------------------------\n")
+	if err != nil {
+		return err
+	}
+
+	return printStat(w, s)
+}
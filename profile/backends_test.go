@@ -0,0 +1,81 @@
+package profile
+
+import (
+	"bytes"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_newFilter(t *testing.T) {
+	f, err := newFilter(Config{All: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "all backends", f.String())
+	assert.True(t, f.match(backendSample{queryText: "SELECT 1"}))
+
+	f, err = newFilter(Config{User: "postgres", Database: "pgbench", AppName: "psql", QueryRegex: "^UPDATE"})
+	assert.NoError(t, err)
+	assert.Equal(t, "backends with user=postgres, database=pgbench, application_name=psql, query~^UPDATE", f.String())
+	assert.True(t, f.match(backendSample{queryText: "UPDATE t SET v = 1"}))
+	assert.False(t, f.match(backendSample{queryText: "SELECT 1"}))
+
+	_, err = newFilter(Config{QueryRegex: "(invalid"})
+	assert.Error(t, err)
+}
+
+func Test_countBackendsWaitings(t *testing.T) {
+	s := newStatsStore()
+	s = countBackendsWaitings(s, []backendSample{{pid: 1}, {pid: 2, waitEntry: "IO.DataFileRead"}}, 0.1)
+	s = countBackendsWaitings(s, []backendSample{{pid: 1}, {pid: 2}}, 0.1)
+	s = countBackendsWaitings(s, nil, 0.1)
+
+	assert.InDelta(t, 0.3, s.durations["Running (on CPU)"], 0.000001)
+	assert.InDelta(t, 0.1, s.durations["IO.DataFileRead"], 0.000001)
+	assert.InDelta(t, 75, s.ratios["Running (on CPU)"], 0.000001)
+	assert.InDelta(t, 25, s.ratios["IO.DataFileRead"], 0.000001)
+}
+
+func Test_printBackendsStat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, printBackendsStat(buf, newStatsStore(), 10, 0))
+	assert.Equal(t, "LOG: Collected 10 samples of 0 backends\n", buf.String())
+
+	s := newStatsStore()
+	s = countBackendsWaitings(s, []backendSample{{pid: 1}, {pid: 2, waitEntry: "Lock.transactionid"}}, 0.5)
+
+	buf.Reset()
+	assert.NoError(t, printBackendsStat(buf, s, 1, 2))
+	assert.Contains(t, buf.String(), "LOG: Collected 1 samples of 2 backends\n")
+	assert.Contains(t, buf.String(), "% time      seconds wait_event\n")
+	assert.Contains(t, buf.String(), " 50.00     0.500000 Lock.transactionid\n")
+	assert.Contains(t, buf.String(), "100.00     1.000000\n")
+}
+
+func Test_getBackendsSnapshot(t *testing.T) {
+	target, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+
+	db, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+
+	go func() {
+		_, err := target.Exec("SELECT pg_sleep(1) /* profiled */")
+		assert.NoError(t, err)
+	}()
+
+	f, err := newFilter(Config{QueryRegex: "profiled"})
+	assert.NoError(t, err)
+
+	var got []backendSample
+	for i := 0; i < 10 && len(got) == 0; i++ {
+		time.Sleep(50 * time.Millisecond)
+		got, err = getBackendsSnapshot(db, f)
+		assert.NoError(t, err)
+	}
+	assert.Len(t, got, 1)
+	assert.Equal(t, "Timeout.PgSleep", got[0].waitEntry)
+
+	target.Close()
+	db.Close()
+}
@@ -0,0 +1,135 @@
+// Accounting CPU time spent by profiled backends in user and system modes (local instances only).
+
+package profile
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cpuTimes defines CPU time spent by process in user and system modes, in seconds.
+type cpuTimes struct {
+	user   float64
+	system float64
+}
+
+// sub returns difference between CPU times.
+func (c cpuTimes) sub(prev cpuTimes) cpuTimes {
+	return cpuTimes{user: c.user - prev.user, system: c.system - prev.system}
+}
+
+// readProcCPUTimes reads CPU times of process from specified proc stat file (/proc/<pid>/stat).
+func readProcCPUTimes(statfile string, ticks float64) (cpuTimes, error) {
+	content, err := ioutil.ReadFile(statfile) // #nosec G304
+	if err != nil {
+		return cpuTimes{}, err
+	}
+
+	// Process name is enclosed into parentheses and could contain spaces, skip it and parse remaining fields. Fields
+	// start from process state (3rd field), utime and stime are 14th and 15th fields.
+	data := string(content)
+	i := strings.LastIndex(data, ")")
+	if i < 0 {
+		return cpuTimes{}, fmt.Errorf("invalid input: %s", strings.TrimSpace(data))
+	}
+
+	fields := strings.Fields(data[i+1:])
+	if len(fields) < 13 {
+		return cpuTimes{}, fmt.Errorf("invalid input: %s", strings.TrimSpace(data))
+	}
+
+	utime, err := strconv.ParseFloat(fields[11], 64)
+	if err != nil {
+		return cpuTimes{}, err
+	}
+
+	stime, err := strconv.ParseFloat(fields[12], 64)
+	if err != nil {
+		return cpuTimes{}, err
+	}
+
+	return cpuTimes{user: utime / ticks, system: stime / ticks}, nil
+}
+
+// cpuTracker tracks CPU times of profiled backends. Nil tracker is valid and does nothing, it is used when CPU times
+// accounting is disabled.
+type cpuTracker struct {
+	procdir string           // path to proc filesystem
+	ticks   float64          // value of system's CLK_TCK
+	first   map[int]cpuTimes // CPU times when backend has been observed first time
+	last    map[int]cpuTimes // CPU times when backend has been observed last time
+}
+
+// newCPUTracker creates new tracker of CPU times.
+func newCPUTracker(procdir string, ticks float64) *cpuTracker {
+	return &cpuTracker{procdir: procdir, ticks: ticks, first: map[int]cpuTimes{}, last: map[int]cpuTimes{}}
+}
+
+// update reads current CPU times of backend. Backends which are already gone are ignored.
+func (t *cpuTracker) update(pid int) error {
+	if t == nil {
+		return nil
+	}
+
+	c, err := readProcCPUTimes(fmt.Sprintf("%s/%d/stat", t.procdir, pid), t.ticks)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read cpu times of process %d failed: %s", pid, err)
+	}
+
+	if _, ok := t.first[pid]; !ok {
+		t.first[pid] = c
+	}
+	t.last[pid] = c
+
+	return nil
+}
+
+// reset forgets CPU times observed so far.
+func (t *cpuTracker) reset() {
+	if t == nil {
+		return
+	}
+
+	t.first = map[int]cpuTimes{}
+	t.last = map[int]cpuTimes{}
+}
+
+// total returns CPU times spent by all tracked backends since they have been observed first time.
+func (t *cpuTracker) total() cpuTimes {
+	var res cpuTimes
+	if t == nil {
+		return res
+	}
+
+	for pid, c := range t.last {
+		d := c.sub(t.first[pid])
+		res.user += d.user
+		res.system += d.system
+	}
+
+	return res
+}
+
+// printCPUTimes prints CPU times spent by tracked backends split by user and system modes.
+func printCPUTimes(w io.Writer, t *cpuTracker) error {
+	if t == nil || len(t.last) == 0 {
+		return nil
+	}
+
+	c := t.total()
+
+	var userPct, systemPct float64
+	if total := c.user + c.system; total > 0 {
+		userPct, systemPct = 100*c.user/total, 100*c.system/total
+	}
+
+	_, err := fmt.Fprintf(w, "LOG: On-CPU time: user %.2fs (%.2f%%), system %.2fs (%.2f%%)\n", c.user, userPct, c.system, systemPct)
+	return err
+}
@@ -0,0 +1,41 @@
+package profile
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_readProcCPUTimes(t *testing.T) {
+	got, err := readProcCPUTimes("testdata/proc/4242/stat", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, cpuTimes{user: 2.5, system: 0.5}, got)
+
+	_, err = readProcCPUTimes("testdata/proc/invalid", 100)
+	assert.Error(t, err)
+}
+
+func Test_cpuTracker(t *testing.T) {
+	cpu := newCPUTracker("testdata/proc", 100)
+	assert.NoError(t, cpu.update(4242))
+	assert.NoError(t, cpu.update(4343)) // non-existent processes are ignored
+	assert.Equal(t, cpuTimes{user: 2.5, system: 0.5}, cpu.first[4242])
+	assert.Len(t, cpu.last, 1)
+
+	cpu.last[4242] = cpuTimes{user: 4, system: 1}
+	assert.Equal(t, cpuTimes{user: 1.5, system: 0.5}, cpu.total())
+
+	var buf bytes.Buffer
+	assert.NoError(t, printCPUTimes(&buf, cpu))
+	assert.Equal(t, "LOG: On-CPU time: user 1.50s (75.00%), system 0.50s (25.00%)\n", buf.String())
+
+	cpu.reset()
+	assert.Equal(t, cpuTimes{}, cpu.total())
+
+	// Nil tracker is used when CPU times accounting is disabled.
+	var disabled *cpuTracker
+	assert.NoError(t, disabled.update(4242))
+	buf.Reset()
+	assert.NoError(t, printCPUTimes(&buf, disabled))
+	assert.Equal(t, "", buf.String())
+}
@@ -0,0 +1,347 @@
+// Continuous profiling: sampling wait events of backends in background and writing samples into rotating files.
+
+package profile
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// RotateHour defines rotation of profile files every hour.
+	RotateHour = "hour"
+	// RotateDay defines rotation of profile files every day.
+	RotateDay = "day"
+
+	// daemonFilePrefix defines prefix of profile files names.
+	daemonFilePrefix = "pgcenter-profile-"
+	// daemonFlushInterval defines how often aggregated samples are written into profile file.
+	daemonFlushInterval = time.Minute
+)
+
+// daemonKey defines backend, its query and wait event observed in a sample.
+type daemonKey struct {
+	pid       int
+	query     string
+	waitEntry string
+}
+
+// daemonSample defines number of samples of backend's query observed in particular wait event.
+type daemonSample struct {
+	Pid       int    `json:"pid"`
+	Query     string `json:"query"`
+	WaitEvent string `json:"wait_event"`
+	Samples   int    `json:"samples"`
+}
+
+// daemonRecord defines samples aggregated during flush interval. Records are written into profile files as JSON
+// documents, one record per line.
+type daemonRecord struct {
+	Time    time.Time      `json:"time"`
+	Samples []daemonSample `json:"samples"`
+}
+
+// newDaemonRecord creates record from aggregated samples. Samples are sorted for producing stable output.
+func newDaemonRecord(ts time.Time, samples map[daemonKey]int) daemonRecord {
+	r := daemonRecord{Time: ts, Samples: make([]daemonSample, 0, len(samples))}
+	for k, v := range samples {
+		r.Samples = append(r.Samples, daemonSample{Pid: k.pid, Query: k.query, WaitEvent: k.waitEntry, Samples: v})
+	}
+
+	sort.Slice(r.Samples, func(i, j int) bool {
+		a, b := r.Samples[i], r.Samples[j]
+		if a.Pid != b.Pid {
+			return a.Pid < b.Pid
+		}
+		if a.Query != b.Query {
+			return a.Query < b.Query
+		}
+		return a.WaitEvent < b.WaitEvent
+	})
+
+	return r
+}
+
+// daemonFileName returns name of profile file which should contain samples taken at specified time.
+func daemonFileName(rotate string, ts time.Time) string {
+	if rotate == RotateDay {
+		return daemonFilePrefix + ts.Format("20060102") + ".jsonl"
+	}
+	return daemonFilePrefix + ts.Format("20060102-15") + ".jsonl"
+}
+
+// daemonWriter writes records into profile files and rotates the files.
+type daemonWriter struct {
+	directory string   // directory where profile files are stored
+	rotate    string   // rotation period
+	keep      int      // number of profile files to keep, zero means keep all files
+	name      string   // name of currently opened profile file
+	file      *os.File // currently opened profile file
+
+	hooks    *hook.Runner // runs user hooks when profile files are rotated
+	instance string       // name of profiled instance used in hooks events
+}
+
+// write writes record into profile file. New profile file is opened when rotation period is over.
+func (d *daemonWriter) write(r daemonRecord) error {
+	name := daemonFileName(d.rotate, r.Time)
+	if name != d.name {
+		prev := d.name
+		err := d.close()
+		if err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(filepath.Join(d.directory, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644) // #nosec G302
+		if err != nil {
+			return err
+		}
+		d.name, d.file = name, f
+
+		err = removeDaemonFiles(d.directory, d.keep)
+		if err != nil {
+			return err
+		}
+
+		if prev != "" {
+			d.hooks.Fire(hook.Event{Event: hook.RecordingRotated, Instance: d.instance, Details: map[string]string{
+				"previous": filepath.Join(d.directory, prev), "current": filepath.Join(d.directory, name),
+			}})
+		}
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.file.Write(append(data, '\n'))
+	return err
+}
+
+// close closes currently opened profile file.
+func (d *daemonWriter) close() error {
+	if d.file == nil {
+		return nil
+	}
+
+	err := d.file.Close()
+	d.name, d.file = "", nil
+	return err
+}
+
+// listDaemonFiles returns sorted list of profile files in the directory. Names of profile files contain timestamps,
+// hence the files are sorted from oldest to newest.
+func listDaemonFiles(directory string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(directory, daemonFilePrefix+"*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// removeDaemonFiles removes oldest profile files and keeps only specified number of newest files.
+func removeDaemonFiles(directory string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	files, err := listDaemonFiles(directory)
+	if err != nil {
+		return err
+	}
+
+	for len(files) > keep {
+		err := os.Remove(files[0])
+		if err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+
+	return nil
+}
+
+// profileDaemonLoop continuously samples wait events of backends matching the filter and writes aggregated samples
+// into rotating profile files.
+func profileDaemonLoop(out io.Writer, conn *postgres.DB, cfg Config, doQuit chan os.Signal) error {
+	f, err := newFilter(cfg)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := hook.NewRunner(cfg.Hooks, func(format string, a ...interface{}) {
+		_, _ = fmt.Fprintf(out, "ERROR: "+format+"\n", a...)
+	})
+	if err != nil {
+		return err
+	}
+	defer hooks.Wait()
+
+	dw := &daemonWriter{
+		directory: cfg.Directory, rotate: cfg.Rotate, keep: cfg.Keep, hooks: hooks, instance: hook.InstanceName(conn.Config),
+	}
+
+	_, err = fmt.Fprintf(out, "LOG: Profiling %s with %s sampling, writing profiles into %s\n", f, cfg.Frequency, cfg.Directory)
+	if err != nil {
+		return err
+	}
+
+	samples := map[daemonKey]int{}
+	last := time.Now()
+
+	smp, reason, err := sampleLoop(cfg, doQuit, func() (bool, string, error) {
+		curr, err := getBackendsSnapshot(conn, f)
+		if err != nil {
+			return false, "", err
+		}
+
+		// Write samples aggregated during flush interval, taken sample is accounted in the next interval.
+		if now := time.Now(); now.Sub(last) >= daemonFlushInterval {
+			err := flushDaemonSamples(dw, last, samples)
+			if err != nil {
+				return false, "", err
+			}
+			samples = map[daemonKey]int{}
+			last = now
+		}
+
+		for _, b := range curr {
+			samples[daemonKey{pid: b.pid, query: normalizeFrame(b.queryText, cfg.Strsize), waitEntry: waitEntryName(b.waitEntry)}]++
+		}
+
+		return false, "", nil
+	})
+	if err != nil {
+		_ = dw.close()
+		return err
+	}
+
+	err = flushDaemonSamples(dw, last, samples)
+	if err != nil {
+		return err
+	}
+
+	err = dw.close()
+	if err != nil {
+		return err
+	}
+
+	if reason != "" {
+		_, err = fmt.Fprintf(out, "LOG: Stop profiling, %s\n", reason)
+		if err != nil {
+			return err
+		}
+	}
+
+	return printSampler(out, smp)
+}
+
+// flushDaemonSamples writes samples aggregated since specified time into profile file. Profile file is synced after
+// writing, hence written samples are not lost if the daemon is killed.
+func flushDaemonSamples(dw *daemonWriter, ts time.Time, samples map[daemonKey]int) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	err := dw.write(newDaemonRecord(ts, samples))
+	if err != nil {
+		_ = dw.close()
+		return fmt.Errorf("write profile failed: %s", err)
+	}
+
+	return dw.file.Sync()
+}
+
+// loadMain reads profile files written in daemon mode and prints the profile.
+func loadMain(out io.Writer, cfg Config) error {
+	sess := newSession(cfg.Strsize, cfg.PerStatement, cfg.GroupBy)
+
+	n, err := loadSession(cfg.Load, sess)
+	if err != nil {
+		return err
+	}
+
+	format := cfg.format()
+	if format == "" || format == FormatText {
+		var total int
+		for _, v := range sess.samples {
+			total += v
+		}
+
+		_, err = fmt.Fprintf(out, "LOG: Loaded %d samples from %d files\n", total, n)
+		if err != nil {
+			return err
+		}
+	}
+
+	return printSession(out, sess, format)
+}
+
+// loadSession reads samples from profile files written by daemon and accounts them in the session. Directories
+// are expanded to profile files they contain.
+func loadSession(paths []string, sess *session) (int, error) {
+	var files []string
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return 0, err
+		}
+
+		if !fi.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		list, err := listDaemonFiles(p)
+		if err != nil {
+			return 0, err
+		}
+		files = append(files, list...)
+	}
+
+	for _, name := range files {
+		err := loadDaemonFile(name, sess)
+		if err != nil {
+			return 0, fmt.Errorf("load %s failed: %s", name, err)
+		}
+	}
+
+	return len(files), nil
+}
+
+// loadDaemonFile reads samples from profile file and accounts them in the session.
+func loadDaemonFile(name string, sess *session) error {
+	f, err := os.Open(filepath.Clean(name))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		var r daemonRecord
+		err := json.Unmarshal(scanner.Bytes(), &r)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range r.Samples {
+			sess.addSamples(s.Pid, s.Query, s.WaitEvent, s.Samples)
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,95 @@
+package profile
+
+import (
+	"bytes"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_daemonFileName(t *testing.T) {
+	ts := time.Date(2021, 3, 14, 15, 9, 26, 0, time.UTC)
+	assert.Equal(t, "pgcenter-profile-20210314-15.jsonl", daemonFileName(RotateHour, ts))
+	assert.Equal(t, "pgcenter-profile-20210314.jsonl", daemonFileName(RotateDay, ts))
+}
+
+func Test_newDaemonRecord(t *testing.T) {
+	ts := time.Date(2021, 3, 14, 15, 9, 0, 0, time.UTC)
+	r := newDaemonRecord(ts, map[daemonKey]int{
+		{pid: 2, query: "SELECT 1", waitEntry: "IO.DataFileRead"}: 1,
+		{pid: 1, query: "SELECT 2", waitEntry: runningEntry}:      3,
+		{pid: 1, query: "SELECT 1", waitEntry: "Lock.tuple"}:      2,
+	})
+
+	assert.Equal(t, daemonRecord{Time: ts, Samples: []daemonSample{
+		{Pid: 1, Query: "SELECT 1", WaitEvent: "Lock.tuple", Samples: 2},
+		{Pid: 1, Query: "SELECT 2", WaitEvent: runningEntry, Samples: 3},
+		{Pid: 2, Query: "SELECT 1", WaitEvent: "IO.DataFileRead", Samples: 1},
+	}}, r)
+}
+
+func Test_daemonWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-profile")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// Rotations are reported to hooks.
+	hooksFile := filepath.Join(dir, "hooks.log")
+	hooks, err := hook.NewRunner([]hook.Config{{Command: []string{"sh", "-c", "cat >> " + hooksFile}}}, nil)
+	assert.NoError(t, err)
+
+	dw := &daemonWriter{directory: dir, rotate: RotateHour, keep: 2, hooks: hooks, instance: "127.0.0.1:5432/postgres"}
+	ts := time.Date(2021, 3, 14, 15, 0, 0, 0, time.UTC)
+	samples := map[daemonKey]int{{pid: 1, query: "SELECT 1", waitEntry: "IO.DataFileRead"}: 2}
+
+	// Write samples within three hours, the oldest file should be removed.
+	for _, d := range []time.Duration{0, time.Minute, time.Hour, 2 * time.Hour} {
+		assert.NoError(t, flushDaemonSamples(dw, ts.Add(d), samples))
+	}
+	assert.NoError(t, dw.close())
+
+	hooks.Wait()
+	data, err := ioutil.ReadFile(hooksFile)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+	for _, l := range lines {
+		assert.Contains(t, l, `"event":"recording_rotated","time"`)
+		assert.Contains(t, l, `"instance":"127.0.0.1:5432/postgres"`)
+	}
+
+	files, err := listDaemonFiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "pgcenter-profile-20210314-16.jsonl"),
+		filepath.Join(dir, "pgcenter-profile-20210314-17.jsonl"),
+	}, files)
+
+	// Load all files from directory.
+	sess := newSession(128, false, nil)
+	n, err := loadSession([]string{dir}, sess)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, map[sessionKey]int{{query: "SELECT 1", waitEntry: "IO.DataFileRead"}: 4}, sess.samples)
+	assert.Equal(t, map[backendKey]int{{pid: 1, waitEntry: "IO.DataFileRead"}: 4}, sess.backends)
+
+	// Load single file.
+	sess = newSession(128, false, nil)
+	n, err = loadSession([]string{files[0]}, sess)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, map[sessionKey]int{{query: "SELECT 1", waitEntry: "IO.DataFileRead"}: 2}, sess.samples)
+
+	_, err = loadSession([]string{filepath.Join(dir, "invalid")}, sess)
+	assert.Error(t, err)
+
+	// Print loaded profile.
+	var buf bytes.Buffer
+	assert.NoError(t, loadMain(&buf, Config{Strsize: 128, Load: []string{dir}, Format: FormatFlamegraph}))
+	assert.Equal(t, "SELECT 1;IO;DataFileRead 4\n", buf.String())
+}
@@ -0,0 +1,114 @@
+// Following backends: waiting for backends matching the filter, attaching to them and profiling their queries.
+
+package profile
+
+import (
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"io"
+	"os"
+	"time"
+)
+
+// profileFollowLoop waits for a backend matching the filter, attaches to it and profiles its queries. When the backend
+// finishes matching query (or exits), the profiler detaches from it and waits for the next matching backend.
+func profileFollowLoop(out io.Writer, conn *postgres.DB, cfg Config, cpu *cpuTracker, doQuit chan os.Signal) error {
+	f, err := newFilter(cfg)
+	if err != nil {
+		return err
+	}
+
+	w := textWriter(out, cfg)
+	sess := newSession(cfg.Strsize, cfg.PerStatement, cfg.GroupBy)
+
+	_, err = fmt.Fprintf(w, "LOG: Following %s with %s sampling\n", f, cfg.Frequency)
+	if err != nil {
+		return err
+	}
+
+	var bp *backendProfiler // profiler of attached backend, nil when waiting for matching backend
+	var attached int
+
+	// Profiling is considered idle when attached backend is finished.
+	smp, reason, err := sampleLoop(cfg, doQuit, func() (bool, string, error) {
+		// Look for matching backend and attach to it.
+		if bp == nil {
+			curr, err := getBackendsSnapshot(conn, f)
+			if err != nil {
+				return false, "", err
+			}
+
+			if len(curr) > 0 {
+				bp = newBackendProfiler(curr[0].pid, cfg.Strsize, cpu)
+				attached++
+
+				_, err = fmt.Fprintf(w, "LOG: Attach to process %d\n", bp.pid)
+				if err != nil {
+					return false, "", err
+				}
+			}
+		}
+
+		events := map[int]string{}
+
+		// Profile attached backend, detach from it when it finishes matching query.
+		if bp != nil {
+			curr, err := getProfileSnapshot(conn, bp.pid)
+			if err != nil && err != pgx.ErrNoRows {
+				return false, "", err
+			}
+
+			if err == pgx.ErrNoRows || curr.state != "active" || !f.match(backendSample{queryText: curr.queryText}) {
+				reason := "matching query finished"
+				if err == pgx.ErrNoRows {
+					reason = "process doesn't exist"
+				}
+
+				err := bp.flush(w)
+				if err != nil {
+					return false, "", err
+				}
+
+				cpu.reset()
+
+				_, err = fmt.Fprintf(w, "LOG: Detach from process %d, %s\n", bp.pid, reason)
+				if err != nil {
+					return false, "", err
+				}
+
+				bp = nil
+			} else {
+				err := bp.step(w, curr)
+				if err != nil {
+					return false, "", err
+				}
+
+				sess.add(bp.pid, curr.queryText, curr.waitEntry)
+				events[bp.pid] = waitEntryName(curr.waitEntry)
+			}
+		}
+
+		sess.observe(time.Now(), events)
+
+		return attached > 0 && bp == nil, "", nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = flushFollowed(w, bp)
+	if err != nil {
+		return err
+	}
+
+	return printSummary(out, w, cfg, sess, smp, reason)
+}
+
+// flushFollowed prints profile of the query of attached backend, if any.
+func flushFollowed(w io.Writer, bp *backendProfiler) error {
+	if bp == nil {
+		return nil
+	}
+	return bp.flush(w)
+}
@@ -0,0 +1,49 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_profileFollowLoop(t *testing.T) {
+	target, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+
+	var pid int
+	err = target.QueryRow("SELECT pg_backend_pid()").Scan(&pid)
+	assert.NoError(t, err)
+
+	db, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+
+	go func() {
+		// waiting for to start profiling
+		time.Sleep(500 * time.Millisecond)
+
+		// run matching query, non-matching query and matching query again
+		_, err = target.Exec("SELECT 'pgcenter-follow', pg_sleep(1)")
+		assert.NoError(t, err)
+		_, err = target.Exec("SELECT pg_sleep(0.5)")
+		assert.NoError(t, err)
+		_, err = target.Exec("SELECT 'pgcenter-follow', pg_sleep(1)")
+		assert.NoError(t, err)
+
+		target.Close()
+	}()
+
+	var buf bytes.Buffer
+	err = profileFollowLoop(&buf, db, Config{
+		Frequency: 50 * time.Millisecond, Strsize: 64, QueryRegex: "pgcenter-follow", Follow: true, Duration: 4 * time.Second,
+	}, nil, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "LOG: Following backends with query~pgcenter-follow with 50ms sampling")
+	assert.Contains(t, buf.String(), fmt.Sprintf("LOG: Attach to process %d", pid))
+	assert.Contains(t, buf.String(), fmt.Sprintf("LOG: Detach from process %d, matching query finished", pid))
+	assert.Contains(t, buf.String(), "Timeout.PgSleep")
+	assert.NotContains(t, buf.String(), "query: SELECT pg_sleep(0.5)")
+	db.Close()
+}
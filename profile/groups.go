@@ -0,0 +1,158 @@
+// Rolling up samples collected during profiling session at different granularities.
+
+package profile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// GroupByNested defines grouping by wait event types with nested wait events.
+	GroupByNested = "nested"
+	// GroupByWaitEventType defines grouping by wait event types.
+	GroupByWaitEventType = "wait_event_type"
+	// GroupByWaitEvent defines grouping by wait events.
+	GroupByWaitEvent = "wait_event"
+	// GroupByQueryid defines grouping by statements with nested wait events.
+	GroupByQueryid = "queryid"
+	// GroupByBackend defines grouping by backends with nested wait events.
+	GroupByBackend = "backend"
+)
+
+// GroupByValues defines all supported groupings.
+var GroupByValues = []string{GroupByNested, GroupByWaitEventType, GroupByWaitEvent, GroupByQueryid, GroupByBackend}
+
+// group defines number of samples of a group and its nested groups.
+type group struct {
+	name     string
+	samples  int
+	children []*group
+	idx      map[string]*group // index of nested groups by names
+}
+
+// add accounts samples in the nested group specified by path of names.
+func (g *group) add(samples int, path ...string) {
+	if len(path) == 0 {
+		return
+	}
+
+	if g.idx == nil {
+		g.idx = map[string]*group{}
+	}
+
+	c, ok := g.idx[path[0]]
+	if !ok {
+		c = &group{name: path[0]}
+		g.idx[path[0]] = c
+		g.children = append(g.children, c)
+	}
+
+	c.samples += samples
+	c.add(samples, path[1:]...)
+}
+
+// sortGroups returns groups sorted by number of samples, nested groups are sorted too.
+func sortGroups(groups []*group) []*group {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].samples == groups[j].samples {
+			return groups[i].name < groups[j].name
+		}
+		return groups[i].samples > groups[j].samples
+	})
+	for _, g := range groups {
+		g.children = sortGroups(g.children)
+	}
+	return groups
+}
+
+// groupSamples rolls up samples of the session accordingly to specified grouping. Returns sorted groups and
+// description of grouping.
+func groupSamples(s *session, groupBy string) ([]*group, string, error) {
+	root := &group{}
+	var title string
+
+	switch groupBy {
+	case GroupByNested:
+		title = "wait_event_type / wait_event"
+		for k, v := range s.samples {
+			root.add(v, strings.SplitN(k.waitEntry, ".", 2)...)
+		}
+	case GroupByWaitEventType:
+		title = "wait_event_type"
+		for k, v := range s.samples {
+			root.add(v, strings.SplitN(k.waitEntry, ".", 2)[0])
+		}
+	case GroupByWaitEvent:
+		title = "wait_event"
+		for k, v := range s.samples {
+			root.add(v, k.waitEntry)
+		}
+	case GroupByQueryid:
+		// pg_stat_activity has no queryid in all supported versions, statements are identified by normalized text.
+		title = "statement / wait_event"
+		for k, v := range s.samples {
+			root.add(v, normalizeQuery(k.query), k.waitEntry)
+		}
+	case GroupByBackend:
+		title = "backend / wait_event"
+		for k, v := range s.backends {
+			root.add(v, fmt.Sprintf("pid %d", k.pid), k.waitEntry)
+		}
+	default:
+		return nil, "", fmt.Errorf("unknown grouping: %s", groupBy)
+	}
+
+	return sortGroups(root.children), title, nil
+}
+
+// printGroup prints samples of the session rolled up accordingly to specified grouping.
+func printGroup(w io.Writer, s *session, groupBy string) error {
+	groups, title, err := groupSamples(s, groupBy)
+	if err != nil {
+		return err
+	}
+
+	var total int
+	for _, g := range groups {
+		total += g.samples
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	_, err = fmt.Fprintf(w, "------ -------- -----------------------------\n"+
+		"%% samp  samples %s\n"+
+		"------ -------- -----------------------------\n", title)
+	if err != nil {
+		return err
+	}
+
+	err = printGroups(w, groups, total, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "------ -------- -----------------------------\n%*.2f %*d\n", 6, 100.0, 8, total)
+	return err
+}
+
+// printGroups prints groups and nested groups with indentation.
+func printGroups(w io.Writer, groups []*group, total int, level int) error {
+	for _, g := range groups {
+		_, err := fmt.Fprintf(w, "%*.2f %*d %s%s\n", 6, 100*float64(g.samples)/float64(total), 8, g.samples, strings.Repeat("  ", level), g.name)
+		if err != nil {
+			return err
+		}
+
+		err = printGroups(w, g.children, total, level+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
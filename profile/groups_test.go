@@ -0,0 +1,90 @@
+package profile
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func Test_groupSamples(t *testing.T) {
+	s := newSession(128, false, nil)
+	s.add(1, "SELECT 1", "IO.DataFileRead")
+	s.add(1, "SELECT 1", "IO.DataFileRead")
+	s.add(1, "SELECT 2", "IO.WALWrite")
+	s.add(2, "SELECT 1", "Lock.tuple")
+	s.add(2, "SELECT 1", "")
+
+	testcases := []struct {
+		groupBy string
+		title   string
+		want    []string // flattened 'name:samples' pairs, nested groups follow their parents
+	}{
+		{
+			groupBy: GroupByNested, title: "wait_event_type / wait_event",
+			want: []string{"IO:3", "DataFileRead:2", "WALWrite:1", "Lock:1", "tuple:1", "Running (on CPU):1"},
+		},
+		{
+			groupBy: GroupByWaitEventType, title: "wait_event_type",
+			want: []string{"IO:3", "Lock:1", "Running (on CPU):1"},
+		},
+		{
+			groupBy: GroupByWaitEvent, title: "wait_event",
+			want: []string{"IO.DataFileRead:2", "IO.WALWrite:1", "Lock.tuple:1", "Running (on CPU):1"},
+		},
+		{
+			groupBy: GroupByQueryid, title: "statement / wait_event",
+			want: []string{"SELECT ?:5", "IO.DataFileRead:2", "IO.WALWrite:1", "Lock.tuple:1", "Running (on CPU):1"},
+		},
+		{
+			groupBy: GroupByBackend, title: "backend / wait_event",
+			want: []string{"pid 1:3", "IO.DataFileRead:2", "IO.WALWrite:1", "pid 2:2", "Lock.tuple:1", "Running (on CPU):1"},
+		},
+	}
+
+	for _, tc := range testcases {
+		groups, title, err := groupSamples(s, tc.groupBy)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.title, title)
+		assert.Equal(t, tc.want, flattenGroups(groups))
+	}
+
+	_, _, err := groupSamples(s, "invalid")
+	assert.Error(t, err)
+}
+
+func Test_printGroup(t *testing.T) {
+	s := newSession(128, false, nil)
+	s.add(1, "SELECT 1", "IO.DataFileRead")
+	s.add(1, "SELECT 1", "IO.DataFileRead")
+	s.add(1, "SELECT 1", "IO.WALWrite")
+	s.add(1, "SELECT 1", "")
+
+	var buf bytes.Buffer
+	assert.NoError(t, printGroup(&buf, s, GroupByNested))
+	assert.Equal(t, "------ -------- -----------------------------\n"+
+		"% samp  samples wait_event_type / wait_event\n"+
+		"------ -------- -----------------------------\n"+
+		" 75.00        3 IO\n"+
+		" 50.00        2   DataFileRead\n"+
+		" 25.00        1   WALWrite\n"+
+		" 25.00        1 Running (on CPU)\n"+
+		"------ -------- -----------------------------\n"+
+		"100.00        4\n", buf.String())
+
+	// Nothing is printed for empty session.
+	buf.Reset()
+	assert.NoError(t, printGroup(&buf, newSession(128, false, nil), GroupByNested))
+	assert.Equal(t, "", buf.String())
+
+	assert.Error(t, printGroup(&buf, s, "invalid"))
+}
+
+func flattenGroups(groups []*group) []string {
+	var res []string
+	for _, g := range groups {
+		res = append(res, g.name+":"+strconv.Itoa(g.samples))
+		res = append(res, flattenGroups(g.children)...)
+	}
+	return res
+}
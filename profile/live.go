@@ -0,0 +1,84 @@
+// Live profiling of a single backend, used for displaying accumulating profile in 'pgcenter top'.
+
+package profile
+
+import (
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"io"
+	"time"
+)
+
+// LiveProfiler accumulates wait events profile of a single backend during the whole profiling session.
+type LiveProfiler struct {
+	pid     int
+	strsize int
+	s       stats
+	samples int       // number of samples when backend has been active
+	query   string    // last observed query
+	last    time.Time // time of previous sample
+	active  bool      // backend has been active in previous sample
+}
+
+// NoProcessError describes profiled backend which doesn't exist, e.g. it has been finished.
+type NoProcessError struct {
+	Pid int
+}
+
+// Error implements error interface.
+func (e *NoProcessError) Error() string {
+	return fmt.Sprintf("process with pid %d doesn't exist", e.Pid)
+}
+
+// NewLiveProfiler creates profiler of backend with specified PID.
+func NewLiveProfiler(pid int, strsize int) *LiveProfiler {
+	return &LiveProfiler{pid: pid, strsize: strsize, s: newStatsStore()}
+}
+
+// Sample takes snapshot of backend's activity and accounts time passed since previous snapshot in backend's current
+// wait event. Returns error if backend doesn't exist.
+func (p *LiveProfiler) Sample(conn *postgres.DB) error {
+	curr, err := getProfileSnapshot(conn, p.pid)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &NoProcessError{Pid: p.pid}
+		}
+		return err
+	}
+
+	p.account(time.Now(), curr)
+	return nil
+}
+
+// account accounts snapshot of backend's activity taken at specified time. Time between samples is accounted only
+// when backend has been active in both samples.
+func (p *LiveProfiler) account(now time.Time, curr profileStat) {
+	if curr.state == "active" && p.active {
+		p.s = countBackendsWaitings(p.s, []backendSample{{waitEntry: curr.waitEntry}}, now.Sub(p.last).Seconds())
+		p.query = curr.queryText
+		p.samples++
+	}
+	p.last = now
+	p.active = curr.state == "active"
+}
+
+// Print prints accumulated profile.
+func (p *LiveProfiler) Print(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "LOG: Profiling process %d, collected %d samples\n", p.pid, p.samples)
+	if err != nil {
+		return err
+	}
+
+	if len(p.s.durations) == 0 {
+		_, err = fmt.Fprintln(w, "LOG: Waiting for active query")
+		return err
+	}
+
+	err = printHeader(w, profileStat{queryText: p.query}, p.strsize)
+	if err != nil {
+		return err
+	}
+
+	return printStat(w, p.s)
+}
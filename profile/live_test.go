@@ -0,0 +1,34 @@
+package profile
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLiveProfiler(t *testing.T) {
+	p := NewLiveProfiler(123, 64)
+
+	var buf bytes.Buffer
+	assert.NoError(t, p.Print(&buf))
+	assert.Equal(t, "LOG: Profiling process 123, collected 0 samples\nLOG: Waiting for active query\n", buf.String())
+
+	ts := time.Date(2021, 3, 14, 15, 0, 0, 0, time.UTC)
+	p.account(ts, profileStat{state: "active", waitEntry: "IO.DataFileRead", queryText: "SELECT 1"})
+	p.account(ts.Add(time.Second), profileStat{state: "active", waitEntry: "IO.DataFileRead", queryText: "SELECT 1"})
+	p.account(ts.Add(2*time.Second), profileStat{state: "idle", queryText: "SELECT 1"})
+	p.account(ts.Add(5*time.Second), profileStat{state: "active", queryText: "SELECT 2"})
+	p.account(ts.Add(8*time.Second), profileStat{state: "active", queryText: "SELECT 2"})
+
+	buf.Reset()
+	assert.NoError(t, p.Print(&buf))
+	assert.Equal(t, "LOG: Profiling process 123, collected 2 samples\n"+
+		"------ ------------ -----------------------------\n"+
+		"% time      seconds wait_event                     query: SELECT 2\n"+
+		"------ ------------ -----------------------------\n"+
+		" 75.00     3.000000 Running (on CPU)\n"+
+		" 25.00     1.000000 IO.DataFileRead\n"+
+		"------ ------------ -----------------------------\n"+
+		"100.00     4.000000\n", buf.String())
+}
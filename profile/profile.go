@@ -5,7 +5,9 @@ package profile
 import (
 	"fmt"
 	"github.com/jackc/pgx/v4"
+	"github.com/lesovsky/pgcenter/internal/hook"
 	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
 	"io"
 	"os"
 	"os/signal"
@@ -14,6 +16,9 @@ import (
 	"time"
 )
 
+// runningEntry defines pseudo wait event used for samples when backend doesn't wait anything and is running on CPU.
+const runningEntry = "Running (on CPU)"
+
 // waitEvent defines particular wait event and how many times it is occurred.
 type waitEvent struct {
 	waitEventName  string
@@ -39,13 +44,65 @@ type profileStat struct {
 
 // Config defines program's configuration options.
 type Config struct {
-	Pid       int // PID of profiled backend
-	Frequency time.Duration
-	Strsize   int // Limit length for query string
+	Pid          int // PID of profiled backend
+	Frequency    time.Duration
+	Strsize      int           // Limit length for query string
+	Format       string        // Output format
+	Output       string        // Structured output format, overrides Format
+	User         string        // Profile backends of specified user
+	Database     string        // Profile backends connected to specified database
+	AppName      string        // Profile backends of specified application
+	QueryRegex   string        // Profile backends which queries match regular expression
+	All          bool          // Profile all backends
+	Duration     time.Duration // Stop profiling after specified time
+	MaxSamples   int           // Stop profiling after taking specified number of samples
+	UntilIdle    bool          // Stop profiling when profiled backends become idle
+	PerStatement bool          // Aggregate samples per statement across executions
+	GroupBy      []string      // Groupings of samples printed at the end of profiling
+	CPUTimes     bool          // Split on-CPU time into user and system time using procfs (local instances only)
+	Daemon       bool          // Continuously profile backends and write profiles into rotating files
+	Directory    string        // Directory for profile files written in daemon mode
+	Rotate       string        // Rotation period of profile files: hour, day
+	Keep         int           // Number of profile files to keep, zero means keep all files
+	Load         []string      // Profile files or directories written in daemon mode, which should be reported
+	Follow       bool          // Attach to backends matching filters one by one instead of profiling them together
+	Hooks        []hook.Config // User commands run when profile files are rotated in daemon mode
+}
+
+// stopReason returns reason of stopping profiling if any of configured limits is reached, or empty string otherwise.
+func (c Config) stopReason(elapsed time.Duration, samples int, idle bool) string {
+	switch {
+	case c.Duration > 0 && elapsed >= c.Duration:
+		return fmt.Sprintf("duration %s is reached", c.Duration)
+	case c.MaxSamples > 0 && samples >= c.MaxSamples:
+		return fmt.Sprintf("%d samples taken", samples)
+	case c.UntilIdle && idle:
+		return "profiled backends became idle"
+	default:
+		return ""
+	}
+}
+
+// format returns format used for printing profile.
+func (c Config) format() string {
+	if c.Output != "" {
+		return c.Output
+	}
+	return c.Format
+}
+
+// Filtered returns true if backends chosen by filters should be profiled instead of single backend.
+func (c Config) Filtered() bool {
+	return c.All || c.User != "" || c.Database != "" || c.AppName != "" || c.QueryRegex != ""
 }
 
 // RunMain is the main entry point for 'pgcenter profile' command
 func RunMain(dbConfig postgres.Config, config Config) error {
+	// Report profiles written in daemon mode, no connection to Postgres required.
+	if len(config.Load) > 0 {
+		return loadMain(os.Stdout, config)
+	}
+
 	// Connect to Postgres
 	conn, err := postgres.Connect(dbConfig)
 	if err != nil {
@@ -57,7 +114,42 @@ func RunMain(dbConfig postgres.Config, config Config) error {
 	doQuit := make(chan os.Signal, 1)
 	signal.Notify(doQuit, syscall.SIGINT, syscall.SIGTERM)
 
-	return profileLoop(os.Stdout, conn, config, doQuit)
+	// CPU times of backends are read from procfs, hence available only for local instances.
+	var cpu *cpuTracker
+	if config.CPUTimes {
+		if !conn.Local {
+			return fmt.Errorf("'--cpu-times' option is supported for local instances only")
+		}
+
+		ticks, err := stat.GetSysticksLocal()
+		if err != nil {
+			return fmt.Errorf("get systicks failed: %s", err)
+		}
+
+		cpu = newCPUTracker("/proc", ticks)
+	}
+
+	if config.Daemon {
+		return profileDaemonLoop(os.Stdout, conn, config, doQuit)
+	}
+
+	if config.Follow {
+		return profileFollowLoop(os.Stdout, conn, config, cpu, doQuit)
+	}
+
+	if config.Filtered() {
+		return profileBackendsLoop(os.Stdout, conn, config, cpu, doQuit)
+	}
+
+	return profileLoop(os.Stdout, conn, config, cpu, doQuit)
+}
+
+// waitEntryName returns name of wait event accounted in profile, empty wait event means backend is running on CPU.
+func waitEntryName(waitEntry string) string {
+	if waitEntry == "" {
+		return runningEntry
+	}
+	return waitEntry
 }
 
 // stats defines local statistics storage for profiled query.
@@ -74,82 +166,143 @@ func newStatsStore() stats {
 	}
 }
 
+// backendProfiler profiles queries executed by a single backend.
+type backendProfiler struct {
+	pid     int
+	strsize int
+	prev    profileStat
+	s       stats
+	cpu     *cpuTracker
+}
+
+// newBackendProfiler creates profiler of specified backend.
+func newBackendProfiler(pid int, strsize int, cpu *cpuTracker) *backendProfiler {
+	return &backendProfiler{pid: pid, strsize: strsize, s: newStatsStore(), cpu: cpu}
+}
+
+// step accounts snapshot of backend's activity. Profile of the query is printed when the query is finished.
+func (p *backendProfiler) step(w io.Writer, curr profileStat) error {
+	switch {
+	case p.prev.state != "active" && curr.state == "active":
+		// !active -> active - a query has been started - begin to count stats.
+		err := printHeader(w, curr, p.strsize)
+		if err != nil {
+			return err
+		}
+		p.s = countWaitings(p.s, curr, profileStat{})
+		p.prev = curr
+	case p.prev.state == "active" && curr.state == "active" && p.prev.changeStateTime == curr.changeStateTime:
+		// active -> active - query continues executing - continue to count stats.
+		p.s = countWaitings(p.s, curr, p.prev)
+		p.prev = curr
+	case p.prev.state == "active" && curr.state == "active" && p.prev.changeStateTime != curr.changeStateTime:
+		// active -> active (new) - a new query has been started - print stat for previous query, count new stats.
+		err := p.flush(w)
+		if err != nil {
+			return err
+		}
+		p.s = resetCounters(p.s)
+		p.cpu.reset()
+		p.s = countWaitings(p.s, curr, profileStat{})
+		p.prev = profileStat{}
+	case p.prev.state == "active" && curr.state != "active":
+		// active -> idle - query has been finished, but no new query started - print stat, waiting for new query.
+		err := p.flush(w)
+		if err != nil {
+			return err
+		}
+		p.s = resetCounters(p.s)
+		p.cpu.reset()
+		p.prev = profileStat{}
+	}
+
+	if curr.state == "active" {
+		return p.cpu.update(p.pid)
+	}
+
+	return nil
+}
+
+// flush prints profile of the query collected so far.
+func (p *backendProfiler) flush(w io.Writer) error {
+	return printQueryStat(w, p.s, p.cpu)
+}
+
 // profileLoop profiles and prints profiling results.
-func profileLoop(w io.Writer, conn *postgres.DB, cfg Config, doQuit chan os.Signal) error {
-	var prev profileStat
-	s := newStatsStore()
+func profileLoop(out io.Writer, conn *postgres.DB, cfg Config, cpu *cpuTracker, doQuit chan os.Signal) error {
+	bp := newBackendProfiler(cfg.Pid, cfg.Strsize, cpu)
+	sess := newSession(cfg.Strsize, cfg.PerStatement, cfg.GroupBy)
+	w := textWriter(out, cfg)
 
 	_, err := fmt.Fprintf(w, "LOG: Profiling process %d with %s sampling\n", cfg.Pid, cfg.Frequency)
 	if err != nil {
 		return err
 	}
 
-	t := time.NewTicker(cfg.Frequency)
-
-	for {
-		curr, profileErr := getProfileSnapshot(conn, cfg.Pid)
-		if profileErr != nil && profileErr == pgx.ErrNoRows {
-			// print collected stats before exit
-			err := printStat(w, s)
-			if err != nil {
-				return err
-			}
-
-			_, err = fmt.Fprintf(w, "LOG: Stop profiling, process with pid %d doesn't exist (%s)\n", cfg.Pid, profileErr.Error())
-			if err != nil {
-				return err
-			}
-
-			return nil
-		} else if profileErr != nil {
-			return profileErr
+	var seenActive bool
+
+	smp, reason, err := sampleLoop(cfg, doQuit, func() (bool, string, error) {
+		curr, err := getProfileSnapshot(conn, cfg.Pid)
+		if err == pgx.ErrNoRows {
+			return false, fmt.Sprintf("process with pid %d doesn't exist (%s)", cfg.Pid, err.Error()), nil
+		} else if err != nil {
+			return false, "", err
 		}
 
-		switch {
-		case prev.state != "active" && curr.state == "active":
-			// !active -> active - a query has been started - begin to count stats.
-			err := printHeader(w, curr, cfg.Strsize)
-			if err != nil {
-				return err
-			}
-			s = countWaitings(s, curr, profileStat{})
-			prev = curr
-		case prev.state == "active" && curr.state == "active" && prev.changeStateTime == curr.changeStateTime:
-			// active -> active - query continues executing - continue to count stats.
-			s = countWaitings(s, curr, prev)
-			prev = curr
-		case prev.state == "active" && curr.state == "active" && prev.changeStateTime != curr.changeStateTime:
-			// active -> active (new) - a new query has been started - print stat for previous query, count new stats.
-			err := printStat(w, s)
-			if err != nil {
-				return err
-			}
-			s = resetCounters(s)
-			s = countWaitings(s, curr, profileStat{})
-			prev = profileStat{}
-		case prev.state == "active" && curr.state != "active":
-			// active -> idle - query has been finished, but no new query started - print stat, waiting for new query.
-			err := printStat(w, s)
-			if err != nil {
-				return err
-			}
-			s = resetCounters(s)
-			prev = profileStat{}
+		err = bp.step(w, curr)
+		if err != nil {
+			return false, "", err
+		}
+
+		events := map[int]string{}
+		if curr.state == "active" {
+			sess.add(cfg.Pid, curr.queryText, curr.waitEntry)
+			events[cfg.Pid] = waitEntryName(curr.waitEntry)
+			seenActive = true
 		}
 
-		// Wait ticker ticks.
-		select {
-		case <-t.C:
-			continue
-		case <-doQuit:
-			t.Stop()
-			err := printStat(w, s)
-			if err != nil {
-				return err
-			}
-			return fmt.Errorf("got interrupt")
+		sess.observe(time.Now(), events)
+
+		return seenActive && curr.state != "active", "", nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// print collected stats before exit
+	err = bp.flush(w)
+	if err != nil {
+		return err
+	}
+
+	return printSummary(out, w, cfg, sess, smp, reason)
+}
+
+// printSummary prints reason of stopping profiling, achieved sampling rate and profile of the whole session. Error is
+// returned if profiling has been interrupted, i.e. reason is empty.
+func printSummary(out io.Writer, w io.Writer, cfg Config, sess *session, smp *sampler, reason string) error {
+	if reason != "" {
+		_, err := fmt.Fprintf(w, "LOG: Stop profiling, %s\n", reason)
+		if err != nil {
+			return err
 		}
 	}
+
+	err := printSampler(w, smp)
+	if err != nil {
+		return err
+	}
+
+	err = printSession(out, sess, cfg.format())
+	if err != nil {
+		return err
+	}
+
+	if reason == "" {
+		return fmt.Errorf("got interrupt")
+	}
+
+	return nil
 }
 
 // getProfileSnapshot get necessary activity snapshot from Postgres.
@@ -178,7 +331,7 @@ func getProfileSnapshot(conn *postgres.DB, pid int) (profileStat, error) {
 func countWaitings(s stats, curr profileStat, prev profileStat) stats {
 	// calculate durations
 	if curr.waitEntry == "" {
-		s.durations["Running"] = s.durations["Running"] + (curr.queryDurationSec - prev.queryDurationSec)
+		s.durations[runningEntry] = s.durations[runningEntry] + (curr.queryDurationSec - prev.queryDurationSec)
 	} else {
 		s.durations[curr.waitEntry] = s.durations[curr.waitEntry] + (curr.queryDurationSec - prev.queryDurationSec)
 	}
@@ -219,6 +372,20 @@ func printHeader(w io.Writer, curr profileStat, strsize int) error {
 	return nil
 }
 
+// printQueryStat prints collected wait events profile of the query and CPU times spent by backend during the query.
+func printQueryStat(w io.Writer, s stats, cpu *cpuTracker) error {
+	if len(s.durations) == 0 {
+		return nil
+	}
+
+	err := printStat(w, s)
+	if err != nil {
+		return err
+	}
+
+	return printCPUTimes(w, cpu)
+}
+
 // printStat prints collected wait events durations and percent ratios.
 func printStat(w io.Writer, s stats) error {
 	if len(s.durations) == 0 {
@@ -46,7 +46,7 @@ func Test_profileLoop(t *testing.T) {
 	}()
 
 	var buf bytes.Buffer
-	err = profileLoop(&buf, db, Config{Pid: pid, Frequency: 50 * time.Millisecond, Strsize: 64}, nil)
+	err = profileLoop(&buf, db, Config{Pid: pid, Frequency: 50 * time.Millisecond, Strsize: 64}, nil, nil)
 	assert.NoError(t, err)
 	assert.Contains(t, buf.String(), fmt.Sprintf("LOG: Profiling process %d with 50ms sampling", pid))
 	assert.Contains(t, buf.String(), "% time      seconds wait_event                     query: SELECT 1, pg_sleep(1)")
@@ -89,12 +89,12 @@ func Test_countWaitings(t *testing.T) {
 	// wait events distribution at the beginning: query was working 1 second, running 0.5s (50%), test entry 0.5s (50%)
 	s := stats{
 		durations: map[string]float64{
-			"Running":     0.5,
-			"Test.Entry1": 0.5,
+			"Running (on CPU)": 0.5,
+			"Test.Entry1":      0.5,
 		},
 		ratios: map[string]float64{
-			"Running":     50,
-			"Test.Entry1": 50,
+			"Running (on CPU)": 50,
+			"Test.Entry1":      50,
 		},
 	}
 	ps := profileStat{
@@ -115,12 +115,12 @@ func Test_countWaitings(t *testing.T) {
 	// all waiting time accounted for Test.Entry1 - running 0.5s (25%), wait entry - 1.5s (75%)
 	want := stats{
 		durations: map[string]float64{
-			"Running":     0.5,
-			"Test.Entry1": 1.5,
+			"Running (on CPU)": 0.5,
+			"Test.Entry1":      1.5,
 		},
 		ratios: map[string]float64{
-			"Running":     25,
-			"Test.Entry1": 75,
+			"Running (on CPU)": 25,
+			"Test.Entry1":      75,
 		},
 	}
 
@@ -136,15 +136,15 @@ func Test_countWaitings(t *testing.T) {
 		queryText:        "SELECT 1",
 	}
 
-	// all running time should be accounted to 'Running' - running 1.5s (50%), wait entry - 1.5s (50%)
+	// all running time should be accounted to 'Running (on CPU)' - running 1.5s (50%), wait entry - 1.5s (50%)
 	want = stats{
 		durations: map[string]float64{
-			"Running":     1.5,
-			"Test.Entry1": 1.5,
+			"Running (on CPU)": 1.5,
+			"Test.Entry1":      1.5,
 		},
 		ratios: map[string]float64{
-			"Running":     50,
-			"Test.Entry1": 50,
+			"Running (on CPU)": 50,
+			"Test.Entry1":      50,
 		},
 	}
 
@@ -155,16 +155,16 @@ func Test_countWaitings(t *testing.T) {
 func Test_resetCounters(t *testing.T) {
 	s := stats{
 		durations: map[string]float64{
-			"Test.Entry3": 140,
-			"Test.Entry2": 330,
-			"Test.Entry1": 1020,
-			"Running":     8510,
+			"Test.Entry3":      140,
+			"Test.Entry2":      330,
+			"Test.Entry1":      1020,
+			"Running (on CPU)": 8510,
 		},
 		ratios: map[string]float64{
-			"Test.Entry2": 3.3,
-			"Running":     85.1,
-			"Test.Entry3": 1.4,
-			"Test.Entry1": 10.2,
+			"Test.Entry2":      3.3,
+			"Running (on CPU)": 85.1,
+			"Test.Entry3":      1.4,
+			"Test.Entry1":      10.2,
 		},
 	}
 
@@ -187,16 +187,16 @@ func Test_printHeader(t *testing.T) {
 func Test_printStat(t *testing.T) {
 	s := stats{
 		durations: map[string]float64{
-			"Test.Entry3": 140,
-			"Test.Entry2": 330,
-			"Test.Entry1": 1020,
-			"Running":     8510,
+			"Test.Entry3":      140,
+			"Test.Entry2":      330,
+			"Test.Entry1":      1020,
+			"Running (on CPU)": 8510,
 		},
 		ratios: map[string]float64{
-			"Test.Entry2": 3.3,
-			"Running":     85.1,
-			"Test.Entry3": 1.4,
-			"Test.Entry1": 10.2,
+			"Test.Entry2":      3.3,
+			"Running (on CPU)": 85.1,
+			"Test.Entry3":      1.4,
+			"Test.Entry1":      10.2,
 		},
 	}
 
@@ -223,3 +223,25 @@ func Test_truncateQuery(t *testing.T) {
 		assert.Equal(t, tc.want, got)
 	}
 }
+
+func TestConfig_stopReason(t *testing.T) {
+	testcases := []struct {
+		cfg     Config
+		elapsed time.Duration
+		samples int
+		idle    bool
+		want    string
+	}{
+		{cfg: Config{}, elapsed: time.Hour, samples: 1000, idle: true, want: ""},
+		{cfg: Config{Duration: time.Minute}, elapsed: 30 * time.Second, want: ""},
+		{cfg: Config{Duration: time.Minute}, elapsed: time.Minute, want: "duration 1m0s is reached"},
+		{cfg: Config{MaxSamples: 10}, samples: 9, want: ""},
+		{cfg: Config{MaxSamples: 10}, samples: 10, want: "10 samples taken"},
+		{cfg: Config{UntilIdle: true}, idle: false, want: ""},
+		{cfg: Config{UntilIdle: true}, idle: true, want: "profiled backends became idle"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, tc.cfg.stopReason(tc.elapsed, tc.samples, tc.idle))
+	}
+}
@@ -0,0 +1,119 @@
+// Adaptive control of sampling frequency.
+
+package profile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// rttFactor defines how many times sampling interval should be longer than round-trip time of sampling query. When
+// the sampling query becomes slow, the interval is increased and sampling is slowed down to avoid producing extra load.
+const rttFactor = 4
+
+// sampler tracks round-trip time of sampling queries and adjusts sampling interval.
+type sampler struct {
+	frequency time.Duration // requested sampling interval
+	interval  time.Duration // current sampling interval
+	rtt       time.Duration // smoothed round-trip time of sampling query
+	start     time.Time     // time when sampling has been started
+	samples   int           // number of taken samples
+	backoffs  int           // number of times when sampling has been slowed down
+}
+
+// newSampler creates new sampler.
+func newSampler(frequency time.Duration, start time.Time) *sampler {
+	return &sampler{frequency: frequency, interval: frequency, start: start}
+}
+
+// observe accounts taken sample and its round-trip time. Returns true if sampling interval has been changed.
+func (s *sampler) observe(rtt time.Duration) bool {
+	s.samples++
+
+	// Use exponentially weighted moving average for smoothing occasional spikes.
+	if s.samples == 1 {
+		s.rtt = rtt
+	} else {
+		s.rtt = (7*s.rtt + rtt) / 8
+	}
+
+	// Interval is increased in steps equal to requested interval, hence small fluctuations of round-trip time don't
+	// change the interval.
+	interval := s.frequency
+	if d := rttFactor * s.rtt; d > interval {
+		interval = s.frequency * ((d + s.frequency - 1) / s.frequency)
+	}
+
+	if interval == s.interval {
+		return false
+	}
+
+	if interval > s.interval {
+		s.backoffs++
+	}
+
+	s.interval = interval
+	return true
+}
+
+// rate returns achieved number of samples per second.
+func (s *sampler) rate(now time.Time) float64 {
+	elapsed := now.Sub(s.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(s.samples) / elapsed
+}
+
+// summary returns description of achieved sampling rate.
+func (s *sampler) summary(now time.Time) string {
+	msg := fmt.Sprintf("LOG: Achieved sample rate %.2f/s, requested %.2f/s", s.rate(now), 1/s.frequency.Seconds())
+	if s.backoffs > 0 {
+		msg += fmt.Sprintf(", sampling slowed down %d times due to round-trip time %s", s.backoffs, s.rtt.Round(time.Microsecond))
+	}
+	return msg
+}
+
+// printSampler prints achieved sampling rate.
+func printSampler(w io.Writer, s *sampler) error {
+	_, err := fmt.Fprintln(w, s.summary(time.Now()))
+	return err
+}
+
+// sampleLoop takes samples with adaptive frequency until any of configured limits is reached or quit signal is
+// received. Sample function takes a single sample and returns true if profiled backends became idle, it also could
+// return its own reason for stopping profiling. Returned reason is empty when profiling has been interrupted.
+func sampleLoop(cfg Config, doQuit chan os.Signal, sample func() (bool, string, error)) (*sampler, string, error) {
+	t := time.NewTicker(cfg.Frequency)
+	defer t.Stop()
+
+	start := time.Now()
+	smp := newSampler(cfg.Frequency, start)
+
+	for {
+		begin := time.Now()
+		idle, reason, err := sample()
+		if err != nil || reason != "" {
+			return smp, reason, err
+		}
+
+		// Slow down sampling if sampling queries become slow.
+		if smp.observe(time.Since(begin)) {
+			t.Reset(smp.interval)
+		}
+
+		// Stop profiling if any of limits is reached.
+		if reason := cfg.stopReason(time.Since(start), smp.samples, idle); reason != "" {
+			return smp, reason, nil
+		}
+
+		// Wait ticker ticks.
+		select {
+		case <-t.C:
+		case <-doQuit:
+			return smp, "", nil
+		}
+	}
+}
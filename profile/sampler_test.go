@@ -0,0 +1,88 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_sampler_observe(t *testing.T) {
+	s := newSampler(10*time.Millisecond, time.Now())
+
+	// Fast sampling queries don't change the interval.
+	assert.False(t, s.observe(time.Millisecond))
+	assert.False(t, s.observe(2*time.Millisecond))
+	assert.Equal(t, 10*time.Millisecond, s.interval)
+
+	// Slow sampling query increases the interval.
+	s = newSampler(10*time.Millisecond, time.Now())
+	assert.True(t, s.observe(5*time.Millisecond))
+	assert.Equal(t, 20*time.Millisecond, s.interval)
+	assert.Equal(t, 1, s.backoffs)
+
+	// Interval returns back when sampling query becomes fast.
+	for i := 0; i < 50; i++ {
+		s.observe(100 * time.Microsecond)
+	}
+	assert.Equal(t, 10*time.Millisecond, s.interval)
+	assert.Equal(t, 1, s.backoffs)
+	assert.Equal(t, 51, s.samples)
+}
+
+func Test_sampler_summary(t *testing.T) {
+	start := time.Now()
+	s := newSampler(100*time.Millisecond, start)
+	for i := 0; i < 10; i++ {
+		s.observe(time.Millisecond)
+	}
+
+	assert.Equal(t, 0.0, s.rate(start))
+	assert.Equal(t, 5.0, s.rate(start.Add(2*time.Second)))
+	assert.Equal(t, "LOG: Achieved sample rate 5.00/s, requested 10.00/s", s.summary(start.Add(2*time.Second)))
+
+	s.observe(time.Second)
+	assert.True(t, strings.HasPrefix(s.summary(start.Add(2*time.Second)), "LOG: Achieved sample rate 5.50/s, requested 10.00/s, sampling slowed down 1 times"))
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, printSampler(buf, s))
+	assert.True(t, strings.HasPrefix(buf.String(), "LOG: Achieved sample rate"))
+}
+
+func Test_sampleLoop(t *testing.T) {
+	cfg := Config{Frequency: time.Millisecond, MaxSamples: 5, UntilIdle: true}
+	var n int
+
+	// Stop when limit of samples is reached.
+	smp, reason, err := sampleLoop(cfg, nil, func() (bool, string, error) { n++; return false, "", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "5 samples taken", reason)
+	assert.Equal(t, 5, smp.samples)
+	assert.Equal(t, 5, n)
+
+	// Stop when profiled backends became idle.
+	_, reason, err = sampleLoop(cfg, nil, func() (bool, string, error) { return true, "", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "profiled backends became idle", reason)
+
+	// Stop with reason returned by sample function.
+	smp, reason, err = sampleLoop(cfg, nil, func() (bool, string, error) { return false, "process doesn't exist", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "process doesn't exist", reason)
+	assert.Equal(t, 0, smp.samples)
+
+	// Failed sample.
+	_, _, err = sampleLoop(cfg, nil, func() (bool, string, error) { return false, "", fmt.Errorf("failed") })
+	assert.Error(t, err)
+
+	// Interrupted profiling.
+	doQuit := make(chan os.Signal, 1)
+	doQuit <- os.Interrupt
+	smp, reason, err = sampleLoop(Config{Frequency: time.Hour}, doQuit, func() (bool, string, error) { return false, "", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "", reason)
+	assert.Equal(t, 1, smp.samples)
+}
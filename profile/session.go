@@ -0,0 +1,360 @@
+// Aggregation of samples collected during the whole profiling session.
+
+package profile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatText defines human-readable profile printed during profiling.
+	FormatText = "text"
+	// FormatFlamegraph defines profile printed as folded stacks suitable for flamegraph.pl or speedscope.
+	FormatFlamegraph = "flamegraph"
+	// FormatJSON defines profile printed as JSON document.
+	FormatJSON = "json"
+	// FormatCSV defines profile printed as CSV table.
+	FormatCSV = "csv"
+)
+
+// sessionKey defines query and wait event observed in a sample.
+type sessionKey struct {
+	query     string
+	waitEntry string
+}
+
+// backendKey defines backend and wait event observed in a sample.
+type backendKey struct {
+	pid       int
+	waitEntry string
+}
+
+// session defines storage of samples collected during the whole profiling session.
+type session struct {
+	strsize      int
+	perStatement bool     // group samples by normalized query text
+	groupBy      []string // groupings printed at the end of session
+	samples      map[sessionKey]int
+	backends     map[backendKey]int
+	waits        *waitTracker // durations of wait events
+}
+
+// newSession creates new session storage.
+func newSession(strsize int, perStatement bool, groupBy []string) *session {
+	return &session{
+		strsize:      strsize,
+		perStatement: perStatement,
+		groupBy:      groupBy,
+		samples:      map[sessionKey]int{},
+		backends:     map[backendKey]int{},
+		waits:        newWaitTracker(),
+	}
+}
+
+// add accounts a sample of backend's query which is in specified wait event.
+func (s *session) add(pid int, query string, waitEntry string) {
+	s.addSamples(pid, query, waitEntry, 1)
+}
+
+// addSamples accounts specified number of samples of backend's query which is in specified wait event.
+func (s *session) addSamples(pid int, query string, waitEntry string, n int) {
+	waitEntry = waitEntryName(waitEntry)
+	if s.perStatement {
+		query = normalizeQuery(query)
+	}
+	s.samples[sessionKey{query: normalizeFrame(query, s.strsize), waitEntry: waitEntry}] += n
+	s.backends[backendKey{pid: pid, waitEntry: waitEntry}] += n
+}
+
+// observe accounts wait events of all sampled backends, it is used for estimating durations of wait events.
+func (s *session) observe(now time.Time, events map[int]string) {
+	s.waits.observe(now, events)
+}
+
+// textWriter returns writer for human-readable output printed during profiling. The output is discarded when profile
+// is printed in other formats or aggregated per statements.
+func textWriter(w io.Writer, cfg Config) io.Writer {
+	format := cfg.format()
+	if (format == "" || format == FormatText) && !cfg.PerStatement {
+		return w
+	}
+	return ioutil.Discard
+}
+
+// printSession prints samples collected during the session in specified format.
+func printSession(w io.Writer, s *session, format string) error {
+	// Waits which are still in progress are accounted up to now.
+	s.waits.finish(time.Now())
+
+	switch format {
+	case "", FormatText:
+		// Text profile is printed during profiling, only statements profile and groupings are printed at the end.
+		if s.perStatement {
+			err := printStatements(w, s)
+			if err != nil {
+				return err
+			}
+		}
+		for _, g := range s.groupBy {
+			err := printGroup(w, s, g)
+			if err != nil {
+				return err
+			}
+		}
+		return printWaitDurations(w, s.waits)
+	case FormatFlamegraph:
+		return printFlamegraph(w, s)
+	case FormatJSON:
+		return printJSON(w, s)
+	case FormatCSV:
+		return printCSV(w, s)
+	default:
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// printFlamegraph prints samples as folded stacks: query;wait_event_type;wait_event count.
+func printFlamegraph(w io.Writer, s *session) error {
+	lines := make([]string, 0, len(s.samples))
+	for k, v := range s.samples {
+		frames := append([]string{k.query}, strings.SplitN(k.waitEntry, ".", 2)...)
+		lines = append(lines, fmt.Sprintf("%s %d", strings.Join(frames, ";"), v))
+	}
+
+	sort.Strings(lines)
+
+	for _, line := range lines {
+		_, err := fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sessionQuery defines number of samples of a query observed in a particular wait event.
+type sessionQuery struct {
+	Query   string `json:"query"`
+	Samples int    `json:"samples"`
+}
+
+// sessionWaitEvent defines number of samples of a particular wait event and queries observed in the wait event.
+type sessionWaitEvent struct {
+	WaitEvent string         `json:"wait_event"`
+	Samples   int            `json:"samples"`
+	Percent   float64        `json:"percent"`
+	Durations *sessionWaits  `json:"durations,omitempty"`
+	Queries   []sessionQuery `json:"queries"`
+}
+
+// sessionWaits defines distribution of estimated durations of a particular wait event, in seconds.
+type sessionWaits struct {
+	Waits int     `json:"waits"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	Max   float64 `json:"max"`
+}
+
+// sessionReport defines structured representation of samples collected during the session.
+type sessionReport struct {
+	Samples    int                `json:"samples"`
+	WaitEvents []sessionWaitEvent `json:"wait_events"`
+}
+
+// report aggregates samples by wait events. Wait events and queries are sorted by number of samples.
+func (s *session) report() sessionReport {
+	events := map[string]*sessionWaitEvent{}
+	var total int
+
+	for k, v := range s.samples {
+		e, ok := events[k.waitEntry]
+		if !ok {
+			e = &sessionWaitEvent{WaitEvent: k.waitEntry}
+			events[k.waitEntry] = e
+		}
+		e.Samples += v
+		e.Queries = append(e.Queries, sessionQuery{Query: k.query, Samples: v})
+		total += v
+	}
+
+	for _, w := range s.waits.stats() {
+		if e, ok := events[w.waitEntry]; ok {
+			e.Durations = &sessionWaits{Waits: w.waits, P50: w.p50.Seconds(), P95: w.p95.Seconds(), Max: w.max.Seconds()}
+		}
+	}
+
+	r := sessionReport{Samples: total, WaitEvents: make([]sessionWaitEvent, 0, len(events))}
+	for _, e := range events {
+		e.Percent = 100 * float64(e.Samples) / float64(total)
+		sort.Slice(e.Queries, func(i, j int) bool {
+			if e.Queries[i].Samples == e.Queries[j].Samples {
+				return e.Queries[i].Query < e.Queries[j].Query
+			}
+			return e.Queries[i].Samples > e.Queries[j].Samples
+		})
+		r.WaitEvents = append(r.WaitEvents, *e)
+	}
+
+	sort.Slice(r.WaitEvents, func(i, j int) bool {
+		if r.WaitEvents[i].Samples == r.WaitEvents[j].Samples {
+			return r.WaitEvents[i].WaitEvent < r.WaitEvents[j].WaitEvent
+		}
+		return r.WaitEvents[i].Samples > r.WaitEvents[j].Samples
+	})
+
+	return r
+}
+
+// printJSON prints samples aggregated by wait events as JSON document.
+func printJSON(w io.Writer, s *session) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s.report())
+}
+
+// printCSV prints samples aggregated by wait events as CSV table, one row per wait event and query.
+func printCSV(w io.Writer, s *session) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write([]string{"wait_event", "wait_event_samples", "wait_event_percent", "query", "query_samples"})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range s.report().WaitEvents {
+		for _, q := range e.Queries {
+			err := cw.Write([]string{
+				e.WaitEvent, strconv.Itoa(e.Samples), strconv.FormatFloat(e.Percent, 'f', 2, 64), q.Query, strconv.Itoa(q.Samples),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// statementEvent defines number of samples of a statement observed in a particular wait event.
+type statementEvent struct {
+	waitEntry string
+	samples   int
+}
+
+// sessionStatement defines samples of a statement split by wait events.
+type sessionStatement struct {
+	query   string
+	samples int
+	events  []statementEvent // sorted by number of samples
+}
+
+// statements aggregates samples by statements. Statements are sorted by number of samples.
+func (s *session) statements() []sessionStatement {
+	idx := map[string]int{}
+	var statements []sessionStatement
+
+	for k, v := range s.samples {
+		i, ok := idx[k.query]
+		if !ok {
+			i = len(statements)
+			idx[k.query] = i
+			statements = append(statements, sessionStatement{query: k.query})
+		}
+		statements[i].samples += v
+		statements[i].events = append(statements[i].events, statementEvent{waitEntry: k.waitEntry, samples: v})
+	}
+
+	for _, st := range statements {
+		events := st.events
+		sort.Slice(events, func(i, j int) bool {
+			if events[i].samples == events[j].samples {
+				return events[i].waitEntry < events[j].waitEntry
+			}
+			return events[i].samples > events[j].samples
+		})
+	}
+
+	sort.Slice(statements, func(i, j int) bool {
+		if statements[i].samples == statements[j].samples {
+			return statements[i].query < statements[j].query
+		}
+		return statements[i].samples > statements[j].samples
+	})
+
+	return statements
+}
+
+// printStatements prints percent of samples per statement per wait event.
+func printStatements(w io.Writer, s *session) error {
+	statements := s.statements()
+
+	var total int
+	for _, st := range statements {
+		total += st.samples
+	}
+
+	_, err := fmt.Fprintf(w, "LOG: Collected %d samples of %d statements\n", total, len(statements))
+	if err != nil {
+		return err
+	}
+
+	for _, st := range statements {
+		_, err := fmt.Fprintf(w, "------ ------- -------- -----------------------------\n"+
+			"%% stmt %% total  samples wait_event                     statement: %s\n"+
+			"------ ------- -------- -----------------------------\n", st.query)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range st.events {
+			_, err := fmt.Fprintf(w, "%*.2f %*.2f %*d %s\n",
+				6, 100*float64(e.samples)/float64(st.samples), 7, 100*float64(e.samples)/float64(total), 8, e.samples, e.waitEntry)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = fmt.Fprintf(w, "------ ------- -------- -----------------------------\n%*.2f %*.2f %*d\n",
+			6, 100.0, 7, 100*float64(st.samples)/float64(total), 8, st.samples)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryLiteralsRE defines string and numeric literals and query parameters.
+var queryLiteralsRE = regexp.MustCompile(`'(?:[^']|'')*'|\$?\b[0-9]+(?:\.[0-9]+)?\b`)
+
+// normalizeQuery replaces string and numeric literals with placeholders, hence executions of the same statement with
+// different values have the same text. Query parameters ($1, $2, ...) are kept as is.
+func normalizeQuery(query string) string {
+	return queryLiteralsRE.ReplaceAllStringFunc(query, func(s string) string {
+		if strings.HasPrefix(s, "$") {
+			return s
+		}
+		return "?"
+	})
+}
+
+// normalizeFrame makes query text suitable for using as a stack frame: removes frames separators and line breaks,
+// squeezes whitespaces and truncates the text.
+func normalizeFrame(query string, limit int) string {
+	query = strings.Join(strings.Fields(strings.ReplaceAll(query, ";", " ")), " ")
+	if query == "" {
+		return "<unknown>"
+	}
+	return truncateQuery(query, limit)
+}
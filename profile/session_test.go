@@ -0,0 +1,136 @@
+package profile
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func Test_session_add(t *testing.T) {
+	s := newSession(16, false, nil)
+	s.add(1, "SELECT 1", "")
+	s.add(1, "SELECT 1", "")
+	s.add(1, "SELECT 1;\n  SELECT 2", "IO.DataFileRead")
+	s.add(1, "", "Lock.transactionid")
+
+	assert.Equal(t, map[sessionKey]int{
+		{query: "SELECT 1", waitEntry: "Running (on CPU)"}:        2,
+		{query: "SELECT 1 SELECT ", waitEntry: "IO.DataFileRead"}: 1,
+		{query: "<unknown>", waitEntry: "Lock.transactionid"}:     1,
+	}, s.samples)
+}
+
+func Test_textWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Equal(t, buf, textWriter(buf, Config{}))
+	assert.Equal(t, buf, textWriter(buf, Config{Format: FormatText}))
+	assert.Equal(t, ioutil.Discard, textWriter(buf, Config{Format: FormatFlamegraph}))
+	assert.Equal(t, ioutil.Discard, textWriter(buf, Config{Format: FormatText, Output: FormatJSON}))
+	assert.Equal(t, ioutil.Discard, textWriter(buf, Config{PerStatement: true}))
+}
+
+func Test_printSession(t *testing.T) {
+	s := newSession(128, false, nil)
+	s.add(1, "UPDATE t SET v = 1", "")
+	s.add(1, "UPDATE t SET v = 1", "Lock.transactionid")
+	s.add(1, "UPDATE t SET v = 1", "Lock.transactionid")
+	s.add(1, "SELECT 1", "IO.DataFileRead")
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, printSession(buf, s, FormatText))
+	assert.Equal(t, "", buf.String())
+
+	assert.NoError(t, printSession(buf, s, FormatFlamegraph))
+	assert.Equal(t, "SELECT 1;IO;DataFileRead 1\nUPDATE t SET v = 1;Lock;transactionid 2\nUPDATE t SET v = 1;Running (on CPU) 1\n", buf.String())
+
+	assert.Error(t, printSession(buf, s, "invalid"))
+}
+
+func Test_session_report(t *testing.T) {
+	s := newSession(128, false, nil)
+	s.add(1, "UPDATE t SET v = 1", "")
+	s.add(1, "UPDATE t SET v = 1", "Lock.transactionid")
+	s.add(1, "UPDATE t SET v = 1", "Lock.transactionid")
+	s.add(1, "UPDATE t SET v = 2", "Lock.transactionid")
+
+	assert.Equal(t, sessionReport{
+		Samples: 4,
+		WaitEvents: []sessionWaitEvent{
+			{WaitEvent: "Lock.transactionid", Samples: 3, Percent: 75, Queries: []sessionQuery{
+				{Query: "UPDATE t SET v = 1", Samples: 2}, {Query: "UPDATE t SET v = 2", Samples: 1},
+			}},
+			{WaitEvent: "Running (on CPU)", Samples: 1, Percent: 25, Queries: []sessionQuery{
+				{Query: "UPDATE t SET v = 1", Samples: 1},
+			}},
+		},
+	}, s.report())
+
+	assert.Equal(t, sessionReport{WaitEvents: []sessionWaitEvent{}}, newSession(128, false, nil).report())
+}
+
+func Test_printSession_structured(t *testing.T) {
+	s := newSession(128, false, nil)
+	s.add(1, "SELECT 1", "")
+	s.add(1, "SELECT \"a,b\"", "IO.DataFileRead")
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, printSession(buf, s, FormatCSV))
+	assert.Equal(t, "wait_event,wait_event_samples,wait_event_percent,query,query_samples\n"+
+		"IO.DataFileRead,1,50.00,\"SELECT \"\"a,b\"\"\",1\n"+
+		"Running (on CPU),1,50.00,SELECT 1,1\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, printSession(buf, s, FormatJSON))
+	assert.Contains(t, buf.String(), `"samples": 2,`)
+	assert.Contains(t, buf.String(), `"wait_event": "IO.DataFileRead",`)
+	assert.Contains(t, buf.String(), `"percent": 50,`)
+	assert.Contains(t, buf.String(), `"query": "SELECT \"a,b\"",`)
+}
+
+func Test_normalizeQuery(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want string
+	}{
+		{in: "SELECT 1", want: "SELECT ?"},
+		{in: "SELECT * FROM t1 WHERE id = 10 AND v > 1.5", want: "SELECT * FROM t1 WHERE id = ? AND v > ?"},
+		{in: "UPDATE t SET name = 'it''s' WHERE id = $1", want: "UPDATE t SET name = ? WHERE id = $1"},
+		{in: "SELECT now()", want: "SELECT now()"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, normalizeQuery(tc.in))
+	}
+}
+
+func Test_printStatements(t *testing.T) {
+	s := newSession(128, true, nil)
+	s.add(1, "UPDATE t SET v = 1 WHERE id = 1", "Lock.transactionid")
+	s.add(1, "UPDATE t SET v = 2 WHERE id = 2", "Lock.transactionid")
+	s.add(1, "UPDATE t SET v = 3 WHERE id = 3", "")
+	s.add(1, "SELECT 'a'", "IO.DataFileRead")
+
+	statements := s.statements()
+	assert.Len(t, statements, 2)
+	assert.Equal(t, "UPDATE t SET v = ? WHERE id = ?", statements[0].query)
+	assert.Equal(t, 3, statements[0].samples)
+	assert.Equal(t, []statementEvent{{waitEntry: "Lock.transactionid", samples: 2}, {waitEntry: "Running (on CPU)", samples: 1}}, statements[0].events)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, printSession(buf, s, FormatText))
+	assert.Equal(t, "LOG: Collected 4 samples of 2 statements\n"+
+		"------ ------- -------- -----------------------------\n"+
+		"% stmt % total  samples wait_event                     statement: UPDATE t SET v = ? WHERE id = ?\n"+
+		"------ ------- -------- -----------------------------\n"+
+		" 66.67   50.00        2 Lock.transactionid\n"+
+		" 33.33   25.00        1 Running (on CPU)\n"+
+		"------ ------- -------- -----------------------------\n"+
+		"100.00   75.00        3\n"+
+		"------ ------- -------- -----------------------------\n"+
+		"% stmt % total  samples wait_event                     statement: SELECT ?\n"+
+		"------ ------- -------- -----------------------------\n"+
+		"100.00   25.00        1 IO.DataFileRead\n"+
+		"------ ------- -------- -----------------------------\n"+
+		"100.00   25.00        1\n", buf.String())
+}
@@ -0,0 +1,127 @@
+// Estimation of wait events durations using consecutive samples of the same backend.
+
+package profile
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// waitRun defines consecutive samples of a backend observed in the same wait event.
+type waitRun struct {
+	waitEntry string
+	start     time.Time // time when the wait event has been observed first time
+}
+
+// waitTracker tracks wait events of backends between samples and estimates durations of the wait events. Wait is
+// considered finished when the backend is observed in another wait event or is not active anymore. Estimated duration
+// is the time between sample when the wait has been observed first time and sample when it isn't observed anymore,
+// hence precision of estimation is limited by sampling interval.
+type waitTracker struct {
+	runs      map[int]waitRun            // waits in progress, by backends PIDs
+	durations map[string][]time.Duration // durations of finished waits, by wait events
+}
+
+// newWaitTracker creates new wait events tracker.
+func newWaitTracker() *waitTracker {
+	return &waitTracker{runs: map[int]waitRun{}, durations: map[string][]time.Duration{}}
+}
+
+// observe accounts wait events of backends observed in a sample taken at specified time. Backends which are absent
+// in the sample are considered as not active.
+func (t *waitTracker) observe(now time.Time, events map[int]string) {
+	for pid, r := range t.runs {
+		if e, ok := events[pid]; !ok || e != r.waitEntry {
+			t.durations[r.waitEntry] = append(t.durations[r.waitEntry], now.Sub(r.start))
+			delete(t.runs, pid)
+		}
+	}
+
+	for pid, e := range events {
+		if _, ok := t.runs[pid]; !ok {
+			t.runs[pid] = waitRun{waitEntry: e, start: now}
+		}
+	}
+}
+
+// finish finishes all waits in progress at specified time.
+func (t *waitTracker) finish(now time.Time) {
+	t.observe(now, nil)
+}
+
+// waitStat defines distribution of durations of a wait event.
+type waitStat struct {
+	waitEntry string
+	waits     int
+	total     time.Duration
+	p50       time.Duration
+	p95       time.Duration
+	max       time.Duration
+}
+
+// stats returns distribution of durations of finished waits, sorted by total duration.
+func (t *waitTracker) stats() []waitStat {
+	res := make([]waitStat, 0, len(t.durations))
+
+	for k, v := range t.durations {
+		d := make([]time.Duration, len(v))
+		copy(d, v)
+		sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+
+		s := waitStat{waitEntry: k, waits: len(d), p50: percentile(d, 0.50), p95: percentile(d, 0.95), max: d[len(d)-1]}
+		for _, x := range d {
+			s.total += x
+		}
+		res = append(res, s)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].total == res[j].total {
+			return res[i].waitEntry < res[j].waitEntry
+		}
+		return res[i].total > res[j].total
+	})
+
+	return res
+}
+
+// percentile returns specified percentile of sorted durations using nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i]
+}
+
+// printWaitDurations prints distribution of wait events durations.
+func printWaitDurations(w io.Writer, t *waitTracker) error {
+	stats := t.stats()
+	if len(stats) == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "------- ------------ ------------ ------------ -----------------------------\n"+
+		"  waits      p50 (s)      p95 (s)      max (s) wait_event\n"+
+		"------- ------------ ------------ ------------ -----------------------------\n")
+	if err != nil {
+		return err
+	}
+
+	for _, s := range stats {
+		_, err := fmt.Fprintf(w, "%*d %*.6f %*.6f %*.6f %s\n", 7, s.waits, 12, s.p50.Seconds(), 12, s.p95.Seconds(), 12, s.max.Seconds(), s.waitEntry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
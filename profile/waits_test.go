@@ -0,0 +1,70 @@
+package profile
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_waitTracker(t *testing.T) {
+	ts := time.Date(2021, 3, 14, 15, 0, 0, 0, time.UTC)
+	sec := func(n int) time.Time { return ts.Add(time.Duration(n) * time.Second) }
+
+	w := newWaitTracker()
+	w.observe(sec(0), map[int]string{1: "IO.DataFileRead", 2: "Lock.tuple"})
+	w.observe(sec(1), map[int]string{1: "IO.DataFileRead", 2: "Lock.tuple"})
+	w.observe(sec(2), map[int]string{1: runningEntry, 2: "Lock.tuple"}) // pid 1 finished IO wait after 2 seconds
+	w.observe(sec(3), map[int]string{1: "IO.DataFileRead"})             // pid 2 finished lock wait after 3 seconds
+	w.observe(sec(4), map[int]string{1: "IO.DataFileRead", 2: "Lock.tuple"})
+	w.finish(sec(5))
+
+	assert.Equal(t, map[string][]time.Duration{
+		"IO.DataFileRead": {2 * time.Second, 2 * time.Second},
+		runningEntry:      {time.Second},
+		"Lock.tuple":      {3 * time.Second, time.Second},
+	}, w.durations)
+	assert.Len(t, w.runs, 0)
+
+	assert.Equal(t, []waitStat{
+		{waitEntry: "IO.DataFileRead", waits: 2, total: 4 * time.Second, p50: 2 * time.Second, p95: 2 * time.Second, max: 2 * time.Second},
+		{waitEntry: "Lock.tuple", waits: 2, total: 4 * time.Second, p50: time.Second, p95: 3 * time.Second, max: 3 * time.Second},
+		{waitEntry: runningEntry, waits: 1, total: time.Second, p50: time.Second, p95: time.Second, max: time.Second},
+	}, w.stats())
+
+	var buf bytes.Buffer
+	assert.NoError(t, printWaitDurations(&buf, w))
+	assert.Equal(t, "------- ------------ ------------ ------------ -----------------------------\n"+
+		"  waits      p50 (s)      p95 (s)      max (s) wait_event\n"+
+		"------- ------------ ------------ ------------ -----------------------------\n"+
+		"      2     2.000000     2.000000     2.000000 IO.DataFileRead\n"+
+		"      2     1.000000     3.000000     3.000000 Lock.tuple\n"+
+		"      1     1.000000     1.000000     1.000000 Running (on CPU)\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, printWaitDurations(&buf, newWaitTracker()))
+	assert.Equal(t, "", buf.String())
+}
+
+func Test_percentile(t *testing.T) {
+	d := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	assert.Equal(t, time.Duration(5), percentile(d, 0.50))
+	assert.Equal(t, time.Duration(10), percentile(d, 0.95))
+	assert.Equal(t, time.Duration(1), percentile(d, 0))
+	assert.Equal(t, time.Duration(0), percentile(nil, 0.50))
+}
+
+func Test_session_report_durations(t *testing.T) {
+	ts := time.Date(2021, 3, 14, 15, 0, 0, 0, time.UTC)
+
+	s := newSession(128, false, nil)
+	s.add(1, "SELECT 1", "IO.DataFileRead")
+	s.observe(ts, map[int]string{1: "IO.DataFileRead"})
+	s.add(1, "SELECT 1", "")
+	s.observe(ts.Add(time.Second), map[int]string{1: runningEntry})
+	s.observe(ts.Add(2*time.Second), nil)
+
+	r := s.report()
+	assert.Equal(t, &sessionWaits{Waits: 1, P50: 1, P95: 1, Max: 1}, r.WaitEvents[0].Durations)
+	assert.Equal(t, &sessionWaits{Waits: 1, P50: 1, P95: 1, Max: 1}, r.WaitEvents[1].Durations)
+}
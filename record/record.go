@@ -3,50 +3,115 @@
 package record
 
 import (
+	"context"
 	"fmt"
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/interrupt"
+	"github.com/lesovsky/pgcenter/internal/plugin"
 	"github.com/lesovsky/pgcenter/internal/postgres"
-	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/push"
 	"github.com/lesovsky/pgcenter/internal/stat"
 	"github.com/lesovsky/pgcenter/internal/view"
 	"os"
-	"os/signal"
 	"time"
 )
 
+// errInterrupted is returned when recording is interrupted by user.
+var errInterrupted = fmt.Errorf("got %s", os.Interrupt)
+
 // Config defines config container for configuring 'pgcenter record'.
 type Config struct {
-	Interval    time.Duration // Statistics recording interval
-	Count       int           // Number of statistics snapshot to record
-	OutputFile  string        // File where statistics will be saved
-	AppendFile  bool          // Append data to file
-	StringLimit int           // Limit of the length, to which query should be trimmed
+	Interval    time.Duration     // Statistics recording interval
+	Count       int               // Number of statistics snapshot to record
+	OutputFile  string            // File where statistics will be saved
+	AppendFile  bool              // Append data to file
+	StringLimit int               // Limit of the length, to which query should be trimmed
+	Alerts      alert.Config      // Alert rules evaluated during recording
+	Plugins     []plugin.Config   // External collectors which stats are recorded with built-in stats
+	Hooks       []hook.Config     // User commands run when alerts fire and resolve
+	Push        push.Config       // Pushing stats rates to external storages during recording
+	Devices     stat.FilterConfig // Patterns of block devices and network interfaces which stats are skipped by alerts and push
 }
 
 // RunMain is the 'pgcenter record' main entry point.
 func RunMain(dbConfig postgres.Config, config Config) error {
+	filter, err := stat.NewFilter(config.Devices)
+	if err != nil {
+		return err
+	}
+
 	app := newApp(config, dbConfig)
 
-	err := app.setup()
+	err = app.setup()
 	if err != nil {
 		return err
 	}
+	defer app.close()
+
+	app.sampler.SetFilter(filter)
 
 	fmt.Printf("INFO: recording to %s\n", config.OutputFile)
 
-	// In case of SIGINT stop program gracefully
-	doQuit := make(chan os.Signal, 1)
-	signal.Notify(doQuit, os.Interrupt)
+	// In case of SIGINT stop program gracefully, collecting in flight is cancelled.
+	ctx, cancel := interrupt.Context(context.Background(), os.Interrupt)
+	defer cancel()
+
+	// Alert rules are evaluated using stats collected for recording, stats used by rules are collected along with them.
+	if config.Alerts.Enabled() {
+		monitor, err := alert.NewMonitor(config.Alerts, func(format string, a ...interface{}) {
+			fmt.Printf("ERROR: "+format+"\n", a...)
+		})
+		if err != nil {
+			return err
+		}
+
+		err = monitor.Configure(app.views)
+		if err != nil {
+			return err
+		}
+
+		hooks, err := hook.NewRunner(config.Hooks, func(format string, a ...interface{}) {
+			fmt.Printf("ERROR: "+format+"\n", a...)
+		})
+		if err != nil {
+			return err
+		}
+		monitor.SetHooks(hooks)
+		monitor.SetInstance(dbConfig)
+		monitor.Require(app.sampler)
+
+		app.alerts = monitor
+		defer monitor.Wait()
+	}
+
+	// Push stats rates in background during recording.
+	if config.Push.Enabled() {
+		pusher, err := push.NewPusher(config.Push, func(format string, a ...interface{}) {
+			fmt.Printf("ERROR: "+format+"\n", a...)
+		})
+		if err != nil {
+			return err
+		}
+
+		pusher.SetFilter(filter)
+
+		go pusher.Run(ctx, dbConfig)
+	}
 
 	// Run recording loop
-	return app.record(doQuit)
+	return app.record(ctx)
 }
 
 // app defines 'pgcenter record' runtime dependencies.
 type app struct {
 	config   Config
 	dbConfig postgres.Config
+	db       *postgres.DB
+	sampler  *stat.Sampler
 	views    view.Views
 	recorder recorder
+	alerts   *alert.Monitor // evaluates alert rules using collected stats, nil if alerts are not configured
 }
 
 // newApp creates new 'pgcenter record' app.
@@ -57,29 +122,45 @@ func newApp(config Config, dbConfig postgres.Config) *app {
 	}
 }
 
-// setup configures necessary queries depending on Postgres version.
+// setup connects to Postgres and configures necessary queries depending on Postgres version. Connection is kept
+// for recording.
 func (app *app) setup() error {
 	db, err := postgres.Connect(app.dbConfig)
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
-	props, err := stat.GetPostgresProperties(db)
+	sampler, err := stat.NewSampler(db, app.config.Interval)
 	if err != nil {
+		db.Close()
 		return err
 	}
 
+	props := sampler.Properties()
+
+	if msg := stat.CheckStatSchemaVersion(props); msg != "" {
+		fmt.Println(msg)
+	}
+
 	// Create and configure stats views depending on running Postgres.
-	opts := query.NewOptions(props.VersionNum, props.Recovery, props.GucTrackCommitTimestamp, app.config.StringLimit)
+	opts := props.QueryOptions(app.config.StringLimit)
 
 	views := view.New()
 	err = views.Configure(opts)
+	if err == nil {
+		err = plugin.AddViews(views, app.config.Plugins)
+	}
 	if err != nil {
+		db.Close()
 		return err
 	}
 
-	app.views = views
+	// Current values of views are recorded, rates are not necessary.
+	for _, v := range views {
+		sampler.AddView(v, false)
+	}
+
+	app.db, app.sampler, app.views = db, sampler, views
 
 	// Create tar recorder.
 	app.recorder = newTarRecorder(tarConfig{
@@ -90,14 +171,20 @@ func (app *app) setup() error {
 	return nil
 }
 
-// record collects statistics and stores into file.
-func (app *app) record(doQuit chan os.Signal) error {
+// close closes connection to Postgres.
+func (app *app) close() {
+	app.db.Close()
+}
+
+// record collects statistics and stores into file. Collected stats are passed to alerts monitor, if it's configured.
+func (app *app) record(ctx context.Context) error {
 	var (
 		count    = app.config.Count
 		interval = app.config.Interval
 	)
 
 	t := time.NewTicker(interval)
+	defer t.Stop()
 
 	// record the number of snapshots requested by user (or record continuously until SIGINT will be received)
 	var n int
@@ -108,12 +195,24 @@ func (app *app) record(doQuit chan os.Signal) error {
 			n++
 		}
 
-		err := app.recorder.open()
+		// Stats collected partially due to interrupt are not recorded.
+		sample, err := app.sampler.Collect(ctx)
+		if ctx.Err() != nil {
+			return errInterrupted
+		}
+		if app.alerts != nil {
+			app.alerts.Evaluate(sample, err)
+		}
+		if err != nil {
+			return err
+		}
+
+		stats, err := app.recorder.collect(sample, app.views)
 		if err != nil {
 			return err
 		}
 
-		stats, err := app.recorder.collect(app.dbConfig, app.views)
+		err = app.recorder.open()
 		if err != nil {
 			return err
 		}
@@ -131,9 +230,8 @@ func (app *app) record(doQuit chan os.Signal) error {
 		select {
 		case <-t.C:
 			continue
-		case sig := <-doQuit:
-			t.Stop()
-			return fmt.Errorf("got %s", sig.String())
+		case <-ctx.Done():
+			return errInterrupted
 		}
 	}
 
@@ -2,12 +2,13 @@ package record
 
 import (
 	"archive/tar"
+	"context"
+	"github.com/lesovsky/pgcenter/internal/alert"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/view"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"testing"
 	"time"
@@ -20,6 +21,7 @@ func Test_app_setup(t *testing.T) {
 	app := newApp(Config{OutputFile: "/tmp/pgcenter-record-testing.stat.tar"}, dbconfig)
 
 	assert.NoError(t, app.setup())
+	defer app.close()
 
 	assert.NotNil(t, app.views)          // views must not be nil
 	assert.Greater(t, len(app.views), 0) // views must contains view objects
@@ -59,10 +61,6 @@ func Test_app_record(t *testing.T) {
 		},
 	}
 
-	// initial stuff
-	doQuit := make(chan os.Signal, 1)
-	signal.Notify(doQuit, os.Interrupt)
-
 	dbconfig, err := postgres.NewTestConfig()
 	assert.NoError(t, err)
 
@@ -70,8 +68,9 @@ func Test_app_record(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			app := newApp(tc.config, dbconfig)
 			assert.NoError(t, app.setup())
+			defer app.close()
 
-			assert.NoError(t, app.record(doQuit))
+			assert.NoError(t, app.record(context.Background()))
 
 			// Read written stats.
 			f, err := os.Open(filepath.Clean(filename))
@@ -93,4 +92,45 @@ func Test_app_record(t *testing.T) {
 		})
 	}
 	assert.NoError(t, os.Remove(filename))
+
+	// Interrupted recording doesn't record partially collected stats.
+	app := newApp(Config{Count: count, Interval: itv, OutputFile: filename}, dbconfig)
+	assert.NoError(t, app.setup())
+	defer app.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, errInterrupted, app.record(ctx))
+	_, err = os.Stat(filename)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_app_record_alerts(t *testing.T) {
+	filename := "/tmp/pgcenter-record-testing.stat.tar"
+
+	dbconfig, err := postgres.NewTestConfig()
+	assert.NoError(t, err)
+
+	// Record itself registers views without rates, rules based on rates request them.
+	app := newApp(Config{Count: 2, Interval: time.Second, OutputFile: filename}, dbconfig)
+	assert.NoError(t, app.setup())
+	defer app.close()
+
+	monitor, err := alert.NewMonitor(alert.Config{Rules: []alert.Rule{
+		{Name: "commits", View: "databases", Metric: "commits", Condition: ">= 0"},
+	}}, t.Logf)
+	assert.NoError(t, err)
+	assert.NoError(t, monitor.Configure(app.views))
+	monitor.Require(app.sampler)
+	app.alerts = monitor
+
+	assert.NoError(t, app.record(context.Background()))
+	monitor.Wait()
+
+	firing := monitor.Firing()
+	assert.Greater(t, len(firing), 0)
+	for _, a := range firing {
+		assert.Equal(t, "commits", a.Rule)
+	}
+	assert.NoError(t, os.Remove(filename))
 }
@@ -4,7 +4,7 @@ import (
 	"archive/tar"
 	"encoding/json"
 	"fmt"
-	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/log"
 	"github.com/lesovsky/pgcenter/internal/stat"
 	"github.com/lesovsky/pgcenter/internal/view"
 	"io"
@@ -16,8 +16,8 @@ import (
 // recorder defines a way of how to record and store collected stats.
 type recorder interface {
 	open() error
-	collect(dbConfig postgres.Config, views view.Views) (map[string]stat.PGresult, error)
-	write(map[string]stat.PGresult) error
+	collect(s stat.Sample, views view.Views) (map[string]stat.Snapshot, error)
+	write(map[string]stat.Snapshot) error
 	close() error
 }
 
@@ -90,29 +90,29 @@ func (c *tarRecorder) open() error {
 	return nil
 }
 
-// collect connects to Postgres, collects and returns stats data.
-func (c *tarRecorder) collect(dbConfig postgres.Config, views view.Views) (map[string]stat.PGresult, error) {
-	db, err := postgres.Connect(dbConfig)
-	if err != nil {
-		return nil, err
-	}
-
-	stats := map[string]stat.PGresult{}
+// collect returns stats snapshots of views taken from collected sample.
+func (c *tarRecorder) collect(s stat.Sample, views view.Views) (map[string]stat.Snapshot, error) {
+	stats := map[string]stat.Snapshot{}
 
 	for k, v := range views {
-		res, err := stat.NewPGresult(db, v.Query)
-		if err != nil {
-			return nil, err
+		vs := s.Views[k]
+		if vs.Err != nil {
+			// Failed plugin doesn't stop recording of other stats.
+			if v.Plugin != nil {
+				log.Warn("skip recording", "view", k, "error", vs.Err)
+				continue
+			}
+			return nil, vs.Err
 		}
 
-		stats[k] = res
+		stats[k] = stat.NewSnapshot(vs.Current, v)
 	}
 
 	return stats, nil
 }
 
-// write accepts stats data and writes it into tar archive.
-func (c *tarRecorder) write(stats map[string]stat.PGresult) error {
+// write accepts stats snapshots and writes it into tar archive.
+func (c *tarRecorder) write(stats map[string]stat.Snapshot) error {
 	for name, v := range stats {
 		data, err := json.Marshal(v)
 		if err != nil {
@@ -140,7 +140,7 @@ func (c *tarRecorder) close() error {
 	if c.writer != nil {
 		err := c.writer.Close()
 		if err != nil {
-			fmt.Printf("closing tar file failed: %s, continue", err)
+			log.Warn("close tar writer failed", "error", err)
 		}
 	}
 
@@ -2,8 +2,10 @@ package record
 
 import (
 	"archive/tar"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/query"
 	"github.com/lesovsky/pgcenter/internal/stat"
@@ -13,6 +15,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func Test_tarRecorder_open_close(t *testing.T) {
@@ -32,19 +35,23 @@ func Test_tarRecorder(t *testing.T) {
 	// create and configure views
 	db, err := postgres.NewTestConnect()
 	assert.NoError(t, err)
-	props, err := stat.GetPostgresProperties(db)
+	defer db.Close()
+	sampler, err := stat.NewSampler(db, time.Second)
 	assert.NoError(t, err)
+	props := sampler.Properties()
 	views := view.New()
 	opts := query.NewOptions(props.VersionNum, props.Recovery, props.GucTrackCommitTimestamp, 0)
 	assert.NoError(t, views.Configure(opts))
-	db.Close()
+	for _, v := range views {
+		sampler.AddView(v, false)
+	}
 
-	// create postgres config
-	dbConfig, err := postgres.NewTestConfig()
+	sample, err := sampler.Collect(context.Background())
 	assert.NoError(t, err)
-	stats, err := tc.collect(dbConfig, views)
+	stats, err := tc.collect(sample, views)
 	assert.NoError(t, err)
 	assert.NotNil(t, stats)
+	assert.Len(t, stats, len(views))
 
 	// check all stats have filled columns
 	for _, s := range stats {
@@ -54,9 +61,30 @@ func Test_tarRecorder(t *testing.T) {
 	assert.NoError(t, tc.close())
 }
 
+func Test_tarRecorder_collect(t *testing.T) {
+	tc := newTarRecorder(tarConfig{})
+	res := stat.PGresult{Valid: true, Ncols: 1, Nrows: 1, Cols: []string{"col1"}, Values: [][]sql.NullString{{{String: "alfa", Valid: true}}}}
+	databases := view.View{Name: "databases"}
+	plugin := view.View{Name: "plugin", Plugin: &view.Plugin{}}
+
+	// Failed plugin is not recorded.
+	stats, err := tc.collect(stat.Sample{Views: map[string]stat.ViewSample{
+		"databases": {Current: res, Result: stat.PGresult{}},
+		"plugin":    {Err: fmt.Errorf("plugin failed")},
+	}}, view.Views{"databases": databases, "plugin": plugin})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]stat.Snapshot{"databases": stat.NewSnapshot(res, databases)}, stats)
+
+	// Failed view fails recording.
+	_, err = tc.collect(stat.Sample{Views: map[string]stat.ViewSample{
+		"databases": {Err: fmt.Errorf("collect databases stats failed")},
+	}}, view.Views{"databases": databases})
+	assert.Error(t, err)
+}
+
 func Test_tarRecorder_write(t *testing.T) {
-	stats := map[string]stat.PGresult{
-		"pgcenter_record_testing": {
+	stats := map[string]stat.Snapshot{
+		"pgcenter_record_testing": stat.NewSnapshot(stat.PGresult{
 			Valid: true, Ncols: 2, Nrows: 4, Cols: []string{"col1", "col2"},
 			Values: [][]sql.NullString{
 				{{String: "alfa", Valid: true}, {String: "12.06157", Valid: true}},
@@ -64,7 +92,7 @@ func Test_tarRecorder_write(t *testing.T) {
 				{{String: "charli", Valid: true}, {String: "18.126", Valid: true}},
 				{{String: "delta", Valid: true}, {String: "137.176", Valid: true}},
 			},
-		},
+		}, view.View{DiffIntvl: [2]int{1, 1}}),
 	}
 
 	filename := "/tmp/pgcenter-record-testing.stat.tar"
@@ -86,9 +114,14 @@ func Test_tarRecorder_write(t *testing.T) {
 	data := make([]byte, hdr.Size) // make data buffer
 	_, err = io.ReadFull(tr, data) // read data from tar to buffer
 	assert.NoError(t, err)
-	got := stat.PGresult{}
+	got := stat.Snapshot{}
 	assert.NoError(t, json.Unmarshal(data, &got))                                    // unmarshal to JSON
-	assert.Equal(t, stats, map[string]stat.PGresult{"pgcenter_record_testing": got}) // compare unmarshalled with origin
+	assert.Equal(t, stats, map[string]stat.Snapshot{"pgcenter_record_testing": got}) // compare unmarshalled with origin
+
+	// Snapshot is readable as plain stats by older versions.
+	plain := stat.PGresult{}
+	assert.NoError(t, json.Unmarshal(data, &plain))
+	assert.Equal(t, stats["pgcenter_record_testing"].PGresult, plain)
 
 	// Cleanup.
 	assert.NoError(t, os.Remove(filename))
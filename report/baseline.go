@@ -0,0 +1,61 @@
+package report
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/baseline"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// doBaseline derives baseline from stats file and saves it into baseline file.
+func doBaseline(w io.Writer, c Config) error {
+	base, err := buildBaseline(c)
+	if err != nil {
+		return err
+	}
+
+	err = base.Save(c.BaselineFile)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(base.Views))
+	for name := range base.Views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	_, err = fmt.Fprintf(w, "INFO: baseline of %s from %s to %s saved into %s: %s\n",
+		c.InputFile, base.Start.Format("2006-01-02 15:04:05"), base.End.Format("2006-01-02 15:04:05"), c.BaselineFile, strings.Join(names, ", "),
+	)
+	return err
+}
+
+// buildBaseline reads stats from file and calculates rates of stats over the requested interval.
+func buildBaseline(c Config) (baseline.Baseline, error) {
+	b := baseline.NewBuilder()
+	views := view.New()
+
+	err := walkStatFile(c, func(name string, ts time.Time, snap stat.Snapshot) error {
+		// Rates are calculated using layout of columns of snapshots, it could differ from layout of current views.
+		if v, ok := views[name]; ok {
+			layout, err := snapshotLayout(name, snap, v)
+			if err != nil {
+				return err
+			}
+			views[name] = layout.Apply(v)
+		}
+
+		b.Add(name, ts, snap.PGresult)
+		return nil
+	})
+	if err != nil {
+		return baseline.Baseline{}, err
+	}
+
+	return b.Build(c.InputFile, views)
+}
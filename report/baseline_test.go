@@ -0,0 +1,36 @@
+package report
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_doBaseline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-report-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	c := Config{InputFile: "testdata/pgcenter.stat.golden.tar", TsEnd: time.Now(), BaselineFile: filepath.Join(dir, "baseline.json")}
+
+	var buf bytes.Buffer
+	assert.NoError(t, doBaseline(&buf, c))
+	assert.Contains(t, buf.String(), "databases")
+	assert.NotContains(t, buf.String(), "activity")
+
+	info, err := os.Stat(c.BaselineFile)
+	assert.NoError(t, err)
+	assert.NotZero(t, info.Size())
+
+	// Only one snapshot in the interval.
+	c.TsStart, c.TsEnd = time.Date(2021, 1, 23, 15, 31, 23, 0, time.Local), time.Date(2021, 1, 23, 15, 31, 23, 0, time.Local)
+	assert.Error(t, doBaseline(&buf, c))
+
+	// Invalid stats file.
+	c = Config{InputFile: "testdata/not-exists.tar", TsEnd: time.Now(), BaselineFile: filepath.Join(dir, "baseline.json")}
+	assert.Error(t, doBaseline(&buf, c))
+}
@@ -20,6 +20,7 @@ const (
 - conflicts	conflicts	Number of queries canceled due to conflicts with recovery in this database. (Conflicts
 				occur only on standby servers; see pg_stat_database_conflicts for details.)
 - deadlocks	deadlocks	Number of deadlocks detected in this database
+- csum_fails	checksum_failures	Number of data page checksum failures detected in this database (Postgres 12+)
 - temp_files	temp_files	Number of temporary files created by queries in this database. All temporary files are 
 				counted, regardless of why the temporary file was created (e.g., sorting or hashing), 
 				and regardless of the log_temp_files setting.
@@ -28,6 +29,13 @@ const (
 				regardless of the log_temp_files setting.
 - read_t	blk_read_time	Time spent reading data file blocks by backends in this database, in milliseconds
 - write_t	blk_write_time	Time spent writing data file blocks by backends in this database, in milliseconds
+- hit_%*	blks_hit,blks_read	Percent of disk blocks found in the buffer cache since stats reset
+- read_lat*	blk_read_time,blks_read	Average time of reading a block since stats reset, in milliseconds (requires track_io_timing)
+- idle_xacts*	pg_stat_activity	Number of backends currently idle in transaction in this database
+- sessions	sessions	Total number of sessions established to this database (Postgres 14+)
+- active_%*	active_time,session_time	Percent of sessions time spent executing statements (Postgres 14+)
+- idle_xact_%*	idle_in_transaction_time,session_time	Percent of sessions time spent idle in transaction (Postgres 14+)
+- sess_lost*	sessions_abandoned,sessions_fatal,sessions_killed	Number of sessions terminated by lost client connection, fatal errors or operator intervention (Postgres 14+)
 - stats_age*	stats_reset	Age of collected statistics in the moment when stats are taken from this database
 
 * - extended value, based on origin and calculated using additional functions.
@@ -95,6 +103,29 @@ Details: https://www.postgresql.org/docs/current/monitoring-stats.html#PG-STAT-A
          https://www.postgresql.org/docs/current/monitoring-stats.html#PG-STATIO-ALL-TABLES-VIEW
 `
 
+	// pgStatTablesIODescription is the detailed description of pg_statio_all_tables view
+	pgStatTablesIODescription = `Tables' IO statistics based on pg_statio_all_tables view:
+
+  column	origin			description
+- relation	schemaname,relname	Name of the table, including schema
+- reads*	heap_blks_read,idx_blks_read,toast_blks_read,tidx_blks_read	Amount of data have been read from disk by this table, its indexes and TOAST, in kB
+- heap_read*	heap_blks_read		Amount of data have been read from this table, in kB
+- heap_hit	heap_blks_hit		Number of buffer hits in this table
+- idx_read*	idx_blks_read		Amount of data have been read from all indexes on this table, in kB
+- idx_hit	idx_blks_hit		Number of buffer hits in all indexes on this table
+- toast_read*	toast_blks_read		Amount of data have been read from this table's TOAST table (if any), in kB
+- toast_hit	toast_blks_hit		Number of buffer hits in this table's TOAST table (if any)
+- tidx_read*	tidx_blks_read		Amount of data have been read from this table's TOAST table indexes (if any), in kB
+- tidx_hit	tidx_blks_hit		Number of buffer hits in this table's TOAST table indexes (if any)
+- heap_hit_%*	heap_blks_hit,heap_blks_read	Percent of this table's blocks found in the buffer cache since stats reset
+- idx_hit_%*	idx_blks_hit,idx_blks_read	Percent of indexes' blocks found in the buffer cache since stats reset
+- hit_%*	*_blks_hit,*_blks_read	Percent of blocks of this table, its indexes and TOAST found in the buffer cache since stats reset
+
+* - extended value, based on origin and calculated using additional functions.
+
+Details: https://www.postgresql.org/docs/current/monitoring-stats.html#PG-STATIO-ALL-TABLES-VIEW
+`
+
 	// pgStatIndexesDescription is the detailed description of pg_stat_all_indexes and pg_statio_all_indexes views
 	pgStatIndexesDescription = `Indexes' statistics based on pg_stat_all_indexes and pg_statio_all_indexes views:
 
@@ -145,6 +176,45 @@ Details: https://www.postgresql.org/docs/current/monitoring-stats.html#PG-STAT-U
 * - extended value, based on origin and calculated using additional functions.
 
 Details: https://www.postgresql.org/docs/current/functions-admin.html#FUNCTIONS-ADMIN-DBOBJECT
+`
+
+	// pgRolesDescription is the detailed description of roles stats
+	pgRolesDescription = `Statistics about roles and their connections based on pg_roles and pg_stat_activity views:
+
+  column	origin			description
+- role		rolname			Name of the role
+- super		rolsuper		Role has superuser privileges
+- login		rolcanlogin		Role can log in
+- conns*	pg_stat_activity	Number of client connections established by the role
+- conn_limit	rolconnlimit		Maximum number of concurrent connections of the role, -1 means no limit
+- conns_%*	rolconnlimit		Percent of the role's connection limit used by its connections
+- active*	pg_stat_activity	Number of connections of the role executing queries
+- idle_xact*	pg_stat_activity	Number of connections of the role idle in transaction
+- valid_until	rolvaliduntil		Password expiry time of the role
+- expire_days*	rolvaliduntil		Number of days until the role's password expires
+
+* - extended value, based on origin and calculated using additional functions.
+
+Details: https://www.postgresql.org/docs/current/view-pg-roles.html
+`
+
+	// pgStatCheckpointsDescription is the detailed description of checkpoints stats
+	pgStatCheckpointsDescription = `Statistics about checkpoints based on pg_stat_bgwriter (pg_stat_checkpointer since Postgres 17) view and pg_control_checkpoint() function:
+
+  column	origin			description
+- max_wal_size	max_wal_size		Value of max_wal_size setting
+- timeout	checkpoint_timeout	Value of checkpoint_timeout setting
+- timed		checkpoints_timed	Number of scheduled checkpoints that have been performed
+- requested	checkpoints_req		Number of requested checkpoints that have been performed
+- req_%*	checkpoints_timed,checkpoints_req	Percent of requested checkpoints, high values mean max_wal_size is too small
+- avg_write*	checkpoint_write_time	Average time spent in writing files to disk per checkpoint
+- avg_sync*	checkpoint_sync_time	Average time spent in synchronizing files to disk per checkpoint
+- last_age*	checkpoint_time		Time since the last checkpoint
+- timeout_used_%*	checkpoint_time,checkpoint_timeout	Percent of checkpoint_timeout elapsed since the last checkpoint
+
+* - extended value, based on origin and calculated using additional functions.
+
+Details: https://www.postgresql.org/docs/current/monitoring-stats.html#MONITORING-PG-STAT-BGWRITER-VIEW
 `
 
 	// pgStatActivityDescription is the detailed description of pg_stat_activity view
@@ -187,6 +257,11 @@ Details: https://www.postgresql.org/docs/current/monitoring-stats.html#PG-STAT-A
 - t_vacuumed_%*	heap_blks_vacuumed	The percent of data vacuumed, in kB
 - scanned	heap_blks_scanned	Amount of data scanned per interval, in kB
 - vacuumed	heap_blks_vacuumed	Amount of data vacuumed per interval, in kB
+- idx_cycles	index_vacuum_count	Number of completed index vacuum cycles
+- pages*	heap_blks_scanned,heap_blks_vacuumed	Number of heap pages processed per interval in the current phase
+- pages_left*	heap_blks_total,heap_blks_scanned,heap_blks_vacuumed	Number of heap pages left to process in the current phase
+- dead_tup*	num_dead_tuples		Number of dead tuples collected per interval
+- eta*		heap_blks_total,heap_blks_scanned,heap_blks_vacuumed	Estimated time left until scanning or vacuuming heap is finished
 - query		query			Text of this workers's "query"
 
 * - extended value, based on origin and calculated using additional functions.
@@ -231,9 +306,14 @@ Details: https://www.postgresql.org/docs/current/progress-reporting.html#CLUSTER
 - phase			phase				Current processing phase of operation
 - locker_pid		current_locker_pid		Process ID of the locker currently being waited for
 - lockers*		lockers_total,lockers_done	Total number of lockers to wait for, and number of lockers already waited for.
+- locker_age*		xact_start			Current transaction's duration of the locker currently being waited for
+- locker_state		state				Current overall state of the locker currently being waited for
 - size_total/done_%*	blocks_total,blocks_done	Total size to be processed and percent of already processed in the current phase, in kB
 - tup_total/done_%*	tuples_total,tuples_done	Total number of tuples to be processed and percent of already processed in the current phase
 - parts_total/done_%*	partitions_total,partitions_done	Total number of partitions on which the index is to be created, and the number of partitions on which the index has been completed
+- pages*		blocks_done			Number of blocks processed per interval in the current phase
+- pages_left*		blocks_total,blocks_done	Number of blocks left to process in the current phase
+- eta*			blocks_total,blocks_done	Estimated time left until scanning table is finished
 - query			query				Text of this workers's "query"
 
 * - extended value, based on origin and calculated using additional functions.
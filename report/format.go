@@ -0,0 +1,94 @@
+package report
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"strings"
+)
+
+// legacyLayout defines columns of a view recorded by versions of pgcenter which didn't keep layout of columns in
+// snapshots. Columns are referenced by names, because their positions depend on versions of pgcenter and Postgres.
+type legacyLayout struct {
+	first string // the first diffed column
+	last  string // the last diffed column
+	key   string // column used as unique key of rows
+}
+
+// legacyLayouts defines layouts of legacy snapshots of views with diffed columns.
+var legacyLayouts = map[string]legacyLayout{
+	"databases":          {first: "commits", last: "write_t", key: "datname"},
+	"tables":             {first: "seq_scan", last: "tidx_hit", key: "relation"},
+	"indexes":            {first: "idx_scan", last: "idx_hit", key: "index"},
+	"sizes":              {first: "total_change", last: "idx_change", key: "relation"},
+	"functions":          {first: "calls", last: "calls", key: "funcid"},
+	"replication":        {first: "wal", last: "wal", key: "pid"},
+	"statements_timings": {first: "all_t", last: "calls", key: "queryid"},
+	"statements_general": {first: "calls", last: "rows", key: "queryid"},
+	"statements_io":      {first: "hits", last: "calls", key: "queryid"},
+	"statements_temp":    {first: "tmp_read", last: "calls", key: "queryid"},
+	"statements_local":   {first: "lo_hits", last: "calls", key: "queryid"},
+	"progress_vacuum":    {first: "scanned", last: "vacuumed", key: "pid"},
+	"progress_cluster":   {first: "tup_scanned", last: "tup_written", key: "pid"},
+}
+
+// snapshotLayout returns layout of columns of the snapshot of the view. Snapshots of current format keep their layout,
+// layout of legacy snapshots is translated using names of columns. Views without legacy layout use layout of the
+// view which has not been changed since they were introduced.
+func snapshotLayout(name string, s stat.Snapshot, v view.View) (stat.Layout, error) {
+	if s.Format > stat.SnapshotFormat {
+		return stat.Layout{}, fmt.Errorf("snapshot of %s has format %d, the latest supported format is %d, upgrade pgcenter", name, s.Format, stat.SnapshotFormat)
+	}
+
+	if s.Format > 0 {
+		if s.Layout == nil {
+			return stat.Layout{}, fmt.Errorf("snapshot of %s has no layout of columns", name)
+		}
+		if err := s.Layout.Validate(s.Ncols); err != nil {
+			return stat.Layout{}, fmt.Errorf("snapshot of %s: %s", name, err)
+		}
+		return *s.Layout, nil
+	}
+
+	l, ok := legacyLayouts[name]
+	if !ok {
+		layout := stat.Layout{DiffIntvl: v.DiffIntvl, UniqueKey: v.UniqueKey, Gauges: v.Gauges}
+		if err := layout.Validate(s.Ncols); err != nil {
+			return stat.Layout{}, fmt.Errorf("snapshot of %s recorded by an older version of pgcenter: %s", name, err)
+		}
+		return layout, nil
+	}
+
+	var missing []string
+	index := func(col string) int {
+		idx, ok := getColumnIndex(s.Cols, col)
+		if !ok {
+			missing = append(missing, col)
+		}
+		return idx
+	}
+
+	first, last, key := index(l.first), index(l.last), index(l.key)
+	if len(missing) > 0 {
+		return stat.Layout{}, fmt.Errorf("snapshot of %s recorded by an older version of pgcenter lacks columns: %s", name, strings.Join(missing, ", "))
+	}
+
+	layout := stat.Layout{DiffIntvl: [2]int{first, last}, UniqueKey: key, Gauges: v.Gauges}
+	if err := layout.Validate(s.Ncols); err != nil {
+		return stat.Layout{}, fmt.Errorf("snapshot of %s recorded by an older version of pgcenter: %s", name, err)
+	}
+	return layout, nil
+}
+
+// sameColumns returns true if stats have the same columns.
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
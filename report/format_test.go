@@ -0,0 +1,64 @@
+package report
+
+import (
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_snapshotLayout(t *testing.T) {
+	views := view.New()
+
+	// Current format, layout is taken from snapshot.
+	snap := stat.NewSnapshot(stat.PGresult{Ncols: 3, Cols: []string{"a", "b", "c"}}, view.View{DiffIntvl: [2]int{1, 2}, UniqueKey: 0})
+	got, err := snapshotLayout("custom", snap, views["databases"])
+	assert.NoError(t, err)
+	assert.Equal(t, stat.Layout{DiffIntvl: [2]int{1, 2}}, got)
+
+	snap.Layout = &stat.Layout{DiffIntvl: [2]int{1, 5}}
+	_, err = snapshotLayout("custom", snap, views["databases"])
+	assert.Error(t, err)
+
+	snap.Format = stat.SnapshotFormat + 1
+	_, err = snapshotLayout("custom", snap, views["databases"])
+	assert.EqualError(t, err, "snapshot of custom has format 2, the latest supported format is 1, upgrade pgcenter")
+
+	// Legacy snapshot of databases recorded from Postgres 11, without checksum failures column.
+	cols := []string{
+		"datname", "commits", "rollbacks", "reads", "hits", "returned", "fetched", "inserts", "updates", "deletes",
+		"conflicts", "deadlocks", "temp_files", "temp_bytes", "read_t", "write_t", "stats_age",
+	}
+	snap = stat.Snapshot{PGresult: stat.PGresult{Ncols: len(cols), Cols: cols}}
+	got, err = snapshotLayout("databases", snap, views["databases"])
+	assert.NoError(t, err)
+	assert.Equal(t, stat.Layout{DiffIntvl: [2]int{1, 15}, UniqueKey: 0}, got)
+
+	// Legacy snapshot which lacks columns.
+	snap = stat.Snapshot{PGresult: stat.PGresult{Ncols: 3, Cols: []string{"relation", "seq_scan", "live"}}}
+	_, err = snapshotLayout("tables", snap, views["tables"])
+	assert.EqualError(t, err, "snapshot of tables recorded by an older version of pgcenter lacks columns: tidx_hit")
+
+	// Legacy snapshot of statements, unique key is found by name.
+	cols = []string{"user", "database", "t_calls", "t_rows", "calls", "rows", "queryid", "query"}
+	snap = stat.Snapshot{PGresult: stat.PGresult{Ncols: len(cols), Cols: cols}}
+	got, err = snapshotLayout("statements_general", snap, views["statements_general"])
+	assert.NoError(t, err)
+	assert.Equal(t, stat.Layout{DiffIntvl: [2]int{4, 5}, UniqueKey: 6}, got)
+
+	// Legacy snapshot of view without legacy layout uses layout of the view.
+	snap = stat.Snapshot{PGresult: stat.PGresult{Ncols: 14}}
+	got, err = snapshotLayout("activity", snap, views["activity"])
+	assert.NoError(t, err)
+	assert.Equal(t, stat.Layout{}, got)
+
+	snap = stat.Snapshot{PGresult: stat.PGresult{Ncols: 5}}
+	_, err = snapshotLayout("tables_io", snap, views["tables_io"])
+	assert.Error(t, err)
+}
+
+func Test_sameColumns(t *testing.T) {
+	assert.True(t, sameColumns([]string{"a", "b"}, []string{"a", "b"}))
+	assert.False(t, sameColumns([]string{"a", "b"}, []string{"a"}))
+	assert.False(t, sameColumns([]string{"a", "b"}, []string{"b", "a"}))
+}
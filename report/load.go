@@ -0,0 +1,342 @@
+package report
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/lesovsky/pgcenter/internal/log"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// loadSchemaName defines name of the schema where loaded stats are stored.
+	loadSchemaName = "pgcenter_report"
+	// loadTsColumn defines name of the column which holds snapshot timestamp.
+	loadTsColumn = "snapshot_ts"
+	// loadColumnsQuery defines query for getting columns and their types of the table created by the previous loads.
+	loadColumnsQuery = "SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2"
+)
+
+// numericRE defines format of values which could be stored as numeric.
+var numericRE = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// loadTable describes destination table for stats snapshots of a single view. Snapshots of the view taken at different
+// times are stored in the same table and distinguished by timestamp column, hence they could be compared using SQL.
+type loadTable struct {
+	name    string
+	cols    []string        // list of columns in order of appearance
+	numeric map[string]bool // columns which values are always numeric
+}
+
+// newLoadTable creates new loadTable.
+func newLoadTable(name string) *loadTable {
+	return &loadTable{name: name, numeric: map[string]bool{}}
+}
+
+// update extends table definition using columns and values from stats snapshot.
+func (t *loadTable) update(res stat.PGresult) {
+	for i, col := range res.Cols {
+		isNumeric, ok := t.numeric[col]
+		if !ok {
+			t.cols = append(t.cols, col)
+			isNumeric = true
+		}
+
+		for _, row := range res.Values {
+			if i >= len(row) || !row[i].Valid {
+				continue
+			}
+			if !numericRE.MatchString(row[i].String) {
+				isNumeric = false
+				break
+			}
+		}
+
+		t.numeric[col] = isNumeric
+	}
+}
+
+// createQuery returns query for creating table in the schema.
+func (t *loadTable) createQuery() string {
+	defs := []string{pgx.Identifier{loadTsColumn}.Sanitize() + " timestamptz NOT NULL"}
+	for _, col := range t.cols {
+		defs = append(defs, pgx.Identifier{col}.Sanitize()+" "+t.columnType(col))
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", t.identifier().Sanitize(), strings.Join(defs, ", "))
+}
+
+// alterQueries returns queries for adding columns to table which have been created earlier.
+func (t *loadTable) alterQueries() []string {
+	var queries []string
+	for _, col := range t.cols {
+		queries = append(queries, fmt.Sprintf(
+			"ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s",
+			t.identifier().Sanitize(), pgx.Identifier{col}.Sanitize(), t.columnType(col),
+		))
+	}
+	return queries
+}
+
+// columnType returns SQL type of the column.
+func (t *loadTable) columnType(col string) string {
+	if t.numeric[col] {
+		return "numeric"
+	}
+	return "text"
+}
+
+// reconcile adjusts table definition to types of columns of the table created by the previous loads. Values of columns
+// created as text are loaded as text. Columns created as numeric are converted to text when loaded values are not
+// numeric, otherwise copying of these values would fail. Returns queries for converting columns.
+func (t *loadTable) reconcile(existing map[string]string) []string {
+	var queries []string
+	for _, col := range t.cols {
+		typ, ok := existing[col]
+		if !ok {
+			continue
+		}
+
+		switch {
+		case typ != "numeric":
+			t.numeric[col] = false
+		case !t.numeric[col]:
+			queries = append(queries, fmt.Sprintf(
+				"ALTER TABLE %s ALTER COLUMN %s TYPE text USING %s::text",
+				t.identifier().Sanitize(), pgx.Identifier{col}.Sanitize(), pgx.Identifier{col}.Sanitize(),
+			))
+		}
+	}
+	return queries
+}
+
+// identifier returns schema-qualified name of the table.
+func (t *loadTable) identifier() pgx.Identifier {
+	return pgx.Identifier{loadSchemaName, t.name}
+}
+
+// rows converts stats snapshot into rows suitable for copying into the table.
+func (t *loadTable) rows(res stat.PGresult, ts time.Time) ([]string, [][]interface{}, error) {
+	cols := append([]string{loadTsColumn}, res.Cols...)
+	rows := make([][]interface{}, 0, len(res.Values))
+
+	for _, values := range res.Values {
+		row := make([]interface{}, 0, len(cols))
+		row = append(row, ts)
+		for i, v := range values {
+			switch {
+			case !v.Valid:
+				row = append(row, nil)
+			case t.numeric[res.Cols[i]]:
+				// Numeric values have to be passed in binary format.
+				n := &pgtype.Numeric{}
+				if err := n.Set(v.String); err != nil {
+					return nil, nil, err
+				}
+				row = append(row, n)
+			default:
+				row = append(row, v.String)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return cols, rows, nil
+}
+
+// doLoad reads stats from file and loads them into database specified by connection string. Loading is cancelled
+// when context is done.
+func doLoad(ctx context.Context, w io.Writer, c Config) error {
+	// Scan the file and define tables structure.
+	tables, err := scanLoadTables(c)
+	if err != nil {
+		return err
+	}
+
+	if len(tables) == 0 {
+		_, err = fmt.Fprintf(w, "INFO: no stats found in %s, nothing to load\n", c.InputFile)
+		return err
+	}
+
+	dbConfig, err := postgres.ParseConfigSSL(c.LoadConninfo, c.LoadSSL)
+	if err != nil {
+		return err
+	}
+
+	db, err := postgres.ConnectContext(ctx, dbConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Transaction is rolled back even if loading has been cancelled.
+	defer func() {
+		_ = tx.Rollback(context.Background())
+	}()
+
+	// Create schema and tables.
+	queries := []string{"CREATE SCHEMA IF NOT EXISTS " + pgx.Identifier{loadSchemaName}.Sanitize()}
+	for _, t := range tables {
+		queries = append(queries, t.createQuery())
+		queries = append(queries, t.alterQueries()...)
+	}
+
+	for _, q := range queries {
+		_, err := tx.Exec(ctx, q)
+		if err != nil {
+			return fmt.Errorf("%s, query: %s", err, q)
+		}
+	}
+
+	// Tables might be created by the previous loads with other types of columns.
+	for _, t := range tables {
+		existing, err := loadTableColumns(ctx, tx, t)
+		if err != nil {
+			return err
+		}
+
+		for _, q := range t.reconcile(existing) {
+			_, err := tx.Exec(ctx, q)
+			if err != nil {
+				return fmt.Errorf("%s, query: %s", err, q)
+			}
+		}
+	}
+
+	// Read the file again and copy snapshots into tables.
+	var snapshots, rows int64
+	err = walkStatFile(c, func(name string, ts time.Time, snap stat.Snapshot) error {
+		t := tables[name]
+		cols, values, err := t.rows(snap.PGresult, ts)
+		if err != nil {
+			return err
+		}
+		n, err := tx.CopyFrom(ctx, t.identifier(), cols, pgx.CopyFromRows(values))
+		if err != nil {
+			return fmt.Errorf("load %s snapshot taken at %s failed: %s", name, ts.Format("2006-01-02 15:04:05"), err)
+		}
+		snapshots++
+		rows += n
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "INFO: loaded %d snapshots (%d rows) from %s into schema %s\n", snapshots, rows, c.InputFile, loadSchemaName)
+	return err
+}
+
+// loadTableColumns returns columns and their types of existing table.
+func loadTableColumns(ctx context.Context, tx pgx.Tx, t *loadTable) (map[string]string, error) {
+	rows, err := tx.Query(ctx, loadColumnsQuery, loadSchemaName, t.name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]string{}
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, err
+		}
+		columns[name] = typ
+	}
+
+	return columns, rows.Err()
+}
+
+// scanLoadTables reads stats from file and defines tables required for loading stats.
+func scanLoadTables(c Config) (map[string]*loadTable, error) {
+	tables := map[string]*loadTable{}
+
+	err := walkStatFile(c, func(name string, _ time.Time, snap stat.Snapshot) error {
+		t, ok := tables[name]
+		if !ok {
+			t = newLoadTable(name)
+			tables[name] = t
+		}
+		t.update(snap.PGresult)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+// walkStatFile reads stats file and calls passed function for every stats snapshot requested by user.
+func walkStatFile(c Config, fn func(name string, ts time.Time, snap stat.Snapshot) error) error {
+	f, err := os.Open(c.InputFile)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := f.Close()
+		if err != nil {
+			fmt.Printf("close file descriptor failed: %s, ignore", err)
+		}
+	}()
+
+	r := tar.NewReader(f)
+
+	for {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("advance read position failed: %s", err)
+		}
+
+		// Filename has format 'report_type.timestamp.json', load all stats if report type is not specified.
+		name := strings.Split(hdr.Name, ".")[0]
+		if c.ReportType != "" {
+			name = c.ReportType
+		}
+
+		err = isFilenameOK(hdr.Name, name)
+		if err != nil {
+			log.Debug("skip file", "file", hdr.Name, "reason", err)
+			continue
+		}
+
+		ts, err := isFilenameTimestampOK(hdr.Name, c.TsStart, c.TsEnd)
+		if err != nil {
+			log.Debug("skip file", "file", hdr.Name, "reason", err)
+			continue
+		}
+
+		snap, err := readFileStat(r, hdr.Size)
+		if err != nil {
+			return err
+		}
+
+		err = fn(name, ts, snap)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
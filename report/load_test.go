@@ -0,0 +1,99 @@
+package report
+
+import (
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_loadTable(t *testing.T) {
+	table := newLoadTable("activity")
+
+	table.update(stat.PGresult{
+		Cols: []string{"pid", "state", "age"},
+		Values: [][]sql.NullString{
+			{{String: "123", Valid: true}, {String: "active", Valid: true}, {String: "1.5", Valid: true}},
+			{{String: "456", Valid: true}, {String: "idle", Valid: true}, {}},
+		},
+	})
+	assert.Equal(t, []string{"pid", "state", "age"}, table.cols)
+	assert.Equal(t, map[string]bool{"pid": true, "state": false, "age": true}, table.numeric)
+
+	// Next snapshot has a new column and non-numeric value in existing column.
+	table.update(stat.PGresult{
+		Cols: []string{"pid", "state", "age", "query"},
+		Values: [][]sql.NullString{
+			{{String: "789", Valid: true}, {String: "active", Valid: true}, {String: "00:01:00", Valid: true}, {String: "SELECT 1", Valid: true}},
+		},
+	})
+	assert.Equal(t, []string{"pid", "state", "age", "query"}, table.cols)
+	assert.Equal(t, map[string]bool{"pid": true, "state": false, "age": false, "query": false}, table.numeric)
+
+	assert.Equal(t,
+		`CREATE TABLE IF NOT EXISTS "pgcenter_report"."activity" ("snapshot_ts" timestamptz NOT NULL, "pid" numeric, "state" text, "age" text, "query" text)`,
+		table.createQuery(),
+	)
+	assert.Len(t, table.alterQueries(), 4)
+	assert.Equal(t, `ALTER TABLE "pgcenter_report"."activity" ADD COLUMN IF NOT EXISTS "pid" numeric`, table.alterQueries()[0])
+
+	ts := time.Now()
+	cols, rows, err := table.rows(stat.PGresult{
+		Cols:   []string{"pid", "state"},
+		Values: [][]sql.NullString{{{String: "123", Valid: true}, {}}},
+	}, ts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"snapshot_ts", "pid", "state"}, cols)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, ts, rows[0][0])
+	assert.Nil(t, rows[0][2])
+}
+
+func Test_loadTable_reconcile(t *testing.T) {
+	table := newLoadTable("activity")
+	table.update(stat.PGresult{
+		Cols:   []string{"pid", "state", "age", "query"},
+		Values: [][]sql.NullString{{{String: "123", Valid: true}, {String: "active", Valid: true}, {String: "00:01:00", Valid: true}, {String: "1", Valid: true}}},
+	})
+
+	// Table created by previous load: 'age' was numeric, 'query' was text, 'state' is a new column.
+	got := table.reconcile(map[string]string{"snapshot_ts": "timestamp with time zone", "pid": "numeric", "age": "numeric", "query": "text"})
+	assert.Equal(t, []string{`ALTER TABLE "pgcenter_report"."activity" ALTER COLUMN "age" TYPE text USING "age"::text`}, got)
+	assert.Equal(t, map[string]bool{"pid": true, "state": false, "age": false, "query": false}, table.numeric)
+
+	// Values of 'query' are loaded as text, hence copying into text column doesn't fail.
+	_, rows, err := table.rows(stat.PGresult{
+		Cols:   []string{"pid", "query"},
+		Values: [][]sql.NullString{{{String: "123", Valid: true}, {String: "1", Valid: true}}},
+	}, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "1", rows[0][2])
+}
+
+func Test_scanLoadTables(t *testing.T) {
+	testcases := []struct {
+		valid      bool
+		config     Config
+		wantTables []string
+	}{
+		{valid: true, config: Config{InputFile: "testdata/pgcenter.stat.golden.tar", ReportType: "activity", TsEnd: time.Now()}, wantTables: []string{"activity"}},
+		{valid: true, config: Config{InputFile: "testdata/pgcenter.stat.golden.tar", ReportType: "databases", TsEnd: time.Now()}, wantTables: []string{"databases"}},
+		{valid: true, config: Config{InputFile: "testdata/pgcenter.stat.invalid.tar", TsEnd: time.Now()}, wantTables: []string{}},
+		{valid: false, config: Config{InputFile: "testdata/not-exists.tar", TsEnd: time.Now()}},
+	}
+
+	for _, tc := range testcases {
+		got, err := scanLoadTables(tc.config)
+		if tc.valid {
+			assert.NoError(t, err)
+			assert.Len(t, got, len(tc.wantTables))
+			for _, name := range tc.wantTables {
+				assert.Contains(t, got, name)
+				assert.NotEmpty(t, got[name].cols)
+			}
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
@@ -2,9 +2,13 @@ package report
 
 import (
 	"archive/tar"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/lesovsky/pgcenter/internal/align"
+	"github.com/lesovsky/pgcenter/internal/interrupt"
+	"github.com/lesovsky/pgcenter/internal/log"
+	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/stat"
 	"github.com/lesovsky/pgcenter/internal/view"
 	"io"
@@ -28,6 +32,9 @@ type Config struct {
 	RowLimit      int
 	TruncLimit    int
 	Rate          time.Duration
+	LoadConninfo  string              // Load stats into database instead of printing report
+	LoadSSL       postgres.SSLOptions // SSL options used for connecting to database where stats are loaded
+	BaselineFile  string              // Save baseline derived from stats into file instead of printing report
 }
 
 const (
@@ -44,6 +51,18 @@ func RunMain(c Config) error {
 		return describeReport(app.writer, c.ReportType)
 	}
 
+	// Save baseline if requested.
+	if c.BaselineFile != "" {
+		return doBaseline(app.writer, c)
+	}
+
+	// Load stats into database if requested. In case of SIGINT loading is cancelled.
+	if c.LoadConninfo != "" {
+		ctx, cancel := interrupt.Context(context.Background(), os.Interrupt)
+		defer cancel()
+		return doLoad(ctx, app.writer, c)
+	}
+
 	// Open file with statistics.
 	f, err := os.Open(c.InputFile)
 	if err != nil {
@@ -75,6 +94,7 @@ type app struct {
 	config Config
 	view   view.View
 	writer io.Writer
+	notice io.Writer // notices are printed separately from the report, to keep output machine-readable
 }
 
 // newApp creates new 'pgcenter record' app.
@@ -86,6 +106,7 @@ func newApp(config Config) *app {
 		config: config,
 		view:   v,
 		writer: os.Stdout,
+		notice: os.Stderr,
 	}
 }
 
@@ -111,20 +132,45 @@ func (app *app) doReport(r *tar.Reader) error {
 		// Check filename - it has valid format and corresponds to requested report type.
 		err = isFilenameOK(hdr.Name, c.ReportType)
 		if err != nil {
+			log.Debug("skip file", "file", hdr.Name, "reason", err)
 			continue
 		}
 
 		// Check timestamp in filename, is it correct and is in requested report interval.
 		ts, err := isFilenameTimestampOK(hdr.Name, c.TsStart, c.TsEnd)
 		if err != nil {
+			log.Debug("skip file", "file", hdr.Name, "reason", err)
 			continue
 		}
 
 		// Read stats from file.
-		currStat, err := readFileStat(r, hdr.Size)
+		snap, err := readFileStat(r, hdr.Size)
+		if err != nil {
+			return err
+		}
+
+		// Snapshots could be recorded by different versions of pgcenter, use layout of columns of the snapshot.
+		layout, err := snapshotLayout(c.ReportType, snap, v)
 		if err != nil {
 			return err
 		}
+		v = layout.Apply(v)
+		currStat := snap.PGresult
+
+		// Columns could differ when stats recorded by different versions of pgcenter are appended to the same file,
+		// such snapshots are not comparable. Start over from the current snapshot.
+		if prevStat.Valid && !sameColumns(prevStat.Cols, currStat.Cols) {
+			_, err := fmt.Fprintf(
+				app.notice,
+				"NOTICE: columns of stats changed at %s, rates are calculated since the change\n",
+				ts.Format("2006-01-02 15:04:05"),
+			)
+			if err != nil {
+				return err
+			}
+			prevStat = stat.PGresult{}
+			orderConfigured = false
+		}
 
 		// if previous stats snapshot is not defined, copy current to previous.
 		// Usually this occurs when reading first stat sample at startup.
@@ -163,6 +209,17 @@ func (app *app) doReport(r *tar.Reader) error {
 			return err
 		}
 
+		if diffStat.Reset {
+			_, err := fmt.Fprintf(
+				app.notice,
+				"NOTICE: stats reset detected at %s, rates are calculated since reset\n",
+				ts.Format("2006-01-02 15:04:05"),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Format the stat
 		formatStatSample(&diffStat, &v, c)
 
@@ -227,19 +284,20 @@ func isFilenameTimestampOK(name string, start, end time.Time) (time.Time, error)
 	return ts, nil
 }
 
-// readFileStat reads content of tar file, unmarshal data and return stat object.
-func readFileStat(r *tar.Reader, bufsz int64) (stat.PGresult, error) {
+// readFileStat reads content of tar file, unmarshal data and return stats snapshot. Snapshots recorded by older
+// versions of pgcenter are read as snapshots of zero format.
+func readFileStat(r *tar.Reader, bufsz int64) (stat.Snapshot, error) {
 	data := make([]byte, bufsz)
 
 	if _, err := io.ReadFull(r, data); err != nil {
-		return stat.PGresult{}, err
+		return stat.Snapshot{}, err
 	}
 
 	// initialize an empty struct and unmarshal data from the buffer
-	res := stat.PGresult{}
+	res := stat.Snapshot{}
 	err := json.Unmarshal(data, &res)
 	if err != nil {
-		return stat.PGresult{}, err
+		return stat.Snapshot{}, err
 	}
 
 	return res, nil
@@ -249,7 +307,7 @@ func readFileStat(r *tar.Reader, bufsz int64) (stat.PGresult, error) {
 func countDiff(curr, prev stat.PGresult, interval int, v view.View) (stat.PGresult, error) {
 	var diff stat.PGresult
 
-	diff, err := stat.Compare(curr, prev, interval, v.DiffIntvl, v.OrderKey, v.OrderDesc, v.UniqueKey)
+	diff, err := stat.Compare(curr, prev, interval, v)
 	if err != nil {
 		return stat.PGresult{}, err
 	}
@@ -418,9 +476,12 @@ func describeReport(w io.Writer, report string) error {
 		"activity":           pgStatActivityDescription,
 		"replication":        pgStatReplicationDescription,
 		"tables":             pgStatTablesDescription,
+		"tables_io":          pgStatTablesIODescription,
 		"indexes":            pgStatIndexesDescription,
 		"functions":          pgStatFunctionsDescription,
 		"sizes":              pgStatSizesDescription,
+		"checkpoints":        pgStatCheckpointsDescription,
+		"roles":              pgRolesDescription,
 		"progress_vacuum":    pgStatProgressVacuumDescription,
 		"progress_cluster":   pgStatProgressClusterDescription,
 		"progress_index":     pgStatProgressCreateIndexDescription,
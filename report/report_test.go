@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/lesovsky/pgcenter/internal/align"
 	"github.com/lesovsky/pgcenter/internal/stat"
@@ -158,6 +159,80 @@ func Test_app_doReport(t *testing.T) {
 	}
 }
 
+func Test_app_doReport_notices(t *testing.T) {
+	v := view.View{Name: "databases", Cols: []string{"datname", "xact"}, Ncols: 2, DiffIntvl: [2]int{1, 1}, ColsWidth: map[int]int{}}
+	newSnapshot := func(cols []string, xact string) stat.Snapshot {
+		return stat.NewSnapshot(stat.PGresult{
+			Valid: true, Ncols: len(cols), Nrows: 1, Cols: cols,
+			Values: [][]sql.NullString{append([]sql.NullString{{String: "db1", Valid: true}}, sql.NullString{String: xact, Valid: true})},
+		}, v)
+	}
+
+	// Counters are reset in the third snapshot, columns are changed in the fourth snapshot.
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i, snap := range []stat.Snapshot{
+		newSnapshot(v.Cols, "100"), newSnapshot(v.Cols, "200"), newSnapshot(v.Cols, "10"), newSnapshot([]string{"datname", "commits"}, "20"),
+	} {
+		data, err := json.Marshal(snap)
+		assert.NoError(t, err)
+		name := fmt.Sprintf("databases.20210123T15310%d.json", i)
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}))
+		_, err = tw.Write(data)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+
+	start, err := time.ParseInLocation("2006-01-02 15:04:05", "2021-01-23 15:31:00", time.Now().Location())
+	assert.NoError(t, err)
+
+	app := newApp(Config{ReportType: "databases", TsStart: start, TsEnd: start.Add(time.Minute), TruncLimit: 32, Rate: time.Second})
+	app.view = v
+	var out, notice bytes.Buffer
+	app.writer, app.notice = &out, &notice
+
+	assert.NoError(t, app.doReport(tar.NewReader(&buf)))
+
+	// Notices are not mixed with report.
+	assert.NotContains(t, out.String(), "NOTICE")
+	assert.Contains(t, notice.String(), "NOTICE: stats reset detected at 2021-01-23 15:31:02")
+	assert.Contains(t, notice.String(), "NOTICE: columns of stats changed at 2021-01-23 15:31:03")
+}
+
+func Test_app_doReport_gauges(t *testing.T) {
+	v := view.View{Name: "sizes", Cols: []string{"relation", "total_change"}, Ncols: 2, DiffIntvl: [2]int{1, 1}, Gauges: []string{"total_change"}, ColsWidth: map[int]int{}}
+
+	// Size of relation decreases in the second snapshot, e.g. after vacuum full.
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i, size := range []string{"200", "100"} {
+		data, err := json.Marshal(stat.NewSnapshot(stat.PGresult{
+			Valid: true, Ncols: 2, Nrows: 1, Cols: v.Cols,
+			Values: [][]sql.NullString{{{String: "public.t1", Valid: true}, {String: size, Valid: true}}},
+		}, v))
+		assert.NoError(t, err)
+		name := fmt.Sprintf("sizes.20210123T15310%d.json", i)
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}))
+		_, err = tw.Write(data)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+
+	start, err := time.ParseInLocation("2006-01-02 15:04:05", "2021-01-23 15:31:00", time.Now().Location())
+	assert.NoError(t, err)
+
+	app := newApp(Config{ReportType: "sizes", TsStart: start, TsEnd: start.Add(time.Minute), TruncLimit: 32, Rate: time.Second})
+	app.view = v
+	var out, notice bytes.Buffer
+	app.writer, app.notice = &out, &notice
+
+	assert.NoError(t, app.doReport(tar.NewReader(&buf)))
+
+	// Decreased gauge is reported as negative rate, not as reset.
+	assert.Contains(t, out.String(), "-100")
+	assert.NotContains(t, notice.String(), "stats reset detected")
+}
+
 func Test_isFilenameOK(t *testing.T) {
 	testcases := []struct {
 		valid  bool
@@ -241,9 +316,10 @@ func Test_readFileStat(t *testing.T) {
 					assert.NoError(t, err)
 					assert.NotNil(t, got.Values)
 					assert.NotNil(t, got.Cols)
+					assert.Equal(t, 0, got.Format) // recorded by an older version
 				} else {
 					assert.Error(t, err)
-					assert.Equal(t, stat.PGresult{}, got)
+					assert.Equal(t, stat.Snapshot{}, got)
 				}
 			}
 		})
@@ -497,9 +573,12 @@ func Test_describeReport(t *testing.T) {
 		{report: "activity", want: pgStatActivityDescription},
 		{report: "replication", want: pgStatReplicationDescription},
 		{report: "tables", want: pgStatTablesDescription},
+		{report: "tables_io", want: pgStatTablesIODescription},
 		{report: "indexes", want: pgStatIndexesDescription},
 		{report: "functions", want: pgStatFunctionsDescription},
 		{report: "sizes", want: pgStatSizesDescription},
+		{report: "checkpoints", want: pgStatCheckpointsDescription},
+		{report: "roles", want: pgRolesDescription},
 		{report: "progress_vacuum", want: pgStatProgressVacuumDescription},
 		{report: "progress_cluster", want: pgStatProgressClusterDescription},
 		{report: "progress_index", want: pgStatProgressCreateIndexDescription},
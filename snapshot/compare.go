@@ -0,0 +1,232 @@
+package snapshot
+
+import (
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/lesovsky/pgcenter/internal/math"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// compareRow defines sum of deltas of the view's row metric between two snapshots.
+type compareRow struct {
+	view   string
+	key    string
+	metric string
+	total  string
+}
+
+// viewReport defines totals of the view's rows, columns are sorted by name.
+type viewReport struct {
+	cols []string
+	keys []string
+	rows map[string]map[string]string // totals keyed by row's key and metric
+}
+
+// parseRange parses range of snapshots in format 'FROM:TO'.
+func parseRange(s string) (int64, int64, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid range of snapshots '%s', use FROM:TO", s)
+	}
+
+	from, err1 := strconv.ParseInt(parts[0], 10, 64)
+	to, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil || from < 0 || to <= from {
+		return 0, 0, fmt.Errorf("invalid range of snapshots '%s', use FROM:TO where FROM is less than TO", s)
+	}
+
+	return from, to, nil
+}
+
+// listSnapshots prints stored snapshots.
+func listSnapshots(db *postgres.DB, schema string, w io.Writer) error {
+	s := pgx.Identifier{schema}.Sanitize()
+
+	rows, err := db.Query(
+		"SELECT s.snapshot_id, s.snapshot_ts, s.source, s.seconds::float8, count(st.snapshot_id) " +
+			"FROM " + s + ".snapshots s LEFT JOIN " + s + ".snapshot_stats st USING (snapshot_id) " +
+			"GROUP BY s.snapshot_id ORDER BY s.snapshot_id",
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	_, err = fmt.Fprintf(w, "%-8s %-25s %-10s %-8s %s\n", "id", "taken", "interval", "rows", "source")
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var (
+			id      int64
+			ts      time.Time
+			source  string
+			seconds float64
+			count   int64
+		)
+
+		err := rows.Scan(&id, &ts, &source, &seconds, &count)
+		if err != nil {
+			return err
+		}
+
+		itv := time.Duration(seconds * float64(time.Second)).Round(time.Second)
+		_, err = fmt.Fprintf(w, "%-8d %-25s %-10s %-8d %s\n", id, ts.Format("2006-01-02 15:04:05 MST"), itv, count, source)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// compareSnapshots prints sums of deltas of views' rows between two snapshots.
+func compareSnapshots(db *postgres.DB, schema string, from, to int64, w io.Writer) error {
+	s := pgx.Identifier{schema}.Sanitize()
+
+	var (
+		count      int64
+		start, end time.Time
+	)
+	err := db.QueryRow(
+		"SELECT count(*), coalesce(min(snapshot_ts - seconds * interval '1 second'), now()), coalesce(max(snapshot_ts), now()) "+
+			"FROM "+s+".snapshots WHERE snapshot_id > $1 AND snapshot_id <= $2", from, to,
+	).Scan(&count, &start, &end)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("no snapshots found between %d and %d", from, to)
+	}
+
+	rows, err := db.Query("SELECT view, key, metric, total::text FROM "+s+".compare($1, $2)", from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var res []compareRow
+	for rows.Next() {
+		var r compareRow
+		err := rows.Scan(&r.view, &r.key, &r.metric, &r.total)
+		if err != nil {
+			return err
+		}
+		res = append(res, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "INFO: changes of stats from %s to %s (%s, snapshots %d-%d)\n",
+		start.Format("2006-01-02 15:04:05 MST"), end.Format("2006-01-02 15:04:05 MST"), end.Sub(start).Round(time.Second), from+1, to,
+	)
+	if err != nil {
+		return err
+	}
+
+	return printReports(w, buildReports(res))
+}
+
+// buildReports groups sums of deltas by views.
+func buildReports(rows []compareRow) map[string]*viewReport {
+	reports := map[string]*viewReport{}
+
+	for _, r := range rows {
+		rep, ok := reports[r.view]
+		if !ok {
+			rep = &viewReport{rows: map[string]map[string]string{}}
+			reports[r.view] = rep
+		}
+
+		if _, ok := rep.rows[r.key]; !ok {
+			rep.rows[r.key] = map[string]string{}
+			rep.keys = append(rep.keys, r.key)
+		}
+		rep.rows[r.key][r.metric] = r.total
+
+		found := false
+		for _, c := range rep.cols {
+			if c == r.metric {
+				found = true
+				break
+			}
+		}
+		if !found {
+			rep.cols = append(rep.cols, r.metric)
+		}
+	}
+
+	for _, rep := range reports {
+		sort.Strings(rep.cols)
+		sort.Strings(rep.keys)
+	}
+
+	return reports
+}
+
+// printReports prints reports of views sorted by names.
+func printReports(w io.Writer, reports map[string]*viewReport) error {
+	names := make([]string, 0, len(reports))
+	for name := range reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rep := reports[name]
+
+		// Calculate width of columns using the longest values.
+		cols := append([]string{"key"}, rep.cols...)
+		widths := make([]int, len(cols))
+		for i, c := range cols {
+			widths[i] = len(c)
+		}
+		for _, key := range rep.keys {
+			widths[0] = math.Max(widths[0], len(key))
+			for i, c := range rep.cols {
+				widths[i+1] = math.Max(widths[i+1], len(rep.rows[key][c]))
+			}
+		}
+
+		_, err := fmt.Fprintf(w, "\n%s:\n", name)
+		if err != nil {
+			return err
+		}
+
+		for i, c := range cols {
+			_, err := fmt.Fprintf(w, "\033[%d;%dm%-*s\033[0m", 37, 1, widths[i]+2, c)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, key := range rep.keys {
+			_, err := fmt.Fprintf(w, "\n%-*s", widths[0]+2, key)
+			if err != nil {
+				return err
+			}
+			for i, c := range rep.cols {
+				_, err := fmt.Fprintf(w, "%-*s", widths[i+1]+2, rep.rows[key][c])
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err = fmt.Fprintf(w, "\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
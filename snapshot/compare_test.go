@@ -0,0 +1,44 @@
+package snapshot
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_parseRange(t *testing.T) {
+	from, to, err := parseRange("3:7")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), from)
+	assert.Equal(t, int64(7), to)
+
+	for _, s := range []string{"", "3", "7:3", "3:3", "a:b", "-1:3", "1:2:3"} {
+		_, _, err := parseRange(s)
+		assert.Error(t, err)
+	}
+}
+
+func Test_buildReports(t *testing.T) {
+	reports := buildReports([]compareRow{
+		{view: "databases", key: "db2", metric: "commits", total: "20"},
+		{view: "databases", key: "db1", metric: "rollbacks", total: "1"},
+		{view: "databases", key: "db1", metric: "commits", total: "10"},
+		{view: "tables", key: "public.t1", metric: "seq_scan", total: "5"},
+	})
+
+	assert.Len(t, reports, 2)
+	assert.Equal(t, &viewReport{
+		cols: []string{"commits", "rollbacks"},
+		keys: []string{"db1", "db2"},
+		rows: map[string]map[string]string{"db1": {"commits": "10", "rollbacks": "1"}, "db2": {"commits": "20"}},
+	}, reports["databases"])
+
+	var buf bytes.Buffer
+	assert.NoError(t, printReports(&buf, reports))
+	assert.Equal(t,
+		"\ndatabases:\n\033[37;1mkey  \033[0m\033[37;1mcommits  \033[0m\033[37;1mrollbacks  \033[0m"+
+			"\ndb1  10       1          \ndb2  20                  \n"+
+			"\ntables:\n\033[37;1mkey        \033[0m\033[37;1mseq_scan  \033[0m\npublic.t1  5         \n",
+		buf.String(),
+	)
+}
@@ -0,0 +1,257 @@
+// 'pgcenter snapshot' - periodically stores diffed stats into Postgres tables and compares stored snapshots.
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/interrupt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultSchema defines default name of the schema where snapshots are stored.
+	DefaultSchema = "pgcenter_snapshots"
+)
+
+// Config defines config container for configuring 'pgcenter snapshot'.
+type Config struct {
+	Target    string        // connection string of database where snapshots are stored, source database is used by default
+	Schema    string        // schema where snapshots are stored
+	Interval  time.Duration // interval between snapshots
+	Count     int           // number of snapshots to take, zero means until interrupted
+	Retention time.Duration // how long snapshots are kept, zero means forever
+	List      bool          // list stored snapshots instead of taking them
+	Compare   string        // range of snapshots 'FROM:TO' to compare instead of taking snapshots
+
+	ResetStatements time.Duration // reset pg_stat_statements after snapshot when specified time passed since last reset
+}
+
+// RunMain is the 'pgcenter snapshot' main entry point.
+func RunMain(dbConfig postgres.Config, config Config) error {
+	targetConfig := dbConfig
+	if config.Target != "" {
+		c, err := postgres.ParseConfig(config.Target)
+		if err != nil {
+			return err
+		}
+		targetConfig = c
+	}
+
+	// In case of SIGINT stop program gracefully, queries in flight are cancelled.
+	ctx, cancel := interrupt.Context(context.Background(), os.Interrupt)
+	defer cancel()
+
+	target, err := postgres.ConnectContext(ctx, targetConfig)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	err = initSchema(target, config.Schema)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case config.List:
+		return listSnapshots(target, config.Schema, os.Stdout)
+	case config.Compare != "":
+		from, to, err := parseRange(config.Compare)
+		if err != nil {
+			return err
+		}
+		return compareSnapshots(target, config.Schema, from, to, os.Stdout)
+	}
+
+	if config.Interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s")
+	}
+
+	db, err := postgres.ConnectContext(ctx, dbConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	props, err := stat.GetPostgresProperties(db)
+	if err != nil {
+		return err
+	}
+
+	if config.ResetStatements > 0 && !props.ExtPGSSAvail {
+		return fmt.Errorf("pg_stat_statements is not available, it could not be reset")
+	}
+
+	views := view.New()
+	err = views.Configure(props.QueryOptions(0))
+	if err != nil {
+		return err
+	}
+
+	s := &snapshotter{config: config, db: db, target: target, views: views, w: os.Stdout}
+	return s.run(ctx)
+}
+
+// snapshotter takes stats snapshots and stores them into target database.
+type snapshotter struct {
+	config Config
+	db     *postgres.DB // database which stats are collected
+	target *postgres.DB // database where snapshots are stored
+	views  view.Views
+	w      io.Writer
+
+	prev      map[string]stat.PGresult // stats of the previous snapshot, used for calculating deltas
+	lastTs    time.Time                // time of the last collection
+	lastReset time.Time                // time of the last reset of pg_stat_statements
+	skipped   map[string]bool          // views which stats are not available
+}
+
+// run takes snapshots with configured interval. The first collected stats are not stored, they are used for calculating
+// deltas of the first snapshot. Taking snapshots is stopped when context is done.
+func (s *snapshotter) run(ctx context.Context) error {
+	s.prev, s.skipped = map[string]stat.PGresult{}, map[string]bool{}
+
+	cfg := s.db.Config.Config
+	source := fmt.Sprintf("%s:%d/%s", cfg.Host, cfg.Port, cfg.Database)
+
+	_, _ = fmt.Fprintf(s.w, "INFO: taking snapshots of %s every %s into schema %s\n", source, s.config.Interval, s.config.Schema)
+
+	s.collect(ctx)
+
+	if s.config.ResetStatements > 0 {
+		s.lastReset = s.statementsResetTime(ctx)
+		_, _ = fmt.Fprintf(s.w, "INFO: pg_stat_statements is reset every %s, statements are archived into snapshots before resets\n", s.config.ResetStatements)
+	}
+
+	t := time.NewTicker(s.config.Interval)
+	defer t.Stop()
+
+	for n := 0; s.config.Count == 0 || n < s.config.Count; n++ {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return fmt.Errorf("got %s", os.Interrupt)
+		}
+
+		prevTs := s.lastTs
+		stats := s.collect(ctx)
+		if ctx.Err() != nil {
+			return fmt.Errorf("got %s", os.Interrupt)
+		}
+
+		id, err := store(ctx, s.target, s.config.Schema, source, s.lastTs, s.lastTs.Sub(prevTs).Seconds(), stats)
+		if err != nil {
+			return fmt.Errorf("store snapshot failed: %s", err)
+		}
+		_, _ = fmt.Fprintf(s.w, "INFO: snapshot %d taken at %s\n", id, s.lastTs.Format("2006-01-02 15:04:05 MST"))
+
+		// Stats of statements before reset are stored in the snapshot taken above.
+		if s.config.ResetStatements > 0 && s.lastTs.Sub(s.lastReset) >= s.config.ResetStatements {
+			err := s.resetStatements(ctx)
+			if err != nil {
+				return fmt.Errorf("reset pg_stat_statements failed: %s", err)
+			}
+			_, _ = fmt.Fprintf(s.w, "INFO: pg_stat_statements reset, statistics before reset are stored in snapshot %d\n", id)
+		}
+
+		if s.config.Retention > 0 {
+			removed, err := cleanup(ctx, s.target, s.config.Schema, s.lastTs.Add(-s.config.Retention))
+			if err != nil {
+				return fmt.Errorf("remove old snapshots failed: %s", err)
+			}
+			if removed > 0 {
+				_, _ = fmt.Fprintf(s.w, "INFO: removed %d snapshots older than %s\n", removed, s.config.Retention)
+			}
+		}
+	}
+
+	return nil
+}
+
+// collect collects stats of views with counters and returns their deltas since previous collection. Views which stats
+// are not available (e.g. pg_stat_statements is not installed) are skipped.
+func (s *snapshotter) collect(ctx context.Context) map[string][]statsRow {
+	names := make([]string, 0, len(s.views))
+	for name, v := range s.views {
+		if v.DiffIntvl != [2]int{0, 0} && !s.skipped[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	s.lastTs = time.Now()
+	stats := map[string][]statsRow{}
+
+	for _, name := range names {
+		v := s.views[name]
+
+		prev, ok := s.prev[name]
+
+		res, err := stat.NewViewResultContext(ctx, s.db, v)
+		if err != nil {
+			// Views which have never been collected are not available, skip them further. Otherwise, forget previous
+			// stats, deltas of the next snapshot would cover more than interval.
+			if !ok {
+				s.skipped[name] = true
+			}
+			delete(s.prev, name)
+			_, _ = fmt.Fprintf(s.w, "WARNING: skip %s: %s\n", name, err)
+			continue
+		}
+
+		if ok {
+			stats[name] = diffRows(res, prev, v)
+		}
+		s.prev[name] = res
+	}
+
+	return stats
+}
+
+// statementsResetTime returns time of the last reset of pg_stat_statements. Time of the first collection is returned if
+// it is not tracked by Postgres (before Postgres 14), or statements have never been reset.
+func (s *snapshotter) statementsResetTime(ctx context.Context) time.Time {
+	var age int64
+	err := s.db.QueryRowContext(ctx, query.GetStatementsResetAge).Scan(&age)
+	if err != nil || age < 0 {
+		return s.lastTs
+	}
+
+	return time.Now().Add(-time.Duration(age) * time.Second)
+}
+
+// resetStatements resets pg_stat_statements and collects stats of statements views again, they are used for
+// calculating deltas of the next snapshot.
+func (s *snapshotter) resetStatements(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, query.ExecResetPgStatStatements)
+	if err != nil {
+		return err
+	}
+
+	s.lastReset = time.Now()
+
+	for name, v := range s.views {
+		if !strings.HasPrefix(name, "statements_") || s.skipped[name] {
+			continue
+		}
+
+		res, err := stat.NewViewResultContext(ctx, s.db, v)
+		if err != nil {
+			// Deltas of the next snapshot could not be calculated, the view will be collected again.
+			delete(s.prev, name)
+			continue
+		}
+		s.prev[name] = res
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// numericRE defines format of values which could be diffed.
+var numericRE = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// statsRow defines diffed stats of a single row of the view.
+type statsRow struct {
+	key   string            // value of the view's unique key column
+	stats map[string]string // deltas of counters keyed by column name
+}
+
+// schemaQueries returns queries which create schema, tables and functions for storing snapshots. Snapshot rows hold
+// time when snapshot has been taken and number of seconds since the previous snapshot, stats rows hold deltas of
+// counters of views' rows in JSON. Function compare() returns sums and rates per second of deltas between two snapshots.
+func schemaQueries(schema string) []string {
+	s := pgx.Identifier{schema}.Sanitize()
+
+	return []string{
+		"CREATE SCHEMA IF NOT EXISTS " + s,
+		"CREATE TABLE IF NOT EXISTS " + s + ".snapshots (" +
+			"snapshot_id bigserial PRIMARY KEY, snapshot_ts timestamptz NOT NULL, source text NOT NULL, seconds numeric NOT NULL)",
+		"CREATE TABLE IF NOT EXISTS " + s + ".snapshot_stats (" +
+			"snapshot_id bigint NOT NULL REFERENCES " + s + ".snapshots ON DELETE CASCADE, view text NOT NULL, key text NOT NULL, stats jsonb NOT NULL)",
+		"CREATE INDEX IF NOT EXISTS snapshot_stats_snapshot_id_view_idx ON " + s + ".snapshot_stats (snapshot_id, view)",
+		"CREATE INDEX IF NOT EXISTS snapshots_snapshot_ts_idx ON " + s + ".snapshots (snapshot_ts)",
+		"CREATE OR REPLACE FUNCTION " + s + ".compare(from_id bigint, to_id bigint) " +
+			"RETURNS TABLE (view text, key text, metric text, total numeric, rate numeric) AS $$ " +
+			"SELECT st.view, st.key, m.key, sum(m.value::numeric), round(sum(m.value::numeric) / nullif((" +
+			"SELECT sum(s.seconds) FROM " + s + ".snapshots s WHERE s.snapshot_id > from_id AND s.snapshot_id <= to_id), 0), 2) " +
+			"FROM " + s + ".snapshot_stats st, jsonb_each_text(st.stats) m " +
+			"WHERE st.snapshot_id > from_id AND st.snapshot_id <= to_id " +
+			"GROUP BY st.view, st.key, m.key $$ LANGUAGE sql STABLE",
+	}
+}
+
+// initSchema creates schema, tables and functions for storing snapshots if they don't exist.
+func initSchema(db *postgres.DB, schema string) error {
+	for _, q := range schemaQueries(schema) {
+		_, err := db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("%s, query: %s", err, q)
+		}
+	}
+	return nil
+}
+
+// diffRows returns deltas of counters of the view's rows between previous and current stats. Rows absent in previous
+// stats are skipped. Counters which became less than in previous stats (e.g. after stats reset) are taken as-is.
+func diffRows(curr, prev stat.PGresult, v view.View) []statsRow {
+	ukey := v.UniqueKey
+
+	prevRows := make(map[string][]string, len(prev.Values))
+	for _, row := range prev.Values {
+		if ukey >= len(row) {
+			continue
+		}
+		values := make([]string, len(row))
+		for i := range row {
+			values[i] = row[i].String
+		}
+		prevRows[row[ukey].String] = values
+	}
+
+	var rows []statsRow
+	for _, row := range curr.Values {
+		if ukey >= len(row) {
+			continue
+		}
+
+		p, ok := prevRows[row[ukey].String]
+		if !ok {
+			continue
+		}
+
+		stats := map[string]string{}
+		for i := v.DiffIntvl[0]; i <= v.DiffIntvl[1] && i < len(row) && i < len(p) && i < len(curr.Cols); i++ {
+			if d, ok := delta(row[i].String, p[i]); ok {
+				stats[curr.Cols[i]] = d
+			}
+		}
+
+		if len(stats) > 0 {
+			rows = append(rows, statsRow{key: row[ukey].String, stats: stats})
+		}
+	}
+
+	return rows
+}
+
+// delta returns difference between current and previous values of counter. False is returned for non-numeric values.
+func delta(curr, prev string) (string, bool) {
+	if !numericRE.MatchString(curr) || !numericRE.MatchString(prev) {
+		return "", false
+	}
+
+	c, _ := strconv.ParseFloat(curr, 64)
+	p, _ := strconv.ParseFloat(prev, 64)
+
+	d := c - p
+	if d < 0 {
+		d = c
+	}
+
+	// Avoid floating point noise in values with fractional part, e.g. times in milliseconds.
+	if strings.Contains(curr, ".") || strings.Contains(prev, ".") {
+		d = math.Round(d*1000) / 1000
+	}
+
+	return strconv.FormatFloat(d, 'f', -1, 64), true
+}
+
+// store saves snapshot of diffed stats and returns its identifier. Storing is cancelled when context is done.
+func store(ctx context.Context, db *postgres.DB, schema string, source string, ts time.Time, seconds float64, stats map[string][]statsRow) (int64, error) {
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Transaction is rolled back even if storing has been cancelled.
+	defer func() {
+		_ = tx.Rollback(context.Background())
+	}()
+
+	var id int64
+	err = tx.QueryRow(ctx,
+		"INSERT INTO "+pgx.Identifier{schema}.Sanitize()+".snapshots (snapshot_ts, source, seconds) VALUES ($1, $2, $3) RETURNING snapshot_id",
+		ts, source, seconds,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	var values [][]interface{}
+	for name, rows := range stats {
+		for _, r := range rows {
+			data, err := json.Marshal(jsonStats(r.stats))
+			if err != nil {
+				return 0, err
+			}
+			values = append(values, []interface{}{id, name, r.key, &pgtype.JSONB{Bytes: data, Status: pgtype.Present}})
+		}
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{schema, "snapshot_stats"}, []string{"snapshot_id", "view", "key", "stats"}, pgx.CopyFromRows(values))
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit(ctx)
+}
+
+// jsonStats converts deltas into JSON numbers.
+func jsonStats(stats map[string]string) map[string]json.Number {
+	res := make(map[string]json.Number, len(stats))
+	for k, v := range stats {
+		res[k] = json.Number(v)
+	}
+	return res
+}
+
+// cleanup removes snapshots taken before specified time and returns number of removed snapshots.
+func cleanup(ctx context.Context, db *postgres.DB, schema string, before time.Time) (int64, error) {
+	tag, err := db.ExecContext(ctx, "DELETE FROM "+pgx.Identifier{schema}.Sanitize()+".snapshots WHERE snapshot_ts < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
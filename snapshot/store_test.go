@@ -0,0 +1,75 @@
+package snapshot
+
+import (
+	"database/sql"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_schemaQueries(t *testing.T) {
+	queries := schemaQueries("pgcenter_snapshots")
+	assert.Equal(t, `CREATE SCHEMA IF NOT EXISTS "pgcenter_snapshots"`, queries[0])
+	for _, q := range queries[1:] {
+		assert.Contains(t, q, `"pgcenter_snapshots".`)
+	}
+}
+
+func Test_diffRows(t *testing.T) {
+	v := view.View{DiffIntvl: [2]int{1, 3}, UniqueKey: 0}
+	newRow := func(values ...string) []sql.NullString {
+		row := make([]sql.NullString, len(values))
+		for i, s := range values {
+			row[i] = sql.NullString{String: s, Valid: s != ""}
+		}
+		return row
+	}
+
+	prev := stat.PGresult{
+		Cols: []string{"datname", "commits", "blks_read", "time_ms", "size"},
+		Values: [][]sql.NullString{
+			newRow("db1", "100", "1000", "10.5", "8MB"),
+			newRow("db2", "200", "", "20.1", "8MB"),
+		},
+	}
+	curr := stat.PGresult{
+		Cols: prev.Cols,
+		Values: [][]sql.NullString{
+			newRow("db1", "150", "1200", "10.7", "9MB"),
+			newRow("db2", "50", "10", "20.1", "8MB"), // stats reset
+			newRow("db3", "10", "10", "1.0", "8MB"),  // new row
+		},
+	}
+
+	assert.Equal(t, []statsRow{
+		{key: "db1", stats: map[string]string{"commits": "50", "blks_read": "200", "time_ms": "0.2"}},
+		{key: "db2", stats: map[string]string{"commits": "50", "time_ms": "0"}},
+	}, diffRows(curr, prev, v))
+}
+
+func Test_delta(t *testing.T) {
+	testcases := []struct {
+		curr, prev string
+		want       string
+		valid      bool
+	}{
+		{curr: "150", prev: "100", want: "50", valid: true},
+		{curr: "10.75", prev: "10.5", want: "0.25", valid: true},
+		{curr: "10", prev: "100", want: "10", valid: true},
+		{curr: "9MB", prev: "8MB", valid: false},
+		{curr: "", prev: "1", valid: false},
+	}
+
+	for _, tc := range testcases {
+		got, ok := delta(tc.curr, tc.prev)
+		assert.Equal(t, tc.valid, ok)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func Test_jsonStats(t *testing.T) {
+	got := jsonStats(map[string]string{"commits": "50", "time_ms": "0.2"})
+	assert.Equal(t, "50", got["commits"].String())
+	assert.Equal(t, "0.2", got["time_ms"].String())
+}
@@ -0,0 +1,381 @@
+// 'pgcenter stat' - takes a number of stats snapshots, prints them to stdout and exits.
+
+package stat
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/align"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatTable defines output as aligned table, similar to 'pgcenter report'.
+	FormatTable = "table"
+	// FormatJSON defines output as JSON object per snapshot, one object per line.
+	FormatJSON = "json"
+	// FormatCSV defines output as CSV with header, each row contains time of the snapshot.
+	FormatCSV = "csv"
+
+	// noTruncLimit defines truncation limit used when truncation of values is disabled.
+	noTruncLimit = 1 << 20
+)
+
+// Config defines config container for configuring 'pgcenter stat'.
+type Config struct {
+	View         string        // name of stats view, unique prefix of the name is allowed
+	Interval     time.Duration // interval between snapshots, rates are calculated over this interval
+	Count        int           // number of snapshots to print
+	Format       string        // output format: table, json, csv
+	OrderColName string        // name of the column used for sorting, view's default is used if empty
+	OrderDesc    bool          // use descending order
+	RowLimit     int           // number of rows per snapshot, 0 means no limit
+	TruncLimit   int           // maximum length of values in table output
+}
+
+// RunMain is the 'pgcenter stat' main entry point.
+func RunMain(dbConfig postgres.Config, config Config) error {
+	err := config.validate()
+	if err != nil {
+		return err
+	}
+
+	db, err := postgres.Connect(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	props, err := stat.GetPostgresProperties(db)
+	if err != nil {
+		return err
+	}
+
+	views := view.New()
+	err = views.Configure(props.QueryOptions(0))
+	if err != nil {
+		return err
+	}
+
+	v, err := selectView(views, config.View)
+	if err != nil {
+		return err
+	}
+
+	app := &app{config: config, db: db, view: v, writer: os.Stdout, notice: os.Stderr}
+
+	return app.run()
+}
+
+// validate checks config.
+func (c Config) validate() error {
+	if c.Interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s")
+	}
+
+	if c.Count < 1 {
+		return fmt.Errorf("count must be at least 1")
+	}
+
+	switch c.Format {
+	case FormatTable, FormatJSON, FormatCSV:
+	default:
+		return fmt.Errorf("unknown format '%s', supported: table, json, csv", c.Format)
+	}
+
+	if c.RowLimit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+
+	return nil
+}
+
+// viewAliases defines short names of views.
+var viewAliases = map[string]string{
+	"statements":      "statements_timings",
+	"statements_time": "statements_timings",
+	"repl":            "replication",
+}
+
+// selectView returns view with specified name. Aliases and unique prefixes of view names are also accepted, e.g.
+// 'statements_time' or 'repl'.
+func selectView(views view.Views, name string) (view.View, error) {
+	if alias, ok := viewAliases[name]; ok {
+		name = alias
+	}
+
+	if v, ok := views[name]; ok {
+		return v, nil
+	}
+
+	var names, matched []string
+	for n := range views {
+		names = append(names, n)
+		if name != "" && strings.HasPrefix(n, name) {
+			matched = append(matched, n)
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(matched)
+
+	switch len(matched) {
+	case 0:
+		return view.View{}, fmt.Errorf("unknown view '%s', available: %s", name, strings.Join(names, ", "))
+	case 1:
+		return views[matched[0]], nil
+	default:
+		return view.View{}, fmt.Errorf("ambiguous view '%s', matches: %s", name, strings.Join(matched, ", "))
+	}
+}
+
+// app defines 'pgcenter stat' runtime dependencies.
+type app struct {
+	config Config
+	db     *postgres.DB
+	view   view.View
+	writer io.Writer
+	notice io.Writer // notices are printed separately from stats, to keep output machine-readable
+}
+
+// run takes snapshots and prints them. Views with rates require one more snapshot taken before the first printed.
+func (app *app) run() error {
+	var (
+		prev stat.PGresult
+		itv  = int(app.config.Interval / time.Second)
+	)
+
+	p, err := newPrinter(app.writer, app.config)
+	if err != nil {
+		return err
+	}
+
+	if app.view.DiffIntvl != [2]int{0, 0} {
+		prev, err = stat.NewPGresult(app.db, app.view.Query, app.view.Args...)
+		if err != nil {
+			return err
+		}
+		time.Sleep(app.config.Interval)
+	}
+
+	for i := 0; i < app.config.Count; i++ {
+		if i > 0 {
+			time.Sleep(app.config.Interval)
+		}
+
+		curr, err := stat.NewPGresult(app.db, app.view.Query, app.view.Args...)
+		if err != nil {
+			return err
+		}
+
+		ts := time.Now()
+
+		res, err := stat.Compare(curr, prev, itv, app.view)
+		if err != nil {
+			return err
+		}
+		prev = curr
+
+		if res.Reset && app.notice != nil {
+			_, err = fmt.Fprintf(app.notice, "NOTICE: stats reset detected at %s, rates are calculated since reset\n", ts.Format("15:04:05"))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = arrange(&res, app.view, app.config)
+		if err != nil {
+			return err
+		}
+
+		err = p.print(ts, app.view.Name, res)
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.flush()
+}
+
+// arrange sorts result using specified column (or view's default) and limits number of rows.
+func arrange(res *stat.PGresult, v view.View, c Config) error {
+	key, desc := v.OrderKey, v.OrderDesc
+	if c.OrderColName != "" {
+		key = -1
+		for i, col := range res.Cols {
+			if col == c.OrderColName {
+				key = i
+				break
+			}
+		}
+		if key < 0 {
+			return fmt.Errorf("unknown column '%s', available: %s", c.OrderColName, strings.Join(res.Cols, ", "))
+		}
+		desc = c.OrderDesc
+	}
+
+	if key < res.Ncols {
+		res.Sort(key, desc)
+	}
+
+	if c.RowLimit > 0 && res.Nrows > c.RowLimit {
+		res.Values = res.Values[:c.RowLimit]
+		res.Nrows = c.RowLimit
+	}
+
+	return nil
+}
+
+// printer prints stats snapshots in particular format.
+type printer interface {
+	print(ts time.Time, name string, res stat.PGresult) error
+	flush() error
+}
+
+// newPrinter creates printer of specified format.
+func newPrinter(w io.Writer, c Config) (printer, error) {
+	switch c.Format {
+	case FormatTable:
+		return &tablePrinter{w: w, truncLimit: c.TruncLimit}, nil
+	case FormatJSON:
+		return &jsonPrinter{enc: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		return &csvPrinter{w: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown format '%s'", c.Format)
+	}
+}
+
+// tablePrinter prints snapshots as aligned tables: header, then rows, the first row begins with time of the snapshot.
+type tablePrinter struct {
+	w          io.Writer
+	truncLimit int
+}
+
+// print implements printer interface.
+func (p *tablePrinter) print(ts time.Time, _ string, res stat.PGresult) error {
+	// Zero limit disables truncation.
+	limit := p.truncLimit
+	if limit <= 0 {
+		limit = noTruncLimit
+	}
+
+	widths, cols := align.SetAlign(res, limit, true)
+
+	var b strings.Builder
+	b.WriteString("         ")
+	for i, col := range cols {
+		if i < len(cols)-1 {
+			fmt.Fprintf(&b, "%-*s", widths[i]+2, col)
+		} else {
+			b.WriteString(col)
+		}
+	}
+	b.WriteString("\n")
+
+	for i, row := range res.Values {
+		if i == 0 {
+			b.WriteString(ts.Format("15:04:05") + " ")
+		} else {
+			b.WriteString("         ")
+		}
+
+		for j := range cols {
+			value := row[j].String
+			if len(value) > widths[j] {
+				value = value[:widths[j]-1] + "~"
+			}
+			if j < len(cols)-1 {
+				fmt.Fprintf(&b, "%-*s", widths[j]+2, value)
+			} else {
+				b.WriteString(value)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(p.w, b.String()+"\n")
+	return err
+}
+
+// flush implements printer interface.
+func (p *tablePrinter) flush() error { return nil }
+
+// snapshot defines snapshot printed in JSON format.
+type snapshot struct {
+	Time    time.Time   `json:"time"`
+	View    string      `json:"view"`
+	Columns []string    `json:"columns"`
+	Rows    [][]*string `json:"rows"` // NULL values are null
+}
+
+// jsonPrinter prints snapshots as JSON objects, one object per line.
+type jsonPrinter struct {
+	enc *json.Encoder
+}
+
+// print implements printer interface.
+func (p *jsonPrinter) print(ts time.Time, name string, res stat.PGresult) error {
+	s := snapshot{Time: ts, View: name, Columns: res.Cols, Rows: make([][]*string, 0, len(res.Values))}
+
+	for _, row := range res.Values {
+		values := make([]*string, len(row))
+		for i := range row {
+			if row[i].Valid {
+				v := row[i].String
+				values[i] = &v
+			}
+		}
+		s.Rows = append(s.Rows, values)
+	}
+
+	return p.enc.Encode(s)
+}
+
+// flush implements printer interface.
+func (p *jsonPrinter) flush() error { return nil }
+
+// csvPrinter prints snapshots as CSV. Header is printed once, each row begins with time of the snapshot.
+type csvPrinter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// print implements printer interface.
+func (p *csvPrinter) print(ts time.Time, _ string, res stat.PGresult) error {
+	if !p.header {
+		if err := p.w.Write(append([]string{"time"}, res.Cols...)); err != nil {
+			return err
+		}
+		p.header = true
+	}
+
+	t := ts.Format(time.RFC3339)
+	for _, row := range res.Values {
+		record := make([]string, 0, len(row)+1)
+		record = append(record, t)
+		for i := range row {
+			record = append(record, row[i].String)
+		}
+		if err := p.w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	// Flush every snapshot, hence output is available for consumers immediately.
+	p.w.Flush()
+	return p.w.Error()
+}
+
+// flush implements printer interface.
+func (p *csvPrinter) flush() error {
+	p.w.Flush()
+	return p.w.Error()
+}
@@ -0,0 +1,179 @@
+package stat
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_validate(t *testing.T) {
+	testcases := []struct {
+		valid  bool
+		config Config
+	}{
+		{valid: true, config: Config{Interval: time.Second, Count: 1, Format: "table"}},
+		{valid: true, config: Config{Interval: 5 * time.Second, Count: 3, Format: "json", RowLimit: 10}},
+		{valid: false, config: Config{Interval: time.Millisecond, Count: 1, Format: "table"}},
+		{valid: false, config: Config{Interval: time.Second, Count: 0, Format: "table"}},
+		{valid: false, config: Config{Interval: time.Second, Count: 1, Format: "xml"}},
+		{valid: false, config: Config{Interval: time.Second, Count: 1, Format: "csv", RowLimit: -1}},
+	}
+
+	for _, tc := range testcases {
+		if tc.valid {
+			assert.NoError(t, tc.config.validate())
+		} else {
+			assert.Error(t, tc.config.validate())
+		}
+	}
+}
+
+func Test_selectView(t *testing.T) {
+	views := view.New()
+
+	testcases := []struct {
+		valid bool
+		name  string
+		want  string
+	}{
+		{valid: true, name: "databases", want: "databases"},
+		{valid: true, name: "statements_time", want: "statements_timings"},
+		{valid: true, name: "repl", want: "replication"},
+		{valid: true, name: "statements", want: "statements_timings"},
+		{valid: true, name: "progress_v", want: "progress_vacuum"},
+		{valid: true, name: "replication_s", want: "replication_slots"},
+		{valid: false, name: "statements_"},
+		{valid: false, name: "progress"},
+		{valid: false, name: "invalid"},
+		{valid: false, name: ""},
+	}
+
+	for _, tc := range testcases {
+		got, err := selectView(views, tc.name)
+		if tc.valid {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got.Name)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
+
+// testResult returns result used in tests.
+func testResult() stat.PGresult {
+	return stat.PGresult{
+		Valid: true, Ncols: 3, Nrows: 3,
+		Cols: []string{"datname", "commits", "query"},
+		Values: [][]sql.NullString{
+			{{String: "db1", Valid: true}, {String: "10", Valid: true}, {String: "SELECT 1", Valid: true}},
+			{{String: "db2", Valid: true}, {String: "30", Valid: true}, {String: "", Valid: false}},
+			{{String: "db3", Valid: true}, {String: "20", Valid: true}, {String: "SELECT 'a long query text'", Valid: true}},
+		},
+	}
+}
+
+func Test_arrange(t *testing.T) {
+	v := view.View{OrderKey: 1, OrderDesc: true}
+
+	// Default order of the view.
+	res := testResult()
+	assert.NoError(t, arrange(&res, v, Config{}))
+	assert.Equal(t, []string{"db2", "db3", "db1"}, []string{res.Values[0][0].String, res.Values[1][0].String, res.Values[2][0].String})
+
+	// Order by specified column and limit.
+	res = testResult()
+	assert.NoError(t, arrange(&res, v, Config{OrderColName: "datname", OrderDesc: false, RowLimit: 2}))
+	assert.Equal(t, 2, res.Nrows)
+	assert.Len(t, res.Values, 2)
+	assert.Equal(t, "db1", res.Values[0][0].String)
+	assert.Equal(t, "db2", res.Values[1][0].String)
+
+	res = testResult()
+	assert.Error(t, arrange(&res, v, Config{OrderColName: "invalid"}))
+}
+
+func Test_printers(t *testing.T) {
+	ts := time.Date(2021, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	// JSON.
+	var buf bytes.Buffer
+	p, err := newPrinter(&buf, Config{Format: FormatJSON})
+	assert.NoError(t, err)
+	assert.NoError(t, p.print(ts, "databases", testResult()))
+	assert.NoError(t, p.print(ts.Add(time.Second), "databases", testResult()))
+	assert.NoError(t, p.flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var got snapshot
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &got))
+	assert.Equal(t, "databases", got.View)
+	assert.Equal(t, []string{"datname", "commits", "query"}, got.Columns)
+	assert.Len(t, got.Rows, 3)
+	assert.Nil(t, got.Rows[1][2])
+	assert.Equal(t, "SELECT 1", *got.Rows[0][2])
+
+	// CSV.
+	buf.Reset()
+	p, err = newPrinter(&buf, Config{Format: FormatCSV})
+	assert.NoError(t, err)
+	assert.NoError(t, p.print(ts, "databases", testResult()))
+	assert.NoError(t, p.print(ts, "databases", testResult()))
+	assert.NoError(t, p.flush())
+
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 7)
+	assert.Equal(t, "time,datname,commits,query", lines[0])
+	assert.Equal(t, "2021-01-01T12:30:00Z,db1,10,SELECT 1", lines[1])
+
+	// Table.
+	buf.Reset()
+	p, err = newPrinter(&buf, Config{Format: FormatTable, TruncLimit: 12})
+	assert.NoError(t, err)
+	assert.NoError(t, p.print(ts, "databases", testResult()))
+	assert.Equal(t,
+		"         datname   commits   query\n"+
+			"12:30:00 db1       10        SELECT 1\n"+
+			"         db2       30        \n"+
+			"         db3       20        SELECT 'a l~\n\n",
+		buf.String(),
+	)
+
+	// Truncation disabled.
+	buf.Reset()
+	p, err = newPrinter(&buf, Config{Format: FormatTable})
+	assert.NoError(t, err)
+	assert.NoError(t, p.print(ts, "databases", testResult()))
+	assert.Contains(t, buf.String(), "SELECT 'a long query text'\n")
+}
+
+func Test_app_run(t *testing.T) {
+	db, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	props, err := stat.GetPostgresProperties(db)
+	assert.NoError(t, err)
+	views := view.New()
+	assert.NoError(t, views.Configure(query.NewOptions(props.VersionNum, props.Recovery, props.GucTrackCommitTimestamp, 0)))
+
+	var buf bytes.Buffer
+	app := &app{
+		config: Config{Interval: time.Second, Count: 2, Format: FormatJSON},
+		db:     db,
+		view:   views["databases"],
+		writer: &buf,
+	}
+
+	assert.NoError(t, app.run())
+	assert.Len(t, strings.Split(strings.TrimSpace(buf.String()), "\n"), 2)
+}
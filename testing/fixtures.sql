@@ -20,6 +20,27 @@ if (my $settings = Linux::Ethtool::Settings->new($_[0])) {
 }
 $$;
 
+CREATE OR REPLACE FUNCTION pgcenter.get_netdevs_link_settings(OUT iface CHARACTER VARYING, OUT speed BIGINT, OUT duplex INTEGER) RETURNS SETOF RECORD
+    LANGUAGE plperlu
+AS $$
+use Linux::Ethtool::Settings;
+open FILE, '/proc/net/dev' or die "pgcenter: failed to open /proc/net/dev: $!\n";
+my $i = 0;
+while (<FILE>) {
+	# skip header.
+	if ($i < 2) { $i++; next; }
+	next unless /^\s*([^:\s]+):/;
+	my $iface = $1;
+	if (my $settings = Linux::Ethtool::Settings->new($iface)) {
+		return_next({iface => $iface, speed => $settings->speed(), duplex => $settings->duplex() ? 1 : 0});
+	} else {
+		return_next({iface => $iface, speed => 0, duplex => -1});
+	}
+}
+close FILE;
+return undef;
+$$;
+
 CREATE OR REPLACE FUNCTION pgcenter.get_sys_clk_ticks() RETURNS integer
     LANGUAGE plperlu
 AS $$
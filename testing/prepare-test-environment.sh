@@ -1,12 +1,12 @@
 #!/bin/bash
 
 # copy configuration files into data directories
-for v in 9.5 9.6 10 11 12 13; do
+for v in 9.5 9.6 10 11 12 13 14 15 16 17; do
   su - postgres -c "mv /etc/postgresql/${v}/main/postgresql.conf /var/lib/postgresql/${v}/main/"
 done
 
 # add extra configuration parameters
-for v in 9.5 9.6 10 11 12 13; do
+for v in 9.5 9.6 10 11 12 13 14 15 16 17; do
   port="219$(echo $v |tr -d .)"
   {
     echo "listen_addresses = '*'
@@ -32,18 +32,18 @@ host all all 0.0.0.0/0 trust"
 done
 
 # run main postgres
-for v in 9.5 9.6 10 11 12 13; do
+for v in 9.5 9.6 10 11 12 13 14 15 16 17; do
   su - postgres -c "/usr/lib/postgresql/${v}/bin/pg_ctl -w -t 30 -l /var/log/postgresql/startup-${v}.log -D /var/lib/postgresql/${v}/main start"
 done
 
 # install pgcenter schema
-for v in 9.5 9.6 10 11 12 13; do
+for v in 9.5 9.6 10 11 12 13 14 15 16 17; do
   port="219$(echo $v |tr -d .)"
   su - postgres -c "psql -p $port -f /usr/local/testing/fixtures.sql"
 done
 
 # check services availability
-for v in 9.5 9.6 10 11 12 13; do
+for v in 9.5 9.6 10 11 12 13 14 15 16 17; do
   port="219$(echo $v |tr -d .)"
   pg_isready -t 10 -h 127.0.0.1 -p "$port" -U postgres -d pgcenter_fixtures
 done
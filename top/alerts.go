@@ -0,0 +1,103 @@
+package top
+
+import (
+	"context"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/lesovsky/pgcenter/internal/header"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+)
+
+// startAlerts creates alerts monitors for all instances and runs them until context is done. Rules are evaluated using
+// stats collected over separate connection to each instance, hence they don't depend on the view shown in UI. Errors of
+// rules evaluation and notifications are shown in command line.
+func startAlerts(ctx context.Context, app *app, config alert.Config) error {
+	logf := func(format string, a ...interface{}) {
+		printCmdline(app.ui, format, a...)
+	}
+
+	for _, inst := range app.instances {
+		m, err := alert.NewMonitor(config, logf)
+		if err != nil {
+			return err
+		}
+		m.SetHooks(app.hooks)
+		m.SetInstance(inst.db.Config)
+
+		db, err := postgres.Connect(inst.db.Config)
+		if err != nil {
+			return err
+		}
+
+		s, err := stat.NewSampler(db, m.Interval())
+		if err != nil {
+			db.Close()
+			return err
+		}
+		s.SetFilter(inst.config.devices)
+
+		views := view.New()
+		err = views.Configure(s.Properties().QueryOptions(0))
+		if err == nil {
+			err = m.Configure(views)
+		}
+		if err != nil {
+			db.Close()
+			return err
+		}
+		m.Require(s)
+
+		inst.alerts = m
+		go func() {
+			s.Run(ctx, m.Evaluate)
+			db.Close()
+		}()
+	}
+
+	return nil
+}
+
+// printAlerts shows banner with firing alerts of the current instance on the right side of command line. The banner
+// is removed when there are no firing alerts.
+func printAlerts(g *gocui.Gui, app *app) error {
+	var alerts []alert.Alert
+	if m := app.instances[app.current].alerts; m != nil {
+		alerts = m.Firing()
+	}
+
+	// Alerts are shown in the header, if it's configured so.
+	if app.config.header.Has(header.Alerts) {
+		alerts = nil
+	}
+
+	if len(alerts) == 0 {
+		if err := g.DeleteView("alerts"); err != nil && err != gocui.ErrUnknownView {
+			return fmt.Errorf("delete alerts view failed: %s", err)
+		}
+		return nil
+	}
+
+	maxX, _ := g.Size()
+	height := app.config.header.Height()
+	v, err := g.SetView("alerts", maxX/2, height-1, maxX, height+1)
+	if err != nil && err != gocui.ErrUnknownView {
+		return fmt.Errorf("set alerts view failed: %s", err)
+	}
+	v.Frame = false
+	v.Clear()
+
+	_, err = fmt.Fprintf(v, "\033[31;1m%s\033[0m", formatAlerts(alerts))
+	return err
+}
+
+// formatAlerts returns banner text: the first alert and number of others.
+func formatAlerts(alerts []alert.Alert) string {
+	s := "ALERT: " + alerts[0].String()
+	if len(alerts) > 1 {
+		s += fmt.Sprintf(", +%d more", len(alerts)-1)
+	}
+	return s
+}
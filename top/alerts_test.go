@@ -0,0 +1,17 @@
+package top
+
+import (
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_formatAlerts(t *testing.T) {
+	alerts := []alert.Alert{
+		{Rule: "replication_lag", Labels: []alert.Label{{Name: "client_addr", Value: "10.0.0.2"}}, Value: 150.123},
+		{Rule: "xid_age", Value: 1.6e9},
+	}
+
+	assert.Equal(t, "ALERT: replication_lag (client_addr=10.0.0.2): 150.12, +1 more", formatAlerts(alerts))
+	assert.Equal(t, "ALERT: xid_age: 1600000000", formatAlerts(alerts[1:]))
+}
@@ -0,0 +1,95 @@
+package top
+
+import (
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/audit"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+)
+
+// auditRecordsMax defines how many the most recent audit records are shown in audit popup.
+const auditRecordsMax = 200
+
+// auditAction writes action performed from UI into audit log. Connected role and role set at runtime are added to
+// the record. Error is returned if record could not be written.
+func auditAction(log *audit.Log, db *postgres.DB, action string, target string, actionErr error) error {
+	r := audit.NewRecord(action, target, actionErr)
+	r.Instance = hook.InstanceName(db.Config)
+	r.Role = db.Config.Role()
+	if db.Config.Config != nil {
+		r.User = db.Config.Config.User
+	}
+
+	return log.Add(r)
+}
+
+// auditMessage appends failure of writing audit record to the message shown in command line.
+func auditMessage(messages *i18n.Catalog, msg string, err error) string {
+	if err == nil {
+		return msg
+	}
+	return messages.Sprintf("cmdline.audit", msg, err)
+}
+
+// showAuditLog opens popup with the most recent records of audit log.
+func showAuditLog(app *app) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		if app.audit == nil {
+			printCmdline(g, app.config.messages.T("cmdline.audit.not_configured"))
+			return nil
+		}
+
+		records, err := audit.Read(app.audit.Path(), auditRecordsMax)
+		if err != nil {
+			printCmdline(g, app.config.messages.T("cmdline.audit.failed"), err)
+			return nil
+		}
+
+		maxX, maxY := g.Size()
+		v, err := g.SetView("auditlog", maxX/10, maxY/5, 9*maxX/10, 4*maxY/5)
+		if err != nil {
+			// gocui.ErrUnknownView is OK, it means a new view has been created.
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("set auditlog view on layout failed: %s", err)
+			}
+		}
+
+		v.Title = app.config.messages.Sprintf("audit.title", app.audit.Path())
+		v.Frame = true
+		v.Autoscroll = true
+		v.Clear()
+
+		content := audit.Format(records)
+		if content == "" {
+			content = app.config.messages.T("audit.empty") + "\n"
+		}
+
+		_, err = fmt.Fprint(v, content)
+		if err != nil {
+			return fmt.Errorf("print on auditlog view failed: %s", err)
+		}
+
+		if _, err := g.SetCurrentView("auditlog"); err != nil {
+			return fmt.Errorf("set auditlog view as current on layout failed: %s", err)
+		}
+
+		return nil
+	}
+}
+
+// closeAuditLog closes popup with audit log.
+func closeAuditLog(g *gocui.Gui, v *gocui.View) error {
+	v.Clear()
+	err := g.DeleteView("auditlog")
+	if err != nil {
+		return fmt.Errorf("delete auditlog view failed: %s", err)
+	}
+
+	if _, err := g.SetCurrentView("sysstat"); err != nil {
+		return fmt.Errorf("set focus on sysstat view failed: %s", err)
+	}
+
+	return nil
+}
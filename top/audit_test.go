@@ -0,0 +1,49 @@
+package top
+
+import (
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/audit"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_auditAction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pgcenter-audit-")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	config, err := postgres.ParseConfig("host=primary port=5432 user=alice dbname=shop")
+	assert.NoError(t, err)
+	db := &postgres.DB{Config: config}
+
+	filename := filepath.Join(dir, "audit.log")
+	log, err := audit.NewLog(filename)
+	assert.NoError(t, err)
+
+	assert.NoError(t, auditAction(log, db, audit.ActionCancel, "pid 123", nil))
+	assert.NoError(t, auditAction(log, db, audit.ActionReload, "", fmt.Errorf("permission denied")))
+	assert.NoError(t, log.Close())
+
+	got, err := audit.Read(filename, 0)
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, "primary:5432/shop", got[0].Instance)
+	assert.Equal(t, "alice", got[0].User)
+	assert.Equal(t, "pid 123", got[0].Target)
+	assert.Equal(t, "ok", got[0].Result)
+	assert.Equal(t, audit.ActionReload, got[1].Action)
+	assert.Equal(t, "permission denied", got[1].Result)
+
+	// Audit is not configured.
+	assert.NoError(t, auditAction(nil, db, audit.ActionCancel, "pid 123", nil))
+}
+
+func Test_auditMessage(t *testing.T) {
+	assert.Equal(t, "Signals: done", auditMessage(i18n.Default(), "Signals: done", nil))
+	assert.Equal(t, "Signals: done Audit: write audit file failed", auditMessage(i18n.Default(), "Signals: done", fmt.Errorf("write audit file failed")))
+}
@@ -0,0 +1,61 @@
+package top
+
+import (
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/stat"
+)
+
+// showCapabilities probes the current Postgres and opens popup with availability of stats views.
+func showCapabilities(app *app) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		return openCapabilities(g, stat.GetCapabilities(app.db, app.postgresProps))
+	}
+}
+
+// openCapabilities opens popup with availability of stats views and reasons why views are not fully available.
+func openCapabilities(g *gocui.Gui, caps []stat.Capability) error {
+	maxX, maxY := g.Size()
+	v, err := g.SetView("capabilities", maxX/8, maxY/6, 7*maxX/8, 5*maxY/6)
+	if err != nil {
+		// gocui.ErrUnknownView is OK, it means a new view has been created.
+		if err != gocui.ErrUnknownView {
+			return fmt.Errorf("set capabilities view on layout failed: %s", err)
+		}
+	}
+
+	v.Title = " Views availability (Esc or q - close) "
+	v.Frame = true
+	v.Clear()
+
+	msg := stat.CapabilitiesSummary(caps)
+	if msg == "" {
+		msg = "All views are available."
+	}
+
+	_, err = fmt.Fprintf(v, "%s\n\n%s", msg, stat.FormatCapabilities(caps))
+	if err != nil {
+		return fmt.Errorf("print on capabilities view failed: %s", err)
+	}
+
+	if _, err := g.SetCurrentView("capabilities"); err != nil {
+		return fmt.Errorf("set capabilities view as current on layout failed: %s", err)
+	}
+
+	return nil
+}
+
+// closeCapabilities closes popup with availability of stats views.
+func closeCapabilities(g *gocui.Gui, v *gocui.View) error {
+	v.Clear()
+	err := g.DeleteView("capabilities")
+	if err != nil {
+		return fmt.Errorf("delete capabilities view failed: %s", err)
+	}
+
+	if _, err := g.SetCurrentView("sysstat"); err != nil {
+		return fmt.Errorf("set focus on sysstat view failed: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,601 @@
+package top
+
+import (
+	"context"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/log"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// clusterRefresh defines refresh interval of cluster overview.
+	clusterRefresh = time.Second
+	// clusterSizeInterval defines interval of updating databases sizes, calculating sizes is relatively expensive.
+	clusterSizeInterval = 30 * time.Second
+	// clusterReconnectInterval defines interval between attempts to connect to instances which are down.
+	clusterReconnectInterval = 5 * time.Second
+	// clusterDiscoveryInterval defines interval of refreshing list of discovered instances.
+	clusterDiscoveryInterval = 10 * time.Second
+)
+
+// ClusterInstance defines instance shown in cluster overview.
+type ClusterInstance struct {
+	Name   string          // name shown in overview
+	Config postgres.Config // connection config
+}
+
+// DiscoverFunc returns current list of cluster instances, e.g. requested from Patroni.
+type DiscoverFunc func(ctx context.Context) ([]ClusterInstance, error)
+
+// RunCluster is the main entry point for cluster overview mode of 'pgcenter top'. Overview shows one row per instance,
+// pressing Enter opens the usual 'pgcenter top' for the selected instance, quitting it returns back to overview.
+// When discover function is specified, list of instances is refreshed periodically, hence failovers are followed.
+func RunCluster(instances []ClusterInstance, discover DiscoverFunc, opts Options) error {
+	if discover != nil {
+		var err error
+		instances, err = discover(context.Background())
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(instances) == 0 {
+		return fmt.Errorf("no instances specified")
+	}
+
+	// Connect to instances before starting UI, hence passwords could be asked. Unavailable instances are shown as down.
+	o := &overview{discover: discover}
+	o.merge(instances)
+	for _, m := range o.members {
+		m.collect(time.Now())
+	}
+
+	defer func() {
+		for _, m := range o.members {
+			if m.db != nil {
+				m.db.Close()
+			}
+		}
+	}()
+
+	for {
+		selected, err := o.run()
+		if err != nil {
+			return err
+		}
+
+		// Overview has been quit.
+		if selected == nil {
+			return nil
+		}
+
+		// Other discovered instances which are up are available for switching with Tab.
+		drillOpts := opts
+		if discover != nil {
+			for _, m := range o.members {
+				if m != selected && m.snapshot().up {
+					drillOpts.Instances = append(drillOpts.Instances, m.Config)
+				}
+			}
+		}
+
+		o.notice = ""
+		err = RunMain(selected.Config, drillOpts)
+		if err != nil {
+			o.notice = fmt.Sprintf("%s: %s", selected.Name, err)
+		}
+	}
+}
+
+// member defines instance in cluster overview and its connection.
+type member struct {
+	ClusterInstance
+	db      *postgres.DB // connection to instance, nil if instance is down
+	query   string       // overview query depending on Postgres version
+	lastTry time.Time    // time of the last connection attempt
+
+	mu   sync.Mutex
+	stat memberStat
+}
+
+// memberStat defines overview stats of the instance.
+type memberStat struct {
+	up       bool
+	err      string  // the last error
+	version  string  // Postgres version
+	recovery bool    // true for standby
+	tps      float64 // transactions per second
+	rates    bool    // tps is available
+	active   int64   // number of active backends
+	replicas int64   // number of connected replicas
+	lag      int64   // replication lag in bytes: replay lag of standby, or maximal lag of replicas of primary
+	size     int64   // total size of databases in bytes, -1 if unknown
+
+	xacts   int64     // transactions counter used for calculating tps
+	xactsTs time.Time // time when counter has been read
+	sizeTs  time.Time // time when size has been read
+}
+
+// overviewRow defines result of overview query.
+type overviewRow struct {
+	version  string
+	recovery bool
+	xacts    int64
+	active   int64
+	replicas int64
+	lag      int64
+}
+
+// update updates stats using the result of overview query and calculates tps.
+func (s *memberStat) update(r overviewRow, now time.Time) {
+	// Counter is decreased after stats reset or failover, tps can't be calculated.
+	s.rates = s.up && !s.xactsTs.IsZero() && r.xacts >= s.xacts && now.After(s.xactsTs)
+	if s.rates {
+		s.tps = float64(r.xacts-s.xacts) / now.Sub(s.xactsTs).Seconds()
+	}
+
+	s.up, s.err = true, ""
+	s.version, s.recovery, s.active, s.replicas, s.lag = r.version, r.recovery, r.active, r.replicas, r.lag
+	s.xacts, s.xactsTs = r.xacts, now
+}
+
+// down marks instance as down.
+func (s *memberStat) down(err error) {
+	s.up, s.rates, s.err = false, false, err.Error()
+	s.xactsTs = time.Time{}
+}
+
+// collect collects overview stats of the instance. Instance which is down is reconnected periodically.
+func (m *member) collect(now time.Time) {
+	if m.db == nil {
+		if now.Sub(m.lastTry) < clusterReconnectInterval {
+			return
+		}
+		m.lastTry = now
+
+		err := m.connect()
+		if err != nil {
+			m.setDown(err)
+			return
+		}
+	}
+
+	var r overviewRow
+	err := m.db.QueryRow(m.query).Scan(&r.version, &r.recovery, &r.xacts, &r.active, &r.replicas, &r.lag)
+	if err != nil {
+		// Connection is lost, reconnect at the next attempt.
+		if m.db.PQstatus() != nil {
+			m.db.Close()
+			m.db = nil
+			m.lastTry = now
+		}
+		m.setDown(err)
+		return
+	}
+
+	m.mu.Lock()
+	m.stat.update(r, now)
+	updateSize := now.Sub(m.stat.sizeTs) >= clusterSizeInterval
+	m.mu.Unlock()
+
+	if updateSize {
+		var size int64
+		if err := m.db.QueryRow(query.ClusterDatabasesSize).Scan(&size); err != nil {
+			size = -1
+		}
+
+		m.mu.Lock()
+		m.stat.size, m.stat.sizeTs = size, now
+		m.mu.Unlock()
+	}
+}
+
+// connect connects to the instance and selects overview query depending on Postgres version.
+func (m *member) connect() error {
+	db, err := postgres.Connect(m.Config)
+	if err != nil {
+		return err
+	}
+
+	props, err := stat.GetPostgresProperties(db)
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	m.db, m.query = db, query.SelectClusterOverviewQuery(props.VersionNum)
+
+	// Connection config keeps password entered by user, use it for further connections.
+	m.Config = db.Config
+
+	m.mu.Lock()
+	m.stat.sizeTs = time.Time{}
+	m.mu.Unlock()
+
+	return nil
+}
+
+// setDown marks instance as down.
+func (m *member) setDown(err error) {
+	m.mu.Lock()
+	m.stat.down(err)
+	m.mu.Unlock()
+}
+
+// snapshot returns copy of the instance stats.
+func (m *member) snapshot() memberStat {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stat
+}
+
+// address returns address of the instance.
+func (m *member) address() string {
+	if m.Config.Config == nil {
+		return ""
+	}
+	return net.JoinHostPort(m.Config.Config.Host, strconv.Itoa(int(m.Config.Config.Port)))
+}
+
+// overview defines cluster overview UI.
+type overview struct {
+	discover     DiscoverFunc // refreshes list of instances, nil if list is static
+	notice       string       // message shown in command line when UI starts
+	lastDiscover time.Time    // time of the last refreshing of instances list
+
+	mu       sync.Mutex
+	members  []*member
+	selected int     // index of selected instance
+	choice   *member // instance chosen with Enter, nil means quit
+}
+
+// merge updates list of members using discovered instances. Known instances keep their connections and stats,
+// connections to instances which have gone are closed.
+func (o *overview) merge(instances []ClusterInstance) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	known := map[string]*member{}
+	for _, m := range o.members {
+		known[m.Name+"\x00"+m.address()] = m
+	}
+
+	members := make([]*member, 0, len(instances))
+	for _, inst := range instances {
+		m := &member{ClusterInstance: inst}
+		key := m.Name + "\x00" + m.address()
+		if k, ok := known[key]; ok {
+			m = k
+			delete(known, key)
+		}
+		members = append(members, m)
+	}
+
+	for _, m := range known {
+		if m.db != nil {
+			m.db.Close()
+			m.db = nil
+		}
+	}
+
+	o.members = members
+	if o.selected >= len(members) {
+		o.selected = len(members) - 1
+	}
+	if o.selected < 0 {
+		o.selected = 0
+	}
+}
+
+// list returns current list of members.
+func (o *overview) list() []*member {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.members
+}
+
+// run runs overview UI until user chooses an instance or quits. Returns chosen instance, or nil on quit.
+func (o *overview) run() (*member, error) {
+	// Terminal is used by UI, log records written to stderr would break the screen.
+	log.DisableStderr()
+
+	g, err := gocui.NewGui(gocui.OutputNormal)
+	if err != nil {
+		return nil, fmt.Errorf("create UI failed: %s", err)
+	}
+	defer g.Close()
+
+	o.choice = nil
+	g.SetManagerFunc(o.layout)
+
+	keys := []key{
+		{"", gocui.KeyCtrlC, o.quit},
+		{"", gocui.KeyCtrlQ, o.quit},
+		{"", 'q', o.quit},
+		{"", gocui.KeyArrowUp, o.move(-1)},
+		{"", 'k', o.move(-1)},
+		{"", gocui.KeyArrowDown, o.move(1)},
+		{"", 'j', o.move(1)},
+		{"", gocui.KeyEnter, o.choose},
+	}
+	for _, k := range keys {
+		if err := g.SetKeybinding(k.viewname, k.key, gocui.ModNone, k.handler); err != nil {
+			return nil, fmt.Errorf("set keybinding failed: %s", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		o.collect(ctx, g)
+		wg.Done()
+	}()
+
+	err = g.MainLoop()
+
+	cancel()
+	wg.Wait()
+
+	if err != nil && err != gocui.ErrQuit {
+		return nil, err
+	}
+
+	return o.choice, nil
+}
+
+// collect collects stats of all instances and updates UI until context is done.
+func (o *overview) collect(ctx context.Context, g *gocui.Gui) {
+	t := time.NewTicker(clusterRefresh)
+	defer t.Stop()
+
+	for {
+		now := time.Now()
+
+		if o.discover != nil && now.Sub(o.lastDiscover) >= clusterDiscoveryInterval {
+			o.lastDiscover = now
+			instances, err := o.discover(ctx)
+			if err != nil {
+				printCmdline(g, "%s", err)
+			} else {
+				o.merge(instances)
+			}
+		}
+
+		var wg sync.WaitGroup
+		for _, m := range o.list() {
+			wg.Add(1)
+			go func(m *member) {
+				m.collect(now)
+				wg.Done()
+			}(m)
+		}
+		wg.Wait()
+
+		g.Update(o.render)
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// layout defines overview UI layout.
+func (o *overview) layout(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	if maxX == 0 || maxY == 0 {
+		return fmt.Errorf("")
+	}
+
+	v, err := g.SetView("cluster", -1, -1, maxX, 1)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return fmt.Errorf("set cluster view on layout failed: %s", err)
+		}
+	}
+	v.Frame = false
+
+	v, err = g.SetView("cmdline", -1, 0, maxX, 2)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return fmt.Errorf("set cmdline view on layout failed: %s", err)
+		}
+		if o.notice != "" {
+			printCmdline(g, "%s", o.notice)
+		}
+	}
+	v.Frame = false
+
+	v, err = g.SetView("overview", -1, 1, maxX, maxY)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return fmt.Errorf("set overview view on layout failed: %s", err)
+		}
+		return o.render(g)
+	}
+	v.Frame = false
+
+	return nil
+}
+
+// render prints overview of instances.
+func (o *overview) render(g *gocui.Gui) error {
+	v, err := g.View("cluster")
+	if err != nil {
+		return fmt.Errorf("set focus on cluster view failed: %s", err)
+	}
+	v.Clear()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	_, err = fmt.Fprintf(v, "pgcenter: %s, cluster overview: %d instances (Up/Down to select, Enter to open, q to quit)",
+		time.Now().Format("2006-01-02 15:04:05"), len(o.members))
+	if err != nil {
+		return err
+	}
+
+	v, err = g.View("overview")
+	if err != nil {
+		return fmt.Errorf("set focus on overview view failed: %s", err)
+	}
+	v.Clear()
+
+	rows := make([]overviewLine, len(o.members))
+	for i, m := range o.members {
+		rows[i] = overviewLine{name: m.Name, address: m.address(), stat: m.snapshot()}
+	}
+
+	return printOverview(v, rows, o.selected)
+}
+
+// quit quits overview.
+func (o *overview) quit(_ *gocui.Gui, _ *gocui.View) error {
+	o.choice = nil
+	return gocui.ErrQuit
+}
+
+// choose quits overview and opens the selected instance.
+func (o *overview) choose(_ *gocui.Gui, _ *gocui.View) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.members) == 0 {
+		return nil
+	}
+
+	o.choice = o.members[o.selected]
+	return gocui.ErrQuit
+}
+
+// move moves selection up or down.
+func (o *overview) move(delta int) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		o.mu.Lock()
+		o.selected += delta
+		if o.selected < 0 {
+			o.selected = 0
+		}
+		if o.selected >= len(o.members) {
+			o.selected = len(o.members) - 1
+		}
+		o.mu.Unlock()
+		return o.render(g)
+	}
+}
+
+// overviewLine defines row of overview.
+type overviewLine struct {
+	name    string
+	address string
+	stat    memberStat
+}
+
+// overviewColumns defines names and widths of overview columns.
+var overviewColumns = []struct {
+	name  string
+	width int
+}{
+	{"name", 16}, {"address", 24}, {"state", 6}, {"version", 10}, {"role", 8}, {"tps", 10},
+	{"active", 7}, {"replicas", 9}, {"lag", 10}, {"size", 10}, {"error", 0},
+}
+
+// printOverview prints overview header and rows, selected row is highlighted.
+func printOverview(w io.Writer, rows []overviewLine, selected int) error {
+	var b strings.Builder
+	for i, col := range overviewColumns {
+		if i < len(overviewColumns)-1 {
+			fmt.Fprintf(&b, "%-*s", col.width+1, col.name)
+		} else {
+			b.WriteString(col.name)
+		}
+	}
+	_, err := fmt.Fprintf(w, "\033[30;47m%s\033[0m\n", b.String())
+	if err != nil {
+		return err
+	}
+
+	for i, r := range rows {
+		line := formatOverviewLine(r)
+		switch {
+		case i == selected:
+			line = "\033[30;46m" + line + "\033[0m"
+		case !r.stat.up:
+			line = "\033[31;1m" + line + "\033[0m"
+		}
+
+		_, err := fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// formatOverviewLine returns row of overview with values aligned to columns widths.
+func formatOverviewLine(r overviewLine) string {
+	s := r.stat
+	values := []string{r.name, r.address, "down", "", "", "", "", "", "", "", s.err}
+
+	if s.up {
+		role := "primary"
+		if s.recovery {
+			role = "standby"
+		}
+
+		tps := "-"
+		if s.rates {
+			tps = strconv.FormatFloat(s.tps, 'f', 1, 64)
+		}
+
+		size := "-"
+		if s.size >= 0 && !s.sizeTs.IsZero() {
+			size = formatBytes(s.size)
+		}
+
+		values = []string{
+			r.name, r.address, "up", s.version, role, tps, strconv.FormatInt(s.active, 10),
+			strconv.FormatInt(s.replicas, 10), formatBytes(s.lag), size, "",
+		}
+	}
+
+	var b strings.Builder
+	for i, col := range overviewColumns {
+		v := values[i]
+		if i == len(overviewColumns)-1 {
+			b.WriteString(v)
+			break
+		}
+		if len(v) > col.width {
+			v = v[:col.width-1] + "~"
+		}
+		fmt.Fprintf(&b, "%-*s", col.width+1, v)
+	}
+
+	return strings.TrimRight(b.String(), " ")
+}
+
+// formatBytes returns human-readable size.
+func formatBytes(n int64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+	v, i := float64(n), 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d %s", n, units[0])
+	}
+	return fmt.Sprintf("%.1f %s", v, units[i])
+}
@@ -0,0 +1,143 @@
+package top
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_memberStat_update(t *testing.T) {
+	now := time.Now()
+	s := memberStat{size: -1}
+
+	// First update, tps is not available.
+	s.update(overviewRow{version: "13.2", xacts: 1000, active: 2, replicas: 1, lag: 512}, now)
+	assert.True(t, s.up)
+	assert.False(t, s.rates)
+	assert.Equal(t, "13.2", s.version)
+	assert.Equal(t, int64(2), s.active)
+	assert.Equal(t, int64(1), s.replicas)
+	assert.Equal(t, int64(512), s.lag)
+
+	// Second update, tps is calculated over interval between updates.
+	s.update(overviewRow{version: "13.2", xacts: 1500, active: 3}, now.Add(2*time.Second))
+	assert.True(t, s.rates)
+	assert.Equal(t, float64(250), s.tps)
+
+	// Counter is decreased, e.g. after stats reset.
+	s.update(overviewRow{version: "13.2", xacts: 100}, now.Add(3*time.Second))
+	assert.False(t, s.rates)
+
+	// Instance is down, tps is not available after it is up again.
+	s.down(fmt.Errorf("connection refused"))
+	assert.False(t, s.up)
+	assert.Equal(t, "connection refused", s.err)
+
+	s.update(overviewRow{version: "13.2", recovery: true, xacts: 200}, now.Add(4*time.Second))
+	assert.True(t, s.up)
+	assert.False(t, s.rates)
+	assert.True(t, s.recovery)
+	assert.Equal(t, "", s.err)
+}
+
+func Test_formatOverviewLine(t *testing.T) {
+	now := time.Now()
+
+	testcases := []struct {
+		line overviewLine
+		want string
+	}{
+		{
+			line: overviewLine{name: "primary", address: "10.0.0.1:5432", stat: memberStat{
+				up: true, version: "13.2", tps: 125.25, rates: true, active: 4, replicas: 2, lag: 2048, size: 3 << 30, sizeTs: now,
+			}},
+			want: "primary          10.0.0.1:5432            up     13.2       primary  125.2      4       2         2.0 kB     3.0 GB",
+		},
+		{
+			line: overviewLine{name: "standby", address: "10.0.0.2:5432", stat: memberStat{
+				up: true, version: "13.2", recovery: true, size: -1, sizeTs: now,
+			}},
+			want: "standby          10.0.0.2:5432            up     13.2       standby  -          0       0         0 B        -",
+		},
+		{
+			line: overviewLine{name: "a-very-long-instance-name", address: "10.0.0.3:5432", stat: memberStat{err: "connection refused"}},
+			want: "a-very-long-ins~ 10.0.0.3:5432            down   " + strings.Repeat(" ", 71) + "connection refused",
+		},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, formatOverviewLine(tc.line))
+	}
+}
+
+func Test_printOverview(t *testing.T) {
+	rows := []overviewLine{
+		{name: "primary", stat: memberStat{up: true}},
+		{name: "standby", stat: memberStat{up: true}},
+		{name: "replica", stat: memberStat{err: "timeout"}},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, printOverview(&buf, rows, 1))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 4)
+	assert.True(t, strings.HasPrefix(lines[0], "\033[30;47mname"))
+	assert.True(t, strings.HasPrefix(lines[1], "primary"))
+	assert.True(t, strings.HasPrefix(lines[2], "\033[30;46mstandby"))
+	assert.True(t, strings.HasPrefix(lines[3], "\033[31;1mreplica"))
+}
+
+func Test_formatBytes(t *testing.T) {
+	testcases := []struct {
+		n    int64
+		want string
+	}{
+		{n: 0, want: "0 B"},
+		{n: 1023, want: "1023 B"},
+		{n: 1536, want: "1.5 kB"},
+		{n: 10 << 20, want: "10.0 MB"},
+		{n: 5 << 40, want: "5.0 TB"},
+		{n: 2048 << 40, want: "2048.0 TB"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, formatBytes(tc.n))
+	}
+}
+
+func Test_overview_merge(t *testing.T) {
+	newInstance := func(name, host string) ClusterInstance {
+		return ClusterInstance{Name: name, Config: postgres.Config{Config: &pgx.ConnConfig{Config: pgconn.Config{Host: host, Port: 5432}}}}
+	}
+
+	o := &overview{}
+	o.merge([]ClusterInstance{newInstance("pg1", "10.0.0.1"), newInstance("pg2", "10.0.0.2"), newInstance("pg3", "10.0.0.3")})
+	assert.Len(t, o.list(), 3)
+
+	pg2 := o.list()[1]
+	pg2.stat.up = true
+	o.selected = 2
+
+	// After failover pg2 becomes the first, pg3 is gone and pg4 is added. Stats of pg2 are kept.
+	o.merge([]ClusterInstance{newInstance("pg2", "10.0.0.2"), newInstance("pg1", "10.0.0.1"), newInstance("pg4", "10.0.0.4")})
+	got := o.list()
+	assert.Len(t, got, 3)
+	assert.Equal(t, "pg2", got[0].Name)
+	assert.True(t, got[0] == pg2)
+	assert.True(t, got[0].snapshot().up)
+	assert.Equal(t, "pg1", got[1].Name)
+	assert.Equal(t, "pg4", got[2].Name)
+	assert.Equal(t, "10.0.0.4:5432", got[2].address())
+	assert.Equal(t, 2, o.selected)
+
+	// Selection is kept within the list.
+	o.merge([]ClusterInstance{newInstance("pg2", "10.0.0.2")})
+	assert.Equal(t, 0, o.selected)
+}
@@ -1,29 +1,66 @@
 package top
 
 import (
+	"context"
+	"github.com/lesovsky/pgcenter/internal/baseline"
+	"github.com/lesovsky/pgcenter/internal/header"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/policy"
 	"github.com/lesovsky/pgcenter/internal/query"
 	"github.com/lesovsky/pgcenter/internal/stat"
 	"github.com/lesovsky/pgcenter/internal/view"
+	"time"
 )
 
+// defaultHistoryWindow defines default period of sampled sessions shown by sessions history view.
+const defaultHistoryWindow = 5 * time.Minute
+
+// historyWindows defines periods of sampled sessions which could be chosen for sessions history view.
+var historyWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute, time.Hour}
+
 // config defines 'top' program runtime configuration.
 type config struct {
-	view         view.View      // Current active view.
-	views        view.Views     // List of all available views.
-	queryOptions query.Options  // Queries' settings that might depend on Postgres version.
-	viewCh       chan view.View // Channel used for passing view settings to stats goroutine.
-	logtail      stat.Logfile   // Logfile used for working with Postgres log file.
-	dialog       dialogType     // Remember current user-started dialog, used for selecting needed dialog handler.
-	menu         menuStyle      // When working with menus, keep properties of the menu.
-	procMask     int            // Process mask used for selecting group of process.
+	view              view.View          // Current active view.
+	views             view.Views         // List of all available views.
+	viewsBack         []string           // Names of views visited before the current one, the last one is the previous view.
+	viewsForward      []string           // Names of views left by going back, they are visited again by going forward.
+	queryOptions      query.Options      // Queries' settings that might depend on Postgres version.
+	viewCh            chan view.View     // Channel used for passing view settings to stats goroutine.
+	viewChanged       bool               // View has been changed, stats should be rendered from cache.
+	rows              int                // Number of rows of stats fitting into the screen, zero if unknown.
+	pages             int                // Number of screens of rows read from Postgres.
+	scrollRow         int                // Number of rows of the current view skipped when it is scrolled down.
+	scrollCol         int                // Number of columns skipped when the view is scrolled right, key column is frozen.
+	groupStatements   bool               // Rows of pg_stat_statements views are grouped by normalized statements.
+	relativeTime      bool               // Ages and timestamps are shown relative to current time, e.g. '2m 13s ago'.
+	logtail           stat.Logfile       // Logfile used for working with Postgres log file.
+	logreader         stat.LogReader     // Reader of Postgres log used instead of log file, e.g. journald or syslog.
+	bpf               *stat.BPFTracer    // Measures latencies of backends with BPF, nil if tracing is disabled.
+	baseline          *baseline.Baseline // Baseline which stats are compared with, nil if comparing is disabled.
+	baselineThreshold float64            // Growth relative to baseline (in percents) highlighted as regression.
+	dialog            dialogType         // Remember current user-started dialog, used for selecting needed dialog handler.
+	menu              menuStyle          // When working with menus, keep properties of the menu.
+	procMask          int                // Process mask used for selecting group of process.
+	profileCancel     context.CancelFunc // Stops live profiling of a backend.
+	readOnly          bool               // Actions which change state of Postgres are disabled.
+	policies          policy.Config      // Policies of actions which change state of Postgres.
+	pending           *pendingAction     // Action waiting for confirmation by user.
+	messages          *i18n.Catalog      // Translated UI messages and names of columns.
+	header            header.Config      // Summary lines shown in the header.
+	devices           stat.Filter        // Filter of block devices and network interfaces which stats are skipped.
 }
 
 // newConfig creates 'top' initial configuration.
 func newConfig() *config {
 	views := view.New()
+	views[view.ASH] = view.SessionsHistory(defaultHistoryWindow)
+	views[view.DatadirHealth] = view.DatadirHealthView()
 
 	return &config{
-		views:  views,
-		viewCh: make(chan view.View),
+		views:    views,
+		viewCh:   make(chan view.View, 1),
+		pages:    1,
+		messages: i18n.Default(),
+		devices:  stat.DefaultFilter(),
 	}
 }
@@ -3,17 +3,20 @@ package top
 import (
 	"fmt"
 	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/log"
 	"github.com/lesovsky/pgcenter/internal/math"
 	"github.com/lesovsky/pgcenter/internal/query"
 	"github.com/lesovsky/pgcenter/internal/view"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
 const (
 	colsWidthMax  = 256 // max width allowed for column, they can't be wider than that value
 	colsWidthStep = 4   // minimal step of changing column's width, 1 is too boring and 4 looks good
+	rowsMargin    = 50  // number of rows read from Postgres in addition to rows fitting into the screen
 )
 
 // orderKeyLeft switches sort order to left column.
@@ -24,7 +27,7 @@ func orderKeyLeft(config *config) func(_ *gocui.Gui, _ *gocui.View) error {
 			config.view.OrderKey = config.view.Ncols - 1
 		}
 
-		config.viewCh <- config.view
+		config.publishView()
 		return nil
 	}
 }
@@ -37,7 +40,7 @@ func orderKeyRight(config *config) func(_ *gocui.Gui, _ *gocui.View) error {
 			config.view.OrderKey = 0
 		}
 
-		config.viewCh <- config.view
+		config.publishView()
 		return nil
 	}
 }
@@ -50,7 +53,7 @@ func increaseWidth(config *config) func(_ *gocui.Gui, _ *gocui.View) error {
 		// Increase the width using current width. Clamp the value, it should not be greater than max allowed limit.
 		config.view.ColsWidth[idx] = math.Min(config.view.ColsWidth[idx]+colsWidthStep, colsWidthMax)
 
-		config.viewCh <- config.view
+		config.publishView()
 		return nil
 	}
 }
@@ -63,7 +66,7 @@ func decreaseWidth(config *config) func(_ *gocui.Gui, _ *gocui.View) error {
 		// Decrease the width using current width. Clamp the value, it should not be less than width of column's name.
 		config.view.ColsWidth[idx] = math.Max(config.view.ColsWidth[idx]-colsWidthStep, len(config.view.Cols[idx]))
 
-		config.viewCh <- config.view
+		config.publishView()
 		return nil
 	}
 }
@@ -74,7 +77,7 @@ func switchSortOrder(config *config) func(g *gocui.Gui, _ *gocui.View) error {
 		config.view.OrderDesc = !config.view.OrderDesc
 		printCmdline(g, "Switch sort order")
 
-		config.viewCh <- config.view
+		config.publishView()
 		return nil
 	}
 }
@@ -106,6 +109,12 @@ func switchViewTo(app *app, c string) func(g *gocui.Gui, _ *gocui.View) error {
 			return nil
 		}
 
+		// data directory is inspected on local filesystem, hence it's not available for remote hosts
+		if c == view.DatadirHealth && (app.db == nil || !app.db.Local) {
+			printCmdline(g, "NOTICE: inspecting data directory is not supported for remote hosts")
+			return nil
+		}
+
 		// Switch to requested view.
 		switch c {
 		case "statements":
@@ -137,26 +146,325 @@ func switchViewTo(app *app, c string) func(g *gocui.Gui, _ *gocui.View) error {
 				viewSwitchHandler(app.config, "progress_vacuum")
 			}
 		default:
+			if !viewSupported(g, app, c) {
+				return nil
+			}
 			viewSwitchHandler(app.config, c)
 		}
 
-		printCmdline(g, app.config.view.Msg)
+		printCmdline(g, viewMessage(app))
+		return nil
+	}
+}
+
+// maxViewHistory defines maximum number of views remembered in history of visited views.
+const maxViewHistory = 50
+
+// pushViewHistory appends name of the view to history, the oldest views are forgotten when history is full.
+func pushViewHistory(history []string, name string) []string {
+	if name == "" {
+		return history
+	}
+	history = append(history, name)
+	if len(history) > maxViewHistory {
+		history = history[len(history)-maxViewHistory:]
+	}
+	return history
+}
+
+// viewBack switches to the previously visited view, like 'back' in a browser.
+func viewBack(app *app) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		config := app.config
+		if len(config.viewsBack) == 0 {
+			printCmdline(g, config.messages.T("cmdline.history.no_previous"))
+			return nil
+		}
+
+		prev := config.viewsBack[len(config.viewsBack)-1]
+		config.viewsBack = config.viewsBack[:len(config.viewsBack)-1]
+		config.viewsForward = pushViewHistory(config.viewsForward, config.view.Name)
+		switchView(config, prev)
+
+		printCmdline(g, viewMessage(app))
+		return nil
+	}
+}
+
+// viewForward switches to the view left by going back, like 'forward' in a browser.
+func viewForward(app *app) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		config := app.config
+		if len(config.viewsForward) == 0 {
+			printCmdline(g, config.messages.T("cmdline.history.no_next"))
+			return nil
+		}
+
+		next := config.viewsForward[len(config.viewsForward)-1]
+		config.viewsForward = config.viewsForward[:len(config.viewsForward)-1]
+		config.viewsBack = pushViewHistory(config.viewsBack, config.view.Name)
+		switchView(config, next)
+
+		printCmdline(g, viewMessage(app))
 		return nil
 	}
 }
 
-// viewSwitchHandler is routine handler which switches views and notify channel.
+// lastView switches to the previously visited view and remembers the current one, hence pressing it repeatedly toggles
+// between two views.
+func lastView(app *app) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		config := app.config
+		if len(config.viewsBack) == 0 {
+			printCmdline(g, config.messages.T("cmdline.history.no_previous"))
+			return nil
+		}
+
+		prev := config.viewsBack[len(config.viewsBack)-1]
+		config.viewsBack = pushViewHistory(config.viewsBack[:len(config.viewsBack)-1], config.view.Name)
+		config.viewsForward = nil
+		switchView(config, prev)
+
+		printCmdline(g, viewMessage(app))
+		return nil
+	}
+}
+
+// ioTimingViews defines views which show time of blocks reads and writes collected when track_io_timing is on.
+var ioTimingViews = map[string]bool{
+	"databases":          true,
+	"statements_timings": true,
+	"statements_latency": true,
+}
+
+// viewMessage returns message shown after switching to the current view. If track_io_timing is off, message of views
+// based on IO timings is complemented by hint about what enabling it would show.
+func viewMessage(app *app) string {
+	msg := app.config.view.Msg
+	if ioTimingViews[app.config.view.Name] && app.postgresProps.GucTrackIOTiming == "off" {
+		msg += ". " + app.config.messages.T("notice.io_timing")
+	}
+
+	return msg
+}
+
+// viewSupported returns true if query of the view is supported by connected Postgres, otherwise user is notified.
+// Views which are not based on registered queries are always supported.
+func viewSupported(g *gocui.Gui, app *app, name string) bool {
+	if _, ok := query.Select(name, app.postgresProps.QueryOptions(0)); ok || query.MinVersion(name) == 0 {
+		return true
+	}
+
+	printCmdline(g, app.config.messages.T("cmdline.view_unsupported"), name)
+	return false
+}
+
+// viewSwitchHandler is routine handler which switches views and notify channel. The current view is remembered in
+// history of visited views.
 func viewSwitchHandler(config *config, c string) {
+	if c != config.view.Name {
+		config.viewsBack = pushViewHistory(config.viewsBack, config.view.Name)
+		config.viewsForward = nil
+	}
+	switchView(config, c)
+}
+
+// switchView switches views without remembering the current view in history.
+func switchView(config *config, c string) {
 	config.views[config.view.Name] = config.view
 	config.view = config.views[c]
-	config.viewCh <- config.view
+	config.pages = 1
+	config.scrollRow, config.scrollCol = 0, 0
+	config.publishView()
+}
+
+// publishView passes current view to stats goroutine without waiting until it is received, hence UI is not blocked
+// when stats goroutine is busy, e.g. waits for a slow query. View which has not been received yet is replaced by the
+// current one. Stats of the current view are rendered from cache on the next update of UI.
+func (c *config) publishView() {
+	c.view.Limit = c.rowsLimit()
+	if c.view.IsStatements() {
+		c.view.Group = c.groupStatements
+	}
+	v := c.view
+	for {
+		select {
+		case c.viewCh <- v:
+			c.viewChanged = true
+			return
+		case pending := <-c.viewCh:
+			// Refresh interval is passed only once and it is not kept in the view, don't lose it.
+			if v.Refresh == 0 {
+				v.Refresh = pending.Refresh
+			}
+		}
+	}
+}
+
+// rowsLimit returns maximum number of rows of the current view read from Postgres. Rows are not limited when screen
+// size is unknown or filters are used, filters are applied by UI to all rows.
+func (c *config) rowsLimit() int {
+	if c.rows <= 0 || len(c.view.Filters) > 0 {
+		return 0
+	}
+	return c.rows*c.pages + rowsMargin
+}
+
+// setRows sets number of rows fitting into the screen, rows limit of the current view is updated accordingly.
+func (c *config) setRows(rows int) {
+	if rows == c.rows {
+		return
+	}
+
+	c.rows = rows
+	c.publishView()
+}
+
+// fetchMoreRows increases number of rows read from Postgres by one screen.
+func fetchMoreRows(config *config) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		config.pages++
+		config.publishView()
+
+		if config.view.LimitedQuery() == config.view.Query {
+			printCmdline(g, config.messages.T("cmdline.rows_limit.all"))
+			return nil
+		}
+
+		printCmdline(g, config.messages.T("cmdline.rows_limit"), config.view.Limit)
+		return nil
+	}
+}
+
+// scrollDown scrolls rows of the current view down by one screen. If the screen goes beyond rows read from Postgres,
+// more rows are read.
+func scrollDown(config *config) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		step := scrollStep(config.rows)
+		config.scrollRow += step
+
+		if config.view.Limit > 0 && config.scrollRow+step > config.rows*config.pages {
+			config.pages++
+			config.publishView()
+			return nil
+		}
+
+		config.viewChanged = true
+		return nil
+	}
+}
+
+// scrollUp scrolls rows of the current view up by one screen.
+func scrollUp(config *config) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		config.scrollRow -= scrollStep(config.rows)
+		if config.scrollRow < 0 {
+			config.scrollRow = 0
+		}
+		config.viewChanged = true
+		return nil
+	}
+}
+
+// scrollRight scrolls columns of the current view right by one column, key column of the view is kept on its place.
+func scrollRight(config *config) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		config.scrollCol++
+		config.viewChanged = true
+		return nil
+	}
+}
+
+// scrollLeft scrolls columns of the current view left by one column.
+func scrollLeft(config *config) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		if config.scrollCol > 0 {
+			config.scrollCol--
+		}
+		config.viewChanged = true
+		return nil
+	}
+}
+
+// scrollHome scrolls the current view back to the first row and the first column.
+func scrollHome(config *config) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		config.scrollRow, config.scrollCol = 0, 0
+		config.viewChanged = true
+		return nil
+	}
+}
+
+// scrollStep returns number of rows scrolled at once: one screen, or one row when screen size is unknown.
+func scrollStep(rows int) int {
+	if rows > 0 {
+		return rows
+	}
+	return 1
+}
+
+// toggleRelativeTime toggles showing ages and timestamps relative to current time, e.g. '2m 13s ago'. Rows are still
+// ordered using original values.
+func toggleRelativeTime(config *config) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		config.relativeTime = !config.relativeTime
+
+		// Relative values have other widths, realign columns of all views.
+		config.view.Aligned = false
+		for name, v := range config.views {
+			v.Aligned = false
+			config.views[name] = v
+		}
+		config.viewChanged = true
+
+		if config.relativeTime {
+			printCmdline(g, config.messages.T("cmdline.relative_time.on"))
+		} else {
+			printCmdline(g, config.messages.T("cmdline.relative_time.off"))
+		}
+		return nil
+	}
+}
+
+// toggleGroup toggles grouping of rows: pg_stat_statements rows are grouped by normalized text of statements, activity
+// rows are grouped by fingerprints of queries. Grouped activity is a separate view, it has its own columns.
+func toggleGroup(config *config) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		switch {
+		case config.view.IsStatements():
+			config.groupStatements = !config.groupStatements
+			config.publishView()
+
+			if config.groupStatements {
+				printCmdline(g, config.messages.T("cmdline.group_statements.on"))
+			} else {
+				printCmdline(g, config.messages.T("cmdline.group_statements.off"))
+			}
+		case config.view.Name == "activity":
+			// Keep sort order and width of columns of grouped view if it has been used before, only query could be changed.
+			grouped, ok := config.views[view.ActivityGrouped]
+			if !ok {
+				grouped = view.GroupActivity(config.view)
+			}
+			grouped.Query, grouped.Args = config.view.Query, config.view.Args
+			config.views[view.ActivityGrouped] = grouped
+
+			viewSwitchHandler(config, view.ActivityGrouped)
+			printCmdline(g, config.messages.T("cmdline.group_activity.on"))
+		case config.view.Name == view.ActivityGrouped:
+			viewSwitchHandler(config, "activity")
+			printCmdline(g, config.messages.T("cmdline.group_activity.off"))
+		}
+
+		return nil
+	}
 }
 
 // toggleSysTables toggles showing system tables/indexes.
 func toggleSysTables(config *config) func(g *gocui.Gui, _ *gocui.View) error {
 	return func(g *gocui.Gui, _ *gocui.View) error {
 		name := config.view.Name
-		if name != "tables" && name != "indexes" && name != "sizes" {
+		if name != "tables" && name != "tables_io" && name != "indexes" && name != "sizes" {
 			return nil
 		}
 
@@ -168,19 +476,19 @@ func toggleSysTables(config *config) func(g *gocui.Gui, _ *gocui.View) error {
 		}
 
 		// Recreate dependant queries accordingly to new view type.
-		for _, t := range []string{"tables", "indexes", "sizes"} {
-			q, err := query.Format(config.views[t].QueryTmpl, config.queryOptions)
+		for _, t := range []string{"tables", "tables_io", "indexes", "sizes"} {
+			q, args, err := query.Format(config.views[t].QueryTmpl, config.queryOptions)
 			if err != nil {
-				// TODO: log error
+				log.Error("format query failed", "view", t, "error", err)
 				continue
 			}
 			v := config.views[t]
-			v.Query = q
+			v.Query, v.Args = q, args
 			config.views[t] = v
 		}
 
 		config.view = config.views[name]
-		config.viewCh <- config.view
+		config.publishView()
 
 		printCmdline(g, "Show relations: "+config.queryOptions.ViewType)
 		return nil
@@ -205,15 +513,15 @@ func changeQueryAge(answer string, config *config) string {
 
 	// Update query options and format activity query.
 	config.queryOptions.QueryAgeThresh = answer
-	q, err := query.Format(config.view.QueryTmpl, config.queryOptions)
+	q, args, err := query.Format(config.view.QueryTmpl, config.queryOptions)
 	if err != nil {
 		config.queryOptions.QueryAgeThresh = fallbackAge // restore fallback
 		return fmt.Sprintf("Activity age: do nothing, %s", err.Error())
 	}
 
 	// Update query and view.
-	config.view.Query = q
-	config.viewCh <- config.view
+	config.view.Query, config.view.Args = q, args
+	config.publishView()
 
 	return "Activity age: set " + answer
 }
@@ -261,13 +569,13 @@ func toggleIdleConns(config *config) func(g *gocui.Gui, _ *gocui.View) error {
 
 		config.queryOptions.ShowNoIdle = !config.queryOptions.ShowNoIdle
 
-		q, err := query.Format(config.view.QueryTmpl, config.queryOptions)
+		q, args, err := query.Format(config.view.QueryTmpl, config.queryOptions)
 		if err != nil {
 			return err
 		}
 
-		config.view.Query = q
-		config.viewCh <- config.view
+		config.view.Query, config.view.Args = q, args
+		config.publishView()
 
 		if config.queryOptions.ShowNoIdle {
 			printCmdline(g, "Show idle connections: off.")
@@ -279,6 +587,78 @@ func toggleIdleConns(config *config) func(g *gocui.Gui, _ *gocui.View) error {
 	}
 }
 
+// setQueryFilter sets databases and users which activity and statements are shown. Filter is pushed down into queries,
+// hence rows of other databases and users are not read from Postgres. Format is [user[,user...]]@[database[,database...]],
+// input without '@' is considered as list of databases, empty input resets filter.
+func setQueryFilter(answer string, config *config) string {
+	users, databases := parseQueryFilter(answer)
+
+	// Remember current filter to restore it if formatting new queries will fail.
+	fallbackUsers, fallbackDatabases := config.queryOptions.Users, config.queryOptions.Databases
+
+	config.queryOptions.Users, config.queryOptions.Databases = users, databases
+
+	// Format queries of all views, filter is used in activity and statements views only, others are not changed.
+	formatted := map[string]view.View{}
+	for name, v := range config.views {
+		q, args, err := query.Format(v.QueryTmpl, config.queryOptions)
+		if err != nil {
+			config.queryOptions.Users, config.queryOptions.Databases = fallbackUsers, fallbackDatabases // restore fallback
+			return fmt.Sprintf("Filter: do nothing, %s", err.Error())
+		}
+		v.Query, v.Args = q, args
+		formatted[name] = v
+	}
+
+	for name, v := range formatted {
+		config.views[name] = v
+	}
+
+	if v, ok := formatted[config.view.Name]; ok {
+		config.view.Query, config.view.Args = v.Query, v.Args
+	}
+	config.publishView()
+
+	if len(users) == 0 && len(databases) == 0 {
+		return "Filter: reset, activity and statements of all databases and users are shown"
+	}
+
+	return fmt.Sprintf("Filter: databases %s, users %s", filterString(databases), filterString(users))
+}
+
+// parseQueryFilter parses filter in format [user[,user...]]@[database[,database...]] and returns lists of users and
+// databases, input without '@' is considered as list of databases.
+func parseQueryFilter(answer string) ([]string, []string) {
+	// Names of roles might contain '@', e.g. in cloud services, hence the last one is used as separator.
+	var users, databases string
+	if i := strings.LastIndex(answer, "@"); i >= 0 {
+		users, databases = answer[:i], answer[i+1:]
+	} else {
+		databases = answer
+	}
+
+	return splitNames(users), splitNames(databases)
+}
+
+// splitNames splits comma-separated list of names, empty names are skipped.
+func splitNames(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// filterString returns list of names used in filter messages.
+func filterString(names []string) string {
+	if len(names) == 0 {
+		return "all"
+	}
+	return strings.Join(names, ",")
+}
+
 // changeRefresh changes current refresh interval.
 func changeRefresh(answer string, config *config) string {
 	if answer == "" {
@@ -297,7 +677,7 @@ func changeRefresh(answer string, config *config) string {
 	// Set refresh interval, send it to stats channel and reset interval in the view.
 	// Refresh interval should not be saved as a per-view setting. It's used as a setting for stats goroutine.
 	config.view.Refresh = time.Duration(interval) * time.Second
-	config.viewCh <- config.view
+	config.publishView()
 	config.view.Refresh = 0
 
 	return "Refresh: ok"
@@ -2,7 +2,11 @@ package top
 
 import (
 	"fmt"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/view"
 	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -247,6 +251,52 @@ func Test_switchViewTo(t *testing.T) {
 	fn := switchViewTo(app, "statements")
 	assert.NoError(t, fn(nil, nil))
 	assert.Equal(t, "databases", app.config.view.Name)
+
+	// Attempt to switch to view which is not supported by Postgres (should stay on current)
+	app.postgresProps.VersionNum = 120000
+	fn = switchViewTo(app, "parallel")
+	assert.NoError(t, fn(nil, nil))
+	assert.Equal(t, "databases", app.config.view.Name)
+
+	// Attempt to switch to data directory health of remote Postgres (should stay on current)
+	app.db = &postgres.DB{Local: false}
+	fn = switchViewTo(app, view.DatadirHealth)
+	assert.NoError(t, fn(nil, nil))
+	assert.Equal(t, "databases", app.config.view.Name)
+}
+
+func Test_viewMessage(t *testing.T) {
+	app := &app{config: newConfig()}
+	app.config.view = app.config.views["databases"]
+
+	app.postgresProps.GucTrackIOTiming = "on"
+	assert.Equal(t, "Show databases statistics", viewMessage(app))
+
+	app.postgresProps.GucTrackIOTiming = "off"
+	assert.True(t, strings.HasPrefix(viewMessage(app), "Show databases statistics. track_io_timing is off"))
+
+	app.config.view = app.config.views["tables"]
+	assert.Equal(t, "Show tables statistics", viewMessage(app))
+}
+
+func Test_config_publishView(t *testing.T) {
+	config := newConfig()
+	config.view = config.views["activity"]
+
+	// Publishing doesn't wait for stats goroutine.
+	config.view.Refresh = 5 * time.Second
+	config.publishView()
+	config.view.Refresh = 0
+	assert.True(t, config.viewChanged)
+
+	// Pending view is replaced, but refresh interval is kept.
+	config.view.OrderKey = 3
+	config.publishView()
+
+	v := <-config.viewCh
+	assert.Equal(t, 3, v.OrderKey)
+	assert.Equal(t, 5*time.Second, v.Refresh)
+	assert.Len(t, config.viewCh, 0)
 }
 
 func Test_toggleSysTables(t *testing.T) {
@@ -259,9 +309,11 @@ func Test_toggleSysTables(t *testing.T) {
 		{name: "tables", current: "user", want: "pg_stat_all", nowant: "pg_stat_user"},
 		{name: "indexes", current: "user", want: "pg_stat_all", nowant: "pg_stat_user"},
 		{name: "sizes", current: "user", want: "pg_stat_all", nowant: "pg_stat_user"},
+		{name: "tables_io", current: "user", want: "pg_statio_all", nowant: "pg_statio_user"},
 		{name: "tables", current: "all", want: "pg_stat_user", nowant: "pg_stat_all"},
 		{name: "indexes", current: "all", want: "pg_stat_user", nowant: "pg_stat_all"},
 		{name: "sizes", current: "all", want: "pg_stat_user", nowant: "pg_stat_all"},
+		{name: "tables_io", current: "all", want: "pg_statio_user", nowant: "pg_statio_all"},
 	}
 
 	config := newConfig()
@@ -344,6 +396,65 @@ func Test_changeQueryAge(t *testing.T) {
 	close(config.viewCh)
 }
 
+func Test_setQueryFilter(t *testing.T) {
+	config := newConfig()
+	config.view = config.views["activity"]
+
+	got := setQueryFilter(`o'brien,a\pp@shop,billing`, config)
+	assert.Equal(t, `Filter: databases shop,billing, users o'brien,a\pp`, got)
+	assert.Equal(t, []string{"shop", "billing"}, config.queryOptions.Databases)
+	assert.Equal(t, []string{"o'brien", `a\pp`}, config.queryOptions.Users)
+	v := <-config.viewCh
+	assert.Contains(t, v.Query, "AND datname = ANY($1::text[]) AND usename = ANY($2::text[])")
+	assert.NotContains(t, v.Query, "brien")
+	assert.Equal(t, []interface{}{[]string{"shop", "billing"}, []string{"o'brien", `a\pp`}}, v.Args)
+	assert.Contains(t, config.views["statements_timings"].Query, "AND d.datname = ANY($1::text[])")
+	assert.Equal(t, v.Args, config.views["statements_timings"].Args)
+	assert.Nil(t, config.views["databases"].Args)
+
+	got = setQueryFilter("", config)
+	assert.Equal(t, "Filter: reset, activity and statements of all databases and users are shown", got)
+	v = <-config.viewCh
+	assert.NotContains(t, v.Query, "datname = ANY")
+	assert.Nil(t, v.Args)
+	assert.NotContains(t, config.views["statements_timings"].Query, "datname = ANY")
+	assert.Nil(t, config.views["statements_timings"].Args)
+
+	t.Run("break formatting", func(t *testing.T) {
+		config.queryOptions.Databases = []string{"shop"}
+		v := config.views["activity"]
+		v.QueryTmpl = "{{" // break query template leads breaking query formatting
+		config.views["activity"] = v
+		got := setQueryFilter("billing", config)
+		assert.True(t, strings.HasPrefix(got, "Filter: do nothing, template: query:1:"))
+		assert.Equal(t, []string{"shop"}, config.queryOptions.Databases) // filter should be the same as before calling setQueryFilter.
+	})
+
+	close(config.viewCh)
+}
+
+func Test_parseQueryFilter(t *testing.T) {
+	testcases := []struct {
+		answer    string
+		users     []string
+		databases []string
+	}{
+		{answer: ""},
+		{answer: "shop", databases: []string{"shop"}},
+		{answer: "@shop, billing", databases: []string{"shop", "billing"}},
+		{answer: "app@", users: []string{"app"}},
+		{answer: "app,etl@shop", users: []string{"app", "etl"}, databases: []string{"shop"}},
+		{answer: "app@server@shop", users: []string{"app@server"}, databases: []string{"shop"}},
+		{answer: " , @ , "},
+	}
+
+	for _, tc := range testcases {
+		users, databases := parseQueryFilter(tc.answer)
+		assert.Equal(t, tc.users, users)
+		assert.Equal(t, tc.databases, databases)
+	}
+}
+
 func Test_parseHumanTimeString(t *testing.T) {
 	testcases := []struct {
 		valid bool
@@ -377,6 +488,46 @@ func Test_parseHumanTimeString(t *testing.T) {
 	}
 }
 
+func Test_toggleGroup(t *testing.T) {
+	config := newConfig()
+	config.view = config.views["statements_timings"]
+
+	fn := toggleGroup(config)
+	assert.NoError(t, fn(nil, nil))
+	assert.True(t, config.groupStatements)
+	assert.True(t, (<-config.viewCh).Group)
+
+	assert.NoError(t, fn(nil, nil))
+	assert.False(t, config.groupStatements)
+	assert.False(t, (<-config.viewCh).Group)
+
+	// grouping is kept when switching between statements views, other views are not grouped.
+	assert.NoError(t, fn(nil, nil))
+	<-config.viewCh
+	viewSwitchHandler(config, "statements_io")
+	assert.True(t, (<-config.viewCh).Group)
+	viewSwitchHandler(config, "activity")
+	assert.False(t, (<-config.viewCh).Group)
+
+	// activity is switched to grouped view and back.
+	config.view.Query = "SELECT 1"
+	assert.NoError(t, fn(nil, nil))
+	v := <-config.viewCh
+	assert.Equal(t, view.ActivityGrouped, v.Name)
+	assert.Equal(t, "SELECT 1", v.Query)
+	assert.True(t, v.Group)
+	assert.True(t, config.groupStatements) // is not changed
+
+	assert.NoError(t, fn(nil, nil))
+	assert.Equal(t, "activity", (<-config.viewCh).Name)
+
+	// test attempt to toggle in view which doesn't support grouping (should be unchanged).
+	config.view = config.views["databases"]
+	assert.NoError(t, fn(nil, nil))
+	assert.True(t, config.groupStatements)
+	assert.Len(t, config.viewCh, 0)
+}
+
 func Test_toggleIdleConns(t *testing.T) {
 	testcases := []struct {
 		showNoIdleInitial bool
@@ -460,3 +611,116 @@ func Test_changeRefresh(t *testing.T) {
 		}
 	})
 }
+
+func Test_config_rowsLimit(t *testing.T) {
+	config := newConfig()
+	config.view = config.views["tables"]
+	assert.Equal(t, 0, config.rowsLimit()) // screen size is unknown
+
+	config.setRows(40)
+	assert.Equal(t, 40+rowsMargin, config.view.Limit)
+	<-config.viewCh
+
+	assert.NoError(t, fetchMoreRows(config)(nil, nil))
+	assert.Equal(t, 80+rowsMargin, config.view.Limit)
+	<-config.viewCh
+
+	// Limit is reset when view is switched.
+	viewSwitchHandler(config, "indexes")
+	assert.Equal(t, 40+rowsMargin, config.view.Limit)
+	<-config.viewCh
+
+	// Rows are not limited when filters are used.
+	config.view.Filters = map[int]*regexp.Regexp{0: regexp.MustCompile("test")}
+	assert.Equal(t, 0, config.rowsLimit())
+}
+
+func Test_scroll(t *testing.T) {
+	config := newConfig()
+	config.view = config.views["tables"]
+	config.setRows(40)
+	<-config.viewCh
+
+	// Rows are scrolled by screen, more rows are read when screen goes beyond rows read.
+	assert.NoError(t, scrollDown(config)(nil, nil))
+	assert.Equal(t, 40, config.scrollRow)
+	assert.Equal(t, 2, config.pages)
+	<-config.viewCh
+
+	assert.NoError(t, scrollUp(config)(nil, nil))
+	assert.NoError(t, scrollUp(config)(nil, nil))
+	assert.Equal(t, 0, config.scrollRow)
+
+	assert.NoError(t, scrollRight(config)(nil, nil))
+	assert.NoError(t, scrollRight(config)(nil, nil))
+	assert.NoError(t, scrollLeft(config)(nil, nil))
+	assert.Equal(t, 1, config.scrollCol)
+
+	assert.NoError(t, scrollDown(config)(nil, nil))
+	assert.NoError(t, scrollHome(config)(nil, nil))
+	assert.Equal(t, 0, config.scrollRow)
+	assert.Equal(t, 0, config.scrollCol)
+
+	// Scrolling is reset when view is switched.
+	assert.NoError(t, scrollRight(config)(nil, nil))
+	viewSwitchHandler(config, "indexes")
+	assert.Equal(t, 0, config.scrollCol)
+}
+
+func Test_toggleRelativeTime(t *testing.T) {
+	config := newConfig()
+	config.view = config.views["activity"]
+	config.view.Aligned = true
+
+	assert.NoError(t, toggleRelativeTime(config)(nil, nil))
+	assert.True(t, config.relativeTime)
+	assert.False(t, config.view.Aligned)
+	assert.True(t, config.viewChanged)
+
+	assert.NoError(t, toggleRelativeTime(config)(nil, nil))
+	assert.False(t, config.relativeTime)
+}
+
+func Test_viewHistory(t *testing.T) {
+	app := &app{config: newConfig()}
+	config := app.config
+	config.view = config.views["activity"]
+
+	viewSwitchHandler(config, "tables")
+	viewSwitchHandler(config, "statements_timings")
+	assert.Equal(t, []string{"activity", "tables"}, config.viewsBack)
+
+	// Going back and forward like in a browser.
+	assert.NoError(t, viewBack(app)(nil, nil))
+	assert.Equal(t, "tables", config.view.Name)
+	assert.NoError(t, viewBack(app)(nil, nil))
+	assert.Equal(t, "activity", config.view.Name)
+	assert.NoError(t, viewBack(app)(nil, nil)) // no previous views
+	assert.Equal(t, "activity", config.view.Name)
+	assert.NoError(t, viewForward(app)(nil, nil))
+	assert.Equal(t, "tables", config.view.Name)
+	assert.Equal(t, []string{"statements_timings"}, config.viewsForward)
+
+	// Switching to another view drops forward history.
+	viewSwitchHandler(config, "indexes")
+	assert.Nil(t, config.viewsForward)
+	assert.NoError(t, viewForward(app)(nil, nil))
+	assert.Equal(t, "indexes", config.view.Name)
+
+	// Last view toggles between two views.
+	assert.NoError(t, lastView(app)(nil, nil))
+	assert.Equal(t, "tables", config.view.Name)
+	assert.NoError(t, lastView(app)(nil, nil))
+	assert.Equal(t, "indexes", config.view.Name)
+	assert.Equal(t, []string{"activity", "tables"}, config.viewsBack)
+}
+
+func Test_pushViewHistory(t *testing.T) {
+	var history []string
+	for i := 0; i < maxViewHistory+5; i++ {
+		history = pushViewHistory(history, fmt.Sprintf("view%d", i))
+	}
+	assert.Len(t, history, maxViewHistory)
+	assert.Equal(t, "view5", history[0])
+	assert.Equal(t, history, pushViewHistory(history, ""))
+}
@@ -3,7 +3,9 @@ package top
 import (
 	"fmt"
 	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/i18n"
 	"strings"
+	"unicode/utf8"
 )
 
 // dialogType defines type of dialog between pgcenter and user.
@@ -16,61 +18,97 @@ const (
 	dialogFilter
 	dialogCancelQuery
 	dialogTerminateBackend
-	dialogCancelGroup
-	dialogTerminateGroup
 	dialogSetMask
 	dialogChangeAge
 	dialogQueryReport
 	dialogChangeRefresh
+	dialogProfileBackend
+	dialogSetRole
+	dialogConfirm
+	dialogExplainPlan
+	dialogPeekChanges
+	dialogQueryFilter
 )
 
 // dialogPrompts returns dialog prompt depending on user-requested actions.
-func dialogPrompts(t dialogType) string {
+func dialogPrompts(t dialogType, messages *i18n.Catalog) string {
 	prompts := map[dialogType]string{
-		dialogPgReload:         "Reload configuration files (y/n): ",
-		dialogFilter:           "Set filter: ",
-		dialogCancelQuery:      "PID to cancel: ",
-		dialogTerminateBackend: "PID to terminate: ",
-		dialogCancelGroup:      "Cancel group of queries. Confirm [Enter - yes, Esc - no]",
-		dialogTerminateGroup:   "Terminate group of backends. Confirm [Enter - yes, Esc - no]",
-		dialogSetMask:          "Set state mask for group backends [a: active, i: idle, x: idle_xact, w: waiting, o: others]: ",
-		dialogChangeAge:        "Enter new min age, format: HH:MM:SS[.NN]: ",
-		dialogQueryReport:      "Enter the queryid: ",
-		dialogChangeRefresh:    "Change refresh (min 1, max 300) to ",
+		dialogPgReload:         "dialog.reload",
+		dialogFilter:           "dialog.filter",
+		dialogCancelQuery:      "dialog.cancel_query",
+		dialogTerminateBackend: "dialog.terminate_backend",
+		dialogSetMask:          "dialog.set_mask",
+		dialogChangeAge:        "dialog.change_age",
+		dialogQueryReport:      "dialog.query_report",
+		dialogChangeRefresh:    "dialog.change_refresh",
+		dialogProfileBackend:   "dialog.profile_backend",
+		dialogSetRole:          "dialog.set_role",
+		dialogExplainPlan:      "dialog.explain_plan",
+		dialogPeekChanges:      "dialog.peek_changes",
+		dialogQueryFilter:      "dialog.query_filter",
 	}
 
-	return prompts[t]
+	id, ok := prompts[t]
+	if !ok {
+		return ""
+	}
+
+	return messages.T(id)
+}
+
+// dialogPrompt returns prompt of the dialog, prompt of confirmation dialog depends on the pending action.
+func dialogPrompt(app *app, t dialogType) string {
+	if t == dialogConfirm && app.config.pending != nil {
+		return app.config.pending.prompt
+	}
+	return dialogPrompts(t, app.config.messages)
 }
 
 // dialogOpen opens view for the dialog.
 func dialogOpen(app *app, d dialogType) func(g *gocui.Gui, _ *gocui.View) error {
 	return func(g *gocui.Gui, _ *gocui.View) error {
-		prompt := dialogPrompts(d)
+		prompt := dialogPrompt(app, d)
 
 		// some types of actions allowed only in specifics stats contexts.
 		if (d > dialogFilter && d <= dialogChangeAge) && app.config.view.Name != "activity" {
 			var msg string
 			switch d {
-			case dialogCancelQuery, dialogTerminateBackend, dialogCancelGroup, dialogTerminateGroup:
-				msg = "Terminate backends or cancel queries allowed in pg_stat_activity view only."
+			case dialogCancelQuery, dialogTerminateBackend:
+				msg = app.config.messages.T("dialog.denied.kill")
 			case dialogSetMask:
-				msg = "State mask setup allowed in pg_stat_activity view only."
+				msg = app.config.messages.T("dialog.denied.mask")
 			case dialogChangeAge:
-				msg = "Changing queries age threshold allowed in pg_stat_activity view only."
+				msg = app.config.messages.T("dialog.denied.age")
 			}
 			printCmdline(g, msg)
 			return nil
 		}
 
+		if d == dialogProfileBackend && app.config.view.Name != "activity" {
+			printCmdline(g, app.config.messages.T("dialog.denied.profile"))
+			return nil
+		}
+
 		if d == dialogQueryReport && !strings.Contains(app.config.view.Name, "statements") {
-			printCmdline(g, "Query reports allowed in pg_stat_statements views only.")
+			printCmdline(g, app.config.messages.T("dialog.denied.report"))
+			return nil
+		}
+
+		if d == dialogExplainPlan && !strings.Contains(app.config.view.Name, "statements") {
+			printCmdline(g, app.config.messages.T("dialog.denied.plan"))
+			return nil
+		}
+
+		if d == dialogPeekChanges && app.config.view.Name != "replication_slots" {
+			printCmdline(g, app.config.messages.T("dialog.denied.peek"))
 			return nil
 		}
 
 		maxX, _ := g.Size()
+		height := app.config.header.Height()
 
 		// Create one-line editable view, print a prompt and set cursor after it.
-		v, err := g.SetView("dialog", len(prompt)-1, 3, maxX-1, 5)
+		v, err := g.SetView("dialog", utf8.RuneCountInString(prompt)-1, height-1, maxX-1, height+1)
 		if err != nil {
 			// gocui.ErrUnknownView is OK it means a new view has been created, continue if it happens.
 			if err != gocui.ErrUnknownView {
@@ -109,43 +147,64 @@ func dialogFinish(app *app) func(g *gocui.Gui, v *gocui.View) error {
 		printCmdline(g, "")
 
 		// Extract user entered answer from buffer.
-		answer := strings.TrimPrefix(v.Buffer(), dialogPrompts(app.config.dialog))
+		answer := strings.TrimPrefix(v.Buffer(), dialogPrompt(app, app.config.dialog))
 		answer = strings.TrimSuffix(answer, "\n")
 
-		var message string
+		var (
+			message string
+			next    func(g *gocui.Gui) error // runs after the dialog is closed, e.g. action which should be confirmed
+		)
 
 		switch app.config.dialog {
 		case dialogPgReload:
-			message = doReload(answer, app.db)
+			message = doReload(answer, app.db, app.audit, app.config.messages)
 		case dialogFilter:
 			message = setFilter(answer, app.config.view)
+			app.config.publishView() // filters are applied by UI to all rows, rows should not be limited by Postgres
 		case dialogCancelQuery:
-			message = killSingle(app.db, "cancel", answer)
+			next = requestKillSingle(app, "cancel", answer)
 		case dialogTerminateBackend:
-			message = killSingle(app.db, "terminate", answer)
+			next = requestKillSingle(app, "terminate", answer)
 		case dialogSetMask:
 			message = setProcMask(answer, app.config)
-		case dialogCancelGroup:
-			message = killGroup(app, "cancel")
-		case dialogTerminateGroup:
-			message = killGroup(app, "terminate")
 		case dialogChangeAge:
 			message = changeQueryAge(answer, app.config)
 		case dialogQueryReport:
 			var r report
-			r, message = getQueryReport(answer, app.postgresProps.VersionNum, app.db)
+			r, message = getQueryReport(answer, app.config.queryOptions, app.db)
 			if message == "" {
 				message = printQueryReport(g, r, app.uiExit)
 			}
 		case dialogChangeRefresh:
 			message = changeRefresh(answer, app.config)
+		case dialogProfileBackend:
+			message = startProfile(app, answer)
+		case dialogSetRole:
+			message = setRole(app, answer)
+		case dialogConfirm:
+			next, message = finishPending(app, answer)
+		case dialogExplainPlan:
+			message = showExplainPlan(app, g, answer)
+		case dialogPeekChanges:
+			next = requestPeekChanges(app, answer)
+		case dialogQueryFilter:
+			message = setQueryFilter(answer, app.config)
 		case dialogNone:
 			// do nothing
 		}
 
 		printCmdline(g, message)
 
-		return dialogClose(g, v)
+		err := dialogClose(g, v)
+		if err != nil {
+			return err
+		}
+
+		if next != nil {
+			return next(g)
+		}
+
+		return nil
 	}
 }
 
@@ -153,7 +212,8 @@ func dialogFinish(app *app) func(g *gocui.Gui, v *gocui.View) error {
 func dialogCancel(app *app) func(g *gocui.Gui, v *gocui.View) error {
 	return func(g *gocui.Gui, v *gocui.View) error {
 		app.config.dialog = dialogNone
-		printCmdline(g, "Do nothing. Operation canceled.")
+		app.config.pending = nil
+		printCmdline(g, app.config.messages.T("dialog.canceled"))
 		return dialogClose(g, v)
 	}
 }
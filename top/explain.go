@@ -0,0 +1,94 @@
+package top
+
+import (
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"strings"
+)
+
+// showExplainPlan finds the most recent plan of the statement logged by auto_explain and shows it in $PAGER program.
+func showExplainPlan(app *app, g *gocui.Gui, answer string) string {
+	queryid := strings.TrimSpace(answer)
+	if queryid == "" {
+		return "Plan: do nothing"
+	}
+
+	var statement string
+	err := app.db.QueryRow(query.GetStatementByQueryID, queryid).Scan(&statement)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "Plan: no statistics for such queryid"
+		}
+		return fmt.Sprintf("Plan: get statement failed: %s", err)
+	}
+
+	plans, msg := readExplainPlans(app)
+	if msg != "" {
+		return msg
+	}
+
+	plan, ok := stat.FindExplainPlan(plans, queryid, statement)
+	if !ok {
+		return "Plan: no plans of the statement in recent log, is auto_explain enabled with log_format=text?"
+	}
+
+	return runPager(g, formatExplainPlan(queryid, plan), app.uiExit)
+}
+
+// readExplainPlans reads plans logged by auto_explain from configured log reader or from the current log file.
+func readExplainPlans(app *app) ([]stat.ExplainPlan, string) {
+	if app.config.logreader != nil {
+		if !app.db.Local && !app.config.logreader.Remote() {
+			return nil, fmt.Sprintf("Plan: reading log from %s is not supported for remote hosts", app.config.logreader.Name())
+		}
+		return tailExplainPlans(app.config.logreader)
+	}
+
+	if !app.db.Local {
+		return nil, "Plan: reading log is not supported for remote hosts, use --log-source syslog:ADDRESS"
+	}
+
+	logfile, err := stat.GetPostgresCurrentLogfile(app.db, app.postgresProps.VersionNum)
+	if err != nil {
+		// Logging collector is likely disabled, try systemd journal.
+		r, jerr := stat.NewJournalReader(stat.DefaultJournaldUnit)
+		if jerr != nil {
+			return nil, fmt.Sprintf("Plan: failed to get log file: %s", err)
+		}
+		defer func() { _ = r.Close() }()
+		return tailExplainPlans(r)
+	}
+
+	plans, err := stat.ReadExplainPlans(logfile)
+	if err != nil {
+		return nil, fmt.Sprintf("Plan: %s", err)
+	}
+
+	return plans, ""
+}
+
+// tailExplainPlans reads plans logged by auto_explain from recent messages of log reader.
+func tailExplainPlans(r stat.LogReader) ([]stat.ExplainPlan, string) {
+	buf, err := r.Tail(stat.ExplainLogLines)
+	if err != nil {
+		return nil, fmt.Sprintf("Plan: read log failed: %s", err)
+	}
+
+	return stat.ParseExplainPlans(buf), ""
+}
+
+// formatExplainPlan returns text of the plan shown to user.
+func formatExplainPlan(queryid string, p stat.ExplainPlan) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "queryid:   %s\n", queryid)
+	fmt.Fprintf(&b, "logged:    %s\n", p.Prefix)
+	fmt.Fprintf(&b, "duration:  %s\n", p.Duration)
+	fmt.Fprintf(&b, "\nquery:\n%s\n", p.Query)
+	fmt.Fprintf(&b, "\nplan:\n%s\n", p.Plan)
+
+	return b.String()
+}
@@ -0,0 +1,29 @@
+package top
+
+import (
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_formatExplainPlan(t *testing.T) {
+	p := stat.ExplainPlan{
+		Prefix:   "2021-03-01 12:00:01.200 UTC [2345]",
+		Duration: "12.345 ms",
+		Query:    "SELECT * FROM orders WHERE id = 42",
+		Plan:     "Index Scan using orders_pkey on orders  (cost=0.29..8.31 rows=1 width=36)\n  Index Cond: (id = 42)",
+	}
+
+	want := `queryid:   123
+logged:    2021-03-01 12:00:01.200 UTC [2345]
+duration:  12.345 ms
+
+query:
+SELECT * FROM orders WHERE id = 42
+
+plan:
+Index Scan using orders_pkey on orders  (cost=0.29..8.31 rows=1 width=36)
+  Index Cond: (id = 42)
+`
+	assert.Equal(t, want, formatExplainPlan("123", p))
+}
@@ -12,7 +12,7 @@ func showExtra(app *app, extra int) func(g *gocui.Gui, v *gocui.View) error {
 	return func(g *gocui.Gui, v *gocui.View) error {
 		// Close 'view' if passed type of extra stats are already displayed
 		if app.config.view.ShowExtra == extra {
-			if extra == stat.CollectLogtail {
+			if extra == stat.CollectLogtail && app.config.logreader == nil {
 				err := app.config.logtail.Close()
 				if err != nil {
 					return err
@@ -30,29 +30,15 @@ func showExtra(app *app, extra int) func(g *gocui.Gui, v *gocui.View) error {
 			msg = "Show block devices statistics"
 		case stat.CollectNetdev:
 			msg = "Show network interfaces statistics"
-		case stat.CollectLogtail:
+		case stat.CollectStorage:
 			if !app.db.Local {
-				printCmdline(g, "Log tail is not supported for remote hosts")
+				printCmdline(g, "Usage of directories is not supported for remote hosts")
 				return nil
 			}
 
-			logfile, err := stat.GetPostgresCurrentLogfile(app.db, app.postgresProps.VersionNum)
-			if err != nil {
-				return err
-			}
-			app.config.logtail.Path = logfile
-			app.config.logtail.Size = 0
-
-			// Check the logfile exists, is not empty and available for reading.
-			if info, err := os.Stat(app.config.logtail.Path); err == nil && info.Size() == 0 {
-				printCmdline(g, "Empty logfile")
-				return nil
-			} else if err != nil {
-				printCmdline(g, "Failed to stat logfile: %s", err)
-				return nil
-			}
-			if err := app.config.logtail.Open(); err != nil {
-				printCmdline(g, "Failed to open %s", app.config.logtail.Path)
+			msg = "Show usage of Postgres directories"
+		case stat.CollectLogtail:
+			if !openLogtail(g, app) {
 				return nil
 			}
 
@@ -70,7 +56,7 @@ func showExtra(app *app, extra int) func(g *gocui.Gui, v *gocui.View) error {
 			app.config.views[k] = v
 		}
 		app.config.view.ShowExtra = extra
-		app.config.viewCh <- app.config.view
+		app.config.publishView()
 
 		printCmdline(g, msg)
 
@@ -78,6 +64,54 @@ func showExtra(app *app, extra int) func(g *gocui.Gui, v *gocui.View) error {
 	}
 }
 
+// openLogtail prepares source of Postgres log for log tail. Log file written by logging collector is used by default.
+// When log file is not available, e.g. Postgres logs into stderr captured by systemd, the journal is used if possible.
+// Returns false if log could not be tailed, reason is shown in command line.
+func openLogtail(g *gocui.Gui, app *app) bool {
+	if app.config.logreader != nil {
+		if !app.db.Local && !app.config.logreader.Remote() {
+			printCmdline(g, "Log tail from %s is not supported for remote hosts", app.config.logreader.Name())
+			return false
+		}
+		return true
+	}
+
+	if !app.db.Local {
+		printCmdline(g, "Log tail is not supported for remote hosts, use --log-source syslog:ADDRESS")
+		return false
+	}
+
+	logfile, err := stat.GetPostgresCurrentLogfile(app.db, app.postgresProps.VersionNum)
+	if err != nil {
+		// Logging collector is likely disabled, try systemd journal.
+		r, jerr := stat.NewJournalReader(stat.DefaultJournaldUnit)
+		if jerr != nil {
+			printCmdline(g, "Failed to get log file: %s", err)
+			return false
+		}
+		app.config.logreader = r
+		return true
+	}
+
+	app.config.logtail.Path = logfile
+	app.config.logtail.Size = 0
+
+	// Check the logfile exists, is not empty and available for reading.
+	if info, err := os.Stat(app.config.logtail.Path); err == nil && info.Size() == 0 {
+		printCmdline(g, "Empty logfile")
+		return false
+	} else if err != nil {
+		printCmdline(g, "Failed to stat logfile: %s", err)
+		return false
+	}
+	if err := app.config.logtail.Open(); err != nil {
+		printCmdline(g, "Failed to open %s", app.config.logtail.Path)
+		return false
+	}
+
+	return true
+}
+
 // openExtraView create new UI view object for displaying extra stats.
 func openExtraView(g *gocui.Gui, _ *gocui.View) error {
 	maxX, maxY := g.Size()
@@ -99,7 +133,7 @@ func closeExtraView(g *gocui.Gui, _ *gocui.View, c *config) error {
 		c.views[k] = v
 	}
 	c.view.ShowExtra = stat.CollectNone
-	c.viewCh <- c.view
+	c.publishView()
 
 	return g.DeleteView("extra")
 }
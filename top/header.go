@@ -0,0 +1,179 @@
+package top
+
+import (
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/lesovsky/pgcenter/internal/header"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"math"
+	"time"
+)
+
+// headerSummary returns optional stats which should be collected for summary lines of the header.
+func headerSummary(c header.Config) stat.Summary {
+	return stat.Summary{
+		Diskstats:   c.Has(header.IO),
+		Netdevs:     c.Has(header.Net),
+		Replication: c.Has(header.Replication),
+	}
+}
+
+// printHeader prints summary lines of the header column. If reading stats of a line failed, the error is printed
+// instead of the line; lines depending on the same failed stats are skipped.
+func printHeader(v *gocui.View, lines []string, s stat.Stat, app *app) error {
+	shown := map[string]bool{}
+
+	for _, line := range lines {
+		source, err := headerLineError(line, s)
+		if err != nil {
+			// Time is printed even if system stats are not available.
+			if line == header.Load {
+				if _, err := fmt.Fprintf(v, "pgcenter: %s\n", time.Now().Format("2006-01-02 15:04:05")); err != nil {
+					return err
+				}
+			}
+
+			if shown[source] {
+				continue
+			}
+			shown[source] = true
+
+			if _, err := fmt.Fprintln(v, formatError(err)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := fmt.Fprintln(v, formatHeaderLine(line, s, app)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// headerLineError returns error occurred during reading stats of the line and the kind of failed stats.
+func headerLineError(line string, s stat.Stat) (string, error) {
+	switch line {
+	case header.Load, header.CPU, header.Mem, header.Swap:
+		return "system", s.SystemError
+	case header.IO, header.Net:
+		return "summary", s.SummaryError
+	case header.Activity, header.Autovacuum, header.Statements, header.Replication:
+		return "activity", s.ActivityError
+	}
+	return "", nil
+}
+
+// formatHeaderLine returns text of the summary line.
+func formatHeaderLine(line string, s stat.Stat, app *app) string {
+	messages := app.config.messages
+
+	switch line {
+	case header.Load:
+		return messages.Sprintf("header.load",
+			time.Now().Format("2006-01-02 15:04:05"),
+			s.LoadAvg.One, s.LoadAvg.Five, s.LoadAvg.Fifteen)
+	case header.CPU:
+		return messages.Sprintf("header.cpu",
+			highlight("%4.1f", s.CpuStat.User), highlight("%4.1f", s.CpuStat.Sys), highlight("%4.1f", s.CpuStat.Nice),
+			highlight("%4.1f", s.CpuStat.Idle), highlight("%4.1f", s.CpuStat.Iowait), highlight("%4.1f", s.CpuStat.Irq),
+			highlight("%4.1f", s.CpuStat.Softirq), highlight("%4.1f", s.CpuStat.Steal))
+	case header.Mem:
+		return messages.Sprintf("header.mem",
+			highlight("%6d", s.Meminfo.MemTotal), highlight("%6d", s.Meminfo.MemFree), highlight("%6d", s.Meminfo.MemUsed),
+			highlight("%8d", s.Meminfo.MemCached+s.Meminfo.MemBuffers+s.Meminfo.MemSlab))
+	case header.Swap:
+		return messages.Sprintf("header.swap",
+			highlight("%6d", s.Meminfo.SwapTotal), highlight("%6d", s.Meminfo.SwapFree), highlight("%6d", s.Meminfo.SwapUsed),
+			highlight("%6d/%d", s.Meminfo.MemDirty, s.Meminfo.MemWriteback))
+	case header.IO:
+		return formatIOSummary(s.Diskstats, messages)
+	case header.Net:
+		return formatNetSummary(s.Netdevs, messages)
+	case header.Postgres:
+		return formatInfoString(app.db.Config, s.Activity.State, app.postgresProps.Version, s.Activity.Uptime, app.postgresProps.Recovery, app.db.Encrypted())
+	case header.Activity:
+		props := app.postgresProps
+		return messages.Sprintf("header.activity",
+			highlight("%3d/%d", s.Activity.ConnTotal, props.GucMaxConnections), highlight("%3d/%d", s.Activity.ConnPrepared, props.GucMaxPrepXacts),
+			highlight("%3d", s.Activity.ConnIdle), highlight("%3d", s.Activity.ConnIdleXact), highlight("%3d", s.Activity.ConnActive),
+			highlight("%3d", s.Activity.ConnWaiting), highlight("%3d", s.Activity.ConnOthers))
+	case header.Autovacuum:
+		// Running anti-wraparound vacuums are marked with badge.
+		antiwrap, badge := highlight("%2d", s.Activity.AVAntiwrap), ""
+		if s.Activity.AVAntiwrap > 0 {
+			antiwrap = wraparoundFormat("%2d", s.Activity.AVAntiwrap)
+			badge = wraparoundFormat("%s", messages.T("header.wraparound_badge"))
+		}
+		return messages.Sprintf("header.autovacuum",
+			highlight("%2d/%d", s.Activity.AVWorkers, app.postgresProps.GucAVMaxWorkers),
+			highlight("%2d", s.Activity.AVUser), antiwrap, highlight("%s", s.Activity.AVMaxTime)) + badge
+	case header.Statements:
+		// Current workload and time since stats of the current view have been reset.
+		return messages.Sprintf("header.statements",
+			highlight("%3d", s.Activity.CallsRate), highlight("%3.3f", s.Activity.StmtAvgTime),
+			highlight("%s", s.Activity.XactMaxTime), highlight("%s", s.Activity.PrepMaxTime)) + formatResetAge(app.config.view, s.Activity, messages)
+	case header.Replication:
+		return formatReplicationSummary(s.Activity, messages)
+	case header.Alerts:
+		var alerts []alert.Alert
+		if m := app.instances[app.current].alerts; m != nil {
+			alerts = m.Firing()
+		}
+		return formatAlertsSummary(alerts, messages)
+	}
+
+	return ""
+}
+
+// formatIOSummary returns summary line with total IO of block devices.
+func formatIOSummary(s stat.Diskstats, messages *i18n.Catalog) string {
+	var reads, writes, rmb, wmb, util float64
+	for _, d := range s {
+		reads += d.Rcompleted
+		writes += d.Wcompleted
+		rmb += d.Rsectors
+		wmb += d.Wsectors
+		util = math.Max(util, d.Util)
+	}
+
+	return messages.Sprintf("header.io",
+		highlight("%7.1f", reads), highlight("%7.1f", writes), highlight("%6.2f", rmb), highlight("%6.2f", wmb), highlight("%5.1f", util))
+}
+
+// formatNetSummary returns summary line with total traffic of network interfaces, loopback interface is not counted.
+func formatNetSummary(s stat.Netdevs, messages *i18n.Catalog) string {
+	var rmbps, tmbps, rpk, tpk, errs float64
+	for _, n := range s {
+		if n.Ifname == "lo" {
+			continue
+		}
+		rmbps += n.Rbytes / 1024 / 128 // conversion to Mbps
+		tmbps += n.Tbytes / 1024 / 128
+		rpk += n.Rpackets
+		tpk += n.Tpackets
+		errs += n.Rerrs + n.Terrs
+	}
+
+	return messages.Sprintf("header.net",
+		highlight("%7.2f", rmbps), highlight("%7.2f", tmbps), highlight("%7.1f", rpk), highlight("%7.1f", tpk), highlight("%4.1f", errs))
+}
+
+// formatReplicationSummary returns summary line with number of replicas and replication lag.
+func formatReplicationSummary(a stat.Activity, messages *i18n.Catalog) string {
+	if a.Replicas < 0 || a.ReplicationLag < 0 {
+		return messages.Sprintf("header.replication", highlight("n/a"), highlight("n/a"))
+	}
+	return messages.Sprintf("header.replication", highlight("%2d", a.Replicas), highlight("%s", formatBytes(a.ReplicationLag)))
+}
+
+// formatAlertsSummary returns summary line with firing alerts: the first alert and number of others.
+func formatAlertsSummary(alerts []alert.Alert, messages *i18n.Catalog) string {
+	if len(alerts) == 0 {
+		return messages.Sprintf("header.alerts", highlight("%s", messages.T("header.alerts_none")))
+	}
+	return messages.Sprintf("header.alerts", fmt.Sprintf("\033[31;1m%s\033[0m", formatAlerts(alerts)))
+}
@@ -0,0 +1,81 @@
+package top
+
+import (
+	"errors"
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/lesovsky/pgcenter/internal/header"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_headerSummary(t *testing.T) {
+	assert.Equal(t, stat.Summary{}, headerSummary(header.Config{}))
+	assert.Equal(t,
+		stat.Summary{Diskstats: true, Replication: true},
+		headerSummary(header.Config{Left: []string{header.Load, header.IO}, Right: []string{header.Replication}}),
+	)
+}
+
+func Test_headerLineError(t *testing.T) {
+	sysErr, actErr := errors.New("system failed"), errors.New("activity failed")
+	s := stat.Stat{SystemError: sysErr}
+	s.ActivityError = actErr
+
+	source, err := headerLineError(header.CPU, s)
+	assert.Equal(t, "system", source)
+	assert.Equal(t, sysErr, err)
+
+	source, err = headerLineError(header.Replication, s)
+	assert.Equal(t, "activity", source)
+	assert.Equal(t, actErr, err)
+
+	_, err = headerLineError(header.IO, s)
+	assert.NoError(t, err)
+	_, err = headerLineError(header.Postgres, s)
+	assert.NoError(t, err)
+}
+
+func Test_formatIOSummary(t *testing.T) {
+	s := stat.Diskstats{
+		{Device: "sda", Rcompleted: 10, Wcompleted: 20.5, Rsectors: 1.5, Wsectors: 2, Util: 12.5},
+		{Device: "sdb", Rcompleted: 5, Wcompleted: 0, Rsectors: 0.25, Wsectors: 0, Util: 40},
+	}
+
+	assert.Equal(t,
+		"      io: \033[37;1m   15.0\033[0m r/s, \033[37;1m   20.5\033[0m w/s, \033[37;1m  1.75\033[0m rMB/s, \033[37;1m  2.00\033[0m wMB/s, \033[37;1m 40.0\033[0m max %util",
+		formatIOSummary(s, i18n.Default()),
+	)
+}
+
+func Test_formatNetSummary(t *testing.T) {
+	s := stat.Netdevs{
+		{Ifname: "lo", Rbytes: 1 << 30, Tbytes: 1 << 30, Rpackets: 1000, Tpackets: 1000},
+		{Ifname: "eth0", Rbytes: 131072, Tbytes: 262144, Rpackets: 100, Tpackets: 200, Rerrs: 1},
+	}
+
+	assert.Equal(t,
+		"     net: \033[37;1m   1.00\033[0m rMbps, \033[37;1m   2.00\033[0m wMbps, \033[37;1m  100.0\033[0m rPk/s, \033[37;1m  200.0\033[0m wPk/s, \033[37;1m 1.0\033[0m errs/s",
+		formatNetSummary(s, i18n.Default()),
+	)
+}
+
+func Test_formatReplicationSummary(t *testing.T) {
+	assert.Equal(t,
+		"  replicas:\033[37;1m 2\033[0m connected, \033[37;1m1.5 MB\033[0m lag",
+		formatReplicationSummary(stat.Activity{Replicas: 2, ReplicationLag: 1572864}, i18n.Default()),
+	)
+	assert.Equal(t,
+		"  replicas:\033[37;1mn/a\033[0m connected, \033[37;1mn/a\033[0m lag",
+		formatReplicationSummary(stat.Activity{Replicas: -1, ReplicationLag: -1}, i18n.Default()),
+	)
+}
+
+func Test_formatAlertsSummary(t *testing.T) {
+	assert.Equal(t, "    alerts: \033[37;1mnone firing\033[0m", formatAlertsSummary(nil, i18n.Default()))
+	assert.Equal(t,
+		"    alerts: \033[31;1mALERT: xid_age: 1600000000\033[0m",
+		formatAlertsSummary([]alert.Alert{{Rule: "xid_age", Value: 1.6e9}}, i18n.Default()),
+	)
+}
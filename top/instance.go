@@ -0,0 +1,94 @@
+package top
+
+import (
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"strconv"
+)
+
+// instance defines connection to Postgres instance and state of its stats views. Stats of all instances are collected
+// simultaneously, but only stats of the current instance are displayed.
+type instance struct {
+	config        *config                 // runtime configuration, including state of stats views.
+	db            *postgres.DB            // connection to Postgres.
+	reconnector   *reconnector            // tracks state of connection to Postgres.
+	postgresProps stat.PostgresProperties // properties of Postgres to which connected to.
+	last          *stat.Stat              // the last collected stats, displayed right after switching to the instance.
+	cache         map[string]stat.Stat    // the last collected stats keyed by view, displayed right after view is changed.
+	alerts        *alert.Monitor          // evaluates alert rules, nil if alerts are not configured.
+	history       *stat.SessionsHistory   // sampled active sessions, kept across restarts of stats collecting.
+}
+
+// sessionsHistorySize defines number of samples of active sessions kept in history, it is one hour of samples taken
+// with default refresh interval.
+const sessionsHistorySize = 3600
+
+// instanceStat defines stats collected from a particular instance.
+type instanceStat struct {
+	inst *instance
+	snap snapshot
+}
+
+// newInstance creates new instance.
+func newInstance(config *config, db *postgres.DB, rc *reconnector) *instance {
+	return &instance{
+		config: config, db: db, reconnector: rc, cache: map[string]stat.Stat{},
+		history: stat.NewSessionsHistory(sessionsHistorySize),
+	}
+}
+
+// addInstance adds Postgres instance to the list of instances which could be switched to.
+func (app *app) addInstance(db *postgres.DB, config *config) {
+	app.instances = append(app.instances, newInstance(config, db, newReconnector()))
+}
+
+// activate makes specified instance the current one. Application's fields always refer to the current instance.
+func (app *app) activate(i int) {
+	// Properties could be changed at runtime (e.g. after changing role), keep them.
+	app.instances[app.current].postgresProps = app.postgresProps
+
+	inst := app.instances[i]
+	app.current = i
+	app.config, app.db, app.reconnector, app.postgresProps = inst.config, inst.db, inst.reconnector, inst.postgresProps
+}
+
+// instanceName returns human-readable name of the current instance.
+func (app *app) instanceName() string {
+	cfg := app.db.Config.Config
+	return fmt.Sprintf("%d of %d (%s:%s)", app.current+1, len(app.instances), cfg.Host, strconv.Itoa(int(cfg.Port)))
+}
+
+// switchInstance switches UI to the next instance. State of stats views is kept per instance.
+func switchInstance(app *app) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		if len(app.instances) < 2 {
+			printCmdline(g, "Only one instance is connected, use --instance option for connecting to others.")
+			return nil
+		}
+
+		app.activate((app.current + 1) % len(app.instances))
+
+		// Key handlers refer to the current instance, rebind them.
+		if err := keybindings(app); err != nil {
+			return err
+		}
+
+		// Extra stats view depends on settings of the instance's view.
+		if app.config.view.ShowExtra == stat.CollectNone {
+			if err := g.DeleteView("extra"); err != nil && err != gocui.ErrUnknownView {
+				return fmt.Errorf("delete extra view failed: %s", err)
+			}
+		} else {
+			if err := openExtraView(g, nil); err != nil {
+				return err
+			}
+		}
+
+		printCmdline(g, "Switched to instance %s", app.instanceName())
+
+		return renderCached(g, app)
+	}
+}
@@ -0,0 +1,49 @@
+package top
+
+import (
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_app_activate(t *testing.T) {
+	newDB := func(connStr string) *postgres.DB {
+		config, err := postgres.ParseConfig(connStr)
+		assert.NoError(t, err)
+		return &postgres.DB{Config: config}
+	}
+
+	db1, db2 := newDB("host=primary port=5432"), newDB("host=standby port=5433")
+	config1, config2 := newConfig(), newConfig()
+
+	app := newApp(db1, config1)
+	app.addInstance(db2, config2)
+	assert.Len(t, app.instances, 2)
+	assert.Equal(t, "1 of 2 (primary:5432)", app.instanceName())
+
+	// Properties changed at runtime are kept after switching.
+	app.postgresProps.Privileges.PgMonitor = true
+
+	app.activate(1)
+	assert.Equal(t, 1, app.current)
+	assert.Equal(t, db2, app.db)
+	assert.Equal(t, config2, app.config)
+	assert.Equal(t, app.instances[1].reconnector, app.reconnector)
+	assert.False(t, app.postgresProps.Privileges.PgMonitor)
+	assert.Equal(t, "2 of 2 (standby:5433)", app.instanceName())
+
+	app.activate(0)
+	assert.Equal(t, db1, app.db)
+	assert.Equal(t, config1, app.config)
+	assert.True(t, app.postgresProps.Privileges.PgMonitor)
+}
+
+func Test_switchInstance(t *testing.T) {
+	config, err := postgres.ParseConfig("host=primary")
+	assert.NoError(t, err)
+
+	// Nothing to switch to.
+	app := newApp(&postgres.DB{Config: config}, newConfig())
+	assert.NoError(t, switchInstance(app)(nil, nil))
+	assert.Equal(t, 0, app.current)
+}
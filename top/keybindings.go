@@ -3,7 +3,9 @@ package top
 import (
 	"fmt"
 	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/policy"
 	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
 )
 
 // Key represents binding between key button and handler should be running when user presses the button.
@@ -13,9 +15,30 @@ type key struct {
 	handler  func(g *gocui.Gui, v *gocui.View) error
 }
 
-// keybindings set up key bindings with handlers.
+// keybindings set up key bindings with handlers, existing key bindings are replaced.
 func keybindings(app *app) error {
-	var keys = []key{
+	keys := keymap(app)
+
+	app.ui.InputEsc = true
+
+	// Handlers refer to the current instance, remove handlers bound before switching to another instance.
+	for _, k := range keys {
+		app.ui.DeleteKeybindings(k.viewname)
+	}
+
+	for _, k := range keys {
+		if err := app.ui.SetKeybinding(k.viewname, k.key, gocui.ModNone, k.handler); err != nil {
+			return fmt.Errorf("setup keybindings failed: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// keymap returns key bindings of the app. Actions which change state of Postgres are wrapped with mutating, psql and
+// setting role are wrapped too, because they allow changing state of Postgres directly or with privileges of other role.
+func keymap(app *app) []key {
+	return []key{
 		{"", gocui.KeyCtrlC, app.quit()},
 		{"", gocui.KeyCtrlQ, app.quit()},
 		{"sysstat", 'q', app.quit()},
@@ -24,57 +47,119 @@ func keybindings(app *app) error {
 		{"sysstat", gocui.KeyArrowUp, increaseWidth(app.config)},
 		{"sysstat", gocui.KeyArrowDown, decreaseWidth(app.config)},
 		{"sysstat", '<', switchSortOrder(app.config)},
+		{"sysstat", 'M', fetchMoreRows(app.config)},
+		{"sysstat", gocui.KeyPgdn, scrollDown(app.config)},
+		{"sysstat", gocui.KeyPgup, scrollUp(app.config)},
+		{"sysstat", ']', scrollRight(app.config)},
+		{"sysstat", '[', scrollLeft(app.config)},
+		{"sysstat", gocui.KeyHome, scrollHome(app.config)},
 		{"sysstat", ',', toggleSysTables(app.config)},
 		{"sysstat", 'I', toggleIdleConns(app.config)},
 		{"sysstat", 'd', switchViewTo(app, "databases")},
 		{"sysstat", 'r', switchViewTo(app, "replication")},
 		{"sysstat", 't', switchViewTo(app, "tables")},
+		{"sysstat", 'T', switchViewTo(app, "tables_io")},
 		{"sysstat", 'i', switchViewTo(app, "indexes")},
 		{"sysstat", 's', switchViewTo(app, "sizes")},
+		{"sysstat", 'S', switchViewTo(app, view.IndexAdvisor)},
+		{"sysstat", 'Z', switchViewTo(app, view.DatadirHealth)},
+		{"sysstat", 'j', switchViewTo(app, "stale_stats")},
+		{"sysstat", 'F', switchViewTo(app, "fdw")},
+		{"sysstat", 'w', switchViewTo(app, "parallel")},
+		{"sysstat", 'o', switchViewTo(app, "replication_slots")},
 		{"sysstat", 'f', switchViewTo(app, "functions")},
+		{"sysstat", 'c', switchViewTo(app, "checkpoints")},
+		{"sysstat", 'u', switchViewTo(app, "roles")},
 		{"sysstat", 'p', switchViewTo(app, "progress")},
 		{"sysstat", 'a', switchViewTo(app, "activity")},
 		{"sysstat", 'x', switchViewTo(app, "statements")},
-		{"sysstat", 'Q', resetStat(app.db, app.postgresProps.ExtPGSSAvail)},
-		{"sysstat", 'E', menuOpen(menuConf, app.config, false)},
+		{"sysstat", '(', viewBack(app)},
+		{"sysstat", ')', viewForward(app)},
+		{"sysstat", gocui.KeyBackspace, lastView(app)},
+		{"sysstat", gocui.KeyBackspace2, lastView(app)},
+		{"sysstat", 'Q', mutating(app, "action.reset_stats", permitted(app, policy.ResetStats, "action.reset_stats", privileged(app, stat.Privileges.CanResetStats, "action.reset_stats", "requirement.reset_stats", requestResetStat(app))))},
+		{"sysstat", 'E', mutating(app, "action.edit_config", permitted(app, policy.EditConfig, "action.edit_config", privileged(app, stat.Privileges.ReadSettings, "action.edit_config", "requirement.read_settings", menuOpen(menuConf, app.config, false))))},
 		{"sysstat", 'X', menuOpen(menuPgss, app.config, app.postgresProps.ExtPGSSAvail)},
+		{"sysstat", 'g', toggleGroup(app.config)},
+		{"sysstat", '@', toggleRelativeTime(app.config)},
 		{"sysstat", 'P', menuOpen(menuProgress, app.config, false)},
-		{"sysstat", 'l', showPgLog(app.db, app.postgresProps.VersionNum, app.uiExit)},
+		{"sysstat", 'H', menuOpen(menuHistory, app.config, false)},
+		{"sysstat", 'e', menuOpen(menuPlugins, app.config, false)},
+		{"sysstat", 'l', privileged(app, stat.Privileges.ReadLogs, "action.show_log", "requirement.read_logs", showPgLog(app))},
 		{"sysstat", 'C', showPgConfig(app.db, app.uiExit)},
-		{"sysstat", '~', runPsql(app.db, app.uiExit)},
+		{"sysstat", '~', mutating(app, "action.psql", runPsql(app.db, app.uiExit))},
 		{"sysstat", 'B', showExtra(app, stat.CollectDiskstats)},
 		{"sysstat", 'N', showExtra(app, stat.CollectNetdev)},
-		{"sysstat", 'L', showExtra(app, stat.CollectLogtail)},
-		{"sysstat", 'R', dialogOpen(app, dialogPgReload)},
+		{"sysstat", 'D', showExtra(app, stat.CollectStorage)},
+		{"sysstat", 'L', privileged(app, stat.Privileges.ReadLogs, "action.show_log", "requirement.read_logs", showExtra(app, stat.CollectLogtail))},
+		{"sysstat", 'R', mutating(app, "action.reload", permitted(app, policy.ReloadConfig, "action.reload", privileged(app, stat.Privileges.CanReloadConf, "action.reload", "requirement.reload", requestReload(app))))},
 		{"sysstat", '/', dialogOpen(app, dialogFilter)},
-		{"sysstat", '-', dialogOpen(app, dialogCancelQuery)},
-		{"sysstat", '_', dialogOpen(app, dialogTerminateBackend)},
+		{"sysstat", '-', mutating(app, "action.cancel", permitted(app, policy.Cancel, "action.cancel", dialogOpen(app, dialogCancelQuery)))},
+		{"sysstat", '_', mutating(app, "action.terminate", permitted(app, policy.Terminate, "action.terminate", dialogOpen(app, dialogTerminateBackend)))},
 		{"sysstat", 'n', dialogOpen(app, dialogSetMask)},
 		{"sysstat", 'm', showProcMask(app.config)},
-		{"sysstat", 'k', dialogOpen(app, dialogCancelGroup)},
-		{"sysstat", 'K', dialogOpen(app, dialogTerminateGroup)},
+		{"sysstat", 'k', mutating(app, "action.cancel", permitted(app, policy.CancelGroup, "action.cancel", requestKillGroup(app, "cancel")))},
+		{"sysstat", 'K', mutating(app, "action.terminate", permitted(app, policy.TerminateGroup, "action.terminate", requestKillGroup(app, "terminate")))},
 		{"sysstat", 'A', dialogOpen(app, dialogChangeAge)},
+		{"sysstat", 'b', dialogOpen(app, dialogQueryFilter)},
 		{"sysstat", 'G', dialogOpen(app, dialogQueryReport)},
+		{"sysstat", 'Y', privileged(app, stat.Privileges.ReadLogs, "action.show_plans", "requirement.read_logs", dialogOpen(app, dialogExplainPlan))},
+		{"sysstat", 'v', permitted(app, policy.PeekChanges, "action.peek", dialogOpen(app, dialogPeekChanges))},
 		{"sysstat", 'z', dialogOpen(app, dialogChangeRefresh)},
+		{"sysstat", 'W', dialogOpen(app, dialogProfileBackend)},
+		{"sysstat", 'O', showConnLog(app)},
+		{"sysstat", 'V', showCapabilities(app)},
+		{"sysstat", 'J', showAuditLog(app)},
+		{"sysstat", 'y', showViewSQL(app.config)},
+		{"sysstat", 'U', mutating(app, "action.set_role", dialogOpen(app, dialogSetRole))},
+		{"sysstat", gocui.KeyTab, switchInstance(app)},
 		{"dialog", gocui.KeyEsc, dialogCancel(app)},
 		{"dialog", gocui.KeyEnter, dialogFinish(app)},
 		{"menu", gocui.KeyEsc, menuClose},
 		{"menu", gocui.KeyArrowUp, moveCursor(moveUp, app.config)},
 		{"menu", gocui.KeyArrowDown, moveCursor(moveDown, app.config)},
 		{"menu", gocui.KeyEnter, menuSelect(app)},
-		{"sysstat", 'h', showHelp},
-		{"sysstat", gocui.KeyF1, showHelp},
+		{"sysstat", 'h', showHelp(app.config.messages)},
+		{"sysstat", gocui.KeyF1, showHelp(app.config.messages)},
 		{"help", gocui.KeyEsc, closeHelp},
 		{"help", 'q', closeHelp},
+		{"profile", gocui.KeyEsc, closeProfile(app)},
+		{"profile", 'q', closeProfile(app)},
+		{"connlog", gocui.KeyEsc, closeConnLog},
+		{"connlog", 'q', closeConnLog},
+		{"capabilities", gocui.KeyEsc, closeCapabilities},
+		{"capabilities", 'q', closeCapabilities},
+		{"auditlog", gocui.KeyEsc, closeAuditLog},
+		{"auditlog", 'q', closeAuditLog},
+		{"sql", 'c', copyViewSQL(app.config)},
+		{"sql", gocui.KeyEsc, closeViewSQL},
+		{"sql", 'q', closeViewSQL},
 	}
+}
 
-	app.ui.InputEsc = true
-
-	for _, k := range keys {
-		if err := app.ui.SetKeybinding(k.viewname, k.key, gocui.ModNone, k.handler); err != nil {
-			return fmt.Errorf("setup keybindings failed: %s", err)
+// mutating wraps handler of an action which changes state of Postgres. In read-only mode the action is disabled and
+// user is notified about that. Action is ID of message with name of the action.
+func mutating(app *app, action string, handler func(g *gocui.Gui, v *gocui.View) error) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		if app.config.readOnly {
+			printCmdline(g, app.config.messages.T("cmdline.read_only"), app.config.messages.T(action))
+			return nil
 		}
+
+		return handler(g, v)
 	}
+}
 
-	return nil
+// privileged wraps handler of an action which requires privileges the current role might not have. If the role has
+// no required privileges the action is disabled and user is notified about that. Privileges are checked at the time
+// of action, because current role could be changed at runtime. Action and requirement are IDs of messages.
+func privileged(app *app, allowed func(stat.Privileges) bool, action string, requirement string, handler func(g *gocui.Gui, v *gocui.View) error) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		if !allowed(app.postgresProps.Privileges) {
+			printCmdline(g, app.config.messages.T("cmdline.requires"), app.config.messages.T(action), app.config.messages.T(requirement))
+			return nil
+		}
+
+		return handler(g, v)
+	}
 }
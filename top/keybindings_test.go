@@ -0,0 +1,76 @@
+package top
+
+import (
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_mutating(t *testing.T) {
+	testcases := []struct {
+		readOnly bool
+		want     bool
+	}{
+		{readOnly: false, want: true},
+		{readOnly: true, want: false},
+	}
+
+	for _, tc := range testcases {
+		app := &app{config: newConfig()}
+		app.config.readOnly = tc.readOnly
+
+		var called bool
+		fn := mutating(app, "action.reset_stats", func(_ *gocui.Gui, _ *gocui.View) error {
+			called = true
+			return nil
+		})
+
+		assert.NoError(t, fn(nil, nil))
+		assert.Equal(t, tc.want, called)
+	}
+}
+
+func Test_keymap_readOnly(t *testing.T) {
+	app := &app{config: newConfig()}
+	app.config.readOnly = true
+
+	// Keys of actions which change state of Postgres, directly or with privileges of other role.
+	mutatingKeys := map[interface{}]bool{'Q': true, 'E': true, 'R': true, '-': true, '_': true, 'k': true, 'K': true, '~': true, 'U': true}
+
+	var found int
+	for _, k := range keymap(app) {
+		if k.viewname != "sysstat" || !mutatingKeys[k.key] {
+			continue
+		}
+		found++
+
+		// Handlers are not run in read-only mode, otherwise they fail without UI and connection to Postgres.
+		assert.NotPanics(t, func() { assert.NoError(t, k.handler(nil, nil)) }, "key %c", k.key)
+		assert.Nil(t, app.config.pending)
+		assert.Equal(t, dialogNone, app.config.dialog)
+	}
+	assert.Equal(t, len(mutatingKeys), found)
+}
+
+func Test_privileged(t *testing.T) {
+	for _, allowed := range []bool{true, false} {
+		app := &app{config: newConfig()}
+		app.postgresProps.Privileges = stat.Privileges{ResetStats: allowed}
+
+		var called bool
+		fn := privileged(app, stat.Privileges.CanResetStats, "action.reset_stats", "requirement.reset_stats", func(_ *gocui.Gui, _ *gocui.View) error {
+			called = true
+			return nil
+		})
+
+		assert.NoError(t, fn(nil, nil))
+		assert.Equal(t, allowed, called)
+
+		// Privileges are checked at the time of action.
+		app.postgresProps.Privileges.ResetStats = !allowed
+		called = false
+		assert.NoError(t, fn(nil, nil))
+		assert.Equal(t, !allowed, called)
+	}
+}
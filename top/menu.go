@@ -3,6 +3,10 @@ package top
 import (
 	"fmt"
 	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/plugin"
+	"github.com/lesovsky/pgcenter/internal/policy"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"strings"
 )
 
 // menuType defines a type of the used menu.
@@ -17,6 +21,8 @@ const (
 	menuPgss                     // menu with pg_stat_statements stats
 	menuProgress                 // menu with pg_stat_progress_* stats
 	menuConf                     // menu with configuration files
+	menuPlugins                  // menu with views of plugins
+	menuHistory                  // menu with windows of active sessions history
 
 	// Directions allowed when working with menu.
 	moveUp   direction = iota // move up
@@ -45,6 +51,9 @@ func selectMenuStyle(t menuType) menuStyle {
 				" pg_stat_statements input/output",
 				" pg_stat_statements temp files input/output",
 				" pg_stat_statements temp tables (local) input/output",
+				" pg_stat_statements parallel workers",
+				" pg_stat_statements JIT compilation",
+				" pg_stat_statements IO latency",
 			},
 		}
 	case menuProgress:
@@ -68,6 +77,20 @@ func selectMenuStyle(t menuType) menuStyle {
 				" recovery.conf",
 			},
 		}
+	case menuPlugins:
+		// Items are plugins configured by user, they are added when menu is opened.
+		s = menuStyle{
+			menuType: menuPlugins,
+			title:    " Choose plugin view (Enter to choose, Esc to exit): ",
+		}
+	case menuHistory:
+		s = menuStyle{
+			menuType: menuHistory,
+			title:    " Choose period of active sessions history (Enter to choose, Esc to exit): ",
+		}
+		for _, w := range historyWindows {
+			s.items = append(s.items, fmt.Sprintf(" last %d minutes", int(w.Minutes())))
+		}
 	default:
 		s = menuStyle{
 			menuType: menuNone,
@@ -88,7 +111,18 @@ func menuOpen(m menuType, config *config, pgssAvail bool) func(g *gocui.Gui, _ *
 			return nil
 		}
 
-		v, err := g.SetView("menu", 0, 5, 72, 6+len(s.items))
+		if s.menuType == menuPlugins {
+			for _, name := range plugin.Names(config.views) {
+				s.items = append(s.items, " "+name)
+			}
+			if len(s.items) == 0 {
+				printCmdline(g, "NOTICE: no plugins are configured, see 'plugins' section of configuration file")
+				return nil
+			}
+		}
+
+		height := config.header.Height()
+		v, err := g.SetView("menu", 0, height+1, 72, height+2+len(s.items))
 		if err != nil {
 			if err != gocui.ErrUnknownView {
 				return err
@@ -114,23 +148,22 @@ func menuSelect(app *app) func(g *gocui.Gui, v *gocui.View) error {
 		// 'cy' points to an index of the selected menu item, use it to switch to a context.
 		_, cy := v.Cursor()
 
+		var next func(g *gocui.Gui) error // runs after the menu is closed
+
 		switch app.config.menu.menuType {
 		case menuPgss:
-			switch cy {
-			case 0:
-				viewSwitchHandler(app.config, "statements_timings")
-			case 1:
-				viewSwitchHandler(app.config, "statements_general")
-			case 2:
-				viewSwitchHandler(app.config, "statements_io")
-			case 3:
-				viewSwitchHandler(app.config, "statements_temp")
-			case 4:
-				viewSwitchHandler(app.config, "statements_local")
-			default:
-				viewSwitchHandler(app.config, "statements_timings")
+			names := []string{
+				"statements_timings", "statements_general", "statements_io", "statements_temp", "statements_local",
+				"statements_parallel", "statements_jit", "statements_latency",
+			}
+			name := names[0]
+			if cy < len(names) {
+				name = names[cy]
+			}
+			if viewSupported(app.ui, app, name) {
+				viewSwitchHandler(app.config, name)
+				printCmdline(app.ui, viewMessage(app))
 			}
-			printCmdline(app.ui, app.config.view.Msg)
 		case menuProgress:
 			switch cy {
 			case 0:
@@ -141,23 +174,33 @@ func menuSelect(app *app) func(g *gocui.Gui, v *gocui.View) error {
 				viewSwitchHandler(app.config, "progress_index")
 			}
 			printCmdline(app.ui, app.config.view.Msg)
+		case menuHistory:
+			if cy < len(historyWindows) {
+				viewSwitchHandler(app.config, view.ASH)
+
+				// Settings of the view (e.g. order, filters) are kept, only period of history is changed.
+				w := view.SessionsHistory(historyWindows[cy])
+				app.config.view.Window, app.config.view.Msg = w.Window, w.Msg
+				app.config.publishView()
+				printCmdline(app.ui, app.config.view.Msg)
+			}
+		case menuPlugins:
+			names := plugin.Names(app.config.views)
+			if cy < len(names) {
+				viewSwitchHandler(app.config, names[cy])
+				printCmdline(app.ui, app.config.view.Msg)
+			}
 		case menuConf:
-			switch cy {
-			case 0:
-				if err := editPgConfig(g, app.db, gucMainConfFile, app.uiExit); err != nil {
-					return err
-				}
-			case 1:
-				if err := editPgConfig(g, app.db, gucHbaFile, app.uiExit); err != nil {
-					return err
-				}
-			case 2:
-				if err := editPgConfig(g, app.db, gucIdentFile, app.uiExit); err != nil {
-					return err
-				}
-			case 3:
-				if err := editPgConfig(g, app.db, gucRecoveryFile, app.uiExit); err != nil {
-					return err
+			files := []string{gucMainConfFile, gucHbaFile, gucIdentFile, gucRecoveryFile}
+			if cy < len(files) {
+				filename, name := files[cy], strings.TrimSpace(app.config.menu.items[cy])
+				prompt := fmt.Sprintf(app.config.messages.T("dialog.confirm.edit_config"), name)
+
+				// Editing is confirmed after the menu is closed, name of the file should be typed when typing is required.
+				next = func(g *gocui.Gui) error {
+					return confirmAction(app, g, policy.EditConfig, prompt, name, func(g *gocui.Gui) error {
+						return editPgConfig(g, app.db, filename, app.uiExit, app.audit, app.config.messages)
+					})
 				}
 			}
 		case menuNone:
@@ -166,7 +209,16 @@ func menuSelect(app *app) func(g *gocui.Gui, v *gocui.View) error {
 
 		// When menu item has been submitted by user, close menu and reset menu properties in the config.
 		app.config.menu = selectMenuStyle(menuNone)
-		return menuClose(g, v)
+		err := menuClose(g, v)
+		if err != nil {
+			return err
+		}
+
+		if next != nil {
+			return next(g)
+		}
+
+		return nil
 	}
 }
 
@@ -11,9 +11,11 @@ func Test_selectMenuStyle(t *testing.T) {
 		want int
 	}{
 		{menu: menuNone, want: 0},
-		{menu: menuPgss, want: 5},
+		{menu: menuPgss, want: 8},
 		{menu: menuProgress, want: 3},
 		{menu: menuConf, want: 4},
+		{menu: menuPlugins, want: 0},
+		{menu: menuHistory, want: 5},
 	}
 
 	for _, tc := range testcases {
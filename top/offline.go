@@ -0,0 +1,273 @@
+package top
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/header"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/log"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// offlineRefresh defines refresh interval of offline mode.
+	offlineRefresh = time.Second
+	// offlinePingInterval defines interval between checks whether Postgres accepts connections.
+	offlinePingInterval = 5 * time.Second
+	// offlineLogLines defines minimal number of log lines shown in offline mode.
+	offlineLogLines = 5
+)
+
+// offline defines offline mode of 'pgcenter top': when local Postgres is unreachable at startup, stats which don't
+// require connection are shown until Postgres accepts connections, hence the first minutes of an outage could be
+// triaged: system stats, usage of Postgres directories, data directory health (including pg_control state) and log tail.
+type offline struct {
+	config    postgres.Config
+	collector *stat.OfflineCollector
+	header    *app // used for formatting system stats lines of the header
+
+	mu        sync.Mutex
+	stat      stat.Offline
+	err       error     // the last connection error
+	since     time.Time // time when Postgres has been found unreachable
+	attempts  int       // number of failed connection attempts
+	reachable bool      // Postgres accepts connections
+}
+
+// runOffline runs offline mode for local Postgres which is unreachable, data directory is located using lock file of
+// Unix socket unless specified. Returns true when Postgres accepts connections, false when user quits.
+func runOffline(dbConfig postgres.Config, opts Options, connErr error) (bool, error) {
+	socketDir, port, ok := dbConfig.LocalSocket()
+	if !ok {
+		return false, connErr
+	}
+
+	datadir := opts.DataDir
+	if datadir == "" {
+		datadir, _ = stat.LocateDataDirectory(socketDir, port)
+	}
+
+	collector, err := stat.NewOfflineCollector(datadir)
+	if err != nil {
+		return false, err
+	}
+
+	messages, err := i18n.New(opts.UI)
+	if err != nil {
+		return false, err
+	}
+
+	hdr := newConfig()
+	hdr.messages = messages
+
+	o := &offline{
+		config:    dbConfig,
+		collector: collector,
+		header:    &app{config: hdr},
+		err:       connErr,
+		since:     time.Now(),
+		attempts:  1,
+	}
+
+	return o.run()
+}
+
+// run runs offline mode UI until Postgres accepts connections or user quits.
+func (o *offline) run() (bool, error) {
+	// Terminal is used by UI, log records written to stderr would break the screen.
+	log.DisableStderr()
+
+	g, err := gocui.NewGui(gocui.OutputNormal)
+	if err != nil {
+		return false, fmt.Errorf("create UI failed: %s", err)
+	}
+	defer g.Close()
+
+	g.SetManagerFunc(o.layout)
+
+	keys := []key{
+		{"", gocui.KeyCtrlC, o.quit},
+		{"", gocui.KeyCtrlQ, o.quit},
+		{"", 'q', o.quit},
+	}
+	for _, k := range keys {
+		if err := g.SetKeybinding(k.viewname, k.key, gocui.ModNone, k.handler); err != nil {
+			return false, fmt.Errorf("set keybinding failed: %s", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		o.collect(ctx, g)
+		wg.Done()
+	}()
+
+	err = g.MainLoop()
+
+	cancel()
+	wg.Wait()
+
+	if err != nil && err != gocui.ErrQuit {
+		return false, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.reachable, nil
+}
+
+// collect collects stats and checks whether Postgres accepts connections until context is done. UI is quit when
+// Postgres accepts connections.
+func (o *offline) collect(ctx context.Context, g *gocui.Gui) {
+	t := time.NewTicker(offlineRefresh)
+	defer t.Stop()
+
+	lastPing := time.Now()
+
+	for {
+		_, maxY := g.Size()
+		s := o.collector.Update(ctx, offlineLogSize(maxY))
+
+		o.mu.Lock()
+		o.stat = s
+		o.mu.Unlock()
+
+		g.Update(o.render)
+
+		if time.Since(lastPing) >= offlinePingInterval {
+			lastPing = time.Now()
+			err := postgres.Ping(o.config)
+
+			o.mu.Lock()
+			if err == nil {
+				o.reachable = true
+			} else {
+				o.err = err
+				o.attempts++
+			}
+			o.mu.Unlock()
+
+			if err == nil {
+				g.Update(func(_ *gocui.Gui) error { return gocui.ErrQuit })
+				return
+			}
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// offlineLogSize returns number of log lines which fit the screen of specified height below other stats.
+func offlineLogSize(height int) int {
+	if n := height - 30; n > offlineLogLines {
+		return n
+	}
+	return offlineLogLines
+}
+
+// layout defines offline mode UI layout.
+func (o *offline) layout(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	if maxX == 0 || maxY == 0 {
+		return fmt.Errorf("")
+	}
+
+	v, err := g.SetView("offline", -1, -1, maxX, maxY)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return fmt.Errorf("set offline view on layout failed: %s", err)
+		}
+		v.Frame = false
+		return o.render(g)
+	}
+
+	return nil
+}
+
+// render prints stats collected in offline mode.
+func (o *offline) render(g *gocui.Gui) error {
+	v, err := g.View("offline")
+	if err != nil {
+		return fmt.Errorf("set focus on offline view failed: %s", err)
+	}
+	v.Clear()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var buf bytes.Buffer
+	o.print(&buf, time.Now())
+	_, err = v.Write(buf.Bytes())
+	return err
+}
+
+// print prints stats collected in offline mode.
+func (o *offline) print(w io.Writer, now time.Time) {
+	s := o.stat
+
+	fmt.Fprintf(w, "\033[31;1mPostgres is unreachable\033[0m for %s, %d connection attempts failed, trying every %s, press q to quit\n",
+		now.Sub(o.since).Round(time.Second), o.attempts, offlinePingInterval)
+	fmt.Fprintf(w, "%s\n", formatError(o.err))
+
+	// System stats.
+	if s.SystemError != nil {
+		fmt.Fprintf(w, "pgcenter: %s\n%s\n", now.Format("2006-01-02 15:04:05"), formatError(s.SystemError))
+	} else {
+		for _, line := range []string{header.Load, header.CPU, header.Mem, header.Swap} {
+			fmt.Fprintln(w, formatHeaderLine(line, stat.Stat{System: s.System}, o.header))
+		}
+	}
+	fmt.Fprintln(w)
+
+	// Data directory health.
+	fmt.Fprintf(w, "\033[30;47m%-16s%-10s%s\033[0m\n", "check", "status", "details")
+	if s.DatadirError != nil {
+		fmt.Fprintln(w, formatError(s.DatadirError))
+	} else {
+		for _, row := range s.Datadir.Values {
+			format := checkFormat(view.DatadirHealth, s.Datadir.Cols, row)
+			if format == "" {
+				format = "%-*s"
+			}
+			fmt.Fprintf(w, format, 16, row[0].String)
+			fmt.Fprintf(w, format, 10, row[1].String)
+			fmt.Fprintf(w, format, 0, row[2].String)
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintln(w)
+
+	// Usage of directories.
+	if s.StorageError != nil {
+		fmt.Fprintf(w, "\033[30;47m%s\033[0m\n%s\n", "Usage of directories:", formatError(s.StorageError))
+	} else {
+		_ = printStorage(w, s.Storage)
+	}
+	fmt.Fprintln(w)
+
+	// Log tail.
+	switch {
+	case s.LogError != nil:
+		fmt.Fprintf(w, "\033[30;47m%s\033[0m\n%s\n", "Log:", formatError(s.LogError))
+	default:
+		fmt.Fprintf(w, "\033[30;47m%s:\033[0m\n%s", s.Logfile, s.Logtail)
+	}
+}
+
+// quit quits offline mode.
+func (o *offline) quit(_ *gocui.Gui, _ *gocui.View) error {
+	return gocui.ErrQuit
+}
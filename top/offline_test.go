@@ -0,0 +1,57 @@
+package top
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_offlineLogSize(t *testing.T) {
+	assert.Equal(t, offlineLogLines, offlineLogSize(0))
+	assert.Equal(t, offlineLogLines, offlineLogSize(32))
+	assert.Equal(t, 20, offlineLogSize(50))
+}
+
+func Test_offline_print(t *testing.T) {
+	now := time.Now()
+	o := &offline{
+		header:   &app{config: newConfig()},
+		err:      errors.New("dial error: no such file or directory"),
+		since:    now.Add(-time.Minute),
+		attempts: 12,
+		stat: stat.Offline{
+			Datadir: stat.PGresult{
+				Cols: []string{"check", "status", "details"},
+				Values: [][]sql.NullString{
+					{{String: "backup_label", Valid: true}, {String: "warning", Valid: true}, {String: "present", Valid: true}},
+				},
+			},
+			System:  stat.System{Storage: stat.Storage{{Name: "pg_wal", Path: "/data/pg_wal", Size: 16777216, Files: 1}}},
+			Logfile: "/data/log/postgresql.log",
+			Logtail: []byte("LOG:  database system was interrupted\n"),
+		},
+	}
+
+	var buf bytes.Buffer
+	o.print(&buf, now)
+	got := buf.String()
+
+	assert.True(t, strings.HasPrefix(got, "\033[31;1mPostgres is unreachable\033[0m for 1m0s, 12 connection attempts failed"))
+	assert.Contains(t, got, "dial error: no such file or directory")
+	assert.Contains(t, got, "\033[33;1mbackup_label    \033[0m")
+	assert.Contains(t, got, "/data/pg_wal")
+	assert.Contains(t, got, "/data/log/postgresql.log:\033[0m\nLOG:  database system was interrupted\n")
+
+	// Failed stats are shown as errors.
+	o.stat = stat.Offline{SystemError: errors.New("no procfs"), DatadirError: errors.New("data directory is unknown")}
+	buf.Reset()
+	o.print(&buf, now)
+	got = buf.String()
+	assert.Contains(t, got, "no procfs")
+	assert.Contains(t, got, "data directory is unknown")
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/audit"
+	"github.com/lesovsky/pgcenter/internal/i18n"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/query"
 	"github.com/lesovsky/pgcenter/internal/stat"
@@ -66,8 +68,9 @@ func showPgConfig(db *postgres.DB, uiExit chan int) func(g *gocui.Gui, _ *gocui.
 	}
 }
 
-// editPgConfig opens specified configuration file in $EDITOR program.
-func editPgConfig(g *gocui.Gui, db *postgres.DB, filename string, uiExit chan int) error {
+// editPgConfig opens specified configuration file in $EDITOR program. Editing is recorded into audit log before
+// opening the editor, if the record can't be written the file is not opened.
+func editPgConfig(g *gocui.Gui, db *postgres.DB, filename string, uiExit chan int, auditlog *audit.Log, messages *i18n.Catalog) error {
 	if !db.Local {
 		printCmdline(g, "Edit config is not supported for remote hosts")
 		return nil
@@ -88,6 +91,11 @@ func editPgConfig(g *gocui.Gui, db *postgres.DB, filename string, uiExit chan in
 		configFile = dataDirectory + "/" + filename
 	}
 
+	if err := auditAction(auditlog, db, audit.ActionEditConfig, configFile, nil); err != nil {
+		printCmdline(g, messages.T("cmdline.edit_config.audit"), err)
+		return nil
+	}
+
 	var editor string
 	if editor = os.Getenv("EDITOR"); editor == "" {
 		editor = "vi"
@@ -1,38 +1,42 @@
 package top
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/jroimartin/gocui"
-	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/stat"
 	"os"
 	"os/exec"
 )
 
 // showPgLog opens Postgres log in $PAGER program.
-func showPgLog(db *postgres.DB, version int, uiExit chan int) func(g *gocui.Gui, _ *gocui.View) error {
+func showPgLog(app *app) func(g *gocui.Gui, _ *gocui.View) error {
 	return func(g *gocui.Gui, _ *gocui.View) error {
-		if !db.Local {
-			printCmdline(g, "Show log is not supported for remote hosts")
-			return nil
+		if app.config.logreader != nil {
+			return showLogReader(g, app.config.logreader, !app.db.Local, app.uiExit)
 		}
 
-		logfile, err := stat.GetPostgresCurrentLogfile(db, version)
-		if err != nil {
-			printCmdline(g, "Can't get path to log file")
+		if !app.db.Local {
+			printCmdline(g, "Show log is not supported for remote hosts")
 			return nil
 		}
 
-		var pager string
-		if pager = os.Getenv("PAGER"); pager == "" {
-			pager = "less"
+		logfile, err := stat.GetPostgresCurrentLogfile(app.db, app.postgresProps.VersionNum)
+		if err != nil {
+			// Logging collector is likely disabled, try systemd journal.
+			r, jerr := stat.NewJournalReader(stat.DefaultJournaldUnit)
+			if jerr != nil {
+				printCmdline(g, "Can't get path to log file")
+				return nil
+			}
+			return showLogReader(g, r, false, app.uiExit)
 		}
 
 		// Exit from UI and stats loop. Restore it after $PAGER is closed.
-		uiExit <- 1
+		app.uiExit <- 1
 		g.Close()
 
-		cmd := exec.Command(pager, logfile) // #nosec G204
+		cmd := exec.Command(getPager(), logfile) // #nosec G204
 		cmd.Stdout = os.Stdout
 
 		if err := cmd.Run(); err != nil {
@@ -42,3 +46,45 @@ func showPgLog(db *postgres.DB, version int, uiExit chan int) func(g *gocui.Gui,
 		return nil
 	}
 }
+
+// showLogReader opens Postgres log read by log reader: journal is opened with journalctl, recent messages of other
+// sources are passed to $PAGER program.
+func showLogReader(g *gocui.Gui, r stat.LogReader, remote bool, uiExit chan int) error {
+	if remote && !r.Remote() {
+		printCmdline(g, "Show log from %s is not supported for remote hosts", r.Name())
+		return nil
+	}
+
+	var cmd *exec.Cmd
+	if jr, ok := r.(*stat.JournalReader); ok {
+		cmd = jr.PagerCommand()
+	} else {
+		buf, err := r.Tail(stat.SyslogLinesMax)
+		if err != nil {
+			printCmdline(g, "Read log failed: %s", err)
+			return nil
+		}
+		cmd = exec.Command(getPager()) // #nosec G204
+		cmd.Stdin = bytes.NewReader(buf)
+	}
+
+	// Exit from UI and stats loop. Restore it after pager is closed.
+	uiExit <- 1
+	g.Close()
+
+	cmd.Stdout = os.Stdout
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("open %s failed: %s", r.Name(), err)
+	}
+
+	return nil
+}
+
+// getPager returns pager program defined by $PAGER, or less.
+func getPager() string {
+	if pager := os.Getenv("PAGER"); pager != "" {
+		return pager
+	}
+	return "less"
+}
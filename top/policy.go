@@ -0,0 +1,78 @@
+package top
+
+import (
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/policy"
+	"strings"
+)
+
+// pendingAction describes action waiting for confirmation by user.
+type pendingAction struct {
+	prompt   string                   // prompt of confirmation dialog
+	expected string                   // answer which should be typed for confirmation, any answer confirms if empty
+	run      func(g *gocui.Gui) error // performs the action
+}
+
+// permitted wraps handler of an action which could be disabled by policy. If the action is disabled user is notified
+// about that, name is the message ID of the action's description.
+func permitted(app *app, action string, name string, handler func(g *gocui.Gui, v *gocui.View) error) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		if app.config.policies.Policy(action) == policy.Disabled {
+			printCmdline(g, app.config.messages.T("cmdline.policy.disabled"), app.config.messages.T(name))
+			return nil
+		}
+
+		return handler(g, v)
+	}
+}
+
+// confirmAction performs action accordingly to its policy: immediately, after confirmation or after typing name of
+// the action's target. Prompt describes the action and is shown in confirmation dialog.
+func confirmAction(app *app, g *gocui.Gui, action string, prompt string, target string, run func(g *gocui.Gui) error) error {
+	switch app.config.policies.Policy(action) {
+	case policy.Disabled:
+		printCmdline(g, app.config.messages.T("cmdline.policy.do_nothing"))
+		return nil
+	case policy.Confirm:
+		app.config.pending = &pendingAction{prompt: prompt + app.config.messages.T("dialog.confirm"), run: run}
+	case policy.Type:
+		app.config.pending = &pendingAction{
+			prompt:   prompt + fmt.Sprintf(app.config.messages.T("dialog.confirm.type"), target),
+			expected: target,
+			run:      run,
+		}
+	default:
+		return run(g)
+	}
+
+	return dialogOpen(app, dialogConfirm)(g, nil)
+}
+
+// finishPending returns pending action if user's answer confirms it, otherwise nil is returned and user is notified.
+// Action is confirmed by typed name of its target, or by empty answer or explicit 'y'/'yes' when name is not required;
+// any other answer (e.g. 'n') cancels the action.
+func finishPending(app *app, answer string) (func(g *gocui.Gui) error, string) {
+	p := app.config.pending
+	app.config.pending = nil
+
+	if p == nil {
+		return nil, ""
+	}
+
+	answer = strings.TrimSpace(answer)
+
+	if p.expected != "" {
+		if answer != p.expected {
+			return nil, app.config.messages.T("dialog.confirm.mismatch")
+		}
+		return p.run, ""
+	}
+
+	switch strings.ToLower(answer) {
+	case "", "y", "yes":
+		return p.run, ""
+	default:
+		return nil, app.config.messages.T("dialog.confirm.rejected")
+	}
+}
@@ -0,0 +1,105 @@
+package top
+
+import (
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/policy"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_permitted(t *testing.T) {
+	var called bool
+	handler := func(_ *gocui.Gui, _ *gocui.View) error {
+		called = true
+		return nil
+	}
+
+	app := &app{config: newConfig()}
+	assert.NoError(t, permitted(app, policy.Terminate, "action.terminate", handler)(nil, nil))
+	assert.True(t, called)
+
+	called = false
+	app.config.policies = policy.Config{Terminate: policy.Disabled}
+	assert.NoError(t, permitted(app, policy.Terminate, "action.terminate", handler)(nil, nil))
+	assert.False(t, called)
+
+	// Other actions are not affected.
+	assert.NoError(t, permitted(app, policy.Cancel, "action.cancel", handler)(nil, nil))
+	assert.True(t, called)
+}
+
+func Test_confirmAction(t *testing.T) {
+	var called int
+	run := func(_ *gocui.Gui) error {
+		called++
+		return nil
+	}
+
+	// Action without confirmation is run immediately.
+	app := &app{config: newConfig()}
+	assert.NoError(t, confirmAction(app, nil, policy.Cancel, "Cancel query of backend 123.", "123", run))
+	assert.Equal(t, 1, called)
+	assert.Nil(t, app.config.pending)
+
+	// Disabled action is not run.
+	app.config.policies = policy.Config{Cancel: policy.Disabled}
+	assert.NoError(t, confirmAction(app, nil, policy.Cancel, "Cancel query of backend 123.", "123", run))
+	assert.Equal(t, 1, called)
+	assert.Nil(t, app.config.pending)
+}
+
+func Test_finishPending(t *testing.T) {
+	var called int
+	run := func(_ *gocui.Gui) error {
+		called++
+		return nil
+	}
+
+	app := &app{config: newConfig()}
+
+	// No pending action.
+	next, msg := finishPending(app, "")
+	assert.Nil(t, next)
+	assert.Equal(t, "", msg)
+
+	// Empty answer or explicit 'yes' confirms action.
+	for _, answer := range []string{"", "y", " Yes "} {
+		app.config.pending = &pendingAction{prompt: "Terminate group of backends. Confirm [Enter or y - yes, Esc or n - no]", run: run}
+		next, msg = finishPending(app, answer)
+		assert.NotNil(t, next)
+		assert.Equal(t, "", msg)
+		assert.Nil(t, app.config.pending)
+	}
+	assert.NoError(t, next(nil))
+	assert.Equal(t, 1, called)
+
+	// Other answers cancel action.
+	for _, answer := range []string{"n", "no", "x"} {
+		app.config.pending = &pendingAction{prompt: "Terminate group of backends. Confirm [Enter or y - yes, Esc or n - no]", run: run}
+		next, msg = finishPending(app, answer)
+		assert.Nil(t, next)
+		assert.Equal(t, "Do nothing. Operation is not confirmed.", msg)
+		assert.Nil(t, app.config.pending)
+	}
+
+	// Typed name should match.
+	app.config.pending = &pendingAction{prompt: "Terminate backend 123. Type '123' to confirm: ", expected: "123", run: run}
+	next, msg = finishPending(app, "124")
+	assert.Nil(t, next)
+	assert.Equal(t, "Do nothing. Typed name doesn't match.", msg)
+	assert.Nil(t, app.config.pending)
+
+	app.config.pending = &pendingAction{prompt: "Terminate backend 123. Type '123' to confirm: ", expected: "123", run: run}
+	next, msg = finishPending(app, " 123 ")
+	assert.NotNil(t, next)
+	assert.Equal(t, "", msg)
+}
+
+func Test_dialogPrompt(t *testing.T) {
+	app := &app{config: newConfig()}
+	assert.Equal(t, "PID to cancel: ", dialogPrompt(app, dialogCancelQuery))
+
+	app.config.pending = &pendingAction{prompt: "Reset statistics of database shop. Type 'shop' to confirm: "}
+	assert.Equal(t, "Reset statistics of database shop. Type 'shop' to confirm: ", dialogPrompt(app, dialogConfirm))
+	assert.Equal(t, "PID to cancel: ", dialogPrompt(app, dialogCancelQuery))
+}
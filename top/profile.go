@@ -0,0 +1,161 @@
+package top
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/profile"
+	"strconv"
+	"time"
+)
+
+const (
+	// profileFrequency defines sampling frequency of live profiling.
+	profileFrequency = 100 * time.Millisecond
+	// profileRefresh defines how often live profile is redrawn.
+	profileRefresh = time.Second
+)
+
+// startProfile starts live profiling of backend with specified PID and opens popup with accumulating profile.
+func startProfile(app *app, answer string) string {
+	pid, err := strconv.Atoi(answer)
+	if err != nil {
+		return fmt.Sprintf("Profile: do nothing, %s", err.Error())
+	}
+
+	// Profiling uses dedicated connection, main connection is used by stats collector.
+	conn, err := postgres.Connect(app.db.Config)
+	if err != nil {
+		return fmt.Sprintf("Profile: do nothing, %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.config.profileCancel = cancel
+
+	p := profile.NewLiveProfiler(pid, 256)
+	g := app.ui
+
+	// Popup should be opened after dialog is closed, otherwise focus is switched back to 'sysstat' view.
+	g.Update(func(g *gocui.Gui) error {
+		return openProfileView(g, pid)
+	})
+
+	go runProfile(ctx, g, conn, p, app.hooks)
+
+	return fmt.Sprintf("Profile: profiling process %d", pid)
+}
+
+// runProfile samples backend's activity and periodically redraws its profile until profiling is stopped. Hooks are run
+// when profiled backend is finished.
+func runProfile(ctx context.Context, g *gocui.Gui, conn *postgres.DB, p *profile.LiveProfiler, hooks *hook.Runner) {
+	defer conn.Close()
+
+	sample := time.NewTicker(profileFrequency)
+	defer sample.Stop()
+	refresh := time.NewTicker(profileRefresh)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sample.C:
+			err := p.Sample(conn)
+			if err != nil {
+				var noProc *profile.NoProcessError
+				if errors.As(err, &noProc) {
+					hooks.Fire(hook.Event{
+						Event: hook.ProfileFinished, Instance: hook.InstanceName(conn.Config),
+						Details: map[string]interface{}{"pid": noProc.Pid},
+					})
+				}
+
+				printProfile(g, p, fmt.Sprintf("LOG: Stop profiling, %s", err))
+				return
+			}
+		case <-refresh.C:
+			printProfile(g, p, "")
+		}
+	}
+}
+
+// printProfile prints accumulated profile in the profile popup.
+func printProfile(g *gocui.Gui, p *profile.LiveProfiler, msg string) {
+	var buf bytes.Buffer
+	err := p.Print(&buf)
+	if err != nil {
+		msg = fmt.Sprintf("LOG: Print profile failed: %s", err)
+	}
+
+	if msg != "" {
+		buf.WriteString(msg + "\n")
+	}
+
+	g.Update(func(g *gocui.Gui) error {
+		// Popup might be already closed.
+		v, err := g.View("profile")
+		if err != nil {
+			return nil
+		}
+
+		v.Clear()
+		_, err = fmt.Fprint(v, buf.String())
+		if err != nil {
+			return fmt.Errorf("print on profile view failed: %s", err)
+		}
+		return nil
+	})
+}
+
+// openProfileView creates popup view for displaying live profile.
+func openProfileView(g *gocui.Gui, pid int) error {
+	maxX, maxY := g.Size()
+	v, err := g.SetView("profile", maxX/8, maxY/5, 7*maxX/8, 4*maxY/5)
+	if err != nil {
+		// gocui.ErrUnknownView is OK, it means a new view has been created.
+		if err != gocui.ErrUnknownView {
+			return fmt.Errorf("set profile view on layout failed: %s", err)
+		}
+	}
+
+	v.Title = fmt.Sprintf(" Wait events profile of process %d (Esc or q - close) ", pid)
+	v.Frame = true
+
+	_, err = fmt.Fprintln(v, "LOG: Waiting for the first samples")
+	if err != nil {
+		return fmt.Errorf("print on profile view failed: %s", err)
+	}
+
+	if _, err := g.SetCurrentView("profile"); err != nil {
+		return fmt.Errorf("set profile view as current on layout failed: %s", err)
+	}
+
+	return nil
+}
+
+// closeProfile stops live profiling and closes profile popup.
+func closeProfile(app *app) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		if app.config.profileCancel != nil {
+			app.config.profileCancel()
+			app.config.profileCancel = nil
+		}
+
+		v.Clear()
+		err := g.DeleteView("profile")
+		if err != nil {
+			return fmt.Errorf("delete profile view failed: %s", err)
+		}
+
+		if _, err := g.SetCurrentView("sysstat"); err != nil {
+			return fmt.Errorf("set focus on sysstat view failed: %s", err)
+		}
+
+		printCmdline(g, "Profile: stopped")
+		return nil
+	}
+}
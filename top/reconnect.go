@@ -0,0 +1,263 @@
+package top
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/log"
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"sync"
+	"time"
+)
+
+const (
+	// reconnectMinDelay defines delay before the second reconnection attempt, the first attempt is made immediately.
+	reconnectMinDelay = time.Second
+	// reconnectMaxDelay defines maximum delay between reconnection attempts.
+	reconnectMaxDelay = time.Minute
+	// connEventsMax defines how many connection events are kept in the log.
+	connEventsMax = 100
+)
+
+// connEvent describes an event related to connection state.
+type connEvent struct {
+	time time.Time
+	msg  string
+}
+
+// reconnector tracks state of connection to Postgres and schedules reconnection attempts using exponential backoff.
+// It is used by stats collecting goroutine and UI, hence access is synchronized.
+type reconnector struct {
+	mu       sync.Mutex
+	lost     bool          // connection is lost and not reestablished yet
+	since    time.Time     // time when connection has been lost
+	attempts int           // number of failed reconnection attempts
+	delay    time.Duration // delay before the next attempt
+	next     time.Time     // time of the next attempt
+	err      error         // the last error
+	events   []connEvent   // recent connection events
+	hooks    *hook.Runner  // runs user hooks when connection is lost and restored
+	instance string        // name of instance used in hooks events
+}
+
+// reconnectingError describes state of reconnection, it is displayed instead of stats while connection is lost.
+type reconnectingError struct {
+	down     time.Duration // how long connection is lost
+	attempts int           // number of failed reconnection attempts
+	next     time.Duration // time left until the next attempt
+	err      error         // the last error
+}
+
+// Error implements error interface.
+func (e *reconnectingError) Error() string {
+	return fmt.Sprintf("reconnecting... connection lost %s ago, %d attempts failed, next attempt in %s: %s",
+		e.down, e.attempts, e.next, e.err)
+}
+
+// newReconnector creates new reconnector.
+func newReconnector() *reconnector {
+	return &reconnector{}
+}
+
+// log adds event to the log of connection events, the oldest events are removed.
+func (r *reconnector) log(now time.Time, format string, a ...interface{}) {
+	r.events = append(r.events, connEvent{time: now, msg: fmt.Sprintf(format, a...)})
+	if len(r.events) > connEventsMax {
+		r.events = r.events[len(r.events)-connEventsMax:]
+	}
+}
+
+// disconnected marks connection as lost, the first reconnection attempt is due immediately.
+func (r *reconnector) disconnected(now time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.lost {
+		return
+	}
+
+	r.lost, r.since, r.attempts, r.delay, r.next, r.err = true, now, 0, reconnectMinDelay, now, err
+	r.log(now, "connection lost: %s", err)
+	log.Warn("connection lost", "instance", r.instance, "error", err)
+
+	r.hooks.Fire(hook.Event{
+		Event: hook.ConnectionLost, Time: now, Instance: r.instance,
+		Details: map[string]string{"error": err.Error()},
+	})
+}
+
+// isLost returns true if connection is lost.
+func (r *reconnector) isLost() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lost
+}
+
+// due returns true if it's time for the next reconnection attempt.
+func (r *reconnector) due(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lost && !now.Before(r.next)
+}
+
+// failed accounts failed reconnection attempt and schedules the next one, delay between attempts is doubled.
+func (r *reconnector) failed(now time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts++
+	r.err = err
+	r.next = now.Add(r.delay)
+	r.log(now, "reconnection attempt %d failed, next attempt in %s: %s", r.attempts, r.delay, err)
+	log.Debug("reconnection attempt failed", "instance", r.instance, "attempt", r.attempts, "error", err)
+
+	r.delay *= 2
+	if r.delay > reconnectMaxDelay {
+		r.delay = reconnectMaxDelay
+	}
+}
+
+// restored marks connection as reestablished.
+func (r *reconnector) restored(now time.Time, restarted bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	msg := "connection restored after %s, %d attempts failed"
+	if restarted {
+		msg += ", Postgres has been restarted (or switched), stats are reset"
+	}
+	r.log(now, msg, now.Sub(r.since).Round(time.Second), r.attempts)
+	log.Info("connection restored", "instance", r.instance, "down", now.Sub(r.since).Round(time.Second), "restarted", restarted)
+
+	r.hooks.Fire(hook.Event{
+		Event: hook.ConnectionRestored, Time: now, Instance: r.instance,
+		Details: map[string]interface{}{
+			"down_seconds": now.Sub(r.since).Seconds(), "failed_attempts": r.attempts, "restarted": restarted,
+		},
+	})
+
+	r.lost, r.attempts, r.err = false, 0, nil
+}
+
+// status returns current state of reconnection.
+func (r *reconnector) status(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var next time.Duration
+	if now.Before(r.next) {
+		next = r.next.Sub(now).Round(time.Second)
+	}
+
+	return &reconnectingError{
+		down:     now.Sub(r.since).Round(time.Second),
+		attempts: r.attempts,
+		next:     next,
+		err:      r.err,
+	}
+}
+
+// print prints log of connection events.
+func (r *reconnector) print(buf *bytes.Buffer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.events) == 0 {
+		buf.WriteString("No connection events\n")
+		return
+	}
+
+	for _, e := range r.events {
+		fmt.Fprintf(buf, "%s %s\n", e.time.Format("2006-01-02 15:04:05"), e.msg)
+	}
+}
+
+// reconnect makes reconnection attempt if it's time for it. Returns stats which should be displayed: while connection
+// is lost, the last collected stats are displayed together with state of reconnection.
+func reconnect(ctx context.Context, db *postgres.DB, c *stat.Collector, rc *reconnector, v view.View, refresh time.Duration, last stat.Stat) stat.Stat {
+	now := time.Now()
+
+	if rc.due(now) {
+		err := postgres.Reconnect(ctx, db)
+		if err == nil {
+			var restarted bool
+			restarted, err = c.Reconnected(db)
+			if err == nil {
+				rc.restored(time.Now(), restarted)
+
+				// Stats snapshots have been reset, take a new "previous" snapshot and wait for the next refresh.
+				if restarted {
+					_, err = c.UpdateContext(ctx, db, v, refresh)
+					if err != nil {
+						return stat.Stat{Error: err}
+					}
+					return last
+				}
+
+				stats, err := c.UpdateContext(ctx, db, v, refresh)
+				if err != nil {
+					stats.Error = err
+				}
+				return stats
+			}
+		}
+
+		rc.failed(time.Now(), err)
+	}
+
+	last.Pgstat.Activity.State = "reconnecting"
+	last.Error = rc.status(time.Now())
+	return last
+}
+
+// showConnLog opens popup with log of connection events.
+func showConnLog(app *app) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		maxX, maxY := g.Size()
+		v, err := g.SetView("connlog", maxX/8, maxY/5, 7*maxX/8, 4*maxY/5)
+		if err != nil {
+			// gocui.ErrUnknownView is OK, it means a new view has been created.
+			if err != gocui.ErrUnknownView {
+				return fmt.Errorf("set connlog view on layout failed: %s", err)
+			}
+		}
+
+		v.Title = " Connection events (Esc or q - close) "
+		v.Frame = true
+		v.Autoscroll = true
+		v.Clear()
+
+		var buf bytes.Buffer
+		app.reconnector.print(&buf)
+
+		_, err = fmt.Fprint(v, buf.String())
+		if err != nil {
+			return fmt.Errorf("print on connlog view failed: %s", err)
+		}
+
+		if _, err := g.SetCurrentView("connlog"); err != nil {
+			return fmt.Errorf("set connlog view as current on layout failed: %s", err)
+		}
+
+		return nil
+	}
+}
+
+// closeConnLog closes popup with log of connection events.
+func closeConnLog(g *gocui.Gui, v *gocui.View) error {
+	v.Clear()
+	err := g.DeleteView("connlog")
+	if err != nil {
+		return fmt.Errorf("delete connlog view failed: %s", err)
+	}
+
+	if _, err := g.SetCurrentView("sysstat"); err != nil {
+		return fmt.Errorf("set focus on sysstat view failed: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package top
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_reconnector(t *testing.T) {
+	rc := newReconnector()
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.False(t, rc.isLost())
+	assert.False(t, rc.due(now))
+
+	rc.disconnected(now, fmt.Errorf("test error"))
+	assert.True(t, rc.isLost())
+	assert.True(t, rc.due(now)) // the first attempt is made immediately
+
+	// Repeated disconnect doesn't reset state.
+	rc.disconnected(now.Add(time.Second), fmt.Errorf("another error"))
+	assert.Equal(t, now, rc.since)
+
+	// Delays between attempts are doubled up to the maximum.
+	var delays []time.Duration
+	ts := now
+	for i := 0; i < 9; i++ {
+		rc.failed(ts, fmt.Errorf("attempt error"))
+		assert.False(t, rc.due(ts))
+		delays = append(delays, rc.next.Sub(ts))
+		ts = rc.next
+		assert.True(t, rc.due(ts))
+	}
+	assert.Equal(t, []time.Duration{
+		1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second,
+		time.Minute, time.Minute, time.Minute,
+	}, delays)
+
+	err := rc.status(ts.Add(-30 * time.Second))
+	assert.Equal(t, &reconnectingError{down: 213 * time.Second, attempts: 9, next: 30 * time.Second, err: fmt.Errorf("attempt error")}, err)
+	assert.Equal(t, "reconnecting... connection lost 3m33s ago, 9 attempts failed, next attempt in 30s: attempt error", err.Error())
+	assert.Equal(t, "WARNING: reconnecting... connection lost 3m33s ago, 9 attempts failed, next attempt in 30s: attempt error", formatError(err))
+
+	rc.restored(ts, true)
+	assert.False(t, rc.isLost())
+	assert.False(t, rc.due(ts))
+
+	// Disconnect after restore starts with minimal delay.
+	rc.disconnected(ts, fmt.Errorf("test error"))
+	rc.failed(ts, fmt.Errorf("attempt error"))
+	assert.Equal(t, ts.Add(time.Second), rc.next)
+
+	var buf bytes.Buffer
+	rc.print(&buf)
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 13)
+	assert.Equal(t, "2021-01-01 12:00:00 connection lost: test error", string(lines[0]))
+	assert.Equal(t, "2021-01-01 12:00:00 reconnection attempt 1 failed, next attempt in 1s: attempt error", string(lines[1]))
+	assert.Equal(t, "2021-01-01 12:04:03 connection restored after 4m3s, 9 attempts failed, Postgres has been restarted (or switched), stats are reset", string(lines[10]))
+}
+
+func Test_reconnector_log(t *testing.T) {
+	rc := newReconnector()
+
+	var buf bytes.Buffer
+	rc.print(&buf)
+	assert.Equal(t, "No connection events\n", buf.String())
+
+	now := time.Now()
+	for i := 0; i < connEventsMax+10; i++ {
+		rc.log(now, "event %d", i)
+	}
+	assert.Len(t, rc.events, connEventsMax)
+	assert.Equal(t, "event 10", rc.events[0].msg)
+}
@@ -3,12 +3,16 @@ package top
 import (
 	"database/sql"
 	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/audit"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/policy"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/query"
 )
 
-// doReload performs reload of Postgres service by executing pg_reload_conf().
-func doReload(answer string, db *postgres.DB) string {
+// doReload performs reload of Postgres service by executing pg_reload_conf(). Reload is recorded into audit log.
+func doReload(answer string, db *postgres.DB, auditlog *audit.Log, messages *i18n.Catalog) string {
 	var message string
 
 	switch answer {
@@ -16,9 +20,10 @@ func doReload(answer string, db *postgres.DB) string {
 		var status sql.NullBool
 
 		err := db.QueryRow(query.ExecReloadConf).Scan(&status)
+		auditErr := auditAction(auditlog, db, audit.ActionReload, "", err)
 		if err != nil {
 			message = fmt.Sprintf("Reload: failed, %s", err.Error())
-			return message
+			return auditMessage(messages, message, auditErr)
 		}
 
 		if status.Bool {
@@ -26,6 +31,7 @@ func doReload(answer string, db *postgres.DB) string {
 		} else {
 			message = "Reload: no error, got NULL response"
 		}
+		message = auditMessage(messages, message, auditErr)
 	case "n":
 		message = "Reload: do nothing, canceled"
 	default:
@@ -34,3 +40,18 @@ func doReload(answer string, db *postgres.DB) string {
 
 	return message
 }
+
+// requestReload reloads Postgres service accordingly to policy of the action. When confirmation is required the usual
+// reload dialog is opened.
+func requestReload(app *app) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		if app.config.policies.Policy(policy.ReloadConfig) == policy.Confirm {
+			return dialogOpen(app, dialogPgReload)(g, v)
+		}
+
+		return confirmAction(app, g, policy.ReloadConfig, app.config.messages.T("dialog.confirm.reload"), "reload", func(g *gocui.Gui) error {
+			printCmdline(g, doReload("y", app.db, app.audit, app.config.messages))
+			return nil
+		})
+	}
+}
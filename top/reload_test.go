@@ -1,6 +1,7 @@
 package top
 
 import (
+	"github.com/lesovsky/pgcenter/internal/i18n"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -20,10 +21,10 @@ func Test_doReload(t *testing.T) {
 	assert.NoError(t, err)
 
 	for _, tc := range testcases {
-		assert.Equal(t, tc.want, doReload(tc.answer, conn))
+		assert.Equal(t, tc.want, doReload(tc.answer, conn, nil, i18n.Default()))
 	}
 
 	// Test with closed conn
 	conn.Close()
-	assert.Equal(t, "Reload: failed, conn closed", doReload(testcases[0].answer, conn))
+	assert.Equal(t, "Reload: failed, conn closed", doReload(testcases[0].answer, conn, nil, i18n.Default()))
 }
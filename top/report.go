@@ -82,13 +82,18 @@ query info:
 )
 
 // getQueryReport queries statements stats, generate the report and returns it.
-func getQueryReport(answer string, version int, db *postgres.DB) (report, string) {
+func getQueryReport(answer string, opts query.Options, db *postgres.DB) (report, string) {
 	if answer == "" {
 		return report{}, "Report: do nothing"
 	}
 
+	q, ok := query.Select("statements_report", opts)
+	if !ok {
+		return report{}, "Report: not supported"
+	}
+
 	var r report
-	err := db.QueryRow(query.SelectQueryReportQuery(version), answer).Scan(
+	err := db.QueryRow(q.Query, answer).Scan(
 		&r.Query, &r.QueryID, &r.Usename, &r.Datname, &r.TotalCalls, &r.TotalRows, &r.TotalAllTime,
 		&r.TotalPlanTime, &r.TotalPlanTimeDistRatio, &r.TotalCPUTime, &r.TotalCPUTimeDistRatio, &r.TotalIOTime, &r.TotalIOTimeDistRatio,
 		&r.Calls, &r.CallsRatio, &r.Rows, &r.RowsRatio,
@@ -117,20 +122,20 @@ func printQueryReport(g *gocui.Gui, r report, uiExit chan int) string {
 		return err.Error()
 	}
 
-	var pager string
-	if pager = os.Getenv("PAGER"); pager == "" {
-		pager = "less"
-	}
+	return runPager(g, buf.String(), uiExit)
+}
 
+// runPager exits from UI and passes content to $PAGER program, returns error message if pager failed.
+func runPager(g *gocui.Gui, content string, uiExit chan int) string {
 	// Exit from UI, will restore it after $PAGER is closed.
 	uiExit <- 1
 	g.Close()
 
-	cmd := exec.Command(pager) // #nosec G204
-	cmd.Stdin = strings.NewReader(buf.String())
+	cmd := exec.Command(getPager()) // #nosec G204
+	cmd.Stdin = strings.NewReader(content)
 	cmd.Stdout = os.Stdout
 
-	err = cmd.Run()
+	err := cmd.Run()
 	if err != nil {
 		return err.Error()
 	}
@@ -2,6 +2,7 @@ package top
 
 import (
 	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/query"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -26,7 +27,7 @@ func Test_getQueryReport(t *testing.T) {
 	}
 
 	for _, tc := range testcases {
-		_, got := getQueryReport(tc.answer, 130000, conn)
+		_, got := getQueryReport(tc.answer, query.Options{Version: 130000}, conn)
 		assert.Equal(t, tc.want, got)
 	}
 }
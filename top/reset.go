@@ -3,6 +3,9 @@ package top
 import (
 	"fmt"
 	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/audit"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/policy"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/query"
 )
@@ -10,24 +13,46 @@ import (
 // resetStat resets Postgres stats counters.
 // Reset statistics that belongs to current database and pg_stat_statements stats.
 // Don't reset shared stats, such as bgwriter or archiver.
-func resetStat(db *postgres.DB, pgssAvail bool) func(g *gocui.Gui, _ *gocui.View) error {
+// Reset is recorded into audit log.
+func resetStat(db *postgres.DB, pgssAvail bool, auditlog *audit.Log, messages *i18n.Catalog) func(g *gocui.Gui, _ *gocui.View) error {
 	return func(g *gocui.Gui, _ *gocui.View) error {
 		msg := "Reset statistics."
 
 		_, err := db.Exec(query.ExecResetStats)
+		auditErr := auditAction(auditlog, db, audit.ActionResetStats, "database stats", err)
 		if err != nil {
 			msg = fmt.Sprintf("Reset statistics failed: %s", err)
 		}
 
 		if pgssAvail {
 			_, err = db.Exec(query.ExecResetPgStatStatements)
+			if e := auditAction(auditlog, db, audit.ActionResetStats, "pg_stat_statements", err); e != nil {
+				auditErr = e
+			}
 			if err != nil {
 				msg = fmt.Sprintf("Reset pg_stat_statements statistics failed: %s", err)
 			}
 		}
 
-		printCmdline(g, msg)
+		printCmdline(g, auditMessage(messages, msg, auditErr))
 
 		return nil
 	}
 }
+
+// requestResetStat resets Postgres stats counters accordingly to policy of the action. Name of the current database
+// should be typed when typing is required.
+func requestResetStat(app *app) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		var dbname string
+		if app.db.Config.Config != nil {
+			dbname = app.db.Config.Config.Database
+		}
+
+		prompt := fmt.Sprintf(app.config.messages.T("dialog.confirm.reset_stats"), dbname)
+
+		return confirmAction(app, g, policy.ResetStats, prompt, dbname, func(g *gocui.Gui) error {
+			return resetStat(app.db, app.postgresProps.ExtPGSSAvail, app.audit, app.config.messages)(g, nil)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package top
 
 import (
+	"github.com/lesovsky/pgcenter/internal/i18n"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -10,10 +11,10 @@ func Test_resetStat(t *testing.T) {
 	conn, err := postgres.NewTestConnect()
 	assert.NoError(t, err)
 
-	fn := resetStat(conn, true)
+	fn := resetStat(conn, true, nil, i18n.Default())
 	assert.NoError(t, fn(nil, nil))
 
-	fn = resetStat(conn, false)
+	fn = resetStat(conn, false, nil, i18n.Default())
 	assert.NoError(t, fn(nil, nil))
 
 	conn.Close()
@@ -0,0 +1,39 @@
+package top
+
+import (
+	"context"
+	"fmt"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"strings"
+)
+
+// setRole changes current role of the session and adjusts available actions accordingly to privileges of the new role.
+func setRole(app *app, answer string) string {
+	role := strings.TrimSpace(answer)
+
+	err := app.db.SetRole(context.Background(), role)
+	if err != nil {
+		return fmt.Sprintf("Set role: %s", err)
+	}
+
+	p, err := stat.GetPrivileges(app.db)
+	if err != nil {
+		return fmt.Sprintf("Set role: get privileges failed: %s", err)
+	}
+
+	applyPrivileges(app, p)
+
+	if role == "" {
+		return "Set role: reset to session user. " + p.Summary(app.config.messages)
+	}
+
+	return fmt.Sprintf("Set role: %s. %s", role, p.Summary(app.config.messages))
+}
+
+// applyPrivileges adjusts actions and queries accordingly to privileges of the current role.
+func applyPrivileges(app *app, p stat.Privileges) {
+	app.postgresProps.Privileges = p
+
+	// Signalling backends of other roles fails without necessary privileges, signal only backends of own roles.
+	app.config.queryOptions.OwnBackendsOnly = !p.SignalAll()
+}
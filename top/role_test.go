@@ -0,0 +1,37 @@
+package top
+
+import (
+	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_applyPrivileges(t *testing.T) {
+	app := &app{config: newConfig()}
+
+	applyPrivileges(app, stat.Privileges{PgMonitor: true})
+	assert.True(t, app.postgresProps.Privileges.PgMonitor)
+	assert.True(t, app.config.queryOptions.OwnBackendsOnly)
+
+	applyPrivileges(app, stat.Privileges{PgSignalBackend: true})
+	assert.False(t, app.postgresProps.Privileges.PgMonitor)
+	assert.False(t, app.config.queryOptions.OwnBackendsOnly)
+}
+
+func Test_setRole(t *testing.T) {
+	db, err := postgres.NewTestConnect()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	app := &app{config: newConfig(), db: db}
+
+	assert.Contains(t, setRole(app, "pg_monitor"), "Set role: pg_monitor. Privileges: pg_monitor")
+	assert.True(t, app.config.queryOptions.OwnBackendsOnly)
+	assert.Equal(t, "pg_monitor", db.Config.Role())
+
+	assert.Equal(t, "Set role: reset to session user. Privileges: superuser, all features are available.", setRole(app, ""))
+	assert.False(t, app.config.queryOptions.OwnBackendsOnly)
+
+	assert.Contains(t, setRole(app, "pgcenter_unknown_role"), "Set role: set role failed")
+}
@@ -3,9 +3,14 @@ package top
 import (
 	"fmt"
 	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/audit"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/policy"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/query"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -16,10 +21,10 @@ const (
 	groupOthers
 )
 
-// killSingle sends cancel or terminate signal to a single Postgres backend.
-func killSingle(db *postgres.DB, mode string, answer string) string {
+// killSingle sends cancel or terminate signal to a single Postgres backend. Sent signal is recorded into audit log.
+func killSingle(db *postgres.DB, mode string, answer string, hooks *hook.Runner, auditlog *audit.Log, messages *i18n.Catalog) string {
 	if mode != "cancel" && mode != "terminate" {
-		return "Signals: do nothing, unknown mode"
+		return messages.T("signals.unknown_mode")
 	}
 
 	var q string
@@ -33,31 +38,74 @@ func killSingle(db *postgres.DB, mode string, answer string) string {
 
 	pid, err := strconv.Atoi(answer)
 	if err != nil {
-		return fmt.Sprintf("Signals: do nothing, %s", err.Error())
+		return messages.Sprintf("signals.do_nothing", err.Error())
 	}
 
 	_, err = db.Exec(q, pid)
+	auditErr := auditAction(auditlog, db, mode, fmt.Sprintf("pid %d", pid), err)
 	if err != nil {
-		return fmt.Sprintf("Signals: do nothing, %s", err.Error())
+		return auditMessage(messages, messages.Sprintf("signals.do_nothing", err.Error()), auditErr)
 	}
 
-	return "Signals: done"
+	fireSignalled(hooks, db, mode, map[string]interface{}{"pid": pid})
+
+	return auditMessage(messages, messages.T("signals.done"), auditErr)
+}
+
+// requestKillSingle returns function which sends signal to a single backend accordingly to policy of the action.
+func requestKillSingle(app *app, mode string, answer string) func(g *gocui.Gui) error {
+	action, prompt := policy.Cancel, "dialog.confirm.cancel"
+	if mode == "terminate" {
+		action, prompt = policy.Terminate, "dialog.confirm.terminate"
+	}
+
+	pid := strings.TrimSpace(answer)
+
+	return func(g *gocui.Gui) error {
+		return confirmAction(app, g, action, fmt.Sprintf(app.config.messages.T(prompt), pid), pid, func(g *gocui.Gui) error {
+			printCmdline(g, killSingle(app.db, mode, pid, app.hooks, app.audit, app.config.messages))
+			return nil
+		})
+	}
 }
 
-// killGroup sends cancel or terminate signal to group of Postgres backends.
+// requestKillGroup sends signal to group of backends accordingly to policy of the action. Group's state mask should
+// be typed when typing is required.
+func requestKillGroup(app *app, mode string) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		if app.config.view.Name != "activity" {
+			printCmdline(g, app.config.messages.T("dialog.denied.kill"))
+			return nil
+		}
+
+		action, prompt := policy.CancelGroup, "dialog.cancel_group"
+		if mode == "terminate" {
+			action, prompt = policy.TerminateGroup, "dialog.terminate_group"
+		}
+
+		group := strings.TrimSpace(strings.TrimPrefix(printMaskString(app.config.procMask), "Mask: "))
+
+		return confirmAction(app, g, action, app.config.messages.T(prompt), group, func(g *gocui.Gui) error {
+			printCmdline(g, killGroup(app, mode))
+			return nil
+		})
+	}
+}
+
+// killGroup sends cancel or terminate signal to group of Postgres backends. Sent signals are recorded into audit log.
 func killGroup(app *app, mode string) string {
 	if app.config.view.Name != "activity" {
-		return "Signals: sending signals allowed in pg_stat_activity only"
+		return app.config.messages.T("signals.activity_only")
 	}
 
 	mask := app.config.procMask
 
 	if mask == 0 {
-		return "Signals: do nothing, process mask is empty"
+		return app.config.messages.T("signals.empty_mask")
 	}
 
 	if mode != "cancel" && mode != "terminate" {
-		return "Signals: do nothing, unknown mode"
+		return app.config.messages.T("signals.unknown_mode")
 	}
 
 	// Select signal function: pg_cancel_backend or pg_terminate_backend.
@@ -78,6 +126,8 @@ func killGroup(app *app, mode string) string {
 		groupOthers:   "state IN ('fastpath function call', 'disabled')",
 	}
 
+	group := strings.TrimSpace(strings.TrimPrefix(printMaskString(mask), "Mask: "))
+
 	// Walk through the states, if state is in the mask then send signal to that group of process.
 	var signalled, signalledTotal int64
 	for state, part := range states {
@@ -88,30 +138,51 @@ func killGroup(app *app, mode string) string {
 			}
 
 			// format query
-			q, err := query.Format(template, app.config.queryOptions)
+			q, args, err := query.Format(template, app.config.queryOptions)
 			if err != nil {
-				return fmt.Sprintf("Signals: %s", err.Error())
+				return app.config.messages.Sprintf("signals.failed", err.Error())
 			}
 
 			// execute query
-			err = app.db.QueryRow(q).Scan(&signalled)
+			err = app.db.QueryRow(q, args...).Scan(&signalled)
 			if err != nil {
-				return fmt.Sprintf("Signals: %s", err.Error())
+				auditErr := auditAction(app.audit, app.db, mode, fmt.Sprintf("group %s, %d signalled", group, signalledTotal), err)
+				return auditMessage(app.config.messages, app.config.messages.Sprintf("signals.failed", err.Error()), auditErr)
 			}
 
 			signalledTotal += signalled
 		}
 	}
 
+	auditErr := auditAction(app.audit, app.db, mode, fmt.Sprintf("group %s, %d signalled", group, signalledTotal), nil)
+
+	if signalledTotal > 0 {
+		fireSignalled(app.hooks, app.db, mode, map[string]interface{}{"group": group, "count": signalledTotal})
+	}
+
 	var msg string
 	switch mode {
 	case "cancel":
-		msg = "Signals: cancelled " + strconv.FormatInt(signalledTotal, 10) + " queries."
+		msg = app.config.messages.Sprintf("signals.cancelled", signalledTotal)
 	case "terminate":
-		msg = "Signals: terminated " + strconv.FormatInt(signalledTotal, 10) + " backends."
+		msg = app.config.messages.Sprintf("signals.terminated", signalledTotal)
+	}
+
+	return auditMessage(app.config.messages, msg, auditErr)
+}
+
+// fireSignalled runs user hooks about backends signalled from UI. Postgres role used for sending signals is added to
+// event details.
+func fireSignalled(hooks *hook.Runner, db *postgres.DB, mode string, details map[string]interface{}) {
+	event := hook.BackendCancelled
+	if mode == "terminate" {
+		event = hook.BackendTerminated
 	}
 
-	return msg
+	if db.Config.Config != nil {
+		details["user"] = db.Config.Config.User
+	}
+	hooks.Fire(hook.Event{Event: event, Instance: hook.InstanceName(db.Config), Details: details})
 }
 
 // setProcMask set process mask.
@@ -2,6 +2,7 @@ package top
 
 import (
 	"fmt"
+	"github.com/lesovsky/pgcenter/internal/i18n"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/stretchr/testify/assert"
 	"sync"
@@ -34,11 +35,20 @@ func Test_killSingle(t *testing.T) {
 	assert.NoError(t, err)
 
 	for _, tc := range testcases {
-		assert.Equal(t, tc.want, killSingle(db, tc.mode, tc.pid))
+		assert.Equal(t, tc.want, killSingle(db, tc.mode, tc.pid, nil, nil, i18n.Default()))
 	}
 
 	db.Close()
-	assert.Equal(t, "Signals: do nothing, conn closed", killSingle(db, "cancel", pid))
+	assert.Equal(t, "Signals: do nothing, conn closed", killSingle(db, "cancel", pid, nil, nil, i18n.Default()))
+}
+
+func Test_killSingle_messages(t *testing.T) {
+	messages, err := i18n.New(i18n.Config{Locale: "ru"})
+	assert.NoError(t, err)
+
+	// Messages are taken from catalog of the configured locale.
+	assert.Equal(t, "Сигналы: ничего не сделано, неизвестный режим", killSingle(nil, "invalid", "123", nil, nil, messages))
+	assert.Equal(t, "Signals: do nothing, unknown mode", killSingle(nil, "invalid", "123", nil, nil, i18n.Default()))
 }
 
 func Test_killGroup(t *testing.T) {
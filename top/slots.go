@@ -0,0 +1,139 @@
+package top
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/policy"
+	"github.com/lesovsky/pgcenter/internal/query"
+	"strings"
+	"time"
+)
+
+const (
+	// peekChangesLimit defines how many changes of logical replication slot are decoded.
+	peekChangesLimit = 10
+	// peekChangesTimeout defines how long decoding of changes could take, decoding of huge transactions is cancelled.
+	peekChangesTimeout = 30 * time.Second
+)
+
+// binaryPlugins defines output plugins which changes are binary and can't be decoded by pg_logical_slot_peek_changes.
+var binaryPlugins = map[string]bool{"pgoutput": true}
+
+// slotProperties describes replication slot properties which define whether its changes could be peeked.
+type slotProperties struct {
+	slotType string
+	plugin   string
+	active   bool
+	samedb   bool // slot belongs to the current database
+}
+
+// slotChange describes change decoded from logical replication slot.
+type slotChange struct {
+	lsn  string
+	xid  string
+	data string
+}
+
+// requestPeekChanges returns function which peeks changes of the slot accordingly to policy of the action.
+func requestPeekChanges(app *app, answer string) func(g *gocui.Gui) error {
+	slot := strings.TrimSpace(answer)
+
+	return func(g *gocui.Gui) error {
+		if slot == "" {
+			printCmdline(g, app.config.messages.T("peek.do_nothing"))
+			return nil
+		}
+
+		prompt := fmt.Sprintf(app.config.messages.T("dialog.confirm.peek"), slot)
+		return confirmAction(app, g, policy.PeekChanges, prompt, slot, func(g *gocui.Gui) error {
+			printCmdline(g, peekChanges(app, g, slot))
+			return nil
+		})
+	}
+}
+
+// peekChanges decodes the first pending changes of logical replication slot without consuming them, and shows the
+// changes in $PAGER program. Changes show what the lagging slot is stuck on, e.g. huge transaction spilled to disk.
+func peekChanges(app *app, g *gocui.Gui, slot string) string {
+	messages := app.config.messages
+
+	ctx, cancel := context.WithTimeout(context.Background(), peekChangesTimeout)
+	defer cancel()
+
+	failed := func(err error) string {
+		if ctx.Err() != nil {
+			return fmt.Sprintf(messages.T("peek.timeout"), peekChangesTimeout)
+		}
+		return fmt.Sprintf(messages.T("peek.failed"), err)
+	}
+
+	var props slotProperties
+	err := app.db.QueryRowContext(ctx, query.GetSlotProperties, slot).Scan(&props.slotType, &props.plugin, &props.active, &props.samedb)
+	if err == pgx.ErrNoRows {
+		return fmt.Sprintf(messages.T("peek.not_found"), slot)
+	} else if err != nil {
+		return failed(err)
+	}
+
+	if msg := peekDenied(slot, props, messages); msg != "" {
+		return msg
+	}
+
+	rows, err := app.db.QueryContext(ctx, query.PeekSlotChanges, slot, peekChangesLimit)
+	if err != nil {
+		return failed(err)
+	}
+	defer rows.Close()
+
+	var changes []slotChange
+	for rows.Next() {
+		var c slotChange
+		if err := rows.Scan(&c.lsn, &c.xid, &c.data); err != nil {
+			return failed(err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return failed(err)
+	}
+
+	if len(changes) == 0 {
+		return fmt.Sprintf(messages.T("peek.no_changes"), slot)
+	}
+
+	return runPager(g, formatSlotChanges(slot, changes), app.uiExit)
+}
+
+// peekDenied returns message explaining why changes of the slot can't be peeked, or empty string if they can. Changes
+// are decoded by text output plugins only, and can't be decoded while slot is used by a consumer or from other database.
+func peekDenied(slot string, props slotProperties, messages *i18n.Catalog) string {
+	switch {
+	case props.slotType != "logical":
+		return fmt.Sprintf(messages.T("peek.physical"), slot)
+	case binaryPlugins[props.plugin]:
+		return fmt.Sprintf(messages.T("peek.binary"), slot, props.plugin)
+	case !props.samedb:
+		return fmt.Sprintf(messages.T("peek.other_database"), slot)
+	case props.active:
+		return fmt.Sprintf(messages.T("peek.active"), slot)
+	default:
+		return ""
+	}
+}
+
+// formatSlotChanges returns text of decoded changes shown to user.
+func formatSlotChanges(slot string, changes []slotChange) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "slot:      %s\n", slot)
+	fmt.Fprintf(&b, "changes:   %d (limit %d, the last transaction is decoded completely)\n\n", len(changes), peekChangesLimit)
+
+	for _, c := range changes {
+		fmt.Fprintf(&b, "%-16s %-10s %s\n", c.lsn, c.xid, c.data)
+	}
+
+	return b.String()
+}
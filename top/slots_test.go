@@ -0,0 +1,58 @@
+package top
+
+import (
+	"github.com/lesovsky/pgcenter/internal/policy"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_requestPeekChanges(t *testing.T) {
+	app := &app{config: newConfig()}
+
+	// Empty answer does nothing.
+	assert.NoError(t, requestPeekChanges(app, " ")(nil))
+	assert.Nil(t, app.config.pending)
+
+	// Disabled action is not run.
+	app.config.policies = policy.Config{PeekChanges: policy.Disabled}
+	assert.NoError(t, requestPeekChanges(app, "sub1")(nil))
+	assert.Nil(t, app.config.pending)
+}
+
+func Test_formatSlotChanges(t *testing.T) {
+	changes := []slotChange{
+		{lsn: "0/1A2B3C4", xid: "741", data: "BEGIN 741"},
+		{lsn: "0/1A2B3C4", xid: "741", data: "table public.orders: INSERT: id[integer]:1 status[text]:'new'"},
+		{lsn: "0/1A2B4D0", xid: "741", data: "COMMIT 741"},
+	}
+
+	want := `slot:      sub1
+changes:   3 (limit 10, the last transaction is decoded completely)
+
+0/1A2B3C4        741        BEGIN 741
+0/1A2B3C4        741        table public.orders: INSERT: id[integer]:1 status[text]:'new'
+0/1A2B4D0        741        COMMIT 741
+`
+	assert.Equal(t, want, formatSlotChanges("sub1", changes))
+}
+
+func Test_peekDenied(t *testing.T) {
+	messages := newConfig().messages
+	logical := slotProperties{slotType: "logical", plugin: "test_decoding", samedb: true}
+
+	assert.Equal(t, "", peekDenied("sub1", logical, messages))
+
+	pgoutput := logical
+	pgoutput.plugin = "pgoutput"
+	assert.Contains(t, peekDenied("sub1", pgoutput, messages), "binary output")
+
+	active := logical
+	active.active = true
+	assert.Contains(t, peekDenied("sub1", active, messages), "is active")
+
+	other := logical
+	other.samedb = false
+	assert.Contains(t, peekDenied("sub1", other, messages), "other database")
+
+	assert.Contains(t, peekDenied("standby1", slotProperties{slotType: "physical"}, messages), "is physical")
+}
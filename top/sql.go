@@ -0,0 +1,156 @@
+package top
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/view"
+	"os"
+	"sort"
+	"strings"
+)
+
+// showViewSQL opens popup with SQL query of the current view.
+func showViewSQL(config *config) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		return openViewSQL(g, formatViewSQL(config.view))
+	}
+}
+
+// openViewSQL opens popup with SQL query of the view.
+func openViewSQL(g *gocui.Gui, text string) error {
+	maxX, maxY := g.Size()
+	v, err := g.SetView("sql", maxX/8, maxY/6, 7*maxX/8, 5*maxY/6)
+	if err != nil {
+		// gocui.ErrUnknownView is OK, it means a new view has been created.
+		if err != gocui.ErrUnknownView {
+			return fmt.Errorf("set sql view on layout failed: %s", err)
+		}
+	}
+
+	v.Title = " SQL of the view (c - copy, Esc or q - close) "
+	v.Frame = true
+	v.Wrap = true
+	v.Clear()
+
+	_, err = fmt.Fprint(v, text)
+	if err != nil {
+		return fmt.Errorf("print on sql view failed: %s", err)
+	}
+
+	if _, err := g.SetCurrentView("sql"); err != nil {
+		return fmt.Errorf("set sql view as current on layout failed: %s", err)
+	}
+
+	return nil
+}
+
+// copyViewSQL copies SQL query of the current view into clipboard of the terminal.
+func copyViewSQL(config *config) func(g *gocui.Gui, _ *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		// Terminal copies the text into clipboard when receives OSC 52 sequence, terminals which don't support it
+		// ignore the sequence.
+		_, err := fmt.Fprint(os.Stdout, osc52(formatViewSQL(config.view)))
+		if err != nil {
+			printCmdline(g, "SQL: copy failed: %s", err)
+			return nil
+		}
+
+		printCmdline(g, "SQL: copied to clipboard (requires terminal with OSC 52 support).")
+		return nil
+	}
+}
+
+// closeViewSQL closes popup with SQL query of the view.
+func closeViewSQL(g *gocui.Gui, v *gocui.View) error {
+	v.Clear()
+	err := g.DeleteView("sql")
+	if err != nil {
+		return fmt.Errorf("delete sql view failed: %s", err)
+	}
+
+	if _, err := g.SetCurrentView("sysstat"); err != nil {
+		return fmt.Errorf("set focus on sysstat view failed: %s", err)
+	}
+
+	return nil
+}
+
+// osc52 returns terminal escape sequence which sets clipboard content to the text.
+func osc52(text string) string {
+	return "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+}
+
+// formatViewSQL returns SQL query executed for the view with applied order and rows limit. Filters are applied by
+// pgcenter to received rows, they are shown as equivalent WHERE clause. Comments explain what is done by pgcenter
+// and can't be reproduced by the query.
+func formatViewSQL(v view.View) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "-- view: %s\n", v.Name)
+
+	if v.Plugin != nil {
+		fmt.Fprintf(&b, "-- view is based on external command, no SQL is executed: %s\n", strings.Join(v.Plugin.Command, " "))
+		return b.String()
+	}
+
+	order := "asc"
+	if v.OrderDesc {
+		order = "desc"
+	}
+	fmt.Fprintf(&b, "-- order: %s %s\n", viewColumnName(v, v.OrderKey), order)
+
+	q := v.LimitedQuery()
+	if q != v.Query {
+		fmt.Fprintf(&b, "-- limit: %d rows\n", v.Limit)
+	}
+
+	for i, arg := range v.Args {
+		fmt.Fprintf(&b, "-- $%d: %v\n", i+1, arg)
+	}
+
+	if v.DiffIntvl != [2]int{0, 0} {
+		fmt.Fprintf(&b, "-- columns %s..%s are cumulative counters, pgcenter shows their rates per second\n",
+			viewColumnName(v, v.DiffIntvl[0]), viewColumnName(v, v.DiffIntvl[1]))
+	}
+
+	if v.Group {
+		b.WriteString("-- rows are grouped by normalized text of statements by pgcenter\n")
+	}
+
+	if conds := viewFilterConds(v); len(conds) > 0 {
+		b.WriteString("-- filters are applied by pgcenter to shown values using Go regular expressions\n")
+		q = fmt.Sprintf("SELECT * FROM (%s) AS f WHERE %s", q, strings.Join(conds, " AND "))
+	}
+
+	fmt.Fprintf(&b, "\n%s;\n", q)
+
+	return b.String()
+}
+
+// viewFilterConds returns filters of the view as SQL conditions ordered by columns.
+func viewFilterConds(v view.View) []string {
+	keys := make([]int, 0, len(v.Filters))
+	for k, re := range v.Filters {
+		if re != nil && re.String() != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Ints(keys)
+
+	conds := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pattern := strings.ReplaceAll(v.Filters[k].String(), "'", "''")
+		conds = append(conds, fmt.Sprintf(`"%s"::text ~ '%s'`, strings.ReplaceAll(viewColumnName(v, k), `"`, `""`), pattern))
+	}
+
+	return conds
+}
+
+// viewColumnName returns name of the view's column, or its number if names are not known yet.
+func viewColumnName(v view.View, idx int) string {
+	if idx >= 0 && idx < len(v.Cols) {
+		return v.Cols[idx]
+	}
+	return fmt.Sprintf("column %d", idx+1)
+}
@@ -0,0 +1,61 @@
+package top
+
+import (
+	"github.com/lesovsky/pgcenter/internal/view"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+)
+
+func Test_formatViewSQL(t *testing.T) {
+	testcases := []struct {
+		name string
+		view view.View
+		want string
+	}{
+		{
+			name: "limited",
+			view: view.View{
+				Name: "sizes", Query: "SELECT relname, size FROM t", Cols: []string{"relname", "size"},
+				OrderKey: 1, OrderDesc: true, Limit: 20, Filters: map[int]*regexp.Regexp{},
+			},
+			want: "-- view: sizes\n-- order: size desc\n-- limit: 20 rows\n\n" +
+				"SELECT * FROM (SELECT relname, size FROM t) AS l ORDER BY 2 DESC NULLS LAST, 1 LIMIT 20;\n",
+		},
+		{
+			name: "diffed and filtered",
+			view: view.View{
+				Name: "tables", Query: "SELECT relname, seq_scan, idx_scan FROM t", Cols: []string{"relname", "seq_scan", "idx_scan"},
+				DiffIntvl: [2]int{1, 2}, OrderKey: 1, Filters: map[int]*regexp.Regexp{0: regexp.MustCompile("^pgbench_'a")},
+			},
+			want: "-- view: tables\n-- order: seq_scan asc\n" +
+				"-- columns seq_scan..idx_scan are cumulative counters, pgcenter shows their rates per second\n" +
+				"-- filters are applied by pgcenter to shown values using Go regular expressions\n\n" +
+				"SELECT * FROM (SELECT relname, seq_scan, idx_scan FROM t) AS f WHERE \"relname\"::text ~ '^pgbench_''a';\n",
+		},
+		{
+			name: "with arguments",
+			view: view.View{
+				Name: "activity", Query: "SELECT pid, datname FROM t WHERE datname = ANY($1::text[])", Cols: []string{"pid", "datname"},
+				Args: []interface{}{[]string{"shop", "o'brien"}}, Filters: map[int]*regexp.Regexp{},
+			},
+			want: "-- view: activity\n-- order: pid asc\n-- $1: [shop o'brien]\n\n" +
+				"SELECT pid, datname FROM t WHERE datname = ANY($1::text[]);\n",
+		},
+		{
+			name: "plugin",
+			view: view.View{Name: "custom", Plugin: &view.Plugin{Command: []string{"/bin/stats", "--json"}}},
+			want: "-- view: custom\n-- view is based on external command, no SQL is executed: /bin/stats --json\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, formatViewSQL(tc.view))
+		})
+	}
+}
+
+func Test_osc52(t *testing.T) {
+	assert.Equal(t, "\x1b]52;c;U0VMRUNUIDE=\a", osc52("SELECT 1"))
+}
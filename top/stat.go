@@ -2,30 +2,55 @@ package top
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgconn"
 	"github.com/jroimartin/gocui"
 	"github.com/lesovsky/pgcenter/internal/align"
+	"github.com/lesovsky/pgcenter/internal/baseline"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/log"
+	"github.com/lesovsky/pgcenter/internal/math"
+	"github.com/lesovsky/pgcenter/internal/pgtime"
 	"github.com/lesovsky/pgcenter/internal/postgres"
 	"github.com/lesovsky/pgcenter/internal/stat"
 	"github.com/lesovsky/pgcenter/internal/view"
+	"io"
 	"os"
+	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
-// collectStat
-func collectStat(ctx context.Context, db *postgres.DB, statCh chan<- stat.Stat, viewCh <-chan view.View) {
+// snapshot defines stats collected in background using particular view.
+type snapshot struct {
+	view string    // name of the view used for collecting stats
+	stat stat.Stat // collected stats
+}
+
+// collectStat collects stats in background using specified view and publishes snapshots of collected stats to UI,
+// updated views are received from UI. Stats are collected when refresh interval expires or when received view requires
+// re-collecting. When connection to Postgres is lost, it is reestablished using reconnector, the last collected stats
+// are sent to UI in the meantime.
+func collectStat(ctx context.Context, db *postgres.DB, rc *reconnector, history *stat.SessionsHistory, summary stat.Summary, filter stat.Filter, v view.View, statCh chan<- snapshot, viewCh <-chan view.View) {
 	c, err := stat.NewCollector(db)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	// Get current view.
-	v := <-viewCh
+	// Sample active sessions at every update for showing sessions history.
+	c.SetSessionsHistory(history)
+
+	// Collect optional stats shown in the header.
+	c.SetSummary(summary)
+
+	// Skip stats of pseudo and virtual devices.
+	c.SetFilter(filter)
 
 	// Enable collecting of extra stats if it's specified in the view.
 	c.ToggleCollectExtra(v.ShowExtra)
@@ -34,7 +59,7 @@ func collectStat(ctx context.Context, db *postgres.DB, statCh chan<- stat.Stat,
 	refresh := v.Refresh
 
 	// Run first update to prefill "previous" snapshot.
-	_, err = c.Update(db, v, refresh)
+	_, err = c.UpdateContext(ctx, db, v, refresh)
 	if err != nil {
 		fmt.Println(err)
 		return
@@ -47,210 +72,381 @@ func collectStat(ctx context.Context, db *postgres.DB, statCh chan<- stat.Stat,
 	// Set settings related to extra stats.
 	extra := v.ShowExtra
 
+	// The last successfully collected stats.
+	var last stat.Stat
+
+	// View received from UI during collecting stats.
+	var received *view.View
+
+	// Stats are collected immediately at start, then when refresh interval expires.
+	collect := true
+	next := time.Now()
+
 	// Collect stat in loop and send it to stat channel.
 	for {
-		// Collect stats.
-		stats, err := c.Update(db, v, refresh)
-		if err != nil {
-			stats.Error = err
-		}
-		statCh <- stats
-
-		// Waiting for receiving new view until refresh interval expired. When new view has been received, use its
-		// settings to adjust collector's behavior.
-		ticker := time.NewTicker(refresh)
-		select {
-		case v = <-viewCh:
-			// Update refresh interval if it is changed.
-			if refresh != v.Refresh && v.Refresh > 0 {
-				refresh = v.Refresh
-				continue
+		if received == nil && collect {
+			var stats stat.Stat
+
+			if rc.isLost() {
+				stats = reconnect(ctx, db, c, rc, v, refresh, last)
+			} else {
+				// Collect stats.
+				stats, received, err = updateStat(ctx, db, c, v, refresh, viewCh)
+				if err != nil {
+					var connErr *stat.ConnError
+					if errors.As(err, &connErr) {
+						rc.disconnected(time.Now(), connErr.Err)
+						stats = reconnect(ctx, db, c, rc, v, refresh, last)
+					} else {
+						log.Warn("collect stats failed", "view", v.Name, "error", err)
+						stats.Error = err
+					}
+				}
 			}
 
-			// Update settings related to collecting extra stats (enable, disable or switch)
-			if extra != v.ShowExtra {
-				extra = v.ShowExtra
-				c.ToggleCollectExtra(extra)
-				continue
+			// Collecting has been interrupted by new view, collected stats are not relevant anymore.
+			if received == nil || !recollectRequired(v, *received) {
+				if stats.Error == nil {
+					last = stats
+				}
+				statCh <- snapshot{view: v.Name, stat: stats}
+				next = time.Now().Add(refresh)
 			}
+		}
 
-			// When view has been updated, stop the ticker and re-initialize stats.
-			ticker.Stop()
-
-			c.Reset()
-			_, err = c.Update(db, v, refresh)
-			if err != nil {
-				statCh <- stat.Stat{Error: err}
+		// Waiting for receiving new view until refresh interval expired.
+		if received == nil {
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case nv := <-viewCh:
+				timer.Stop()
+				received = &nv
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				collect = true
+				continue
 			}
+		}
 
-			continue
-		case <-ctx.Done():
-			ticker.Stop()
+		if ctx.Err() != nil {
 			return
-		case <-ticker.C:
-			continue
 		}
-	}
-}
 
-// printStat prints collected stats in UI.
-func printStat(app *app, s stat.Stat, props stat.PostgresProperties) {
-	app.ui.Update(func(g *gocui.Gui) error {
-		v, err := g.View("sysstat")
-		if err != nil {
-			return fmt.Errorf("set focus on sysstat view failed: %s", err)
-		}
-		v.Clear()
-		err = printSysstat(v, s)
-		if err != nil {
-			return fmt.Errorf("print sysstat failed: %s", err)
+		// When new view has been received, use its settings to adjust collector's behavior.
+		prev := v
+		v, received = *received, nil
+		collect = true
+
+		// Update refresh interval if it is changed.
+		if refresh != v.Refresh && v.Refresh > 0 {
+			refresh = v.Refresh
+			continue
 		}
 
-		v, err = g.View("pgstat")
-		if err != nil {
-			return fmt.Errorf("set focus on pgstat view failed: %s", err)
+		// Update settings related to collecting extra stats (enable, disable or switch)
+		if extra != v.ShowExtra {
+			extra = v.ShowExtra
+			c.ToggleCollectExtra(extra)
+			continue
 		}
-		v.Clear()
-		err = printPgstat(v, s, props, app.db)
-		if err != nil {
-			return fmt.Errorf("print summary postgres stat failed: %s", err)
+
+		// Order, columns width and filters are applied by UI to the last collected stats, wait for the next refresh.
+		if !recollectRequired(prev, v) {
+			collect = false
+			continue
 		}
 
-		v, err = g.View("dbstat")
-		if err != nil {
-			return fmt.Errorf("set focus on dbstat view failed: %s", err)
+		// When view has been updated, re-initialize stats.
+		c.Reset()
+		if rc.isLost() {
+			continue
 		}
-		v.Clear()
 
-		err = printDbstat(v, app.config, s)
-		if err != nil {
-			return fmt.Errorf("print main postgres stat failed: %s", err)
+		_, received, err = updateStat(ctx, db, c, v, refresh, viewCh)
+		if received == nil && err != nil {
+			statCh <- snapshot{view: v.Name, stat: stat.Stat{Error: err}}
 		}
+	}
+}
 
-		if app.config.view.ShowExtra > stat.CollectNone {
-			v, err := g.View("extra")
-			if err != nil {
-				return fmt.Errorf("set focus on extra view failed: %s", err)
-			}
+// recollectRequired returns true if stats collected with previous view are not relevant for the current view, e.g.
+// when view has been switched or its query or query's arguments have been changed. Order or limit of rows read by the
+// query could be changed too when rows are limited by Postgres. Sessions history is rebuilt when its window is changed.
+func recollectRequired(prev, curr view.View) bool {
+	return prev.Name != curr.Name || prev.LimitedQuery() != curr.LimitedQuery() ||
+		!reflect.DeepEqual(prev.Args, curr.Args) || prev.Window != curr.Window
+}
 
-			switch app.config.view.ShowExtra {
-			case stat.CollectDiskstats:
-				v.Clear()
-				err := printIostat(v, s.Diskstats)
-				if err != nil {
-					return err
-				}
-			case stat.CollectNetdev:
-				v.Clear()
-				err := printNetdev(v, s.Netdevs)
-				if err != nil {
-					return err
-				}
-			case stat.CollectLogtail:
-				size, buf, err := readLogfileRecent(v, app.config.logtail)
-				if err != nil {
-					printCmdline(g, "Tail Postgres log failed: %s", err)
-					return err
+// updateStat collects stats. When new view which requires re-collecting is received from UI or context is done during
+// collecting, the in-flight query is cancelled, hence slow or stuck query doesn't block UI. Returns view received
+// during collecting, if any.
+func updateStat(ctx context.Context, db *postgres.DB, c *stat.Collector, v view.View, refresh time.Duration, viewCh <-chan view.View) (stat.Stat, *view.View, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var received *view.View
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case nv := <-viewCh:
+				received = &nv
+				if recollectRequired(v, nv) {
+					cancel()
+					return
 				}
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
 
-				if size < app.config.logtail.Size {
-					v.Clear()
-					err := app.config.logtail.Reopen(app.db, app.postgresProps.VersionNum)
-					if err != nil {
-						printCmdline(g, "Tail Postgres log failed: %s", err)
-						return err
-					}
-				}
+	stats, err := c.UpdateContext(ctx, db, v, refresh)
 
-				// Update info about logfile size.
-				app.config.logtail.Size = size
+	close(done)
+	<-exited
 
-				err = printLogtail(v, app.config.logtail.Path, buf)
-				if err != nil {
-					return err
-				}
-			}
+	return stats, received, err
+}
+
+// printStat saves stats collected in background into cache of the instance and prints them in UI if the instance is
+// the current one. Stats collected with a view which has been already switched by user are ignored.
+func printStat(app *app, inst *instance, snap snapshot) {
+	app.ui.Update(func(g *gocui.Gui) error {
+		if snap.view != inst.config.view.Name {
+			return nil
 		}
-		return nil
+
+		s := snap.stat
+		inst.last = &s
+		inst.cache[snap.view] = s
+
+		if inst != app.instances[app.current] {
+			return nil
+		}
+
+		if msg := resetNotice(s, app.config.messages); msg != "" {
+			printCmdline(g, msg)
+		}
+
+		return renderCached(g, app)
 	})
 }
 
-// printSysstat prints system stats on UI.
-func printSysstat(v *gocui.View, s stat.Stat) error {
-	var err error
+// resetNotice returns notice about reset of counters detected in collected stats. Empty string is returned when counters
+// have not been reset, decreased gauges are not considered as reset.
+func resetNotice(s stat.Stat, messages *i18n.Catalog) string {
+	if !s.Result.Reset {
+		return ""
+	}
+	return messages.T("notice.stats_reset")
+}
 
-	/* line1: current time and load average */
-	_, err = fmt.Fprintf(v, "pgcenter: %s, load average: %.2f, %.2f, %.2f\n",
-		time.Now().Format("2006-01-02 15:04:05"),
-		s.LoadAvg.One, s.LoadAvg.Five, s.LoadAvg.Fifteen)
-	if err != nil {
-		return err
+// renderCached prints the last collected stats of the current instance in UI. Stats of the current view are taken
+// from cache, hence they are printed immediately after view has been switched or changed, without waiting for stats
+// collected with the changed view.
+func renderCached(g *gocui.Gui, app *app) error {
+	inst := app.instances[app.current]
+	if inst.last == nil {
+		return nil
 	}
 
-	/* line2: cpu usage */
-	_, err = fmt.Fprintf(v, "    %%cpu: \033[37;1m%4.1f\033[0m us, \033[37;1m%4.1f\033[0m sy, \033[37;1m%4.1f\033[0m ni, \033[37;1m%4.1f\033[0m id, \033[37;1m%4.1f\033[0m wa, \033[37;1m%4.1f\033[0m hi, \033[37;1m%4.1f\033[0m si, \033[37;1m%4.1f\033[0m st\n",
-		s.CpuStat.User, s.CpuStat.Sys, s.CpuStat.Nice, s.CpuStat.Idle,
-		s.CpuStat.Iowait, s.CpuStat.Irq, s.CpuStat.Softirq, s.CpuStat.Steal)
-	if err != nil {
-		return err
+	v := app.config.view
+	s := *inst.last
+	cached := inst.cache[v.Name]
+	s.Pgstat.Result, s.Error = cached.Result, cached.Error
+
+	// Annotate backends with latencies measured with BPF.
+	if app.config.bpf != nil && v.Name == "activity" {
+		s.Result = stat.AnnotateActivity(s.Result, app.config.bpf.Latency())
 	}
 
-	/* line3: memory usage */
-	_, err = fmt.Fprintf(v, " MiB mem: \033[37;1m%6d\033[0m total, \033[37;1m%6d\033[0m free, \033[37;1m%6d\033[0m used, \033[37;1m%8d\033[0m buff/cached\n",
-		s.Meminfo.MemTotal, s.Meminfo.MemFree, s.Meminfo.MemUsed,
-		s.Meminfo.MemCached+s.Meminfo.MemBuffers+s.Meminfo.MemSlab)
-	if err != nil {
-		return err
+	// Compare ordered column with baseline.
+	if app.config.baseline != nil {
+		s.Result = app.config.baseline.Compare(v.Name, s.Result, v.OrderKey, v.UniqueKey)
 	}
 
-	/* line4: swap usage, dirty and writeback */
-	_, err = fmt.Fprintf(v, "MiB swap: \033[37;1m%6d\033[0m total, \033[37;1m%6d\033[0m free, \033[37;1m%6d\033[0m used, \033[37;1m%6d/%d\033[0m dirty/writeback\n",
-		s.Meminfo.SwapTotal, s.Meminfo.SwapFree, s.Meminfo.SwapUsed,
-		s.Meminfo.MemDirty, s.Meminfo.MemWriteback)
-	if err != nil {
-		return err
+	// Stats could be collected with another order, sort them accordingly to the current order.
+	if v.OrderKey < s.Result.Ncols {
+		s.Result.Sort(v.OrderKey, v.OrderDesc)
 	}
 
-	return nil
+	return renderStat(g, app, s)
 }
 
-// printPgstat prints summary Postgres stats on UI.
-func printPgstat(v *gocui.View, s stat.Stat, props stat.PostgresProperties, db *postgres.DB) error {
-	// line1: details of used connection, version, uptime and recovery status
-	_, err := fmt.Fprintln(v, formatInfoString(db.Config, s.Activity.State, props.Version, s.Activity.Uptime, props.Recovery))
+// renderStat prints collected stats of the current instance in UI.
+func renderStat(g *gocui.Gui, app *app, s stat.Stat) error {
+	left, right := app.config.header.Columns()
+
+	v, err := g.View("sysstat")
 	if err != nil {
-		return err
+		return fmt.Errorf("set focus on sysstat view failed: %s", err)
+	}
+	v.Clear()
+	err = printHeader(v, left, s, app)
+	if err != nil {
+		return fmt.Errorf("print sysstat failed: %s", err)
 	}
 
-	// line2: current state of connections: total, idle, idle xacts, active, waiting, others
-	_, err = fmt.Fprintf(v, "  activity:\033[37;1m%3d/%d\033[0m conns,\033[37;1m%3d/%d\033[0m prepared,\033[37;1m%3d\033[0m idle,\033[37;1m%3d\033[0m idle_xact,\033[37;1m%3d\033[0m active,\033[37;1m%3d\033[0m waiting,\033[37;1m%3d\033[0m others\n",
-		s.Activity.ConnTotal, props.GucMaxConnections, s.Activity.ConnPrepared, props.GucMaxPrepXacts,
-		s.Activity.ConnIdle, s.Activity.ConnIdleXact, s.Activity.ConnActive,
-		s.Activity.ConnWaiting, s.Activity.ConnOthers)
+	v, err = g.View("pgstat")
 	if err != nil {
-		return err
+		return fmt.Errorf("set focus on pgstat view failed: %s", err)
+	}
+	v.Clear()
+	err = printHeader(v, right, s, app)
+	if err != nil {
+		return fmt.Errorf("print summary postgres stat failed: %s", err)
 	}
 
-	// line3: current state of autovacuum: number of workers, anti-wraparound, manual vacuums and time of oldest vacuum
-	_, err = fmt.Fprintf(v, "autovacuum: \033[37;1m%2d/%d\033[0m workers/max, \033[37;1m%2d\033[0m manual, \033[37;1m%2d\033[0m wraparound, \033[37;1m%s\033[0m vac_maxtime\n",
-		s.Activity.AVWorkers, props.GucAVMaxWorkers,
-		s.Activity.AVUser, s.Activity.AVAntiwrap, s.Activity.AVMaxTime)
+	v, err = g.View("dbstat")
 	if err != nil {
-		return err
+		return fmt.Errorf("set focus on dbstat view failed: %s", err)
+	}
+	v.Clear()
+
+	err = printDbstat(v, app.config, s)
+	if err != nil {
+		return fmt.Errorf("print main postgres stat failed: %s", err)
 	}
 
-	// line4: current workload
-	_, err = fmt.Fprintf(v, "statements: \033[37;1m%3d\033[0m stmt/s, \033[37;1m%3.3f\033[0m stmt_avgtime, \033[37;1m%s\033[0m xact_maxtime, \033[37;1m%s\033[0m prep_maxtime\n",
-		s.Activity.CallsRate, s.Activity.StmtAvgTime, s.Activity.XactMaxTime, s.Activity.PrepMaxTime)
+	err = printAlerts(g, app)
 	if err != nil {
 		return err
 	}
 
+	if app.config.view.ShowExtra > stat.CollectNone {
+		v, err := g.View("extra")
+		if err != nil {
+			return fmt.Errorf("set focus on extra view failed: %s", err)
+		}
+
+		// If reading extra stats failed, print the error instead of stats.
+		if s.ExtraError != nil && app.config.view.ShowExtra != stat.CollectLogtail {
+			v.Clear()
+			_, err := fmt.Fprint(v, formatError(s.ExtraError))
+			return err
+		}
+
+		switch app.config.view.ShowExtra {
+		case stat.CollectDiskstats:
+			v.Clear()
+			err := printIostat(v, s.Diskstats)
+			if err != nil {
+				return err
+			}
+		case stat.CollectNetdev:
+			v.Clear()
+			err := printNetdev(v, s.Netdevs)
+			if err != nil {
+				return err
+			}
+		case stat.CollectStorage:
+			v.Clear()
+			err := printStorage(v, s.Storage)
+			if err != nil {
+				return err
+			}
+		case stat.CollectLogtail:
+			if app.config.logreader != nil {
+				_, y := v.Size()
+				buf, err := app.config.logreader.Tail(y - 1)
+				if err != nil {
+					printCmdline(g, "Tail Postgres log failed: %s", err)
+					return nil
+				}
+
+				return printLogtail(v, app.config.logreader.Name(), buf)
+			}
+
+			size, buf, err := readLogfileRecent(v, app.config.logtail)
+			if err != nil {
+				printCmdline(g, "Tail Postgres log failed: %s", err)
+				return err
+			}
+
+			if size < app.config.logtail.Size {
+				v.Clear()
+				err := app.config.logtail.Reopen(app.db, app.postgresProps.VersionNum)
+				if err != nil {
+					printCmdline(g, "Tail Postgres log failed: %s", err)
+					return err
+				}
+			}
+
+			// Update info about logfile size.
+			app.config.logtail.Size = size
+
+			err = printLogtail(v, app.config.logtail.Path, buf)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// highlight formats value printed in bold white.
+func highlight(format string, a ...interface{}) string {
+	return "\033[37;1m" + fmt.Sprintf(format, a...) + "\033[0m"
+}
+
+// wraparoundFormat formats value printed in bold magenta, it is used for marking anti-wraparound vacuums.
+func wraparoundFormat(format string, a ...interface{}) string {
+	return "\033[35;1m" + fmt.Sprintf(format, a...) + "\033[0m"
+}
+
+// formatResetAge returns time since stats of the view have been reset. Empty string is returned for views without
+// cumulative counters or when reset time is not tracked for them.
+func formatResetAge(v view.View, a stat.Activity, messages *i18n.Catalog) string {
+	if v.DiffIntvl == [2]int{0, 0} {
+		return ""
+	}
+
+	var age int64
+	switch {
+	case v.IsStatements():
+		age = a.StatementsResetAge
+	case v.Name == "databases", v.Name == "tables", v.Name == "tables_io", v.Name == "indexes", v.Name == view.IndexAdvisor, v.Name == "functions":
+		age = a.StatsResetAge
+	default:
+		return ""
+	}
+
+	if age < 0 {
+		return messages.Sprintf("header.reset_age", highlight("n/a"))
+	}
+
+	return messages.Sprintf("header.reset_age", highlight("%s", formatAge(time.Duration(age)*time.Second)))
+}
+
+// formatAge returns duration in compact format with two the most significant units, e.g. '3d4h', '2h15m', '42s'.
+func formatAge(d time.Duration) string {
+	days := int64(d / (24 * time.Hour))
+	hours := int64(d/time.Hour) % 24
+	minutes := int64(d/time.Minute) % 60
+	seconds := int64(d/time.Second) % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm%ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
 // formatInfoString combines connection's and general Postgres properties and provides info string.
-func formatInfoString(cfg postgres.Config, state, version, uptime, recovery string) string {
+func formatInfoString(cfg postgres.Config, state, version, uptime, recovery string, encrypted bool) string {
 	props := []string{cfg.Config.Host, strconv.Itoa(int(cfg.Config.Port)), cfg.Config.User, cfg.Config.Database, version}
 	for i, v := range props {
 		if len(props[i]) >= 20 {
@@ -263,9 +459,20 @@ func formatInfoString(cfg postgres.Config, state, version, uptime, recovery stri
 		props[3] = props[2]
 	}
 
+	ssl := "off"
+	if encrypted {
+		ssl = "on"
+	}
+
+	// Show role set at runtime, if any.
+	var role string
+	if r := cfg.Role(); r != "" {
+		role = ", role: " + r
+	}
+
 	return fmt.Sprintf(
-		"state [%s]: %s:%s %s@%s (ver: %s, up %s, recovery: %.1s)",
-		state, props[0], props[1], props[2], props[3], props[4], uptime, recovery,
+		"state [%s]: %s:%s %s@%s (ver: %s, up %s, recovery: %.1s, ssl: %s%s)",
+		state, props[0], props[1], props[2], props[3], props[4], uptime, recovery, ssl, role,
 	)
 }
 
@@ -284,19 +491,40 @@ func printDbstat(v *gocui.View, config *config, s stat.Stat) error {
 	// Align values within columns, use fixed aligning instead of dynamic.
 	if !config.view.Aligned {
 		widthes, cols := align.SetAlign(s.Result, 1000, false) // use high limit (1000) to avoid truncating last value.
+
+		// Ages and timestamps shown in relative form have their own widths.
+		if config.relativeTime {
+			for i := range widthes {
+				if i < len(cols) && relativeColumn(cols[i]) {
+					widthes[i] = relativeWidth(s.Result, i)
+				}
+			}
+		}
+
+		// Columns could be renamed in UI, widen columns up to length of their new names.
+		for i := range widthes {
+			if i < len(cols) {
+				widthes[i] = math.Max(widthes[i], utf8.RuneCountInString(config.messages.Column(config.view.Name, cols[i])))
+			}
+		}
+
 		config.view.Cols = cols
 		config.view.ColsWidth = widthes
 		config.view.Aligned = true
 	}
 
+	// Key column of the view is frozen when the view is scrolled right.
+	var cols []int
+	cols, config.scrollCol = visibleColumns(s.Result.Ncols, config.view.UniqueKey, config.scrollCol)
+
 	// Print header.
-	err := printStatHeader(v, s, config)
+	err := printStatHeader(v, s, config, cols)
 	if err != nil {
 		return err
 	}
 
 	// Print data.
-	err = printStatData(v, s, config, isFilterRequired(config.view.Filters))
+	err = printStatData(v, s, config, cols, isFilterRequired(config.view.Filters))
 	if err != nil {
 		return err
 	}
@@ -310,6 +538,11 @@ func formatError(err error) string {
 		return ""
 	}
 
+	var rcErr *reconnectingError
+	if errors.As(err, &rcErr) {
+		return fmt.Sprintf("WARNING: %s", rcErr.Error())
+	}
+
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
 		return fmt.Sprintf("%s: %s\nDETAIL: %s\nHINT: %s", pgErr.Severity, pgErr.Message, pgErr.Detail, pgErr.Hint)
@@ -318,11 +551,11 @@ func formatError(err error) string {
 	return fmt.Sprintf("ERROR: %s", err.Error())
 }
 
-// printStatHeader prints stats header.
-func printStatHeader(v *gocui.View, s stat.Stat, config *config) error {
+// printStatHeader prints stats header, only the specified columns are printed.
+func printStatHeader(v *gocui.View, s stat.Stat, config *config, cols []int) error {
 	var pname string
-	for i := 0; i < s.Result.Ncols; i++ {
-		name := s.Result.Cols[i]
+	for _, i := range cols {
+		name := config.messages.Column(config.view.Name, s.Result.Cols[i])
 
 		// mark filtered column
 		if config.view.Filters[i] != nil && config.view.Filters[i].String() != "" {
@@ -352,57 +585,280 @@ func printStatHeader(v *gocui.View, s stat.Stat, config *config) error {
 	return nil
 }
 
-// printStatData prints stats data.
-func printStatData(v *gocui.View, s stat.Stat, config *config, filter bool) error {
-	var doPrint bool
-	for colnum, rownum := 0, 0; rownum < s.Result.Nrows; rownum, colnum = rownum+1, 0 {
-		// be optimistic, we want to print the row.
-		doPrint = true
-
-		// apply filters using regexp
-		if filter {
-			for i := 0; i < s.Result.Ncols; i++ {
-				if config.view.Filters[i] != nil {
-					if config.view.Filters[i].MatchString(s.Result.Values[rownum][i].String) {
-						doPrint = true
-						break
-					} else {
-						doPrint = false
-					}
-				}
-			}
+// printStatData prints stats data, only the specified columns are printed. Rows scrolled out are skipped.
+func printStatData(v *gocui.View, s stat.Stat, config *config, cols []int, filter bool) error {
+	// select rows using regexp filters
+	var rows []int
+	for rownum := 0; rownum < s.Result.Nrows; rownum++ {
+		if !filter || rowMatches(config.view.Filters, s.Result.Values[rownum], s.Result.Ncols) {
+			rows = append(rows, rownum)
+		}
+	}
+
+	// don't scroll beyond the last screen when all rows have been read
+	if config.view.Limit == 0 || s.Result.Nrows < config.view.Limit {
+		last := len(rows) - scrollStep(config.rows)
+		if last < 0 {
+			last = 0
+		}
+		if config.scrollRow > last {
+			config.scrollRow = last
+		}
+	}
+	if config.scrollRow >= len(rows) {
+		return nil
+	}
+
+	now := time.Now()
+	for _, rownum := range rows[config.scrollRow:] {
+		// rows of anti-wraparound and aggressive vacuums are marked, check it before values are truncated
+		rowFormat := vacuumFormat(config.view.Name, s.Result.Cols, s.Result.Values[rownum])
+		if rowFormat == "" {
+			rowFormat = adviceFormat(config.view.Name, s.Result.Cols, s.Result.Values[rownum])
+		}
+		if rowFormat == "" {
+			rowFormat = checkFormat(config.view.Name, s.Result.Cols, s.Result.Values[rownum])
 		}
 
 		// print values
-		for i := range s.Result.Cols {
-			if doPrint {
-				// truncate values that longer than column width
-				valuelen := len(s.Result.Values[rownum][colnum].String)
-				if valuelen > config.view.ColsWidth[i] {
-					width := config.view.ColsWidth[i]
-					// truncate value up to column width and replace last character with '~' symbol
-					s.Result.Values[rownum][colnum].String = s.Result.Values[rownum][colnum].String[:width-1] + "~"
-				}
+		for _, i := range cols {
+			value := s.Result.Values[rownum][i].String
 
-				// print value
-				_, err := fmt.Fprintf(v, "%-*s", config.view.ColsWidth[i]+2, s.Result.Values[rownum][colnum].String)
-				if err != nil {
-					return err
-				}
-				colnum++
+			// ages and timestamps are shown relative to current time if requested, rows are ordered by original values
+			if config.relativeTime && relativeColumn(s.Result.Cols[i]) {
+				value = relativeValue(value, now)
 			}
-		}
-		if doPrint {
-			_, err := fmt.Fprintf(v, "\n")
+
+			// truncate values that longer than column width
+			if len(value) > config.view.ColsWidth[i] {
+				width := config.view.ColsWidth[i]
+				// truncate value up to column width and replace last character with '~' symbol
+				value = value[:width-1] + "~"
+			}
+
+			// print value, values exceeding thresholds and regressions relative to baseline are highlighted
+			format := thresholdFormat(config.view.Name, s.Result.Cols[i], value)
+			if rowFormat != "" {
+				format = rowFormat
+			}
+			if config.baseline != nil && s.Result.Cols[i] == baseline.Column &&
+				baseline.IsRegression(value, config.baselineThreshold) {
+				format = "\033[31;1m%-*s\033[0m"
+			}
+
+			_, err := fmt.Fprintf(v, format, config.view.ColsWidth[i]+2, value)
 			if err != nil {
 				return err
 			}
 		}
+		_, err := fmt.Fprintf(v, "\n")
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// rowMatches returns true if any value of the row matches filter of its column.
+func rowMatches(filters map[int]*regexp.Regexp, row []sql.NullString, ncols int) bool {
+	for i := 0; i < ncols; i++ {
+		if filters[i] != nil && filters[i].MatchString(row[i].String) {
+			return true
+		}
+	}
+	return false
+}
+
+// relativeColumn returns true if the column contains ages or timestamps which could be shown in relative form.
+func relativeColumn(name string) bool {
+	for _, suffix := range []string{"_age", "_start", "_until", "_reset"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return strings.HasPrefix(name, "last_")
+}
+
+// relativeValue returns age or timestamp in relative form, e.g. '2m 13s ago'. Other values are returned as-is.
+func relativeValue(value string, now time.Time) string {
+	if rel, ok := pgtime.Relative(value, now); ok {
+		return rel
+	}
+	return value
+}
+
+// relativeWidth returns width of the column which values are shown in relative form. Width is enough at least for
+// values like '59m 59s ago', hence growing ages are not truncated.
+func relativeWidth(res stat.PGresult, col int) int {
+	now := time.Now()
+	width := math.Max(len("59m 59s ago"), utf8.RuneCountInString(res.Cols[col]))
+	for _, row := range res.Values {
+		width = math.Max(width, utf8.RuneCountInString(relativeValue(row[col].String, now)))
+	}
+	return width
+}
+
+// visibleColumns returns indexes of columns printed when the view is scrolled right by offset columns, and the offset
+// limited by number of columns. The key column is printed first and it is never scrolled out.
+func visibleColumns(ncols, key, offset int) ([]int, int) {
+	if key < 0 || key >= ncols {
+		key = -1
+	}
+
+	var rest []int
+	for i := 0; i < ncols; i++ {
+		if i != key {
+			rest = append(rest, i)
+		}
+	}
+
+	if offset > len(rest)-1 {
+		offset = len(rest) - 1
+	}
+
+	// Columns are printed in original order when the view is not scrolled.
+	if offset <= 0 {
+		cols := make([]int, ncols)
+		for i := range cols {
+			cols[i] = i
+		}
+		return cols, 0
+	}
+
+	cols := make([]int, 0, ncols-offset)
+	if key >= 0 {
+		cols = append(cols, key)
+	}
+	return append(cols, rest[offset:]...), offset
+}
+
+// threshold defines values of the column which are highlighted as warning or critical.
+type threshold struct {
+	warning  float64
+	critical float64
+	lower    bool // values lower than thresholds are highlighted, e.g. cache hit ratio
+}
+
+// thresholds defines thresholds of columns highlighted in stats views, keyed by view and column names.
+var thresholds = map[string]map[string]threshold{
+	"databases": {
+		"hit_%":      {warning: 99, critical: 90, lower: true},
+		"deadlocks":  {warning: 1, critical: 5},
+		"csum_fails": {warning: 1, critical: 1},
+		"idle_xacts": {warning: 5, critical: 20},
+	},
+	"replication_slots": {
+		"spill_bytes": {warning: 1 << 20, critical: 10 << 20}, // bytes per second spilled to disk by logical decoding
+	},
+	"statements_jit": {
+		"jit_%": {warning: 10, critical: 25},
+	},
+	"stale_stats": {
+		"mod_%": {warning: 100, critical: 500}, // autoanalyze is due or lagging behind modifications
+	},
+	"roles": {
+		"conns_%":     {warning: 80, critical: 95},
+		"expire_days": {warning: 14, critical: 3, lower: true},
+	},
+}
+
+// thresholdFormat returns format for printing the value of the column, values exceeding thresholds are printed in
+// yellow (warning) or red (critical).
+func thresholdFormat(view string, column string, value string) string {
+	t, ok := thresholds[view][column]
+	if !ok {
+		return "%-*s"
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "%-*s"
+	}
+
+	if t.lower {
+		v, t.warning, t.critical = -v, -t.warning, -t.critical
+	}
+
+	switch {
+	case v >= t.critical:
+		return "\033[31;1m%-*s\033[0m"
+	case v >= t.warning:
+		return "\033[33;1m%-*s\033[0m"
+	default:
+		return "%-*s"
+	}
+}
+
+// reWraparoundVacuum matches queries of autovacuum workers running to prevent transaction IDs wraparound.
+var reWraparoundVacuum = regexp.MustCompile(`(?i)^autovacuum:.*to prevent wraparound`)
+
+// reAggressiveVacuum matches manual vacuums which freeze tuples aggressively, hence scan all pages of tables.
+var reAggressiveVacuum = regexp.MustCompile(`(?i)^vacuum\b.*\bfreeze\b`)
+
+// vacuumFormat returns format for printing values of the row which describes anti-wraparound vacuum (in magenta) or
+// aggressive vacuum (in cyan). Rows are marked in activity and vacuum progress views, empty string is returned for
+// other rows.
+func vacuumFormat(view string, cols []string, row []sql.NullString) string {
+	if view != "activity" && view != "progress_vacuum" {
+		return ""
+	}
+
+	for i, name := range cols {
+		if name != "query" || i >= len(row) {
+			continue
+		}
+
+		switch {
+		case reWraparoundVacuum.MatchString(row[i].String):
+			return "\033[35;1m%-*s\033[0m"
+		case reAggressiveVacuum.MatchString(row[i].String):
+			return "\033[36;1m%-*s\033[0m"
+		}
+	}
+
+	return ""
+}
+
+// adviceFormat returns format for printing values of the row which is flagged as candidate for indexing by index
+// advisor (in yellow), empty string is returned for other rows.
+func adviceFormat(v string, cols []string, row []sql.NullString) string {
+	if v != view.IndexAdvisor {
+		return ""
+	}
+
+	for i, name := range cols {
+		if name == "advice" && i < len(row) && row[i].String != "" {
+			return "\033[33;1m%-*s\033[0m"
+		}
+	}
+
+	return ""
+}
+
+// checkFormat returns format for printing values of the row which describes failed check of data directory health:
+// warning (in yellow), critical or failed check (in red), empty string is returned for other rows.
+func checkFormat(v string, cols []string, row []sql.NullString) string {
+	if v != view.DatadirHealth {
+		return ""
+	}
+
+	for i, name := range cols {
+		if name != "status" || i >= len(row) {
+			continue
+		}
+
+		switch row[i].String {
+		case "warning":
+			return "\033[33;1m%-*s\033[0m"
+		case "critical", "error":
+			return "\033[31;1m%-*s\033[0m"
+		}
+	}
+
+	return ""
+}
+
 // printIostat prints extra 'iostat' - block IO devices stats.
 func printIostat(v *gocui.View, s stat.Diskstats) error {
 	// print header
@@ -460,6 +916,34 @@ func printNetdev(v *gocui.View, s stat.Netdevs) error {
 	return nil
 }
 
+// printStorage prints usage of Postgres directories.
+func printStorage(v io.Writer, s stat.Storage) error {
+	// print header
+	_, err := fmt.Fprintf(v, "\033[30;47m          Directory:        Size      Growth/s     Files       Limit   %%Limit   Path\033[0m\n")
+	if err != nil {
+		return err
+	}
+
+	for _, u := range s {
+		limit, usage := "-", "-"
+		if u.Limit > 0 {
+			limit = formatBytes(u.Limit)
+			usage = fmt.Sprintf("%.2f", float64(u.Size)*100/float64(u.Limit))
+		}
+
+		growth := formatBytes(int64(u.Rate))
+		if u.Rate < 0 {
+			growth = "-" + formatBytes(int64(-u.Rate))
+		}
+
+		_, err := fmt.Fprintf(v, "%20s%12s%14s%10d%12s%9s   %s\n", u.Name, formatBytes(u.Size), growth, u.Files, limit, usage, u.Path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // readLogfileRecent reads necessary number of recent lines in logfile and return them.
 func readLogfileRecent(v *gocui.View, logfile stat.Logfile) (int64, []byte, error) {
 	// Calculate necessary number of lines and buffer size depending on size available screen.
@@ -1,31 +1,103 @@
 package top
 
 import (
+	"database/sql"
 	"fmt"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
+	"github.com/lesovsky/pgcenter/internal/i18n"
 	"github.com/lesovsky/pgcenter/internal/postgres"
+	"github.com/lesovsky/pgcenter/internal/stat"
+	"github.com/lesovsky/pgcenter/internal/view"
 	"github.com/stretchr/testify/assert"
+	"regexp"
 	"testing"
+	"time"
 )
 
+func Test_recollectRequired(t *testing.T) {
+	v := view.View{Name: "activity", Query: "SELECT 1", OrderKey: 1}
+
+	sorted := v
+	sorted.OrderKey, sorted.OrderDesc = 2, true
+	assert.False(t, recollectRequired(v, sorted))
+
+	changed := v
+	changed.Query = "SELECT 2"
+	assert.True(t, recollectRequired(v, changed))
+
+	assert.True(t, recollectRequired(v, view.View{Name: "databases", Query: "SELECT 1"}))
+
+	// Query is the same, but filtered values are changed.
+	filtered := v
+	filtered.Args = []interface{}{[]string{"shop"}}
+	assert.True(t, recollectRequired(v, filtered))
+
+	// Order of limited rows is applied by Postgres.
+	limited := v
+	limited.Limit = 10
+	assert.True(t, recollectRequired(v, limited))
+
+	sorted = limited
+	sorted.OrderKey, sorted.OrderDesc = 2, true
+	assert.True(t, recollectRequired(limited, sorted))
+
+	// Sessions history is rebuilt for another period.
+	assert.True(t, recollectRequired(view.SessionsHistory(time.Minute), view.SessionsHistory(time.Hour)))
+	assert.False(t, recollectRequired(view.SessionsHistory(time.Minute), view.SessionsHistory(time.Minute)))
+}
+
 func Test_formatInfoString(t *testing.T) {
 	testcases := []struct {
-		cfg  postgres.Config
-		want string
+		cfg       postgres.Config
+		encrypted bool
+		want      string
 	}{
 		{
 			cfg:  postgres.Config{Config: &pgx.ConnConfig{Config: pgconn.Config{Host: "127.0.0.1", Port: 1234, User: "test", Database: "testdb"}}},
-			want: "state [up]: 127.0.0.1:1234 test@testdb (ver: 13.1 on x86_64-~, up 01:23:48, recovery: f)",
+			want: "state [up]: 127.0.0.1:1234 test@testdb (ver: 13.1 on x86_64-~, up 01:23:48, recovery: f, ssl: off)",
 		},
 		{
 			cfg:  postgres.Config{Config: &pgx.ConnConfig{Config: pgconn.Config{Host: "127.0.0.1", Port: 1234, User: "test", Database: ""}}},
-			want: "state [up]: 127.0.0.1:1234 test@test (ver: 13.1 on x86_64-~, up 01:23:48, recovery: f)",
+			want: "state [up]: 127.0.0.1:1234 test@test (ver: 13.1 on x86_64-~, up 01:23:48, recovery: f, ssl: off)",
 		},
+		{
+			cfg:       postgres.Config{Config: &pgx.ConnConfig{Config: pgconn.Config{Host: "127.0.0.1", Port: 1234, User: "test", Database: "testdb"}}},
+			encrypted: true,
+			want:      "state [up]: 127.0.0.1:1234 test@testdb (ver: 13.1 on x86_64-~, up 01:23:48, recovery: f, ssl: on)",
+		},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, formatInfoString(tc.cfg, "up", "13.1 on x86_64-pc-linux-gnu Debian", "01:23:48", "f", tc.encrypted))
+	}
+}
+
+func Test_formatResetAge(t *testing.T) {
+	views := view.New()
+	a := stat.Activity{StatsResetAge: 90000, StatementsResetAge: -1}
+
+	assert.Equal(t, ", \033[37;1m1d1h\033[0m since reset", formatResetAge(views["databases"], a, i18n.Default()))
+	assert.Equal(t, ", \033[37;1m1d1h\033[0m since reset", formatResetAge(views["tables"], a, i18n.Default()))
+	assert.Equal(t, ", \033[37;1mn/a\033[0m since reset", formatResetAge(views["statements_timings"], a, i18n.Default()))
+	assert.Equal(t, "", formatResetAge(views["activity"], a, i18n.Default()))
+	assert.Equal(t, "", formatResetAge(views["replication"], a, i18n.Default()))
+}
+
+func Test_formatAge(t *testing.T) {
+	testcases := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "0s"},
+		{d: 42 * time.Second, want: "42s"},
+		{d: 5*time.Minute + 3*time.Second, want: "5m3s"},
+		{d: 2*time.Hour + 15*time.Minute + 10*time.Second, want: "2h15m"},
+		{d: 75 * time.Hour, want: "3d3h"},
 	}
 
 	for _, tc := range testcases {
-		assert.Equal(t, tc.want, formatInfoString(tc.cfg, "up", "13.1 on x86_64-pc-linux-gnu Debian", "01:23:48", "f"))
+		assert.Equal(t, tc.want, formatAge(tc.d))
 	}
 }
 
@@ -47,3 +119,174 @@ func Test_formatError(t *testing.T) {
 		assert.Equal(t, tc.want, got)
 	}
 }
+
+func Test_thresholdFormat(t *testing.T) {
+	testcases := []struct {
+		view   string
+		column string
+		value  string
+		want   string
+	}{
+		{view: "databases", column: "hit_%", value: "99.50", want: "%-*s"},
+		{view: "databases", column: "hit_%", value: "95.00", want: "\033[33;1m%-*s\033[0m"},
+		{view: "databases", column: "hit_%", value: "80.00", want: "\033[31;1m%-*s\033[0m"},
+		{view: "databases", column: "hit_%", value: "", want: "%-*s"},
+		{view: "databases", column: "deadlocks", value: "0", want: "%-*s"},
+		{view: "databases", column: "deadlocks", value: "2", want: "\033[33;1m%-*s\033[0m"},
+		{view: "databases", column: "deadlocks", value: "5", want: "\033[31;1m%-*s\033[0m"},
+		{view: "databases", column: "idle_xacts", value: "7", want: "\033[33;1m%-*s\033[0m"},
+		{view: "databases", column: "commits", value: "100", want: "%-*s"},
+		{view: "tables", column: "deadlocks", value: "2", want: "%-*s"},
+		{view: "roles", column: "conns_%", value: "96.00", want: "\033[31;1m%-*s\033[0m"},
+		{view: "roles", column: "expire_days", value: "10", want: "\033[33;1m%-*s\033[0m"},
+		{view: "roles", column: "expire_days", value: "-1", want: "\033[31;1m%-*s\033[0m"},
+		{view: "roles", column: "expire_days", value: "", want: "%-*s"},
+		{view: "statements_jit", column: "jit_%", value: "12.50", want: "\033[33;1m%-*s\033[0m"},
+		{view: "statements_jit", column: "jit_%", value: "40.00", want: "\033[31;1m%-*s\033[0m"},
+		{view: "replication_slots", column: "spill_bytes", value: "65536", want: "%-*s"},
+		{view: "stale_stats", column: "mod_%", value: "120.00", want: "\033[33;1m%-*s\033[0m"},
+		{view: "stale_stats", column: "mod_%", value: "800.00", want: "\033[31;1m%-*s\033[0m"},
+		{view: "replication_slots", column: "spill_bytes", value: "2097152", want: "\033[33;1m%-*s\033[0m"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, thresholdFormat(tc.view, tc.column, tc.value))
+	}
+}
+
+func Test_vacuumFormat(t *testing.T) {
+	cols := []string{"pid", "state", "query"}
+	row := func(query string) []sql.NullString {
+		return []sql.NullString{{String: "123", Valid: true}, {String: "active", Valid: true}, {String: query, Valid: true}}
+	}
+
+	testcases := []struct {
+		view  string
+		query string
+		want  string
+	}{
+		{view: "activity", query: "autovacuum: VACUUM public.orders (to prevent wraparound)", want: "\033[35;1m%-*s\033[0m"},
+		{view: "progress_vacuum", query: "autovacuum: VACUUM ANALYZE public.orders (to prevent wraparound)", want: "\033[35;1m%-*s\033[0m"},
+		{view: "activity", query: "autovacuum: VACUUM public.orders", want: ""},
+		{view: "activity", query: "VACUUM (FREEZE, VERBOSE) orders", want: "\033[36;1m%-*s\033[0m"},
+		{view: "progress_vacuum", query: "vacuum freeze orders", want: "\033[36;1m%-*s\033[0m"},
+		{view: "activity", query: "VACUUM orders", want: ""},
+		{view: "activity", query: "SELECT * FROM orders WHERE note = 'vacuum freeze'", want: ""},
+		{view: "tables", query: "autovacuum: VACUUM public.orders (to prevent wraparound)", want: ""},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, vacuumFormat(tc.view, cols, row(tc.query)), tc.query)
+	}
+
+	// View without query column.
+	assert.Equal(t, "", vacuumFormat("activity", []string{"pid"}, []sql.NullString{{String: "123", Valid: true}}))
+}
+
+func Test_adviceFormat(t *testing.T) {
+	cols := []string{"relation", "advice"}
+	row := func(advice string) []sql.NullString {
+		return []sql.NullString{{String: "public.orders", Valid: true}, {String: advice, Valid: true}}
+	}
+
+	assert.Equal(t, "\033[33;1m%-*s\033[0m", adviceFormat(view.IndexAdvisor, cols, row("index candidate")))
+	assert.Equal(t, "", adviceFormat(view.IndexAdvisor, cols, row("")))
+	assert.Equal(t, "", adviceFormat("tables", cols, row("index candidate")))
+}
+
+func Test_checkFormat(t *testing.T) {
+	cols := []string{"check", "status", "details"}
+	row := func(status string) []sql.NullString {
+		return []sql.NullString{{String: "backup_label", Valid: true}, {String: status, Valid: true}, {String: "", Valid: true}}
+	}
+
+	assert.Equal(t, "\033[33;1m%-*s\033[0m", checkFormat(view.DatadirHealth, cols, row("warning")))
+	assert.Equal(t, "\033[31;1m%-*s\033[0m", checkFormat(view.DatadirHealth, cols, row("critical")))
+	assert.Equal(t, "\033[31;1m%-*s\033[0m", checkFormat(view.DatadirHealth, cols, row("error")))
+	assert.Equal(t, "", checkFormat(view.DatadirHealth, cols, row("ok")))
+	assert.Equal(t, "", checkFormat("tables", cols, row("warning")))
+}
+
+func Test_visibleColumns(t *testing.T) {
+	testcases := []struct {
+		ncols, key, offset int
+		want               []int
+		wantOffset         int
+	}{
+		{ncols: 5, key: 0, offset: 0, want: []int{0, 1, 2, 3, 4}, wantOffset: 0},
+		{ncols: 5, key: 0, offset: 2, want: []int{0, 3, 4}, wantOffset: 2},
+		{ncols: 5, key: 3, offset: 1, want: []int{3, 1, 2, 4}, wantOffset: 1},
+		{ncols: 5, key: 0, offset: 10, want: []int{0, 4}, wantOffset: 3},
+		{ncols: 5, key: 7, offset: 2, want: []int{2, 3, 4}, wantOffset: 2}, // key column is out of range
+		{ncols: 0, key: 0, offset: 1, want: []int{}, wantOffset: 0},
+	}
+
+	for _, tc := range testcases {
+		cols, offset := visibleColumns(tc.ncols, tc.key, tc.offset)
+		assert.Equal(t, tc.want, cols)
+		assert.Equal(t, tc.wantOffset, offset)
+	}
+}
+
+func Test_rowMatches(t *testing.T) {
+	row := []sql.NullString{{String: "12345", Valid: true}, {String: "postgres", Valid: true}}
+	assert.True(t, rowMatches(map[int]*regexp.Regexp{1: regexp.MustCompile("^post")}, row, 2))
+	assert.True(t, rowMatches(map[int]*regexp.Regexp{0: regexp.MustCompile("^0"), 1: regexp.MustCompile("gres")}, row, 2))
+	assert.False(t, rowMatches(map[int]*regexp.Regexp{0: regexp.MustCompile("^0")}, row, 2))
+	assert.False(t, rowMatches(map[int]*regexp.Regexp{}, row, 2))
+}
+
+func Test_relativeColumn(t *testing.T) {
+	for _, name := range []string{"xact_age", "backend_start", "valid_until", "stats_reset", "last_vacuum"} {
+		assert.True(t, relativeColumn(name), name)
+	}
+	for _, name := range []string{"pid", "total_time", "avg_write", "state"} {
+		assert.False(t, relativeColumn(name), name)
+	}
+}
+
+func Test_relativeValue(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	assert.Equal(t, "2m 13s ago", relativeValue("00:02:13", now))
+	assert.Equal(t, "1d 2h ago", relativeValue("2021-03-03 03:00:00+00", now))
+	assert.Equal(t, "never", relativeValue("never", now))
+	assert.Equal(t, "", relativeValue("", now))
+}
+
+func Test_relativeWidth(t *testing.T) {
+	res := stat.PGresult{Cols: []string{"pid", "xact_age"}, Values: [][]sql.NullString{
+		{{String: "1", Valid: true}, {String: "00:00:05", Valid: true}},
+		{{String: "2", Valid: true}, {String: "1234 days 01:00:00", Valid: true}},
+	}}
+	assert.Equal(t, 12, relativeWidth(res, 1)) // '1234d 1h ago'
+
+	res.Values = res.Values[:1]
+	assert.Equal(t, 11, relativeWidth(res, 1))
+}
+
+func Test_resetNotice(t *testing.T) {
+	v := view.New()["tables"]
+	cols := []string{
+		"relation", "seq_scan", "seq_read", "idx_scan", "idx_fetch", "inserts", "updates", "deletes", "hot_updates", "live", "dead",
+		"heap_read", "heap_hit", "idx_read", "idx_hit", "toast_read", "toast_hit", "tidx_read", "tidx_hit",
+	}
+	newResult := func(seqScan, dead string) stat.PGresult {
+		row := make([]sql.NullString, len(cols))
+		for i := range row {
+			row[i] = sql.NullString{String: "100", Valid: true}
+		}
+		row[0].String, row[1].String, row[10].String = "public.pgbench_accounts", seqScan, dead
+		return stat.PGresult{Valid: true, Ncols: len(cols), Nrows: 1, Cols: cols, Values: [][]sql.NullString{row}}
+	}
+
+	// Number of dead tuples is a gauge, its decrease (e.g. after vacuum) is not a reset.
+	res, err := stat.Compare(newResult("10", "500"), newResult("10", "1000"), 1, v)
+	assert.NoError(t, err)
+	assert.Equal(t, "-500", res.Values[0][10].String)
+	assert.Equal(t, "", resetNotice(stat.Stat{Pgstat: stat.Pgstat{Result: res}}, i18n.Default()))
+
+	// Decreased counter of sequential scans is a reset.
+	res, err = stat.Compare(newResult("5", "500"), newResult("10", "1000"), 1, v)
+	assert.NoError(t, err)
+	assert.Equal(t, i18n.Default().T("notice.stats_reset"), resetNotice(stat.Stat{Pgstat: stat.Pgstat{Result: res}}, i18n.Default()))
+}
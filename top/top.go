@@ -2,23 +2,151 @@ package top
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/alert"
+	"github.com/lesovsky/pgcenter/internal/audit"
+	"github.com/lesovsky/pgcenter/internal/baseline"
+	"github.com/lesovsky/pgcenter/internal/header"
+	"github.com/lesovsky/pgcenter/internal/hook"
+	"github.com/lesovsky/pgcenter/internal/i18n"
+	"github.com/lesovsky/pgcenter/internal/notify"
+	"github.com/lesovsky/pgcenter/internal/plugin"
+	"github.com/lesovsky/pgcenter/internal/policy"
 	"github.com/lesovsky/pgcenter/internal/postgres"
-	"github.com/lesovsky/pgcenter/internal/query"
+	"github.com/lesovsky/pgcenter/internal/push"
 	"github.com/lesovsky/pgcenter/internal/stat"
+	"os"
 )
 
+// Options defines user-defined options of 'pgcenter top' command.
+type Options struct {
+	ReadOnly  bool               // disable actions which change state of Postgres
+	Actions   policy.Config      // confirmation policies of actions which change state of Postgres
+	Instances []postgres.Config  // additional instances which could be switched to
+	Alerts    alert.Config       // alert rules evaluated for all instances
+	Plugins   []plugin.Config    // external collectors shown as views
+	Hooks     []hook.Config      // user commands run on events
+	Push      push.Config        // pushing stats rates of all instances to external storages
+	LogSource string             // source of Postgres log used in log tail: file, journald[:UNIT], syslog:ADDRESS
+	BPF       bool               // measure latencies of local backends with BPF
+	Baseline  *baseline.Baseline // baseline which stats of the main instance are compared with, nil if not used
+	Threshold float64            // growth relative to baseline (in percents) highlighted as regression
+	UI        i18n.Config        // language of UI messages and names of columns
+	Notify    notify.Config      // terminal bell and desktop notifications about events
+	Header    header.Config      // summary lines shown in the header and their order
+	AuditFile string             // file where actions which change state of Postgres are recorded, default is used if empty
+	Offline   bool               // show offline mode when local Postgres is unreachable at startup, instead of exiting
+	DataDir   string             // data directory of local Postgres used in offline mode, located automatically if empty
+	Databases []string           // show activity and statements of specified databases only, all if empty
+	Users     []string           // show activity and statements of specified users only, all if empty
+	Devices   stat.FilterConfig  // patterns of block devices and network interfaces which stats are skipped
+}
+
 // RunMain is the main entry point for 'pgcenter top' command
-func RunMain(dbConfig postgres.Config) error {
+func RunMain(dbConfig postgres.Config, opts Options) error {
 	// Connect to Postgres.
 	db, err := postgres.Connect(dbConfig)
 	if err != nil {
-		return err
+		// Local Postgres might be down, in offline mode it could be triaged until it accepts connections.
+		if _, _, local := dbConfig.LocalSocket(); !opts.Offline || !local || !postgres.Unreachable(err) {
+			return err
+		}
+
+		reachable, err := runOffline(dbConfig, opts, err)
+		if err != nil || !reachable {
+			return err
+		}
+
+		db, err = postgres.Connect(dbConfig)
+		if err != nil {
+			return err
+		}
 	}
 	defer db.Close()
 
+	// Setup reader of Postgres log, by default log files are used and reader is not necessary.
+	logreader, err := stat.NewLogReader(opts.LogSource)
+	if err != nil {
+		return err
+	}
+	if logreader != nil {
+		defer func() { _ = logreader.Close() }()
+	}
+
+	// Check policies of actions.
+	err = opts.Actions.Validate()
+	if err != nil {
+		return err
+	}
+
+	// Check notifications about events.
+	err = opts.Notify.Validate()
+	if err != nil {
+		return err
+	}
+
+	// Check summary lines of the header.
+	err = opts.Header.Validate()
+	if err != nil {
+		return err
+	}
+
+	// Compile patterns of skipped devices once, filter is shared by collectors of all instances.
+	filter, err := stat.NewFilter(opts.Devices)
+	if err != nil {
+		return err
+	}
+
+	// Select language of UI.
+	messages, err := i18n.New(opts.UI)
+	if err != nil {
+		return err
+	}
+
 	// Create application instance.
-	app := newApp(db, newConfig())
+	config := newConfig()
+	config.readOnly = opts.ReadOnly
+	config.policies = opts.Actions
+	config.messages = messages
+	config.logreader = logreader
+	config.header = opts.Header
+	config.devices = filter
+	config.baseline, config.baselineThreshold = opts.Baseline, opts.Threshold
+	config.queryOptions.Databases, config.queryOptions.Users = opts.Databases, opts.Users
+
+	err = plugin.AddViews(config.views, opts.Plugins)
+	if err != nil {
+		return err
+	}
+
+	app := newApp(db, config)
+
+	// Connect to additional instances, each instance has its own state of stats views.
+	for _, c := range opts.Instances {
+		db, err := postgres.Connect(c)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		config := newConfig()
+		config.readOnly = opts.ReadOnly
+		config.policies = opts.Actions
+		config.messages = messages
+		config.logreader = logreader
+		config.header = opts.Header
+		config.devices = filter
+		config.queryOptions.Databases, config.queryOptions.Users = opts.Databases, opts.Users
+
+		err = plugin.AddViews(config.views, opts.Plugins)
+		if err != nil {
+			return err
+		}
+
+		app.addInstance(db, config)
+	}
 
 	// Setup application.
 	err = app.setup()
@@ -26,8 +154,115 @@ func RunMain(dbConfig postgres.Config) error {
 		return err
 	}
 
+	// Setup audit log of actions which change state of Postgres.
+	auditlog, err := audit.NewLog(opts.AuditFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = auditlog.Close() }()
+	app.audit = auditlog
+
+	// Setup user hooks, failed hooks are reported in command line.
+	hooks, err := hook.NewRunner(opts.Hooks, func(format string, a ...interface{}) {
+		printCmdline(app.ui, format, a...)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Notify user about events, notifications are shown even when terminal window is not focused.
+	if opts.Notify.Enabled() {
+		n := notify.New(opts.Notify, os.Stdout, func(format string, a ...interface{}) {
+			printCmdline(app.ui, format, a...)
+		})
+		hooks = hooks.Listen(n.Notify)
+	}
+
+	defer hooks.Wait()
+	app.setHooks(hooks)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Run alerts monitors, they are independent of UI.
+	if opts.Alerts.Enabled() {
+		err = startAlerts(ctx, app, opts.Alerts)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Run BPF tracing of local instances, it is independent of UI.
+	if opts.BPF {
+		err = startBPF(ctx, app)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Run pushing of stats, it is independent of UI.
+	if opts.Push.Enabled() {
+		err = startPush(ctx, app, opts.Push)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Run application workers and UI.
-	return mainLoop(context.Background(), app)
+	return mainLoop(ctx, app)
+}
+
+// startBPF creates BPF tracer and runs it until context is done. Tracer measures latencies of all Postgres processes
+// on the host, hence it is shared by all local instances. Errors of tracing are shown in command line.
+func startBPF(ctx context.Context, app *app) error {
+	if !app.db.Local {
+		return fmt.Errorf("BPF tracing is supported for local Postgres only")
+	}
+
+	tracer, err := stat.NewBPFTracer(func(format string, a ...interface{}) {
+		printCmdline(app.ui, format, a...)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range app.instances {
+		if inst.db.Local {
+			inst.config.bpf = tracer
+		}
+	}
+
+	go tracer.Run(ctx)
+
+	return nil
+}
+
+// startPush creates pushers for all instances and runs them until context is done. Errors of collecting and pushing
+// stats are shown in command line.
+func startPush(ctx context.Context, app *app, config push.Config) error {
+	if config.Stdout() {
+		return fmt.Errorf("pushing stats into stdout is not supported in top, it is used by UI")
+	}
+
+	logf := func(format string, a ...interface{}) {
+		printCmdline(app.ui, format, a...)
+	}
+
+	pushers := make([]*push.Pusher, len(app.instances))
+	for i := range app.instances {
+		p, err := push.NewPusher(config, logf)
+		if err != nil {
+			return err
+		}
+		pushers[i] = p
+	}
+
+	for i, inst := range app.instances {
+		pushers[i].SetFilter(inst.config.devices)
+		go pushers[i].Run(ctx, inst.db.Config)
+	}
+
+	return nil
 }
 
 // app defines application and all necessary dependencies.
@@ -36,20 +271,71 @@ type app struct {
 	ui            *gocui.Gui              // UI instance.
 	uiExit        chan int                // used for signaling when to need exiting from UI.
 	uiError       error                   // hold error occurred during executing UI.
+	uiNotice      string                  // message shown when UI starts.
+	uiCaps        []stat.Capability       // availability of views shown when UI starts, nil if all views are available.
 	db            *postgres.DB            // connection to Postgres.
+	reconnector   *reconnector            // tracks state of connection to Postgres.
 	postgresProps stat.PostgresProperties // properties of Postgres to which connected to.
+	stopWork      context.CancelFunc      // stops stats collecting and cancels in-flight queries.
+	instances     []*instance             // all connected instances, fields above refer to the current one.
+	current       int                     // index of the current instance.
+	hooks         *hook.Runner            // runs user hooks on events, nil if hooks are not configured.
+	audit         *audit.Log              // records actions which change state of Postgres.
 }
 
 // newApp creates new application instance.
 func newApp(db *postgres.DB, config *config) *app {
-	return &app{
-		config: config,
-		db:     db,
+	app := &app{
+		config:      config,
+		db:          db,
+		reconnector: newReconnector(),
 	}
+
+	app.instances = []*instance{newInstance(config, db, app.reconnector)}
+
+	return app
 }
 
-// setup performs initial application setup based on Postgres settings to which application connected to.
+// setup performs initial application setup based on settings of Postgres instances to which application connected to.
 func (app *app) setup() error {
+	for i := range app.instances {
+		app.activate(i)
+
+		err := app.setupInstance()
+		if err != nil {
+			return err
+		}
+	}
+
+	app.activate(0)
+	app.uiExit = make(chan int)
+
+	// Show warning about outdated stats schema when UI starts, otherwise show capabilities of connected role.
+	if msg := stat.CheckStatSchemaVersion(app.postgresProps); msg != "" {
+		app.uiError = errors.New(msg)
+	} else {
+		app.uiNotice = app.postgresProps.Privileges.Summary(app.config.messages)
+	}
+
+	// Show views which are not fully available on connected Postgres when UI starts.
+	caps := stat.GetCapabilities(app.db, app.postgresProps)
+	if stat.CapabilitiesSummary(caps) != "" {
+		app.uiCaps = caps
+	}
+
+	return nil
+}
+
+// setHooks sets runner of user hooks for application and connections of all instances.
+func (app *app) setHooks(hooks *hook.Runner) {
+	app.hooks = hooks
+	for _, inst := range app.instances {
+		inst.reconnector.hooks, inst.reconnector.instance = hooks, hook.InstanceName(inst.db.Config)
+	}
+}
+
+// setupInstance performs setup of the current instance based on Postgres settings.
+func (app *app) setupInstance() error {
 	// Fetch Postgres properties.
 	props, err := stat.GetPostgresProperties(app.db)
 	if err != nil {
@@ -57,7 +343,10 @@ func (app *app) setup() error {
 	}
 
 	// Create query options needed for formatting necessary queries.
-	opts := query.NewOptions(props.VersionNum, props.Recovery, props.GucTrackCommitTimestamp, 256)
+	opts := props.QueryOptions(256)
+
+	// Filter of databases and users is defined by user before setup.
+	opts.Databases, opts.Users = app.config.queryOptions.Databases, app.config.queryOptions.Users
 
 	// Create and configure stats views adjusting them depending on running Postgres.
 	err = app.config.views.Configure(opts)
@@ -70,7 +359,7 @@ func (app *app) setup() error {
 
 	app.config.queryOptions = opts
 	app.postgresProps = props
-	app.uiExit = make(chan int)
+	applyPrivileges(app, props.Privileges)
 
 	return nil
 }
@@ -78,6 +367,14 @@ func (app *app) setup() error {
 // quit performs graceful application quit.
 func (app *app) quit() func(g *gocui.Gui, _ *gocui.View) error {
 	return func(g *gocui.Gui, _ *gocui.View) error {
+		for _, inst := range app.instances {
+			if inst.config.profileCancel != nil {
+				inst.config.profileCancel()
+			}
+		}
+		if app.stopWork != nil {
+			app.stopWork()
+		}
 		close(app.uiExit)
 		g.Close()
 		app.db.Close()
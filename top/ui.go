@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/jroimartin/gocui"
+	"github.com/lesovsky/pgcenter/internal/log"
 	"github.com/lesovsky/pgcenter/internal/stat"
 	"sync"
 	"time"
@@ -13,6 +14,9 @@ import (
 func mainLoop(ctx context.Context, app *app) error {
 	var e errorRate
 
+	// Terminal is used by UI, log records written to stderr would break the screen.
+	log.DisableStderr()
+
 	// Run in infinite loop - if UI crashes then reinitialize it.
 	for {
 		// Init UI
@@ -33,6 +37,7 @@ func mainLoop(ctx context.Context, app *app) error {
 
 		var wg sync.WaitGroup
 		ctx, cancel := context.WithCancel(ctx)
+		app.stopWork = cancel
 
 		// Run backend workers which collect and print stats.
 		wg.Add(1)
@@ -41,6 +46,15 @@ func mainLoop(ctx context.Context, app *app) error {
 			wg.Done()
 		}()
 
+		// Show availability of views once, after the first drawing of UI.
+		if app.uiCaps != nil {
+			caps := app.uiCaps
+			app.uiCaps = nil
+			g.Update(func(g *gocui.Gui) error {
+				return openCapabilities(g, caps)
+			})
+		}
+
 		// Run UI management loop.
 		err = g.MainLoop()
 		if err != nil {
@@ -68,34 +82,68 @@ func mainLoop(ctx context.Context, app *app) error {
 	}
 }
 
+// doWork collects stats of all instances and prints stats of the current instance.
 func doWork(ctx context.Context, app *app) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	var wg sync.WaitGroup
-	statCh := make(chan stat.Stat)
+	statCh := make(chan instanceStat)
+
+	for _, inst := range app.instances {
+		// Start collecting using current view and default refresh interval. Refresh interval is not saved as
+		// per-view setting.
+		v := inst.config.view
+		v.Refresh = time.Second
+
+		ch := make(chan snapshot)
+
+		wg.Add(2)
+		go func(inst *instance) {
+			collectStat(ctx, inst.db, inst.reconnector, inst.history, headerSummary(inst.config.header), inst.config.devices, v, ch, inst.config.viewCh)
+			close(ch)
+			wg.Done()
+		}(inst)
+
+		// Forward stats of the instance to UI.
+		go func(inst *instance) {
+			for s := range ch {
+				select {
+				case statCh <- instanceStat{inst: inst, snap: s}:
+				case <-ctx.Done():
+				}
+			}
+			wg.Done()
+		}(inst)
+	}
 
-	wg.Add(1)
 	go func() {
-		collectStat(ctx, app.db, statCh, app.config.viewCh)
+		wg.Wait()
 		close(statCh)
-		wg.Done()
 	}()
 
-	// Send default view and default refresh interval to stats collector goroutine.
-	app.config.view.Refresh = time.Second
-	app.config.viewCh <- app.config.view
-
-	// Reset refresh interval, it should not be saved as per-view setting.
-	app.config.view.Refresh = 0
-
 	for {
 		select {
 		case <-app.uiExit:
 			// used for exit from UI (not the program) in case when need to open $PAGER or $EDITOR programs.
+			cancel()
+			for range statCh {
+			}
 			return
-		case s := <-statCh:
-			printStat(app, s, app.postgresProps)
+		case s, ok := <-statCh:
+			if !ok {
+				// All collectors have been stopped, wait until UI exits.
+				select {
+				case <-app.uiExit:
+				case <-ctx.Done():
+				}
+				return
+			}
+			printStat(app, s.inst, s.snap)
 		case <-ctx.Done():
-			close(statCh)
-			wg.Wait()
+			// Drain stats channel until collector goroutines finish and the channel is closed.
+			for range statCh {
+			}
 			return
 		}
 	}
@@ -112,8 +160,17 @@ func layout(app *app) func(g *gocui.Gui) error {
 			return fmt.Errorf("")
 		}
 
+		// Height of the header depends on number of configured summary lines. If the left column is empty, lines of the
+		// right column take full width.
+		height := app.config.header.Height()
+		left, _ := app.config.header.Columns()
+		pgstatX := maxX / 2
+		if len(left) == 0 {
+			pgstatX = -1
+		}
+
 		// Sysstat view.
-		v, err := app.ui.SetView("sysstat", -1, -1, maxX-1/2, 4)
+		v, err := app.ui.SetView("sysstat", -1, -1, maxX-1/2, height)
 		if err != nil {
 			if err != gocui.ErrUnknownView {
 				return fmt.Errorf("set sysstat view on layout failed: %s", err)
@@ -127,7 +184,7 @@ func layout(app *app) func(g *gocui.Gui) error {
 		}
 
 		// Postgres activity view.
-		v, err = app.ui.SetView("pgstat", maxX/2, -1, maxX, 4)
+		v, err = app.ui.SetView("pgstat", pgstatX, -1, maxX, height)
 		if err != nil {
 			if err != gocui.ErrUnknownView {
 				return fmt.Errorf("set pgstat view on layout failed: %s", err)
@@ -138,7 +195,7 @@ func layout(app *app) func(g *gocui.Gui) error {
 		}
 
 		// Command line.
-		v, err = app.ui.SetView("cmdline", -1, 3, maxX, 5)
+		v, err = app.ui.SetView("cmdline", -1, height-1, maxX, height+1)
 		if err != nil {
 			if err != gocui.ErrUnknownView {
 				return fmt.Errorf("set cmdline view on layout failed: %s", err)
@@ -147,6 +204,9 @@ func layout(app *app) func(g *gocui.Gui) error {
 			if app.uiError != nil {
 				printCmdline(app.ui, "%s", app.uiError)
 				app.uiError = nil
+			} else if app.uiNotice != "" {
+				printCmdline(app.ui, "%s", app.uiNotice)
+				app.uiNotice = ""
 			}
 		}
 		if v != nil {
@@ -154,7 +214,7 @@ func layout(app *app) func(g *gocui.Gui) error {
 		}
 
 		// Postgres main stats view.
-		v, err = app.ui.SetView("dbstat", -1, 4, maxX, maxY-1)
+		v, err = app.ui.SetView("dbstat", -1, height, maxX, maxY-1)
 		if err != nil {
 			if err != gocui.ErrUnknownView {
 				return fmt.Errorf("set dbstat view on layout failed: %s", err)
@@ -162,6 +222,10 @@ func layout(app *app) func(g *gocui.Gui) error {
 		}
 		if v != nil {
 			v.Frame = false
+
+			// Rows read from Postgres are limited by screen height, the first line is used by header.
+			_, y := v.Size()
+			app.config.setRows(y - 1)
 		}
 
 		// Extra stats view.
@@ -181,6 +245,13 @@ func layout(app *app) func(g *gocui.Gui) error {
 			}
 		}
 
+		// View has been changed by user, render stats of the changed view from cache instead of waiting for stats
+		// collected with it.
+		if app.config.viewChanged {
+			app.config.viewChanged = false
+			return renderCached(app.ui, app)
+		}
+
 		return nil
 	}
 }